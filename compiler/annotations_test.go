@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModuleAnnotations(t *testing.T) {
+	dir := t.TempDir()
+	helpers := `def double(n: int):
+    return n * 2
+
+def greet(name):
+    return "hi " + name
+`
+	if err := os.WriteFile(filepath.Join(dir, "helpers.simple"), []byte(helpers), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := filepath.Join(dir, "program")
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		want   string // the error, or "" when the program compiles
+	}{
+		{"annotated", "import helpers\n\nprint(helpers.double(4))\n", ""},
+		{"unannotated", "import helpers\n\nprint(helpers.greet(\"bob\"))\n", ""},
+		{"mismatch", "import helpers\n\nprint(helpers.double(\"x\"))\n", "argument 'n' to helpers.double() must be int, not str (Line 3, Column 21)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compile(tt.source, outputDir, true)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Fatalf("compiling: %v", err)
+			case tt.want != "" && err == nil:
+				t.Fatalf("compiled, want error %q", tt.want)
+			case tt.want != "" && !strings.Contains(err.Error(), tt.want):
+				t.Fatalf("error %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallableAnnotation(t *testing.T) {
+	source := `def twice(job: callable):
+    job()
+    job()
+
+def hello():
+    print("hello")
+
+twice(hello)
+twice(lambda: print("lambda"))
+`
+	want := "hello\nhello\nlambda\nlambda\n"
+	if got := runProgram(t, source); got != want {
+		t.Errorf("printed\n%s\nwant\n%s", got, want)
+	}
+}
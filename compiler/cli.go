@@ -0,0 +1,213 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Commands
+//
+// simple is run as simple <command> [flags] [arguments]. run builds a
+// program and runs it, and is the command when the first argument is a
+// .simple file, so simple hello.simple still runs hello.simple. build only
+// builds it, test builds and runs the test programs of a project, fmt lays
+// programs out and doc prints what a module defines; the other commands are
+// tools around the compiler. The flags may come before or after the
+// command; the usage of a command lists the ones it uses.
+
+// command is a command of simple.
+type command struct {
+	name    string
+	args    string // what follows the command and its flags, for its usage
+	summary string
+	flags   []string                // the names of the flags it uses, for its usage
+	run     func(args []string) int // returns the status simple exits with
+}
+
+// buildFlags are the flags of the commands that build a program, and
+// runFlags those of the commands that run one too.
+var (
+	buildFlags = []string{"v", "vv", "report", "sandbox", "O", "release", "trace", "reproducible", "verify-reproducible", "transform", "define", "platform", "lambda", "gcf", "azure"}
+	runFlags   = append([]string{"timeout", "cpu", "memory", "hot"}, buildFlags...)
+	testFlags  = []string{"v", "vv", "sandbox", "O", "release", "trace", "transform", "define", "timeout", "cpu", "memory"}
+)
+
+// commands are the commands of simple, in the order help lists them. They
+// are set by init, as help refers to them.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"run", "file.simple", "build a program and run it", runFlags, func(args []string) int {
+			return withProgram(args, func(filename string) int { return buildProgram(filename, true) })
+		}},
+		{"build", "file.simple", "build a program without running it, and print the path of the binary", buildFlags, func(args []string) int {
+			return withProgram(args, func(filename string) int { return buildProgram(filename, false) })
+		}},
+		{"test", "[file_test.simple|dir ...]", "build and run the test programs, *_test.simple, of directories, . unless others are given", testFlags, runTests},
+		{"fmt", "file.simple|dir ...", "lay programs out in the standard way, rewriting their files", nil, formatCommand},
+		{"doc", "file.simple [name]", "print the functions and classes a module defines, with their comments", nil, docCommand},
+		{"selftest", "", "check that the compiler builds and runs a suite of programs as Python runs them", []string{"v", "vv"}, selftestCommand},
+		{"import-from-python", "script.py", "translate a Python script into Simple", nil, importCommand},
+		{"grammar", "textmate|tree-sitter [dir]", "export a grammar for highlighting Simple in an editor", nil, grammarCommand},
+		{"licenses", "file.simple|dir", "list the Go modules built into a program, with their licenses", nil, licensesCommand},
+		{"version", "", "print the compiler version; with -check, warn when the stdlib is older than the compiler", []string{"check"}, versionCommand},
+		{"upgrade", "", "install the latest release of the compiler and its stdlib", nil, upgradeCommand},
+		{"help", "[command]", "print the usage of simple or of a command", nil, helpCommand},
+	}
+}
+
+// commandFor returns the command args start with and the arguments that
+// follow it, after parsing the flags given after the command. A .simple
+// file is run. It returns nil for no command, or an unknown one.
+func commandFor(args []string) (*command, []string) {
+	if len(args) == 0 {
+		return nil, args
+	}
+	if strings.HasSuffix(args[0], ".simple") {
+		return lookupCommand("run"), args
+	}
+	cmd := lookupCommand(args[0])
+	if cmd == nil {
+		return nil, args
+	}
+	flag.Usage = func() { commandUsage(cmd) }
+	flag.CommandLine.Parse(args[1:])
+	return cmd, flag.Args()
+}
+
+// lookupCommand returns the command of the given name, or nil.
+func lookupCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// usage prints the usage of simple: its commands and flags.
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Simple compiles Python-like .simple programs into Go binaries.")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "usage: simple <command> [flags] [arguments]")
+	fmt.Fprintln(out, "       simple [flags] file.simple")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "The commands are:")
+	fmt.Fprintln(out)
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-20s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Run simple help <command> for the usage of a command.")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "The flags are:")
+	flag.PrintDefaults()
+}
+
+// commandUsage prints the usage of a command and the flags it uses.
+func commandUsage(cmd *command) {
+	out := flag.CommandLine.Output()
+	if len(cmd.flags) == 0 {
+		fmt.Fprintf(out, "usage: simple %s %s\n\n", cmd.name, cmd.args)
+		fmt.Fprintf(out, "%s%s.\n", strings.ToUpper(cmd.summary[:1]), cmd.summary[1:])
+		return
+	}
+	fmt.Fprintf(out, "usage: simple %s [flags] %s\n\n", cmd.name, cmd.args)
+	fmt.Fprintf(out, "%s%s.\n\n", strings.ToUpper(cmd.summary[:1]), cmd.summary[1:])
+	fmt.Fprintln(out, "The flags are:")
+	// A flag set of the command's flags alone prints them as flag does
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.SetOutput(out)
+	for _, name := range cmd.flags {
+		f := flag.Lookup(name)
+		flags.Var(f.Value, f.Name, f.Usage)
+		flags.Lookup(name).DefValue = f.DefValue
+	}
+	flags.PrintDefaults()
+}
+
+// withProgram runs build with the .simple file of the arguments of run or
+// build.
+func withProgram(args []string, build func(filename string) int) int {
+	if len(args) != 1 || !strings.HasSuffix(args[0], ".simple") {
+		flag.Usage()
+		return 2
+	}
+	return build(args[0])
+}
+
+func helpCommand(args []string) int {
+	switch {
+	case len(args) == 0:
+		usage()
+	case len(args) == 1 && lookupCommand(args[0]) != nil:
+		commandUsage(lookupCommand(args[0]))
+	default:
+		printErrorf("unknown command %q; run simple help for the commands", strings.Join(args, " "))
+		return 2
+	}
+	return 0
+}
+
+func selftestCommand(args []string) int {
+	if len(args) != 0 {
+		flag.Usage()
+		return 2
+	}
+	failed, err := selftest()
+	if err != nil {
+		printError(err)
+		return 1
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func importCommand(args []string) int {
+	if len(args) != 1 || !strings.HasSuffix(args[0], ".py") {
+		flag.Usage()
+		return 2
+	}
+	if err := importFromPython(args[0]); err != nil {
+		printError(err)
+		return 1
+	}
+	return 0
+}
+
+func grammarCommand(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		flag.Usage()
+		return 2
+	}
+	dir := "."
+	if len(args) == 2 {
+		dir = args[1]
+	}
+	written, err := exportGrammar(args[0], dir)
+	if err != nil {
+		printError(err)
+		return 1
+	}
+	for _, path := range written {
+		fmt.Println("wrote", path)
+	}
+	return 0
+}
+
+func licensesCommand(args []string) int {
+	if len(args) != 1 {
+		flag.Usage()
+		return 2
+	}
+	if err := licenseReport(args[0]); err != nil {
+		printError(err)
+		return 1
+	}
+	return 0
+}
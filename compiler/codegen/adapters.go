@@ -0,0 +1,50 @@
+package codegen
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// generateAdapters returns the source of the adapter types the analyzer
+// asked for, registering any packages their signatures need as imports.
+// It must run before the imports are written.
+func (cg *CodeGenerator) generateAdapters() string {
+	names := make([]string, 0, len(cg.analyzer.Adapters))
+	for name := range cg.analyzer.Adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	for _, name := range names {
+		adapter := cg.analyzer.Adapters[name]
+		sig := adapter.Method.Type().(*types.Signature)
+
+		params := []string{}
+		args := []string{}
+		for i := 0; i < sig.Params().Len(); i++ {
+			paramType := types.TypeString(sig.Params().At(i).Type(), cg.qualify)
+			arg := fmt.Sprintf("p%d", i)
+			if sig.Variadic() && i == sig.Params().Len()-1 {
+				paramType = "..." + strings.TrimPrefix(paramType, "[]")
+				arg += "..."
+			}
+			params = append(params, fmt.Sprintf("p%d %s", i, paramType))
+			args = append(args, arg)
+		}
+		results := strings.TrimPrefix(types.TypeString(sig, cg.qualify), types.TypeString(types.NewSignatureType(nil, nil, nil, sig.Params(), nil, sig.Variadic()), cg.qualify))
+
+		fmt.Fprintf(&out, "// %s lets a function be used as a %s.%s.\n", name, adapter.Interface.Package, adapter.Interface.Name)
+		fmt.Fprintf(&out, "type %s func(%s)%s\n\n", name, strings.Join(params, ", "), results)
+		fmt.Fprintf(&out, "func (f %s) %s(%s)%s {\n", name, adapter.Method.Name(), strings.Join(params, ", "), results)
+		if sig.Results().Len() > 0 {
+			fmt.Fprintf(&out, "\treturn f(%s)\n", strings.Join(args, ", "))
+		} else {
+			fmt.Fprintf(&out, "\tf(%s)\n", strings.Join(args, ", "))
+		}
+		fmt.Fprint(&out, "}\n\n")
+	}
+	return out.String()
+}
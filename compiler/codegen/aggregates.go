@@ -0,0 +1,106 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+)
+
+// generateAggregate writes a call of min(), max() or sum().
+func (cg *CodeGenerator) generateAggregate(file *os.File, ce *parser.CallExpression, ac *semantic.AggregateCall) {
+	if ac.Name == "sum" {
+		cg.generateSum(file, ac)
+		return
+	}
+	resultType := cg.typeToGoString(ac.Type)
+	writeArgument := func(arg parser.Expression) {
+		if resultType == "float64" {
+			cg.generateFloat(file, arg)
+		} else {
+			cg.generateExpression(file, arg)
+		}
+	}
+
+	if ac.Items == nil && ac.Key == nil && (resultType == "int" || resultType == "float64" || resultType == "string") {
+		// Go's own min and max compare values of its ordered types
+		fmt.Fprintf(file, "%s(", ac.Name)
+		for i, arg := range ac.Arguments {
+			if i > 0 {
+				fmt.Fprint(file, ", ")
+			}
+			writeArgument(arg)
+		}
+		fmt.Fprint(file, ")")
+		return
+	}
+
+	cg.useHelper("simpleBest")
+	fmt.Fprintf(file, "simpleBest(%q, ", ac.Name)
+	if ac.Items != nil {
+		cg.generateItems(file, ac.Kind, ac.Items)
+	} else {
+		fmt.Fprintf(file, "[]%s{", resultType)
+		for i, arg := range ac.Arguments {
+			if i > 0 {
+				fmt.Fprint(file, ", ")
+			}
+			writeArgument(arg)
+		}
+		fmt.Fprint(file, "}")
+	}
+	fmt.Fprint(file, ", ")
+	cg.generateKey(file, ac.ItemType, ac.Key)
+	if ac.Default != nil {
+		fmt.Fprintf(file, ", func() %s { return ", resultType)
+		cg.generateExpression(file, ac.Default)
+		fmt.Fprint(file, " }")
+	} else {
+		fmt.Fprint(file, ", nil")
+	}
+	fmt.Fprintf(file, ", %d, %q)", ce.Token.Line, cg.tracebackFunction())
+}
+
+// generateSum writes a call of sum() as a call to simpleSum, which adds
+// the items of a list of ints or floats in a typed loop, or to
+// simpleSumAny for items of no known type. A float start added to ints is
+// added to their sum.
+func (cg *CodeGenerator) generateSum(file *os.File, ac *semantic.AggregateCall) {
+	sumType := cg.typeToGoString(ac.Type)
+	itemType := cg.typeToGoString(ac.ItemType)
+	switch {
+	case sumType != "int" && sumType != "float64", itemType != "int" && itemType != "float64":
+		cg.useHelper("simpleSumAny")
+		fmt.Fprint(file, "simpleSumAny(")
+		cg.generateExpression(file, ac.Items)
+		fmt.Fprint(file, ", ")
+		if ac.Start != nil {
+			cg.generateExpression(file, ac.Start)
+		} else {
+			fmt.Fprint(file, "0")
+		}
+		fmt.Fprint(file, ")")
+	case sumType != itemType:
+		// sum(counts, 0.5), a float start for ints
+		cg.useHelper("simpleSum")
+		fmt.Fprint(file, "(float64(simpleSum(")
+		cg.generateItems(file, ac.Kind, ac.Items)
+		fmt.Fprint(file, ", 0)) + ")
+		cg.generateFloat(file, ac.Start)
+		fmt.Fprint(file, ")")
+	default:
+		cg.useHelper("simpleSum")
+		fmt.Fprint(file, "simpleSum(")
+		cg.generateItems(file, ac.Kind, ac.Items)
+		fmt.Fprint(file, ", ")
+		switch {
+		case ac.Start == nil:
+			fmt.Fprint(file, "0")
+		case itemType == "float64":
+			cg.generateFloat(file, ac.Start)
+		default:
+			cg.generateExpression(file, ac.Start)
+		}
+		fmt.Fprint(file, ")")
+	}
+}
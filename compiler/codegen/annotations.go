@@ -0,0 +1,53 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+)
+
+// generateReturnValue writes the value of a return statement. A value whose
+// type is only known when the program runs is asserted to the type the
+// function's result is annotated with.
+func (cg *CodeGenerator) generateReturnValue(file *os.File, value parser.Expression) {
+	if cg.result != nil {
+		cg.generateAsserted(file, value, cg.result)
+		return
+	}
+	cg.generateExpression(file, value)
+}
+
+// annotatedArgument writes an argument of a call of a function defined with
+// def whose parameter is annotated, asserting a value whose type is only
+// known when the program runs to the parameter's type. It reports whether
+// the parameter is annotated.
+func (cg *CodeGenerator) annotatedArgument(file *os.File, ce *parser.CallExpression, i int, arg parser.Expression) bool {
+	ft, ok := cg.analyzer.InferExpressionTypes(ce.Function, false)[0].(*parser.FunctionType)
+	if !ok {
+		return false
+	}
+	want := cg.analyzer.ParameterAnnotation(ft, i)
+	if want == nil {
+		return false
+	}
+	cg.generateAsserted(file, arg, want)
+	return true
+}
+
+// generateAsserted writes a value, asserted to type want when its own type
+// is only known when the program runs. A float may be any number.
+func (cg *CodeGenerator) generateAsserted(file *os.File, value parser.Expression, want parser.Type) {
+	got := goTypeName(cg.analyzer.InferExpressionTypes(value, false)[0].String())
+	switch name := goTypeName(want.String()); {
+	case got != "interface{}" || name == "interface{}" || name == "void":
+		cg.generateExpression(file, value)
+	case name == "float64":
+		cg.useHelper("simpleToFloat")
+		fmt.Fprint(file, "simpleToFloat(")
+		cg.generateExpression(file, value)
+		fmt.Fprint(file, ")")
+	default:
+		cg.generateExpression(file, value)
+		fmt.Fprintf(file, ".(%s)", name)
+	}
+}
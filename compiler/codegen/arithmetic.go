@@ -0,0 +1,76 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+)
+
+// generatePower writes ** as simpleIntPow of two ints, which is exact where
+// the floats of math.Pow lose digits past 2 ** 53, as math.Pow of floats,
+// and as simplePowAny when the types are only known when the program runs.
+func (cg *CodeGenerator) generatePower(file *os.File, ie *parser.InfixExpression) {
+	switch cg.analyzer.InferExpressionTypes(ie, false)[0].String() {
+	case "int":
+		cg.generateHelperCall(file, "simpleIntPow", ie)
+		return
+	case "interface{}":
+		cg.generateHelperCall(file, "simplePowAny", ie)
+		return
+	}
+	cg.imports["math"] = true
+	fmt.Fprint(file, "math.Pow(")
+	cg.generateFloat(file, ie.Left)
+	fmt.Fprint(file, ", ")
+	cg.generateFloat(file, ie.Right)
+	fmt.Fprint(file, ")")
+}
+
+// generateFloorDivision writes // as simpleFloorDiv of two ints, as
+// math.Floor of the division of floats, and as simpleFloorDivAny when the
+// types are only known when the program runs.
+func (cg *CodeGenerator) generateFloorDivision(file *os.File, ie *parser.InfixExpression) {
+	switch cg.analyzer.InferExpressionTypes(ie, false)[0].String() {
+	case "int":
+		cg.generateHelperCall(file, "simpleFloorDiv", ie)
+		return
+	case "interface{}":
+		cg.generateHelperCall(file, "simpleFloorDivAny", ie)
+		return
+	}
+	cg.imports["math"] = true
+	fmt.Fprint(file, "math.Floor(")
+	cg.generateFloat(file, ie.Left)
+	fmt.Fprint(file, " / ")
+	cg.generateFloat(file, ie.Right)
+	fmt.Fprint(file, ")")
+}
+
+// generateHelperCall writes an operator as the call of a helper with its
+// operands.
+func (cg *CodeGenerator) generateHelperCall(file *os.File, helper string, ie *parser.InfixExpression) {
+	cg.useHelper(helper)
+	fmt.Fprintf(file, "%s(", helper)
+	cg.generateExpression(file, ie.Left)
+	fmt.Fprint(file, ", ")
+	cg.generateExpression(file, ie.Right)
+	fmt.Fprint(file, ")")
+}
+
+// generateFloat writes a number as a float64, converting an int, or a
+// number of a type only known when the program runs.
+func (cg *CodeGenerator) generateFloat(file *os.File, x parser.Expression) {
+	switch goTypeName(cg.analyzer.InferExpressionTypes(x, false)[0].String()) {
+	case "float64":
+		cg.generateExpression(file, x)
+	case "int":
+		fmt.Fprint(file, "float64(")
+		cg.generateExpression(file, x)
+		fmt.Fprint(file, ")")
+	default:
+		cg.useHelper("simpleToFloat")
+		fmt.Fprint(file, "simpleToFloat(")
+		cg.generateExpression(file, x)
+		fmt.Fprint(file, ")")
+	}
+}
@@ -0,0 +1,53 @@
+package codegen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BuildInfo describes how a Simple program was compiled. It is embedded in
+// the generated binary and printed when the binary is run with --version.
+type BuildInfo struct {
+	CompilerVersion string
+	SourceHash      string
+	BuildTime       string
+}
+
+// WriteBuildInfo writes simple_buildinfo.go into outputDir. The file is kept
+// apart from main.go so its imports never clash with the program's own.
+func WriteBuildInfo(outputDir string, info BuildInfo) error {
+	file, err := os.Create(filepath.Join(outputDir, "simple_buildinfo.go"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fmt.Fprint(file, "// Code generated by the Simple compiler. DO NOT EDIT.\n\n")
+	fmt.Fprint(file, "package main\n\n")
+	fmt.Fprintln(file, "import (")
+	fmt.Fprintf(file, "\t%q\n", "fmt")
+	fmt.Fprintf(file, "\t%q\n", "os")
+	fmt.Fprintln(file, ")")
+	fmt.Fprintln(file)
+	fmt.Fprintln(file, "const (")
+	fmt.Fprintf(file, "\tsimpleCompilerVersion = %q\n", info.CompilerVersion)
+	fmt.Fprintf(file, "\tsimpleSourceHash      = %q\n", info.SourceHash)
+	fmt.Fprintf(file, "\tsimpleBuildTime       = %q\n", info.BuildTime)
+	fmt.Fprintln(file, ")")
+	fmt.Fprintln(file)
+	fmt.Fprintln(file, "func init() {")
+	fmt.Fprintln(file, "\tif len(os.Args) == 2 && os.Args[1] == \"--version\" {")
+	fmt.Fprintln(file, "\t\tfmt.Println(simpleCompilerVersion)")
+	fmt.Fprintln(file, "\t\tfmt.Println(\"source sha256:\", simpleSourceHash)")
+	if info.BuildTime != "" {
+		// A reproducible build leaves the time out unless SOURCE_DATE_EPOCH
+		// gives one
+		fmt.Fprintln(file, "\t\tfmt.Println(\"built:\", simpleBuildTime)")
+	}
+	fmt.Fprintln(file, "\t\tos.Exit(0)")
+	fmt.Fprintln(file, "\t}")
+	fmt.Fprintln(file, "}")
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+	"strings"
+)
+
+// generateClass writes a class as a Go struct with a field for each of its
+// attributes, embedding the struct of the class it inherits from, and a
+// method with a pointer receiver for __init__ and each of its other
+// methods. A NewX function makes an instance, gives the fields their
+// defaults from the class bodies and calls __init__. A class whose objects
+// are keys compared by __eq__ gets a simpleEq method, which simpleKey calls
+// with a key of any type.
+func (cg *CodeGenerator) generateClass(file *os.File, cs *parser.ClassStatement) {
+	class, ok := cg.analyzer.Classes[cs.Name.Value]
+	if !ok {
+		return
+	}
+
+	fmt.Fprintf(file, "type %s struct {\n", class.Name)
+	if class.Parent != nil {
+		fmt.Fprintf(file, "\t%s\n", class.Parent.Name)
+	}
+	for _, field := range class.Fields {
+		fmt.Fprintf(file, "\t%s %s\n", cg.memberName(field.Name), cg.typeToGoString(field.Type))
+	}
+	fmt.Fprint(file, "}\n\n")
+
+	params := []string{}
+	args := []string{}
+	if initClass := class.InitClass(); initClass != nil {
+		for i, param := range initClass.Params() {
+			params = append(params, fmt.Sprintf("%s %s", cg.goName(param.Value), cg.typeToGoString(initClass.ParamTypes[i])))
+			args = append(args, cg.goName(param.Value))
+		}
+	}
+	fmt.Fprintf(file, "func New%s(%s) *%s {\n", class.Name, strings.Join(params, ", "), class.Name)
+	cg.indentLevel++
+	cg.writeIndent(file)
+	fmt.Fprintf(file, "self := &%s{}\n", class.Name)
+	chain := []*semantic.Class{}
+	for c := class; c != nil; c = c.Parent {
+		chain = append([]*semantic.Class{c}, chain...)
+	}
+	for _, c := range chain {
+		for _, d := range c.Defaults {
+			cg.writeIndent(file)
+			fmt.Fprintf(file, "self.%s = ", cg.memberName(d.Left[0].String()))
+			cg.generateExpression(file, d.Value)
+			fmt.Fprintln(file)
+		}
+	}
+	if class.InitClass() != nil {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "self.__init__(%s)\n", strings.Join(args, ", "))
+	}
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "return self")
+	cg.indentLevel--
+	fmt.Fprint(file, "}\n\n")
+
+	if class.Init != nil {
+		cg.generateMethodFunc(file, class, class.Init, class.InitTable, class.Params(), class.ParamTypes, nil)
+	}
+	for _, method := range class.Methods {
+		cg.generateMethod(file, class, method)
+	}
+	if cg.analyzer.KeyClasses[class.Name] {
+		fmt.Fprintf(file, "func (self *%s) simpleEq(other any) bool {\n", class.Name)
+		fmt.Fprintf(file, "\to, ok := other.(*%s)\n", class.Name)
+		fmt.Fprint(file, "\treturn ok && self.__eq__(o)\n}\n\n")
+	}
+}
+
+// generateMethod writes a method of a class as a Go method whose receiver,
+// self, is a pointer to the class's struct.
+func (cg *CodeGenerator) generateMethod(file *os.File, class *semantic.Class, method *semantic.Method) {
+	cg.generateMethodFunc(file, class, method.Fn, method.Table, method.Params(), method.Type.ParameterTypes, method.Type)
+}
+
+// generateMethodFunc writes a Go method with the given parameters after
+// self, whose body is analyzed in table. __init__ has no type, as it has
+// no results.
+func (cg *CodeGenerator) generateMethodFunc(file *os.File, class *semantic.Class, fn *parser.FunctionLiteral, table *semantic.SymbolTable, params []*parser.Identifier, paramTypes []parser.Type, ft *parser.FunctionType) {
+	prevTable := cg.analyzer.CurrentTable
+	cg.analyzer.CurrentTable = table
+	prevFunction, prevResult, prevTries := cg.function, cg.result, cg.tries
+	cg.function, cg.result, cg.tries = ft, cg.analyzer.ResultAnnotation(fn), nil
+	declared := []string{"self"}
+	paramList := []string{}
+	for i, param := range params {
+		paramList = append(paramList, fmt.Sprintf("%s %s", cg.goName(param.Value), cg.typeToGoString(paramTypes[i])))
+		declared = append(declared, param.Value)
+	}
+	for _, name := range declared {
+		if symbol, ok := table.Symbols[name]; ok {
+			symbol.Metadata = map[string]any{"set": true}
+		}
+	}
+
+	returnType := ""
+	if ft != nil {
+		returnType = resultString(ft)
+	}
+	if returnType != "" {
+		fmt.Fprintf(file, "func (self *%s) %s(%s) %s {\n", class.Name, cg.memberName(fn.Name.Value), strings.Join(paramList, ", "), returnType)
+	} else {
+		fmt.Fprintf(file, "func (self *%s) %s(%s) {\n", class.Name, cg.memberName(fn.Name.Value), strings.Join(paramList, ", "))
+	}
+	cg.Returns["currentFunc"] = map[string]bool{"expects": returnType != "", "done": false}
+	cg.indentLevel++
+	if cg.Trace {
+		cg.writeTracePrologue(file, class.Name+"."+fn.Name.Value, params)
+	}
+	cg.generateBlockStatement(file, fn.Body, prevTable)
+	if cg.Returns["currentFunc"]["expects"] && !cg.Returns["currentFunc"]["done"] {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "return %s\n", strings.Join(defaultReturnValues(ft), ", "))
+	}
+	cg.indentLevel--
+	fmt.Fprint(file, "}\n\n")
+	cg.Returns["currentFunc"] = map[string]bool{"expects": false, "done": false}
+	cg.analyzer.CurrentTable = prevTable
+	cg.function, cg.result, cg.tries = prevFunction, prevResult, prevTries
+}
+
+// generateConstructorCall writes a call that constructs a class as a call
+// of its NewX function. Keyword arguments have been put in order by the
+// analyzer.
+func (cg *CodeGenerator) generateConstructorCall(file *os.File, ce *parser.CallExpression, className string) {
+	fmt.Fprintf(file, "New%s(", className)
+	cg.generateExpressionList(file, ce.Arguments)
+	fmt.Fprint(file, ")")
+}
+
+// isEmptyInitCall reports whether a call is of an __init__ that does
+// nothing: that of object, as super().__init__() is in a class that
+// inherits from no other, or of a class that neither defines nor inherits
+// one.
+func (cg *CodeGenerator) isEmptyInitCall(expr parser.Expression) bool {
+	ce, ok := expr.(*parser.CallExpression)
+	if !ok {
+		return false
+	}
+	se, ok := ce.Function.(*parser.SelectorExpression)
+	if !ok || se.Selector.Value != "__init__" {
+		return false
+	}
+	if inner, ok := se.Left.(*parser.CallExpression); ok && semantic.IsSuperCall(inner) {
+		if _, ok := cg.analyzer.SuperCalls[inner]; !ok {
+			return true
+		}
+	}
+	class, ok := cg.analyzer.ClassOf(cg.analyzer.InferExpressionTypes(se.Left, false)[0])
+	return ok && class.InitClass() == nil
+}
@@ -1,160 +1,270 @@
 package codegen
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/sasogeek/simple/compiler/lexer"
 	"github.com/sasogeek/simple/compiler/parser"
 	"github.com/sasogeek/simple/compiler/semantic"
 	"github.com/sasogeek/simple/compiler/transformer"
+	"github.com/sasogeek/simple/compiler/verbose"
 	"go/types"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 )
 
 // CodeGenerator generates Go code from the AST.
 type CodeGenerator struct {
-	outputDir   string
-	imports     map[string]bool
-	indentLevel int
-	analyzer    *semantic.Analyzer
-	Returns     map[string]map[string]bool
-	isMain      bool
-	stdLib      map[string]bool
+	outputDir     string
+	imports       map[string]bool
+	importAliases map[string]string // import path -> alias
+	simpleModules map[string]bool   // names bound to imported Simple modules
+	indentLevel   int
+	analyzer      *semantic.Analyzer
+	Returns       map[string]map[string]bool
+	isMain        bool
+	stdLib        map[string]bool
+	HotReload     bool // route top-level functions through the hot reload registry
+	Optimize      int  // optimization level: 1 builds strings of strings and ints without fmt
+	Release       bool // leave assert statements out
+	Trace         bool // log every call of the program's functions; see writeTracePrologue
+	adapters      string
+	helpers       map[string]bool      // runtime helpers used by the generated code
+	function      *parser.FunctionType // the function being generated, nil at the top level
+	result        parser.Type          // the type its result is annotated with, or nil
+	tries         []*tryFrame          // try and with statements being generated in it, innermost last
+	tryCount      int
+	withCount     int
+	matches       []*matchFrame // match statements being generated in it, innermost last
+	matchCount    int
+	exported      map[string]bool   // a module's functions, exported by their Go names
+	reserved      map[string]bool   // names the program's variables and functions can't have in Go
+	names         map[string]bool   // names the program uses
+	declared      map[string]bool   // names the program declares
+	renames       map[string]string // Go names given to the program's names
+	Platform      string            // the platform the program is built for, or ""; see heroku.go
+	listens       bool              // whether it calls http.ListenAndServe, made to listen on the platform's port
 }
 
 func NewCodeGenerator(outputDir string, analyzer *semantic.Analyzer, isMain bool) *CodeGenerator {
-	stdLib := map[string]bool{
-		"json": true,
-	}
 	return &CodeGenerator{
-		outputDir:   outputDir,
-		imports:     make(map[string]bool),
-		indentLevel: 0,
-		analyzer:    analyzer,
-		Returns:     make(map[string]map[string]bool),
-		isMain:      isMain,
-		stdLib:      stdLib,
+		outputDir:     outputDir,
+		imports:       make(map[string]bool),
+		importAliases: make(map[string]string),
+		simpleModules: make(map[string]bool),
+		helpers:       make(map[string]bool),
+		exported:      make(map[string]bool),
+		reserved:      reservedNames(),
+		renames:       make(map[string]string),
+		indentLevel:   0,
+		analyzer:      analyzer,
+		Returns:       make(map[string]map[string]bool),
+		isMain:        isMain,
+		stdLib:        semantic.StdlibModules,
 	}
 }
 
 // GenerateCode generates Go code from the program.
 func (cg *CodeGenerator) GenerateCode(program *parser.Program) error {
-
+	cg.collectNames(program)
 	if cg.isMain {
-		mainFilePath := filepath.Join(cg.outputDir, "main.go")
-		mainFile, err := os.Create(mainFilePath)
-		if err != nil {
-			return err
-		}
-		defer mainFile.Close()
-
-		fmt.Fprintln(mainFile, "package main\n")
+		mainRenames = cg.renames
+	}
 
-		// Collect imports
-		err = cg.collectImports(program)
-		if err != nil {
-			return err
-		}
+	// Collect imports
+	if err := cg.collectImports(program); err != nil {
+		return err
+	}
 
-		// Write imports
-		if len(cg.imports) > 0 {
-			fmt.Fprintln(mainFile, "import (")
-			for imp := range cg.imports {
-				fmt.Fprintf(mainFile, "\t%q\n", imp)
+	if cg.isMain {
+		return cg.writeFile(filepath.Join(cg.outputDir, "main.go"), "main", func(mainFile *os.File) {
+			cg.generateConstants(mainFile, program)
+			cg.generateGlobals(mainFile)
+
+			// Generate code for global statements (functions)
+			for _, stmt := range program.Statements {
+				if _, ok := stmt.(*parser.FunctionLiteral); ok {
+					cg.generateFunction(mainFile, stmt.(*parser.FunctionLiteral), cg.analyzer.CurrentTable, false)
+				}
+				if cs, ok := stmt.(*parser.ClassStatement); ok {
+					cg.generateClass(mainFile, cs)
+				}
 			}
-			fmt.Fprintln(mainFile, ")\n")
-		}
 
-		// Generate code for global statements (functions)
-		for _, stmt := range program.Statements {
-			if _, ok := stmt.(*parser.FunctionLiteral); ok {
-				cg.generateFunction(mainFile, stmt.(*parser.FunctionLiteral), cg.analyzer.CurrentTable, false)
+			// Generate main function
+			fmt.Fprintln(mainFile, "func main() {")
+			cg.indentLevel++
+			if cg.raisesExceptions(program) {
+				cg.useHelper("simpleException")
+				cg.writeIndent(mainFile)
+				fmt.Fprintln(mainFile, "defer simpleUncaught()")
 			}
-		}
-
-		// Generate main function
-		fmt.Fprintln(mainFile, "func main() {")
-		cg.indentLevel++
-		for _, stmt := range program.Statements {
-			if _, ok := stmt.(*parser.FunctionLiteral); !ok {
-				cg.generateStatement(mainFile, stmt, cg.analyzer.CurrentTable)
+			for _, stmt := range program.Statements {
+				switch stmt.(type) {
+				case *parser.FunctionLiteral, *parser.ClassStatement:
+				default:
+					if !cg.isConstant(stmt) {
+						cg.generateStatement(mainFile, stmt, cg.analyzer.CurrentTable)
+					}
+				}
 			}
-		}
-		cg.indentLevel--
-		fmt.Fprintln(mainFile, "}")
-
-		return nil
-
-	} else {
-		mainFilePath := filepath.Join(cg.outputDir, fmt.Sprintf("%s.go", filepath.Base(cg.outputDir)))
-		mainFile, err := os.Create(mainFilePath)
-		if err != nil {
-			return err
-		}
-		defer mainFile.Close()
-		fmt.Fprintln(mainFile, fmt.Sprintf("package %s\n", filepath.Base(cg.outputDir)))
-
-		// Collect imports
-		err = cg.collectImports(program)
-		if err != nil {
-			return err
-		}
+			cg.indentLevel--
+			fmt.Fprint(mainFile, "}\n\n")
+		})
+	}
 
-		// Write imports
-		if len(cg.imports) > 0 {
-			fmt.Fprintln(mainFile, "import (")
-			for imp := range cg.imports {
-				fmt.Fprintf(mainFile, "\t%q\n", imp)
-			}
-			fmt.Fprintln(mainFile, ")\n")
+	packageName := filepath.Base(cg.outputDir)
+	for _, stmt := range program.Statements {
+		if fn, ok := stmt.(*parser.FunctionLiteral); ok && fn.Name != nil {
+			cg.exported[fn.Name.Value] = true
 		}
+	}
+	return cg.writeFile(filepath.Join(cg.outputDir, packageName+".go"), packageName, func(mainFile *os.File) {
+		cg.generateConstants(mainFile, program)
+		cg.generateGlobals(mainFile)
 
 		// Generate code for global statements (functions)
 		for _, stmt := range program.Statements {
 			if _, ok := stmt.(*parser.FunctionLiteral); ok {
 				cg.generateFunction(mainFile, stmt.(*parser.FunctionLiteral), cg.analyzer.CurrentTable, true)
 			}
+			if cs, ok := stmt.(*parser.ClassStatement); ok {
+				cg.generateClass(mainFile, cs)
+			}
 		}
+	})
+}
 
-		return nil
+// writeFile writes a generated Go file. The code is generated first, into a
+// temporary file, so that the helpers and packages it uses can be registered
+// while it is written, and then copied in after the imports.
+func (cg *CodeGenerator) writeFile(path string, packageName string, generate func(file *os.File)) error {
+	body, err := os.CreateTemp("", "simple-*.go")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(body.Name())
+	defer body.Close()
 
+	generate(body)
+	body.WriteString(cg.adapters)
+	cg.writeHelpers(body)
+
+	// fmt is imported for code that uses it without registering it, and
+	// left out when nothing does
+	code, err := os.ReadFile(body.Name())
+	if err != nil {
+		return err
+	}
+	if cg.importAliases["fmt"] == "" && !bytes.Contains(code, []byte("fmt.")) {
+		delete(cg.imports, "fmt")
 	}
 
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fmt.Fprintf(file, "package %s\n\n", packageName)
+	cg.writeImports(file)
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(file, body)
+	return err
 }
 
 // collectImports collects imports from the program.
 func (cg *CodeGenerator) collectImports(program *parser.Program) error {
 	for _, stmt := range program.Statements {
 		if imp, ok := stmt.(*parser.ImportStatement); ok {
-			if imp.IsSimpleImport {
-				// Handle simple import
-				packageName := imp.ImportedModule.Value
-				if err := cg.processSimpleImport(packageName); err != nil {
-					return fmt.Errorf("failed to process simple import '%s': %v", packageName, err)
+			for _, spec := range imp.Imports {
+				if spec.IsSimpleImport {
+					// Handle simple import
+					packageName := spec.ImportedModule.Value
+					importPath, err := cg.processSimpleImport(packageName)
+					if err != nil {
+						return fmt.Errorf("failed to process simple import '%s': %v", packageName, err)
+					}
+					cg.simpleModules[spec.Name()] = true
+					if spec.Alias != "" {
+						cg.importAliases[importPath] = spec.Alias
+					}
+				} else {
+					// Handle Go import
+					module := strings.Trim(spec.ImportedModule.Value, "\"")
+					cg.imports[module] = true
+					if spec.Alias != "" {
+						cg.importAliases[module] = spec.Alias
+					}
 				}
-			} else {
-				// Handle Go import
-				module := strings.Trim(imp.ImportedModule.Value, "\"")
-				cg.imports[module] = true
 			}
 		}
 	}
 
 	cg.imports["fmt"] = true
+	cg.structLiteralImports()
+	cg.adapters = cg.generateAdapters()
+	cg.collectHelpers()
 
 	return nil
 }
 
-// processSimpleImport processes a simple import by generating a separate Go package.
-func (cg *CodeGenerator) processSimpleImport(packageName string) error {
+// writeImports writes all collected imports as a single sorted import group.
+func (cg *CodeGenerator) writeImports(file *os.File) {
+	if len(cg.imports) == 0 {
+		return
+	}
+	paths := cg.Imports()
+
+	fmt.Fprintln(file, "import (")
+	for _, imp := range paths {
+		if alias, ok := cg.importAliases[imp]; ok {
+			fmt.Fprintf(file, "\t%s %q\n", alias, imp)
+		} else {
+			fmt.Fprintf(file, "\t%q\n", imp)
+		}
+	}
+	fmt.Fprint(file, ")\n\n")
+}
+
+// Imports returns the sorted import paths of the generated file.
+func (cg *CodeGenerator) Imports() []string {
+	paths := make([]string, 0, len(cg.imports))
+	for imp := range cg.imports {
+		paths = append(paths, imp)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// qualify names a package in generated code by its alias or name,
+// registering it as an import.
+func (cg *CodeGenerator) qualify(p *types.Package) string {
+	if alias, ok := cg.importAliases[p.Path()]; ok {
+		return alias
+	}
+	cg.imports[p.Path()] = true
+	return p.Name()
+}
+
+// processSimpleImport processes a simple import by generating a separate Go
+// package, and returns the path the package is imported by.
+func (cg *CodeGenerator) processSimpleImport(packageName string) (string, error) {
 	// Prevent processing the same package multiple times
 	if cg.stdLib[packageName] {
-		cg.imports[fmt.Sprintf("%s/lib/%s", filepath.Base(cg.outputDir), packageName)] = true
-		return nil
+		importPath := fmt.Sprintf("%s/lib/%s", filepath.Base(cg.outputDir), packageName)
+		cg.imports[importPath] = true
+		return importPath, nil
 	}
-	if _, alreadyProcessed := cg.imports[packageName]; alreadyProcessed {
-		return nil
+	importPath := fmt.Sprintf("%s/%s", filepath.Base(cg.outputDir), packageName)
+	if _, alreadyProcessed := cg.imports[importPath]; alreadyProcessed {
+		return importPath, nil
 	}
 
 	// Assume the simple file has a .simple extension
@@ -163,7 +273,7 @@ func (cg *CodeGenerator) processSimpleImport(packageName string) error {
 	data, err := os.ReadFile(simpleFilePath)
 	if err != nil {
 
-		return fmt.Errorf("could not read simple file '%s': %v", simpleFilePath, err)
+		return "", fmt.Errorf("could not read simple file '%s': %v", simpleFilePath, err)
 	}
 
 	l := lexer.NewLexer(string(data))
@@ -174,7 +284,15 @@ func (cg *CodeGenerator) processSimpleImport(packageName string) error {
 
 	// Perform semantic analysis
 	analyzer := semantic.NewAnalyzer()
+	analyzer.OutputDir = filepath.Join(cg.outputDir, packageName)
 	analyzer.Analyze(ast, []parser.Statement{})
+	analyzer.CheckExports(ast)
+	if errs := analyzer.FatalErrors(); len(errs) > 0 {
+		return "", fmt.Errorf("%s: %s", packageName, strings.Join(errs, "; "))
+	}
+	for _, warning := range analyzer.Warnings() {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", packageName, warning)
+	}
 
 	// Initialize Transformer
 	transformer := transformer.NewTransformer(analyzer)
@@ -185,7 +303,7 @@ func (cg *CodeGenerator) processSimpleImport(packageName string) error {
 	// Create a directory for the package
 	packageDir := filepath.Join(cg.outputDir, packageName)
 	if err := os.MkdirAll(packageDir, os.ModePerm); err != nil {
-		return fmt.Errorf("could not create package directory '%s': %v", packageDir, err)
+		return "", fmt.Errorf("could not create package directory '%s': %v", packageDir, err)
 	}
 
 	// Initialize a new CodeGenerator for the package
@@ -196,16 +314,9 @@ func (cg *CodeGenerator) processSimpleImport(packageName string) error {
 
 	// Add the package to imports (use relative path or module path as needed)
 	// Here, we assume the package can be imported using its directory name
-	cg.imports[fmt.Sprintf("%s/%s", filepath.Base(cg.outputDir), packageName)] = true
+	cg.imports[importPath] = true
 
-	return nil
-}
-
-func capitalize(name string) string {
-	if name == "" {
-		return ""
-	}
-	return strings.ToUpper(name[:1]) + name[1:]
+	return importPath, nil
 }
 
 // Helper function to check if a built-in function is used
@@ -223,16 +334,16 @@ func (cg *CodeGenerator) isBuiltinUsed(name string, program *parser.Program) boo
 		return true
 	})
 	if !found {
-		fmt.Printf("Builtin function '%s' NOT found in AST\n", name)
+		verbose.Logf(2, "builtin function '%s' not found in AST", name)
 	}
 	return found
 }
 
 // generateFunction generates Go code for a function definition.
 func (cg *CodeGenerator) generateFunction(file *os.File, fn *parser.FunctionLiteral, prevSymbolTable *semantic.SymbolTable, exported bool) {
-	funcName := fn.Name.Value
+	funcName := cg.goName(fn.Name.Value)
 	if exported {
-		funcName = capitalize(funcName)
+		funcName = semantic.GoName(fn.Name.Value)
 	}
 
 	// Get the function symbol from the symbol table
@@ -243,6 +354,10 @@ func (cg *CodeGenerator) generateFunction(file *os.File, fn *parser.FunctionLite
 	}
 
 	functionType, ok := symbol.Type.(*parser.FunctionType)
+	decoration, decorated := cg.analyzer.Decorations[fn]
+	if decorated {
+		functionType, ok = decoration.Function, true
+	}
 	if !ok {
 		fmt.Fprintf(os.Stderr, "Symbol '%s' is not a function\n", fn.Name.Value)
 		return
@@ -259,14 +374,16 @@ func (cg *CodeGenerator) generateFunction(file *os.File, fn *parser.FunctionLite
 				elemType := strings.Split(pt.ElementType.String(), "/")
 				paramType = "*" + elemType[len(elemType)-1]
 			case *parser.BasicType:
-				paramType = pt.String()
+				paramType = goTypeName(pt.String())
 			case *parser.ArrayType:
-				paramType = pt.String()
+				paramType = goTypeName(pt.String())
 			case *parser.MapType:
-				paramType = pt.String()
+				paramType = goTypeName(pt.String())
+			case *parser.FunctionType:
+				paramType = cg.typeToGoString(pt)
 			}
 		}
-		params = append(params, fmt.Sprintf("%s %s", p.Value, paramType))
+		params = append(params, fmt.Sprintf("%s %s", cg.goName(p.Value), paramType))
 		paramSymbol, _ := cg.analyzer.SymbolTables.Tables[fn.Name.Value].Resolve(p.Value)
 		paramSymbol.Metadata = map[string]any{"set": true}
 		paramSymbol.Name = p.Value
@@ -275,102 +392,127 @@ func (cg *CodeGenerator) generateFunction(file *os.File, fn *parser.FunctionLite
 	}
 
 	// Determine return type
-	returnType := ""
-	cg.Returns["currentFunc"] = map[string]bool{"expects": false, "done": false}
-
-	if len(functionType.ReturnTypes) > 0 {
-		// Build the return type string
-		returnTypeNames := []string{}
-		for _, rt := range functionType.ReturnTypes {
-			returnTypeNames = append(returnTypeNames, rt.String())
-		}
-		if len(returnTypeNames) == 1 {
-			if returnTypeNames[0] == "void" {
-				returnType = ""
-			} else {
-				returnType = returnTypeNames[0]
-			}
-		} else {
-			returnType = fmt.Sprintf("(%s)", strings.Join(returnTypeNames, ", "))
-		}
-		cg.Returns["currentFunc"]["expects"] = true
-	}
+	returnType := resultString(functionType)
+	cg.Returns["currentFunc"] = map[string]bool{"expects": len(functionType.ReturnTypes) > 0, "done": false}
+
+	prevFunction, prevResult, prevTries, prevMatches := cg.function, cg.result, cg.tries, cg.matches
+	cg.function, cg.result, cg.tries, cg.matches = functionType, cg.analyzer.ResultAnnotation(fn), nil, nil
 
+	literal := fmt.Sprintf("func(%s)", strings.Join(params, ", "))
+	if returnType != "" {
+		literal += " " + returnType
+	}
+	closing := "}\n"
 	cg.writeIndent(file)
 	functionSymbol, ok := prevSymbolTable.Resolve(cg.analyzer.CurrentTable.Name)
+	nested := false
 	if ok {
-		switch functionSymbol.Type.(type) {
-		case *parser.FunctionType:
-			if returnType != "" {
-				if functionSymbol.Metadata == nil {
-					fmt.Fprintf(file, "%s := func(%s) %s {\n", funcName, strings.Join(params, ", "), returnType)
-					functionSymbol.Metadata = map[string]any{"set": true}
-				} else {
-					fmt.Fprintf(file, "%s = func(%s) %s {\n", funcName, strings.Join(params, ", "), returnType)
-					functionSymbol.Metadata["set"] = true
-				}
-			} else {
-				if functionSymbol.Metadata == nil {
-					fmt.Fprintf(file, "%s := func(%s) {\n", funcName, strings.Join(params, ", "))
-					functionSymbol.Metadata = map[string]any{"set": true}
-				} else {
-					fmt.Fprintf(file, "%s = func(%s) {\n", funcName, strings.Join(params, ", "))
-					functionSymbol.Metadata["set"] = true
-				}
-			}
-		default:
-			if returnType != "" {
-				fmt.Fprintf(file, "func %s(%s) %s {\n", funcName, strings.Join(params, ", "), returnType)
-			} else {
-				fmt.Fprintf(file, "func %s(%s) {\n", funcName, strings.Join(params, ", "))
-			}
-		}
-	} else {
-		if returnType != "" {
-			fmt.Fprintf(file, "func %s(%s) %s {\n", funcName, strings.Join(params, ", "), returnType)
+		_, nested = functionSymbol.Type.(*parser.FunctionType)
+	}
+	if nested {
+		if functionSymbol.Metadata == nil {
+			fmt.Fprintf(file, "%s := ", funcName)
+			functionSymbol.Metadata = map[string]any{"set": true}
 		} else {
-			fmt.Fprintf(file, "func %s(%s) {\n", funcName, strings.Join(params, ", "))
+			fmt.Fprintf(file, "%s = ", funcName)
+			functionSymbol.Metadata["set"] = true
+		}
+		if decorated {
+			closing = "}" + cg.writeDecorators(file, fn) + "\n"
 		}
+		fmt.Fprintf(file, "%s {\n", literal)
+	} else if decorated {
+		// The decorators are applied when the program starts, to a function
+		// that can call itself through its decorated name
+		fmt.Fprintf(file, "var %s %s\n\nfunc init() {\n", funcName, cg.typeToGoString(decoration.Type))
+		cg.indentLevel++
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%s = ", funcName)
+		closing = "}" + cg.writeDecorators(file, fn) + "\n}\n"
+		fmt.Fprintf(file, "%s {\n", literal)
+	} else {
+		fmt.Fprintf(file, "func %s%s {\n", funcName, strings.TrimPrefix(literal, "func"))
 	}
 
 	cg.indentLevel++
+	if cg.HotReload && cg.isMain && prevSymbolTable == cg.analyzer.GlobalTable && !decorated {
+		cg.writeHotPrologue(file, fn, params, returnType)
+	}
+	if cg.Trace {
+		cg.writeTracePrologue(file, fn.Name.Value, fn.Parameters)
+	}
 	prevTable := cg.analyzer.CurrentTable
 	cg.analyzer.CurrentTable = cg.analyzer.SymbolTables.Tables[fn.Name.Value]
-	cg.generateBlockStatement(file, fn.Body, prevTable)
+	if _, ok := cg.analyzer.Generators[fn]; ok {
+		cg.generateGeneratorBody(file, fn, functionType.ReturnTypes[0].(*parser.FunctionType), prevTable)
+	} else {
+		cg.generateBlockStatement(file, fn.Body, prevTable)
+	}
 	cg.indentLevel--
 	cg.writeIndent(file)
-	if returnType != "" {
-		if cg.Returns["currentFunc"]["expects"] && cg.Returns["currentFunc"]["done"] {
-			fmt.Fprintf(file, "}\n")
-		} else if cg.Returns["currentFunc"]["expects"] && !cg.Returns["currentFunc"]["done"] {
-			// Generate default return values
-			defaultReturnValues := []string{}
-			for _, rt := range functionType.ReturnTypes {
-				switch rt.String() {
-				case "int":
-					defaultReturnValues = append(defaultReturnValues, "0")
-				case "string":
-					defaultReturnValues = append(defaultReturnValues, "\"\"")
-				case "bool":
-					defaultReturnValues = append(defaultReturnValues, "false")
-				default:
-					defaultReturnValues = append(defaultReturnValues, "nil")
-				}
-			}
-			fmt.Fprintf(file, "return %s\n", strings.Join(defaultReturnValues, ", "))
-			fmt.Fprintf(file, "}\n")
-		} else {
-			fmt.Fprintf(file, "}\n")
-		}
-	} else {
-		fmt.Fprintln(file, "}\n")
+	if returnType != "" && cg.Returns["currentFunc"]["expects"] && !cg.Returns["currentFunc"]["done"] {
+		// Generate default return values
+		fmt.Fprintf(file, "return %s\n", strings.Join(defaultReturnValues(functionType), ", "))
+	}
+	if decorated && !nested {
+		cg.indentLevel--
+	}
+	fmt.Fprint(file, closing)
+	if returnType == "" {
+		fmt.Fprintln(file)
 	}
 	fmt.Fprintln(file) // Add an empty line for readability
 	cg.analyzer.CurrentTable = prevTable
+	cg.function, cg.result, cg.tries, cg.matches = prevFunction, prevResult, prevTries, prevMatches
 	cg.Returns["currentFunc"]["expects"] = false
 	cg.Returns["currentFunc"]["done"] = false
 }
 
+// writeDecorators writes the calls of the decorators of a function up to
+// the function they are given, and returns the parentheses that close them.
+func (cg *CodeGenerator) writeDecorators(file *os.File, fn *parser.FunctionLiteral) string {
+	for _, decorator := range fn.Decorators {
+		cg.generateExpression(file, decorator)
+		fmt.Fprint(file, "(")
+	}
+	return strings.Repeat(")", len(fn.Decorators))
+}
+
+// resultString returns the Go results of a function: nothing for a void
+// function, a type, or several types in parentheses.
+func resultString(ft *parser.FunctionType) string {
+	names := []string{}
+	for _, rt := range ft.ReturnTypes {
+		names = append(names, goTypeName(rt.String()))
+	}
+	switch {
+	case len(names) == 0 || (len(names) == 1 && names[0] == "void"):
+		return ""
+	case len(names) == 1:
+		return names[0]
+	}
+	return fmt.Sprintf("(%s)", strings.Join(names, ", "))
+}
+
+// defaultReturnValues returns the values a function returns when its body
+// ends without a return statement.
+func defaultReturnValues(ft *parser.FunctionType) []string {
+	values := []string{}
+	for _, rt := range ft.ReturnTypes {
+		switch rt.String() {
+		case "int":
+			values = append(values, "0")
+		case "string":
+			values = append(values, "\"\"")
+		case "bool":
+			values = append(values, "false")
+		default:
+			values = append(values, "nil")
+		}
+	}
+	return values
+}
+
 func (cg *CodeGenerator) generateAssignmentStatement(file *os.File, as *parser.AssignmentStatement) {
 	cg.writeIndent(file)
 
@@ -379,10 +521,14 @@ func (cg *CodeGenerator) generateAssignmentStatement(file *os.File, as *parser.A
 	useShortDeclaration := false
 
 	for _, expr := range as.Left {
-		exprStr := expr.String()
+		exprStr := cg.goExpression(expr).String()
+		if ie, ok := expr.(*parser.IndexExpression); ok {
+			exprStr = cg.indexExpressionString(ie)
+		}
 
 		// Check if the left-hand side is a simple identifier
-		if ident, ok := expr.(*parser.Identifier); ok {
+		// The blank identifier never declares anything
+		if ident, ok := expr.(*parser.Identifier); ok && ident.Value != "_" {
 			symbol, found := cg.analyzer.CurrentTable.Resolve(ident.Value)
 			if found && symbol.Metadata == nil {
 				useShortDeclaration = true
@@ -400,8 +546,8 @@ func (cg *CodeGenerator) generateAssignmentStatement(file *os.File, as *parser.A
 
 	// Generate the assignment statement
 	for ex := range lhsExpressions {
-		if _, found := cg.analyzer.CurrentTable.Resolve(lhsExpressions[ex]); found {
-			if len(cg.analyzer.Assignments[lhsExpressions[ex]]["types"]) > 1 {
+		if _, found := cg.analyzer.CurrentTable.Resolve(as.Left[ex].String()); found {
+			if len(cg.analyzer.Assignments[as.Left[ex].String()]["types"]) > 1 {
 				switch as.Value.(type) {
 				case *parser.CallExpression:
 					continue
@@ -417,7 +563,11 @@ func (cg *CodeGenerator) generateAssignmentStatement(file *os.File, as *parser.A
 		}
 	}
 	fmt.Fprintf(file, "%s %s ", strings.Join(lhsExpressions, ", "), assignmentOperator)
-	cg.generateExpression(file, as.Value)
+	if _, ok := cg.tupleType(as.Value); ok && len(as.Left) > 1 {
+		cg.generateUnpackedTuple(file, as.Value)
+	} else {
+		cg.generateExpression(file, as.Value)
+	}
 	fmt.Fprintln(file)
 
 	// Update the symbol table
@@ -444,24 +594,68 @@ func (cg *CodeGenerator) generateAssignmentStatement(file *os.File, as *parser.A
 	}
 }
 
+// generateAugmentedAssignment generates Go code for an augmented assignment
+// such as x += 1. Values added to a string are converted to strings and int
+// values added to a float are converted to floats, as in binary operations.
+// Untyped variables are assigned the binary operation itself.
+func (cg *CodeGenerator) generateAugmentedAssignment(file *os.File, as *parser.AssignmentStatement) {
+	cg.writeIndent(file)
+	target := as.Left[0]
+	targetName := cg.goExpression(target).String()
+	if ie, ok := target.(*parser.IndexExpression); ok {
+		targetName = cg.indexExpressionString(ie)
+	}
+	targetType := cg.getExpressionType(target).String()
+	valueType := cg.analyzer.InferExpressionTypes(as.Value, false)[0].String()
+
+	switch {
+	case targetType == "interface{}", targetType == "string" && as.Operator == "*", as.Operator == "**", as.Operator == "//":
+		fmt.Fprintf(file, "%s = ", targetName)
+		cg.generateInfixExpression(file, &parser.InfixExpression{Token: as.Token, Left: target, Operator: as.Operator, Right: as.Value})
+	case targetType == "string" && valueType != "string":
+		fmt.Fprintf(file, "%s %s= ", targetName, as.Operator)
+		cg.generateStringExpression(file, as.Value)
+	case targetType == "float64" && valueType == "int":
+		fmt.Fprintf(file, "%s %s= float64(", targetName, as.Operator)
+		cg.generateExpression(file, as.Value)
+		fmt.Fprint(file, ")")
+	default:
+		fmt.Fprintf(file, "%s %s= ", targetName, as.Operator)
+		cg.generateExpression(file, as.Value)
+	}
+	fmt.Fprintln(file)
+}
+
 // generateStatement generates Go code for a statement.
 func (cg *CodeGenerator) generateStatement(file *os.File, stmt parser.Statement, prevSymbolTable *semantic.SymbolTable) {
+	cg.declareAssignmentExpressions(file, stmt)
 	switch s := stmt.(type) {
 	case *parser.ExpressionStatement:
-		if s != nil {
+		if s != nil && !cg.isEmptyInitCall(s.Expression) {
 			cg.writeIndent(file)
 			cg.generateExpression(file, s.Expression)
 			fmt.Fprintln(file)
 
 		}
 	case *parser.AssignmentStatement:
-		cg.generateAssignmentStatement(file, s)
+		if s.Operator != "" {
+			cg.generateAugmentedAssignment(file, s)
+		} else {
+			cg.generateAssignmentStatement(file, s)
+		}
 	case *parser.ReturnStatement:
-
+		if cg.innermostTry() != nil {
+			cg.generateTryReturn(file, s)
+			break
+		}
 		cg.writeIndent(file)
 		fmt.Fprint(file, "return ")
-		if s.ReturnValue != nil {
-			cg.generateExpression(file, s.ReturnValue)
+		if tl, ok := s.ReturnValue.(*parser.TupleLiteral); ok {
+			// return a, b returns a Go result for each value
+			cg.generateExpressionList(file, tl.Elements)
+			cg.Returns["currentFunc"]["done"] = true
+		} else if s.ReturnValue != nil {
+			cg.generateReturnValue(file, s.ReturnValue)
 			cg.Returns["currentFunc"]["done"] = true
 		}
 		fmt.Fprintln(file)
@@ -471,16 +665,42 @@ func (cg *CodeGenerator) generateStatement(file *os.File, stmt parser.Statement,
 		cg.generateWhileStatement(file, s, prevSymbolTable)
 	case *parser.ForStatement:
 		cg.generateForStatement(file, s, prevSymbolTable)
+	case *parser.TryStatement:
+		cg.generateTryStatement(file, s, prevSymbolTable)
+	case *parser.WithStatement:
+		cg.generateWithStatement(file, s, prevSymbolTable)
+	case *parser.MatchStatement:
+		cg.generateMatchStatement(file, s, prevSymbolTable)
+	case *parser.RaiseStatement:
+		cg.generateRaiseStatement(file, s)
+	case *parser.AssertStatement:
+		cg.generateAssertStatement(file, s)
+	case *parser.YieldStatement:
+		cg.generateYieldStatement(file, s)
 	case *parser.FunctionLiteral:
-		if cg.isMain {
-			cg.generateFunction(file, s, prevSymbolTable, false)
-		} else {
-			cg.generateFunction(file, s, prevSymbolTable, true)
-		}
+		// Functions defined inside others are local to them, in modules too
+		cg.generateFunction(file, s, prevSymbolTable, false)
+	case *parser.BreakStatement:
+		cg.generateExit(file, tryBreak, nil)
+	case *parser.ContinueStatement:
+		cg.generateExit(file, tryContinue, nil)
 	case *parser.DeferStatement:
+		cg.writeIndent(file)
+		fmt.Fprint(file, "defer ")
 		cg.generateExpression(file, s.Expression)
+		fmt.Fprintln(file)
+	case *parser.GlobalStatement:
+		// Declares how the function's assignments are generated, and is
+		// nothing itself
+	case *parser.PassStatement:
+		// Go blocks can be empty
+	case *parser.DelStatement:
+		cg.generateDelStatement(file, s)
 	case *parser.GoStatement:
+		cg.writeIndent(file)
+		fmt.Fprint(file, "go ")
 		cg.generateExpression(file, s.Expression)
+		fmt.Fprintln(file)
 	default:
 		// Handle other statements as needed
 	}
@@ -500,7 +720,12 @@ func (cg *CodeGenerator) generateExpression(file *os.File, expr parser.Expressio
 		//} else {
 		//	fmt.Fprintf(file, "%s", e.Value)
 		//}
-		fmt.Fprint(file, e.Value)
+		// A module's functions call each other by their exported names
+		if symbol, ok := cg.analyzer.CurrentTable.Resolve(e.Value); ok && cg.exported[e.Value] && symbol == cg.analyzer.GlobalTable.Symbols[e.Value] {
+			fmt.Fprint(file, semantic.GoName(e.Value))
+		} else {
+			fmt.Fprint(file, cg.identifierName(e.Value))
+		}
 	case *parser.IntegerLiteral:
 		fmt.Fprint(file, e.TokenLiteral())
 	case *parser.StringLiteral:
@@ -513,20 +738,30 @@ func (cg *CodeGenerator) generateExpression(file *os.File, expr parser.Expressio
 			fmt.Fprintf(file, "%q", e.Value)
 		}
 
+	case *parser.FStringLiteral:
+		cg.generateFStringLiteral(file, e)
+	case *parser.LambdaExpression:
+		cg.generateLambdaExpression(file, e)
+	case *parser.ConditionalExpression:
+		cg.generateConditionalExpression(file, e)
+	case *parser.AssignmentExpression:
+		cg.generateAssignmentExpression(file, e)
+	case *parser.ListComprehension:
+		cg.generateListComprehension(file, e)
+	case *parser.DictComprehension:
+		cg.generateDictComprehension(file, e)
+	case *parser.TupleLiteral:
+		cg.generateTupleLiteral(file, e)
 	case *parser.BooleanLiteral:
 		if e.Value {
 			fmt.Fprint(file, "true")
 		} else {
 			fmt.Fprint(file, "false")
 		}
+	case *parser.NoneLiteral:
+		fmt.Fprint(file, "nil")
 	case *parser.CallExpression:
 		cg.generateCallExpression(file, e)
-	case *parser.DeferLiteral:
-		cg.writeIndent(file)
-		fmt.Fprintf(file, "%s", e.Value)
-	case *parser.GoLiteral:
-		cg.writeIndent(file)
-		fmt.Fprintf(file, "%s", e.Value)
 	case *parser.InfixExpression:
 		cg.generateInfixExpression(file, e)
 	case *parser.PrefixExpression:
@@ -539,23 +774,25 @@ func (cg *CodeGenerator) generateExpression(file *os.File, expr parser.Expressio
 		cg.generateArrayLiteral(file, e)
 	case *parser.MapLiteral:
 		cg.generateMapLiteral(file, e)
+	case *parser.SetLiteral:
+		cg.generateSetLiteral(file, e)
 	case *parser.IndexExpression:
-		fmt.Fprint(file, e.String())
+		cg.generateIndexExpression(file, e)
 	default:
 
 	}
 }
 
-// isImportedPackage checks if a given identifier is an imported package.
-func (cg *CodeGenerator) isImportedPackage(ident string) bool {
-	_, exists := cg.imports[ident]
-	return exists
+// isSimpleModule checks if a given identifier names an imported Simple module,
+// whose functions are called by their Go names.
+func (cg *CodeGenerator) isSimpleModule(ident string) bool {
+	return cg.simpleModules[ident]
 }
 
 func (cg *CodeGenerator) generateArrayLiteral(file *os.File, arr *parser.ArrayLiteral) {
-	fmt.Fprintf(file, "[]%s{", arr.Type.String())
+	fmt.Fprintf(file, "[]%s{", goTypeName(arr.Type.String()))
 	for _, el := range arr.Elements {
-		fmt.Fprint(file, el)
+		cg.generateExpression(file, el)
 		fmt.Fprint(file, ", ")
 	}
 	fmt.Fprint(file, "}")
@@ -568,17 +805,19 @@ func (cg *CodeGenerator) generateMapLiteral(file *os.File, m *parser.MapLiteral)
 
 	if m.Type != nil {
 		if mt, ok := m.Type.(*parser.MapType); ok {
-			keyType = mt.KeyType.String()
-			valueType = mt.ValueType.String()
+			keyType = goTypeName(mt.KeyType.String())
+			valueType = goTypeName(mt.ValueType.String())
 		}
 	}
 
 	// Write the map type
 	fmt.Fprintf(file, "map[%s]%s{", keyType, valueType)
 
-	// Iterate over key-value pairs
+	// Iterate over key-value pairs in the order they are written, so that
+	// the same program always generates the same code
 	first := true
-	for key, value := range m.Pairs {
+	for _, key := range m.Keys {
+		value := m.Pairs[key]
 		if !first {
 			fmt.Fprint(file, ", ")
 		}
@@ -595,21 +834,188 @@ func (cg *CodeGenerator) generateMapLiteral(file *os.File, m *parser.MapLiteral)
 	fmt.Fprint(file, "}")
 }
 
-func (cg *CodeGenerator) generateTypeConversionExpression(file *os.File, expr *parser.TypeConversionExpression) string {
-	//exprCode := cg.generateExpression(file, expr.Expression)
-	targetTypeCode := cg.typeToGoString(expr.TargetType)
+// generateIndexExpression writes an index expression read for its value.
+// Go can't index a value of no known type, such as a dict in a dict of any
+// values, so simpleIndex looks inside it at run time.
+func (cg *CodeGenerator) generateIndexExpression(file *os.File, ie *parser.IndexExpression) {
+	if !cg.analyzer.UntypedIndexes[ie] {
+		fmt.Fprint(file, cg.indexExpressionString(ie))
+		return
+	}
+	if name := cg.analyzer.InferExpressionTypes(ie.Left, false)[0].String(); name != "interface{}" && name != "any" {
+		fmt.Fprint(file, cg.indexExpressionString(ie))
+		return
+	}
+	cg.useHelper("simpleIndex")
+	fmt.Fprint(file, "simpleIndex(")
+	cg.generateExpression(file, ie.Left)
+	fmt.Fprint(file, ", ")
+	cg.generateExpression(file, ie.Index)
+	fmt.Fprintf(file, ", %d, %q)", ie.Token.Line, cg.tracebackFunction())
+}
+
+// indexExpressionString returns an index expression as Go code. A key of
+// no known type, such as a function parameter, is asserted to the key type
+// of a dict with typed keys, as Go won't index one with an interface{}, and
+// a key compared by __eq__ is looked up with simpleKey. The values of a
+// tuple are the fields of its struct.
+func (cg *CodeGenerator) indexExpressionString(ie *parser.IndexExpression) string {
+	left := cg.goExpression(ie.Left).String()
+	if l, ok := ie.Left.(*parser.IndexExpression); ok {
+		left = cg.indexExpressionString(l)
+	}
+	if i, ok := cg.analyzer.TupleIndexes[ie]; ok {
+		return fmt.Sprintf("%s.Item%d", left, i)
+	}
+	if tl, ok := ie.Index.(*parser.TupleLiteral); ok {
+		return fmt.Sprintf("%s[%s]", left, cg.tupleLiteralString(tl))
+	}
+	keyType := mapKeyType(cg.getExpressionType(ie.Left))
+	if keyType == "" || ie.End != nil {
+		rendered := cg.goExpression(ie).(*parser.IndexExpression)
+		rendered.Left = &parser.Identifier{Value: left}
+		return rendered.String()
+	}
+	return fmt.Sprintf("%s[%s]", left, cg.mapKeyString(ie, left, keyType))
+}
+
+// mapKeyString returns the key of an index expression of a dict with keys
+// of keyType, whose Go expression is left: the key of the dict equal to it
+// if objects are keys by value, and a key of no known type asserted to be
+// of keyType.
+func (cg *CodeGenerator) mapKeyString(ie *parser.IndexExpression, left, keyType string) string {
+	index := cg.goExpression(ie.Index).String()
+	if cg.analyzer.KeyedByValue(&parser.BasicType{Name: keyType}) || cg.analyzer.KeyedByValue(cg.getExpressionType(ie.Index)) {
+		cg.useHelper("simpleKey")
+		return fmt.Sprintf("simpleKey[%s](%s, %s)", goTypeName(keyType), left, index)
+	}
+	if keyType == "any" || keyType == "interface{}" {
+		return index
+	}
+	if indexType := cg.getExpressionType(ie.Index).String(); indexType != "interface{}" && indexType != "any" {
+		return index
+	}
+	return fmt.Sprintf("%s.(%s)", index, goTypeName(keyType))
+}
+
+// mapKeyType returns the key type of a dict type, or "" for other types.
+func mapKeyType(t parser.Type) string {
+	switch typ := t.(type) {
+	case *parser.MapType:
+		return typ.KeyType.String()
+	case *parser.BasicType:
+		if !strings.HasPrefix(typ.Name, "map[") {
+			return ""
+		}
+		depth := 0
+		for i, ch := range typ.Name[len("map"):] {
+			switch ch {
+			case '[':
+				depth++
+			case ']':
+				depth--
+				if depth == 0 {
+					return typ.Name[len("map[") : len("map")+i]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// floatTypeName matches Simple's float type inside a type name such as
+// map[float]string, but not Go's float64.
+var floatTypeName = regexp.MustCompile(`\bfloat\b`)
 
+// goTypeName turns a Simple type name into the Go one, float into float64.
+func goTypeName(name string) string {
+	return floatTypeName.ReplaceAllString(name, "float64")
+}
+
+func (cg *CodeGenerator) generateTypeConversionExpression(file *os.File, expr *parser.TypeConversionExpression) {
 	// Generate Go code for the type conversion
-	return fmt.Sprintf("%s(%s)", targetTypeCode, expr.Expression.String())
+	fmt.Fprintf(file, "%s(", cg.typeToGoString(expr.TargetType))
+	cg.generateExpression(file, expr.Expression)
+	fmt.Fprint(file, ")")
+}
+
+// generateStructLiteral writes a keyword-argument struct construction such
+// as http.Client(timeout=5) as a composite literal.
+func (cg *CodeGenerator) generateStructLiteral(file *os.File, ce *parser.CallExpression, sl *semantic.StructLiteral) {
+	fmt.Fprintf(file, "%s{", cg.typeToGoString(sl.Type))
+	i := 0
+	for _, arg := range ce.Arguments {
+		ka, ok := arg.(*parser.KeywordArgument)
+		if !ok || i >= len(sl.Fields) {
+			continue
+		}
+		if i > 0 {
+			fmt.Fprint(file, ", ")
+		}
+		fmt.Fprintf(file, "%s: ", sl.Fields[i])
+		cg.generateExpression(file, ka.Value)
+		i++
+	}
+	fmt.Fprint(file, "}")
+}
+
+// structLiteralImports registers the packages named by type conversions
+// that the transformer inserted into struct constructions.
+func (cg *CodeGenerator) structLiteralImports() {
+	for ce, sl := range cg.analyzer.StructLiterals {
+		i := 0
+		for _, arg := range ce.Arguments {
+			ka, ok := arg.(*parser.KeywordArgument)
+			if !ok || i >= len(sl.Fields) {
+				continue
+			}
+			if _, converted := ka.Value.(*parser.TypeConversionExpression); converted {
+				fieldType := sl.FieldGoTypes[i]
+				if ptr, ok := fieldType.(*types.Pointer); ok {
+					fieldType = ptr.Elem()
+				}
+				if named, ok := fieldType.(*types.Named); ok && named.Obj().Pkg() != nil {
+					if _, aliased := cg.importAliases[named.Obj().Pkg().Path()]; !aliased {
+						cg.imports[named.Obj().Pkg().Path()] = true
+					}
+				}
+			}
+			i++
+		}
+	}
 }
 
 // Helper method to convert parser.Type to Go type string
 func (cg *CodeGenerator) typeToGoString(t parser.Type) string {
 	switch typ := t.(type) {
 	case *parser.BasicType:
-		return typ.Name
+		return goTypeName(typ.Name)
 	case *parser.PointerType:
 		return "*" + cg.typeToGoString(typ.ElementType)
+	case *parser.ArrayType:
+		return "[]" + cg.typeToGoString(typ.ElementType)
+	case *parser.MapType:
+		return fmt.Sprintf("map[%s]%s", cg.typeToGoString(typ.KeyType), cg.typeToGoString(typ.ValueType))
+	case *parser.SetType:
+		return fmt.Sprintf("map[%s]struct{}", cg.typeToGoString(typ.ElementType))
+	case *parser.TupleType:
+		fields := []string{}
+		for i, et := range typ.ElementTypes {
+			fields = append(fields, fmt.Sprintf("Item%d %s", i, cg.typeToGoString(et)))
+		}
+		if len(fields) == 0 {
+			return "struct{}"
+		}
+		return "struct{ " + strings.Join(fields, "; ") + " }"
+	case *parser.FunctionType:
+		params := []string{}
+		for _, pt := range typ.ParameterTypes {
+			params = append(params, cg.typeToGoString(pt))
+		}
+		if results := resultString(typ); results != "" {
+			return fmt.Sprintf("func(%s) %s", strings.Join(params, ", "), results)
+		}
+		return fmt.Sprintf("func(%s)", strings.Join(params, ", "))
 	case *parser.NamedType:
 		if typ.Package != "" {
 			return fmt.Sprintf("%s.%s", typ.Package, typ.Name)
@@ -622,6 +1028,10 @@ func (cg *CodeGenerator) typeToGoString(t parser.Type) string {
 }
 
 func (cg *CodeGenerator) generateSelectorExpression(file *os.File, se *parser.SelectorExpression) {
+	if ce, ok := cg.analyzer.TypeCall(se.Left); ok && se.Selector.Value == "__name__" {
+		cg.generateTypeCall(file, ce, true)
+		return
+	}
 	// Generate code for the left expression
 	cg.generateExpression(file, se.Left)
 
@@ -629,11 +1039,61 @@ func (cg *CodeGenerator) generateSelectorExpression(file *os.File, se *parser.Se
 	fmt.Fprint(file, ".")
 
 	// Generate the selector (method or field name)
-	cg.generateExpression(file, se.Selector)
+	fmt.Fprint(file, cg.memberName(se.Selector.Value))
 }
 
 // generateInfixExpression generates Go code for an infix expression.
 func (cg *CodeGenerator) generateInfixExpression(file *os.File, ie *parser.InfixExpression) {
+	if fs, ok := cg.analyzer.StringFormats[ie]; ok {
+		cg.generateFStringLiteral(file, fs)
+		return
+	}
+	if tc, ok := cg.analyzer.TypeChecks[ie]; ok {
+		cg.generateTypeCheck(file, tc)
+		return
+	}
+	if ie.Operator == "and" || ie.Operator == "or" {
+		cg.generateLogicalExpression(file, ie)
+		return
+	}
+	switch ie.Operator {
+	case "**":
+		cg.generatePower(file, ie)
+		return
+	case "//":
+		cg.generateFloorDivision(file, ie)
+		return
+	case "in", "not in":
+		cg.generateMembership(file, ie)
+		return
+	case "is", "is not":
+		cg.generateIdentity(file, ie)
+		return
+	case "|", "&", "^", "-":
+		if cg.analyzer.IsSetOperation(ie) {
+			cg.generateSetOperation(file, ie)
+			return
+		}
+		if ie.Operator != "-" {
+			cg.generateBitwiseOperation(file, ie)
+			return
+		}
+	}
+	if cg.isStructuralComparison(ie) {
+		cg.generateStructuralComparison(file, ie)
+		return
+	}
+	if str, count, ok := cg.analyzer.StringRepeat(ie); ok {
+		cg.generateStringRepeat(file, str, count)
+		return
+	}
+	if cg.analyzer.IsStringComparison(ie) {
+		cg.generateExpression(file, ie.Left)
+		fmt.Fprintf(file, " %s ", ie.Operator)
+		cg.generateExpression(file, ie.Right)
+		return
+	}
+
 	switch ie.Operator {
 	case "+", "-", "*", "/", "%", "<", "<=", ">", ">=", "==":
 		leftType := cg.getExpressionType(ie.Left)
@@ -712,7 +1172,7 @@ func (cg *CodeGenerator) getNumericCastType(leftType, rightType types.Type) stri
 
 func (cg *CodeGenerator) generateNumericExpression(file *os.File, expr parser.Expression, castType string) {
 	exprType := cg.getExpressionType(expr)
-	if exprType.String() != castType {
+	if goTypeName(exprType.String()) != castType {
 		cg.generateExpression(file, expr)
 		switch expr.(type) {
 		case *parser.Identifier:
@@ -724,7 +1184,14 @@ func (cg *CodeGenerator) generateNumericExpression(file *os.File, expr parser.Ex
 }
 
 func (cg *CodeGenerator) generateStringExpression(file *os.File, expr parser.Expression) {
-	fmt.Fprint(file, "fmt.Sprintf(\"%v\", ")
+	if cg.isErrorExpression(expr) {
+		cg.generateErrorString(file, expr)
+		return
+	}
+	if cg.generateFastString(file, expr) {
+		return
+	}
+	fmt.Fprintf(file, "fmt.Sprintf(%q, ", "%v")
 	cg.generateExpression(file, expr)
 	fmt.Fprint(file, ")")
 }
@@ -740,11 +1207,32 @@ func (cg *CodeGenerator) getExpressionType(expr parser.Expression) parser.Type {
 		return symbol.Type
 	case *parser.IntegerLiteral:
 		return &parser.BasicType{Name: "int"}
-	case *parser.StringLiteral:
+	case *parser.StringLiteral, *parser.FStringLiteral:
 		return &parser.BasicType{Name: "string"}
 	case *parser.BooleanLiteral:
 		return &parser.BasicType{Name: "bool"}
+	case *parser.AssignmentExpression:
+		return cg.getExpressionType(e.Value)
+	case *parser.ArrayLiteral, *parser.MapLiteral, *parser.SetLiteral, *parser.TupleLiteral:
+		return cg.analyzer.InferExpressionTypes(e, false)[0]
 	case *parser.CallExpression:
+		if tt, ok := cg.analyzer.ResultTuples[e]; ok {
+			return tt
+		}
+		if _, ok := cg.analyzer.SetCalls[e]; ok {
+			return cg.analyzer.InferExpressionTypes(e, false)[0]
+		}
+		if class, ok := cg.analyzer.ConstructorOf(e); ok {
+			return class.InstanceType()
+		}
+		if parent, ok := cg.analyzer.SuperCalls[e]; ok {
+			return parent.InstanceType()
+		}
+		if se, ok := e.Function.(*parser.SelectorExpression); ok {
+			if _, method, ok := cg.analyzer.MethodOf(se); ok {
+				return method.Type.ReturnTypes[0]
+			}
+		}
 		// Handle call expressions accordingly
 		if ident, ok := e.Function.(*parser.Identifier); ok {
 			symbol, found := cg.analyzer.GlobalTable.Resolve(ident.Value)
@@ -761,10 +1249,25 @@ func (cg *CodeGenerator) getExpressionType(expr parser.Expression) parser.Type {
 		}
 		return &parser.BasicType{Name: "interface{}"}
 	case *parser.IndexExpression:
-		return &parser.BasicType{Name: "int"}
+		return cg.analyzer.InferExpressionTypes(e, false)[0]
 	case *parser.InfixExpression:
+		switch e.Operator {
+		case "and", "or", "in", "not in", "is", "is not", "<", "<=", ">", ">=", "==", "!=":
+			return &parser.BasicType{Name: "bool"}
+		case "**", "//":
+			return cg.analyzer.InferExpressionTypes(e, false)[0]
+		}
+		if _, _, ok := cg.analyzer.StringRepeat(e); ok {
+			return &parser.BasicType{Name: "string"}
+		}
 		return cg.getExpressionType(e.Left)
 	case *parser.SelectorExpression:
+		if fieldType, ok := cg.analyzer.FieldType(e); ok {
+			return fieldType
+		}
+		if _, ok := cg.analyzer.TypeCall(e.Left); ok && e.Selector.Value == "__name__" {
+			return &parser.BasicType{Name: "string"}
+		}
 		// Handle qualified identifiers (e.g., "math.Pi")
 		if ident, ok := e.Left.(*parser.Identifier); ok {
 			fqName := fmt.Sprintf("%s.%s", ident.Value, e.Selector.Value)
@@ -782,6 +1285,10 @@ func (cg *CodeGenerator) getExpressionType(expr parser.Expression) parser.Type {
 
 // generatePrefixExpression generates Go code for a prefix expression.
 func (cg *CodeGenerator) generatePrefixExpression(file *os.File, pe *parser.PrefixExpression) {
+	if pe.Operator == "not" {
+		cg.generateNot(file, pe.Right)
+		return
+	}
 	fmt.Fprintf(file, "%s ", pe.Operator)
 	cg.generateExpression(file, pe.Right)
 	//fmt.Fprint(file, ")")
@@ -793,12 +1300,46 @@ func (cg *CodeGenerator) generateCallExpression(file *os.File, ce *parser.CallEx
 	case *parser.SelectorExpression:
 		switch ce.Function.(*parser.SelectorExpression).Left.(type) {
 		case *parser.Identifier:
-			if cg.isImportedPackage(fmt.Sprintf("%s/%s", filepath.Base(cg.outputDir), ce.Function.(*parser.SelectorExpression).Left.(*parser.Identifier).Value)) {
-				ce.Function.(*parser.SelectorExpression).Selector.Value = capitalize(ce.Function.(*parser.SelectorExpression).Selector.Value)
+			if cg.isSimpleModule(ce.Function.(*parser.SelectorExpression).Left.(*parser.Identifier).Value) {
+				ce.Function.(*parser.SelectorExpression).Selector.Value = semantic.GoName(ce.Function.(*parser.SelectorExpression).Selector.Value)
 			}
 		}
 	}
 
+	if cg.Platform == "heroku" && cg.isMain && cg.isListenAndServe(ce) {
+		cg.generateServe(file, ce)
+		return
+	}
+	if fs, ok := cg.analyzer.StringFormats[ce]; ok {
+		cg.generateFStringLiteral(file, fs)
+		return
+	}
+	if tt, ok := cg.analyzer.ResultTuples[ce]; ok {
+		cg.generateResultTuple(file, ce, tt)
+		return
+	}
+	if class, ok := cg.analyzer.ConstructorOf(ce); ok {
+		cg.generateConstructorCall(file, ce, class.Name)
+		return
+	}
+	if parent, ok := cg.analyzer.SuperCalls[ce]; ok {
+		// super() is the embedded struct of the parent
+		fmt.Fprintf(file, "self.%s", parent.Name)
+		return
+	}
+	if sc, ok := cg.analyzer.SetCalls[ce]; ok {
+		cg.generateSetCall(file, ce, sc)
+		return
+	}
+	if sl, ok := cg.analyzer.StructLiterals[ce]; ok {
+		cg.generateStructLiteral(file, ce, sl)
+		return
+	}
+	if sc, ok := cg.analyzer.StructConversions[ce]; ok {
+		cg.generateStructConversion(file, ce, sc)
+		return
+	}
+
 	// Check if this CallExpression needs any wrappers
 	wrappers, ok := cg.analyzer.WrapFunctionCalls[ce]
 	if ok && len(wrappers) > 0 {
@@ -835,17 +1376,88 @@ func (cg *CodeGenerator) generateCallExpression(file *os.File, ce *parser.CallEx
 		switch ident.Value {
 		case "print":
 			// Handle 'print' as a special case
+			if cg.generateFastPrint(file, ce) {
+				return
+			}
 			fmt.Fprint(file, "fmt.Println(")
 			for i, arg := range ce.Arguments {
-				cg.generateExpression(file, arg)
+				cg.generatePrintArgument(file, arg)
 				if i < len(ce.Arguments)-1 {
 					fmt.Fprint(file, ", ")
 				}
 			}
 			fmt.Fprint(file, ")")
 			return
+		case "copy", "deepcopy":
+			if cg.analyzer.IsBuiltinCall(ce, ident.Value) {
+				cg.generateCopy(file, ident.Value, ce.Arguments[0])
+				return
+			}
+		case "to_dict":
+			if cg.analyzer.IsBuiltinCall(ce, ident.Value) {
+				cg.useHelper("simpleToDict")
+				fmt.Fprint(file, "simpleToDict(")
+				cg.generateExpression(file, ce.Arguments[0])
+				fmt.Fprint(file, ")")
+				return
+			}
+		case "open":
+			if cg.analyzer.IsBuiltinCall(ce, ident.Value) {
+				cg.generateOpen(file, ce)
+				return
+			}
+		case "input":
+			if cg.analyzer.IsBuiltinCall(ce, ident.Value) {
+				cg.generateInput(file, ce)
+				return
+			}
+		case "isinstance":
+			if tc, ok := cg.analyzer.TypeChecks[ce]; ok {
+				cg.generateTypeCheck(file, tc)
+				return
+			}
+		case "type":
+			if cg.analyzer.IsBuiltinCall(ce, ident.Value) {
+				cg.generateTypeCall(file, ce, false)
+				return
+			}
+		case "round":
+			if len(ce.Arguments) > 0 {
+				cg.generateRound(file, ce)
+				return
+			}
+		case "sorted":
+			if sc, ok := cg.analyzer.SortedCalls[ce]; ok {
+				cg.generateSorted(file, sc)
+				return
+			}
+		case "min", "max", "sum":
+			if ac, ok := cg.analyzer.AggregateCalls[ce]; ok {
+				cg.generateAggregate(file, ce, ac)
+				return
+			}
+		case "map", "filter":
+			if lc, ok := cg.analyzer.MapCalls[ce]; ok {
+				cg.generateListComprehension(file, lc)
+				return
+			}
+		case "str", "repr":
+			if len(ce.Arguments) == 1 {
+				cg.generateFormatCall(file, ident.Value, ce.Arguments[0])
+				return
+			}
 		case "len":
 			// Handle 'len' as a special case
+			if len(ce.Arguments) == 1 {
+				if tt, ok := cg.tupleType(ce.Arguments[0]); ok {
+					fmt.Fprint(file, len(tt.ElementTypes))
+					return
+				}
+			}
+			if lc, ok := cg.analyzer.LenCalls[ce]; ok {
+				cg.generateLen(file, ce, lc)
+				return
+			}
 			fmt.Fprint(file, "len(")
 			for i, arg := range ce.Arguments {
 				cg.generateExpression(file, arg)
@@ -906,7 +1518,7 @@ func (cg *CodeGenerator) generateCallExpression(file *os.File, ce *parser.CallEx
 		//} else {
 		if arg == nil {
 			fmt.Fprint(file, "nil")
-		} else {
+		} else if !cg.annotatedArgument(file, ce, i, arg) {
 			cg.generateExpression(file, arg)
 			switch arg.(type) {
 			case *parser.Identifier:
@@ -981,14 +1593,23 @@ func (cg *CodeGenerator) generateBlockStatement(file *os.File, block *parser.Blo
 // generateIfStatement generates Go code for an if statement.
 func (cg *CodeGenerator) generateIfStatement(file *os.File, is *parser.IfStatement, prevSymbolTable *semantic.SymbolTable) {
 	cg.writeIndent(file)
+	cg.generateIf(file, is, prevSymbolTable)
+}
+
+// generateIf writes an if statement from the if keyword on, continuing elif
+// branches as else if.
+func (cg *CodeGenerator) generateIf(file *os.File, is *parser.IfStatement, prevSymbolTable *semantic.SymbolTable) {
 	fmt.Fprint(file, "if ")
-	cg.generateExpression(file, is.Condition)
+	cg.generateCondition(file, is.Condition)
 	fmt.Fprintln(file, " {")
 	cg.indentLevel++
 	cg.generateBlockStatement(file, is.Consequence, prevSymbolTable)
 	cg.indentLevel--
 	cg.writeIndent(file)
-	if is.Alternative != nil {
+	if elif := is.Elif(); elif != nil {
+		fmt.Fprint(file, "} else ")
+		cg.generateIf(file, elif, prevSymbolTable)
+	} else if is.Alternative != nil {
 		fmt.Fprintln(file, "} else {")
 		cg.indentLevel++
 		cg.generateBlockStatement(file, is.Alternative, prevSymbolTable)
@@ -1002,9 +1623,11 @@ func (cg *CodeGenerator) generateIfStatement(file *os.File, is *parser.IfStateme
 
 // generateWhileStatement generates Go code for a while loop.
 func (cg *CodeGenerator) generateWhileStatement(file *os.File, ws *parser.WhileStatement, prevSymbolTable *semantic.SymbolTable) {
+	cg.enterLoop()
+	defer cg.exitLoop()
 	cg.writeIndent(file)
 	fmt.Fprint(file, "for ")
-	cg.generateExpression(file, ws.Condition)
+	cg.generateCondition(file, ws.Condition)
 	switch ws.Condition.(type) {
 	case *parser.InfixExpression:
 		switch ws.Condition.(*parser.InfixExpression).Left.(type) {
@@ -1028,12 +1651,31 @@ func (cg *CodeGenerator) generateWhileStatement(file *os.File, ws *parser.WhileS
 
 // generateForStatement generates Go code for a for loop.
 func (cg *CodeGenerator) generateForStatement(file *os.File, fs *parser.ForStatement, prevSymbolTable *semantic.SymbolTable) {
+	cg.enterLoop()
+	defer cg.exitLoop()
+	if loop, ok := cg.analyzer.IteratorLoops[fs]; ok {
+		cg.generateIteratorLoop(file, fs, loop, prevSymbolTable)
+		return
+	}
+	if en, ok := cg.analyzer.Enumerations[fs]; ok {
+		cg.generateEnumerateLoop(file, fs, en, prevSymbolTable)
+		return
+	}
+	if z, ok := cg.analyzer.Zips[fs]; ok {
+		cg.generateZipLoop(file, fs, z, prevSymbolTable)
+		return
+	}
+	if r, ok := cg.analyzer.Ranges[fs]; ok {
+		cg.generateRangeLoop(file, fs, r, prevSymbolTable)
+		return
+	}
+	variable := cg.goName(fs.Variable.Value)
 	cg.writeIndent(file)
 	switch fs.Iterable.(type) {
 	case *parser.IntegerLiteral:
-		fmt.Fprintf(file, "for %s := range ", fs.Variable.Value)
-	case *parser.ArrayLiteral:
-		fmt.Fprintf(file, "for _, %s := range ", fs.Variable.Value)
+		fmt.Fprintf(file, "for %s := range ", variable)
+	case *parser.ArrayLiteral, *parser.ListComprehension:
+		fmt.Fprintf(file, "for _, %s := range ", variable)
 	case *parser.Identifier:
 		symbol, _ := cg.analyzer.CurrentTable.Resolve(fs.Iterable.(*parser.Identifier).Value)
 		switch st := symbol.Type.(type) {
@@ -1043,24 +1685,37 @@ func (cg *CodeGenerator) generateForStatement(file *os.File, fs *parser.ForState
 				if fs.Variable.Value == "_" {
 					fmt.Fprint(file, "for _ = range ")
 				} else {
-					fmt.Fprintf(file, "for %s := range ", fs.Variable.Value)
+					fmt.Fprintf(file, "for %s := range ", variable)
 				}
 			case "[]any":
-				fmt.Fprintf(file, "for _, %s := range ", fs.Variable.Value)
+				fmt.Fprintf(file, "for _, %s := range ", variable)
 			case "chan any":
-				fmt.Fprintf(file, "for %s := range ", fs.Variable.Value)
+				fmt.Fprintf(file, "for %s := range ", variable)
 			default:
 				if strings.Contains(st.Name, "map") {
-					fmt.Fprintf(file, "for %s, _ := range ", fs.Variable.Value)
+					fmt.Fprintf(file, "for %s, _ := range ", variable)
 				} else {
-					fmt.Fprintf(file, "for _, %s := range ", fs.Variable.Value)
+					fmt.Fprintf(file, "for _, %s := range ", variable)
 				}
 			}
 		}
 		symbol.Metadata = map[string]any{"set": true}
-
+	case *parser.CallExpression:
+		if _, ok := cg.analyzer.SortedCalls[fs.Iterable.(*parser.CallExpression)]; ok {
+			fmt.Fprintf(file, "for _, %s := range ", variable)
+		} else if strings.HasPrefix(cg.analyzer.InferExpressionTypes(fs.Iterable, false)[0].String(), "[]") {
+			// A function returning a list
+			fmt.Fprintf(file, "for _, %s := range ", variable)
+		} else {
+			fmt.Fprintf(file, "for %s, _ := range ", variable)
+		}
 	default:
-		fmt.Fprintf(file, "for %s, _ := range ", fs.Variable.Value)
+		if strings.HasPrefix(cg.analyzer.InferExpressionTypes(fs.Iterable, false)[0].String(), "[]") {
+			// A field, or an item of a list or dict, holding a list
+			fmt.Fprintf(file, "for _, %s := range ", variable)
+		} else {
+			fmt.Fprintf(file, "for %s, _ := range ", variable)
+		}
 	}
 
 	cg.generateExpression(file, fs.Iterable)
@@ -0,0 +1,162 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+	"strings"
+)
+
+// generateListComprehension writes a list comprehension as a function
+// literal, called in place, that appends each element to a new slice.
+func (cg *CodeGenerator) generateListComprehension(file *os.File, lc *parser.ListComprehension) {
+	c := cg.analyzer.Comprehensions[lc.Clause]
+	cg.generateComprehension(file, lc.Clause, c, []parser.Expression{lc.Element}, func() {
+		fmt.Fprint(file, "simpleResult = append(simpleResult, ")
+		cg.generateExpression(file, lc.Element)
+		fmt.Fprintln(file, ")")
+	})
+}
+
+// generateDictComprehension writes a dict comprehension as a function
+// literal, called in place, that sets each key of a new map. Keys compared
+// by __eq__ are looked up with simpleKey.
+func (cg *CodeGenerator) generateDictComprehension(file *os.File, dc *parser.DictComprehension) {
+	c := cg.analyzer.Comprehensions[dc.Clause]
+	cg.generateComprehension(file, dc.Clause, c, []parser.Expression{dc.Key, dc.Value}, func() {
+		fmt.Fprint(file, "simpleResult[")
+		if cg.analyzer.KeyedByValue(cg.analyzer.InferExpressionTypes(dc.Key, false)[0]) {
+			cg.useHelper("simpleKey")
+			fmt.Fprint(file, "simpleKey(simpleResult, ")
+			cg.generateExpression(file, dc.Key)
+			fmt.Fprint(file, ")")
+		} else {
+			cg.generateExpression(file, dc.Key)
+		}
+		fmt.Fprint(file, "] = ")
+		cg.generateExpression(file, dc.Value)
+		fmt.Fprintln(file)
+	})
+}
+
+// generateComprehension writes the function literal of a comprehension,
+// with add writing the statement that adds the results to simpleResult.
+func (cg *CodeGenerator) generateComprehension(file *os.File, fc *parser.ForClause, c *semantic.Comprehension, results []parser.Expression, add func()) {
+	resultType := cg.typeToGoString(c.Type)
+	fmt.Fprintf(file, "func() %s {\n", resultType)
+	cg.indentLevel++
+	cg.writeIndent(file)
+	fmt.Fprintf(file, "simpleResult := %s{}\n", resultType)
+
+	// Variables the comprehension doesn't use are _, as Go requires
+	used := map[string]bool{}
+	for _, e := range append(results, fc.Condition) {
+		if e == nil {
+			continue
+		}
+		parser.Inspect(e, func(n parser.Node) bool {
+			if ident, ok := n.(*parser.Identifier); ok {
+				used[ident.Value] = true
+			}
+			return true
+		})
+	}
+	targets := []string{}
+	if c.Kind == "list" || c.Kind == "string" || c.Kind == "any" {
+		targets = append(targets, "_")
+	}
+	for _, v := range fc.Variables {
+		if used[v.Value] {
+			targets = append(targets, cg.goName(v.Value))
+		} else {
+			targets = append(targets, "_")
+		}
+	}
+	for len(targets) > 0 && targets[len(targets)-1] == "_" {
+		targets = targets[:len(targets)-1]
+	}
+
+	cg.writeIndent(file)
+	switch {
+	case c.Kind == "next":
+		// Iterators defined in Simple are called until they stop
+		fmt.Fprint(file, "simpleIter := ")
+		cg.generateExpression(file, fc.Iterable)
+		fmt.Fprintln(file)
+		cg.writeIndent(file)
+		fmt.Fprint(file, "for")
+	case len(targets) == 0:
+		fmt.Fprint(file, "for range ")
+	default:
+		fmt.Fprintf(file, "for %s := range ", strings.Join(targets, ", "))
+	}
+	switch c.Kind {
+	case "next":
+	case "items":
+		cg.generateExpression(file, fc.Iterable.(*parser.CallExpression).Function.(*parser.SelectorExpression).Left)
+	case "string":
+		cg.imports["strings"] = true
+		fmt.Fprint(file, "strings.Split(")
+		cg.generateExpression(file, fc.Iterable)
+		fmt.Fprint(file, `, "")`)
+	case "any":
+		cg.useHelper("simpleItems")
+		fmt.Fprint(file, "simpleItems(")
+		cg.generateExpression(file, fc.Iterable)
+		fmt.Fprint(file, ")")
+	default:
+		cg.generateExpression(file, fc.Iterable)
+	}
+	fmt.Fprintln(file, " {")
+	cg.indentLevel++
+	if c.Kind == "next" {
+		cg.writeNextValue(file, targets)
+	}
+
+	prevTable := cg.analyzer.CurrentTable
+	cg.analyzer.CurrentTable = c.Scope
+	if fc.Condition != nil {
+		cg.writeIndent(file)
+		fmt.Fprint(file, "if ")
+		cg.generateTruth(file, fc.Condition)
+		fmt.Fprintln(file, " {")
+		cg.indentLevel++
+	}
+	cg.writeIndent(file)
+	add()
+	if fc.Condition != nil {
+		cg.indentLevel--
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "}")
+	}
+	cg.analyzer.CurrentTable = prevTable
+
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "return simpleResult")
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprint(file, "}()")
+}
+
+// writeNextValue writes the call of the __next__ method that gives the
+// variable of a comprehension over an iterator defined in Simple its value,
+// and leaves the loop once the iterator raises StopIteration.
+func (cg *CodeGenerator) writeNextValue(file *os.File, targets []string) {
+	cg.useHelper("simpleNext")
+	variable := "_"
+	if len(targets) > 0 {
+		variable = targets[0]
+	}
+	cg.writeIndent(file)
+	fmt.Fprintf(file, "%s, ok := simpleNext(simpleIter.__next__)\n", variable)
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "if !ok {")
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "\tbreak")
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+}
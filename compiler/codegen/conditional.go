@@ -0,0 +1,60 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+	"strings"
+)
+
+// generateConditionalExpression writes a conditional expression as a Go
+// function literal called in place, which evaluates only the branch chosen.
+func (cg *CodeGenerator) generateConditionalExpression(file *os.File, ce *parser.ConditionalExpression) {
+	resultType := cg.analyzer.ConditionalType(ce)
+	fmt.Fprintf(file, "func() %s { if ", cg.typeToGoString(resultType))
+	cg.generateCondition(file, ce.Condition)
+	fmt.Fprint(file, " { return ")
+	cg.generateBranch(file, ce.Consequence, resultType)
+	fmt.Fprint(file, " }; return ")
+	cg.generateBranch(file, ce.Alternative, resultType)
+	fmt.Fprint(file, " }()")
+}
+
+// generateBranch writes a branch of a conditional expression, converting an
+// int to the float the other branch makes it.
+func (cg *CodeGenerator) generateBranch(file *os.File, branch parser.Expression, resultType parser.Type) {
+	if resultType.String() == "float64" && cg.analyzer.InferExpressionTypes(branch, false)[0].String() == "int" {
+		fmt.Fprint(file, "float64(")
+		cg.generateExpression(file, branch)
+		fmt.Fprint(file, ")")
+		return
+	}
+	cg.generateExpression(file, branch)
+}
+
+// generateTruth writes a condition that need not be a bool, as the if of a
+// comprehension or filter() may be, the way Python tests it: a number is
+// true unless it is zero, and a string, list or dict unless it is empty.
+func (cg *CodeGenerator) generateTruth(file *os.File, cond parser.Expression) {
+	operand := func() {
+		if _, ok := cond.(*parser.InfixExpression); ok {
+			fmt.Fprint(file, "(")
+			defer fmt.Fprint(file, ")")
+		}
+		cg.generateExpression(file, cond)
+	}
+	switch name := goTypeName(cg.analyzer.InferExpressionTypes(cond, false)[0].String()); {
+	case name == "int" || name == "float64":
+		operand()
+		fmt.Fprint(file, " != 0")
+	case name == "string":
+		operand()
+		fmt.Fprint(file, ` != ""`)
+	case strings.HasPrefix(name, "[]") || strings.HasPrefix(name, "map["):
+		fmt.Fprint(file, "len(")
+		cg.generateExpression(file, cond)
+		fmt.Fprint(file, ") > 0")
+	default:
+		cg.generateCondition(file, cond)
+	}
+}
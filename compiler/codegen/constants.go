@@ -0,0 +1,36 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+)
+
+// generateConstants writes the constants of a file as Go constants, ahead
+// of its functions, which can use them.
+func (cg *CodeGenerator) generateConstants(file *os.File, program *parser.Program) {
+	written := false
+	for _, stmt := range program.Statements {
+		if !cg.isConstant(stmt) {
+			continue
+		}
+		as := stmt.(*parser.AssignmentStatement)
+		fmt.Fprintf(file, "const %s = ", cg.identifierName(as.Left[0].String()))
+		cg.generateExpression(file, as.Value)
+		fmt.Fprintln(file)
+		written = true
+	}
+	if written {
+		fmt.Fprintln(file)
+	}
+}
+
+// isConstant reports whether stmt assigns a constant, which is written by
+// generateConstants rather than where it is assigned.
+func (cg *CodeGenerator) isConstant(stmt parser.Statement) bool {
+	as, ok := stmt.(*parser.AssignmentStatement)
+	if !ok || as == nil || len(as.Left) != 1 {
+		return false
+	}
+	return cg.analyzer.Constants[as.Left[0].String()] == as
+}
@@ -0,0 +1,29 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+)
+
+// generateDelStatement writes a del statement: Go's delete for a dict item,
+// and for a variable, which Go can't take out of its scope, a use of it, so
+// that a variable only assigned before it is deleted is still used.
+func (cg *CodeGenerator) generateDelStatement(file *os.File, ds *parser.DelStatement) {
+	for _, target := range ds.Targets {
+		cg.writeIndent(file)
+		ie, ok := target.(*parser.IndexExpression)
+		if !ok {
+			fmt.Fprint(file, "_ = ")
+			cg.generateExpression(file, target)
+			fmt.Fprintln(file)
+			continue
+		}
+		left := cg.goExpression(ie.Left).String()
+		if l, ok := ie.Left.(*parser.IndexExpression); ok {
+			left = cg.indexExpressionString(l)
+		}
+		keyType := mapKeyType(cg.getExpressionType(ie.Left))
+		fmt.Fprintf(file, "delete(%s, %s)\n", left, cg.mapKeyString(ie, left, keyType))
+	}
+}
@@ -0,0 +1,47 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+)
+
+// generateEnumerateLoop writes for i, item in enumerate(items, start): as a
+// range loop over the items, whose index is offset by start at the top of
+// the body.
+func (cg *CodeGenerator) generateEnumerateLoop(file *os.File, fs *parser.ForStatement, en *semantic.Enumeration, prevSymbolTable *semantic.SymbolTable) {
+	index, item := cg.loopVariable(fs, 0), cg.loopVariable(fs, 1)
+
+	cg.writeIndent(file)
+	switch {
+	case item != "_":
+		fmt.Fprintf(file, "for %s, %s := range ", index, item)
+	case index != "_":
+		fmt.Fprintf(file, "for %s := range ", index)
+	default:
+		fmt.Fprint(file, "for range ")
+	}
+	cg.generateItems(file, en.Kind, en.Items)
+	fmt.Fprintln(file, " {")
+	cg.indentLevel++
+	if en.Start != nil && index != "_" {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%s += ", index)
+		cg.generateExpression(file, en.Start)
+		fmt.Fprintln(file)
+	}
+	cg.generateBlockStatement(file, fs.Body, prevSymbolTable)
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+}
+
+// loopVariable returns the Go name of the variable at position i of a loop
+// with several, or _ when the body doesn't use it.
+func (cg *CodeGenerator) loopVariable(fs *parser.ForStatement, i int) string {
+	if !usesIdentifier(fs.Body, fs.Variables[i].Value) {
+		return "_"
+	}
+	return cg.goName(fs.Variables[i].Value)
+}
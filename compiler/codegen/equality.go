@@ -0,0 +1,78 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+	"strings"
+)
+
+// isStructuralComparison reports whether an == or != comparison compares
+// contents rather than values Go can compare with ==: lists, dicts, tuples,
+// and untyped values that may hold them. Comparisons with None stay nil
+// checks.
+func (cg *CodeGenerator) isStructuralComparison(ie *parser.InfixExpression) bool {
+	if ie.Operator != "==" && ie.Operator != "!=" {
+		return false
+	}
+	for _, side := range []parser.Expression{ie.Left, ie.Right} {
+		if _, ok := side.(*parser.NoneLiteral); ok {
+			return false
+		}
+		if ident, ok := side.(*parser.Identifier); ok && ident.Value == "nil" {
+			return false
+		}
+	}
+	for _, side := range []parser.Expression{ie.Left, ie.Right} {
+		switch t := cg.getExpressionType(side).String(); {
+		case strings.HasPrefix(t, "[]"), strings.HasPrefix(t, "map["), strings.HasPrefix(t, "struct{"):
+			return true
+		case t == "interface{}" || t == "any":
+			// Variables without a static type, such as function parameters,
+			// and the items of lists and dicts of them
+			switch side.(type) {
+			case *parser.Identifier, *parser.IndexExpression:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// generateStructuralComparison writes an == or != comparison as a call to
+// simpleEqual, which compares lists and dicts item by item like Python.
+func (cg *CodeGenerator) generateStructuralComparison(file *os.File, ie *parser.InfixExpression) {
+	cg.useHelper("simpleEqual")
+	if ie.Operator == "!=" {
+		fmt.Fprint(file, "!")
+	}
+	fmt.Fprint(file, "simpleEqual(")
+	cg.generateExpression(file, ie.Left)
+	fmt.Fprint(file, ", ")
+	cg.generateExpression(file, ie.Right)
+	fmt.Fprint(file, ")")
+}
+
+// generateIdentity writes a is b as a == b, which compares the pointers of
+// objects, and a is not b as a != b. A value that can't be nil, such as a
+// number, is never None, so it is compared with nil as an any.
+func (cg *CodeGenerator) generateIdentity(file *os.File, ie *parser.InfixExpression) {
+	operator := "=="
+	if ie.Operator == "is not" {
+		operator = "!="
+	}
+	left, right := ie.Left, ie.Right
+	if _, ok := left.(*parser.NoneLiteral); ok {
+		left, right = right, left
+	}
+	_, none := right.(*parser.NoneLiteral)
+	if _, ok := left.(*parser.NoneLiteral); ok || (none && !cg.analyzer.IsNilable(cg.analyzer.InferExpressionTypes(left, false)[0])) {
+		fmt.Fprint(file, "any(")
+		cg.generateExpression(file, left)
+		fmt.Fprintf(file, ") %s nil", operator)
+		return
+	}
+	cg.generateExpression(file, left)
+	fmt.Fprintf(file, " %s ", operator)
+	cg.generateExpression(file, right)
+}
@@ -0,0 +1,41 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+)
+
+// isErrorExpression reports whether expr is a Go error value.
+func (cg *CodeGenerator) isErrorExpression(expr parser.Expression) bool {
+	switch expr.(type) {
+	case nil, *parser.InfixExpression:
+		// Comparisons such as err != None are bools
+		return false
+	}
+	return cg.getExpressionType(expr).String() == "error"
+}
+
+// generateCondition writes the condition of an if or while statement. An
+// error is true when it is set, so `if err:` tests err != nil and
+// `if !err:` tests err == nil.
+func (cg *CodeGenerator) generateCondition(file *os.File, cond parser.Expression) {
+	if pe, ok := cond.(*parser.PrefixExpression); ok && pe.Operator == "!" && cg.isErrorExpression(pe.Right) {
+		cg.generateExpression(file, pe.Right)
+		fmt.Fprint(file, " == nil")
+		return
+	}
+	cg.generateExpression(file, cond)
+	if cg.isErrorExpression(cond) {
+		fmt.Fprint(file, " != nil")
+	}
+}
+
+// generateErrorString writes an error as its message, for printing and
+// string concatenation.
+func (cg *CodeGenerator) generateErrorString(file *os.File, expr parser.Expression) {
+	cg.useHelper("simpleErrorString")
+	fmt.Fprint(file, "simpleErrorString(")
+	cg.generateExpression(file, expr)
+	fmt.Fprint(file, ")")
+}
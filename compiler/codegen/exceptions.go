@@ -0,0 +1,522 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+	"strings"
+)
+
+// Try statements
+//
+// A try statement runs its body in a closure with a deferred function that
+// recovers the panic of an exception and runs the except clause for it.
+// A finally block is deferred by a closure around that one, so it runs
+// whether the body returns, raises or is handled. Return, break and continue
+// can't leave the closures directly: they set the statement's flow variable
+// to one of the flows below and return from the closure, and the code after
+// the statement does what they would have done.
+
+const (
+	tryReturn   = 1
+	tryBreak    = 2
+	tryContinue = 3
+)
+
+// tryFrame is a try statement whose blocks are being generated, or a with
+// statement, whose body runs in a closure too.
+type tryFrame struct {
+	name    string   // prefix of the statement's variables, such as try1
+	loops   int      // loops open inside the statement
+	results []string // variables holding the values a return inside it returns
+	handled string   // while its except clauses are generated, the variable holding the recovered value
+}
+
+// innermostTry returns the try or with statement being generated that
+// return, break and continue must pass through, or nil if there is none.
+func (cg *CodeGenerator) innermostTry() *tryFrame {
+	if len(cg.tries) == 0 {
+		return nil
+	}
+	return cg.tries[len(cg.tries)-1]
+}
+
+// enterLoop and exitLoop keep count of the loops inside the innermost try
+// and match statements, whose break and continue statements are their own.
+func (cg *CodeGenerator) enterLoop() {
+	if frame := cg.innermostTry(); frame != nil {
+		frame.loops++
+	}
+	if len(cg.matches) > 0 {
+		cg.matches[len(cg.matches)-1].loops++
+	}
+}
+
+func (cg *CodeGenerator) exitLoop() {
+	if frame := cg.innermostTry(); frame != nil {
+		frame.loops--
+	}
+	if len(cg.matches) > 0 {
+		cg.matches[len(cg.matches)-1].loops--
+	}
+}
+
+// generateTryStatement writes a try statement.
+func (cg *CodeGenerator) generateTryStatement(file *os.File, ts *parser.TryStatement, prevSymbolTable *semantic.SymbolTable) {
+	if ts == nil {
+		return
+	}
+	cg.tryCount++
+	frame := &tryFrame{name: fmt.Sprintf("try%d", cg.tryCount)}
+	flows := tryFlows(ts)
+	cg.declareFrame(file, frame, ts, flows)
+	if ts.Else != nil {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%sOk := false\n", frame.name)
+	}
+
+	cg.tries = append(cg.tries, frame)
+	if ts.Finally != nil {
+		cg.openClosure(file)
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "defer func() {")
+		cg.indentLevel++
+		cg.generateBlockStatement(file, ts.Finally, prevSymbolTable)
+		cg.closeClosure(file)
+	}
+	cg.openClosure(file)
+	if len(ts.Handlers) > 0 {
+		cg.generateHandlers(file, frame, ts.Handlers, prevSymbolTable)
+	}
+	cg.generateBlockStatement(file, ts.Body, prevSymbolTable)
+	if ts.Else != nil {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%sOk = true\n", frame.name)
+	}
+	cg.closeClosure(file)
+	if ts.Finally != nil {
+		// The else block runs before the finally block
+		cg.generateTryElse(file, frame, ts.Else, prevSymbolTable)
+		cg.closeClosure(file)
+	}
+	cg.tries = cg.tries[:len(cg.tries)-1]
+	cg.generateFlows(file, frame, flows)
+	if ts.Finally == nil {
+		cg.generateTryElse(file, frame, ts.Else, prevSymbolTable)
+	}
+}
+
+// declareFrame declares what a statement run in closures needs before
+// them: the variables keeping the values a return inside it returns, the
+// variables first assigned inside it, and its flow variable.
+func (cg *CodeGenerator) declareFrame(file *os.File, frame *tryFrame, stmt parser.Statement, flows map[int]bool) {
+	// Values returned inside the statement are kept until the function
+	// can return them
+	if outer := cg.innermostTry(); outer != nil {
+		frame.results = outer.results
+	} else if flows[tryReturn] && cg.function != nil && resultString(cg.function) != "" {
+		for i, rt := range cg.function.ReturnTypes {
+			result := fmt.Sprintf("%sResult%d", frame.name, i)
+			cg.writeIndent(file)
+			fmt.Fprintf(file, "var %s %s\n", result, goTypeName(rt.String()))
+			frame.results = append(frame.results, result)
+		}
+	}
+	cg.declareTryVariables(file, stmt)
+	if len(flows) > 0 {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "var %sFlow int\n", frame.name)
+	}
+}
+
+// generateFlows writes what the return, break and continue statements that
+// left the closures of a statement do once they are done.
+func (cg *CodeGenerator) generateFlows(file *os.File, frame *tryFrame, flows map[int]bool) {
+	if flows[tryReturn] && cg.innermostTry() == nil && cg.Returns["currentFunc"] != nil {
+		// Returning from the closures doesn't end the function, which
+		// still needs a return statement at its end
+		cg.Returns["currentFunc"]["done"] = false
+	}
+	for _, flow := range []int{tryReturn, tryBreak, tryContinue} {
+		if !flows[flow] {
+			continue
+		}
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "if %sFlow == %d {\n", frame.name, flow)
+		cg.indentLevel++
+		cg.generateExit(file, flow, frame.results)
+		cg.indentLevel--
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "}")
+	}
+}
+
+// openClosure starts a closure that is called where it is written.
+func (cg *CodeGenerator) openClosure(file *os.File) {
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "func() {")
+	cg.indentLevel++
+}
+
+// closeClosure ends a closure started by openClosure, or a deferred one.
+func (cg *CodeGenerator) closeClosure(file *os.File) {
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}()")
+}
+
+// generateHandlers writes the deferred function that handles an exception
+// raised in the body of a try statement. An exception no except clause
+// handles is raised again.
+func (cg *CodeGenerator) generateHandlers(file *os.File, frame *tryFrame, handlers []*parser.ExceptClause, prevSymbolTable *semantic.SymbolTable) {
+	cg.useHelper("simpleException")
+	recovered := frame.name + "Panic"
+	exception := frame.name + "Exception"
+
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "defer func() {")
+	cg.indentLevel++
+	cg.writeIndent(file)
+	fmt.Fprintf(file, "if %s := recover(); %s != nil {\n", recovered, recovered)
+	cg.indentLevel++
+	if first := handlers[0]; cg.exceptionClasses(first) != nil || (first.Name != nil && usesIdentifier(first.Body, first.Name.Value)) {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%s := simpleCatch(%s)\n", exception, recovered)
+	}
+
+	frame.handled = recovered
+	defer func() { frame.handled = "" }()
+	for i, handler := range handlers {
+		classes := cg.exceptionClasses(handler)
+		if classes == nil && i == 0 {
+			// Every exception is handled, with no need to test
+			cg.generateHandler(file, handler, exception, prevSymbolTable)
+			break
+		}
+		cg.writeIndent(file)
+		switch {
+		case classes == nil:
+			fmt.Fprintln(file, "} else {")
+		case i == 0:
+			fmt.Fprintf(file, "if %s.matches(%s) {\n", exception, strings.Join(classes, ", "))
+		default:
+			fmt.Fprintf(file, "} else if %s.matches(%s) {\n", exception, strings.Join(classes, ", "))
+		}
+		cg.indentLevel++
+		cg.generateHandler(file, handler, exception, prevSymbolTable)
+		cg.indentLevel--
+		if classes == nil {
+			// Later clauses can't be reached
+			cg.writeIndent(file)
+			fmt.Fprintln(file, "}")
+			break
+		}
+		if i == len(handlers)-1 {
+			cg.writeIndent(file)
+			fmt.Fprintln(file, "} else {")
+			cg.indentLevel++
+			cg.writeIndent(file)
+			fmt.Fprintf(file, "panic(%s)\n", recovered)
+			cg.indentLevel--
+			cg.writeIndent(file)
+			fmt.Fprintln(file, "}")
+		}
+	}
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+	cg.closeClosure(file)
+}
+
+// generateHandler writes the body of an except clause, binding its name to
+// the exception if the body uses it.
+func (cg *CodeGenerator) generateHandler(file *os.File, handler *parser.ExceptClause, exception string, prevSymbolTable *semantic.SymbolTable) {
+	if handler.Name != nil && usesIdentifier(handler.Body, handler.Name.Value) {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%s := error(%s)\n", cg.goName(handler.Name.Value), exception)
+	}
+	cg.generateBlockStatement(file, handler.Body, prevSymbolTable)
+}
+
+// exceptionClasses returns the quoted names of the exception classes an
+// except clause handles, or nil if it handles every exception.
+func (cg *CodeGenerator) exceptionClasses(handler *parser.ExceptClause) []string {
+	if len(handler.Types) == 0 {
+		return nil
+	}
+	classes := []string{}
+	seen := map[string]bool{}
+	for _, t := range handler.Types {
+		if t.Value == "Exception" {
+			return nil
+		}
+		for _, name := range cg.analyzer.ExceptionNames(t.Value) {
+			if !seen[name] {
+				seen[name] = true
+				classes = append(classes, fmt.Sprintf("%q", name))
+			}
+		}
+	}
+	return classes
+}
+
+// generateRaiseStatement writes a raise statement as a panic with the
+// exception. A bare raise panics again with the value the except clause
+// recovered.
+func (cg *CodeGenerator) generateRaiseStatement(file *os.File, rs *parser.RaiseStatement) {
+	cg.useHelper("simpleException")
+	cg.writeIndent(file)
+	if rs.Exception == nil {
+		for i := len(cg.tries) - 1; i >= 0; i-- {
+			if cg.tries[i].handled != "" {
+				fmt.Fprintf(file, "panic(%s)\n", cg.tries[i].handled)
+				return
+			}
+		}
+		return
+	}
+
+	function := cg.tracebackFunction()
+	class, message, ok := cg.analyzer.RaisedException(rs)
+	if !ok {
+		fmt.Fprint(file, "panic(simpleRaiseError(")
+		cg.generateExpression(file, rs.Exception)
+		fmt.Fprintf(file, ", %d, %q))\n", rs.Token.Line, function)
+		return
+	}
+	fmt.Fprintf(file, "panic(simpleRaise(%q, ", class)
+	switch {
+	case message == nil:
+		fmt.Fprint(file, `""`)
+	case cg.getExpressionType(message).String() == "string":
+		cg.generateExpression(file, message)
+	default:
+		cg.generateFormatCall(file, "str", message)
+	}
+	fmt.Fprintf(file, ", %d, %q))\n", rs.Token.Line, function)
+}
+
+// generateAssertStatement writes an assert statement as a panic with an
+// AssertionError when its condition is false. A release build leaves the
+// check out, and keeps the condition, never evaluated, only so that Go still
+// sees its variables used.
+func (cg *CodeGenerator) generateAssertStatement(file *os.File, as *parser.AssertStatement) {
+	cg.writeIndent(file)
+	if cg.Release {
+		fmt.Fprint(file, "_ = false && (")
+		cg.generateCondition(file, as.Condition)
+		fmt.Fprintln(file, ")")
+		return
+	}
+	cg.useHelper("simpleException")
+	fmt.Fprint(file, "if !(")
+	cg.generateCondition(file, as.Condition)
+	fmt.Fprintln(file, ") {")
+	cg.indentLevel++
+	cg.writeIndent(file)
+	fmt.Fprint(file, `panic(simpleRaise("AssertionError", `)
+	switch {
+	case as.Message == nil:
+		fmt.Fprint(file, `""`)
+	case cg.getExpressionType(as.Message).String() == "string":
+		cg.generateExpression(file, as.Message)
+	default:
+		cg.generateFormatCall(file, "str", as.Message)
+	}
+	fmt.Fprintf(file, ", %d, %q))\n", as.Token.Line, cg.tracebackFunction())
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+}
+
+// tracebackFunction returns the name of the function being generated as a
+// traceback gives it, <module> outside functions.
+func (cg *CodeGenerator) tracebackFunction() string {
+	if cg.analyzer.CurrentTable == cg.analyzer.GlobalTable {
+		return "<module>"
+	}
+	return cg.analyzer.CurrentTable.Name
+}
+
+// raisesExceptions reports whether a program has raise or assert statements
+// or calls such as open, input and max, whose exceptions are reported as Python reports them if nothing
+// catches them.
+func (cg *CodeGenerator) raisesExceptions(program *parser.Program) bool {
+	found := false
+	parser.Inspect(program, func(n parser.Node) bool {
+		switch n := n.(type) {
+		case *parser.RaiseStatement:
+			found = true
+		case *parser.AssertStatement:
+			found = !cg.Release
+		case *parser.CallExpression:
+			sc, ok := cg.analyzer.SetCalls[n]
+			lc, untyped := cg.analyzer.LenCalls[n]
+			ac, aggregate := cg.analyzer.AggregateCalls[n]
+			found = cg.analyzer.IsBuiltinCall(n, "open") || cg.analyzer.IsBuiltinCall(n, "input") || (ok && sc.Name == "remove") || (untyped && lc.Untyped) || (aggregate && ac.Items != nil && ac.Name != "sum" && ac.Default == nil)
+		case *parser.IndexExpression:
+			found = cg.analyzer.UntypedIndexes[n]
+		}
+		return !found
+	})
+	return found
+}
+
+// generateTryElse writes the else block of a try statement, if it has one,
+// to run when the body raised nothing.
+func (cg *CodeGenerator) generateTryElse(file *os.File, frame *tryFrame, block *parser.BlockStatement, prevSymbolTable *semantic.SymbolTable) {
+	if block == nil {
+		return
+	}
+	cg.writeIndent(file)
+	fmt.Fprintf(file, "if %sOk {\n", frame.name)
+	cg.indentLevel++
+	cg.generateBlockStatement(file, block, prevSymbolTable)
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+}
+
+// declareTryVariables declares the variables first assigned inside a try or
+// with statement before it, so that they outlive the closures it is made of.
+func (cg *CodeGenerator) declareTryVariables(file *os.File, stmt parser.Statement) {
+	parser.Inspect(stmt, func(n parser.Node) bool {
+		switch s := n.(type) {
+		case *parser.FunctionLiteral, *parser.ClassStatement:
+			return false
+		case *parser.AssignmentStatement:
+			for _, left := range s.Left {
+				ident, ok := left.(*parser.Identifier)
+				if !ok {
+					continue
+				}
+				symbol, found := cg.analyzer.CurrentTable.Resolve(ident.Value)
+				if !found || symbol.Metadata != nil {
+					continue
+				}
+				cg.writeIndent(file)
+				fmt.Fprintf(file, "var %s %s\n", cg.goName(ident.Value), cg.typeToGoString(symbol.Type))
+				symbol.Metadata = map[string]any{"set": true}
+			}
+		}
+		return true
+	})
+}
+
+// generateTryReturn writes a return statement inside a try statement: the
+// values are kept for the function to return once the statement is done.
+func (cg *CodeGenerator) generateTryReturn(file *os.File, rs *parser.ReturnStatement) {
+	frame := cg.innermostTry()
+	if rs.ReturnValue != nil && len(frame.results) > 0 {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%s = ", strings.Join(frame.results, ", "))
+		if tl, ok := rs.ReturnValue.(*parser.TupleLiteral); ok {
+			cg.generateExpressionList(file, tl.Elements)
+		} else {
+			cg.generateExpression(file, rs.ReturnValue)
+		}
+		fmt.Fprintln(file)
+	}
+	cg.generateExit(file, tryReturn, frame.results)
+}
+
+// generateExit writes a return, break or continue. Inside a try statement,
+// one that isn't a break or continue of a loop inside the statement sets
+// the statement's flow and returns from its closure. Outside, a return
+// returns the kept results. A break in a match statement is passed on by
+// the statement, as a Go break would only leave its switch.
+func (cg *CodeGenerator) generateExit(file *os.File, flow int, results []string) {
+	if frame := cg.innermostMatch(); frame != nil && flow == tryBreak {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%sBreak = true\n", frame.name)
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "break")
+		return
+	}
+	if frame := cg.innermostTry(); frame != nil && (flow == tryReturn || frame.loops == 0) {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%sFlow = %d\n", frame.name, flow)
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "return")
+		return
+	}
+	cg.writeIndent(file)
+	switch flow {
+	case tryReturn:
+		if len(results) > 0 {
+			fmt.Fprintf(file, "return %s\n", strings.Join(results, ", "))
+		} else {
+			fmt.Fprintln(file, "return")
+		}
+	case tryBreak:
+		fmt.Fprintln(file, "break")
+	case tryContinue:
+		fmt.Fprintln(file, "continue")
+	}
+}
+
+// tryFlows returns the flows that leave a try statement: return statements
+// anywhere in it, and break and continue statements outside its loops.
+func tryFlows(ts *parser.TryStatement) map[int]bool {
+	flows := map[int]bool{}
+	for _, block := range ts.Blocks() {
+		addFlows(flows, block.Statements, false)
+	}
+	return flows
+}
+
+func addFlows(flows map[int]bool, statements []parser.Statement, inLoop bool) {
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case *parser.ReturnStatement, *parser.YieldStatement:
+			// A yield returns when the loop over the generator stops
+			flows[tryReturn] = true
+		case *parser.BreakStatement:
+			if !inLoop {
+				flows[tryBreak] = true
+			}
+		case *parser.ContinueStatement:
+			if !inLoop {
+				flows[tryContinue] = true
+			}
+		case *parser.IfStatement:
+			// A statement the parser couldn't finish is left as a nil node
+			if s == nil {
+				continue
+			}
+			if s.Consequence != nil {
+				addFlows(flows, s.Consequence.Statements, inLoop)
+			}
+			if s.Alternative != nil {
+				addFlows(flows, s.Alternative.Statements, inLoop)
+			}
+		case *parser.WhileStatement:
+			if s != nil && s.Body != nil {
+				addFlows(flows, s.Body.Statements, true)
+			}
+		case *parser.ForStatement:
+			if s != nil && s.Body != nil {
+				addFlows(flows, s.Body.Statements, true)
+			}
+		case *parser.TryStatement:
+			if s == nil {
+				continue
+			}
+			for _, block := range s.Blocks() {
+				addFlows(flows, block.Statements, inLoop)
+			}
+		case *parser.MatchStatement:
+			if s == nil {
+				continue
+			}
+			for _, block := range s.Blocks() {
+				addFlows(flows, block.Statements, inLoop)
+			}
+		case *parser.WithStatement:
+			if s != nil && s.Body != nil {
+				addFlows(flows, s.Body.Statements, inLoop)
+			}
+		}
+	}
+}
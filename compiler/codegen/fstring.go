@@ -0,0 +1,165 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// generateFStringLiteral writes an f-string as a fmt.Sprintf call, with a
+// verb for each value chosen from its type and format spec.
+func (cg *CodeGenerator) generateFStringLiteral(file *os.File, fs *parser.FStringLiteral) {
+	var format, text strings.Builder
+	args := []func(){}
+	for _, part := range fs.Parts {
+		if part.Expression == nil {
+			format.WriteString(strings.ReplaceAll(part.Text, "%", "%%"))
+			text.WriteString(part.Text)
+			continue
+		}
+		if part.Debug {
+			format.WriteString(strings.ReplaceAll(part.Text, "%", "%%"))
+		}
+		verb, arg := cg.formatVerb(file, part)
+		format.WriteString(verb)
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		fmt.Fprint(file, strconv.Quote(text.String()))
+		return
+	}
+	if cg.generateFastFString(file, fs) {
+		return
+	}
+	fmt.Fprintf(file, "fmt.Sprintf(%s", strconv.Quote(format.String()))
+	for _, arg := range args {
+		fmt.Fprint(file, ", ")
+		arg()
+	}
+	fmt.Fprint(file, ")")
+}
+
+// formatVerb returns the fmt verb for one {field} of an f-string and a
+// function writing the value it formats. Values without a spec print as
+// print would show them; the analyzer has checked that the spec suits the
+// value and can be written as a verb.
+func (cg *CodeGenerator) formatVerb(file *os.File, part parser.FStringPart) (string, func()) {
+	expr := part.Expression
+	kind := cg.analyzer.InferExpressionTypes(expr, false)[0].String()
+	arg := func() { cg.generateExpression(file, expr) }
+	switch {
+	case part.Conversion == 'r':
+		kind = "string"
+		arg = func() { cg.generateFormatCall(file, "repr", expr) }
+	case part.Conversion == 's', cg.isErrorExpression(expr):
+		kind = "string"
+		arg = func() { cg.generateFormatCall(file, "str", expr) }
+	case kind == "float64":
+		kind = "float"
+	}
+
+	spec, _ := parser.ParseFormatSpec(part.Spec)
+	if spec.Grouping != 0 {
+		// fmt has no thousands separators: the number is formatted without
+		// its width, simpleGroup adds the separators, and the result is
+		// padded as a string
+		inner := spec
+		inner.Fill, inner.Align, inner.Width, inner.Grouping = ' ', 0, "", 0
+		innerVerb, innerArg := cg.formatVerb(file, parser.FStringPart{Expression: expr, Conversion: part.Conversion, Spec: inner.String()})
+		cg.useHelper("simpleGroup")
+		flags := ""
+		if spec.Align == '<' {
+			flags = "-"
+		}
+		return "%" + flags + spec.Width + "s", func() {
+			fmt.Fprintf(file, "simpleGroup(fmt.Sprintf(%s, ", strconv.Quote(innerVerb))
+			innerArg()
+			fmt.Fprintf(file, "), %s)", strconv.Quote(string(spec.Grouping)))
+		}
+	}
+	verb := spec.Type
+	if verb == 0 {
+		switch {
+		case kind == "string":
+			verb = 's'
+		case kind == "int":
+			verb = 'd'
+		case spec.Precision != "" && kind != "float":
+			// An untyped value: %v applies the precision to both floats and strings
+			verb = 'v'
+		case spec.Precision != "":
+			verb = 'g'
+		default:
+			verb = 's'
+			arg = func() { cg.generateFormatCall(file, "str", expr) }
+		}
+	}
+
+	var flags strings.Builder
+	if spec.Width != "" && (spec.Align == '<' || (spec.Align == 0 && verb == 's' && kind == "string")) {
+		flags.WriteByte('-')
+	}
+	if spec.Sign == '+' || spec.Sign == ' ' {
+		flags.WriteRune(spec.Sign)
+	}
+	if spec.Alternate && strings.ContainsRune("bxX", verb) {
+		flags.WriteByte('#')
+	}
+	if spec.Zero || spec.Fill == '0' {
+		flags.WriteByte('0')
+	}
+	flags.WriteString(spec.Width)
+	precision := spec.Precision
+	if precision == "" && strings.ContainsRune("gG%", verb) {
+		// Python's default precision; Go's %g would print every digit
+		precision = "6"
+	}
+	if precision != "" {
+		flags.WriteString("." + precision)
+	}
+
+	switch verb {
+	case 'o':
+		if spec.Alternate {
+			// Go writes Python's 0o prefix with %O
+			verb = 'O'
+		}
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		arg = cg.floatArgument(file, kind, expr, "")
+	case '%':
+		return "%" + flags.String() + "f%%", cg.floatArgument(file, kind, expr, " * 100")
+	case 's':
+		if kind != "string" {
+			arg = func() { cg.generateFormatCall(file, "str", expr) }
+		}
+	}
+	return "%" + flags.String() + string(verb), arg
+}
+
+// floatArgument returns a function writing expr as a float for the float
+// formats, followed by scale, such as " * 100" for percentages.
+func (cg *CodeGenerator) floatArgument(file *os.File, kind string, expr parser.Expression, scale string) func() {
+	return func() {
+		switch kind {
+		case "float":
+			if scale == "" {
+				cg.generateExpression(file, expr)
+				return
+			}
+			fmt.Fprint(file, "(")
+			cg.generateExpression(file, expr)
+			fmt.Fprint(file, scale+")")
+		case "int":
+			fmt.Fprint(file, "float64(")
+			cg.generateExpression(file, expr)
+			fmt.Fprint(file, ")"+scale)
+		default:
+			cg.useHelper("simpleToFloat")
+			fmt.Fprint(file, "simpleToFloat(")
+			cg.generateExpression(file, expr)
+			fmt.Fprint(file, ")"+scale)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+)
+
+// generateGeneratorBody writes the body of a generator, which returns an
+// iterator function of type iterator. The iterator runs the body as a for
+// loop ranging over it asks for values, and the body's returns return from
+// it.
+func (cg *CodeGenerator) generateGeneratorBody(file *os.File, fn *parser.FunctionLiteral, iterator *parser.FunctionType, prevSymbolTable *semantic.SymbolTable) {
+	cg.writeIndent(file)
+	fmt.Fprintf(file, "return func(yield %s) {\n", cg.typeToGoString(iterator.ParameterTypes[0]))
+	cg.indentLevel++
+	prevFunction := cg.function
+	cg.function = &parser.FunctionType{ReturnTypes: []parser.Type{&parser.BasicType{Name: "void"}}}
+	cg.generateBlockStatement(file, fn.Body, prevSymbolTable)
+	cg.function = prevFunction
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+	cg.Returns["currentFunc"]["done"] = true
+}
+
+// generateYieldStatement writes a yield, which passes its value to the loop
+// over the generator and returns when the loop has stopped.
+func (cg *CodeGenerator) generateYieldStatement(file *os.File, ys *parser.YieldStatement) {
+	cg.writeIndent(file)
+	fmt.Fprint(file, "if !yield(")
+	cg.generateExpression(file, ys.Value)
+	fmt.Fprintln(file, ") {")
+	cg.indentLevel++
+	cg.generateExit(file, tryReturn, nil)
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+}
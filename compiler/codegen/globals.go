@@ -0,0 +1,32 @@
+package codegen
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// generateGlobals writes the module variables that functions declare
+// global as Go package variables, ahead of the functions, which share them
+// with main. Assigning one is then never a declaration.
+func (cg *CodeGenerator) generateGlobals(file *os.File) {
+	names := []string{}
+	for name := range cg.analyzer.Globals {
+		symbol, ok := cg.analyzer.GlobalTable.Symbols[name]
+		if !ok || cg.analyzer.Constants[name] != nil {
+			continue
+		}
+		if symbol.Metadata == nil {
+			symbol.Metadata = map[string]any{}
+		}
+		symbol.Metadata["set"] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(file, "var %s %s\n", cg.identifierName(name), cg.typeToGoString(cg.analyzer.GlobalTable.Symbols[name].Type))
+	}
+	if len(names) > 0 {
+		fmt.Fprintln(file)
+	}
+}
@@ -0,0 +1,79 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+)
+
+// Platforms
+//
+// A program built for a platform runs as it does anywhere else, with what
+// the platform expects of it added. On heroku, a web server must listen on
+// the port Heroku gives in PORT, and monitors check that it is up on
+// /healthz, so the main program's calls of http.ListenAndServe call
+// simpleServe instead. It listens on PORT when that is set, and on the
+// address the program asks for otherwise, so the program runs the same
+// locally, and answers /healthz itself before passing requests on to the
+// program's handler.
+
+// Platforms are the platforms a program can be built for.
+var Platforms = []string{"heroku"}
+
+// isListenAndServe reports whether a call is one of http.ListenAndServe,
+// as the program names net/http.
+func (cg *CodeGenerator) isListenAndServe(ce *parser.CallExpression) bool {
+	se, ok := ce.Function.(*parser.SelectorExpression)
+	if !ok || se.Selector.Value != "ListenAndServe" || len(ce.Arguments) != 2 {
+		return false
+	}
+	left, ok := se.Left.(*parser.Identifier)
+	if !ok || !cg.imports["net/http"] {
+		return false
+	}
+	name := "http"
+	if alias, ok := cg.importAliases["net/http"]; ok {
+		name = alias
+	}
+	return left.Value == name
+}
+
+// Listens reports whether the program calls http.ListenAndServe, and so
+// listens where the platform it is built for expects.
+func (cg *CodeGenerator) Listens() bool {
+	return cg.listens
+}
+
+// generateServe writes a call of http.ListenAndServe as a call of
+// simpleServe.
+func (cg *CodeGenerator) generateServe(file *os.File, ce *parser.CallExpression) {
+	cg.useHelper("simpleServe")
+	cg.listens = true
+	fmt.Fprint(file, "simpleServe(")
+	cg.generateExpression(file, ce.Arguments[0])
+	fmt.Fprint(file, ", ")
+	cg.generateExpression(file, ce.Arguments[1])
+	fmt.Fprint(file, ")")
+}
+
+var serveHelper = runtimeHelper{
+	imports: []string{"net/http", "os"},
+	source: `// simpleServe listens and serves as http.ListenAndServe does, on
+// the port in PORT when it is set, answering /healthz with ok and passing
+// other requests to handler, or to http.DefaultServeMux when it is nil.
+func simpleServe(addr string, handler http.Handler) error {
+	if port := os.Getenv("PORT"); port != "" {
+		addr = ":" + port
+	}
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.Handle("/", handler)
+	return http.ListenAndServe(addr, mux)
+}
+`,
+}
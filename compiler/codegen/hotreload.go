@@ -0,0 +1,170 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Hot reload (experimental)
+//
+// With HotReload set, every top-level function of the main program starts
+// with a prologue that looks the function up in a registry and, if a newer
+// build of it has been loaded, calls that instead. The registry lives in
+// simple_hot.go, which also watches .simple_hot/current for the path of the latest
+// plugin and loads its HotFunctions into the registry.
+//
+// Plugins are built from the same directory with the simplehotplugin build
+// tag, which swaps simple_hot.go for simple_hot_plugin.go: an empty registry,
+// so plugin code always runs its own functions, and the HotFunctions table.
+//
+// A plugin also has its own copy of the program's package variables, which
+// main never ran in, so a function using a global variable isn't reloaded;
+// hot.go refuses the change instead.
+
+// writeHotPrologue writes the registry lookup at the top of a hot reloadable function.
+func (cg *CodeGenerator) writeHotPrologue(file *os.File, fn *parser.FunctionLiteral, params []string, returnType string) {
+	args := []string{}
+	for _, p := range fn.Parameters {
+		args = append(args, cg.goName(p.Value))
+	}
+	call := fmt.Sprintf("hot(%s)", strings.Join(args, ", "))
+
+	cg.writeIndent(file)
+	fmt.Fprintf(file, "if hot, ok := simpleHotLookup(%q).(func(%s) %s); ok {\n", fn.Name.Value, strings.Join(params, ", "), returnType)
+	cg.indentLevel++
+	cg.writeIndent(file)
+	if returnType != "" {
+		fmt.Fprintf(file, "return %s\n", call)
+	} else {
+		fmt.Fprintf(file, "%s\n", call)
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "return")
+	}
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+}
+
+// WriteHotReloadHost writes simple_hot.go, the function registry and plugin
+// watcher linked into the running program.
+func WriteHotReloadHost(outputDir string) error {
+	file, err := os.Create(filepath.Join(outputDir, "simple_hot.go"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(hotReloadHostSource)
+	return err
+}
+
+// WriteHotReloadPlugin writes simple_hot_plugin.go, exporting the named
+// functions from the given generation of hot reload plugin.
+func WriteHotReloadPlugin(outputDir string, functions []string, generation int) error {
+	file, err := os.Create(filepath.Join(outputDir, "simple_hot_plugin.go"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fmt.Fprint(file, "// Code generated by the Simple compiler. DO NOT EDIT.\n\n")
+	fmt.Fprint(file, "//go:build simplehotplugin\n\n")
+	fmt.Fprint(file, "package main\n\n")
+	fmt.Fprintln(file, "// simpleHotGeneration keeps every plugin's source, and so its plugin path, unique.")
+	fmt.Fprintf(file, "const simpleHotGeneration = %d\n\n", generation)
+	fmt.Fprintln(file, "func simpleHotLookup(name string) interface{} { return nil }")
+	fmt.Fprintln(file)
+	fmt.Fprintln(file, "// HotFunctions are the functions this plugin replaces in the running program.")
+	fmt.Fprintln(file, "var HotFunctions = map[string]interface{}{")
+	for _, name := range functions {
+		goName := name
+		if renamed, ok := mainRenames[name]; ok {
+			goName = renamed
+		}
+		fmt.Fprintf(file, "\t%q: %s,\n", name, goName)
+	}
+	fmt.Fprintln(file, "}")
+	return nil
+}
+
+const hotReloadHostSource = `// Code generated by the Simple compiler. DO NOT EDIT.
+
+//go:build !simplehotplugin
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	simpleHotMu        sync.RWMutex
+	simpleHotFunctions = map[string]interface{}{}
+)
+
+func simpleHotLookup(name string) interface{} {
+	simpleHotMu.RLock()
+	defer simpleHotMu.RUnlock()
+	return simpleHotFunctions[name]
+}
+
+func simpleHotLoad(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return err
+	}
+	sym, err := p.Lookup("HotFunctions")
+	if err != nil {
+		return err
+	}
+	functions, ok := sym.(*map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected HotFunctions type %T", sym)
+	}
+	names := []string{}
+	simpleHotMu.Lock()
+	for name, fn := range *functions {
+		simpleHotFunctions[name] = fn
+		names = append(names, name)
+	}
+	simpleHotMu.Unlock()
+	sort.Strings(names)
+	fmt.Fprintf(os.Stderr, "[simple] hot reloaded: %s\n", strings.Join(names, ", "))
+	return nil
+}
+
+func init() {
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	current := filepath.Join(filepath.Dir(exe), ".simple_hot", "current")
+	go func() {
+		loaded := ""
+		if data, err := os.ReadFile(current); err == nil {
+			loaded = strings.TrimSpace(string(data))
+		}
+		for range time.Tick(250 * time.Millisecond) {
+			data, err := os.ReadFile(current)
+			path := strings.TrimSpace(string(data))
+			if err != nil || path == loaded {
+				continue
+			}
+			loaded = path
+			if err := simpleHotLoad(path); err != nil {
+				fmt.Fprintf(os.Stderr, "[simple] hot reload failed: %v\n", err)
+			}
+		}
+	}()
+}
+`
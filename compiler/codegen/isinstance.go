@@ -0,0 +1,158 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+	"strings"
+)
+
+// generateTypeCheck writes isinstance(x, T), or type(x) == T, as a type
+// switch on x in a function literal called with it, so x is evaluated once:
+//
+//	func(v any) bool { switch v.(type) { case int, bool: return true }; return false }(x)
+//
+// Lists, dicts, sets and tuples are kinds of Go type rather than types, so
+// a check for one of them is left to simpleIsKind.
+func (cg *CodeGenerator) generateTypeCheck(file *os.File, tc *semantic.TypeCheck) {
+	if tc.Negated {
+		fmt.Fprint(file, "!")
+	}
+	if len(tc.Types) == 0 {
+		fmt.Fprint(file, "simpleIsKind(")
+		cg.generateExpression(file, tc.Value)
+		fmt.Fprintf(file, ", %s)", cg.kinds(tc.Kinds))
+		return
+	}
+	types := []string{}
+	for _, t := range tc.Types {
+		types = append(types, cg.typeToGoString(&parser.BasicType{Name: t}))
+	}
+	fallback := "false"
+	if len(tc.Kinds) > 0 {
+		fallback = fmt.Sprintf("simpleIsKind(v, %s)", cg.kinds(tc.Kinds))
+	}
+	fmt.Fprintf(file, "func(v any) bool { switch v.(type) { case %s: return true }; return %s }(", strings.Join(types, ", "), fallback)
+	cg.generateExpression(file, tc.Value)
+	fmt.Fprint(file, ")")
+}
+
+// kinds returns the arguments of simpleIsKind after the value, the kinds it
+// tests for.
+func (cg *CodeGenerator) kinds(kinds []string) string {
+	cg.useHelper("simpleIsKind")
+	quoted := []string{}
+	for _, kind := range kinds {
+		quoted = append(quoted, fmt.Sprintf("%q", kind))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// generateTypeCall writes type(x), the name of x's type as Python prints
+// it, or type(x).__name__, the name alone.
+func (cg *CodeGenerator) generateTypeCall(file *os.File, ce *parser.CallExpression, name bool) {
+	helper := "simpleTypeOf"
+	if name {
+		helper = "simpleTypeName"
+	}
+	cg.useHelper(helper)
+	fmt.Fprintf(file, "%s(", helper)
+	cg.generateExpression(file, ce.Arguments[0])
+	fmt.Fprint(file, ")")
+}
+
+var isKindHelper = runtimeHelper{
+	imports: []string{"reflect"},
+	helpers: []string{"simpleIsTuple"},
+	source: `// simpleIsKind reports whether v is of one of kinds: list, dict, set or
+// tuple.
+func simpleIsKind(v interface{}, kinds ...string) bool {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return false
+	}
+	for _, kind := range kinds {
+		switch kind {
+		case "list":
+			if t.Kind() == reflect.Slice {
+				return true
+			}
+		case "dict":
+			if t.Kind() == reflect.Map && t.Elem() != reflect.TypeOf(struct{}{}) {
+				return true
+			}
+		case "set":
+			if t.Kind() == reflect.Map && t.Elem() == reflect.TypeOf(struct{}{}) {
+				return true
+			}
+		case "tuple":
+			if simpleIsTuple(t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+`,
+}
+
+var typeNameHelper = runtimeHelper{
+	imports: []string{"reflect"},
+	helpers: []string{"simpleIsTuple"},
+	source: `// simpleTypeName returns the name Python gives the type of v, as in int or
+// list, or the name of its class.
+func simpleTypeName(v interface{}) string {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return "NoneType"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.String:
+		return "str"
+	case reflect.Slice:
+		return "list"
+	case reflect.Map:
+		if t.Elem() == reflect.TypeOf(struct{}{}) {
+			return "set"
+		}
+		return "dict"
+	case reflect.Func:
+		return "function"
+	case reflect.Struct:
+		if simpleIsTuple(t) {
+			return "tuple"
+		}
+	case reflect.Pointer:
+		if t.Elem().Kind() == reflect.Struct {
+			return t.Elem().Name()
+		}
+	}
+	return t.String()
+}
+
+`,
+}
+
+var typeOfHelper = runtimeHelper{
+	imports: []string{"reflect"},
+	helpers: []string{"simpleTypeName"},
+	source: `// simpleTypeOf returns the type of v as Python prints it, as in
+// <class 'int'>, or <class '__main__.Point'> for a class.
+func simpleTypeOf(v interface{}) string {
+	name := simpleTypeName(v)
+	if t := reflect.TypeOf(v); t != nil && t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct {
+		name = "__main__." + name
+	}
+	return "<class '" + name + "'>"
+}
+
+`,
+}
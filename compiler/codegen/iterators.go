@@ -0,0 +1,119 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+)
+
+// generateIteratorLoop writes a for loop over a Go iterator as the loop Go
+// code would use for it, such as for scanner.Scan() { line := scanner.Text() }.
+// An iterator defined in Simple has its __next__ method called until it
+// raises StopIteration.
+func (cg *CodeGenerator) generateIteratorLoop(file *os.File, fs *parser.ForStatement, loop *semantic.IteratorLoop, prevSymbolTable *semantic.SymbolTable) {
+	// Iterables other than plain names are evaluated once, into simpleIter;
+	// range does that itself for iterator functions
+	iterable := cg.goExpression(fs.Iterable).String()
+	if _, ok := fs.Iterable.(*parser.Identifier); !ok && loop.Kind != semantic.FuncIterator {
+		iterable = "simpleIter"
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "{")
+		cg.indentLevel++
+		cg.writeIndent(file)
+		fmt.Fprint(file, "simpleIter := ")
+		cg.generateExpression(file, fs.Iterable)
+		fmt.Fprintln(file)
+	}
+
+	variable := cg.goName(fs.Variable.Value)
+	if !usesIdentifier(fs.Body, fs.Variable.Value) {
+		variable = "_"
+	}
+
+	cg.writeIndent(file)
+	switch loop.Kind {
+	case semantic.ScanIterator:
+		fmt.Fprintf(file, "for %s.Scan() {\n", iterable)
+		cg.writeLoopVariable(file, variable, iterable+".Text()")
+	case semantic.RowsIterator:
+		fmt.Fprintf(file, "for %s.Next() {\n", iterable)
+		if loop.Value {
+			cg.writeLoopVariable(file, variable, "simpleScanRow(&"+iterable+")")
+		} else {
+			cg.writeLoopVariable(file, variable, "simpleScanRow("+iterable+")")
+		}
+	case semantic.NextIterator, semantic.ClassIterator:
+		next := iterable + ".Next()"
+		if loop.Kind == semantic.ClassIterator {
+			cg.useHelper("simpleNext")
+			next = "simpleNext(" + iterable + ".__next__)"
+		}
+		fmt.Fprintln(file, "for {")
+		cg.indentLevel++
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%s, ok := %s\n", variable, next)
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "if !ok {")
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "\tbreak")
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "}")
+		cg.indentLevel--
+	case semantic.FuncIterator:
+		if variable == "_" {
+			fmt.Fprint(file, "for range ")
+		} else {
+			fmt.Fprintf(file, "for %s := range ", variable)
+		}
+		cg.generateExpression(file, fs.Iterable)
+		fmt.Fprintln(file, " {")
+	}
+
+	cg.indentLevel++
+	cg.generateBlockStatement(file, fs.Body, prevSymbolTable)
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+
+	// Scanners and rows stop on errors as well as at the end
+	if loop.Kind == semantic.ScanIterator || loop.Kind == semantic.RowsIterator {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "if err := %s.Err(); err != nil {\n", iterable)
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "\tpanic(err)")
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "}")
+	}
+
+	if iterable == "simpleIter" {
+		cg.indentLevel--
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "}")
+	}
+}
+
+// writeLoopVariable writes the assignment of the loop variable at the top
+// of an iterator loop body.
+func (cg *CodeGenerator) writeLoopVariable(file *os.File, variable, value string) {
+	cg.indentLevel++
+	cg.writeIndent(file)
+	if variable == "_" {
+		fmt.Fprintf(file, "_ = %s\n", value)
+	} else {
+		fmt.Fprintf(file, "%s := %s\n", variable, value)
+	}
+	cg.indentLevel--
+}
+
+// usesIdentifier reports whether name appears in node.
+func usesIdentifier(node parser.Node, name string) bool {
+	found := false
+	parser.Inspect(node, func(n parser.Node) bool {
+		if ident, ok := n.(*parser.Identifier); ok && ident.Value == name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,30 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+	"strings"
+)
+
+// generateLambdaExpression writes a lambda as a Go function literal
+// returning its body.
+func (cg *CodeGenerator) generateLambdaExpression(file *os.File, le *parser.LambdaExpression) {
+	lambda := cg.analyzer.LambdaOf(le)
+	params := []string{}
+	for i, param := range le.Parameters {
+		params = append(params, cg.goName(param.Value)+" "+cg.typeToGoString(lambda.Type.ParameterTypes[i]))
+	}
+
+	prevTable := cg.analyzer.CurrentTable
+	cg.analyzer.CurrentTable = lambda.Scope
+	defer func() { cg.analyzer.CurrentTable = prevTable }()
+
+	if returnType := lambda.Type.ReturnTypes[0]; returnType.String() != "void" {
+		fmt.Fprintf(file, "func(%s) %s { return ", strings.Join(params, ", "), cg.typeToGoString(returnType))
+	} else {
+		fmt.Fprintf(file, "func(%s) { ", strings.Join(params, ", "))
+	}
+	cg.generateExpression(file, le.Body)
+	fmt.Fprint(file, " }")
+}
@@ -0,0 +1,30 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+)
+
+// generateLen writes a call of len that Go's len can't make: a call of the
+// __len__ or Len method giving the length, a count of the characters of a
+// string, or a call of simpleLen, which measures a value of no known type
+// when the program runs.
+func (cg *CodeGenerator) generateLen(file *os.File, ce *parser.CallExpression, lc *semantic.LenCall) {
+	if lc.Method != nil {
+		cg.generateExpression(file, lc.Method)
+		return
+	}
+	if lc.String {
+		cg.imports["unicode/utf8"] = true
+		fmt.Fprint(file, "utf8.RuneCountInString(")
+		cg.generateExpression(file, ce.Arguments[0])
+		fmt.Fprint(file, ")")
+		return
+	}
+	cg.useHelper("simpleLen")
+	fmt.Fprint(file, "simpleLen(")
+	cg.generateExpression(file, ce.Arguments[0])
+	fmt.Fprintf(file, ", %d, %q)", ce.Token.Line, cg.tracebackFunction())
+}
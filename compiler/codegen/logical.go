@@ -0,0 +1,49 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+)
+
+// generateLogicalExpression writes an and or or expression as Go's && or
+// ||, which short-circuit the same way. Errors are true when set, as in
+// conditions.
+func (cg *CodeGenerator) generateLogicalExpression(file *os.File, ie *parser.InfixExpression) {
+	operator := "&&"
+	if ie.Operator == "or" {
+		operator = "||"
+	}
+	cg.generateLogicalOperand(file, ie.Left, ie.Operator)
+	fmt.Fprintf(file, " %s ", operator)
+	cg.generateLogicalOperand(file, ie.Right, ie.Operator)
+}
+
+// generateLogicalOperand writes an operand of and or or. An or grouped
+// inside an and keeps its parentheses.
+func (cg *CodeGenerator) generateLogicalOperand(file *os.File, expr parser.Expression, operator string) {
+	if inner, ok := expr.(*parser.InfixExpression); ok && inner.Operator == "or" && operator == "and" {
+		fmt.Fprint(file, "(")
+		cg.generateCondition(file, expr)
+		fmt.Fprint(file, ")")
+		return
+	}
+	cg.generateCondition(file, expr)
+}
+
+// generateNot writes not as Go's !. not err is true when there is no error.
+func (cg *CodeGenerator) generateNot(file *os.File, expr parser.Expression) {
+	if cg.isErrorExpression(expr) {
+		cg.generateExpression(file, expr)
+		fmt.Fprint(file, " == nil")
+		return
+	}
+	fmt.Fprint(file, "!")
+	if _, ok := expr.(*parser.InfixExpression); ok {
+		fmt.Fprint(file, "(")
+		cg.generateExpression(file, expr)
+		fmt.Fprint(file, ")")
+		return
+	}
+	cg.generateExpression(file, expr)
+}
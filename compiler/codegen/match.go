@@ -0,0 +1,144 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+)
+
+// matchFrame is a match statement being generated. A break in it leaving a
+// loop around it would only leave the Go switch, so it sets <name>Break and
+// leaves the switch, after which the loop is left.
+type matchFrame struct {
+	name  string // prefix of the statement's variables, such as match1
+	loops int    // loops open inside the statement
+	tries int    // try and with statements open around it
+}
+
+// innermostMatch returns the match statement a break must leave before its
+// loop, or nil if there is none.
+func (cg *CodeGenerator) innermostMatch() *matchFrame {
+	if len(cg.matches) == 0 {
+		return nil
+	}
+	frame := cg.matches[len(cg.matches)-1]
+	if frame.loops > 0 || frame.tries != len(cg.tries) {
+		return nil
+	}
+	return frame
+}
+
+// generateMatchStatement writes a match statement as a switch on its
+// subject, or a type switch when its cases match types. The subject is kept
+// in a variable for the cases binding names to it.
+func (cg *CodeGenerator) generateMatchStatement(file *os.File, ms *parser.MatchStatement, prevSymbolTable *semantic.SymbolTable) {
+	m, ok := cg.analyzer.Matches[ms]
+	if !ok {
+		return
+	}
+	cg.matchCount++
+	frame := &matchFrame{name: fmt.Sprintf("match%d", cg.matchCount), tries: len(cg.tries)}
+	flows := map[int]bool{}
+	for _, block := range ms.Blocks() {
+		addFlows(flows, block.Statements, false)
+	}
+	if flows[tryBreak] {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%sBreak := false\n", frame.name)
+	}
+
+	bound := false
+	for _, mc := range ms.Cases {
+		for _, name := range caseNames(mc) {
+			bound = bound || usesIdentifier(mc.Body, name.Value)
+		}
+	}
+	cg.writeIndent(file)
+	switch {
+	case m.Types && bound:
+		fmt.Fprintf(file, "switch %s := any(", frame.name)
+		cg.generateExpression(file, ms.Subject)
+		fmt.Fprintln(file, ").(type) {")
+	case m.Types:
+		fmt.Fprint(file, "switch any(")
+		cg.generateExpression(file, ms.Subject)
+		fmt.Fprintln(file, ").(type) {")
+	case bound:
+		fmt.Fprintf(file, "switch %s := ", frame.name)
+		cg.generateExpression(file, ms.Subject)
+		fmt.Fprintf(file, "; %s {\n", frame.name)
+	default:
+		fmt.Fprint(file, "switch ")
+		cg.generateExpression(file, ms.Subject)
+		fmt.Fprintln(file, " {")
+	}
+
+	cg.matches = append(cg.matches, frame)
+	for _, mc := range ms.Cases {
+		cg.writeIndent(file)
+		cg.writeCase(file, mc, m)
+		cg.indentLevel++
+		prevTable := cg.analyzer.CurrentTable
+		if scope, ok := m.Scopes[mc]; ok {
+			cg.analyzer.CurrentTable = scope
+			for _, name := range caseNames(mc) {
+				if usesIdentifier(mc.Body, name.Value) {
+					cg.writeIndent(file)
+					fmt.Fprintf(file, "%s := %s\n", cg.goName(name.Value), frame.name)
+				}
+			}
+		}
+		cg.generateBlockStatement(file, mc.Body, prevSymbolTable)
+		cg.analyzer.CurrentTable = prevTable
+		cg.indentLevel--
+	}
+	cg.matches = cg.matches[:len(cg.matches)-1]
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+
+	if flows[tryBreak] {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "if %sBreak {\n", frame.name)
+		cg.indentLevel++
+		cg.generateExit(file, tryBreak, nil)
+		cg.indentLevel--
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "}")
+	}
+}
+
+// writeCase writes the case clause of a case of a match statement: the
+// values or types it matches, or default for one matching anything.
+func (cg *CodeGenerator) writeCase(file *os.File, mc *parser.MatchCase, m *semantic.Match) {
+	if len(mc.Patterns) == 1 && mc.Patterns[0].Value == nil && mc.Patterns[0].Type == nil {
+		fmt.Fprintln(file, "default:")
+		return
+	}
+	fmt.Fprint(file, "case ")
+	for i, pattern := range mc.Patterns {
+		if i > 0 {
+			fmt.Fprint(file, ", ")
+		}
+		if pattern.Type != nil {
+			fmt.Fprint(file, cg.typeToGoString(m.Patterns[pattern]))
+		} else {
+			cg.generateExpression(file, pattern.Value)
+		}
+	}
+	fmt.Fprintln(file, ":")
+}
+
+// caseNames returns the names a case binds to the subject.
+func caseNames(mc *parser.MatchCase) []*parser.Identifier {
+	names := []*parser.Identifier{}
+	for _, pattern := range mc.Patterns {
+		if pattern.Capture != nil {
+			names = append(names, pattern.Capture)
+		}
+	}
+	if mc.Name != nil {
+		names = append(names, mc.Name)
+	}
+	return names
+}
@@ -0,0 +1,47 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+)
+
+// generateMembership writes item in container as the analyzer chose: a map
+// lookup, slices.Contains or strings.Contains, or simpleIn when Go can't
+// look for the item directly. item not in container is its negation.
+func (cg *CodeGenerator) generateMembership(file *os.File, ie *parser.InfixExpression) {
+	m, ok := cg.analyzer.Memberships[ie]
+	if !ok {
+		return
+	}
+	if ie.Operator == "not in" {
+		fmt.Fprint(file, "!")
+	}
+	switch {
+	case m.Kind == "string" && m.Direct:
+		cg.imports["strings"] = true
+		fmt.Fprint(file, "strings.Contains(")
+		cg.generateExpression(file, ie.Right)
+		fmt.Fprint(file, ", ")
+		cg.generateExpression(file, ie.Left)
+	case m.Kind == "dict" && m.Direct:
+		cg.useHelper("simpleHasKey")
+		fmt.Fprint(file, "simpleHasKey(")
+		cg.generateExpression(file, ie.Right)
+		fmt.Fprint(file, ", ")
+		cg.generateExpression(file, ie.Left)
+	case m.Kind == "list" && m.Direct:
+		cg.imports["slices"] = true
+		fmt.Fprint(file, "slices.Contains(")
+		cg.generateExpression(file, ie.Right)
+		fmt.Fprint(file, ", ")
+		cg.generateExpression(file, ie.Left)
+	default:
+		cg.useHelper("simpleIn")
+		fmt.Fprint(file, "simpleIn(")
+		cg.generateExpression(file, ie.Left)
+		fmt.Fprint(file, ", ")
+		cg.generateExpression(file, ie.Right)
+	}
+	fmt.Fprint(file, ")")
+}
@@ -0,0 +1,188 @@
+package codegen
+
+import (
+	"github.com/sasogeek/simple/compiler/parser"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Go names
+//
+// A Simple name can be one Go reserves. A Go keyword such as type or func
+// can't name anything in Go, and a variable or function of the program
+// named after a predeclared identifier such as len or string, or after a
+// package the generated code uses such as fmt, would hide it from the code
+// around it. A function named main or init would be taken for the Go
+// functions a program starts with. Such names are given an underscore, or as many as keep them
+// apart from the program's other names, in the generated code. Fields and
+// methods only need to avoid the keywords.
+//
+// The renamed names are recorded so that messages about the generated
+// code, such as those of the Go compiler, can be given in Simple names.
+
+// goKeywords are the keywords of Go.
+var goKeywords = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true,
+	"func": true, "go": true, "goto": true, "if": true, "import": true,
+	"interface": true, "map": true, "package": true, "range": true, "return": true,
+	"select": true, "struct": true, "switch": true, "type": true, "var": true,
+}
+
+// goPredeclared are the predeclared identifiers of Go.
+var goPredeclared = map[string]bool{
+	"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true,
+	"complex128": true, "error": true, "float32": true, "float64": true, "int": true,
+	"int8": true, "int16": true, "int32": true, "int64": true, "rune": true,
+	"string": true, "uint": true, "uint8": true, "uint16": true, "uint32": true,
+	"uint64": true, "uintptr": true, "true": true, "false": true, "iota": true,
+	"nil": true, "append": true, "cap": true, "clear": true, "close": true,
+	"complex": true, "copy": true, "delete": true, "imag": true, "len": true,
+	"make": true, "max": true, "min": true, "new": true, "panic": true,
+	"print": true, "println": true, "real": true, "recover": true,
+}
+
+// generatedPackages are the packages the generated code refers to outside
+// the runtime helpers.
+var generatedPackages = []string{"cmp", "fmt", "maps", "math", "os", "slices", "strconv", "strings"}
+
+// renamed maps the Go names given to Simple names to the Simple names, for
+// every file generated.
+var renamed = map[string]string{}
+
+// mainRenames are the Go names given to the names of the main program, for
+// hot reload plugins to export its functions by.
+var mainRenames = map[string]string{}
+
+// reservedNames returns the names a variable or function of the program
+// can't have in Go: main, init, the predeclared identifiers and the
+// packages of the generated code and its runtime helpers.
+func reservedNames() map[string]bool {
+	reserved := map[string]bool{"main": true, "init": true}
+	for name := range goPredeclared {
+		reserved[name] = true
+	}
+	for _, pkg := range generatedPackages {
+		reserved[pkg] = true
+	}
+	for _, helper := range runtimeHelpers {
+		for _, imp := range helper.imports {
+			reserved[path.Base(imp)] = true
+		}
+	}
+	return reserved
+}
+
+// collectNames records the names the program uses, which renamed names
+// must stay apart from, and those it declares.
+func (cg *CodeGenerator) collectNames(program *parser.Program) {
+	cg.names, cg.declared = map[string]bool{}, map[string]bool{}
+	parser.Inspect(program, func(n parser.Node) bool {
+		if ident, ok := n.(*parser.Identifier); ok && ident != nil {
+			cg.names[ident.Value] = true
+		}
+		for _, ident := range parser.Bindings(n) {
+			cg.names[ident.Value] = true
+			cg.declared[ident.Value] = true
+		}
+		return true
+	})
+}
+
+// goName returns the Go name of a variable, parameter or function the
+// program declares.
+func (cg *CodeGenerator) goName(name string) string {
+	if !goKeywords[name] && !cg.reserved[name] {
+		return name
+	}
+	if goName, ok := cg.renames[name]; ok {
+		return goName
+	}
+	goName := name + "_"
+	for cg.names[goName] {
+		goName += "_"
+	}
+	cg.renames[name] = goName
+	renamed[goName] = name
+	return goName
+}
+
+// identifierName returns the Go name of an identifier used in an
+// expression. A reserved name is only renamed when it refers to something
+// the program declares, rather than to the Go identifier or package.
+func (cg *CodeGenerator) identifierName(name string) string {
+	if goKeywords[name] {
+		return cg.goName(name)
+	}
+	if !cg.reserved[name] || !cg.declared[name] {
+		return name
+	}
+	symbol, ok := cg.analyzer.CurrentTable.Resolve(name)
+	if !ok || symbol.Scope == "builtin" || symbol.Scope == "imported" {
+		return name
+	}
+	return cg.goName(name)
+}
+
+// memberName returns the Go name of a field or method.
+func (cg *CodeGenerator) memberName(name string) string {
+	if goKeywords[name] {
+		return cg.goName(name)
+	}
+	return name
+}
+
+// goExpression returns a copy of an assignment target or index with the
+// names in it given their Go names, for writing it with String.
+func (cg *CodeGenerator) goExpression(expr parser.Expression) parser.Expression {
+	switch e := expr.(type) {
+	case *parser.Identifier:
+		return &parser.Identifier{Token: e.Token, Value: cg.identifierName(e.Value)}
+	case *parser.SelectorExpression:
+		return &parser.SelectorExpression{
+			Token:    e.Token,
+			Left:     cg.goExpression(e.Left),
+			Selector: &parser.Identifier{Token: e.Selector.Token, Value: cg.memberName(e.Selector.Value)},
+		}
+	case *parser.IndexExpression:
+		copied := *e
+		copied.Left = cg.goExpression(e.Left)
+		copied.Index = cg.goExpression(e.Index)
+		copied.End = cg.goExpression(e.End)
+		return &copied
+	case *parser.CallExpression:
+		// An object used as a key is constructed by its NewX function
+		ident, ok := e.Function.(*parser.Identifier)
+		if !ok || cg.analyzer.Classes[ident.Value] == nil {
+			return expr
+		}
+		copied := *e
+		copied.Function = &parser.Identifier{Token: ident.Token, Value: "New" + ident.Value}
+		copied.Arguments = []parser.Expression{}
+		for _, arg := range e.Arguments {
+			copied.Arguments = append(copied.Arguments, cg.goExpression(arg))
+		}
+		return &copied
+	}
+	return expr
+}
+
+// SimpleNames rewrites the Go names given to Simple names in a message
+// about the generated code, such as the Go compiler's, to the Simple names.
+func SimpleNames(message string) string {
+	if len(renamed) == 0 {
+		return message
+	}
+	names := []string{}
+	for goName := range renamed {
+		names = append(names, regexp.QuoteMeta(goName))
+	}
+	// Longer names first, so type__ isn't read as type_
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	pattern := regexp.MustCompile(`\b(` + strings.Join(names, "|") + `)\b`)
+	return pattern.ReplaceAllStringFunc(message, func(goName string) string {
+		return renamed[goName]
+	})
+}
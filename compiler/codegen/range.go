@@ -0,0 +1,120 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+)
+
+// generateRangeLoop writes for i in range(...): as a Go loop counting in
+// ints: range(stop) as for i := range stop, and the others as a three-part
+// for loop, comparing with < or > as the step is positive or negative.
+// Python works out the stop and step once, before the loop, so a stop the
+// body may change, or a step that isn't a constant, is evaluated into
+// simpleStop or simpleStep in a block of its own.
+func (cg *CodeGenerator) generateRangeLoop(file *os.File, fs *parser.ForStatement, r *semantic.Range, prevSymbolTable *semantic.SymbolTable) {
+	variable := cg.goName(fs.Variable.Value)
+	if r.Start == nil {
+		cg.writeIndent(file)
+		if usesIdentifier(fs.Body, fs.Variable.Value) {
+			fmt.Fprintf(file, "for %s := range ", variable)
+		} else {
+			fmt.Fprint(file, "for range ")
+		}
+		cg.generateInt(file, r.Stop)
+		fmt.Fprintln(file, " {")
+		cg.generateRangeBody(file, fs, prevSymbolTable)
+		return
+	}
+
+	block := false
+	declare := func(name string, value parser.Expression) string {
+		if !block {
+			block = true
+			cg.writeIndent(file)
+			fmt.Fprintln(file, "{")
+			cg.indentLevel++
+		}
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%s := ", name)
+		cg.generateInt(file, value)
+		fmt.Fprintln(file)
+		return name
+	}
+	stop := ""
+	switch s := r.Stop.(type) {
+	case *parser.Identifier:
+		if usesIdentifier(fs.Body, s.Value) {
+			stop = declare("simpleStop", r.Stop)
+		}
+	default:
+		if _, constant := semantic.ConstantInt(s); !constant {
+			stop = declare("simpleStop", r.Stop)
+		}
+	}
+	step := ""
+	if r.StepValue == 0 {
+		step = declare("simpleStep", r.Step)
+	}
+
+	cg.writeIndent(file)
+	fmt.Fprintf(file, "for %s := ", variable)
+	cg.generateInt(file, r.Start)
+	fmt.Fprint(file, "; ")
+	writeStop := func() {
+		if stop != "" {
+			fmt.Fprint(file, stop)
+		} else {
+			cg.generateInt(file, r.Stop)
+		}
+	}
+	switch {
+	case r.StepValue > 0:
+		fmt.Fprintf(file, "%s < ", variable)
+		writeStop()
+	case r.StepValue < 0:
+		fmt.Fprintf(file, "%s > ", variable)
+		writeStop()
+	default:
+		fmt.Fprintf(file, "(%s > 0 && %s < ", step, variable)
+		writeStop()
+		fmt.Fprintf(file, ") || (%s < 0 && %s > ", step, variable)
+		writeStop()
+		fmt.Fprint(file, ")")
+	}
+	switch {
+	case r.StepValue == 1:
+		fmt.Fprintf(file, "; %s++ {\n", variable)
+	case r.StepValue == -1:
+		fmt.Fprintf(file, "; %s-- {\n", variable)
+	case r.StepValue > 0:
+		fmt.Fprintf(file, "; %s += %d {\n", variable, r.StepValue)
+	case r.StepValue < 0:
+		fmt.Fprintf(file, "; %s -= %d {\n", variable, -r.StepValue)
+	default:
+		fmt.Fprintf(file, "; %s += %s {\n", variable, step)
+	}
+	cg.generateRangeBody(file, fs, prevSymbolTable)
+
+	if block {
+		cg.indentLevel--
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "}")
+	}
+}
+
+// generateRangeBody writes the body of a loop over range() and closes it.
+func (cg *CodeGenerator) generateRangeBody(file *os.File, fs *parser.ForStatement, prevSymbolTable *semantic.SymbolTable) {
+	cg.indentLevel++
+	cg.generateBlockStatement(file, fs.Body, prevSymbolTable)
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+}
+
+// generateInt writes an argument of range(), asserted to an int when it is
+// untyped, as an unannotated parameter is.
+func (cg *CodeGenerator) generateInt(file *os.File, value parser.Expression) {
+	cg.generateAsserted(file, value, &parser.BasicType{Name: "int"})
+}
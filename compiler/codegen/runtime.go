@@ -0,0 +1,1607 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"go/types"
+	"os"
+	"sort"
+)
+
+// Runtime helpers
+//
+// Some Simple features need more Go than fits in an expression. Their
+// helpers are appended to the generated file that uses them, together with
+// the imports they need, rather than living in a package of their own that
+// every program would have to depend on.
+
+// runtimeHelper is the source of a helper, the packages it imports and the
+// other helpers it calls.
+type runtimeHelper struct {
+	imports []string
+	helpers []string
+	source  string
+}
+
+// runtimeHelpers are the helpers by name.
+var runtimeHelpers = map[string]runtimeHelper{
+	"simpleBest":        bestHelper,
+	"simpleCompare":     compareHelper,
+	"simpleCopy":        copyHelper,
+	"simpleEqual":       equalHelper,
+	"simpleErrorString": errorStringHelper,
+	"simpleException":   exceptionHelper,
+	"simpleFloorDiv":    floorDivHelper,
+	"simpleFloorDivAny": floorDivAnyHelper,
+	"simpleGroup":       groupHelper,
+	"simpleHasKey":      hasKeyHelper,
+	"simpleIn":          inHelper,
+	"simpleIndex":       indexHelper,
+	"simpleInput":       inputHelper,
+	"simpleIntPow":      intPowHelper,
+	"simplePowAny":      powAnyHelper,
+	"simpleIsKind":      isKindHelper,
+	"simpleIsTuple":     tupleHelper,
+	"simpleLen":         lenHelper,
+	"simpleItems":       itemsHelper,
+	"simpleKey":         keyHelper,
+	"simpleNext":        nextHelper,
+	"simpleNumber":      numberHelper,
+	"simpleOpen":        openHelper,
+	"simpleRound":       roundHelper,
+	"simpleScanRow":     scanRowHelper,
+	"simpleServe":       serveHelper,
+	"simpleSet":         setHelper,
+	"simpleSorted":      sortedHelper,
+	"simpleStr":         strHelper,
+	"simpleSum":         sumHelper,
+	"simpleSumAny":      sumAnyHelper,
+	"simpleToDict":      toDictHelper,
+	"simpleToFloat":     toFloatHelper,
+	"simpleToStruct":    toStructHelper,
+	"simpleTrace":       traceHelper,
+	"simpleTypeName":    typeNameHelper,
+	"simpleTypeOf":      typeOfHelper,
+}
+
+// useHelper marks a helper as used, registering the packages it imports.
+func (cg *CodeGenerator) useHelper(name string) {
+	cg.helpers[name] = true
+	for _, imp := range runtimeHelpers[name].imports {
+		cg.imports[imp] = true
+	}
+	for _, helper := range runtimeHelpers[name].helpers {
+		cg.useHelper(helper)
+	}
+}
+
+// collectHelpers marks the helpers the analysis shows the program needs.
+// Other helpers are marked as the code using them is generated.
+func (cg *CodeGenerator) collectHelpers() {
+	if len(cg.analyzer.StructConversions) > 0 {
+		cg.useHelper("simpleToStruct")
+		for _, sc := range cg.analyzer.StructConversions {
+			types.TypeString(sc.GoType, cg.qualify)
+		}
+	}
+
+	for _, loop := range cg.analyzer.IteratorLoops {
+		if loop.Kind == semantic.RowsIterator {
+			cg.useHelper("simpleScanRow")
+		}
+	}
+}
+
+// writeHelpers writes the helpers the generated code used.
+func (cg *CodeGenerator) writeHelpers(file *os.File) {
+	names := make([]string, 0, len(cg.helpers))
+	for name := range cg.helpers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		file.WriteString(runtimeHelpers[name].source)
+	}
+}
+
+// generateStructConversion writes a to_struct call as a call to simpleToStruct.
+func (cg *CodeGenerator) generateStructConversion(file *os.File, ce *parser.CallExpression, sc *semantic.StructConversion) {
+	typeName := types.TypeString(sc.GoType, cg.qualify)
+	if sc.Pointer {
+		typeName = "*" + typeName
+	}
+	fmt.Fprintf(file, "simpleToStruct[%s](", typeName)
+	cg.generateExpression(file, ce.Arguments[0])
+	fmt.Fprint(file, ")")
+}
+
+// generatePrintArgument writes an argument of print formatted the way
+// Python prints it. Strings need no formatting and errors are printed by
+// their message.
+func (cg *CodeGenerator) generatePrintArgument(file *os.File, arg parser.Expression) {
+	switch {
+	case cg.getExpressionType(arg).String() == "string":
+		cg.generateExpression(file, arg)
+	case cg.isErrorExpression(arg):
+		cg.generateErrorString(file, arg)
+	default:
+		cg.generateFormatCall(file, "str", arg)
+	}
+}
+
+// generateFormatCall writes a call of the str or repr builtin.
+func (cg *CodeGenerator) generateFormatCall(file *os.File, name string, arg parser.Expression) {
+	if name == "str" && cg.isErrorExpression(arg) {
+		cg.generateErrorString(file, arg)
+		return
+	}
+	if name == "str" && cg.generateFastString(file, arg) {
+		return
+	}
+	cg.useHelper("simpleStr")
+	if name == "str" {
+		fmt.Fprint(file, "simpleStr(")
+	} else {
+		fmt.Fprint(file, "simpleRepr(")
+	}
+	cg.generateExpression(file, arg)
+	fmt.Fprint(file, ")")
+}
+
+// generateCopy writes a call of the copy or deepcopy builtin.
+func (cg *CodeGenerator) generateCopy(file *os.File, name string, arg parser.Expression) {
+	cg.useHelper("simpleCopy")
+	if name == "copy" {
+		fmt.Fprint(file, "simpleCopy(")
+	} else {
+		fmt.Fprint(file, "simpleDeepCopy(")
+	}
+	cg.generateExpression(file, arg)
+	fmt.Fprint(file, ")")
+}
+
+// generateRound writes a call of round. Without digits it rounds to an int
+// with math.RoundToEven, as Python rounds halfway cases to the even number;
+// with digits it calls simpleRound.
+func (cg *CodeGenerator) generateRound(file *os.File, ce *parser.CallExpression) {
+	x := ce.Arguments[0]
+	kind := goTypeName(cg.analyzer.InferExpressionTypes(x, false)[0].String())
+
+	if len(ce.Arguments) == 1 {
+		if kind == "int" {
+			cg.generateExpression(file, x)
+			return
+		}
+		cg.imports["math"] = true
+		fmt.Fprint(file, "int(math.RoundToEven(")
+		cg.generateFloat(file, x)
+		fmt.Fprint(file, "))")
+		return
+	}
+	cg.useHelper("simpleRound")
+	if kind == "int" {
+		fmt.Fprint(file, "int(")
+	}
+	fmt.Fprint(file, "simpleRound(")
+	cg.generateFloat(file, x)
+	fmt.Fprint(file, ", ")
+	cg.generateExpression(file, ce.Arguments[1])
+	fmt.Fprint(file, ")")
+	if kind == "int" {
+		fmt.Fprint(file, ")")
+	}
+}
+
+// generateInput writes a call of input as a call to simpleInput, with the
+// prompt formatted by str when it isn't a string.
+func (cg *CodeGenerator) generateInput(file *os.File, ce *parser.CallExpression) {
+	cg.useHelper("simpleInput")
+	fmt.Fprint(file, "simpleInput(")
+	switch {
+	case len(ce.Arguments) == 0:
+		fmt.Fprint(file, `""`)
+	case cg.getExpressionType(ce.Arguments[0]).String() == "string":
+		cg.generateExpression(file, ce.Arguments[0])
+	default:
+		cg.generateFormatCall(file, "str", ce.Arguments[0])
+	}
+	fmt.Fprintf(file, ", %d, %q)", ce.Token.Line, cg.tracebackFunction())
+}
+
+var inputHelper = runtimeHelper{
+	imports: []string{"bufio", "fmt", "io", "os", "strings"},
+	helpers: []string{"simpleException"},
+	source: `// simpleStdin buffers standard input for simpleInput.
+var simpleStdin = bufio.NewReader(os.Stdin)
+
+// simpleInput writes the prompt and returns the next line of standard
+// input without its line ending, as Python's input does. At the end of
+// the input it raises the EOFError Python raises.
+func simpleInput(prompt string, line int, function string) string {
+	fmt.Print(prompt)
+	text, err := simpleStdin.ReadString('\n')
+	if err == io.EOF && text == "" {
+		panic(simpleRaise("EOFError", "EOF when reading a line", line, function))
+	}
+	if err != nil && err != io.EOF {
+		panic(simpleRaise("OSError", err.Error(), line, function))
+	}
+	text = strings.TrimSuffix(text, "\n")
+	return strings.TrimSuffix(text, "\r")
+}
+
+`,
+}
+
+var toStructHelper = runtimeHelper{
+	imports: []string{"fmt", "reflect", "strings"},
+	source: `// simpleToStruct builds a T, a struct or a pointer to one, from a dict.
+// Keys match field names ignoring case and underscores, so "read_timeout"
+// sets ReadTimeout. Dicts and lists nested in the dict fill nested structs,
+// slices and maps.
+func simpleToStruct[T any](d interface{}) T {
+	var out T
+	if err := simpleFill(reflect.ValueOf(&out).Elem(), reflect.ValueOf(d)); err != nil {
+		panic(fmt.Sprintf("to_struct: %v", err))
+	}
+	return out
+}
+
+func simpleFieldKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+func simpleFill(dst, src reflect.Value) error {
+	for src.Kind() == reflect.Interface && !src.IsNil() {
+		src = src.Elem()
+	}
+	if !src.IsValid() || (src.Kind() == reflect.Interface && src.IsNil()) {
+		return nil
+	}
+	switch {
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+	case dst.Kind() == reflect.Pointer:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return simpleFill(dst.Elem(), src)
+	case dst.Kind() == reflect.Struct && src.Kind() == reflect.Map:
+		fields := map[string]int{}
+		for i := 0; i < dst.NumField(); i++ {
+			if dst.Type().Field(i).IsExported() {
+				fields[simpleFieldKey(dst.Type().Field(i).Name)] = i
+			}
+		}
+		iter := src.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			i, ok := fields[simpleFieldKey(key)]
+			if !ok {
+				return fmt.Errorf("%s has no field '%s'", dst.Type(), key)
+			}
+			if err := simpleFill(dst.Field(i), iter.Value()); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	case dst.Kind() == reflect.Slice && (src.Kind() == reflect.Slice || src.Kind() == reflect.Array):
+		out := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if err := simpleFill(out.Index(i), src.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		dst.Set(out)
+	case dst.Kind() == reflect.Map && src.Kind() == reflect.Map:
+		out := reflect.MakeMapWithSize(dst.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			key := reflect.New(dst.Type().Key()).Elem()
+			if err := simpleFill(key, iter.Key()); err != nil {
+				return err
+			}
+			value := reflect.New(dst.Type().Elem()).Elem()
+			if err := simpleFill(value, iter.Value()); err != nil {
+				return fmt.Errorf("%v: %w", iter.Key().Interface(), err)
+			}
+			out.SetMapIndex(key, value)
+		}
+		dst.Set(out)
+	case src.Type().ConvertibleTo(dst.Type()) && (dst.Kind() == reflect.String) == (src.Kind() == reflect.String):
+		// Numbers convert to numbers, such as an int to a time.Duration,
+		// but not to strings
+		dst.Set(src.Convert(dst.Type()))
+	default:
+		return fmt.Errorf("cannot use %v (%s) as %s", src.Interface(), src.Type(), dst.Type())
+	}
+	return nil
+}
+
+`,
+}
+
+var toDictHelper = runtimeHelper{
+	imports: []string{"reflect", "strings"},
+	source: `// simpleToDict turns a Go struct, or a pointer to one, into a dict with a
+// key for each exported field: the name in its json tag, which for
+// protobuf messages is the field's name in the .proto file, or else the
+// field's name. Nested structs become dicts and slices lists; other values
+// are kept as they are.
+func simpleToDict(v interface{}) map[string]interface{} {
+	d, _ := simpleDictValue(reflect.ValueOf(v)).(map[string]interface{})
+	return d
+}
+
+func simpleDictValue(v reflect.Value) interface{} {
+	original := v
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Struct:
+		d := map[string]interface{}{}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			key := field.Name
+			if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag == "-" {
+				continue
+			} else if tag != "" {
+				key = tag
+			}
+			d[key] = simpleDictValue(v.Field(i))
+		}
+		if len(d) == 0 && v.NumField() > 0 {
+			// Structs with only unexported fields, such as time.Time,
+			// are values rather than records
+			return original.Interface()
+		}
+		return d
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = simpleDictValue(v.Index(i))
+		}
+		return items
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return v.Interface()
+		}
+		d := map[string]interface{}{}
+		iter := v.MapRange()
+		for iter.Next() {
+			d[iter.Key().String()] = simpleDictValue(iter.Value())
+		}
+		return d
+	}
+	return v.Interface()
+}
+
+`,
+}
+
+var exceptionHelper = runtimeHelper{
+	imports: []string{"fmt", "os", "runtime", "strings"},
+	helpers: []string{"simpleErrorString"},
+	source: `// simpleException is an exception, with the name of its class and, for
+// one raised by a raise statement, where it was raised. Its message is its
+// error message.
+type simpleException struct {
+	class    string
+	message  string
+	line     int
+	function string
+}
+
+func (e *simpleException) Error() string {
+	return e.message
+}
+
+// matches reports whether the exception is of one of the classes.
+func (e *simpleException) matches(classes ...string) bool {
+	for _, class := range classes {
+		if e.class == class {
+			return true
+		}
+	}
+	return false
+}
+
+// simpleRaise makes the exception of a raise statement.
+func simpleRaise(class, message string, line int, function string) *simpleException {
+	return &simpleException{class: class, message: message, line: line, function: function}
+}
+
+// simpleRaiseError makes the exception of a raise statement that raises an
+// error. An exception bound by an except clause is raised as it is.
+func simpleRaiseError(err error, line int, function string) *simpleException {
+	if e, ok := err.(*simpleException); ok {
+		return e
+	}
+	return simpleRaise("Exception", simpleErrorString(err), line, function)
+}
+
+// simpleCatch turns a value a try statement recovered into an exception.
+// Go runtime errors become the exceptions Python raises for the same
+// mistakes, such as an IndexError for an index out of range, and other
+// panics become an Exception.
+func simpleCatch(r interface{}) *simpleException {
+	switch r := r.(type) {
+	case *simpleException:
+		return r
+	case runtime.Error:
+		message := strings.TrimPrefix(r.Error(), "runtime error: ")
+		switch {
+		case strings.Contains(message, "divide by zero"):
+			return &simpleException{class: "ZeroDivisionError", message: "division by zero"}
+		case strings.Contains(message, "out of range"):
+			return &simpleException{class: "IndexError", message: message}
+		case strings.Contains(message, "interface conversion"):
+			return &simpleException{class: "TypeError", message: message}
+		case strings.Contains(message, "nil pointer"), strings.Contains(message, "nil map"):
+			return &simpleException{class: "AttributeError", message: message}
+		}
+		return &simpleException{class: "RuntimeError", message: message}
+	case error:
+		return &simpleException{class: "Exception", message: r.Error()}
+	}
+	return &simpleException{class: "Exception", message: fmt.Sprint(r)}
+}
+
+// simpleUncaught reports an exception raised by the program that nothing
+// caught as Python does, with where it was raised, and exits. Other panics
+// carry on.
+func simpleUncaught() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	e, ok := r.(*simpleException)
+	if !ok {
+		panic(r)
+	}
+	fmt.Fprintln(os.Stderr, "Traceback (most recent call last):")
+	fmt.Fprintf(os.Stderr, "  line %d, in %s\n", e.line, e.function)
+	if e.message == "" {
+		fmt.Fprintln(os.Stderr, e.class)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", e.class, e.message)
+	}
+	os.Exit(1)
+}
+
+`,
+}
+
+var scanRowHelper = runtimeHelper{
+	source: `// simpleScanRow reads the current row of a query as a list of column
+// values, with text columns as strings rather than bytes.
+func simpleScanRow(rows interface {
+	Columns() ([]string, error)
+	Scan(dest ...any) error
+}) []interface{} {
+	columns, err := rows.Columns()
+	if err != nil {
+		panic(err)
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		panic(err)
+	}
+	for i, value := range values {
+		if b, ok := value.([]byte); ok {
+			values[i] = string(b)
+		}
+	}
+	return values
+}
+
+`,
+}
+
+var errorStringHelper = runtimeHelper{
+	source: `// simpleErrorString formats an error as its message, or None when there is
+// no error. Error methods that panic, typically on a nil pointer stored in
+// the error, count as no error rather than printing a panic message.
+func simpleErrorString(err error) (s string) {
+	if err == nil {
+		return "None"
+	}
+	defer func() {
+		if recover() != nil {
+			s = "None"
+		}
+	}()
+	return err.Error()
+}
+
+`,
+}
+
+var strHelper = runtimeHelper{
+	imports: []string{"fmt", "math", "reflect", "sort", "strconv", "strings"},
+	helpers: []string{"simpleErrorString", "simpleIsTuple", "simpleNumber"},
+	source: `// simpleStr formats a value the way Python's str does: True, False and
+// None, floats with a decimal point, and lists, dicts and sets with their
+// items formatted by simpleRepr. Dict keys and the items of sets are sorted,
+// as Go maps have no order. Values with a String method, the __str__ of a
+// class, are formatted by it.
+func simpleStr(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return simpleErrorString(v)
+	case fmt.Stringer:
+		return simpleCall(v.String)
+	}
+	return simpleRepr(v)
+}
+
+// simpleRepr formats a value the way Python's repr does, quoting strings.
+// Values with a Repr method, the __repr__ of a class, are formatted by it,
+// and Go values with a String method by that.
+func simpleRepr(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "None"
+	case interface{ Repr() string }:
+		return simpleCall(v.Repr)
+	case error:
+		return simpleErrorString(v)
+	case fmt.Stringer:
+		return simpleCall(v.String)
+	}
+	return simpleFormatValue(reflect.ValueOf(v))
+}
+
+// simpleCall calls a formatting method, formatting a nil pointer receiver
+// that the method can't handle as None.
+func simpleCall(format func() string) (s string) {
+	defer func() {
+		if recover() != nil {
+			s = "None"
+		}
+	}()
+	return format()
+}
+
+func simpleFormatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return "None"
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return "None"
+		}
+		if v.Kind() == reflect.Pointer && v.Elem().Kind() != reflect.Struct {
+			return simpleRepr(v.Elem().Interface())
+		}
+		// An instance of a class defined in the program
+		if v.Kind() == reflect.Pointer && v.Elem().Type().PkgPath() == "main" {
+			return fmt.Sprintf("<__main__.%s object at %#x>", v.Elem().Type().Name(), v.Pointer())
+		}
+	case reflect.Bool:
+		if v.Bool() {
+			return "True"
+		}
+		return "False"
+	case reflect.Float32, reflect.Float64:
+		return simpleFloat(v.Float(), v.Type().Bits())
+	case reflect.String:
+		return simpleQuote(v.String())
+	case reflect.Slice, reflect.Array:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = simpleRepr(v.Index(i).Interface())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Struct:
+		if simpleIsTuple(v.Type()) {
+			items := make([]string, v.NumField())
+			for i := range items {
+				items[i] = simpleRepr(v.Field(i).Interface())
+			}
+			if len(items) == 1 {
+				return "(" + items[0] + ",)"
+			}
+			return "(" + strings.Join(items, ", ") + ")"
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return simpleLess(keys[i], keys[j]) })
+		items := make([]string, len(keys))
+		// A set is a map to empty structs
+		if elem := v.Type().Elem(); elem.Kind() == reflect.Struct && elem.NumField() == 0 {
+			if len(keys) == 0 {
+				return "set()"
+			}
+			for i, key := range keys {
+				items[i] = simpleRepr(key.Interface())
+			}
+			return "{" + strings.Join(items, ", ") + "}"
+		}
+		for i, key := range keys {
+			items[i] = simpleRepr(key.Interface()) + ": " + simpleRepr(v.MapIndex(key).Interface())
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	if v.CanInterface() {
+		return fmt.Sprint(v.Interface())
+	}
+	return fmt.Sprint(v)
+}
+
+// simpleFloat formats a float like Python: 3.0 rather than 3, and exponents
+// only for very large or very small numbers.
+func simpleFloat(f float64, bits int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-4 || abs >= 1e16) {
+		return strconv.FormatFloat(f, 'e', -1, bits)
+	}
+	s := strconv.FormatFloat(f, 'f', -1, bits)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// simpleQuote quotes a string like Python, in single quotes unless the
+// string contains single quotes and no double quotes.
+func simpleQuote(s string) string {
+	quote := '\''
+	if strings.ContainsRune(s, '\'') && !strings.ContainsRune(s, '"') {
+		quote = '"'
+	}
+	var b strings.Builder
+	b.WriteRune(quote)
+	for _, r := range s {
+		switch {
+		case r == quote || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString("\\n")
+		case r == '\r':
+			b.WriteString("\\r")
+		case r == '\t':
+			b.WriteString("\\t")
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteRune(quote)
+	return b.String()
+}
+
+// simpleLess orders dict keys: numbers by value, everything else by its repr.
+func simpleLess(a, b reflect.Value) bool {
+	for a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+	if x, ok := simpleNumber(a); ok {
+		if y, ok := simpleNumber(b); ok {
+			return x < y
+		}
+	}
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return a.String() < b.String()
+	}
+	return simpleFormatValue(a) < simpleFormatValue(b)
+}
+
+`,
+}
+
+var numberHelper = runtimeHelper{
+	imports: []string{"reflect"},
+	source: `// simpleNumber returns the value of a number of any Go numeric type.
+func simpleNumber(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+`,
+}
+
+var equalHelper = runtimeHelper{
+	imports: []string{"reflect"},
+	helpers: []string{"simpleNumber"},
+	source: `// simpleEqual compares values the way Python's == does: lists and dicts
+// by their items, structs by their fields, and numbers by value whatever
+// their Go type, so 1 == 1.0. Other values are equal when Go's == says so.
+func simpleEqual(a, b interface{}) bool {
+	return simpleEqualValues(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func simpleEqualValues(a, b reflect.Value) bool {
+	for a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+	if simpleIsNil(a) || simpleIsNil(b) {
+		return simpleIsNil(a) && simpleIsNil(b)
+	}
+	if x, ok := simpleNumber(a); ok {
+		y, ok := simpleNumber(b)
+		return ok && x == y
+	}
+	switch a.Kind() {
+	case reflect.Slice, reflect.Array:
+		if (b.Kind() != reflect.Slice && b.Kind() != reflect.Array) || a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !simpleEqualValues(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if b.Kind() != reflect.Map || a.Len() != b.Len() {
+			return false
+		}
+		iter := a.MapRange()
+		for iter.Next() {
+			if !iter.Key().Type().AssignableTo(b.Type().Key()) {
+				return false
+			}
+			value := b.MapIndex(iter.Key())
+			if !value.IsValid() || !simpleEqualValues(iter.Value(), value) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		if a.Type() != b.Type() {
+			return false
+		}
+		for i := 0; i < a.NumField(); i++ {
+			if !simpleEqualValues(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return a.Type() == b.Type() && a.Comparable() && a.Equal(b)
+}
+
+// simpleIsNil reports whether v is None: no value, or a nil pointer or
+// interface. Nil lists and dicts are empty rather than None.
+func simpleIsNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+`,
+}
+
+var copyHelper = runtimeHelper{
+	imports: []string{"reflect"},
+	source: `// simpleCopy returns a shallow copy of a list, dict or object: a new one
+// holding the same items, so changing one doesn't change the other.
+func simpleCopy[T any](v T) T {
+	out, _ := simpleCopyValue(reflect.ValueOf(&v).Elem(), nil).Interface().(T)
+	return out
+}
+
+// simpleDeepCopy returns a copy of v that shares nothing with it: the lists,
+// dicts and objects it holds are copied too, all the way down.
+func simpleDeepCopy[T any](v T) T {
+	out, _ := simpleCopyValue(reflect.ValueOf(&v).Elem(), map[uintptr]reflect.Value{}).Interface().(T)
+	return out
+}
+
+// simpleCopyValue copies v. Copies are deep when seen is not nil, which
+// maps the objects already copied to their copies so that cycles end.
+func simpleCopyValue(v reflect.Value, seen map[uintptr]reflect.Value) reflect.Value {
+	item := func(x reflect.Value) reflect.Value {
+		if seen == nil {
+			return x
+		}
+		return simpleCopyValue(x, seen)
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(item(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), item(iter.Value()))
+		}
+		return out
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		if copied, ok := seen[v.Pointer()]; ok {
+			return copied
+		}
+		out := reflect.New(v.Type().Elem())
+		if seen != nil {
+			seen[v.Pointer()] = out
+		}
+		out.Elem().Set(item(v.Elem()))
+		return out
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(simpleCopyValue(v.Elem(), seen))
+		return out
+	case reflect.Struct, reflect.Array:
+		// Assignment copies these; a deep copy also copies what they hold
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		if seen == nil {
+			return out
+		}
+		if v.Kind() == reflect.Array {
+			for i := 0; i < v.Len(); i++ {
+				out.Index(i).Set(simpleCopyValue(v.Index(i), seen))
+			}
+			return out
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if out.Field(i).CanSet() {
+				out.Field(i).Set(simpleCopyValue(v.Field(i), seen))
+			}
+		}
+		return out
+	}
+	return v
+}
+
+`,
+}
+
+var toFloatHelper = runtimeHelper{
+	imports: []string{"fmt", "reflect"},
+	helpers: []string{"simpleNumber"},
+	source: `// simpleToFloat returns a number of any Go numeric type as a float64, for
+// the float formats of f-strings such as {price:.2f}.
+func simpleToFloat(v interface{}) float64 {
+	if f, ok := simpleNumber(reflect.ValueOf(v)); ok {
+		return f
+	}
+	panic(fmt.Sprintf("can't format %v of type %T as a number", v, v))
+}
+
+`,
+}
+
+var groupHelper = runtimeHelper{
+	imports: []string{"strings"},
+	source: `// simpleGroup separates the thousands of the whole part of a formatted
+// number with sep, as the , and _ of a format spec do: 1234567.5 becomes
+// 1,234,567.5.
+func simpleGroup(number string, sep string) string {
+	start := strings.IndexAny(number, "0123456789")
+	if start < 0 {
+		return number
+	}
+	end := start
+	for end < len(number) && number[end] >= '0' && number[end] <= '9' {
+		end++
+	}
+	digits := number[start:end]
+	var grouped strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteString(sep)
+		}
+		grouped.WriteRune(digit)
+	}
+	return number[:start] + grouped.String() + number[end:]
+}
+
+`,
+}
+
+var openHelper = runtimeHelper{
+	imports: []string{"errors", "fmt", "io/fs", "os", "strings", "syscall"},
+	helpers: []string{"simpleException"},
+	source: `// simpleOpen opens a file as Python's open does, with a mode such as "r",
+// "w" or "a+", and raises the OSError Python raises when it can't. Text and
+// binary modes are the same, files being bytes in Go.
+func simpleOpen(name, mode string, line int, function string) *os.File {
+	flags := map[string]int{
+		"r":  os.O_RDONLY,
+		"w":  os.O_WRONLY | os.O_CREATE | os.O_TRUNC,
+		"a":  os.O_WRONLY | os.O_CREATE | os.O_APPEND,
+		"x":  os.O_WRONLY | os.O_CREATE | os.O_EXCL,
+		"r+": os.O_RDWR,
+		"w+": os.O_RDWR | os.O_CREATE | os.O_TRUNC,
+		"a+": os.O_RDWR | os.O_CREATE | os.O_APPEND,
+		"x+": os.O_RDWR | os.O_CREATE | os.O_EXCL,
+	}
+	flag, ok := flags[strings.NewReplacer("b", "", "t", "").Replace(mode)]
+	if !ok {
+		panic(simpleRaise("ValueError", "invalid mode: '"+mode+"'", line, function))
+	}
+	file, err := os.OpenFile(name, flag, 0o666)
+	if err == nil {
+		// Go opens directories for reading, Python doesn't
+		if info, statErr := file.Stat(); statErr == nil && info.IsDir() {
+			file.Close()
+			err = &fs.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+		}
+	}
+	if err == nil {
+		return file
+	}
+	class := "OSError"
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		class = "FileNotFoundError"
+	case errors.Is(err, fs.ErrExist):
+		class = "FileExistsError"
+	case errors.Is(err, fs.ErrPermission):
+		class = "PermissionError"
+	case errors.Is(err, syscall.EISDIR):
+		class = "IsADirectoryError"
+	}
+	message := err.Error()
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		text := errno.Error()
+		message = fmt.Sprintf("[Errno %d] %s: '%s'", int(errno), strings.ToUpper(text[:1])+text[1:], name)
+	}
+	panic(simpleRaise(class, message, line, function))
+}
+
+`,
+}
+
+var roundHelper = runtimeHelper{
+	imports: []string{"math", "strconv"},
+	source: `// simpleRound rounds x to n digits after the point, or before it when n is
+// negative, as Python's round does: halfway cases go to the even digit,
+// judged by the exact value of the float, so round(2.675, 2) is 2.67.
+func simpleRound(x float64, n int) float64 {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return x
+	}
+	if n >= 0 {
+		rounded, _ := strconv.ParseFloat(strconv.FormatFloat(x, 'f', n, 64), 64)
+		return rounded
+	}
+	scale := math.Pow(10, float64(-n))
+	return math.RoundToEven(x/scale) * scale
+}
+
+`,
+}
+
+var intPowHelper = runtimeHelper{
+	source: `// simpleIntPow raises an int to a power, as ** does for ints, by repeated
+// squaring. A negative power of an int is a float, which ** of ints known to
+// be negative when the program is compiled makes instead.
+func simpleIntPow(base, exponent int) int {
+	if exponent < 0 {
+		panic("negative exponent in ** of ints; make the base a float, as in 2.0 ** n")
+	}
+	result := 1
+	for exponent > 0 {
+		if exponent&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exponent >>= 1
+	}
+	return result
+}
+
+`,
+}
+
+var floorDivHelper = runtimeHelper{
+	source: `// simpleFloorDiv divides ints as // does, rounding towards minus infinity
+// where Go's / rounds towards zero, so -7 // 2 is -4.
+func simpleFloorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+`,
+}
+
+var powAnyHelper = runtimeHelper{
+	imports: []string{"math"},
+	helpers: []string{"simpleIntPow", "simpleToFloat"},
+	source: `// simplePowAny is ** of numbers whose types are only known when the program
+// runs: an int of ints and a power that isn't negative, else a float.
+func simplePowAny(base, exponent interface{}) interface{} {
+	b, bInt := base.(int)
+	e, eInt := exponent.(int)
+	if bInt && eInt && e >= 0 {
+		return simpleIntPow(b, e)
+	}
+	return math.Pow(simpleToFloat(base), simpleToFloat(exponent))
+}
+
+`,
+}
+
+var floorDivAnyHelper = runtimeHelper{
+	imports: []string{"math"},
+	helpers: []string{"simpleFloorDiv", "simpleToFloat"},
+	source: `// simpleFloorDivAny is // of numbers whose types are only known when the
+// program runs: an int of ints, else a float.
+func simpleFloorDivAny(a, b interface{}) interface{} {
+	x, xInt := a.(int)
+	y, yInt := b.(int)
+	if xInt && yInt {
+		return simpleFloorDiv(x, y)
+	}
+	return math.Floor(simpleToFloat(a) / simpleToFloat(b))
+}
+
+`,
+}
+
+var sortedHelper = runtimeHelper{
+	imports: []string{"slices"},
+	source: `// simpleSorted returns the items in a new list sorted by their keys, as
+// Python's sorted does. The sort is stable: items with equal keys keep their
+// order, reversed or not. Each item's key is computed once.
+func simpleSorted[T, K any](items []T, key func(T) K, compare func(K, K) int, reverse bool) []T {
+	keys := make([]K, len(items))
+	order := make([]int, len(items))
+	for i, item := range items {
+		keys[i] = key(item)
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(i, j int) int {
+		if reverse {
+			return compare(keys[j], keys[i])
+		}
+		return compare(keys[i], keys[j])
+	})
+	sorted := make([]T, 0, len(items))
+	for _, i := range order {
+		sorted = append(sorted, items[i])
+	}
+	return sorted
+}
+
+`,
+}
+
+var bestHelper = runtimeHelper{
+	helpers: []string{"simpleException"},
+	source: `// simpleBest returns the first of the items with the largest key, for
+// max, or the smallest, for min, as Python's max and min do. Without items
+// it returns what empty does, or raises a ValueError when there is no
+// default, from the line and function of the call.
+func simpleBest[T, K any](name string, items []T, key func(T) K, compare func(K, K) int, empty func() T, line int, function string) T {
+	if len(items) == 0 {
+		if empty == nil {
+			panic(simpleRaise("ValueError", name+"() arg is an empty sequence", line, function))
+		}
+		return empty()
+	}
+	sign := 1
+	if name == "min" {
+		sign = -1
+	}
+	best, bestKey := items[0], key(items[0])
+	for _, item := range items[1:] {
+		if k := key(item); compare(k, bestKey)*sign > 0 {
+			best, bestKey = item, k
+		}
+	}
+	return best
+}
+
+`,
+}
+
+var sumHelper = runtimeHelper{
+	source: `// simpleSum adds the items to start, as Python's sum does.
+func simpleSum[T int | float64](items []T, start T) T {
+	for _, item := range items {
+		start += item
+	}
+	return start
+}
+
+`,
+}
+
+var sumAnyHelper = runtimeHelper{
+	imports: []string{"fmt", "reflect"},
+	helpers: []string{"simpleItems", "simpleNumber"},
+	source: `// simpleSumAny adds items of no known type to start, as Python's sum
+// does: the sum is an int while the numbers are, and a float once one isn't.
+func simpleSumAny(items interface{}, start interface{}) interface{} {
+	ints, floats, isFloat := 0, 0.0, false
+	for _, item := range append([]interface{}{start}, simpleItems(items)...) {
+		if n, ok := item.(int); ok {
+			ints += n
+			continue
+		}
+		n, ok := simpleNumber(reflect.ValueOf(item))
+		if !ok {
+			panic(fmt.Sprintf("unsupported operand type(s) for +: 'int' and '%T'", item))
+		}
+		floats, isFloat = floats+n, true
+	}
+	if isFloat {
+		return float64(ints) + floats
+	}
+	return ints
+}
+
+`,
+}
+
+var compareHelper = runtimeHelper{
+	imports: []string{"cmp", "fmt", "reflect", "strings"},
+	helpers: []string{"simpleEqual", "simpleIsTuple", "simpleNumber"},
+	source: `// simpleCompare orders values the way Python's < does: numbers by value
+// whatever their Go type, with False and True as 0 and 1, strings by their
+// characters, and lists and tuples item by item. Other values can't be
+// ordered.
+func simpleCompare[T any](a, b T) int {
+	return simpleCompareValues(reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem())
+}
+
+func simpleCompareValues(a, b reflect.Value) int {
+	for a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+	number := func(v reflect.Value) (float64, bool) {
+		if v.Kind() == reflect.Bool {
+			if v.Bool() {
+				return 1, true
+			}
+			return 0, true
+		}
+		return simpleNumber(v)
+	}
+	isList := func(v reflect.Value) bool {
+		return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+	}
+	isTuple := func(v reflect.Value) bool {
+		return v.Kind() == reflect.Struct && simpleIsTuple(v.Type())
+	}
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			return cmp.Compare(x, y)
+		}
+	}
+	switch {
+	case a.Kind() == reflect.String && b.Kind() == reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case isList(a) && isList(b):
+		for i := 0; i < a.Len() && i < b.Len(); i++ {
+			if c := simpleCompareValues(a.Index(i), b.Index(i)); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.Len(), b.Len())
+	case isTuple(a) && isTuple(b):
+		for i := 0; i < a.NumField() && i < b.NumField(); i++ {
+			if c := simpleCompareValues(a.Field(i), b.Field(i)); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.NumField(), b.NumField())
+	}
+	typeName := func(v reflect.Value) string {
+		if simpleIsNil(v) {
+			return "NoneType"
+		}
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return "float"
+		case reflect.String:
+			return "str"
+		case reflect.Slice, reflect.Array:
+			return "list"
+		case reflect.Map:
+			return "dict"
+		}
+		if isTuple(v) {
+			return "tuple"
+		}
+		return v.Type().String()
+	}
+	panic(fmt.Sprintf("'<' not supported between instances of '%s' and '%s'", typeName(a), typeName(b)))
+}
+
+`,
+}
+
+var tupleHelper = runtimeHelper{
+	imports: []string{"reflect", "strconv"},
+	source: `// simpleIsTuple reports whether t is the struct of a tuple, whose fields
+// are Item0, Item1 and so on.
+func simpleIsTuple(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t.Name() != "" {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Name != "Item"+strconv.Itoa(i) {
+			return false
+		}
+	}
+	return true
+}
+
+`,
+}
+
+var itemsHelper = runtimeHelper{
+	imports: []string{"fmt", "reflect"},
+	source: `// simpleItems returns what iterating over a value of no known type gives:
+// the items of a list, the keys of a dict or the characters of a string.
+func simpleItems(v interface{}) []interface{} {
+	rv := reflect.ValueOf(v)
+	items := []interface{}{}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, rv.Index(i).Interface())
+		}
+	case reflect.Map:
+		for _, key := range rv.MapKeys() {
+			items = append(items, key.Interface())
+		}
+	case reflect.String:
+		for _, r := range rv.String() {
+			items = append(items, string(r))
+		}
+	default:
+		panic(fmt.Sprintf("'%T' object is not iterable", v))
+	}
+	return items
+}
+
+`,
+}
+
+var hasKeyHelper = runtimeHelper{
+	helpers: []string{"simpleKey"},
+	source: `// simpleHasKey reports whether key is a key of m.
+func simpleHasKey[K comparable, V any](m map[K]V, key K) bool {
+	_, ok := m[simpleKey(m, key)]
+	return ok
+}
+
+`,
+}
+
+var inHelper = runtimeHelper{
+	imports: []string{"fmt", "reflect", "strings"},
+	helpers: []string{"simpleEqual", "simpleIsTuple", "simpleKey"},
+	source: `// simpleIn reports whether item is in container the way Python's in does:
+// a key of a dict, an item of a list or tuple, or a substring of a string.
+// Keys and items are compared as simpleEqual compares them, so 1 is in
+// [1.0], and keys compared by __eq__ as simpleSameKey compares them.
+func simpleIn(item, container interface{}) bool {
+	c := reflect.ValueOf(container)
+	for c.Kind() == reflect.Interface && !c.IsNil() {
+		c = c.Elem()
+	}
+	x := reflect.ValueOf(item)
+	switch c.Kind() {
+	case reflect.String:
+		s, ok := item.(string)
+		if !ok {
+			panic(fmt.Sprintf("'in <string>' requires string as left operand, not %T", item))
+		}
+		return strings.Contains(c.String(), s)
+	case reflect.Map:
+		if x.IsValid() && x.Type().AssignableTo(c.Type().Key()) && c.MapIndex(x).IsValid() {
+			return true
+		}
+		iter := c.MapRange()
+		for iter.Next() {
+			if simpleEqualValues(iter.Key(), x) || simpleSameKey(item, iter.Key().Interface()) {
+				return true
+			}
+		}
+		return false
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < c.Len(); i++ {
+			if simpleEqualValues(c.Index(i), x) {
+				return true
+			}
+		}
+		return false
+	case reflect.Struct:
+		if simpleIsTuple(c.Type()) {
+			for i := 0; i < c.NumField(); i++ {
+				if simpleEqualValues(c.Field(i), x) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	panic(fmt.Sprintf("argument of type %T is not iterable", container))
+}
+
+`,
+}
+
+var indexHelper = runtimeHelper{
+	imports: []string{"fmt", "reflect"},
+	helpers: []string{"simpleException", "simpleIsTuple"},
+	source: `// simpleIndex returns container[key] for a container of no known type,
+// such as a dict nested in a dict of any values: the value of a key of a
+// dict, an item of a list or tuple, or a character of a string. A missing
+// key raises a KeyError and an index out of range an IndexError, as in
+// Python, from the line and function of the index.
+func simpleIndex(container, key interface{}, line int, function string) interface{} {
+	c := reflect.ValueOf(container)
+	k := reflect.ValueOf(key)
+	if c.Kind() == reflect.Map {
+		if k.IsValid() && k.Type().AssignableTo(c.Type().Key()) {
+			if v := c.MapIndex(k); v.IsValid() {
+				return v.Interface()
+			}
+		}
+		if s, ok := key.(string); ok {
+			panic(simpleRaise("KeyError", fmt.Sprintf("'%s'", s), line, function))
+		}
+		panic(simpleRaise("KeyError", fmt.Sprint(key), line, function))
+	}
+
+	// position returns the position of an index of n items of a list,
+	// tuple or string, counting a negative index from the end
+	position := func(n int, kind string) int {
+		if !k.IsValid() || !k.CanInt() {
+			panic(simpleRaise("TypeError", fmt.Sprintf("indices must be integers, not %T", key), line, function))
+		}
+		i := int(k.Int())
+		if i < 0 {
+			i += n
+		}
+		if i < 0 || i >= n {
+			panic(simpleRaise("IndexError", kind+" index out of range", line, function))
+		}
+		return i
+	}
+	switch c.Kind() {
+	case reflect.Slice, reflect.Array:
+		return c.Index(position(c.Len(), "list")).Interface()
+	case reflect.String:
+		runes := []rune(c.String())
+		return string(runes[position(len(runes), "string")])
+	case reflect.Struct:
+		if simpleIsTuple(c.Type()) {
+			return c.Field(position(c.NumField(), "tuple")).Interface()
+		}
+	}
+	panic(simpleRaise("TypeError", fmt.Sprintf("'%T' object is not subscriptable", container), line, function))
+}
+
+`,
+}
+
+var keyHelper = runtimeHelper{
+	source: `// simpleKey returns the key of m equal to key, or key if m has none. Keys
+// are compared by ==, except for objects of classes with __eq__ and
+// __hash__ methods, which are the same key when simpleSameKey says so.
+func simpleKey[K comparable, V any](m map[K]V, key K) K {
+	if _, ok := m[key]; ok {
+		return key
+	}
+	if _, ok := any(key).(interface{ simpleEq(any) bool }); !ok {
+		return key
+	}
+	for other := range m {
+		if simpleSameKey(key, other) {
+			return other
+		}
+	}
+	return key
+}
+
+// simpleSameKey reports whether two objects of classes with __eq__ and
+// __hash__ methods are the same key: as in Python, when their hashes are
+// equal and __eq__ says they are.
+func simpleSameKey(key, other any) bool {
+	k, ok := key.(interface {
+		__hash__() int
+		simpleEq(any) bool
+	})
+	o, hashable := other.(interface{ __hash__() int })
+	return ok && hashable && k.__hash__() == o.__hash__() && k.simpleEq(other)
+}
+
+`,
+}
+
+var setHelper = runtimeHelper{
+	imports: []string{"fmt"},
+	helpers: []string{"simpleException", "simpleKey"},
+	source: `// simpleSet makes a set of items, which Go holds as a map from the items
+// to empty structs.
+func simpleSet[T comparable](items ...T) map[T]struct{} {
+	set := make(map[T]struct{}, len(items))
+	for _, item := range items {
+		set[simpleKey(set, item)] = struct{}{}
+	}
+	return set
+}
+
+// simpleSetAdd adds an item to a set.
+func simpleSetAdd[T comparable](set map[T]struct{}, item T) {
+	set[simpleKey(set, item)] = struct{}{}
+}
+
+// simpleSetRemove removes an item from a set, raising a KeyError from the
+// line and function of the call if the set doesn't hold it.
+func simpleSetRemove[T comparable](set map[T]struct{}, item T, line int, function string) {
+	key := simpleKey(set, item)
+	if _, ok := set[key]; !ok {
+		if s, ok := any(item).(string); ok {
+			panic(simpleRaise("KeyError", fmt.Sprintf("'%s'", s), line, function))
+		}
+		panic(simpleRaise("KeyError", fmt.Sprint(item), line, function))
+	}
+	delete(set, key)
+}
+
+// simpleUnion returns the items of either set, a | b.
+func simpleUnion[T comparable](a, b map[T]struct{}) map[T]struct{} {
+	set := make(map[T]struct{}, len(a)+len(b))
+	for item := range a {
+		set[item] = struct{}{}
+	}
+	for item := range b {
+		set[simpleKey(set, item)] = struct{}{}
+	}
+	return set
+}
+
+// simpleIntersection returns the items of both sets, a & b.
+func simpleIntersection[T comparable](a, b map[T]struct{}) map[T]struct{} {
+	set := map[T]struct{}{}
+	for item := range a {
+		if _, ok := b[simpleKey(b, item)]; ok {
+			set[item] = struct{}{}
+		}
+	}
+	return set
+}
+
+// simpleDifference returns the items of a that aren't in b, a - b.
+func simpleDifference[T comparable](a, b map[T]struct{}) map[T]struct{} {
+	set := map[T]struct{}{}
+	for item := range a {
+		if _, ok := b[simpleKey(b, item)]; !ok {
+			set[item] = struct{}{}
+		}
+	}
+	return set
+}
+
+// simpleSymmetricDifference returns the items of one set or the other but
+// not both, a ^ b.
+func simpleSymmetricDifference[T comparable](a, b map[T]struct{}) map[T]struct{} {
+	set := simpleDifference(a, b)
+	for item := range b {
+		if _, ok := a[simpleKey(a, item)]; !ok {
+			set[item] = struct{}{}
+		}
+	}
+	return set
+}
+
+`,
+}
+
+var lenHelper = runtimeHelper{
+	imports: []string{"fmt", "reflect", "unicode/utf8"},
+	helpers: []string{"simpleException", "simpleIsTuple"},
+	source: `// simpleLen returns len(v) for a value of no known type: the number of
+// characters of a string, the length of a list, dict, set or channel, the
+// number of values of a tuple, or what the __len__ method of a class or the
+// Len method of a Go value returns. Other values raise a TypeError from the
+// line and function of the call.
+func simpleLen(v interface{}, line int, function string) int {
+	switch v := v.(type) {
+	case interface{ __len__() int }:
+		return v.__len__()
+	case interface{ Len() int }:
+		return v.Len()
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(value.String())
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return value.Len()
+	case reflect.Struct:
+		if simpleIsTuple(value.Type()) {
+			return value.NumField()
+		}
+	}
+	panic(simpleRaise("TypeError", fmt.Sprintf("object of type '%T' has no len()", v), line, function))
+}
+
+`,
+}
+
+var nextHelper = runtimeHelper{
+	helpers: []string{"simpleException"},
+	source: `// simpleNext calls the __next__ method of an iterator defined in Simple,
+// reporting false once it raises StopIteration.
+func simpleNext[V any](next func() V) (value V, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, isException := r.(*simpleException); !isException || e.class != "StopIteration" {
+				panic(r)
+			}
+		}
+	}()
+	return next(), true
+}
+
+`,
+}
@@ -0,0 +1,199 @@
+package codegen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Serverless packages
+//
+// A program packaged as a cloud function is run by a shim once for every
+// invocation, with the event or request body on its standard input; what it
+// prints is the response and what it writes to standard error goes to the
+// function's logs. The shims import nothing but the standard library, so
+// packaging needs no downloads. AWS Lambda and Azure Functions run a binary
+// of the shim; Google Cloud Functions builds its source.
+
+// ServerlessProviders are the providers a program can be packaged for.
+var ServerlessProviders = []string{"lambda", "gcf", "azure"}
+
+// WriteServerlessShim writes the shim that runs the program, at the path
+// program in the package, on provider into dir: a main package for lambda
+// and azure, to be built into the package, and a package with the Simple
+// function for gcf.
+func WriteServerlessShim(dir, provider, program string) error {
+	var header string
+	switch provider {
+	case "lambda":
+		header = lambdaShimSource
+	case "gcf":
+		header = gcfShimSource
+	case "azure":
+		header = azureShimSource
+	default:
+		return fmt.Errorf("unknown serverless provider %q", provider)
+	}
+
+	file, err := os.Create(filepath.Join(dir, "simple_serverless.go"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fmt.Fprint(file, "// Code generated by the Simple compiler. DO NOT EDIT.\n\n")
+	fmt.Fprint(file, header)
+	fmt.Fprintf(file, "\n// program is the path of the program's binary in the package.\nconst program = %q\n", program)
+	_, err = file.WriteString(shimRunSource)
+	return err
+}
+
+const lambdaShimSource = `// Command bootstrap runs a Simple program on the provided runtime of AWS
+// Lambda, taking invocations from the Lambda runtime API.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+func main() {
+	api := "http://" + os.Getenv("AWS_LAMBDA_RUNTIME_API") + "/2018-06-01/runtime/invocation/"
+	for {
+		response, err := http.Get(api + "next")
+		if err != nil {
+			log.Fatal(err)
+		}
+		event, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if response.StatusCode != http.StatusOK {
+			log.Fatalf("next invocation: %s: %s", response.Status, event)
+		}
+		id := response.Header.Get("Lambda-Runtime-Aws-Request-Id")
+		out, err := run(event, nil)
+		if err != nil {
+			report, _ := json.Marshal(map[string]string{"errorMessage": err.Error(), "errorType": "SimpleError"})
+			post(api+id+"/error", report)
+			continue
+		}
+		post(api+id+"/response", out)
+	}
+}
+
+// post sends the result of an invocation to the runtime API.
+func post(url string, body []byte) {
+	response, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Fatal(err)
+	}
+	response.Body.Close()
+}
+
+// programPath returns where the program is: next to the shim.
+func programPath() string {
+	self, err := os.Executable()
+	if err != nil {
+		return "./" + program
+	}
+	return filepath.Join(filepath.Dir(self), program)
+}
+`
+
+const gcfShimSource = `// Package function runs a Simple program on Google Cloud Functions as the
+// HTTP function Simple, its entry point.
+package function
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Simple runs the program on a request.
+func Simple(w http.ResponseWriter, r *http.Request) {
+	handle(w, r)
+}
+
+// programPath returns where the program is: with the function's source,
+// which Cloud Functions keeps in serverless_function_source_code.
+func programPath() string {
+	path := filepath.Join("serverless_function_source_code", program)
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return "./" + program
+}
+`
+
+const azureShimSource = `// Command handler runs a Simple program as a custom handler of Azure
+// Functions, which forwards it the requests of the function's HTTP trigger.
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+func main() {
+	port := os.Getenv("FUNCTIONS_CUSTOMHANDLER_PORT")
+	if port == "" {
+		port = "8080"
+	}
+	log.Fatal(http.ListenAndServe(":"+port, http.HandlerFunc(handle)))
+}
+
+// programPath returns where the program is: next to the shim.
+func programPath() string {
+	self, err := os.Executable()
+	if err != nil {
+		return "./" + program
+	}
+	return filepath.Join(filepath.Dir(self), program)
+}
+`
+
+const shimRunSource = `
+// run runs the program on one invocation, with input on its standard input
+// and env added to its environment, and returns what it printed.
+func run(input []byte, env []string) ([]byte, error) {
+	cmd := exec.Command(programPath())
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Output()
+}
+
+// handle runs the program on an HTTP request, with the body on its standard
+// input and the method and query string in REQUEST_METHOD and QUERY_STRING,
+// as for a CGI script.
+func handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	out, err := run(body, []string{"REQUEST_METHOD=" + r.Method, "QUERY_STRING=" + r.URL.RawQuery})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(out)
+}
+`
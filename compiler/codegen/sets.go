@@ -0,0 +1,109 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+)
+
+// generateSetLiteral writes a set literal as a call to simpleSet, which
+// makes a map of the items to empty structs.
+func (cg *CodeGenerator) generateSetLiteral(file *os.File, sl *parser.SetLiteral) {
+	cg.useHelper("simpleSet")
+	fmt.Fprintf(file, "simpleSet[%s](", goTypeName(sl.ElementType.String()))
+	for i, el := range sl.Elements {
+		if i > 0 {
+			fmt.Fprint(file, ", ")
+		}
+		cg.generateExpression(file, el)
+	}
+	fmt.Fprint(file, ")")
+}
+
+// generateSetCall writes a call of the set builtin or of a method of a set.
+// Removing an item the set doesn't hold raises a KeyError from the line of
+// the call.
+func (cg *CodeGenerator) generateSetCall(file *os.File, ce *parser.CallExpression, sc *semantic.SetCall) {
+	switch sc.Name {
+	case "set":
+		cg.useHelper("simpleSet")
+		fmt.Fprintf(file, "simpleSet[%s](", cg.typeToGoString(sc.ItemType))
+		if sc.Argument != nil {
+			cg.generateItems(file, sc.Kind, sc.Argument)
+			fmt.Fprint(file, "...")
+		}
+		fmt.Fprint(file, ")")
+		return
+	case "add":
+		cg.useHelper("simpleSet")
+		fmt.Fprint(file, "simpleSetAdd(")
+	case "remove":
+		cg.useHelper("simpleSet")
+		fmt.Fprint(file, "simpleSetRemove(")
+	case "discard":
+		if cg.analyzer.KeyedByValue(sc.ItemType) {
+			cg.useHelper("simpleKey")
+			fmt.Fprint(file, "delete(")
+			cg.generateExpression(file, sc.Set)
+			fmt.Fprint(file, ", simpleKey(")
+			cg.generateExpression(file, sc.Set)
+			fmt.Fprint(file, ", ")
+			cg.generateExpression(file, sc.Argument)
+			fmt.Fprint(file, "))")
+			return
+		}
+		fmt.Fprint(file, "delete(")
+	}
+	cg.generateExpression(file, sc.Set)
+	fmt.Fprint(file, ", ")
+	cg.generateExpression(file, sc.Argument)
+	if sc.Name == "remove" {
+		fmt.Fprintf(file, ", %d, %q", ce.Token.Line, cg.tracebackFunction())
+	}
+	fmt.Fprint(file, ")")
+}
+
+// setOperations are the runtime helpers of the operators on sets.
+var setOperations = map[string]string{
+	"|": "simpleUnion",
+	"&": "simpleIntersection",
+	"-": "simpleDifference",
+	"^": "simpleSymmetricDifference",
+}
+
+// generateSetOperation writes a union, intersection, difference or
+// symmetric difference of sets as a call to the helper making a new set.
+func (cg *CodeGenerator) generateSetOperation(file *os.File, ie *parser.InfixExpression) {
+	cg.useHelper("simpleSet")
+	fmt.Fprintf(file, "%s(", setOperations[ie.Operator])
+	cg.generateExpression(file, ie.Left)
+	fmt.Fprint(file, ", ")
+	cg.generateExpression(file, ie.Right)
+	fmt.Fprint(file, ")")
+}
+
+// generateBitwiseOperation writes |, & or ^ on numbers, or on bools as the
+// logical operators Go has for them. Go ranks these operators with + and *
+// rather than below them as Python does, so the operation and operations
+// inside it are parenthesized.
+func (cg *CodeGenerator) generateBitwiseOperation(file *os.File, ie *parser.InfixExpression) {
+	operator := ie.Operator
+	if cg.analyzer.InferExpressionTypes(ie, false)[0].String() == "bool" {
+		operator = map[string]string{"|": "||", "&": "&&", "^": "!="}[operator]
+	}
+	fmt.Fprint(file, "(")
+	for i, operand := range []parser.Expression{ie.Left, ie.Right} {
+		if i > 0 {
+			fmt.Fprintf(file, " %s ", operator)
+		}
+		if _, ok := operand.(*parser.InfixExpression); ok {
+			fmt.Fprint(file, "(")
+			cg.generateExpression(file, operand)
+			fmt.Fprint(file, ")")
+		} else {
+			cg.generateExpression(file, operand)
+		}
+	}
+	fmt.Fprint(file, ")")
+}
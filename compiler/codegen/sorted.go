@@ -0,0 +1,75 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+)
+
+// generateSorted writes a call of sorted as a call to simpleSorted, which
+// sorts a copy of the items by their keys.
+func (cg *CodeGenerator) generateSorted(file *os.File, sc *semantic.SortedCall) {
+	cg.useHelper("simpleSorted")
+	fmt.Fprint(file, "simpleSorted(")
+	cg.generateItems(file, sc.Kind, sc.Items)
+
+	fmt.Fprint(file, ", ")
+	cg.generateKey(file, sc.ItemType, sc.Key)
+	fmt.Fprint(file, ", ")
+	if sc.Reverse != nil {
+		cg.generateCondition(file, sc.Reverse)
+	} else {
+		fmt.Fprint(file, "false")
+	}
+	fmt.Fprint(file, ")")
+}
+
+// generateKey writes the key function of builtins such as sorted, which
+// returns the items themselves when key is nil, and the function comparing
+// its keys: cmp.Compare for Go's ordered types, and simpleCompare, which
+// compares the way Python does, for others.
+func (cg *CodeGenerator) generateKey(file *os.File, itemType parser.Type, key *parser.LambdaExpression) {
+	keyType := cg.typeToGoString(itemType)
+	if key != nil {
+		keyType = cg.typeToGoString(cg.analyzer.LambdaOf(key).Type.ReturnTypes[0])
+		cg.generateLambdaExpression(file, key)
+	} else {
+		fmt.Fprintf(file, "func(item %s) %s { return item }", keyType, keyType)
+	}
+
+	switch keyType {
+	case "int", "float64", "string":
+		cg.imports["cmp"] = true
+		fmt.Fprintf(file, ", cmp.Compare[%s]", keyType)
+	default:
+		cg.useHelper("simpleCompare")
+		fmt.Fprintf(file, ", simpleCompare[%s]", keyType)
+	}
+}
+
+// generateItems writes a slice of the items builtins such as sorted take
+// from a value of the given kind: a list itself, the keys of a dict, the
+// characters of a string, or the items of a value of no known type.
+func (cg *CodeGenerator) generateItems(file *os.File, kind string, items parser.Expression) {
+	switch kind {
+	case "dict":
+		cg.imports["maps"] = true
+		cg.imports["slices"] = true
+		fmt.Fprint(file, "slices.Collect(maps.Keys(")
+		cg.generateExpression(file, items)
+		fmt.Fprint(file, "))")
+	case "string":
+		cg.imports["strings"] = true
+		fmt.Fprint(file, "strings.Split(")
+		cg.generateExpression(file, items)
+		fmt.Fprint(file, `, "")`)
+	case "any":
+		cg.useHelper("simpleItems")
+		fmt.Fprint(file, "simpleItems(")
+		cg.generateExpression(file, items)
+		fmt.Fprint(file, ")")
+	default:
+		cg.generateExpression(file, items)
+	}
+}
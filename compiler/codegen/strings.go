@@ -0,0 +1,131 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+	"strconv"
+)
+
+// Garbage-free strings
+//
+// Strings are built with fmt.Sprintf and simpleStr, which take their values
+// as interface{} values and allocate for each. At optimization level 1, set
+// with -O 1, strings made of strings and ints are joined with + and
+// strconv instead, as concatenation, f-strings, str and print make them in
+// the request handlers of busy web servers. Values of other types are
+// formatted as before, so the program prints the same at every level.
+
+// generateFastString writes expr as a string without fmt if the
+// optimization level allows it and its type is string or int, reporting
+// whether it did.
+func (cg *CodeGenerator) generateFastString(file *os.File, expr parser.Expression) bool {
+	if !cg.fastString(expr) {
+		return false
+	}
+	if cg.analyzer.InferExpressionTypes(expr, false)[0].String() == "int" {
+		cg.imports["strconv"] = true
+		fmt.Fprint(file, "strconv.Itoa(")
+		cg.generateExpression(file, expr)
+		fmt.Fprint(file, ")")
+		return true
+	}
+	// A string made by another operator is kept whole beside +
+	if ie, ok := expr.(*parser.InfixExpression); ok && ie.Operator != "+" {
+		fmt.Fprint(file, "(")
+		cg.generateExpression(file, expr)
+		fmt.Fprint(file, ")")
+		return true
+	}
+	cg.generateExpression(file, expr)
+	return true
+}
+
+// fastString reports whether generateFastString writes expr.
+func (cg *CodeGenerator) fastString(expr parser.Expression) bool {
+	if cg.Optimize < 1 || cg.isErrorExpression(expr) {
+		return false
+	}
+	switch cg.analyzer.InferExpressionTypes(expr, false)[0].String() {
+	case "string", "int":
+		return true
+	}
+	return false
+}
+
+// generateFastFString writes an f-string whose fields are strings and ints
+// without format specs as the concatenation of its parts, reporting whether
+// it could.
+func (cg *CodeGenerator) generateFastFString(file *os.File, fs *parser.FStringLiteral) bool {
+	for _, part := range fs.Parts {
+		if part.Expression != nil && (part.Debug || part.Conversion != 0 || part.Spec != "" || !cg.fastString(part.Expression)) {
+			return false
+		}
+	}
+	fmt.Fprint(file, "(")
+	for i, part := range fs.Parts {
+		if i > 0 {
+			fmt.Fprint(file, " + ")
+		}
+		if part.Expression == nil {
+			fmt.Fprint(file, strconv.Quote(part.Text))
+		} else {
+			cg.generateFastString(file, part.Expression)
+		}
+	}
+	fmt.Fprint(file, ")")
+	return true
+}
+
+// generateFastPrint writes a call of print whose arguments are strings and
+// ints as one write of their line to standard output, reporting whether it
+// could.
+func (cg *CodeGenerator) generateFastPrint(file *os.File, ce *parser.CallExpression) bool {
+	if cg.importAliases["os"] != "" {
+		return false
+	}
+	for _, arg := range ce.Arguments {
+		if !cg.fastString(arg) {
+			return false
+		}
+	}
+	cg.imports["os"] = true
+	fmt.Fprint(file, "os.Stdout.WriteString(")
+	// Println separates its operands by spaces and ends the line
+	for i, arg := range ce.Arguments {
+		if i > 0 {
+			fmt.Fprint(file, `" " + `)
+		}
+		cg.generateFastString(file, arg)
+		fmt.Fprint(file, " + ")
+	}
+	fmt.Fprint(file, `"\n")`)
+	return true
+}
+
+// generateStringRepeat writes a string repeated by * as strings.Repeat. A
+// count below zero makes the empty string, as in Python, where Repeat would
+// panic.
+func (cg *CodeGenerator) generateStringRepeat(file *os.File, str, count parser.Expression) {
+	cg.imports["strings"] = true
+	fmt.Fprint(file, "strings.Repeat(")
+	cg.generateExpression(file, str)
+	fmt.Fprint(file, ", ")
+	if n, ok := literalInt(count); ok && n >= 0 {
+		cg.generateExpression(file, count)
+	} else {
+		fmt.Fprint(file, "max(")
+		cg.generateNumericExpression(file, count, "int")
+		fmt.Fprint(file, ", 0)")
+	}
+	fmt.Fprint(file, ")")
+}
+
+// literalInt returns the value of an int literal.
+func literalInt(expr parser.Expression) (int64, bool) {
+	if il, ok := expr.(*parser.IntegerLiteral); ok && il != nil {
+		n, ok := il.Value.(int64)
+		return n, ok
+	}
+	return 0, false
+}
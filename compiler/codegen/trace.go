@@ -0,0 +1,65 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+)
+
+// Tracing
+//
+// With Trace set, every function and method of the main program starts by
+// deferring a call of simpleTrace, which logs the call to standard error
+// with its arguments as repr shows them, and then its return with how long
+// it took. Calls are indented by how deeply they are nested, so the log of
+// a run reads as its call tree:
+//
+//	[trace] -> fib(n=2)
+//	[trace]   -> fib(n=1)
+//	[trace]   <- fib 1.2µs
+//
+// Calls made in goroutines of their own are logged too, interleaved with
+// the others.
+
+// writeTracePrologue writes the simpleTrace call at the top of a traced
+// function. name is the function as the log shows it, as in Point.move for
+// a method.
+func (cg *CodeGenerator) writeTracePrologue(file *os.File, name string, params []*parser.Identifier) {
+	cg.useHelper("simpleTrace")
+	cg.writeIndent(file)
+	fmt.Fprintf(file, "defer simpleTrace(%q", name)
+	for _, param := range params {
+		fmt.Fprintf(file, ", %q, %s", param.Value, cg.goName(param.Value))
+	}
+	fmt.Fprintln(file, ")()")
+}
+
+var traceHelper = runtimeHelper{
+	imports: []string{"fmt", "os", "strings", "sync/atomic", "time"},
+	helpers: []string{"simpleStr"},
+	source: `// simpleTraceDepth is how deeply the calls simpleTrace logs are nested.
+var simpleTraceDepth atomic.Int32
+
+// simpleTrace logs a call of a function, given the names and values of its
+// arguments in turn, and returns the function the call defers to log its
+// return. Long arguments are cut short.
+func simpleTrace(function string, args ...interface{}) func() {
+	params := []string{}
+	for i := 0; i+1 < len(args); i += 2 {
+		value := simpleRepr(args[i+1])
+		if runes := []rune(value); len(runes) > 60 {
+			value = string(runes[:57]) + "..."
+		}
+		params = append(params, fmt.Sprintf("%s=%s", args[i], value))
+	}
+	indent := strings.Repeat("  ", int(simpleTraceDepth.Add(1)-1))
+	fmt.Fprintf(os.Stderr, "[trace] %s-> %s(%s)\n", indent, function, strings.Join(params, ", "))
+	start := time.Now()
+	return func() {
+		simpleTraceDepth.Add(-1)
+		fmt.Fprintf(os.Stderr, "[trace] %s<- %s %s\n", indent, function, time.Since(start))
+	}
+}
+
+`,
+}
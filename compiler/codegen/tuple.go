@@ -0,0 +1,93 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+	"strings"
+)
+
+// tupleType returns the tuple type of an expression, if it is a tuple.
+func (cg *CodeGenerator) tupleType(expr parser.Expression) (*parser.TupleType, bool) {
+	return cg.analyzer.TupleOf(cg.analyzer.InferExpressionTypes(expr, false)[0])
+}
+
+// generateTupleLiteral writes a tuple as a struct literal with a field for
+// each value.
+func (cg *CodeGenerator) generateTupleLiteral(file *os.File, tl *parser.TupleLiteral) {
+	tt, _ := cg.tupleType(tl)
+	fmt.Fprintf(file, "%s{", cg.typeToGoString(tt))
+	for i, el := range tl.Elements {
+		if i > 0 {
+			fmt.Fprint(file, ", ")
+		}
+		cg.generateExpression(file, el)
+	}
+	fmt.Fprint(file, "}")
+}
+
+// tupleLiteralString returns a tuple used as a dict key as Go code. Like
+// the rest of an index expression, it is written from its source.
+func (cg *CodeGenerator) tupleLiteralString(tl *parser.TupleLiteral) string {
+	tt, _ := cg.tupleType(tl)
+	elements := []string{}
+	for _, el := range tl.Elements {
+		if nested, ok := el.(*parser.TupleLiteral); ok {
+			elements = append(elements, cg.tupleLiteralString(nested))
+		} else {
+			elements = append(elements, cg.goExpression(el).String())
+		}
+	}
+	return fmt.Sprintf("%s{%s}", cg.typeToGoString(tt), strings.Join(elements, ", "))
+}
+
+// generateUnpackedTuple writes the values of a tuple assigned to several
+// targets, as x, y = 1, 2 and x, y = t are in Go. A tuple computed by an
+// expression is unpacked by a function literal so that it is computed once.
+func (cg *CodeGenerator) generateUnpackedTuple(file *os.File, value parser.Expression) {
+	if tl, ok := value.(*parser.TupleLiteral); ok {
+		cg.generateExpressionList(file, tl.Elements)
+		return
+	}
+	tt, _ := cg.tupleType(value)
+	items := []string{}
+	types := []string{}
+	for i, et := range tt.ElementTypes {
+		items = append(items, fmt.Sprintf("Item%d", i))
+		types = append(types, cg.typeToGoString(et))
+	}
+	if ident, ok := value.(*parser.Identifier); ok {
+		name := cg.identifierName(ident.Value)
+		fmt.Fprintf(file, "%s.%s", name, strings.Join(items, ", "+name+"."))
+		return
+	}
+	fmt.Fprintf(file, "func(t %s) (%s) { return t.%s }(", cg.typeToGoString(tt), strings.Join(types, ", "), strings.Join(items, ", t."))
+	cg.generateExpression(file, value)
+	fmt.Fprint(file, ")")
+}
+
+// generateExpressionList writes values separated by commas.
+func (cg *CodeGenerator) generateExpressionList(file *os.File, values []parser.Expression) {
+	for i, value := range values {
+		if i > 0 {
+			fmt.Fprint(file, ", ")
+		}
+		cg.generateExpression(file, value)
+	}
+}
+
+// generateResultTuple writes a call of a function with several results,
+// used as a single value, as a function literal that makes a tuple of the
+// results.
+func (cg *CodeGenerator) generateResultTuple(file *os.File, ce *parser.CallExpression, tt *parser.TupleType) {
+	results := []string{}
+	for i := range tt.ElementTypes {
+		results = append(results, fmt.Sprintf("r%d", i))
+	}
+	typeName := cg.typeToGoString(tt)
+	fmt.Fprintf(file, "func() %s { %s := ", typeName, strings.Join(results, ", "))
+	delete(cg.analyzer.ResultTuples, ce)
+	cg.generateCallExpression(file, ce)
+	cg.analyzer.ResultTuples[ce] = tt
+	fmt.Fprintf(file, "; return %s{%s} }()", typeName, strings.Join(results, ", "))
+}
@@ -0,0 +1,63 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+)
+
+// generateAssignmentExpression writes name := value as a Go function literal
+// called in place, which assigns the value to the name and returns it. The
+// name is declared before the statement by declareAssignmentExpressions.
+// Assigned to a name of no one type, the value keeps its own type, so that
+// the expression can still be compared, as in (n := n - 1) >= 0.
+func (cg *CodeGenerator) generateAssignmentExpression(file *os.File, ae *parser.AssignmentExpression) {
+	name := cg.goExpression(ae.Target).String()
+	targetType := cg.typeToGoString(cg.getExpressionType(ae.Target))
+	valueType := cg.typeToGoString(cg.getExpressionType(ae.Value))
+	if targetType == valueType {
+		fmt.Fprintf(file, "func() %s { %s = ", targetType, name)
+		cg.generateExpression(file, ae.Value)
+		fmt.Fprintf(file, "; return %s }()", name)
+		return
+	}
+	fmt.Fprintf(file, "func() %s { value := ", valueType)
+	cg.generateExpression(file, ae.Value)
+	fmt.Fprintf(file, "; %s = value; return value }()", name)
+}
+
+// declareAssignmentExpressions declares the names assigned by the assignment
+// expressions of a statement that aren't declared yet, before the statement,
+// so that they outlive it as in Python. The conditions of elif branches are
+// in the statement of their if, since Go can't declare a variable between
+// an if and its else if.
+func (cg *CodeGenerator) declareAssignmentExpressions(file *os.File, stmt parser.Statement) {
+	parser.Inspect(stmt, func(n parser.Node) bool {
+		switch node := n.(type) {
+		case *parser.BlockStatement, *parser.FunctionLiteral, *parser.ClassStatement, *parser.LambdaExpression:
+			// Their statements declare their own names
+			return false
+		case *parser.IfStatement:
+			if node != nil {
+				cg.declareAssignmentExpressions(file, &parser.ExpressionStatement{Expression: node.Condition})
+				if elif := node.Elif(); elif != nil {
+					cg.declareAssignmentExpressions(file, elif)
+				}
+			}
+			return false
+		case *parser.AssignmentExpression:
+			ident, ok := node.Target.(*parser.Identifier)
+			if !ok {
+				break
+			}
+			symbol, found := cg.analyzer.CurrentTable.Resolve(ident.Value)
+			if !found || symbol.Metadata != nil {
+				break
+			}
+			cg.writeIndent(file)
+			fmt.Fprintf(file, "var %s %s\n", cg.goExpression(ident).String(), cg.typeToGoString(symbol.Type))
+			symbol.Metadata = map[string]any{"set": true}
+		}
+		return true
+	})
+}
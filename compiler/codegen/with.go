@@ -0,0 +1,130 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+)
+
+// With statements
+//
+// A with statement runs its body in a closure, as a try statement does,
+// after entering its context managers and deferring their exits, so that
+// they exit however the body ends: a file is closed, a lock unlocked and
+// __exit__ called even when the body returns or raises. Return, break and
+// continue leave the closure through the statement's flow variable.
+
+// generateWithStatement writes a with statement.
+func (cg *CodeGenerator) generateWithStatement(file *os.File, ws *parser.WithStatement, prevSymbolTable *semantic.SymbolTable) {
+	if ws == nil {
+		return
+	}
+	cg.withCount++
+	frame := &tryFrame{name: fmt.Sprintf("with%d", cg.withCount)}
+	flows := map[int]bool{}
+	addFlows(flows, ws.Body.Statements, false)
+	cg.declareFrame(file, frame, ws, flows)
+
+	// Names first bound by the statement are declared in its closure, and
+	// not outside it
+	declared := []*semantic.Symbol{}
+	for _, item := range ws.Items {
+		if item.Name == nil {
+			continue
+		}
+		if symbol, found := cg.analyzer.CurrentTable.Resolve(item.Name.Value); found && symbol.Metadata == nil {
+			declared = append(declared, symbol)
+		}
+	}
+
+	cg.tries = append(cg.tries, frame)
+	cg.openClosure(file)
+	for i, item := range ws.Items {
+		if manager, ok := cg.analyzer.ContextManagers[item]; ok {
+			cg.enterContext(file, fmt.Sprintf("%sContext%d", frame.name, i), item, manager, ws.Body)
+		}
+	}
+	cg.generateBlockStatement(file, ws.Body, prevSymbolTable)
+	cg.closeClosure(file)
+	cg.tries = cg.tries[:len(cg.tries)-1]
+	for _, symbol := range declared {
+		symbol.Metadata = nil
+	}
+	cg.generateFlows(file, frame, flows)
+}
+
+// enterContext writes the entering of an item of a with statement and the
+// deferred exit. The item is evaluated once, into the variable named by
+// context unless it is a variable already or its name is bound to it.
+func (cg *CodeGenerator) enterContext(file *os.File, context string, item *parser.WithItem, manager *semantic.ContextManager, body *parser.BlockStatement) {
+	switch ident, isIdent := item.Context.(*parser.Identifier); {
+	case item.Name != nil && manager.Kind != semantic.ClassContext:
+		context = cg.goName(item.Name.Value)
+		cg.bindName(file, item.Name.Value)
+		cg.generateExpression(file, item.Context)
+		fmt.Fprintln(file)
+	case isIdent:
+		context = cg.identifierName(ident.Value)
+	default:
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%s := ", context)
+		if manager.Value {
+			// Methods such as Lock take a pointer, which a copy would not share
+			fmt.Fprint(file, "&")
+		}
+		cg.generateExpression(file, item.Context)
+		fmt.Fprintln(file)
+	}
+
+	switch manager.Kind {
+	case semantic.ClassContext:
+		if item.Name != nil && usesIdentifier(body, item.Name.Value) {
+			cg.bindName(file, item.Name.Value)
+		} else {
+			cg.writeIndent(file)
+		}
+		fmt.Fprintf(file, "%s.__enter__()\n", context)
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "defer %s.__exit__()\n", context)
+	case semantic.LockContext:
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%s.Lock()\n", context)
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "defer %s.Unlock()\n", context)
+	case semantic.CloserContext:
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "defer %s.Close()\n", context)
+	}
+}
+
+// bindName starts the assignment of the name of an item of a with statement,
+// declaring it unless it was declared already.
+func (cg *CodeGenerator) bindName(file *os.File, name string) {
+	cg.writeIndent(file)
+	symbol, found := cg.analyzer.CurrentTable.Resolve(name)
+	if found && symbol.Metadata == nil {
+		fmt.Fprintf(file, "%s := ", cg.goName(name))
+		symbol.Metadata = map[string]any{"set": true}
+		return
+	}
+	fmt.Fprintf(file, "%s = ", cg.goName(name))
+}
+
+// generateOpen writes a call of open, whose mode is "r" unless it is given.
+func (cg *CodeGenerator) generateOpen(file *os.File, ce *parser.CallExpression) {
+	cg.useHelper("simpleOpen")
+	fmt.Fprint(file, "simpleOpen(")
+	for _, arg := range ce.Arguments {
+		if cg.getExpressionType(arg).String() == "string" {
+			cg.generateExpression(file, arg)
+		} else {
+			cg.generateFormatCall(file, "str", arg)
+		}
+		fmt.Fprint(file, ", ")
+	}
+	if len(ce.Arguments) == 1 {
+		fmt.Fprint(file, `"r", `)
+	}
+	fmt.Fprintf(file, "%d, %q)", ce.Token.Line, cg.tracebackFunction())
+}
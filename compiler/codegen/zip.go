@@ -0,0 +1,64 @@
+package codegen
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+	"strings"
+)
+
+// generateZipLoop writes for a, b in zip(xs, ys): as a loop counting up to
+// the length of the shortest list, taking the item of each at the index at
+// the top of the body. Lists other than plain names are evaluated once,
+// into simpleZip0, simpleZip1 and so on, in a block of their own.
+func (cg *CodeGenerator) generateZipLoop(file *os.File, fs *parser.ForStatement, z *semantic.Zip, prevSymbolTable *semantic.SymbolTable) {
+	lists := make([]string, len(z.Lists))
+	block := false
+	for i, list := range z.Lists {
+		if ident, ok := list.(*parser.Identifier); ok && z.Kinds[i] == "list" {
+			lists[i] = cg.goName(ident.Value)
+			continue
+		}
+		if !block {
+			block = true
+			cg.writeIndent(file)
+			fmt.Fprintln(file, "{")
+			cg.indentLevel++
+		}
+		lists[i] = fmt.Sprintf("simpleZip%d", i)
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%s := ", lists[i])
+		cg.generateItems(file, z.Kinds[i], list)
+		fmt.Fprintln(file)
+	}
+
+	lengths := make([]string, len(lists))
+	for i, list := range lists {
+		lengths[i] = "len(" + list + ")"
+	}
+	cg.writeIndent(file)
+	fmt.Fprintf(file, "for simpleI := 0; simpleI < min(%s); simpleI++ {\n", strings.Join(lengths, ", "))
+	cg.indentLevel++
+	variables, items := []string{}, []string{}
+	for i, list := range lists {
+		if variable := cg.loopVariable(fs, i); variable != "_" {
+			variables = append(variables, variable)
+			items = append(items, list+"[simpleI]")
+		}
+	}
+	if len(variables) > 0 {
+		cg.writeIndent(file)
+		fmt.Fprintf(file, "%s := %s\n", strings.Join(variables, ", "), strings.Join(items, ", "))
+	}
+	cg.generateBlockStatement(file, fs.Body, prevSymbolTable)
+	cg.indentLevel--
+	cg.writeIndent(file)
+	fmt.Fprintln(file, "}")
+
+	if block {
+		cg.indentLevel--
+		cg.writeIndent(file)
+		fmt.Fprintln(file, "}")
+	}
+}
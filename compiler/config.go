@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+	"path/filepath"
+)
+
+// projectConfigFile is read from the directory of the compiled program.
+const projectConfigFile = "simple.json"
+
+// projectConfig holds project-wide compiler settings.
+type projectConfig struct {
+	Imports struct {
+		Allow []string `json:"allow"`
+		Deny  []string `json:"deny"`
+	} `json:"imports"`
+	// Transforms are the plugins run as -transform runs them, relative to
+	// the directory of simple.json.
+	Transforms []string `json:"transforms"`
+}
+
+// loadProjectConfig reads simple.json from dir. A missing file yields an empty config.
+func loadProjectConfig(dir string) (*projectConfig, error) {
+	config := &projectConfig{}
+	path := filepath.Join(dir, projectConfigFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", path, err)
+	}
+	for i, transform := range config.Transforms {
+		if !filepath.IsAbs(transform) {
+			config.Transforms[i] = filepath.Join(dir, transform)
+		}
+	}
+	return config, nil
+}
+
+// sandboxImports are the standard library packages a sandboxed program may
+// import: none of them can touch the file system, network, processes or
+// memory safety.
+var sandboxImports = []string{
+	"bytes", "container/heap", "container/list", "container/ring",
+	"encoding/base64", "encoding/hex", "encoding/json", "errors", "fmt",
+	"hash/crc32", "html", "math", "math/big", "math/bits", "math/rand",
+	"regexp", "sort", "strconv", "strings", "sync", "text/template",
+	"time", "unicode", "unicode/utf8",
+}
+
+// importPolicy builds the analyzer policy from the project config. In
+// sandbox mode the sandbox allowlist replaces the project's, while the
+// project's deny rules still apply.
+func (c *projectConfig) importPolicy(sandbox bool) semantic.Policy {
+	policy := semantic.Policy{
+		Allow: c.Imports.Allow,
+		Deny:  c.Imports.Deny,
+	}
+	if sandbox {
+		policy.Offline = true
+		policy.Allow = sandboxImports
+	}
+	return policy
+}
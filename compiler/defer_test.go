@@ -0,0 +1,80 @@
+package main
+
+import "testing"
+
+func TestDefer(t *testing.T) {
+	tests := []struct {
+		name, source, want string
+	}{
+		{
+			name: "last in, first out",
+			source: `def show(label):
+    print(label)
+
+def f():
+    defer show("first")
+    defer show("second")
+    defer show("third")
+    print("body")
+
+f()
+`,
+			want: "body\nthird\nsecond\nfirst\n",
+		},
+		{
+			name: "arguments evaluated at the defer",
+			source: `def show(label):
+    print(label)
+
+def f():
+    x = 1
+    defer show("x was " + str(x))
+    x = 2
+    print("x is " + str(x))
+
+f()
+`,
+			want: "x is 2\nx was 1\n",
+		},
+		{
+			name: "run on return",
+			source: `def show(label):
+    print(label)
+
+def f(n):
+    defer show("deferred")
+    if n > 0:
+        return "early"
+    print("body")
+    return "late"
+
+print(f(1))
+print(f(0))
+`,
+			want: "deferred\nearly\nbody\ndeferred\nlate\n",
+		},
+		{
+			name: "run on raise",
+			source: `def show(label):
+    print(label)
+
+def f():
+    defer show("cleanup")
+    raise ValueError("boom")
+
+try:
+    f()
+except ValueError as e:
+    print("caught " + str(e))
+`,
+			want: "cleanup\ncaught boom\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := runProgram(t, test.source); got != test.want {
+				t.Errorf("printed\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"sort"
+	"strings"
+)
+
+// defineFlags are the -define NAME=value flags, which set the values of
+// variables the program assigns at its top level, such as a DEBUG constant
+// turned off for release builds.
+type defineFlags map[string]string
+
+var defines = defineFlags{}
+
+func (d defineFlags) String() string {
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = name + "=" + d[name]
+	}
+	return strings.Join(names, ",")
+}
+
+func (d defineFlags) Set(value string) error {
+	name, literal, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("%q isn't NAME=value", value)
+	}
+	d[name] = literal
+	return nil
+}
+
+// applyDefines gives the variables named by -define flags their values, in
+// place of the values the program assigns them at its top level.
+func applyDefines(program *parser.Program) error {
+	for name, value := range defines {
+		assigned := false
+		for _, stmt := range program.Statements {
+			as, ok := stmt.(*parser.AssignmentStatement)
+			if !ok || as.Operator != "" || len(as.Left) != 1 || as.Left[0].String() != name {
+				continue
+			}
+			as.Value = defineValue(value)
+			assigned = true
+			break
+		}
+		if !assigned {
+			return fmt.Errorf("-define %s: the program doesn't assign %s at its top level", name, name)
+		}
+	}
+	return nil
+}
+
+// defineValue returns the literal a -define flag gives: True, False, a
+// number or a quoted string, or otherwise the text itself as a string.
+func defineValue(value string) parser.Expression {
+	p := parser.NewParser(lexer.NewLexer(value))
+	program := p.ParseProgram()
+	if len(program.Statements) == 1 {
+		if es, ok := program.Statements[0].(*parser.ExpressionStatement); ok && semantic.IsLiteral(es.Expression) {
+			return es.Expression
+		}
+	}
+	return &parser.StringLiteral{Token: lexer.Token{Type: lexer.TokenString, Literal: value}, Value: value}
+}
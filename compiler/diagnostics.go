@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+)
+
+// Diagnostics
+//
+// Errors and warnings are written to standard error, so that they don't mix
+// with what a command prints, such as the path of the binary simple build
+// makes. On a terminal they are coloured: Error in red, warning in yellow,
+// and the line and column an error is at in bold, so that the message is
+// easy to find among the output of the go command. Setting NO_COLOR, as
+// programs following no-color.org do, or TERM=dumb turns colour off.
+
+// colorDiagnostics is whether diagnostics are coloured.
+var colorDiagnostics = colorEnabled()
+
+// colorEnabled reports whether standard error is a terminal that colour
+// can be written to. Windows consoles show escape codes only when asked to,
+// so diagnostics are plain there.
+func colorEnabled() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" || runtime.GOOS == "windows" {
+		return false
+	}
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ANSI escape codes of the colours of diagnostics.
+const (
+	red    = "\x1b[31;1m"
+	yellow = "\x1b[33;1m"
+	bold   = "\x1b[1m"
+	reset  = "\x1b[0m"
+)
+
+// position matches the position compiler errors end with.
+var position = regexp.MustCompile(`\(Line \d+, Column \d+\)`)
+
+// colored returns s in the colour code, or s itself without colour.
+func colored(code, s string) string {
+	if !colorDiagnostics {
+		return s
+	}
+	return code + s + reset
+}
+
+// printError writes an error to standard error.
+func printError(err error) {
+	message := err.Error()
+	if colorDiagnostics {
+		message = position.ReplaceAllString(message, bold+"$0"+reset)
+	}
+	fmt.Fprintln(os.Stderr, colored(red, "Error:"), message)
+}
+
+// printErrorf formats and writes an error to standard error.
+func printErrorf(format string, args ...any) {
+	printError(fmt.Errorf(format, args...))
+}
+
+// printWarning writes a warning to standard error.
+func printWarning(message string) {
+	fmt.Fprintln(os.Stderr, colored(yellow, "warning:"), message)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"os"
+	"strings"
+)
+
+// Documentation
+//
+// simple doc prints what a module offers the programs importing it: the
+// comment it starts with, then its public functions and classes, each with
+// the comment written directly above it, as Go's doc comments are written.
+// A module or function without such a comment is documented by its
+// docstring instead. A class is listed with its public methods and
+// __init__; names starting with _ are private and left out. Given a name,
+// doc prints only that function or class.
+
+// docCommand prints the documentation of the module of args, or of the
+// function or class of it named.
+func docCommand(args []string) int {
+	if len(args) < 1 || len(args) > 2 || !strings.HasSuffix(args[0], ".simple") {
+		flag.Usage()
+		return 2
+	}
+	src, err := os.ReadFile(args[0])
+	if err != nil {
+		printError(err)
+		return 1
+	}
+	lines := strings.Split(string(src), "\n")
+	p := parser.NewParser(lexer.NewLexer(string(src)))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		printErrorf("%s", strings.Join(errs, "\n"))
+		return 1
+	}
+
+	if len(args) == 1 {
+		comment := moduleComment(lines)
+		if len(comment) == 0 {
+			comment = docstringLines(program.Doc)
+		}
+		if len(comment) > 0 {
+			fmt.Println(strings.Join(comment, "\n"))
+			fmt.Println()
+		}
+	}
+	found := false
+	for _, stmt := range program.Statements {
+		switch stmt := stmt.(type) {
+		case *parser.FunctionLiteral:
+			if stmt.Name == nil || semantic.IsPrivate(stmt.Name.Value) || len(args) == 2 && stmt.Name.Value != args[1] {
+				continue
+			}
+			found = true
+			printDoc(lines, "", signature(stmt), stmt.Token.Line, stmt.Doc)
+		case *parser.ClassStatement:
+			if stmt.Name == nil || semantic.IsPrivate(stmt.Name.Value) || len(args) == 2 && stmt.Name.Value != args[1] {
+				continue
+			}
+			found = true
+			heading := "class " + stmt.Name.Value
+			if len(stmt.Bases) > 0 {
+				bases := []string{}
+				for _, base := range stmt.Bases {
+					bases = append(bases, base.Value)
+				}
+				heading += "(" + strings.Join(bases, ", ") + ")"
+			}
+			printDoc(lines, "", heading, stmt.Token.Line, "")
+			if stmt.Body == nil {
+				continue
+			}
+			for _, s := range stmt.Body.Statements {
+				method, ok := s.(*parser.FunctionLiteral)
+				if !ok || method.Name == nil || semantic.IsPrivate(method.Name.Value) && method.Name.Value != "__init__" {
+					continue
+				}
+				printDoc(lines, "    ", signature(method), method.Token.Line, method.Doc)
+			}
+		}
+	}
+	if len(args) == 2 && !found {
+		printErrorf("%s defines no public function or class %s", args[0], args[1])
+		return 1
+	}
+	return 0
+}
+
+// moduleComment returns the text of the comment lines a module starts
+// with, after a #! line.
+func moduleComment(lines []string) []string {
+	var comment []string
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if i == 0 && strings.HasPrefix(line, "#!") {
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		comment = append(comment, commentText(line))
+	}
+	return comment
+}
+
+// printDoc prints a heading, such as the signature of a function, and the
+// comment written directly above its line, above any decorators, or else
+// its docstring, indented under it.
+func printDoc(lines []string, indent, heading string, line int, doc string) {
+	fmt.Println(indent + heading)
+	var comment []string
+	for i := line - 2; i >= 0 && i < len(lines); i-- {
+		text := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(text, "@") {
+			continue
+		}
+		if !strings.HasPrefix(text, "#") {
+			break
+		}
+		comment = append([]string{commentText(text)}, comment...)
+	}
+	if len(comment) == 0 {
+		comment = docstringLines(doc)
+	}
+	for _, text := range comment {
+		fmt.Println(strings.TrimRight(indent+"    "+text, " "))
+	}
+	fmt.Println()
+}
+
+// docstringLines returns the lines of a docstring as Python's
+// inspect.cleandoc does: without the indentation its lines after the first
+// share, or the blank lines around them.
+func docstringLines(doc string) []string {
+	lines := strings.Split(strings.ReplaceAll(doc, "\t", "    "), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		if text := strings.TrimLeft(line, " "); text != "" && (indent < 0 || len(line)-len(text) < indent) {
+			indent = len(line) - len(text)
+		}
+	}
+	lines[0] = strings.TrimSpace(lines[0])
+	for i := 1; i < len(lines); i++ {
+		lines[i] = strings.TrimRight(lines[i], " ")
+		if len(lines[i]) >= indent && indent > 0 {
+			lines[i] = lines[i][indent:]
+		}
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// commentText returns the text of a comment line without its #.
+func commentText(line string) string {
+	text := strings.TrimPrefix(line, "#")
+	return strings.TrimPrefix(text, " ")
+}
+
+// signature returns the def line of a function, with the annotations it
+// was written with.
+func signature(fl *parser.FunctionLiteral) string {
+	params := []string{}
+	for i, param := range fl.Parameters {
+		if i < len(fl.Annotations) && fl.Annotations[i] != nil {
+			params = append(params, param.Value+": "+fl.Annotations[i].String())
+			continue
+		}
+		params = append(params, param.Value)
+	}
+	sig := "def " + fl.Name.Value + "(" + strings.Join(params, ", ") + ")"
+	if fl.Result != nil {
+		sig += " -> " + fl.Result.String()
+	}
+	return sig
+}
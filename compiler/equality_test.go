@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestStructuralEquality(t *testing.T) {
+	source := `def same(a, b):
+    return a == b
+
+nested = [[1, 2], [3]]
+lists = {"a": [1, 2], "b": [3]}
+print([1, 2] == [1, 2])
+print([1, 2] != [1, 2])
+print([1, 2] == [2, 1])
+print(nested[0] == [1, 2])
+print(nested[1] != [1, 2])
+print([1, 2] == nested[0])
+print({"x": 1} == {"x": 1})
+print({"x": 1} == {"x": 2})
+print(lists["a"] == [1, 2])
+print(nested[0][1] == 2)
+print(same([1], [1]))
+`
+	want := "True\nFalse\nFalse\nTrue\nTrue\nTrue\nTrue\nFalse\nTrue\nTrue\nTrue\n"
+	if got := runProgram(t, source); got != want {
+		t.Errorf("printed\n%s\nwant\n%s", got, want)
+	}
+}
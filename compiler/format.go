@@ -0,0 +1,218 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Formatting
+//
+// simple fmt lays programs out one way, so that programs read alike and a
+// change shows only what changed: blocks are indented by four spaces, tabs
+// included; lines don't end in spaces; no more than two blank lines come in
+// a row, and none at the start or end of the file, which ends in a newline.
+// A comment on a line of its own is indented as the block it is in, or as
+// the code after it. What is within a line is left as written, as are the
+// lines of strings in triple quotes and backticks, and a line continuing a
+// statement, inside brackets or after a backslash, moves with the line it
+// continues. A file whose indentation the lexer would reject is left alone.
+
+// formatCommand formats the .simple files of args, files or directories,
+// rewriting those whose layout changes and printing their names.
+func formatCommand(args []string) int {
+	if len(args) == 0 {
+		flag.Usage()
+		return 2
+	}
+	var files []string
+	for _, arg := range args {
+		err := filepath.WalkDir(arg, func(path string, entry fs.DirEntry, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case entry.IsDir() && path != arg && strings.HasPrefix(entry.Name(), "."):
+				return filepath.SkipDir
+			case !entry.IsDir() && (path == arg || strings.HasSuffix(path, ".simple")):
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			printError(err)
+			return 1
+		}
+	}
+
+	status := 0
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			printError(err)
+			status = 1
+			continue
+		}
+		formatted, err := formatSource(string(src))
+		if err != nil {
+			printErrorf("%s: %v", file, err)
+			status = 1
+			continue
+		}
+		if formatted == string(src) {
+			continue
+		}
+		if err := os.WriteFile(file, []byte(formatted), 0644); err != nil {
+			printError(err)
+			status = 1
+			continue
+		}
+		fmt.Println(file)
+	}
+	return status
+}
+
+// formatSource returns a program laid out in the standard way.
+func formatSource(src string) (string, error) {
+	var out []string
+	indents := []int{0} // the widths of the blocks open, as written
+	quote := ""         // the quotes of a string the last line ended inside
+	depth := 0          // the brackets open
+	continued := false  // whether the last line ended in a backslash
+	shift := 0          // how far the last statement moved
+	blanks := 0         // the blank lines since the last line out
+	comments := []int{} // the lines of out that are comments waiting for code
+
+	emit := func(line string) {
+		for i := 0; len(out) > 0 && i < min(blanks, 2); i++ {
+			out = append(out, "")
+		}
+		blanks = 0
+		out = append(out, line)
+	}
+	indentComments := func(width int) {
+		for _, i := range comments {
+			out[i] = strings.Repeat(" ", width) + strings.TrimLeft(out[i], " \t")
+		}
+		comments = comments[:0]
+	}
+
+	for n, line := range strings.Split(src, "\n") {
+		if quote != "" {
+			// The line is the text of a string
+			quote, depth, continued = scanLine(line, quote, depth)
+			emit(line)
+			continue
+		}
+		content := strings.TrimLeft(line, " \t")
+		nextQuote, nextDepth, nextContinued := scanLine(content, "", depth)
+		if nextQuote == "" {
+			content = strings.TrimRight(content, " \t\r")
+		}
+		width := indentWidth(line)
+
+		switch {
+		case content == "":
+			blanks++
+		case depth > 0 || continued:
+			emit(strings.Repeat(" ", max(width+shift, 0)) + content)
+		case strings.HasPrefix(content, "#"):
+			emit(content)
+			level := -1
+			for i, w := range indents {
+				if w == width {
+					level = i
+				}
+			}
+			if level >= 0 {
+				out[len(out)-1] = strings.Repeat(" ", 4*level) + content
+			} else {
+				comments = append(comments, len(out)-1)
+			}
+		default:
+			if top := indents[len(indents)-1]; width > top {
+				indents = append(indents, width)
+			}
+			for width < indents[len(indents)-1] {
+				indents = indents[:len(indents)-1]
+			}
+			if width != indents[len(indents)-1] {
+				return "", fmt.Errorf("line %d is indented less than the block it is in, but not as little as the block around it", n+1)
+			}
+			newWidth := 4 * (len(indents) - 1)
+			shift = newWidth - width
+			indentComments(newWidth)
+			emit(strings.Repeat(" ", newWidth) + content)
+		}
+		quote, depth, continued = nextQuote, nextDepth, nextContinued
+	}
+	indentComments(4 * (len(indents) - 1))
+	if len(out) == 0 {
+		return "", nil
+	}
+	return strings.Join(out, "\n") + "\n", nil
+}
+
+// indentWidth returns the width of the indentation of a line, counting a
+// tab as four spaces as the lexer does.
+func indentWidth(line string) int {
+	width := 0
+	for _, ch := range line {
+		switch ch {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// scanLine reads a line that starts inside a string in the given quotes,
+// or outside any when quote is empty, with depth brackets open. It returns
+// the quotes of the string the line ends inside, the brackets open at its
+// end, and whether it ends in a backslash joining it to the next.
+func scanLine(line, quote string, depth int) (string, int, bool) {
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		if quote != "" {
+			switch {
+			case ch == '\\' && quote != "`":
+				i++
+			case strings.HasPrefix(line[i:], quote):
+				i += len(quote) - 1
+				quote = ""
+			}
+			continue
+		}
+		switch ch {
+		case '#':
+			return "", depth, false
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth = max(depth-1, 0)
+		case '`':
+			quote = "`"
+		case '"', '\'':
+			quote = string(ch)
+			if triple := strings.Repeat(quote, 3); strings.HasPrefix(line[i:], triple) {
+				quote = triple
+				i += 2
+			}
+		case '\\':
+			if i == len(line)-1 {
+				return "", depth, true
+			}
+		}
+	}
+	if len(quote) == 1 && quote != "`" {
+		// A string in one quote ends at the end of its line
+		quote = ""
+	}
+	return quote, depth, false
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Golden files
+//
+// TestGolden compiles every program in testdata/golden and checks that the
+// Go generated for x.simple is the same as x.go.golden beside it and that go
+// vet finds nothing wrong with it. It guards code generation against changes
+// no one meant to make. After a change that was meant,
+//
+//	go test -run TestGolden -update
+//
+// rewrites the golden files of the programs whose generated Go changed, to
+// be reviewed with the change.
+
+var update = flag.Bool("update", false, "rewrite the golden files of the programs whose generated Go changed")
+
+func TestGolden(t *testing.T) {
+	programs, err := filepath.Glob(filepath.Join("testdata", "golden", "*.simple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(programs) == 0 {
+		t.Fatal("no programs in testdata/golden")
+	}
+	for _, program := range programs {
+		t.Run(strings.TrimSuffix(filepath.Base(program), ".simple"), func(t *testing.T) {
+			content, err := os.ReadFile(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outputDir := filepath.Join(t.TempDir(), "golden")
+			if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := compile(string(content), outputDir, true); err != nil {
+				t.Fatal(err)
+			}
+			generated, err := os.ReadFile(filepath.Join(outputDir, "main.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := strings.TrimSuffix(program, ".simple") + ".go.golden"
+			want, err := os.ReadFile(golden)
+			switch {
+			case *update && !bytes.Equal(generated, want):
+				if err := os.WriteFile(golden, generated, 0644); err != nil {
+					t.Fatal(err)
+				}
+				t.Logf("updated %s", golden)
+			case os.IsNotExist(err):
+				t.Fatalf("no golden file %s; run with -update to write it", golden)
+			case err != nil:
+				t.Fatal(err)
+			case !bytes.Equal(generated, want):
+				line, w, g := firstDifference(want, generated)
+				t.Fatalf("generated Go differs from %s at line %d:\n  want: %s\n  got:  %s", golden, line, w, g)
+			}
+
+			if err := writeGoMod(outputDir); err != nil {
+				t.Fatal(err)
+			}
+			cmd := exec.Command("go", "vet", ".")
+			cmd.Dir = outputDir
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("go vet: %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+// TestConformance runs the conformance suite of simple selftest: each
+// program x.simple of testdata/conformance is built and run, and must print
+// what its x.out says Python prints.
+func TestConformance(t *testing.T) {
+	programs, err := filepath.Glob(filepath.Join(conformanceDir, "*.simple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, program := range programs {
+		name := strings.TrimSuffix(filepath.Base(program), ".simple")
+		t.Run(name, func(t *testing.T) {
+			if err := selftestProgram(filepath.ToSlash(program), filepath.Join(t.TempDir(), name)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// runProgram compiles, builds and runs a Simple program, returning what it
+// prints.
+func runProgram(t *testing.T, source string) string {
+	t.Helper()
+	out, err := buildAndRun([]byte(source), filepath.Join(t.TempDir(), "program"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
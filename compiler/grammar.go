@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Editor grammars
+//
+// simple grammar exports a grammar for highlighting Simple in an editor:
+// textmate writes simple.tmLanguage.json, which VS Code and most editors
+// read, and tree-sitter writes grammar.js and queries/highlights.scm, which
+// tree-sitter generate makes into a parser for Neovim and other editors. The
+// keywords and operators are the lexer's own, so exporting again after a
+// keyword or operator is added keeps highlighting in step with the language.
+
+// grammarFormats are the formats simple grammar exports.
+var grammarFormats = []string{"textmate", "tree-sitter"}
+
+// grammarWords are the lexer's keywords, in the groups editors colour
+// differently.
+type grammarWords struct {
+	keywords  []string // def, if, return and the other statements
+	operators []string // and, or and not
+	constants []string // True, False and None
+	builtins  []string // print
+}
+
+// lexerWords sorts the lexer's keywords into groups.
+func lexerWords() grammarWords {
+	var words grammarWords
+	for word, tokenType := range lexer.Keywords() {
+		switch tokenType {
+		case lexer.TokenTrue, lexer.TokenFalse, lexer.TokenNone:
+			words.constants = append(words.constants, word)
+		case lexer.TokenAnd, lexer.TokenOr, lexer.TokenNot:
+			words.operators = append(words.operators, word)
+		case lexer.TokenIdentifier:
+			words.builtins = append(words.builtins, word)
+		default:
+			words.keywords = append(words.keywords, word)
+		}
+	}
+	for _, group := range [][]string{words.keywords, words.operators, words.constants, words.builtins} {
+		slices.Sort(group)
+	}
+	return words
+}
+
+// exportGrammar writes the grammar of a format into dir and returns the
+// files it wrote.
+func exportGrammar(format, dir string) ([]string, error) {
+	files := map[string]string{}
+	switch format {
+	case "textmate":
+		grammar, err := textMateGrammar()
+		if err != nil {
+			return nil, err
+		}
+		files["simple.tmLanguage.json"] = grammar
+	case "tree-sitter":
+		files["grammar.js"] = treeSitterGrammar()
+		files[filepath.Join("queries", "highlights.scm")] = treeSitterHighlights
+	default:
+		return nil, fmt.Errorf("unknown grammar format %q; use %s", format, strings.Join(grammarFormats, " or "))
+	}
+
+	var written []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+	}
+	slices.Sort(written)
+	return written, nil
+}
+
+// textMatePattern is a rule of a TextMate grammar, matching a token with a
+// regular expression or a span between two.
+type textMatePattern struct {
+	Name     string            `json:"name"`
+	Match    string            `json:"match,omitempty"`
+	Begin    string            `json:"begin,omitempty"`
+	End      string            `json:"end,omitempty"`
+	Patterns []textMatePattern `json:"patterns,omitempty"`
+}
+
+// textMateGrammar returns a TextMate grammar for Simple.
+func textMateGrammar() (string, error) {
+	words := lexerWords()
+	operators, punctuation := lexer.Symbols()
+
+	escape := textMatePattern{
+		Name:  "constant.character.escape.simple",
+		Match: `\\(?:[0-7]{1,3}|x[0-9A-Fa-f]{2}|u[0-9A-Fa-f]{4}|U[0-9A-Fa-f]{8}|.)`,
+	}
+	interpolation := textMatePattern{
+		Name:  "meta.interpolation.simple",
+		Match: `\{[^{}]*\}`,
+	}
+	var strs []textMatePattern
+	for _, quote := range []string{`"""`, `'''`, `"`, `'`} {
+		name, end := "string.quoted.double.simple", quote
+		switch {
+		case len(quote) == 3:
+			name = "string.quoted.triple.simple"
+		case quote == `'`:
+			name = "string.quoted.single.simple"
+		}
+		if len(quote) == 1 {
+			// A string in one quote ends at the end of its line
+			end = quote + "|$"
+		}
+		strs = append(strs,
+			textMatePattern{Name: name, Begin: `\b[fF]` + quote, End: end, Patterns: []textMatePattern{escape, interpolation}},
+			textMatePattern{Name: name, Begin: quote, End: end, Patterns: []textMatePattern{escape}},
+		)
+	}
+
+	patterns := []textMatePattern{{Name: "comment.line.number-sign.simple", Match: `#.*$`}}
+	patterns = append(patterns, strs...)
+	patterns = append(patterns,
+		textMatePattern{Name: "string.quoted.other.raw.simple", Begin: "`", End: "`"},
+		textMatePattern{Name: "constant.numeric.simple", Match: `\b\d+(?:\.\d*)?`},
+		textMatePattern{Name: "keyword.control.simple", Match: wordsPattern(words.keywords)},
+		textMatePattern{Name: "keyword.operator.logical.simple", Match: wordsPattern(words.operators)},
+		textMatePattern{Name: "constant.language.simple", Match: wordsPattern(words.constants)},
+		textMatePattern{Name: "support.function.builtin.simple", Match: wordsPattern(words.builtins)},
+		textMatePattern{Name: "keyword.operator.simple", Match: symbolsPattern(operators)},
+		textMatePattern{Name: "punctuation.simple", Match: symbolsPattern(punctuation)},
+	)
+
+	grammar := struct {
+		Schema    string            `json:"$schema"`
+		Comment   string            `json:"comment"`
+		Name      string            `json:"name"`
+		ScopeName string            `json:"scopeName"`
+		FileTypes []string          `json:"fileTypes"`
+		Patterns  []textMatePattern `json:"patterns"`
+	}{
+		Schema:    "https://raw.githubusercontent.com/martinring/tmlanguage/master/tmlanguage.json",
+		Comment:   "Generated by " + version + " with simple grammar textmate; export it again rather than editing it.",
+		Name:      "Simple",
+		ScopeName: "source.simple",
+		FileTypes: []string{"simple"},
+		Patterns:  patterns,
+	}
+	content, err := json.MarshalIndent(grammar, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(content) + "\n", nil
+}
+
+// wordsPattern returns a regular expression matching any of words whole.
+func wordsPattern(words []string) string {
+	return `\b(?:` + strings.Join(words, "|") + `)\b`
+}
+
+// symbolsPattern returns a regular expression matching any of symbols,
+// which are longest first so that ** isn't read as two *.
+func symbolsPattern(symbols []string) string {
+	quoted := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		quoted[i] = regexp.QuoteMeta(symbol)
+	}
+	return strings.Join(quoted, "|")
+}
+
+// treeSitterGrammar returns a tree-sitter grammar for Simple. It reads the
+// tokens of a program, which is all highlighting needs, and not the
+// statements they make.
+func treeSitterGrammar() string {
+	words := lexerWords()
+	operators, punctuation := lexer.Symbols()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Generated by %s with simple grammar tree-sitter; export it again\n", version)
+	b.WriteString("// rather than editing it.\n")
+	b.WriteString("module.exports = grammar({\n")
+	b.WriteString("  name: 'simple',\n\n")
+	b.WriteString("  extras: $ => [/\\s/, $.comment],\n\n")
+	b.WriteString("  word: $ => $.identifier,\n\n")
+	b.WriteString("  rules: {\n")
+	b.WriteString("    source_file: $ => repeat($._token),\n\n")
+	b.WriteString("    _token: $ => choice($.keyword, $.word_operator, $.constant, $.builtin, $.operator, $.punctuation, $.string, $.number, $.identifier),\n\n")
+	writeTreeSitterChoice(&b, "keyword", words.keywords)
+	writeTreeSitterChoice(&b, "word_operator", words.operators)
+	writeTreeSitterChoice(&b, "constant", words.constants)
+	writeTreeSitterChoice(&b, "builtin", words.builtins)
+	writeTreeSitterChoice(&b, "operator", operators)
+	writeTreeSitterChoice(&b, "punctuation", punctuation)
+	b.WriteString("    string: $ => token(choice(\n")
+	b.WriteString("      /[fF]?\"\"\"([^\"\\\\]|\\\\(.|\\n)|\"[^\"\\\\]|\"\"[^\"\\\\])*\"\"\"/,\n")
+	b.WriteString("      /[fF]?'''([^'\\\\]|\\\\(.|\\n)|'[^'\\\\]|''[^'\\\\])*'''/,\n")
+	b.WriteString("      /[fF]?\"([^\"\\\\\\n]|\\\\(.|\\n))*\"/,\n")
+	b.WriteString("      /[fF]?'([^'\\\\\\n]|\\\\(.|\\n))*'/,\n")
+	b.WriteString("      /`[^`]*`/,\n")
+	b.WriteString("    )),\n\n")
+	b.WriteString("    number: $ => /\\d+(\\.\\d*)?/,\n\n")
+	b.WriteString("    identifier: $ => /[\\p{L}_][\\p{L}\\p{Nd}_]*/,\n\n")
+	b.WriteString("    comment: $ => token(seq('#', /.*/)),\n")
+	b.WriteString("  },\n")
+	b.WriteString("});\n")
+	return b.String()
+}
+
+// writeTreeSitterChoice writes a rule of a tree-sitter grammar matching any
+// of tokens.
+func writeTreeSitterChoice(b *strings.Builder, rule string, tokens []string) {
+	quoted := make([]string, len(tokens))
+	for i, token := range tokens {
+		quoted[i] = strconv.Quote(token)
+	}
+	fmt.Fprintf(b, "    %s: $ => choice(%s),\n\n", rule, strings.Join(quoted, ", "))
+}
+
+// treeSitterHighlights are the highlight queries of the tree-sitter grammar.
+const treeSitterHighlights = `(comment) @comment
+(string) @string
+(number) @number
+(keyword) @keyword
+(word_operator) @keyword.operator
+(constant) @constant.builtin
+(builtin) @function.builtin
+(operator) @operator
+(punctuation) @punctuation.delimiter
+(identifier) @variable
+`
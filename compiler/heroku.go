@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Heroku
+//
+// -platform heroku readies the directory of a program to be pushed to
+// Heroku as it is. Heroku's Go buildpack builds the generated package from
+// its go.mod and installs the binary in bin, named after the last element
+// of the module path, and the Procfile written beside it runs that binary
+// as the app's web process. The code generator makes the program's calls
+// of http.ListenAndServe listen on the port Heroku gives in PORT and answer
+// health checks on /healthz; see codegen/heroku.go.
+
+// writeProcfile writes the Procfile of the Heroku app in outputDir, whose
+// go.mod has been created, returning its path. The web process of a
+// Procfile already there is replaced and its other processes are kept.
+func writeProcfile(outputDir string) (string, error) {
+	goMod, err := os.ReadFile(filepath.Join(outputDir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	module := modulePath(goMod)
+	if module == "" {
+		return "", fmt.Errorf("%s has no module path", filepath.Join(outputDir, "go.mod"))
+	}
+	procfile := filepath.Join(outputDir, "Procfile")
+	lines := []string{"web: ./bin/" + path.Base(module)}
+	if old, err := os.ReadFile(procfile); err == nil {
+		for _, line := range strings.Split(strings.TrimRight(string(old), "\n"), "\n") {
+			if process, _, _ := strings.Cut(line, ":"); strings.TrimSpace(process) != "web" && strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return procfile, os.WriteFile(procfile, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// modulePath returns the module path a go.mod declares, or "".
+func modulePath(goMod []byte) string {
+	for _, line := range strings.Split(string(goMod), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlatformHeroku(t *testing.T) {
+	defer func(old string) { platform = old }(platform)
+	platform = "heroku"
+
+	source := `import "fmt"
+import "net/http"
+
+def hello(w, r):
+    fmt.Fprintln(w, "hello")
+
+http.HandleFunc("/", hello)
+http.ListenAndServe(":8080", None)
+`
+	outputDir := filepath.Join(t.TempDir(), "web")
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := compile(source, outputDir, true); err != nil {
+		t.Fatal(err)
+	}
+	generated, err := os.ReadFile(filepath.Join(outputDir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`simpleServe(":8080", nil)`, `os.Getenv("PORT")`, `mux.HandleFunc("/healthz"`} {
+		if !strings.Contains(string(generated), want) {
+			t.Errorf("main.go doesn't contain %s:\n%s", want, generated)
+		}
+	}
+}
+
+func TestWriteProcfile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/web\n\ngo 1.23.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Procfile"), []byte("web: ./old\nworker: ./bin/worker\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	procfile, err := writeProcfile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(procfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "web: ./bin/web\nworker: ./bin/worker\n"; string(got) != want {
+		t.Errorf("Procfile is\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/codegen"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"github.com/sasogeek/simple/compiler/verbose"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// hotReload is set by --hot. It makes compile route the main program's
+// top-level functions through the hot reload registry.
+var hotReload bool
+
+// hotDir holds the plugins of a hot reload session, inside the output directory.
+const hotDir = ".simple_hot"
+
+// programSources splits a program into the source of each top-level
+// function and the source of everything else. It works on lines rather than
+// the AST so that any edit, including to comments, counts as a change.
+func programSources(content string) (map[string]string, string) {
+	functions := map[string]string{}
+	var rest strings.Builder
+	current := ""
+	for _, line := range strings.Split(content, "\n") {
+		indented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.TrimSpace(line) == ""
+		if !indented {
+			current = ""
+			if strings.HasPrefix(line, "def ") {
+				name := strings.TrimSpace(strings.TrimPrefix(line, "def "))
+				if i := strings.IndexAny(name, "( :"); i >= 0 {
+					name = name[:i]
+				}
+				current = name
+			}
+		}
+		if current != "" {
+			functions[current] += line + "\n"
+		} else {
+			rest.WriteString(line + "\n")
+		}
+	}
+	return functions, rest.String()
+}
+
+// changedFunctions returns the functions whose source differs between two
+// versions of a program, or an error if the change cannot be hot reloaded.
+func changedFunctions(oldContent, newContent string) ([]string, error) {
+	oldFuncs, oldRest := programSources(oldContent)
+	newFuncs, newRest := programSources(newContent)
+	if oldRest != newRest {
+		return nil, fmt.Errorf("top-level code changed")
+	}
+	if len(oldFuncs) != len(newFuncs) {
+		return nil, fmt.Errorf("functions were added or removed")
+	}
+
+	changed := []string{}
+	for name, src := range newFuncs {
+		oldSrc, ok := oldFuncs[name]
+		if !ok {
+			return nil, fmt.Errorf("functions were added or removed")
+		}
+		if oldSrc != src {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// globalsUsed returns, for each of the named top-level functions of program
+// that uses a global variable, the first such variable it uses. A plugin
+// has its own copy of the program's package variables, which main never
+// ran in, so a reloaded function would see them unset and its assignments
+// would never reach the rest of the program.
+func globalsUsed(program *parser.Program, functions []string) map[string]string {
+	globals := semantic.Globals(program)
+	named := map[string]bool{}
+	for _, name := range functions {
+		named[name] = true
+	}
+	used := map[string]string{}
+	for _, stmt := range program.Statements {
+		parser.Inspect(stmt, func(n parser.Node) bool {
+			fn, ok := n.(*parser.FunctionLiteral)
+			if !ok || fn == nil || fn.Name == nil {
+				return true
+			}
+			if named[fn.Name.Value] {
+				if name := globalUsed(fn, globals); name != "" {
+					used[fn.Name.Value] = name
+				}
+			}
+			return false
+		})
+	}
+	return used
+}
+
+// globalUsed returns the first global variable fn uses, or "". A name fn
+// binds itself is a local variable unless fn declares it global.
+func globalUsed(fn *parser.FunctionLiteral, globals map[string]bool) string {
+	locals := map[string]bool{}
+	declared := map[string]bool{}
+	parser.Inspect(fn.Body, func(n parser.Node) bool {
+		if gs, ok := n.(*parser.GlobalStatement); ok && gs != nil {
+			for _, name := range gs.Names {
+				declared[name.Value] = true
+			}
+		}
+		for _, ident := range parser.Bindings(n) {
+			locals[ident.Value] = true
+		}
+		return true
+	})
+	for _, param := range fn.Parameters {
+		locals[param.Value] = true
+	}
+	used := ""
+	parser.Inspect(fn.Body, func(n parser.Node) bool {
+		ident, ok := n.(*parser.Identifier)
+		if ok && ident != nil && used == "" && globals[ident.Value] && (declared[ident.Value] || !locals[ident.Value]) {
+			used = ident.Value
+		}
+		return used == ""
+	})
+	return used
+}
+
+// checkHotGlobals returns an error if one of the changed functions of
+// content uses a global variable, which hot reloading can't carry over.
+func checkHotGlobals(content string, changed []string) error {
+	program := parser.NewParser(lexer.NewLexer(content)).ParseProgram()
+	used := globalsUsed(program, changed)
+	for _, name := range changed {
+		if global, ok := used[name]; ok {
+			return fmt.Errorf("%s uses the global variable %s, which a reloaded function can't share", name, global)
+		}
+	}
+	return nil
+}
+
+// buildHotPlugin compiles the changed functions of content into a plugin and
+// points the running program at it through .simple_hot/current.
+func buildHotPlugin(content, outputDir string, changed []string, generation int) error {
+	if _, err := compile(content, outputDir, true); err != nil {
+		return err
+	}
+	if err := codegen.WriteHotReloadPlugin(outputDir, changed, generation); err != nil {
+		return err
+	}
+
+	// Building from a file list names the plugin after a hash of the files,
+	// which differs every generation; the runtime refuses to load the same
+	// plugin path twice. The plugin is built with the flags the program
+	// was, such as -trimpath, or the runtime refuses to load it
+	pluginFile := filepath.Join(outputDir, hotDir, fmt.Sprintf("%d.so", generation))
+	args := append([]string{"build"}, goBuildFlags()...)
+	cmd := exec.Command("go", append(args, "-buildmode=plugin", "-tags", "simplehotplugin", "-o", pluginFile,
+		"main.go", "simple_buildinfo.go", "simple_hot_plugin.go")...)
+	cmd.Dir = outputDir
+	cmd.Stdout = os.Stdout
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	fmt.Fprint(os.Stderr, codegen.SimpleNames(stderr.String()))
+	if err != nil {
+		return fmt.Errorf("failed to build hot reload plugin: %w", err)
+	}
+
+	current := filepath.Join(outputDir, hotDir, "current")
+	if err := os.WriteFile(current+".tmp", []byte(pluginFile), 0644); err != nil {
+		return err
+	}
+	return os.Rename(current+".tmp", current)
+}
+
+// runHot runs the binary and, while it runs, rebuilds changed functions of
+// filename into plugins that the binary swaps in without restarting.
+func runHot(filename, outputDir, binaryName string) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	os.RemoveAll(filepath.Join(outputDir, hotDir))
+	if err := os.MkdirAll(filepath.Join(outputDir, hotDir), os.ModePerm); err != nil {
+		return err
+	}
+
+	cmd := exec.Command(filepath.Join(outputDir, binaryName))
+	cmd.Dir = outputDir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	run, err := startLimited(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to run binary: %w", err)
+	}
+	functions, _ := programSources(string(content))
+	names := []string{}
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if used := globalsUsed(parser.NewParser(lexer.NewLexer(string(content))).ParseProgram(), names); len(used) > 0 {
+		users := []string{}
+		for _, name := range names {
+			if _, ok := used[name]; ok {
+				users = append(users, name)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "[simple] warning: hot reload can't swap in functions that use global variables (%s); changing them needs a restart\n", strings.Join(users, ", "))
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- run.result(cmd.Wait()) }()
+
+	info, _ := os.Stat(filename)
+	modTime := info.ModTime()
+	generation := 0
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-exited:
+			return err
+		case <-ticker.C:
+			info, err := os.Stat(filename)
+			if err != nil || !info.ModTime().After(modTime) {
+				continue
+			}
+			modTime = info.ModTime()
+			newContent, err := os.ReadFile(filename)
+			if err != nil {
+				continue
+			}
+
+			changed, err := changedFunctions(string(content), string(newContent))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[simple] cannot hot reload (%v); restart to apply\n", err)
+				continue
+			}
+			if len(changed) == 0 {
+				continue
+			}
+			if err := checkHotGlobals(string(newContent), changed); err != nil {
+				fmt.Fprintf(os.Stderr, "[simple] cannot hot reload (%v); restart to apply\n", err)
+				continue
+			}
+			generation++
+			verbose.Logf(1, "hot reloading %s", strings.Join(changed, ", "))
+			if err := buildHotPlugin(string(newContent), outputDir, changed, generation); err != nil {
+				fmt.Fprintf(os.Stderr, "[simple] hot reload failed: %v\n", err)
+				continue
+			}
+			content = newContent
+		}
+	}
+}
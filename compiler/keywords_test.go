@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeywordArguments(t *testing.T) {
+	source := `def greet(name: str, greeting: str):
+    print(greeting + " " + name)
+    return greeting + ", " + name
+
+greet(name="bob", greeting="hi")
+greet("amy", greeting="yo")
+x = greet(greeting="hello", name="cat")
+print(x)
+`
+	want := "hi bob\nyo amy\nhello cat\nhello, cat\n"
+	if got := runProgram(t, source); got != want {
+		t.Errorf("printed\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestKeywordArgumentErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"statement", "def greet(name):\n    return name\n\ngreet(nam=\"bob\")\n", "greet() got an unexpected keyword argument 'nam'"},
+		{"expression", "def greet(name):\n    return name\n\nx = greet(nam=\"bob\")\n", "greet() got an unexpected keyword argument 'nam'"},
+		{"twice", "def f(a, b):\n    return a\n\nf(1, a=2)\n", "f() got multiple values for argument 'a'"},
+		{"missing", "def f(a, b):\n    return a\n\nf(b=2)\n", "f() missing 1 required positional argument: 'a'"},
+		{"builtin", "print(sep=\"-\")\n", "print() takes no keyword arguments"},
+		{"go function", "import \"strings\"\n\nx = strings.Repeat(s=\"a\", count=2)\n", "strings.Repeat() takes no keyword arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compile(tt.source, filepath.Join(t.TempDir(), "program"), true)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
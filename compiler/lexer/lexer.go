@@ -2,6 +2,9 @@ package lexer
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -16,6 +19,7 @@ const (
 	TokenIdentifier   TokenType = "IDENTIFIER"
 	TokenNumber       TokenType = "NUMBER"
 	TokenString       TokenType = "STRING"
+	TokenFString      TokenType = "FSTRING"
 	TokenOperator     TokenType = "OPERATOR"
 	TokenKeyword      TokenType = "KEYWORD"
 	TokenNewline      TokenType = "NEWLINE"
@@ -32,6 +36,11 @@ const (
 	TokenBraceOpen    TokenType = "{"
 	TokenBraceClose   TokenType = "}"
 	TokenDot          TokenType = "DOT"
+	TokenAt           TokenType = "@"
+	TokenArrow        TokenType = "->"
+	TokenPipe         TokenType = "|"
+	TokenAmpersand    TokenType = "&"
+	TokenCaret        TokenType = "^"
 
 	// Comparison Operators
 	TokenEQ    TokenType = "=="
@@ -46,19 +55,37 @@ const (
 	TokenTrue  TokenType = "TRUE"
 	TokenFalse TokenType = "FALSE"
 
+	TokenNone TokenType = "NONE"
+
+	// Logical Operators
+	TokenAnd TokenType = "AND"
+	TokenOr  TokenType = "OR"
+	TokenNot TokenType = "NOT"
+
 	// Arithmetic Operators
 	TokenPlus     TokenType = "+"
 	TokenMinus    TokenType = "-"
 	TokenAsterisk TokenType = "*"
+	TokenPower    TokenType = "**"
 	TokenSlash    TokenType = "/"
+	TokenFloorDiv TokenType = "//"
 	TokenModulo   TokenType = "%"
 	TokenBang     TokenType = "!"
 
-	// Assignment Operator
-	TokenAssign TokenType = "="
-
-	TokenDefer TokenType = "defer"
-	TokenGo    TokenType = "go"
+	// Assignment Operators
+	TokenAssign         TokenType = "="
+	TokenPlusAssign     TokenType = "+="
+	TokenMinusAssign    TokenType = "-="
+	TokenAsteriskAssign TokenType = "*="
+	TokenPowerAssign    TokenType = "**="
+	TokenSlashAssign    TokenType = "/="
+	TokenFloorDivAssign TokenType = "//="
+	TokenModuloAssign   TokenType = "%="
+	TokenWalrus         TokenType = ":="
+
+	TokenDefer  TokenType = "defer"
+	TokenGo     TokenType = "go"
+	TokenLambda TokenType = "LAMBDA"
 )
 
 // Token represents a lexical token.
@@ -71,21 +98,50 @@ type Token struct {
 
 // keywords maps keyword strings to their token types.
 var keywords = map[string]TokenType{
-	"def":    TokenKeyword, // Function definition
-	"return": TokenKeyword,
-	"if":     TokenKeyword,
-	"else":   TokenKeyword,
-	"elif":   TokenKeyword,
-	"while":  TokenKeyword,
-	"for":    TokenKeyword,
-	"in":     TokenKeyword,
-	"import": TokenKeyword,
-	"defer":  TokenDefer,
-	"go":     TokenGo,
-	"print":  TokenIdentifier,
-	"True":   TokenTrue,
-	"False":  TokenFalse,
-	"None":   TokenKeyword,
+	"def":      TokenKeyword, // Function definition
+	"class":    TokenKeyword,
+	"return":   TokenKeyword,
+	"if":       TokenKeyword,
+	"else":     TokenKeyword,
+	"elif":     TokenKeyword,
+	"while":    TokenKeyword,
+	"for":      TokenKeyword,
+	"break":    TokenKeyword,
+	"continue": TokenKeyword,
+	"pass":     TokenKeyword,
+	"del":      TokenKeyword,
+	"in":       TokenKeyword,
+	"is":       TokenKeyword,
+	"import":   TokenKeyword,
+	"as":       TokenKeyword,
+	"try":      TokenKeyword,
+	"except":   TokenKeyword,
+	"finally":  TokenKeyword,
+	"raise":    TokenKeyword,
+	"assert":   TokenKeyword,
+	"with":     TokenKeyword,
+	"yield":    TokenKeyword,
+	"global":   TokenKeyword,
+	"nonlocal": TokenKeyword,
+	"defer":    TokenDefer,
+	"go":       TokenGo,
+	"print":    TokenIdentifier,
+	"True":     TokenTrue,
+	"False":    TokenFalse,
+	"None":     TokenNone,
+	"and":      TokenAnd,
+	"or":       TokenOr,
+	"not":      TokenNot,
+	"lambda":   TokenLambda,
+}
+
+// IsAssignment reports whether t is = or an augmented assignment such as +=.
+func IsAssignment(t TokenType) bool {
+	switch t {
+	case TokenAssign, TokenPlusAssign, TokenMinusAssign, TokenAsteriskAssign, TokenPowerAssign, TokenSlashAssign, TokenFloorDivAssign, TokenModuloAssign:
+		return true
+	}
+	return false
 }
 
 // LookupIdent checks if an identifier is a keyword and returns the appropriate token type.
@@ -96,6 +152,67 @@ func LookupIdent(ident string) TokenType {
 	return TokenIdentifier
 }
 
+// Keywords returns the words the lexer reads as other than identifiers, and
+// print, with their token types.
+func Keywords() map[string]TokenType {
+	return maps.Clone(keywords)
+}
+
+// Symbols returns the operators the lexer reads and its punctuation, such as
+// brackets and commas, each longest first. They are found by lexing runs of
+// ASCII punctuation marks, so they always agree with NextToken.
+func Symbols() (operators, punctuation []string) {
+	var marks []string
+	for ch := '!'; ch <= '~'; ch++ {
+		if unicode.IsPunct(ch) || unicode.IsSymbol(ch) {
+			marks = append(marks, string(ch))
+		}
+	}
+	// Each prefix of an operator is one, as in *, ** and **=, so only
+	// symbols are extended by another mark
+	candidates := marks
+	for len(candidates) > 0 {
+		var longer []string
+		for _, candidate := range candidates {
+			tokenType, ok := lexSymbol(candidate)
+			if !ok {
+				continue
+			}
+			switch tokenType {
+			case TokenParenOpen, TokenParenClose, TokenBracketOpen, TokenBracketClose, TokenBraceOpen, TokenBraceClose, TokenComma, TokenColon, TokenSemicolon, TokenDot, TokenArrow:
+				punctuation = append(punctuation, candidate)
+			default:
+				operators = append(operators, candidate)
+			}
+			for _, mark := range marks {
+				longer = append(longer, candidate+mark)
+			}
+		}
+		candidates = longer
+	}
+	longestFirst := func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	}
+	slices.SortFunc(operators, longestFirst)
+	slices.SortFunc(punctuation, longestFirst)
+	return operators, punctuation
+}
+
+// lexSymbol returns the type of the token s is read as, and whether s is
+// read as a single operator or punctuation mark.
+func lexSymbol(s string) (TokenType, bool) {
+	l := NewLexer(s)
+	tok := l.NextToken()
+	switch tok.Type {
+	case TokenIdentifier, TokenNumber, TokenString, TokenFString, TokenNewline, TokenIllegal, TokenEOF:
+		return tok.Type, false
+	}
+	return tok.Type, tok.Literal == s && l.NextToken().Type == TokenEOF
+}
+
 // Lexer represents a lexical scanner.
 type Lexer struct {
 	input         string
@@ -107,6 +224,7 @@ type Lexer struct {
 	indentStack   []int   // Stack to keep track of indentation levels
 	pendingTokens []Token // Queue for INDENT/DEDENT tokens
 	AtNewLine     bool    // Indicates if the lexer is at the start of a new line
+	nesting       int     // Depth of open (, [ and { brackets
 }
 
 // NewLexer initializes a new Lexer.
@@ -123,8 +241,28 @@ func NewLexer(input string) *Lexer {
 	return l
 }
 
-// readChar reads the next character.
+// NewLexerAt creates a lexer for source embedded in a file, such as an
+// expression in an f-string, numbering positions from line and column.
+func NewLexerAt(input string, line, column int) *Lexer {
+	l := NewLexer("")
+	l.input = input
+	l.line = line
+	l.column = column - 1
+	l.position, l.readPosition = 0, 0
+	l.readChar()
+	return l
+}
+
+// readChar reads the next character. Columns count runes, not bytes, so
+// positions stay accurate for multi-byte UTF-8 input.
 func (l *Lexer) readChar() {
+	// Moving past a newline starts the next line
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+
+	l.position = l.readPosition
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -133,15 +271,7 @@ func (l *Lexer) readChar() {
 		l.readPosition += width
 	}
 
-	if l.ch == '\n' {
-		l.line++
-		l.column = 0
-		//l.AtNewLine = true
-	} else {
-		l.column++
-	}
-
-	l.position = l.readPosition
+	l.column++
 }
 
 // peekChar peeks at the next character without advancing positions.
@@ -173,6 +303,9 @@ func (l *Lexer) NextToken() Token {
 
 	l.skipWhitespace()
 
+	// Record where the token starts; multi-rune tokens advance past it
+	line, column := l.line, l.column
+
 	var tok Token
 
 	switch l.ch {
@@ -181,110 +314,192 @@ func (l *Lexer) NextToken() Token {
 			ch := l.ch
 			l.readChar()
 			literal := string(ch) + string(l.ch)
-			tok = Token{Type: TokenEQ, Literal: literal, Line: l.line, Column: l.column - 1}
+			tok = Token{Type: TokenEQ, Literal: literal, Line: line, Column: column}
 		} else {
-			tok = Token{Type: TokenAssign, Literal: string(l.ch), Line: l.line, Column: l.column}
+			tok = Token{Type: TokenAssign, Literal: string(l.ch), Line: line, Column: column}
 		}
 	case '+':
-		tok = Token{Type: TokenPlus, Literal: string(l.ch), Line: l.line, Column: l.column}
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenPlusAssign, Literal: "+=", Line: line, Column: column}
+		} else {
+			tok = Token{Type: TokenPlus, Literal: string(l.ch), Line: line, Column: column}
+		}
 	case '-':
-		tok = Token{Type: TokenMinus, Literal: string(l.ch), Line: l.line, Column: l.column}
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenMinusAssign, Literal: "-=", Line: line, Column: column}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = Token{Type: TokenArrow, Literal: "->", Line: line, Column: column}
+		} else {
+			tok = Token{Type: TokenMinus, Literal: string(l.ch), Line: line, Column: column}
+		}
 	case '*':
-		tok = Token{Type: TokenAsterisk, Literal: string(l.ch), Line: l.line, Column: l.column}
+		if l.peekChar() == '*' {
+			l.readChar()
+			if l.peekChar() == '=' {
+				l.readChar()
+				tok = Token{Type: TokenPowerAssign, Literal: "**=", Line: line, Column: column}
+			} else {
+				tok = Token{Type: TokenPower, Literal: "**", Line: line, Column: column}
+			}
+		} else if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenAsteriskAssign, Literal: "*=", Line: line, Column: column}
+		} else {
+			tok = Token{Type: TokenAsterisk, Literal: string(l.ch), Line: line, Column: column}
+		}
 	case '/':
-		tok = Token{Type: TokenSlash, Literal: string(l.ch), Line: l.line, Column: l.column}
+		if l.peekChar() == '/' {
+			l.readChar()
+			if l.peekChar() == '=' {
+				l.readChar()
+				tok = Token{Type: TokenFloorDivAssign, Literal: "//=", Line: line, Column: column}
+			} else {
+				tok = Token{Type: TokenFloorDiv, Literal: "//", Line: line, Column: column}
+			}
+		} else if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenSlashAssign, Literal: "/=", Line: line, Column: column}
+		} else {
+			tok = Token{Type: TokenSlash, Literal: string(l.ch), Line: line, Column: column}
+		}
 	case '%':
-		tok = Token{Type: TokenModulo, Literal: string(l.ch), Line: l.line, Column: l.column}
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenModuloAssign, Literal: "%=", Line: line, Column: column}
+		} else {
+			tok = Token{Type: TokenModulo, Literal: string(l.ch), Line: line, Column: column}
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
 			l.readChar()
 			literal := string(ch) + string(l.ch)
-			tok = Token{Type: TokenNotEQ, Literal: literal, Line: l.line, Column: l.column - 1}
+			tok = Token{Type: TokenNotEQ, Literal: literal, Line: line, Column: column}
 		} else {
-			tok = Token{Type: TokenBang, Literal: string(l.ch), Line: l.line, Column: l.column}
+			tok = Token{Type: TokenBang, Literal: string(l.ch), Line: line, Column: column}
 		}
 	case '>':
 		if l.peekChar() == '=' {
 			ch := l.ch
 			l.readChar()
 			literal := string(ch) + string(l.ch)
-			tok = Token{Type: TokenGTE, Literal: literal, Line: l.line, Column: l.column - 1}
+			tok = Token{Type: TokenGTE, Literal: literal, Line: line, Column: column}
 		} else {
-			tok = Token{Type: TokenGT, Literal: string(l.ch), Line: l.line, Column: l.column}
+			tok = Token{Type: TokenGT, Literal: string(l.ch), Line: line, Column: column}
 		}
 	case '<':
 		if l.peekChar() == '=' {
 			ch := l.ch
 			l.readChar()
 			literal := string(ch) + string(l.ch)
-			tok = Token{Type: TokenLTE, Literal: literal, Line: l.line, Column: l.column - 1}
+			tok = Token{Type: TokenLTE, Literal: literal, Line: line, Column: column}
 		} else if l.peekChar() == '-' {
 			ch := l.ch
 			l.readChar()
 			literal := string(ch) + string(l.ch)
-			tok = Token{Type: TokenChan, Literal: literal, Line: l.line, Column: l.column - 1}
+			tok = Token{Type: TokenChan, Literal: literal, Line: line, Column: column}
 		} else {
-			tok = Token{Type: TokenLT, Literal: string(l.ch), Line: l.line, Column: l.column}
+			tok = Token{Type: TokenLT, Literal: string(l.ch), Line: line, Column: column}
 		}
 	case '(':
-		tok = Token{Type: TokenParenOpen, Literal: string(l.ch), Line: l.line, Column: l.column}
+		l.nesting++
+		tok = Token{Type: TokenParenOpen, Literal: string(l.ch), Line: line, Column: column}
 	case ')':
-		tok = Token{Type: TokenParenClose, Literal: string(l.ch), Line: l.line, Column: l.column}
+		l.closeBracket()
+		tok = Token{Type: TokenParenClose, Literal: string(l.ch), Line: line, Column: column}
 	case '[':
-		tok = Token{Type: TokenBracketOpen, Literal: string(l.ch), Line: l.line, Column: l.column}
+		l.nesting++
+		tok = Token{Type: TokenBracketOpen, Literal: string(l.ch), Line: line, Column: column}
 	case ']':
-		tok = Token{Type: TokenBracketClose, Literal: string(l.ch), Line: l.line, Column: l.column}
+		l.closeBracket()
+		tok = Token{Type: TokenBracketClose, Literal: string(l.ch), Line: line, Column: column}
 	case '{':
-		tok = Token{Type: TokenBraceOpen, Literal: string(l.ch), Line: l.line, Column: l.column}
+		l.nesting++
+		tok = Token{Type: TokenBraceOpen, Literal: string(l.ch), Line: line, Column: column}
 	case '}':
-		tok = Token{Type: TokenBraceClose, Literal: string(l.ch), Line: l.line, Column: l.column}
+		l.closeBracket()
+		tok = Token{Type: TokenBraceClose, Literal: string(l.ch), Line: line, Column: column}
 	case ',':
-		tok = Token{Type: TokenComma, Literal: string(l.ch), Line: l.line, Column: l.column}
+		tok = Token{Type: TokenComma, Literal: string(l.ch), Line: line, Column: column}
 	case ';':
-		tok = Token{Type: TokenSemicolon, Literal: string(l.ch), Line: l.line, Column: l.column}
+		tok = Token{Type: TokenSemicolon, Literal: string(l.ch), Line: line, Column: column}
 	case ':':
-		tok = Token{Type: TokenColon, Literal: string(l.ch), Line: l.line, Column: l.column}
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenWalrus, Literal: ":=", Line: line, Column: column}
+		} else {
+			tok = Token{Type: TokenColon, Literal: string(l.ch), Line: line, Column: column}
+		}
 	case '"', '\'', '`':
 		//quoteChar := l.ch
 		literal := l.readString(l.ch)
 		//if quoteChar == '`' {
 		//	literal = strings.Trim(literal, `\"`)
 		//}
-		tok = Token{Type: TokenString, Literal: literal, Line: l.line, Column: l.column - len(literal) - 1}
+		tok = Token{Type: TokenString, Literal: literal, Line: line, Column: column}
 		return tok
 	case '\n':
-		tok = Token{Type: TokenNewline, Literal: "\\n", Line: l.line, Column: l.column}
+		tok = Token{Type: TokenNewline, Literal: "\\n", Line: line, Column: column}
 		l.readChar()
+		if l.nesting > 0 {
+			// Newlines inside brackets join lines, as in Python
+			return l.NextToken()
+		}
 		l.AtNewLine = true
 		return tok
 	case 0:
 		// At EOF, emit DEDENT tokens for any remaining indentation levels
 		if len(l.indentStack) > 1 {
 			l.indentStack = l.indentStack[:len(l.indentStack)-1]
-			return Token{Type: TokenDedent, Literal: "DEDENT", Line: l.line, Column: l.column}
+			return Token{Type: TokenDedent, Literal: "DEDENT", Line: line, Column: column}
 		}
-		tok = Token{Type: TokenEOF, Literal: "", Line: l.line, Column: l.column}
+		tok = Token{Type: TokenEOF, Literal: "", Line: line, Column: column}
 	case '.':
-		tok = Token{Type: TokenDot, Literal: string(l.ch), Line: l.line, Column: l.column}
+		tok = Token{Type: TokenDot, Literal: string(l.ch), Line: line, Column: column}
+	case '@':
+		tok = Token{Type: TokenAt, Literal: string(l.ch), Line: line, Column: column}
+	case '|':
+		tok = Token{Type: TokenPipe, Literal: string(l.ch), Line: line, Column: column}
+	case '&':
+		// &name passes a pointer to a Go function, as in
+		// json.Unmarshal(data, &value); otherwise & is an operator
+		if isLetter(l.peekChar()) {
+			literal := l.readIdentifier()
+			return Token{Type: LookupIdent(literal), Literal: literal, Line: line, Column: column}
+		}
+		tok = Token{Type: TokenAmpersand, Literal: string(l.ch), Line: line, Column: column}
+	case '^':
+		tok = Token{Type: TokenCaret, Literal: string(l.ch), Line: line, Column: column}
 	case '#':
 		l.skipComment()
-		tok = Token{Type: TokenNewline, Literal: "\\n", Line: l.line, Column: l.column}
+		tok = Token{Type: TokenNewline, Literal: "\\n", Line: line, Column: column}
 		l.readChar()
+		if l.nesting > 0 {
+			return l.NextToken()
+		}
 		l.AtNewLine = true
 		return tok
 	default:
-		if l.ch == '&' || isLetter(l.ch) {
+		if (l.ch == 'f' || l.ch == 'F') && (l.peekChar() == '"' || l.peekChar() == '\'') {
+			// An f-string; the parser splits out the {expressions} in it
+			l.readChar()
+			tok = Token{Type: TokenFString, Literal: l.readString(l.ch), Line: line, Column: column}
+			return tok
+		}
+		if isLetter(l.ch) {
 			literal := l.readIdentifier()
 			tokenType := LookupIdent(literal)
-			tok = Token{Type: tokenType, Literal: literal, Line: l.line, Column: l.column - len(literal) + 1}
+			tok = Token{Type: tokenType, Literal: literal, Line: line, Column: column}
 			return tok
 		} else if isDigit(l.ch) {
 			literal := l.readNumber()
-			tok = Token{Type: TokenNumber, Literal: literal, Line: l.line, Column: l.column - len(literal) + 1}
+			tok = Token{Type: TokenNumber, Literal: literal, Line: line, Column: column}
 			return tok
 		} else {
-			tok = Token{Type: TokenIllegal, Literal: string(l.ch), Line: l.line, Column: l.column}
+			tok = Token{Type: TokenIllegal, Literal: string(l.ch), Line: line, Column: column}
 		}
 	}
 
@@ -306,6 +521,7 @@ func (l *Lexer) PeekAhead(n int) Token {
 	savedPendingTokens := make([]Token, len(l.pendingTokens))
 	copy(savedPendingTokens, l.pendingTokens)
 	savedAtNewLine := l.AtNewLine
+	savedNesting := l.nesting
 
 	var tok Token
 	for i := 0; i <= n; i++ {
@@ -324,10 +540,18 @@ func (l *Lexer) PeekAhead(n int) Token {
 	l.indentStack = savedIndentStack
 	l.pendingTokens = savedPendingTokens
 	l.AtNewLine = savedAtNewLine
+	l.nesting = savedNesting
 
 	return tok
 }
 
+// closeBracket leaves one level of bracket nesting.
+func (l *Lexer) closeBracket() {
+	if l.nesting > 0 {
+		l.nesting--
+	}
+}
+
 // skipWhitespace skips over spaces and tabs.
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
@@ -336,28 +560,33 @@ func (l *Lexer) skipWhitespace() {
 }
 
 func (l *Lexer) skipComment() {
-	for l.ch != '\n' {
+	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
 }
 
 // readIdentifier reads an identifier and advances the lexer's positions.
 func (l *Lexer) readIdentifier() string {
-	position := l.readPosition - 1
-	for isLetter(l.ch) || isDigit(l.ch) || l.ch == '&' || l.ch == '{' || l.ch == '}' {
+	position := l.position
+	if l.ch == '&' {
+		l.readChar()
+	}
+	for isLetter(l.ch) || isDigit(l.ch) || l.ch == '{' {
+		// Braces belong to an identifier only as a type such as interface{},
+		// never a closing brace after a name, as in {"key": value}
 		if l.ch == '{' {
-			for l.ch != '}' {
+			for l.ch != '}' && l.ch != 0 {
 				l.readChar()
 			}
 		}
 		l.readChar()
 	}
-	return l.input[position : l.readPosition-1]
+	return l.input[position:l.position]
 }
 
 // readNumber reads a number (integer or float) and advances the lexer's positions.
 func (l *Lexer) readNumber() string {
-	position := l.readPosition - 1
+	position := l.position
 	hasDot := false
 
 	for {
@@ -371,7 +600,7 @@ func (l *Lexer) readNumber() string {
 		}
 	}
 
-	return l.input[position : l.readPosition-1]
+	return l.input[position:l.position]
 }
 
 // readString reads a string literal, handling escape sequences and multi-line strings.
@@ -396,8 +625,9 @@ func (l *Lexer) readString(quoteChar rune) string {
 		return ch
 	}
 
-	// Check if it's a triple-quoted string
-	if l.ch == quoteChar && l.peekChar() == quoteChar {
+	// Check if it's a triple-quoted string. Two quotes followed by anything
+	// else is an empty string.
+	if quoteChar != '`' && l.ch == quoteChar && l.peekChar() == quoteChar {
 		isTripleQuoted = true
 		// Consume the next two quoteChars
 		l.readChar() // Skip second quoteChar
@@ -408,7 +638,6 @@ func (l *Lexer) readString(quoteChar rune) string {
 		// Backtick strings are raw strings; read until the closing backtick
 		for {
 			if l.ch == '`' {
-				//fmt.Println(string(l.peekChar()))
 				l.readChar() // Consume closing backtick
 				break
 			}
@@ -422,7 +651,7 @@ func (l *Lexer) readString(quoteChar rune) string {
 	} else if isTripleQuoted {
 		// Triple-quoted string
 		for {
-			if l.ch == quoteChar && l.peekChar() == quoteChar && peekAhead(1) == quoteChar {
+			if l.ch == quoteChar && l.peekChar() == quoteChar && peekAhead(2) == quoteChar {
 				// Consume the three quoteChars
 				l.readChar() // Skip first quoteChar
 				l.readChar() // Skip second quoteChar
@@ -435,24 +664,7 @@ func (l *Lexer) readString(quoteChar rune) string {
 			}
 			if l.ch == '\\' {
 				l.readChar()
-				switch l.ch {
-				case 'n':
-					sb.WriteRune('\n')
-				case 't':
-					sb.WriteRune('\t')
-				case 'r':
-					sb.WriteRune('\r')
-				case '\\':
-					sb.WriteRune('\\')
-				case '\'':
-					sb.WriteRune('\'')
-				case '"':
-					sb.WriteRune('"')
-				default:
-					// Unknown escape sequence, include the backslash and the character
-					sb.WriteRune('\\')
-					sb.WriteRune(l.ch)
-				}
+				l.readEscape(&sb)
 				l.readChar()
 			} else {
 				sb.WriteRune(l.ch)
@@ -468,24 +680,7 @@ func (l *Lexer) readString(quoteChar rune) string {
 			}
 			if l.ch == '\\' {
 				l.readChar()
-				switch l.ch {
-				case 'n':
-					sb.WriteRune('\n')
-				case 't':
-					sb.WriteRune('\t')
-				case 'r':
-					sb.WriteRune('\r')
-				case '\\':
-					sb.WriteRune('\\')
-				case '\'':
-					sb.WriteRune('\'')
-				case '"':
-					sb.WriteRune('"')
-				default:
-					// Unknown escape sequence, include the backslash and the character
-					sb.WriteRune('\\')
-					sb.WriteRune(l.ch)
-				}
+				l.readEscape(&sb)
 				l.readChar()
 			} else if l.ch == 0 || l.ch == '\n' {
 				// Reached EOF or newline without closing quote
@@ -500,6 +695,76 @@ func (l *Lexer) readString(quoteChar rune) string {
 	return sb.String()
 }
 
+// readEscape decodes the escape sequence whose first character (after the
+// backslash) is l.ch and writes it to sb. The lexer is left on the last
+// character of the sequence.
+func (l *Lexer) readEscape(sb *strings.Builder) {
+	switch l.ch {
+	case 'n':
+		sb.WriteRune('\n')
+	case 't':
+		sb.WriteRune('\t')
+	case 'r':
+		sb.WriteRune('\r')
+	case 'a':
+		sb.WriteRune('\a')
+	case 'b':
+		sb.WriteRune('\b')
+	case 'f':
+		sb.WriteRune('\f')
+	case 'v':
+		sb.WriteRune('\v')
+	case '\\':
+		sb.WriteRune('\\')
+	case '\'':
+		sb.WriteRune('\'')
+	case '"':
+		sb.WriteRune('"')
+	case '`':
+		sb.WriteRune('`')
+	case '\n':
+		// A backslash at the end of a line continues the string on the next one
+	case 'x':
+		l.readHexEscape(sb, 2)
+	case 'u':
+		l.readHexEscape(sb, 4)
+	case 'U':
+		l.readHexEscape(sb, 8)
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		// Octal escape of up to three digits, e.g. \0 or \101
+		value := l.ch - '0'
+		for i := 0; i < 2 && l.peekChar() >= '0' && l.peekChar() <= '7'; i++ {
+			l.readChar()
+			value = value*8 + (l.ch - '0')
+		}
+		sb.WriteRune(value)
+	case 0:
+		// Backslash at EOF
+		sb.WriteRune('\\')
+	default:
+		// Unknown escape sequence, include the backslash and the character
+		sb.WriteRune('\\')
+		sb.WriteRune(l.ch)
+	}
+}
+
+// readHexEscape decodes a \x, \u or \U escape with exactly n hex digits
+// following l.ch. Malformed escapes are kept verbatim.
+func (l *Lexer) readHexEscape(sb *strings.Builder, n int) {
+	end := l.readPosition + n
+	if end <= len(l.input) {
+		if value, err := strconv.ParseUint(l.input[l.readPosition:end], 16, 32); err == nil && utf8.ValidRune(rune(value)) {
+			for i := 0; i < n; i++ {
+				l.readChar()
+			}
+			sb.WriteRune(rune(value))
+			return
+		}
+	}
+	sb.WriteRune('\\')
+	sb.WriteRune(l.ch)
+}
+
 // handleIndentation handles indentation at the start of a new line.
 func (l *Lexer) handleIndentation() Token {
 	spaces := 0
@@ -0,0 +1,181 @@
+package lexer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode"
+	"unicode/utf8"
+)
+
+// lexAll returns the tokens of input up to and including EOF, failing the
+// test if the lexer doesn't reach EOF in a number of tokens proportional
+// to the input.
+func lexAll(t *testing.T, input string) []Token {
+	t.Helper()
+	l := NewLexer(input)
+	limit := 4*len(input) + 64
+	var tokens []Token
+	for len(tokens) < limit {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == TokenEOF {
+			return tokens
+		}
+	}
+	t.Fatalf("no EOF after %d tokens of %q", limit, input)
+	return nil
+}
+
+// relexable reports whether a token is one whose text lexes back to the
+// same token on its own.
+func relexable(tok Token) bool {
+	switch tok.Type {
+	case TokenIdentifier, TokenNumber, TokenKeyword, TokenTrue, TokenFalse, TokenNone, TokenAnd, TokenOr, TokenNot, TokenLambda, TokenDefer, TokenGo:
+		return true
+	}
+	typ, ok := lexSymbol(tok.Literal)
+	return ok && typ == tok.Type
+}
+
+// checkProperties checks what holds of the tokens of any input: lexing
+// ends, positions never go backwards, and the text of identifiers,
+// numbers, keywords and operators lexes back to the same token.
+func checkProperties(t *testing.T, input string) {
+	tokens := lexAll(t, input)
+	for i := 1; i < len(tokens); i++ {
+		prev, tok := tokens[i-1], tokens[i]
+		if tok.Line < prev.Line || tok.Line == prev.Line && tok.Column < prev.Column {
+			t.Fatalf("%q: token %d %s %q at %d:%d comes before token %d %s %q at %d:%d", input, i, tok.Type, tok.Literal, tok.Line, tok.Column, i-1, prev.Type, prev.Literal, prev.Line, prev.Column)
+		}
+	}
+	for _, tok := range tokens {
+		if !relexable(tok) {
+			continue
+		}
+		again := NewLexer(tok.Literal).NextToken()
+		if again.Type != tok.Type || again.Literal != tok.Literal {
+			t.Fatalf("%q: %s %q lexes again as %s %q", input, tok.Type, tok.Literal, again.Type, again.Literal)
+		}
+	}
+}
+
+// seeds are the Simple programs of testdata, and inputs that have tripped
+// the lexer up.
+func seeds(t testing.TB) []string {
+	inputs := []string{
+		"", "''", `""`, `""""""`, `"""a""b"""`, `'\x41\u00e9\U0001F600\101'`,
+		`"\xZZ"`, `"\`, "'abc\n", "if x:\n    y = 1\n  z = 2\n", "\tx\n",
+		"a //= 2 ** 3 != 4 -> b := c <- d", "`raw\\n`", "f\"{x!r:>10}\"",
+		"x = 1.5e3 + .5 + 0x1F", "é = 'ü'", "\xff\xfe", "# comment\n\n\n", "u{",
+	}
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "*", "*.simple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, string(src))
+	}
+	return inputs
+}
+
+func TestLexerProperties(t *testing.T) {
+	for _, input := range seeds(t) {
+		checkProperties(t, input)
+	}
+	check := func(input string) bool {
+		checkProperties(t, input)
+		return true
+	}
+	if err := quick.Check(check, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func FuzzLexer(f *testing.F) {
+	for _, input := range seeds(f) {
+		f.Add(input)
+	}
+	f.Fuzz(checkProperties)
+}
+
+// quoteSimple writes s as a Simple string literal between quotes, escaping
+// what can't appear in it as it is.
+func quoteSimple(s, quotes string) string {
+	var b strings.Builder
+	b.WriteString(quotes)
+	for _, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case strings.ContainsRune(quotes, r):
+			b.WriteString(`\` + string(r))
+		case r > 0xFFFF:
+			fmt.Fprintf(&b, `\U%08x`, r)
+		case !unicode.IsPrint(r):
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString(quotes)
+	return b.String()
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	for _, quotes := range []string{`"`, `'`, `"""`, `'''`} {
+		check := func(s string) bool {
+			if !utf8.ValidString(s) {
+				return true
+			}
+			literal := quoteSimple(s, quotes)
+			tokens := lexAll(t, literal)
+			if tokens[0].Type != TokenString || tokens[0].Literal != s {
+				t.Logf("%s lexes as %s %q, not the string %q", literal, tokens[0].Type, tokens[0].Literal, s)
+				return false
+			}
+			return tokens[1].Type == TokenEOF || tokens[1].Type == TokenNewline
+		}
+		if err := quick.Check(check, nil); err != nil {
+			t.Fatalf("%s: %v", quotes, err)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		literal, want string
+	}{
+		{`"\x41\x7a"`, "Az"},
+		{`"\u00e9"`, "é"},
+		{`"\U0001F600"`, "😀"},
+		{`"\101\0"`, "A\x00"},
+		{`"\xZZ"`, `\xZZ`},
+		{`"\q"`, `\q`},
+		{`''`, ""},
+		{`""`, ""},
+		{`""""""`, ""},
+		{`"""a""b"""`, `a""b`},
+		{`'''it's'''`, "it's"},
+		{"`a\\nb`", `a\nb`},
+	}
+	for _, test := range tests {
+		tok := NewLexer(test.literal).NextToken()
+		if tok.Type != TokenString || tok.Literal != test.want {
+			t.Errorf("%s lexes as %s %q, want the string %q", test.literal, tok.Type, tok.Literal, test.want)
+		}
+	}
+}
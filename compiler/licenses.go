@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// License report
+//
+// A program's imports are downloaded with go mod tidy, so a Simple binary
+// can hold third-party code its author never chose by hand. simple licenses
+// lists the Go modules built into a program, with their versions and
+// licenses, for review before the binary is shipped. The modules are those
+// of the packages the program links, from the go.mod and go.sum of its last
+// build, and each license is recognised from the license file of the module
+// in the module cache. The Go standard library, which every binary holds,
+// is listed as std.
+
+// licenseFiles are the names, in upper case and without an extension, of
+// the files modules keep their license in.
+var licenseFiles = []string{"LICENSE", "LICENCE", "COPYING", "LICENSE-MIT", "LICENSE-APACHE", "UNLICENSE"}
+
+// knownLicenses recognises licenses by phrases of their text, most specific
+// first, as SPDX identifiers.
+var knownLicenses = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "names of its contributors"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"ISC", []string{"Permission to use, copy, modify, and distribute this software for any purpose"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+	{"CC0-1.0", []string{"CC0 1.0 Universal"}},
+}
+
+// moduleLicense returns the SPDX identifiers of the licenses in the license
+// files of the module in dir, "unknown" for a license file it doesn't
+// recognise, or "not found" when the module has none.
+func moduleLicense(dir string) string {
+	if dir == "" {
+		return "not found"
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "not found"
+	}
+	var ids []string
+	for _, entry := range entries {
+		name := strings.ToUpper(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		if entry.IsDir() || !slices.Contains(licenseFiles, name) {
+			continue
+		}
+		text, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		id := recogniseLicense(string(text))
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return "not found"
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, " AND ")
+}
+
+// recogniseLicense returns the SPDX identifier of a license's text, or
+// "unknown".
+func recogniseLicense(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	for _, license := range knownLicenses {
+		matches := true
+		for _, phrase := range license.phrases {
+			if !strings.Contains(text, phrase) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return license.id
+		}
+	}
+	return "unknown"
+}
+
+// linkedModules returns the modules of the packages the program built in
+// outputDir links, with the Go standard library as std if it links any of
+// it, sorted by path.
+func linkedModules(outputDir string) ([]goModule, error) {
+	cmd := exec.Command("go", "list", "-deps", "-json", ".")
+	cmd.Dir = outputDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %v\n%s", err, stderr.String())
+	}
+
+	type module struct {
+		Path    string
+		Version string
+		Dir     string
+		Main    bool
+		Replace *module
+	}
+	seen := map[string]bool{}
+	var modules []goModule
+	standard := false
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var pkg struct {
+			Standard bool
+			Module   *module
+		}
+		if err := decoder.Decode(&pkg); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if pkg.Standard {
+			standard = true
+			continue
+		}
+		m := pkg.Module
+		if m == nil || m.Main || seen[m.Path] {
+			continue
+		}
+		seen[m.Path] = true
+		gm := goModule{Path: m.Path, Version: m.Version, License: moduleLicense(m.Dir)}
+		if r := m.Replace; r != nil {
+			// The replacement is the code built
+			gm.Replace = strings.TrimSuffix(r.Path+"@"+r.Version, "@")
+			gm.License = moduleLicense(r.Dir)
+		}
+		modules = append(modules, gm)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+
+	if standard {
+		out, err := exec.Command("go", "env", "GOVERSION", "GOROOT").Output()
+		if err != nil {
+			return nil, err
+		}
+		env := strings.Fields(string(out))
+		if len(env) == 2 {
+			modules = append([]goModule{{Path: "std", Version: env[0], License: moduleLicense(env[1])}}, modules...)
+		}
+	}
+	return modules, nil
+}
+
+// licenseReport prints the modules built into the program compiled from
+// target, a .simple file or its output directory, with their licenses.
+func licenseReport(target string) error {
+	outputDir := target
+	if strings.HasSuffix(target, ".simple") {
+		outputDir = filepath.Dir(target)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "go.mod")); err != nil {
+		return fmt.Errorf("%s has no go.mod; build the program with simple first", outputDir)
+	}
+	modules, err := linkedModules(outputDir)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MODULE\tVERSION\tLICENSE")
+	unrecognised := 0
+	for _, m := range modules {
+		path := m.Path
+		if m.Replace != "" {
+			path += " => " + m.Replace
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", path, m.Version, m.License)
+		if strings.Contains(m.License, "unknown") || m.License == "not found" {
+			unrecognised++
+		}
+	}
+	w.Flush()
+	if unrecognised > 0 {
+		fmt.Printf("\n%d module(s) need their license reviewed by hand\n", unrecognised)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Resource limits
+//
+// A program run by simple can be given limits, so that a runaway one, such
+// as a while loop that never ends, fails instead of hanging a CI pipeline.
+// -timeout stops the program after a time on the clock, -cpu after a time
+// on the processor, and -memory when it takes more memory than it is given.
+// -cpu and -memory are limits of the operating system, set on Linux by
+// simple running itself in the program's place to set them and exec it; the
+// Go runtime is also told the memory limit with GOMEMLIMIT, so that it
+// collects garbage harder before reaching it, and that is all -memory does
+// elsewhere. A program stopped by a limit makes simple exit with an error
+// naming the limit.
+
+// runLimits are the limits of a program run by simple, zero where there is
+// none.
+type runLimits struct {
+	timeout time.Duration
+	cpu     time.Duration
+	memory  byteSize
+}
+
+// limits are set by -timeout, -cpu and -memory.
+var limits runLimits
+
+// limitsArg is the first argument of simple when it runs itself to set
+// the -cpu and -memory limits of a program and exec it.
+const limitsArg = "__limits"
+
+// byteSize is an amount of memory, a number of bytes that may be followed
+// by K, M or G, as in 512M.
+type byteSize int64
+
+func (b *byteSize) String() string {
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}} {
+		if *b > 0 && int64(*b)%unit.size == 0 {
+			return strconv.FormatInt(int64(*b)/unit.size, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSize) Set(value string) error {
+	number, unit := strings.ToUpper(value), int64(1)
+	number = strings.TrimSuffix(strings.TrimSuffix(number, "B"), "I")
+	switch {
+	case strings.HasSuffix(number, "K"):
+		unit = 1 << 10
+	case strings.HasSuffix(number, "M"):
+		unit = 1 << 20
+	case strings.HasSuffix(number, "G"):
+		unit = 1 << 30
+	}
+	n, err := strconv.ParseInt(strings.TrimRight(number, "KMG"), 10, 64)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("%q isn't an amount of memory, such as 512M or 2G", value)
+	}
+	*b = byteSize(n * unit)
+	return nil
+}
+
+// limitedRun is a program started within the limits, which the signals
+// simple is sent are forwarded to.
+type limitedRun struct {
+	cmd         *exec.Cmd
+	timer       *time.Timer
+	timedOut    atomic.Bool
+	stopSignals func() os.Signal
+	memory      *memoryWatch // its standard error, watched when -memory is set
+}
+
+// outOfMemory are what the Go runtime's fatal errors say when a program
+// can't have the memory it asks for.
+var outOfMemory = []string{"out of memory", "cannot allocate memory"}
+
+// memoryWatch passes on what a program writes to standard error, noting
+// whether it says it ran out of memory.
+type memoryWatch struct {
+	w    io.Writer
+	tail []byte // the end of what was written, which the message may continue
+	seen atomic.Bool
+}
+
+func (m *memoryWatch) Write(p []byte) (int, error) {
+	text := append(m.tail, p...)
+	longest := 0
+	for _, message := range outOfMemory {
+		if bytes.Contains(text, []byte(message)) {
+			m.seen.Store(true)
+		}
+		longest = max(longest, len(message))
+	}
+	m.tail = append([]byte(nil), text[max(0, len(text)-longest+1):]...)
+	return m.w.Write(p)
+}
+
+// startLimited starts cmd within the limits.
+func startLimited(cmd *exec.Cmd) (*limitedRun, error) {
+	if limits.memory > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, fmt.Sprintf("GOMEMLIMIT=%d", limits.memory))
+	}
+	var memory *memoryWatch
+	if limits.memory > 0 {
+		memory = &memoryWatch{w: cmd.Stderr}
+		if cmd.Stderr == nil {
+			memory.w = io.Discard
+		}
+		cmd.Stderr = memory
+	}
+	if err := limitCommand(cmd); err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	run := &limitedRun{cmd: cmd, stopSignals: forwardSignals(cmd.Process), memory: memory}
+	if limits.timeout > 0 {
+		run.timer = time.AfterFunc(limits.timeout, func() {
+			run.timedOut.Store(true)
+			cmd.Process.Kill()
+		})
+	}
+	return run, nil
+}
+
+// result returns the error of a program that has exited, given the error
+// of its Wait, naming the limit or signal that stopped it.
+func (r *limitedRun) result(err error) error {
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	signal := r.stopSignals()
+	switch {
+	case r.timedOut.Load():
+		return fmt.Errorf("the program ran for longer than -timeout %s and was stopped", limits.timeout)
+	case err == nil:
+		return nil
+	case signal != nil:
+		return &signalError{signal}
+	case limits.cpu > 0 && cpuExceeded(r.cmd.ProcessState):
+		return fmt.Errorf("the program used more than -cpu %s of processor time and was stopped", limits.cpu)
+	case r.memory != nil && (r.memory.seen.Load() || memoryExceeded(r.cmd.ProcessState)):
+		return fmt.Errorf("failed to run binary: %w; its memory is limited to %s by -memory", err, &limits.memory)
+	}
+	return fmt.Errorf("failed to run binary: %w", err)
+}
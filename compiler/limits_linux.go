@@ -0,0 +1,79 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// limitCommand changes cmd to run its program through simple, which sets
+// the -cpu and -memory limits on itself and then execs the program. A
+// process keeps its limits across exec, so the program is within them from
+// its first instruction, with no window after it has started in which it
+// runs without them.
+func limitCommand(cmd *exec.Cmd) error {
+	if limits.cpu == 0 && limits.memory == 0 {
+		return nil
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	program, err := filepath.Abs(cmd.Path)
+	if err != nil {
+		return err
+	}
+	args := []string{self, limitsArg, strconv.FormatInt(int64(limits.cpu), 10), strconv.FormatInt(int64(limits.memory), 10), program}
+	cmd.Path = self
+	cmd.Args = append(args, cmd.Args[1:]...)
+	return nil
+}
+
+// execLimited is simple run by limitCommand, with args the limits and the
+// program to exec within them. It returns only if it fails.
+func execLimited(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("%s needs the -cpu and -memory limits and a program", limitsArg)
+	}
+	cpu, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	memory, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return err
+	}
+	if cpu > 0 {
+		// The kernel sends SIGXCPU at the limit, and SIGKILL a second later
+		seconds := uint64(math.Ceil(time.Duration(cpu).Seconds()))
+		if err := syscall.Setrlimit(syscall.RLIMIT_CPU, &syscall.Rlimit{Cur: seconds, Max: seconds + 1}); err != nil {
+			return fmt.Errorf("-cpu: %w", err)
+		}
+	}
+	if memory > 0 {
+		if err := syscall.Setrlimit(syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: uint64(memory), Max: uint64(memory)}); err != nil {
+			return fmt.Errorf("-memory: %w", err)
+		}
+	}
+	return syscall.Exec(args[2], args[2:], os.Environ())
+}
+
+// cpuExceeded reports whether a program was stopped by its -cpu limit.
+func cpuExceeded(state *os.ProcessState) bool {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && (status.Signal() == syscall.SIGXCPU || status.Signal() == syscall.SIGKILL)
+}
+
+// memoryExceeded reports whether a program was killed, as the kernel kills
+// one it has no memory left for.
+func memoryExceeded(state *os.ProcessState) bool {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+}
@@ -0,0 +1,36 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// limitCommand reports that -cpu is only supported on Linux. -memory is
+// left to GOMEMLIMIT.
+func limitCommand(cmd *exec.Cmd) error {
+	if limits.cpu > 0 {
+		return errors.New("-cpu is only supported on Linux")
+	}
+	return nil
+}
+
+// execLimited is never run, as limitCommand never runs simple to set
+// limits outside Linux.
+func execLimited(args []string) error {
+	return errors.New("-cpu and -memory limits are only set on Linux")
+}
+
+// cpuExceeded reports whether a program was stopped by its -cpu limit,
+// which only Linux sets.
+func cpuExceeded(state *os.ProcessState) bool {
+	return false
+}
+
+// memoryExceeded reports whether a program was killed for want of memory,
+// which is only told on Linux.
+func memoryExceeded(state *os.ProcessState) bool {
+	return false
+}
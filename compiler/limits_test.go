@@ -0,0 +1,30 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestMemoryWatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   bool
+	}{
+		{"assertion", []string{"AssertionError\n"}, false},
+		{"runtime", []string{"fatal error: runtime: out of memory\n"}, true},
+		{"mmap", []string{"fatal error: runtime: cannot allocate memory\n"}, true},
+		{"split", []string{"fatal error: runtime: out of me", "mory\n"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &memoryWatch{w: io.Discard}
+			for _, w := range tt.writes {
+				m.Write([]byte(w))
+			}
+			if got := m.seen.Load(); got != tt.want {
+				t.Errorf("saw running out of memory: %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
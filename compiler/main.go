@@ -1,21 +1,27 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/sasogeek/simple/compiler/codegen"
 	"github.com/sasogeek/simple/compiler/lexer"
 	"github.com/sasogeek/simple/compiler/parser"
 	"github.com/sasogeek/simple/compiler/semantic"
 	"github.com/sasogeek/simple/compiler/transformer"
+	"github.com/sasogeek/simple/compiler/verbose"
 	"os"
 	"os/exec"
-	"os/user"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
-// Function to navigate to a directory and create go.mod with a given Go version
-func createGoMod(dir, goVersion string) error {
+// Function to navigate to a directory and create go.mod with a given Go version.
+// Offline skips `go mod tidy`, which may download modules.
+func createGoMod(dir, goVersion string, offline bool) error {
 	err := os.Chdir(dir)
 	if err != nil {
 		return fmt.Errorf("failed to navigate to directory: %w", err)
@@ -39,6 +45,10 @@ func createGoMod(dir, goVersion string) error {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	err = cmd.Run()
+	if offline {
+		return err
+	}
+	defer verbose.Phase(1, "go mod tidy")()
 	cmd = exec.Command("go", "mod", "tidy")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -52,14 +62,18 @@ func createGoMod(dir, goVersion string) error {
 
 // Function to run go build and return the binary's name
 func buildGoProject(dir string, binaryName string) (string, error) {
-	//binaryName := filepath.Base(dir)
-
 	// Run go build
-	cmd := exec.Command("go", "build", "-o", binaryName)
+	defer verbose.Phase(1, "go build")()
+	args := append([]string{"build"}, goBuildFlags()...)
+	cmd := exec.Command("go", append(args, "-o", binaryName)...)
 	cmd.Dir = dir
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	// The Go compiler reports on the generated code, so names renamed in
+	// it are given back their Simple names
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
 	err := cmd.Run()
+	fmt.Fprint(os.Stderr, codegen.SimpleNames(stderr.String()))
 	if err != nil {
 		return "", fmt.Errorf("failed to build the project: %w", err)
 	}
@@ -76,30 +90,50 @@ func runBinary(binaryName string) error {
 	// Execute the binary
 	cmd := exec.Command(binaryName)
 	cmd.Dir = filepath.Dir(binaryName)
+	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	err := cmd.Run()
+	run, err := startLimited(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to run binary: %w", err)
 	}
+	return run.result(cmd.Wait())
+}
 
-	return nil
+// compileStdlibModules compiles the stdlib modules among imports into the
+// lib directory of outputDir. Only the modules the program imports are
+// compiled; loading the Go packages of the others would slow down every
+// build.
+func compileStdlibModules(imports []string, outputDir string) {
+	stdlibFiles, _ := stdlib()
+	for _, file := range stdlibFiles {
+		name := strings.Split(filepath.Base(file), ".")[0]
+		if !slices.Contains(imports, filepath.Base(outputDir)+"/lib/"+name) {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err == nil {
+			destDir := filepath.Join(outputDir, "lib/"+name)
+			verbose.Logf(2, "compiling stdlib module %s into %s", file, destDir)
+			os.MkdirAll(destDir, os.ModePerm)
+			if _, err := compile(string(content), destDir, false); err != nil {
+				printErrorf("stdlib module %s: %v", name, err)
+			}
+		}
+	}
 }
 
 func stdlib() ([]string, error) {
 	var files []string
-	usr, err := user.Current()
-	homeDir := usr.HomeDir
-	dir := filepath.Join(homeDir, "simple/stdlib")
+	dir := semantic.StdlibDir()
 	entries, err := os.ReadDir(dir)
-	//fmt.Println("entries: ", entries)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".simple") {
 			files = append(files, filepath.Join(dir, entry.Name()))
 		}
 	}
@@ -107,54 +141,210 @@ func stdlib() ([]string, error) {
 	return files, nil
 }
 
-func compile(content string, outputDir string, isMain bool) {
+// optimize is the optimization level set by -O, release is set by
+// --release and trace by --trace; compile passes them to the code
+// generator of every module, though only the main program is traced.
+var (
+	optimize int
+	release  bool
+	trace    bool
+)
+
+// compile generates Go code for a Simple program into outputDir and returns
+// the import paths of the generated file.
+func compile(content string, outputDir string, isMain bool) ([]string, error) {
+	verbose.Logf(1, "compiling %s", outputDir)
+
 	// Initialize Lexer
 	l := lexer.NewLexer(content)
 
 	// Initialize Parser
 	p := parser.NewParser(l)
 
-	// Parse the program
+	// Parse the program (the lexer is driven by the parser, so this covers lexing too)
+	done := verbose.Phase(1, "lexing and parsing")
 	ast := p.ParseProgram()
+	done()
+	if isMain {
+		if err := applyDefines(ast); err != nil {
+			return nil, err
+		}
+		if err := transformer.RunPlugins(ast); err != nil {
+			return nil, err
+		}
+	}
 
 	// Initialize Semantic Analyzer
 	analyzer := semantic.NewAnalyzer()
+	analyzer.OutputDir = outputDir
 
 	// Perform Semantic Analysis
+	done = verbose.Phase(1, "semantic analysis")
 	analyzer.Analyze(ast, []parser.Statement{})
+	if !isMain {
+		analyzer.CheckExports(ast)
+	}
+	done()
+	if errs := analyzer.FatalErrors(); len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	for _, warning := range analyzer.Warnings() {
+		printWarning(warning)
+	}
 
 	// Initialize Transformer
 	transformer := transformer.NewTransformer(analyzer)
 
 	// Perform Transformation
+	done = verbose.Phase(1, "transformation")
 	transformer.Transform(ast, ast)
+	done()
 
 	// Initialize Code Generator
 	cg := codegen.NewCodeGenerator(outputDir, analyzer, isMain)
+	cg.HotReload = hotReload
+	cg.Optimize = optimize
+	cg.Release = release
+	cg.Trace = trace && isMain
+	cg.Platform = platform
 
 	// Generate Go Code
+	done = verbose.Phase(1, "code generation")
 	err := cg.GenerateCode(ast)
-	if err != nil {
-		fmt.Println("Error:", err)
-		//return
+	done()
+	if err == nil && isMain && platform != "" && !cg.Listens() {
+		printWarning(fmt.Sprintf("-platform %s only listens on the platform's port and answers /healthz for a program that calls http.ListenAndServe, which this one doesn't", platform))
 	}
+	return cg.Imports(), err
 }
 
 const version = "Simple 0.0.4"
 
+// goVersion is the Go version of the modules of generated programs.
+const goVersion = "1.23.1"
+
+// The flags of the commands that build a program, set by main.
+var (
+	reportPath string
+	sandbox    bool
+	verify     bool
+	platform   string
+	targets    map[string]*bool
+)
+
+// checkStdlib is set by -check, for simple version.
+var checkStdlib bool
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == limitsArg {
+		printError(execLimited(os.Args[2:]))
+		os.Exit(1)
+	}
+	showVersion := flag.Bool("version", false, "print the compiler version and exit")
+	v := flag.Bool("v", false, "log each compiler phase with timings")
+	vv := flag.Bool("vv", false, "like -v, and also log every package load and stdlib module")
+	flag.StringVar(&reportPath, "report", "", "write a JSON build report to `file`")
+	flag.BoolVar(&sandbox, "sandbox", false, "for untrusted code: only allow safe standard library imports, never download modules or run the binary")
+	flag.IntVar(&optimize, "O", 0, "optimization `level`: 1 builds strings of strings and ints without fmt, for high-throughput services")
+	flag.BoolVar(&release, "release", false, "leave assert statements out of the binary")
+	flag.BoolVar(&trace, "trace", false, "log every call of the program's functions and methods, with its arguments, and every return, with how long the call took, to standard error")
+	flag.BoolVar(&reproducible, "reproducible", false, "build the same Go and binary, byte for byte, from the same program: the build time comes from SOURCE_DATE_EPOCH, and go build uses -trimpath and the modules go.sum pins")
+	flag.BoolVar(&verify, "verify-reproducible", false, "build the program a second time in another directory and fail unless the two builds are identical, instead of running it; implies -reproducible")
+	flag.DurationVar(&limits.timeout, "timeout", 0, "stop the program if it runs for longer than `duration`, as in 30s")
+	flag.DurationVar(&limits.cpu, "cpu", 0, "stop the program if it uses more than `duration` of processor time (Linux)")
+	flag.Var(&limits.memory, "memory", "stop the program if it takes more than `size` of memory, as in 512M (on Linux; elsewhere it only sets GOMEMLIMIT)")
+	flag.BoolVar(&hotReload, "hot", false, "experimental: while the program runs, reload changed functions without restarting it")
+	flag.BoolVar(&checkStdlib, "check", false, "with version, warn when the stdlib in ~/simple/stdlib is older than the compiler")
+	flag.Var(&transforms, "transform", "run the AST transform of the Go plugin at `path`, a .so file or a package directory, on the program before it is analyzed; can be repeated")
+	flag.Var(defines, "define", "set `NAME=value` for a variable the program assigns at its top level, as in -define DEBUG=False; can be repeated")
+	flag.StringVar(&platform, "platform", "", "build a web server for `platform`: heroku writes a Procfile that runs it as the web process of a Heroku app, built by Heroku's Go buildpack, and makes http.ListenAndServe listen on PORT and answer /healthz")
+	targets = map[string]*bool{
+		"lambda": flag.Bool("lambda", false, "package the program as an AWS Lambda function zip instead of running it"),
+		"gcf":    flag.Bool("gcf", false, "package the program as a Google Cloud Functions source zip instead of running it"),
+		"azure":  flag.Bool("azure", false, "package the program as an Azure Functions custom handler zip instead of running it"),
+	}
+	flag.Usage = usage
+	flag.Parse()
+
 	// Check if the --version flag is passed
-	if len(os.Args) == 2 && os.Args[1] == "--version" {
+	if *showVersion {
 		fmt.Println(version)
 		return
 	}
 
-	//filename := "examples/myapp/myapp.simple"
-	filename := os.Args[1]
+	cmd, args := commandFor(flag.Args())
+	if *vv {
+		verbose.Level = 2
+	} else if *v {
+		verbose.Level = 1
+	}
+	if cmd == nil {
+		if len(args) > 0 {
+			printErrorf("unknown command %q; run simple help for the commands", args[0])
+		} else {
+			usage()
+		}
+		os.Exit(2)
+	}
+	os.Exit(cmd.run(args))
+}
+
+// buildProgram compiles the program in filename and builds it, then runs it
+// if run is set, and returns the status simple exits with.
+func buildProgram(filename string, run bool) int {
+	provider := ""
+	for _, name := range codegen.ServerlessProviders {
+		if !*targets[name] {
+			continue
+		}
+		if provider != "" {
+			printErrorf("-%s and -%s can't be used together", provider, name)
+			return 2
+		}
+		provider = name
+	}
+	if hotReload && !run {
+		printErrorf("-hot reloads a program as it runs; use simple run")
+		return 2
+	}
+	if provider != "" && hotReload {
+		printErrorf("-%s and -hot can't be used together", provider)
+		return 2
+	}
+	if platform != "" && !slices.Contains(codegen.Platforms, platform) {
+		printErrorf("unknown platform %q; the platforms are %s", platform, strings.Join(codegen.Platforms, ", "))
+		return 2
+	}
+	if provider != "" && platform != "" {
+		printErrorf("-%s and -platform can't be used together", provider)
+		return 2
+	}
+	if verify {
+		other := provider
+		if hotReload {
+			other = "hot"
+		}
+		if other != "" {
+			printErrorf("-verify-reproducible and -%s can't be used together", other)
+			return 2
+		}
+		reproducible = true
+	}
+
+	if sandbox {
+		// Belt and braces: no go command we start may reach the network
+		os.Setenv("GOPROXY", "off")
+		os.Setenv("GOFLAGS", "-mod=mod")
+	}
+
+	if reportPath != "" {
+		// Resolve now; building changes the working directory
+		reportPath, _ = filepath.Abs(reportPath)
+	}
 	mainContent, err := os.ReadFile(filename)
 	if err != nil {
-		fmt.Printf("Error reading file: %v\n", err)
-		return
+		printErrorf("reading file: %v", err)
+		return 1
 	}
 
 	// Code Generation
@@ -162,53 +352,160 @@ func main() {
 	cwd, _ := os.Getwd()
 	outputDir := filepath.Join(cwd, filename[:len(filename)-len(binaryName)-7])
 	os.MkdirAll(outputDir, os.ModePerm)
+	verbose.Logf(1, "output directory: %s", outputDir)
 
-	//fmt.Println("output directory: ", outputDir)
+	report := newBuildReport(filename, outputDir)
 
-	goVersion := "1.23.1"
+	config, err := loadProjectConfig(filepath.Dir(filepath.Join(cwd, filename)))
+	if err != nil {
+		printError(err)
+		return 1
+	}
+	if err := loadTransforms(append(config.Transforms, transforms...)); err != nil {
+		printError(err)
+		return 1
+	}
 
 	// Step 1: Create go.mod file
-	err = createGoMod(outputDir, goVersion)
-	//if err != nil {
-	//	fmt.Println("Error:", err)
-	//	return
-	//}
-
-	stdlibFiles, err := stdlib()
-	for _, file := range stdlibFiles {
-		content, err := os.ReadFile(file)
-		if err == nil {
-			destDir := filepath.Join(outputDir, "lib/"+strings.Split(filepath.Base(file), ".")[0])
-			//fmt.Println("stdlib dest: ", destDir)
-			os.MkdirAll(destDir, os.ModePerm)
-			compile(string(content), destDir, false)
+	err = createGoMod(outputDir, goVersion, sandbox)
+
+	report.timed("compile", func() {
+		// The stdlib is trusted; the policy only restricts the program itself
+		semantic.ActivePolicy = config.importPolicy(sandbox)
+
+		var imports []string
+		imports, err = compile(string(mainContent), outputDir, true)
+		report.addImports(imports)
+		semantic.ActivePolicy = semantic.Policy{}
+		compileStdlibModules(imports, outputDir)
+	})
+	if err != nil {
+		if reportPath != "" {
+			report.write(reportPath, err)
 		}
+		printError(err)
+		return 1
 	}
 
-	compile(string(mainContent), outputDir, true)
-
-	//goVersion := "1.23.1"
+	// Embed compiler version and source metadata in the binary
+	sum := sha256.Sum256(mainContent)
+	builtAt, err := buildTime()
+	if err != nil {
+		printError(err)
+		return 1
+	}
+	buildInfo := codegen.BuildInfo{
+		CompilerVersion: version,
+		SourceHash:      hex.EncodeToString(sum[:]),
+		BuildTime:       builtAt,
+	}
+	err = codegen.WriteBuildInfo(outputDir, buildInfo)
+	if err == nil {
+		if hotReload {
+			err = codegen.WriteHotReloadHost(outputDir)
+		} else {
+			os.Remove(filepath.Join(outputDir, "simple_hot.go"))
+		}
+	}
+	if err != nil {
+		printError(err)
+		return 1
+	}
 
 	// Step 1: Create go.mod file
-	err = createGoMod(outputDir, goVersion)
-	//if err != nil {
-	//	fmt.Println("Error:", err)
-	//	return
-	//}
+	report.timed("go_mod", func() {
+		err = createGoMod(outputDir, goVersion, sandbox)
+	})
+
+	if provider != "" {
+		var zipPath string
+		report.timed("package", func() {
+			zipPath, err = packageFunction(provider, outputDir, binaryName)
+		})
+		if err == nil {
+			report.Package = zipPath
+		}
+		if reportPath != "" {
+			if reportErr := report.write(reportPath, err); reportErr != nil {
+				printErrorf("writing report: %v", reportErr)
+			}
+		}
+		if err != nil {
+			printError(err)
+			return 1
+		}
+		fmt.Println(zipPath)
+		return 0
+	}
+
+	if platform == "heroku" {
+		procfile, err := writeProcfile(outputDir)
+		if err != nil {
+			printError(err)
+			return 1
+		}
+		fmt.Println(procfile)
+	}
 
 	// Step 2: Build the project
-	_, err = buildGoProject(outputDir, binaryName)
+	report.timed("go_build", func() {
+		_, err = buildGoProject(outputDir, binaryName)
+	})
+	if err == nil {
+		report.Binary = filepath.Join(outputDir, binaryName)
+	}
+	if reportPath != "" {
+		if reportErr := report.write(reportPath, err); reportErr != nil {
+			printErrorf("writing report: %v", reportErr)
+		}
+	}
 	if err != nil {
-		fmt.Println("Error:", err)
-		return
+		printError(err)
+		return 1
 	}
 
 	fmt.Printf("%s/%s\n", outputDir, binaryName)
 
+	if verify {
+		binarySum, err := verifyReproducible(outputDir, binaryName, func(dir string) error {
+			semantic.ActivePolicy = config.importPolicy(sandbox)
+			imports, err := compile(string(mainContent), dir, true)
+			semantic.ActivePolicy = semantic.Policy{}
+			if err != nil {
+				return err
+			}
+			compileStdlibModules(imports, dir)
+			if err := codegen.WriteBuildInfo(dir, buildInfo); err != nil {
+				return err
+			}
+			_, err = buildGoProject(dir, binaryName)
+			return err
+		})
+		if err != nil {
+			printError(err)
+			return 1
+		}
+		fmt.Println("reproducible: sha256", binarySum)
+		return 0
+	}
+
+	if sandbox || !run {
+		return 0
+	}
+
 	// Step 3: Run the binary
-	err = runBinary(filepath.Join(outputDir, binaryName))
+	if hotReload {
+		err = runHot(filepath.Join(cwd, filename), outputDir, binaryName)
+	} else {
+		err = runBinary(filepath.Join(outputDir, binaryName))
+	}
+	var stopped *signalError
+	if errors.As(err, &stopped) {
+		return stopped.exitCode()
+	}
 	if err != nil {
-		fmt.Println("Error:", err)
-		return
+		printError(err)
+		return 1
 	}
+	return 0
 }
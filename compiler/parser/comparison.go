@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/lexer"
+)
+
+// Chained comparisons
+//
+// As in Python, a < b < c means a < b and b < c, with b evaluated once and
+// c only if a < b. The parser expands a chain into comparisons joined by
+// and. An operand shared by two comparisons that could have effects, such
+// as a call, is passed once to a lambda holding the rest of the chain:
+// a < f() < c is (lambda simpleOperand1: a < simpleOperand1 and
+// simpleOperand1 < c)(f()).
+
+// isComparison reports whether t is a comparison operator, or begins one.
+// Membership tests with in and not in, and identity tests with is and is
+// not, are comparisons too, so a in b == c chains.
+func isComparison(t lexer.Token) bool {
+	switch t.Type {
+	case lexer.TokenEQ, lexer.TokenNotEQ, lexer.TokenLT, lexer.TokenLTE, lexer.TokenGT, lexer.TokenGTE, lexer.TokenNot:
+		return true
+	case lexer.TokenKeyword:
+		return t.Literal == "in" || t.Literal == "is"
+	}
+	return false
+}
+
+// comparisonOperator reads the comparison operator at the current token,
+// joining not in and is not into one operator.
+func (p *Parser) comparisonOperator() (lexer.Token, bool) {
+	operator := p.curToken
+	switch {
+	case operator.Type == lexer.TokenNot:
+		if p.peekToken.Type != lexer.TokenKeyword || p.peekToken.Literal != "in" {
+			p.errors = append(p.errors, fmt.Sprintf("expected in after not, got %s instead (Line %d, Column %d)", p.peekToken.Literal, p.peekToken.Line, p.peekToken.Column))
+			return operator, false
+		}
+		p.nextToken()
+		operator.Literal = "not in"
+	case operator.Literal == "is" && p.peekToken.Type == lexer.TokenNot:
+		p.nextToken()
+		operator.Literal = "is not"
+	}
+	return operator, true
+}
+
+// parseComparison parses a comparison and the comparisons chained to it.
+// Comparisons share a precedence in a chain, so a == b < c chains as
+// a < b == c does.
+func (p *Parser) parseComparison(left Expression) Expression {
+	operators := []lexer.Token{}
+	operands := []Expression{left}
+	for {
+		operator, ok := p.comparisonOperator()
+		if !ok {
+			return nil
+		}
+		operators = append(operators, operator)
+		p.nextToken()
+		right := p.parseExpression(LESSGREATER)
+		if right == nil {
+			return nil
+		}
+		operands = append(operands, right)
+		if !isComparison(p.peekToken) {
+			break
+		}
+		p.nextToken()
+	}
+	if len(operands) == 2 {
+		return comparison(operands[0], operators[0], operands[1])
+	}
+
+	// The first operand is evaluated before the second, so it is passed to
+	// the lambda first when the second is
+	if !isPure(operands[0]) && !isPure(operands[1]) {
+		return p.bindOperand(operands[0], operators[0], func(operand Expression) Expression {
+			return p.chain(operand, operators, operands[1:])
+		})
+	}
+	return p.chain(operands[0], operators, operands[1:])
+}
+
+// chain returns the comparisons of left and the operands after it, joined
+// by and.
+func (p *Parser) chain(left Expression, operators []lexer.Token, operands []Expression) Expression {
+	if len(operands) == 1 {
+		return comparison(left, operators[0], operands[0])
+	}
+	rest := func(right Expression) Expression {
+		return &InfixExpression{
+			Token:    lexer.Token{Type: lexer.TokenAnd, Literal: "and", Line: operators[1].Line, Column: operators[1].Column},
+			Left:     comparison(left, operators[0], right),
+			Operator: "and",
+			Right:    p.chain(right, operators[1:], operands[1:]),
+		}
+	}
+	if isPure(operands[0]) {
+		return rest(operands[0])
+	}
+	return p.bindOperand(operands[0], operators[0], rest)
+}
+
+// bindOperand returns a call passing operand to a lambda whose body is
+// body of the lambda's parameter. The call is placed at the comparison
+// operand is compared by.
+func (p *Parser) bindOperand(operand Expression, at lexer.Token, body func(Expression) Expression) Expression {
+	p.operands++
+	name := fmt.Sprintf("simpleOperand%d", p.operands)
+	param := &Identifier{Token: lexer.Token{Type: lexer.TokenIdentifier, Literal: name, Line: at.Line, Column: at.Column}, Value: name}
+	return &CallExpression{
+		Token: lexer.Token{Type: lexer.TokenParenOpen, Literal: "(", Line: at.Line, Column: at.Column},
+		Function: &LambdaExpression{
+			Token:      lexer.Token{Type: lexer.TokenLambda, Literal: "lambda", Line: at.Line, Column: at.Column},
+			Parameters: []*Identifier{param},
+			Body:       body(param),
+		},
+		Arguments: []Expression{operand},
+	}
+}
+
+// comparison returns the comparison of left and right.
+func comparison(left Expression, operator lexer.Token, right Expression) Expression {
+	return &InfixExpression{Token: operator, Left: left, Operator: operator.Literal, Right: right}
+}
+
+// isPure reports whether evaluating an expression twice is the same as
+// evaluating it once: names, literals and the fields of names.
+func isPure(expr Expression) bool {
+	switch e := expr.(type) {
+	case *Identifier, *IntegerLiteral, *StringLiteral, *BooleanLiteral, *NoneLiteral:
+		return true
+	case *SelectorExpression:
+		return isPure(e.Left)
+	}
+	return false
+}
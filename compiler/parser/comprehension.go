@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"strings"
+)
+
+// ForClause is the `for x in items if condition` of a comprehension. A
+// dict's items() give two variables, the key and the value.
+type ForClause struct {
+	Token     lexer.Token // The 'for' token
+	Variables []*Identifier
+	Iterable  Expression
+	Condition Expression // nil without an if
+}
+
+func (fc *ForClause) String() string {
+	names := []string{}
+	for _, v := range fc.Variables {
+		names = append(names, v.Value)
+	}
+	out := "for " + strings.Join(names, ", ") + " in " + fc.Iterable.String()
+	if fc.Condition != nil {
+		out += " if " + fc.Condition.String()
+	}
+	return out
+}
+
+// ListComprehension represents [element for x in items if condition].
+type ListComprehension struct {
+	Token   lexer.Token // The '[' token
+	Element Expression
+	Clause  *ForClause
+}
+
+func (lc *ListComprehension) expressionNode()      {}
+func (lc *ListComprehension) TokenLiteral() string { return lc.Token.Literal }
+func (lc *ListComprehension) String() string {
+	return "[" + lc.Element.String() + " " + lc.Clause.String() + "]"
+}
+
+// DictComprehension represents {key: value for x in items if condition}.
+type DictComprehension struct {
+	Token  lexer.Token // The '{' token
+	Key    Expression
+	Value  Expression
+	Clause *ForClause
+}
+
+func (dc *DictComprehension) expressionNode()      {}
+func (dc *DictComprehension) TokenLiteral() string { return dc.Token.Literal }
+func (dc *DictComprehension) String() string {
+	return "{" + dc.Key.String() + ": " + dc.Value.String() + " " + dc.Clause.String() + "}"
+}
+
+// parseForClause parses the for clause of a comprehension, with the 'for'
+// as the next token, up to and including the closing bracket end.
+func (p *Parser) parseForClause(end lexer.TokenType) *ForClause {
+	p.nextToken()
+	fc := &ForClause{Token: p.curToken}
+	for {
+		if !p.expectPeek(lexer.TokenIdentifier) {
+			return nil
+		}
+		fc.Variables = append(fc.Variables, &Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		if p.peekToken.Type != lexer.TokenComma {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(lexer.TokenKeyword) || p.curToken.Literal != "in" {
+		msg := fmt.Sprintf("expected 'in', got %s instead (Line %d, Column %d)", p.curToken.Literal, p.curToken.Line, p.curToken.Column)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	p.nextToken()
+	// The if after the iterable filters it, rather than starting a
+	// conditional expression
+	fc.Iterable = p.parseExpression(TERNARY)
+
+	if p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "if" {
+		p.nextToken()
+		p.nextToken()
+		fc.Condition = p.parseExpression(LOWEST)
+	}
+	if fc.Iterable == nil || !p.expectPeek(end) {
+		return nil
+	}
+	return fc
+}
+
+// parseListComprehension parses the rest of [element for ...], with the
+// element already parsed.
+func (p *Parser) parseListComprehension(token lexer.Token, element Expression) Expression {
+	clause := p.parseForClause(lexer.TokenBracketClose)
+	if clause == nil {
+		return nil
+	}
+	return &ListComprehension{Token: token, Element: element, Clause: clause}
+}
+
+// parseDictComprehension parses the rest of {key: value for ...}, with the
+// key and value already parsed.
+func (p *Parser) parseDictComprehension(token lexer.Token, key, value Expression) Expression {
+	clause := p.parseForClause(lexer.TokenBraceClose)
+	if clause == nil {
+		return nil
+	}
+	return &DictComprehension{Token: token, Key: key, Value: value, Clause: clause}
+}
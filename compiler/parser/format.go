@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseFormatTemplate splits the template of str.format, such as
+// "{} is {age:>3}", into text and {fields}. A field's Source names the
+// argument it formats: "" for the next one, an index, or a keyword.
+func ParseFormatTemplate(s string) ([]FStringPart, error) {
+	parts := []FStringPart{}
+	var text strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"), strings.HasPrefix(s[i:], "}}"):
+			text.WriteByte(s[i])
+			i++
+		case s[i] == '}':
+			return nil, fmt.Errorf("single '}' encountered in format string")
+		case s[i] == '{':
+			end := fieldEnd(s, i+1)
+			if end < 0 {
+				return nil, fmt.Errorf("expected '}' before end of string")
+			}
+			if text.Len() > 0 {
+				parts = append(parts, FStringPart{Text: text.String()})
+				text.Reset()
+			}
+			source, conversion, spec, ok := splitField(s[i+1 : end])
+			if !ok {
+				return nil, fmt.Errorf("invalid conversion character in {%s}; expected 'r' or 's'", s[i+1:end])
+			}
+			parts = append(parts, FStringPart{Source: source, Conversion: conversion, Spec: spec})
+			i = end
+		default:
+			text.WriteByte(s[i])
+		}
+	}
+	if text.Len() > 0 {
+		parts = append(parts, FStringPart{Text: text.String()})
+	}
+	return parts, nil
+}
+
+// ParsePercentTemplate splits the template of the % operator, such as
+// "%-10s|%5.2f%%", into text and a field for each conversion specifier.
+// Each specifier is written as an f-string field would be, so %-10s has
+// the conversion 's' and the spec "<10". A field's Source is the specifier.
+func ParsePercentTemplate(s string) ([]FStringPart, error) {
+	parts := []FStringPart{}
+	var text strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			text.WriteByte(s[i])
+			continue
+		}
+		start := i
+		i++
+		if i < len(s) && s[i] == '%' {
+			text.WriteByte('%')
+			continue
+		}
+		if i < len(s) && s[i] == '(' {
+			return nil, fmt.Errorf("%%(name) keys aren't supported; use .format() or an f-string")
+		}
+
+		var align, sign byte
+		alternate, zero := false, false
+	flags:
+		for ; i < len(s); i++ {
+			switch s[i] {
+			case '-':
+				align = '<'
+			case '+':
+				sign = '+'
+			case ' ':
+				if sign == 0 {
+					sign = ' '
+				}
+			case '#':
+				alternate = true
+			case '0':
+				zero = true
+			default:
+				break flags
+			}
+		}
+		digits := func() string {
+			from := i
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+			}
+			return s[from:i]
+		}
+		width := digits()
+		precision := ""
+		if i < len(s) && s[i] == '.' {
+			i++
+			if precision = digits(); precision == "" {
+				precision = "0"
+			}
+		}
+		if i < len(s) && s[i] == '*' {
+			return nil, fmt.Errorf("'*' widths and precisions aren't supported")
+		}
+		// Python accepts and ignores C's length modifiers
+		for i < len(s) && strings.IndexByte("hlL", s[i]) >= 0 {
+			i++
+		}
+		if i >= len(s) {
+			return nil, fmt.Errorf("incomplete format")
+		}
+
+		part := FStringPart{Source: s[start : i+1]}
+		verb := s[i]
+		switch verb {
+		case 's', 'r':
+			part.Conversion = rune(verb)
+			verb, sign, alternate, zero = 0, 0, false, false
+			if align == 0 && width != "" {
+				// %10s right-aligns, where {:10} would left-align a string
+				align = '>'
+			}
+		case 'd', 'i', 'u':
+			verb = 'd'
+		case 'o', 'x', 'X', 'e', 'E', 'f', 'F', 'g', 'G':
+		case 'c', 'a':
+			return nil, fmt.Errorf("%%%c isn't supported", verb)
+		default:
+			return nil, fmt.Errorf("unsupported format character '%c' (0x%x) at index %d", s[i], s[i], i)
+		}
+
+		var spec strings.Builder
+		if align != 0 {
+			spec.WriteByte(align)
+		}
+		if sign != 0 {
+			spec.WriteByte(sign)
+		}
+		if alternate {
+			spec.WriteByte('#')
+		}
+		if zero && align == 0 {
+			spec.WriteByte('0')
+		}
+		spec.WriteString(width)
+		if precision != "" {
+			spec.WriteString("." + precision)
+		}
+		if verb != 0 {
+			spec.WriteByte(verb)
+		}
+		part.Spec = spec.String()
+
+		if text.Len() > 0 {
+			parts = append(parts, FStringPart{Text: text.String()})
+			text.Reset()
+		}
+		parts = append(parts, part)
+	}
+	if text.Len() > 0 {
+		parts = append(parts, FStringPart{Text: text.String()})
+	}
+	return parts, nil
+}
@@ -0,0 +1,298 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"strings"
+	"unicode/utf8"
+)
+
+// FStringLiteral represents an f-string such as f"total: {x + 1:.2f}". Its
+// parts are text and the expressions interpolated between them.
+type FStringLiteral struct {
+	Token  lexer.Token // The f-string token
+	Parts  []FStringPart
+	Errors []string // Problems in the braces, reported by the analyzer
+}
+
+// FStringPart is a run of text in an f-string, or one {expression} when
+// Expression is set.
+type FStringPart struct {
+	Text       string // The text, or for {x=} the text printed before the value
+	Expression Expression
+	Source     string // The expression as written, for {x=}
+	Conversion rune   // 'r' for {x!r}, 's' for {x!s}, otherwise 0
+	Spec       string // The format spec after the colon, as in {x:>8}
+	Debug      bool   // {x=} prints the expression before its value
+}
+
+func (fs *FStringLiteral) expressionNode()      {}
+func (fs *FStringLiteral) TokenLiteral() string { return fs.Token.Literal }
+func (fs *FStringLiteral) String() string {
+	var out strings.Builder
+	out.WriteString("f\"")
+	for _, part := range fs.Parts {
+		if part.Expression == nil {
+			text := strings.ReplaceAll(part.Text, "{", "{{")
+			out.WriteString(strings.ReplaceAll(text, "}", "}}"))
+			continue
+		}
+		if part.Debug {
+			out.WriteString("{" + part.Text)
+		} else {
+			out.WriteString("{" + part.Source)
+		}
+		if part.Conversion != 0 {
+			out.WriteString("!" + string(part.Conversion))
+		}
+		if part.Spec != "" {
+			out.WriteString(":" + part.Spec)
+		}
+		out.WriteString("}")
+	}
+	out.WriteString("\"")
+	return out.String()
+}
+
+// parseFStringLiteral parses an f-string token, along with any strings
+// written right after it, which Python joins to it.
+func (p *Parser) parseFStringLiteral() Expression {
+	fs := &FStringLiteral{Token: p.curToken}
+	p.splitFString(fs, p.curToken)
+	for p.peekToken.Type == lexer.TokenString || p.peekToken.Type == lexer.TokenFString {
+		p.nextToken()
+		if p.curToken.Type == lexer.TokenString {
+			fs.Parts = append(fs.Parts, FStringPart{Text: p.curToken.Literal})
+		} else {
+			p.splitFString(fs, p.curToken)
+		}
+	}
+	return fs
+}
+
+// splitFString adds the text and {expressions} of an f-string token to fs.
+// Doubled braces stand for the brace itself.
+func (p *Parser) splitFString(fs *FStringLiteral, tok lexer.Token) {
+	s := tok.Literal
+	var text strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"), strings.HasPrefix(s[i:], "}}"):
+			text.WriteByte(s[i])
+			i++
+		case s[i] == '}':
+			fs.Errors = append(fs.Errors, fmt.Sprintf("f-string: single '}' is not allowed (Line %d, Column %d)", tok.Line, tok.Column))
+		case s[i] == '{':
+			end := fieldEnd(s, i+1)
+			if end < 0 {
+				fs.Errors = append(fs.Errors, fmt.Sprintf("f-string: expecting '}' (Line %d, Column %d)", tok.Line, tok.Column))
+				return
+			}
+			if text.Len() > 0 {
+				fs.Parts = append(fs.Parts, FStringPart{Text: text.String()})
+				text.Reset()
+			}
+			// The column of the field, after the f and the opening quote
+			column := tok.Column + 2 + utf8.RuneCountInString(s[:i+1])
+			fs.Parts = append(fs.Parts, p.parseFStringField(fs, s[i+1:end], tok.Line, column))
+			i = end
+		default:
+			text.WriteByte(s[i])
+		}
+	}
+	if text.Len() > 0 {
+		fs.Parts = append(fs.Parts, FStringPart{Text: text.String()})
+	}
+}
+
+// fieldEnd returns the index of the brace closing the field that starts at
+// start, skipping brackets and quoted strings in the expression, or -1.
+func fieldEnd(s string, start int) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// parseFStringField parses the inside of one {field}: an expression, then
+// optionally = for debugging, !r or !s, and a format spec after a colon.
+func (p *Parser) parseFStringField(fs *FStringLiteral, field string, line, column int) FStringPart {
+	source, conversion, spec, ok := splitField(field)
+	part := FStringPart{Conversion: conversion, Spec: spec}
+	if !ok {
+		fs.Errors = append(fs.Errors, fmt.Sprintf("f-string: invalid conversion character in {%s}; expected 'r' or 's' (Line %d, Column %d)", field, line, column))
+		return part
+	}
+
+	trimmed := strings.TrimRight(source, " ")
+	if strings.HasSuffix(trimmed, "=") && !strings.HasSuffix(trimmed, "==") && !strings.HasSuffix(trimmed, "!=") &&
+		!strings.HasSuffix(trimmed, "<=") && !strings.HasSuffix(trimmed, ">=") {
+		part.Debug = true
+		part.Text = source
+		source = strings.TrimSuffix(trimmed, "=")
+		if part.Conversion == 0 && part.Spec == "" {
+			part.Conversion = 'r'
+		}
+	}
+
+	part.Source = strings.TrimSpace(source)
+	if part.Source == "" {
+		fs.Errors = append(fs.Errors, fmt.Sprintf("f-string: empty expression not allowed (Line %d, Column %d)", line, column))
+		return part
+	}
+	column += len(source) - len(strings.TrimLeft(source, " "))
+	sub := NewParser(lexer.NewLexerAt(part.Source, line, column))
+	part.Expression = sub.parseExpression(LOWEST)
+	if part.Expression == nil || len(sub.errors) > 0 || (sub.peekToken.Type != lexer.TokenEOF && sub.peekToken.Type != lexer.TokenNewline) {
+		part.Expression = nil
+		fs.Errors = append(fs.Errors, fmt.Sprintf("f-string: invalid expression {%s} (Line %d, Column %d)", part.Source, line, column))
+	}
+	return part
+}
+
+// splitField splits the inside of a {field} into its expression or name,
+// its !r or !s conversion and the format spec after the colon. It reports
+// false for any other conversion.
+func splitField(field string) (source string, conversion rune, spec string, ok bool) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case depth == 0 && c == ':':
+			return field[:i], 0, field[i+1:], true
+		case depth == 0 && c == '!' && (i+1 >= len(field) || field[i+1] != '='):
+			rest := field[i+1:]
+			if rest == "" || (rest[0] != 'r' && rest[0] != 's') || (len(rest) > 1 && rest[1] != ':') {
+				return field[:i], 0, "", false
+			}
+			if len(rest) > 1 {
+				spec = rest[2:]
+			}
+			return field[:i], rune(rest[0]), spec, true
+		}
+	}
+	return field, 0, "", true
+}
+
+// FormatSpec is a parsed Python format spec, the part after the colon in
+// f"{price:>10,.2f}": [[fill]align][sign][#][0][width][grouping][.precision][type].
+type FormatSpec struct {
+	Fill      rune   // Padding character, ' ' unless given
+	Align     rune   // '<', '>', '^' or '=', or 0 for the default
+	Sign      rune   // '+', '-' or ' ', or 0 for the default
+	Alternate bool   // '#': 0x, 0o and 0b prefixes
+	Zero      bool   // '0': pad numbers with zeros
+	Width     string // Minimum width, or ""
+	Grouping  rune   // ',' or '_' to group thousands, or 0
+	Precision string // Digits after the '.', or ""
+	Type      rune   // 'd', 'f', 's' and so on, or 0 for the default
+}
+
+// String writes the spec as it would be written after the colon.
+func (fs FormatSpec) String() string {
+	var out strings.Builder
+	if fs.Align != 0 {
+		if fs.Fill != ' ' {
+			out.WriteRune(fs.Fill)
+		}
+		out.WriteRune(fs.Align)
+	}
+	if fs.Sign != 0 {
+		out.WriteRune(fs.Sign)
+	}
+	if fs.Alternate {
+		out.WriteByte('#')
+	}
+	if fs.Zero {
+		out.WriteByte('0')
+	}
+	out.WriteString(fs.Width)
+	if fs.Grouping != 0 {
+		out.WriteRune(fs.Grouping)
+	}
+	if fs.Precision != "" {
+		out.WriteString("." + fs.Precision)
+	}
+	if fs.Type != 0 {
+		out.WriteRune(fs.Type)
+	}
+	return out.String()
+}
+
+// ParseFormatSpec parses a format spec such as ">10,.2f".
+func ParseFormatSpec(spec string) (FormatSpec, error) {
+	fs := FormatSpec{Fill: ' '}
+	rest := []rune(spec)
+	isAlign := func(r rune) bool { return strings.ContainsRune("<>=^", r) }
+	if len(rest) >= 2 && isAlign(rest[1]) {
+		fs.Fill, fs.Align, rest = rest[0], rest[1], rest[2:]
+	} else if len(rest) >= 1 && isAlign(rest[0]) {
+		fs.Align, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 && strings.ContainsRune("+- ", rest[0]) {
+		fs.Sign, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 && rest[0] == '#' {
+		fs.Alternate, rest = true, rest[1:]
+	}
+	if len(rest) > 0 && rest[0] == '0' {
+		fs.Zero, rest = true, rest[1:]
+	}
+	digits := func() string {
+		n := 0
+		for n < len(rest) && rest[n] >= '0' && rest[n] <= '9' {
+			n++
+		}
+		d := string(rest[:n])
+		rest = rest[n:]
+		return d
+	}
+	fs.Width = digits()
+	if len(rest) > 0 && (rest[0] == ',' || rest[0] == '_') {
+		fs.Grouping, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 && rest[0] == '.' {
+		rest = rest[1:]
+		if fs.Precision = digits(); fs.Precision == "" {
+			return fs, fmt.Errorf("format spec '%s' is missing the precision after '.'", spec)
+		}
+	}
+	if len(rest) > 0 && strings.ContainsRune("bcdeEfFgGnosxX%", rest[0]) {
+		fs.Type, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 {
+		return fs, fmt.Errorf("invalid format spec '%s'", spec)
+	}
+	return fs, nil
+}
@@ -80,6 +80,49 @@ func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
 func (al *ArrayLiteral) String() string       { return al.Token.Literal }
 
+// TupleLiteral represents a comma-separated list of values, such as
+// (1, "a") or the right-hand side of x, y = y, x.
+type TupleLiteral struct {
+	Token    lexer.Token // The '(' token, or the first token of a bare tuple
+	Elements []Expression
+}
+
+func (tl *TupleLiteral) expressionNode()      {}
+func (tl *TupleLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TupleLiteral) String() string {
+	elements := []string{}
+	for _, el := range tl.Elements {
+		elements = append(elements, el.String())
+	}
+	if len(elements) == 1 {
+		return "(" + elements[0] + ",)"
+	}
+	return "(" + strings.Join(elements, ", ") + ")"
+}
+
+// TupleType represents the type of a tuple, a fixed number of values of
+// their own types. Its Go type is a struct with a field for each value,
+// Item0, Item1 and so on, so that tuples compare with == and can be dict
+// keys.
+type TupleType struct {
+	ElementTypes []Type
+}
+
+func (tt *TupleType) TypeName() string {
+	return tt.String()
+}
+
+func (tt *TupleType) String() string {
+	fields := []string{}
+	for i, t := range tt.ElementTypes {
+		fields = append(fields, fmt.Sprintf("Item%d %s", i, t.String()))
+	}
+	if len(fields) == 0 {
+		return "struct{}"
+	}
+	return "struct{ " + strings.Join(fields, "; ") + " }"
+}
+
 // MapType represents a map/dictionary type with key and value types.
 type MapType struct {
 	KeyType   Type
@@ -98,6 +141,7 @@ func (mt *MapType) String() string {
 type MapLiteral struct {
 	Token     lexer.Token // The '{' token
 	Pairs     map[Expression]Expression
+	Keys      []Expression // the keys of Pairs in the order they are written
 	Type      Type
 	KeyType   Type
 	ValueType Type
@@ -107,6 +151,32 @@ func (ml *MapLiteral) expressionNode()      {}
 func (ml *MapLiteral) TokenLiteral() string { return ml.Token.Literal }
 func (ml *MapLiteral) String() string       { return ml.Token.Literal }
 
+// SetType represents a set type with element type. Its Go type is a map
+// from the elements to empty structs.
+type SetType struct {
+	ElementType Type
+}
+
+func (st *SetType) TypeName() string {
+	return "map[" + st.ElementType.TypeName() + "]struct{}"
+}
+
+func (st *SetType) String() string {
+	return fmt.Sprintf("map[%s]struct{}", st.ElementType.String())
+}
+
+// SetLiteral represents a set literal such as {1, 2, 3}. {} is an empty
+// dict, as in Python.
+type SetLiteral struct {
+	Token       lexer.Token // The '{' token
+	Elements    []Expression
+	ElementType Type
+}
+
+func (sl *SetLiteral) expressionNode()      {}
+func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SetLiteral) String() string       { return sl.Token.Literal }
+
 // BuiltinType represents a built-in function type.
 type BuiltinType struct {
 	Name string
@@ -137,11 +207,15 @@ func (ft *FunctionType) String() string {
 		params = append(params, p.String())
 	}
 
-	if len(ft.ReturnTypes) > 0 {
+	// A function returning nothing has the result void
+	if len(ft.ReturnTypes) > 0 && !(len(ft.ReturnTypes) == 1 && ft.ReturnTypes[0].String() == "void") {
 		returnTypes := []string{}
 		for _, r := range ft.ReturnTypes {
 			returnTypes = append(returnTypes, r.String())
 		}
+		if len(returnTypes) == 1 {
+			return fmt.Sprintf("func(%s) %s", strings.Join(params, ", "), returnTypes[0])
+		}
 		return fmt.Sprintf("func(%s) (%s)", strings.Join(params, ", "), strings.Join(returnTypes, ", "))
 	}
 
@@ -208,6 +282,7 @@ type Expression interface {
 // Program is the root node of the AST.
 type Program struct {
 	Statements []Statement
+	Doc        string // the string the program starts with, its docstring, or ""
 }
 
 func (p *Program) statementNode()       {}
@@ -251,6 +326,15 @@ func (bl *BooleanLiteral) expressionNode()      {}
 func (bl *BooleanLiteral) TokenLiteral() string { return bl.Token.Literal }
 func (bl *BooleanLiteral) String() string       { return bl.Token.Literal }
 
+// NoneLiteral represents None, the absence of a value.
+type NoneLiteral struct {
+	Token lexer.Token
+}
+
+func (nl *NoneLiteral) expressionNode()      {}
+func (nl *NoneLiteral) TokenLiteral() string { return nl.Token.Literal }
+func (nl *NoneLiteral) String() string       { return nl.Token.Literal }
+
 // StringLiteral represents a string literal.
 type StringLiteral struct {
 	Token lexer.Token
@@ -263,10 +347,14 @@ func (sl *StringLiteral) String() string       { return "\"" + sl.Value + "\"" }
 
 // FunctionLiteral represents a function definition.
 type FunctionLiteral struct {
-	Token      lexer.Token
-	Name       *Identifier
-	Parameters []*Identifier
-	Body       *BlockStatement
+	Token       lexer.Token
+	Name        *Identifier
+	Parameters  []*Identifier
+	Annotations []*TypeAnnotation // the annotation of each parameter, nil where it has none
+	Result      *TypeAnnotation   // the annotation after ->, or nil
+	Body        *BlockStatement
+	Decorators  []Expression // @decorator lines above the def, outermost first
+	Doc         string       // the string the body starts with, its docstring, or ""
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -275,19 +363,71 @@ func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FunctionLiteral) String() string {
 	var out strings.Builder
 	params := []string{}
-	for _, p := range fl.Parameters {
+	for i, p := range fl.Parameters {
+		if i < len(fl.Annotations) && fl.Annotations[i] != nil {
+			params = append(params, p.String()+": "+fl.Annotations[i].String())
+			continue
+		}
 		params = append(params, p.String())
 	}
+	for _, d := range fl.Decorators {
+		out.WriteString("@" + d.String() + "\n")
+	}
 	out.WriteString("def ")
 	out.WriteString(fl.Name.String())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(")")
+	if fl.Result != nil {
+		out.WriteString(" -> " + fl.Result.String())
+	}
 	out.WriteString(":\n")
 	out.WriteString(fl.Body.String())
 	return out.String()
 }
 
+// TypeAnnotation is the type a parameter or the result of a function is
+// annotated with, as in int, list[str] or dict[str, int].
+type TypeAnnotation struct {
+	Token     lexer.Token
+	Name      string
+	Arguments []*TypeAnnotation // the types in brackets, as in the str of list[str]
+}
+
+func (ta *TypeAnnotation) TokenLiteral() string { return ta.Token.Literal }
+func (ta *TypeAnnotation) String() string {
+	if len(ta.Arguments) == 0 {
+		return ta.Name
+	}
+	args := []string{}
+	for _, arg := range ta.Arguments {
+		args = append(args, arg.String())
+	}
+	return ta.Name + "[" + strings.Join(args, ", ") + "]"
+}
+
+// ClassStatement represents a class definition, whose body gives fields
+// their defaults and defines methods.
+type ClassStatement struct {
+	Token lexer.Token // The 'class' token
+	Name  *Identifier
+	Bases []*Identifier // the classes it inherits from, as in class Dog(Animal)
+	Body  *BlockStatement
+}
+
+func (cs *ClassStatement) statementNode()       {}
+func (cs *ClassStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ClassStatement) String() string {
+	bases := []string{}
+	for _, base := range cs.Bases {
+		bases = append(bases, base.String())
+	}
+	if len(bases) > 0 {
+		return "class " + cs.Name.String() + "(" + strings.Join(bases, ", ") + "):\n" + cs.Body.String()
+	}
+	return "class " + cs.Name.String() + ":\n" + cs.Body.String()
+}
+
 // BlockStatement represents a block of statements.
 type BlockStatement struct {
 	Token      lexer.Token
@@ -341,6 +481,70 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// ConditionalExpression represents a conditional expression, a if c else b.
+type ConditionalExpression struct {
+	Token       lexer.Token // The 'if' token
+	Consequence Expression
+	Condition   Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()      {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) String() string {
+	if ce.Alternative == nil {
+		return "(" + ce.Consequence.String() + " if " + ce.Condition.String() + ")"
+	}
+	return "(" + ce.Consequence.String() + " if " + ce.Condition.String() + " else " + ce.Alternative.String() + ")"
+}
+
+// AssignmentExpression represents an assignment expression, name := value,
+// which assigns value to name and is its value.
+type AssignmentExpression struct {
+	Token  lexer.Token // The ':=' token
+	Target Expression
+	Value  Expression
+}
+
+func (ae *AssignmentExpression) expressionNode()      {}
+func (ae *AssignmentExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignmentExpression) String() string {
+	return "(" + ae.Target.String() + " := " + ae.Value.String() + ")"
+}
+
+// LambdaExpression represents an anonymous function such as lambda x: x.age.
+type LambdaExpression struct {
+	Token      lexer.Token // The 'lambda' token
+	Parameters []*Identifier
+	Body       Expression
+}
+
+func (le *LambdaExpression) expressionNode()      {}
+func (le *LambdaExpression) TokenLiteral() string { return le.Token.Literal }
+func (le *LambdaExpression) String() string {
+	params := []string{}
+	for _, p := range le.Parameters {
+		params = append(params, p.Value)
+	}
+	if len(params) == 0 {
+		return "lambda: " + le.Body.String()
+	}
+	return "lambda " + strings.Join(params, ", ") + ": " + le.Body.String()
+}
+
+// KeywordArgument represents a name=value argument in a call.
+type KeywordArgument struct {
+	Token lexer.Token // The name token
+	Name  *Identifier
+	Value Expression
+}
+
+func (ka *KeywordArgument) expressionNode()      {}
+func (ka *KeywordArgument) TokenLiteral() string { return ka.Token.Literal }
+func (ka *KeywordArgument) String() string {
+	return ka.Name.String() + "=" + ka.Value.String()
+}
+
 // ReturnStatement represents a return statement.
 type ReturnStatement struct {
 	Token       lexer.Token
@@ -358,6 +562,22 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// YieldStatement represents a yield statement, which makes the function it
+// is in a generator.
+type YieldStatement struct {
+	Token lexer.Token
+	Value Expression
+}
+
+func (ys *YieldStatement) statementNode()       {}
+func (ys *YieldStatement) TokenLiteral() string { return ys.Token.Literal }
+func (ys *YieldStatement) String() string {
+	if ys.Value == nil {
+		return "yield"
+	}
+	return "yield " + ys.Value.String()
+}
+
 // IfStatement represents an if statement.
 type IfStatement struct {
 	Token       lexer.Token
@@ -370,17 +590,31 @@ func (is *IfStatement) statementNode()       {}
 func (is *IfStatement) TokenLiteral() string { return is.Token.Literal }
 func (is *IfStatement) String() string {
 	var out strings.Builder
-	out.WriteString("if ")
+	out.WriteString(is.Token.Literal + " ")
 	out.WriteString(is.Condition.String())
 	out.WriteString(":\n")
 	out.WriteString(is.Consequence.String())
-	if is.Alternative != nil {
+	if elif := is.Elif(); elif != nil {
+		out.WriteString(elif.String())
+	} else if is.Alternative != nil {
 		out.WriteString("else:\n")
 		out.WriteString(is.Alternative.String())
 	}
 	return out.String()
 }
 
+// Elif returns the if statement of an elif branch, or nil if the statement
+// has no elif.
+func (is *IfStatement) Elif() *IfStatement {
+	if is.Alternative == nil || len(is.Alternative.Statements) != 1 {
+		return nil
+	}
+	if elif, ok := is.Alternative.Statements[0].(*IfStatement); ok && elif.Token.Literal == "elif" {
+		return elif
+	}
+	return nil
+}
+
 // WhileStatement represents a while loop.
 type WhileStatement struct {
 	Token     lexer.Token
@@ -401,10 +635,11 @@ func (ws *WhileStatement) String() string {
 
 // ForStatement represents a for loop.
 type ForStatement struct {
-	Token    lexer.Token
-	Variable *Identifier
-	Iterable Expression
-	Body     *BlockStatement
+	Token     lexer.Token
+	Variable  *Identifier
+	Variables []*Identifier // The names of for i, item in enumerate(items), which has no Variable
+	Iterable  Expression
+	Body      *BlockStatement
 }
 
 func (fs *ForStatement) statementNode()       {}
@@ -412,7 +647,15 @@ func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
 func (fs *ForStatement) String() string {
 	var out strings.Builder
 	out.WriteString("for ")
-	out.WriteString(fs.Variable.String())
+	if fs.Variable != nil {
+		out.WriteString(fs.Variable.String())
+	}
+	for i, v := range fs.Variables {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(v.String())
+	}
 	out.WriteString(" in ")
 	out.WriteString(fs.Iterable.String())
 	out.WriteString(":\n")
@@ -420,11 +663,211 @@ func (fs *ForStatement) String() string {
 	return out.String()
 }
 
+// TryStatement represents a try statement: its body, the except clauses
+// that handle exceptions raised in it, and the optional else block, run
+// when the body raises nothing, and finally block, run in any case.
+type TryStatement struct {
+	Token    lexer.Token
+	Body     *BlockStatement
+	Handlers []*ExceptClause
+	Else     *BlockStatement
+	Finally  *BlockStatement
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) String() string {
+	var out strings.Builder
+	out.WriteString("try:\n")
+	out.WriteString(ts.Body.String())
+	for _, handler := range ts.Handlers {
+		out.WriteString(handler.String())
+	}
+	if ts.Else != nil {
+		out.WriteString("else:\n")
+		out.WriteString(ts.Else.String())
+	}
+	if ts.Finally != nil {
+		out.WriteString("finally:\n")
+		out.WriteString(ts.Finally.String())
+	}
+	return out.String()
+}
+
+// Blocks returns the blocks of a try statement in order: its body, the
+// bodies of its except clauses, and its else and finally blocks if it has
+// them.
+func (ts *TryStatement) Blocks() []*BlockStatement {
+	blocks := []*BlockStatement{ts.Body}
+	for _, handler := range ts.Handlers {
+		blocks = append(blocks, handler.Body)
+	}
+	for _, block := range []*BlockStatement{ts.Else, ts.Finally} {
+		if block != nil {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// WithStatement represents a with statement, which enters its context
+// managers before running its body and exits them, in reverse order, when
+// the body is done however it ends.
+type WithStatement struct {
+	Token lexer.Token
+	Items []*WithItem
+	Body  *BlockStatement
+}
+
+func (ws *WithStatement) statementNode()       {}
+func (ws *WithStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WithStatement) String() string {
+	var out strings.Builder
+	items := []string{}
+	for _, item := range ws.Items {
+		items = append(items, item.String())
+	}
+	out.WriteString("with " + strings.Join(items, ", ") + ":\n")
+	out.WriteString(ws.Body.String())
+	return out.String()
+}
+
+// WithItem is a context manager of a with statement. Name, if set, is bound
+// to the value entering it gives.
+type WithItem struct {
+	Context Expression
+	Name    *Identifier
+}
+
+func (wi *WithItem) String() string {
+	if wi.Name != nil {
+		return wi.Context.String() + " as " + wi.Name.String()
+	}
+	return wi.Context.String()
+}
+
+// MatchStatement represents a match statement, which runs the body of the
+// first case with a pattern matching its subject.
+type MatchStatement struct {
+	Token   lexer.Token
+	Subject Expression
+	Cases   []*MatchCase
+}
+
+func (ms *MatchStatement) statementNode()       {}
+func (ms *MatchStatement) TokenLiteral() string { return ms.Token.Literal }
+func (ms *MatchStatement) String() string {
+	var out strings.Builder
+	out.WriteString("match " + ms.Subject.String() + ":\n")
+	for _, mc := range ms.Cases {
+		out.WriteString(mc.String())
+	}
+	return out.String()
+}
+
+// Blocks returns the bodies of the cases of a match statement.
+func (ms *MatchStatement) Blocks() []*BlockStatement {
+	blocks := []*BlockStatement{}
+	for _, mc := range ms.Cases {
+		blocks = append(blocks, mc.Body)
+	}
+	return blocks
+}
+
+// MatchCase is a case of a match statement. Its patterns are alternatives,
+// written with | between them. Name, if set, is bound to the subject when
+// the case matches, and Guard is the condition after if.
+type MatchCase struct {
+	Token    lexer.Token
+	Patterns []*CasePattern
+	Name     *Identifier
+	Guard    Expression
+	Body     *BlockStatement
+}
+
+func (mc *MatchCase) TokenLiteral() string { return mc.Token.Literal }
+func (mc *MatchCase) String() string {
+	var out strings.Builder
+	patterns := []string{}
+	for _, pattern := range mc.Patterns {
+		patterns = append(patterns, pattern.String())
+	}
+	out.WriteString("case " + strings.Join(patterns, " | "))
+	if mc.Name != nil {
+		out.WriteString(" as " + mc.Name.String())
+	}
+	if mc.Guard != nil {
+		out.WriteString(" if " + mc.Guard.String())
+	}
+	out.WriteString(":\n")
+	out.WriteString(mc.Body.String())
+	return out.String()
+}
+
+// CasePattern is a pattern of a case: a value compared with the subject, a
+// class pattern such as int() or Point(p), which matches the subject's type,
+// or a name. The name _ matches anything; any other name matches anything
+// and is bound to the subject, as the name in a class pattern is.
+type CasePattern struct {
+	Token   lexer.Token
+	Value   Expression
+	Type    *Identifier
+	Capture *Identifier
+}
+
+func (cp *CasePattern) String() string {
+	switch {
+	case cp.Value != nil:
+		return cp.Value.String()
+	case cp.Type != nil && cp.Capture != nil:
+		return cp.Type.String() + "(" + cp.Capture.String() + ")"
+	case cp.Type != nil:
+		return cp.Type.String() + "()"
+	case cp.Capture != nil:
+		return cp.Capture.String()
+	}
+	return "_"
+}
+
+// ExceptClause is an except clause of a try statement. A bare except has no
+// types and handles every exception. Name, if set, is bound to the
+// exception being handled.
+type ExceptClause struct {
+	Token lexer.Token
+	Types []*Identifier
+	Name  *Identifier
+	Body  *BlockStatement
+}
+
+func (ec *ExceptClause) TokenLiteral() string { return ec.Token.Literal }
+func (ec *ExceptClause) String() string {
+	var out strings.Builder
+	out.WriteString("except")
+	switch len(ec.Types) {
+	case 0:
+	case 1:
+		out.WriteString(" " + ec.Types[0].String())
+	default:
+		names := []string{}
+		for _, t := range ec.Types {
+			names = append(names, t.String())
+		}
+		out.WriteString(" (" + strings.Join(names, ", ") + ")")
+	}
+	if ec.Name != nil {
+		out.WriteString(" as " + ec.Name.String())
+	}
+	out.WriteString(":\n")
+	out.WriteString(ec.Body.String())
+	return out.String()
+}
+
 // AssignmentStatement represents a variable assignment.
 type AssignmentStatement struct {
-	Token lexer.Token
-	Left  []Expression
-	Value Expression
+	Token    lexer.Token
+	Left     []Expression
+	Operator string // the operator of an augmented assignment, "+" for +=, or "" for =
+	Value    Expression
 }
 
 func (as *AssignmentStatement) statementNode()       {}
@@ -437,18 +880,54 @@ func (as *AssignmentStatement) String() string {
 		}
 		out.WriteString(name.String())
 	}
-	out.WriteString(" = ")
+	out.WriteString(" " + as.Operator + "= ")
 	if as.Value != nil {
 		out.WriteString(as.Value.String())
 	}
 	return out.String()
 }
 
-// ImportStatement represents an import statement.
-type ImportStatement struct {
-	Token          lexer.Token
+// ImportSpec is a single module named by an import statement. A quoted name
+// ("net/http") is a Go package path; a bare name (json) is a Simple module.
+// Alias is the name given with `as`, or one assigned by ResolveImportNames.
+type ImportSpec struct {
 	ImportedModule *StringLiteral
 	IsSimpleImport bool
+	Alias          string
+}
+
+// Name returns the name the module is bound to in the importing program.
+func (spec *ImportSpec) Name() string {
+	if spec.Alias != "" {
+		return spec.Alias
+	}
+	if spec.IsSimpleImport {
+		return spec.ImportedModule.Value
+	}
+	// Go package names are the last path element, ignoring a major version suffix
+	parts := strings.Split(strings.Trim(spec.ImportedModule.Value, "\""), "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = parts[len(parts)-2]
+	}
+	return name
+}
+
+func (spec *ImportSpec) String() string {
+	name := spec.ImportedModule.String()
+	if spec.IsSimpleImport {
+		name = spec.ImportedModule.Value
+	}
+	if spec.Alias != "" {
+		return name + " as " + spec.Alias
+	}
+	return name
+}
+
+// ImportStatement represents an import statement naming one or more modules.
+type ImportStatement struct {
+	Token   lexer.Token
+	Imports []*ImportSpec
 }
 
 func (is *ImportStatement) statementNode()       {}
@@ -456,11 +935,116 @@ func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
 func (is *ImportStatement) String() string {
 	var out strings.Builder
 	out.WriteString("import ")
-	out.WriteString(is.ImportedModule.String())
+	for i, spec := range is.Imports {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(spec.String())
+	}
 	return out.String()
 }
 
-// DeferStatement represents an import statement.
+// BreakStatement represents a break statement.
+type BreakStatement struct {
+	Token lexer.Token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) String() string       { return "break" }
+
+// ContinueStatement represents a continue statement.
+type ContinueStatement struct {
+	Token lexer.Token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) String() string       { return "continue" }
+
+// DelStatement represents a del statement, which deletes the variables and
+// dict items it names, separated by commas.
+type DelStatement struct {
+	Token   lexer.Token
+	Targets []Expression
+}
+
+func (ds *DelStatement) statementNode()       {}
+func (ds *DelStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DelStatement) String() string {
+	targets := []string{}
+	for _, target := range ds.Targets {
+		targets = append(targets, target.String())
+	}
+	return "del " + strings.Join(targets, ", ")
+}
+
+// PassStatement represents a pass statement, which does nothing, for blocks
+// that must have a statement but need none, such as the body of a stub.
+type PassStatement struct {
+	Token lexer.Token
+}
+
+func (ps *PassStatement) statementNode()       {}
+func (ps *PassStatement) TokenLiteral() string { return ps.Token.Literal }
+func (ps *PassStatement) String() string       { return "pass" }
+
+// RaiseStatement represents a raise statement. A bare raise, which raises
+// the exception being handled again, has no exception.
+type RaiseStatement struct {
+	Token     lexer.Token
+	Exception Expression
+}
+
+func (rs *RaiseStatement) statementNode()       {}
+func (rs *RaiseStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *RaiseStatement) String() string {
+	if rs.Exception == nil {
+		return "raise"
+	}
+	return "raise " + rs.Exception.String()
+}
+
+// AssertStatement represents an assert statement, which raises an
+// AssertionError, with its message if it has one, when its condition is
+// false. An assert without a condition has none, which the analyzer reports.
+type AssertStatement struct {
+	Token     lexer.Token
+	Condition Expression
+	Message   Expression
+}
+
+func (as *AssertStatement) statementNode()       {}
+func (as *AssertStatement) TokenLiteral() string { return as.Token.Literal }
+func (as *AssertStatement) String() string {
+	if as.Condition == nil {
+		return "assert"
+	}
+	if as.Message == nil {
+		return "assert " + as.Condition.String()
+	}
+	return "assert " + as.Condition.String() + ", " + as.Message.String()
+}
+
+// GlobalStatement represents a global or nonlocal statement, after which
+// assigning one of its names in a function rebinds the variable of the
+// module, or of an enclosing function, rather than making a local one.
+type GlobalStatement struct {
+	Token lexer.Token // The global or nonlocal token
+	Names []*Identifier
+}
+
+func (gs *GlobalStatement) statementNode()       {}
+func (gs *GlobalStatement) TokenLiteral() string { return gs.Token.Literal }
+func (gs *GlobalStatement) String() string {
+	names := []string{}
+	for _, name := range gs.Names {
+		names = append(names, name.Value)
+	}
+	return gs.Token.Literal + " " + strings.Join(names, ", ")
+}
+
+// DeferStatement represents a defer statement.
 type DeferStatement struct {
 	Token      lexer.Token
 	Expression Expression
@@ -475,7 +1059,7 @@ func (ds *DeferStatement) String() string {
 	return out.String()
 }
 
-// GoStatement represents an import statement.
+// GoStatement represents a go statement.
 type GoStatement struct {
 	Token      lexer.Token
 	Expression Expression
@@ -490,26 +1074,6 @@ func (gs *GoStatement) String() string {
 	return out.String()
 }
 
-// DeferLiteral represents a string literal.
-type DeferLiteral struct {
-	Token lexer.Token
-	Value string
-}
-
-func (dl *DeferLiteral) expressionNode()      {}
-func (dl *DeferLiteral) TokenLiteral() string { return dl.Token.Literal }
-func (dl *DeferLiteral) String() string       { return "\"" + dl.Value + "\"" }
-
-// GoLiteral represents a string literal.
-type GoLiteral struct {
-	Token lexer.Token
-	Value string
-}
-
-func (gl *GoLiteral) expressionNode()      {}
-func (gl *GoLiteral) TokenLiteral() string { return gl.Token.Literal }
-func (gl *GoLiteral) String() string       { return "\"" + gl.Value + "\"" }
-
 // InfixExpression represents an infix expression.
 type InfixExpression struct {
 	Token    lexer.Token
@@ -606,12 +1170,21 @@ func (ie *IndexExpression) String() string {
 const (
 	_ int = iota
 	LOWEST
+	WALRUS  // name := value
+	TERNARY // a if c else b
 	CHAN
+	OR          // or
+	AND         // and
+	NOT         // not X
 	EQUALS      // == or !=
 	LESSGREATER // >, <, >=, <=
+	BITOR       // |
+	BITXOR      // ^
+	BITAND      // &
 	SUM         // + or -
-	PRODUCT     // *, /, %
+	PRODUCT     // *, /, //, %
 	PREFIX      // -X or !X
+	POWER       // **, which binds tighter than a - before it
 	CALL        // function calls
 	SELECTOR    = iota + 1
 )
@@ -620,19 +1193,36 @@ const (
 var precedences = map[lexer.TokenType]int{
 	lexer.TokenEQ:          EQUALS,
 	lexer.TokenNotEQ:       EQUALS,
+	lexer.TokenNot:         EQUALS, // not in
 	lexer.TokenLT:          LESSGREATER,
 	lexer.TokenLTE:         LESSGREATER,
 	lexer.TokenGT:          LESSGREATER,
 	lexer.TokenGTE:         LESSGREATER,
+	lexer.TokenPipe:        BITOR,
+	lexer.TokenCaret:       BITXOR,
+	lexer.TokenAmpersand:   BITAND,
 	lexer.TokenPlus:        SUM,
 	lexer.TokenMinus:       SUM,
 	lexer.TokenAsterisk:    PRODUCT,
 	lexer.TokenSlash:       PRODUCT,
+	lexer.TokenFloorDiv:    PRODUCT,
+	lexer.TokenPower:       POWER,
 	lexer.TokenModulo:      PRODUCT,
 	lexer.TokenParenOpen:   CALL, // For function calls
 	lexer.TokenDot:         SELECTOR,
 	lexer.TokenBracketOpen: CALL,
 	lexer.TokenChan:        CHAN,
+	lexer.TokenOr:          OR,
+	lexer.TokenAnd:         AND,
+	lexer.TokenWalrus:      WALRUS,
+}
+
+// keywordPrecedences maps the keywords that continue an expression to their
+// precedence.
+var keywordPrecedences = map[string]int{
+	"if": TERNARY,
+	"in": EQUALS,
+	"is": EQUALS,
 }
 
 // Parser represents a parser.
@@ -645,6 +1235,8 @@ type Parser struct {
 
 	prefixParseFns map[lexer.TokenType]prefixParseFn
 	infixParseFns  map[lexer.TokenType]infixParseFn
+
+	operands int // operands of chained comparisons bound to lambdas
 }
 
 type (
@@ -665,33 +1257,45 @@ func NewParser(l *lexer.Lexer) *Parser {
 	p.registerPrefix(lexer.TokenIdentifier, p.parseIdentifier)
 	p.registerPrefix(lexer.TokenNumber, p.parseIntegerLiteral)
 	p.registerPrefix(lexer.TokenString, p.parseStringLiteral)
+	p.registerPrefix(lexer.TokenFString, p.parseFStringLiteral)
 	p.registerPrefix(lexer.TokenBang, p.parsePrefixExpression)
 	p.registerPrefix(lexer.TokenMinus, p.parsePrefixExpression)
 	p.registerPrefix(lexer.TokenChan, p.parsePrefixExpression)
+	p.registerPrefix(lexer.TokenNot, p.parsePrefixExpression)
 	p.registerPrefix(lexer.TokenParenOpen, p.parseGroupedExpression)
 	p.registerPrefix(lexer.TokenTrue, p.parseBooleanLiteral)
 	p.registerPrefix(lexer.TokenFalse, p.parseBooleanLiteral)
+	p.registerPrefix(lexer.TokenNone, p.parseNoneLiteral)
 	p.registerPrefix(lexer.TokenBracketOpen, p.parseArrayLiteral)
 	p.registerPrefix(lexer.TokenBraceOpen, p.parseMapLiteral)
-	p.registerPrefix(lexer.TokenDefer, p.parseDeferLiteral)
-	p.registerPrefix(lexer.TokenGo, p.parseGoLiteral)
+	p.registerPrefix(lexer.TokenLambda, p.parseLambdaExpression)
 
 	// Register infix parsers.
 	p.registerInfix(lexer.TokenPlus, p.parseInfixExpression)
 	p.registerInfix(lexer.TokenMinus, p.parseInfixExpression)
 	p.registerInfix(lexer.TokenAsterisk, p.parseInfixExpression)
 	p.registerInfix(lexer.TokenSlash, p.parseInfixExpression)
+	p.registerInfix(lexer.TokenFloorDiv, p.parseInfixExpression)
+	p.registerInfix(lexer.TokenPower, p.parseInfixExpression)
 	p.registerInfix(lexer.TokenModulo, p.parseInfixExpression)
-	p.registerInfix(lexer.TokenEQ, p.parseInfixExpression)
-	p.registerInfix(lexer.TokenNotEQ, p.parseInfixExpression)
-	p.registerInfix(lexer.TokenLT, p.parseInfixExpression)
+	p.registerInfix(lexer.TokenPipe, p.parseInfixExpression)
+	p.registerInfix(lexer.TokenCaret, p.parseInfixExpression)
+	p.registerInfix(lexer.TokenAmpersand, p.parseInfixExpression)
+	p.registerInfix(lexer.TokenEQ, p.parseComparison)
+	p.registerInfix(lexer.TokenNotEQ, p.parseComparison)
+	p.registerInfix(lexer.TokenNot, p.parseComparison)
+	p.registerInfix(lexer.TokenLT, p.parseComparison)
 	p.registerInfix(lexer.TokenChan, p.parseInfixExpression)
-	p.registerInfix(lexer.TokenLTE, p.parseInfixExpression)
-	p.registerInfix(lexer.TokenGT, p.parseInfixExpression)
-	p.registerInfix(lexer.TokenGTE, p.parseInfixExpression)
+	p.registerInfix(lexer.TokenLTE, p.parseComparison)
+	p.registerInfix(lexer.TokenGT, p.parseComparison)
+	p.registerInfix(lexer.TokenGTE, p.parseComparison)
+	p.registerInfix(lexer.TokenAnd, p.parseInfixExpression)
+	p.registerInfix(lexer.TokenOr, p.parseInfixExpression)
 	p.registerInfix(lexer.TokenParenOpen, p.parseCallExpression)
 	p.registerInfix(lexer.TokenDot, p.parseSelectorExpression)
 	p.registerInfix(lexer.TokenBracketOpen, p.parseIndexExpression)
+	p.registerInfix(lexer.TokenKeyword, p.parseKeywordExpression)
+	p.registerInfix(lexer.TokenWalrus, p.parseAssignmentExpression)
 
 	// Read two tokens to initialize curToken and peekToken.
 	p.nextToken()
@@ -728,7 +1332,16 @@ func (p *Parser) parseArrayLiteral() Expression {
 		Token: p.curToken,
 	}
 
-	array.Elements = p.parseExpressionList(lexer.TokenBracketClose)
+	if p.peekToken.Type == lexer.TokenBracketClose {
+		p.nextToken()
+	} else {
+		p.nextToken()
+		first := p.parseExpression(LOWEST)
+		if p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "for" {
+			return p.parseListComprehension(array.Token, first)
+		}
+		array.Elements = p.parseExpressionListAfter(first, lexer.TokenBracketClose)
+	}
 
 	if len(array.Elements) == 0 {
 		anyType := &BasicType{Name: "any"}
@@ -779,6 +1392,10 @@ func (p *Parser) parseMapLiteral() Expression {
 			return nil
 		}
 
+		if len(m.Pairs) == 0 && (p.peekToken.Type == lexer.TokenComma || p.peekToken.Type == lexer.TokenBraceClose) {
+			return p.parseSetLiteral(m.Token, key)
+		}
+
 		keyType := p.inferExpressionType(key)
 		keyTypes = append(keyTypes, keyType)
 
@@ -792,15 +1409,25 @@ func (p *Parser) parseMapLiteral() Expression {
 			return nil
 		}
 
+		if len(m.Pairs) == 0 && p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "for" {
+			return p.parseDictComprehension(m.Token, key, value)
+		}
+
 		valueType := p.inferExpressionType(value)
 		valueTypes = append(valueTypes, valueType)
 
 		m.Pairs[key] = value
+		m.Keys = append(m.Keys, key)
 
 		if p.peekToken.Type != lexer.TokenComma {
 			break
 		}
 		p.nextToken()
+
+		// Allow a trailing comma before the closing brace
+		if p.peekToken.Type == lexer.TokenBraceClose {
+			break
+		}
 	}
 
 	if !p.expectPeek(lexer.TokenBraceClose) {
@@ -818,6 +1445,21 @@ func (p *Parser) parseMapLiteral() Expression {
 	return m
 }
 
+// parseSetLiteral parses the rest of a set literal after its first element.
+func (p *Parser) parseSetLiteral(token lexer.Token, first Expression) Expression {
+	set := &SetLiteral{Token: token}
+	set.Elements = p.parseExpressionListAfter(first, lexer.TokenBraceClose)
+	if set.Elements == nil {
+		return nil
+	}
+	elTypes := []Type{}
+	for _, el := range set.Elements {
+		elTypes = append(elTypes, p.inferExpressionType(el))
+	}
+	set.ElementType = p.inferCommonType(elTypes)
+	return set
+}
+
 func (p *Parser) inferExpressionType(expr Expression) Type {
 	switch e := expr.(type) {
 	case *IntegerLiteral:
@@ -827,7 +1469,7 @@ func (p *Parser) inferExpressionType(expr Expression) Type {
 		case float64:
 			return &BasicType{Name: "float"}
 		}
-	case *StringLiteral:
+	case *StringLiteral, *FStringLiteral:
 		return &BasicType{Name: "string"}
 	case *BooleanLiteral:
 		return &BasicType{Name: "bool"}
@@ -843,6 +1485,8 @@ func (p *Parser) inferExpressionType(expr Expression) Type {
 	case *MapLiteral:
 		// Already inferred during parsing
 		return e.Type
+	case *SetLiteral:
+		return &SetType{ElementType: e.ElementType}
 	case *Identifier:
 		// Type inference for identifiers may require symbol table lookup
 		return &BasicType{Name: "any"}
@@ -915,6 +1559,11 @@ func (p *Parser) noPrefixParseFnError(t lexer.TokenType) {
 
 // peekPrecedence returns the precedence of the peek token.
 func (p *Parser) peekPrecedence() int {
+	if p.peekToken.Type == lexer.TokenKeyword {
+		if p, ok := keywordPrecedences[p.peekToken.Literal]; ok {
+			return p
+		}
+	}
 	if p, ok := precedences[p.peekToken.Type]; ok {
 		return p
 	}
@@ -941,13 +1590,18 @@ func (p *Parser) ParseProgram() *Program {
 		}
 		p.nextToken()
 	}
+	program.Doc, program.Statements = docstring(program.Statements)
 
 	return program
 }
 
 // parseStatement parses a single statement.
 func (p *Parser) parseStatement() Statement {
-	p.skipNewlines()
+	// Skip blank lines, but not the newline that ends a one word statement
+	// such as break
+	if p.curToken.Type == lexer.TokenNewline {
+		p.skipNewlines()
+	}
 
 	// Check if we've reached EOF
 	if p.curToken.Type == lexer.TokenEOF {
@@ -959,29 +1613,71 @@ func (p *Parser) parseStatement() Statement {
 		switch p.curToken.Literal {
 		case "def":
 			return p.parseFunctionDefinition()
+		case "class":
+			return p.parseClassStatement()
 		case "return":
 			return p.parseReturnStatement()
+		case "yield":
+			return p.parseYieldStatement()
 		case "if":
 			return p.parseIfStatement()
 		case "while":
 			return p.parseWhileStatement()
 		case "for":
 			return p.parseForStatement()
+		case "try":
+			return p.parseTryStatement()
+		case "with":
+			return p.parseWithStatement()
+		case "raise":
+			return p.parseRaiseStatement()
+		case "assert":
+			return p.parseAssertStatement()
+		case "break":
+			return p.parseBreakStatement()
+		case "continue":
+			return p.parseContinueStatement()
+		case "pass":
+			return p.parsePassStatement()
+		case "del":
+			return p.parseDelStatement()
 		case "import":
 			return p.parseImportStatement()
+		case "global", "nonlocal":
+			return p.parseGlobalStatement()
 		default:
 			return nil
 		}
 	case lexer.TokenIdentifier:
+		// match is a soft keyword, starting a statement only on a line
+		// that opens a block
+		if p.curToken.Literal == "match" && p.opensBlock() {
+			return p.parseMatchStatement()
+		}
+		// Look for an assignment, ignoring keyword arguments inside
+		// brackets, the first of which may be the peeked token
 		x := 1
-		for p.l.PeekAhead(x).Type != lexer.TokenAssign && p.l.PeekAhead(x).Type != lexer.TokenNewline {
+		depth := 0
+		switch p.peekToken.Type {
+		case lexer.TokenParenOpen, lexer.TokenBracketOpen, lexer.TokenBraceOpen:
+			depth = 1
+		}
+		for tt := p.l.PeekAhead(x).Type; (!lexer.IsAssignment(tt) || depth > 0) && tt != lexer.TokenNewline && tt != lexer.TokenEOF; tt = p.l.PeekAhead(x).Type {
+			switch tt {
+			case lexer.TokenParenOpen, lexer.TokenBracketOpen, lexer.TokenBraceOpen:
+				depth++
+			case lexer.TokenParenClose, lexer.TokenBracketClose, lexer.TokenBraceClose:
+				depth--
+			}
 			x++
 		}
-		if p.l.PeekAhead(x).Type == lexer.TokenAssign || p.peekToken.Type == lexer.TokenComma || p.peekToken.Type == lexer.TokenAssign {
+		if lexer.IsAssignment(p.l.PeekAhead(x).Type) || p.peekToken.Type == lexer.TokenComma || lexer.IsAssignment(p.peekToken.Type) {
 			return p.parseAssignmentStatement()
 		} else {
 			return p.parseExpressionStatement()
 		}
+	case lexer.TokenAt:
+		return p.parseDecoratedDefinition()
 	case lexer.TokenDefer:
 		return p.parseDeferStatement()
 	case lexer.TokenGo:
@@ -1016,11 +1712,16 @@ func (p *Parser) parseAssignmentStatement() *AssignmentStatement {
 
 	// Parse the identifiers on the left-hand side
 	stmt.Left = p.parseAssignmentLeftHandSide()
+	if lexer.IsAssignment(p.curToken.Type) && p.curToken.Type != lexer.TokenAssign {
+		stmt.Operator = strings.TrimSuffix(p.curToken.Literal, "=")
+	}
 
 	p.nextToken() // Move to the start of the right-hand side expression
 
-	// Parse the expression on the right-hand side
-	stmt.Value = p.parseExpression(LOWEST)
+	// Parse the expression on the right-hand side, where several values
+	// separated by commas make a tuple
+	token := p.curToken
+	stmt.Value = p.parseBareTuple(token, p.parseExpression(LOWEST))
 
 	// Optional: handle end of statement (e.g., newlines, semicolons)
 	if p.peekToken.Type == lexer.TokenNewline || p.peekToken.Type == lexer.TokenSemicolon {
@@ -1040,7 +1741,7 @@ func (p *Parser) parseAssignmentLeftHandSide() []Expression {
 		}
 		expressions = append(expressions, expr)
 
-		if p.peekToken.Type == lexer.TokenAssign {
+		if lexer.IsAssignment(p.peekToken.Type) {
 			break
 		}
 
@@ -1076,48 +1777,190 @@ func (p *Parser) parseFunctionDefinition() Statement {
 		return nil
 	}
 
-	fl.Parameters = p.parseFunctionParameters()
+	fl.Parameters, fl.Annotations = p.parseFunctionParameters()
+	if fl.Parameters == nil {
+		return nil
+	}
+
+	if p.peekToken.Type == lexer.TokenArrow {
+		p.nextToken()
+		p.nextToken()
+		if fl.Result = p.parseTypeAnnotation(); fl.Result == nil {
+			return nil
+		}
+	}
 
 	if !p.expectPeek(lexer.TokenColon) {
 		return nil
 	}
 
 	fl.Body = p.parseBlockStatement()
+	if fl.Body != nil {
+		fl.Doc, fl.Body.Statements = docstring(fl.Body.Statements)
+	}
 
 	return fl
 }
 
-// parseFunctionParameters parses function parameters.
-func (p *Parser) parseFunctionParameters() []*Identifier {
-	identifiers := []*Identifier{}
+// docstring returns the string statements start with, the docstring of the
+// function or module they are the body of, and the statements after it. A
+// docstring documents and isn't run, so it is kept out of the statements.
+func docstring(statements []Statement) (string, []Statement) {
+	if len(statements) > 0 {
+		if es, ok := statements[0].(*ExpressionStatement); ok && es != nil {
+			if sl, ok := es.Expression.(*StringLiteral); ok {
+				return sl.Value, statements[1:]
+			}
+		}
+	}
+	return "", statements
+}
 
-	if p.peekToken.Type == lexer.TokenParenClose {
+// parseDecoratedDefinition parses the @decorator lines above a function
+// definition and the definition.
+func (p *Parser) parseDecoratedDefinition() Statement {
+	decorators := []Expression{}
+	for p.curToken.Type == lexer.TokenAt {
+		p.nextToken()
+		decorator := p.parseExpression(LOWEST)
+		if decorator == nil || !p.expectPeek(lexer.TokenNewline) {
+			return nil
+		}
+		decorators = append(decorators, decorator)
+		p.skipNewlines()
 		p.nextToken()
-		return identifiers
 	}
 
-	p.nextToken()
-	ident := &Identifier{
+	if p.curToken.Type != lexer.TokenKeyword || p.curToken.Literal != "def" {
+		msg := fmt.Sprintf("expected def after decorators, got %s instead (Line %d, Column %d)", p.curToken.Literal, p.curToken.Line, p.curToken.Column)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	fl, ok := p.parseFunctionDefinition().(*FunctionLiteral)
+	if !ok || fl == nil {
+		return nil
+	}
+	fl.Decorators = decorators
+	return fl
+}
+
+// parseClassStatement parses a class definition. The parentheses after the
+// name, which list the classes it inherits from, may be left out.
+func (p *Parser) parseClassStatement() Statement {
+	cs := &ClassStatement{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(lexer.TokenIdentifier) {
+		return nil
+	}
+
+	cs.Name = &Identifier{
 		Token: p.curToken,
 		Value: p.curToken.Literal,
 	}
-	identifiers = append(identifiers, ident)
 
-	for p.peekToken.Type == lexer.TokenComma {
+	if p.peekToken.Type == lexer.TokenParenOpen {
+		p.nextToken()
+		for p.peekToken.Type == lexer.TokenIdentifier {
+			p.nextToken()
+			cs.Bases = append(cs.Bases, &Identifier{Token: p.curToken, Value: p.curToken.Literal})
+			if p.peekToken.Type != lexer.TokenComma {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(lexer.TokenParenClose) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(lexer.TokenColon) {
+		return nil
+	}
+
+	cs.Body = p.parseBlockStatement()
+
+	return cs
+}
+
+// parseFunctionParameters parses function parameters and their
+// annotations, as in (a: int, b), where b has none.
+func (p *Parser) parseFunctionParameters() ([]*Identifier, []*TypeAnnotation) {
+	identifiers := []*Identifier{}
+	annotations := []*TypeAnnotation{}
+
+	if p.peekToken.Type == lexer.TokenParenClose {
 		p.nextToken()
+		return identifiers, annotations
+	}
+
+	for {
 		p.nextToken()
 		ident := &Identifier{
 			Token: p.curToken,
 			Value: p.curToken.Literal,
 		}
+		var annotation *TypeAnnotation
+		if p.peekToken.Type == lexer.TokenColon {
+			p.nextToken()
+			p.nextToken()
+			if annotation = p.parseTypeAnnotation(); annotation == nil {
+				return nil, nil
+			}
+		}
 		identifiers = append(identifiers, ident)
+		annotations = append(annotations, annotation)
+
+		if p.peekToken.Type != lexer.TokenComma {
+			break
+		}
+		p.nextToken()
+		if p.peekToken.Type == lexer.TokenParenClose {
+			break
+		}
 	}
 
 	if !p.expectPeek(lexer.TokenParenClose) {
-		return nil
+		return nil, nil
 	}
 
-	return identifiers
+	return identifiers, annotations
+}
+
+// parseTypeAnnotation parses the type of an annotation, a name that may be
+// followed by types in brackets. The name may be quoted, as a class is
+// inside its own definition.
+func (p *Parser) parseTypeAnnotation() *TypeAnnotation {
+	if p.curToken.Type == lexer.TokenString {
+		return &TypeAnnotation{Token: p.curToken, Name: p.curToken.Literal}
+	}
+	if p.curToken.Type != lexer.TokenIdentifier && p.curToken.Type != lexer.TokenNone {
+		msg := fmt.Sprintf("expected a type, got %s instead (Line %d, Column %d)", p.curToken.Literal, p.curToken.Line, p.curToken.Column)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	ta := &TypeAnnotation{Token: p.curToken, Name: p.curToken.Literal}
+	if p.peekToken.Type != lexer.TokenBracketOpen {
+		return ta
+	}
+	p.nextToken()
+	for {
+		p.nextToken()
+		arg := p.parseTypeAnnotation()
+		if arg == nil {
+			return nil
+		}
+		ta.Arguments = append(ta.Arguments, arg)
+		if p.peekToken.Type != lexer.TokenComma {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(lexer.TokenBracketClose) {
+		return nil
+	}
+	return ta
 }
 
 // parseBlockStatement parses a block of statements.
@@ -1127,6 +1970,21 @@ func (p *Parser) parseBlockStatement() *BlockStatement {
 		Statements: []Statement{},
 	}
 
+	// A block can be one statement on the line of its colon, as in
+	// def stub(): pass
+	if p.peekToken.Type != lexer.TokenNewline && p.peekToken.Type != lexer.TokenEOF {
+		p.nextToken()
+		stmt := p.parseStatement()
+		if stmt == nil {
+			return nil
+		}
+		block.Statements = append(block.Statements, stmt)
+		if p.peekToken.Type == lexer.TokenNewline {
+			p.nextToken()
+		}
+		return block
+	}
+
 	if !p.expectPeek(lexer.TokenNewline) {
 		return nil
 	}
@@ -1171,7 +2029,8 @@ func (p *Parser) parseReturnStatement() *ReturnStatement {
 
 	p.nextToken()
 
-	rs.ReturnValue = p.parseExpression(LOWEST)
+	token := p.curToken
+	rs.ReturnValue = p.parseBareTuple(token, p.parseExpression(LOWEST))
 
 	if p.peekToken.Type == lexer.TokenNewline {
 		p.nextToken()
@@ -1180,6 +2039,44 @@ func (p *Parser) parseReturnStatement() *ReturnStatement {
 	return rs
 }
 
+// parseYieldStatement parses a yield statement. A bare yield is kept with no
+// value for the analyzer to report.
+func (p *Parser) parseYieldStatement() *YieldStatement {
+	ys := &YieldStatement{
+		Token: p.curToken,
+	}
+
+	if p.peekToken.Type != lexer.TokenNewline && p.peekToken.Type != lexer.TokenEOF {
+		p.nextToken()
+		token := p.curToken
+		ys.Value = p.parseBareTuple(token, p.parseExpression(LOWEST))
+	}
+	if p.peekToken.Type == lexer.TokenNewline {
+		p.nextToken()
+	}
+
+	return ys
+}
+
+// parseBareTuple parses the rest of a tuple written without parentheses,
+// as in x, y = 1, 2 or return a, b, given its first value. A value with no
+// comma after it is returned as it is.
+func (p *Parser) parseBareTuple(token lexer.Token, first Expression) Expression {
+	if p.peekToken.Type != lexer.TokenComma {
+		return first
+	}
+	elements := []Expression{first}
+	for p.peekToken.Type == lexer.TokenComma {
+		p.nextToken()
+		if p.peekToken.Type == lexer.TokenNewline || p.peekToken.Type == lexer.TokenEOF {
+			break
+		}
+		p.nextToken()
+		elements = append(elements, p.parseExpression(LOWEST))
+	}
+	return &TupleLiteral{Token: token, Elements: elements}
+}
+
 // parseIfStatement parses an if statement.
 func (p *Parser) parseIfStatement() *IfStatement {
 	is := &IfStatement{
@@ -1195,7 +2092,16 @@ func (p *Parser) parseIfStatement() *IfStatement {
 
 	is.Consequence = p.parseBlockStatement()
 
-	if p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "else" {
+	if p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "elif" {
+		p.nextToken() // Move to 'elif'
+
+		// elif is an if statement that is the only statement of the else block
+		elif := p.parseIfStatement()
+		if elif == nil {
+			return nil
+		}
+		is.Alternative = &BlockStatement{Token: elif.Token, Statements: []Statement{elif}}
+	} else if p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "else" {
 		p.nextToken() // Move to 'else'
 
 		if !p.expectPeek(lexer.TokenColon) {
@@ -1226,110 +2132,587 @@ func (p *Parser) parseWhileStatement() *WhileStatement {
 	return ws
 }
 
-// parseForStatement parses a for loop.
-func (p *Parser) parseForStatement() *ForStatement {
-	fs := &ForStatement{
+// parseTryStatement parses a try statement with its except clauses and
+// else and finally blocks, which must come in that order. A try statement
+// with neither except clauses nor a finally block is reported by the
+// analyzer.
+func (p *Parser) parseTryStatement() *TryStatement {
+	ts := &TryStatement{
 		Token: p.curToken,
 	}
 
-	if !p.expectPeek(lexer.TokenIdentifier) {
+	if !p.expectPeek(lexer.TokenColon) {
 		return nil
 	}
-
-	fs.Variable = &Identifier{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
-	}
-
-	if !p.expectPeek(lexer.TokenKeyword) || p.curToken.Literal != "in" {
-		msg := fmt.Sprintf("expected 'in', got %s instead (Line %d, Column %d)", p.curToken.Literal, p.curToken.Line, p.curToken.Column)
-		p.errors = append(p.errors, msg)
+	ts.Body = p.parseBlockStatement()
+	if ts.Body == nil {
 		return nil
 	}
 
-	p.nextToken()
-	fs.Iterable = p.parseExpression(LOWEST)
+	for p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "except" {
+		p.nextToken() // Move to 'except'
+		handler := p.parseExceptClause()
+		if handler == nil {
+			return nil
+		}
+		ts.Handlers = append(ts.Handlers, handler)
+	}
 
-	if !p.expectPeek(lexer.TokenColon) {
-		return nil
+	if p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "else" {
+		p.nextToken() // Move to 'else'
+		if !p.expectPeek(lexer.TokenColon) {
+			return nil
+		}
+		if ts.Else = p.parseBlockStatement(); ts.Else == nil {
+			return nil
+		}
 	}
 
-	fs.Body = p.parseBlockStatement()
+	if p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "finally" {
+		p.nextToken() // Move to 'finally'
+		if !p.expectPeek(lexer.TokenColon) {
+			return nil
+		}
+		if ts.Finally = p.parseBlockStatement(); ts.Finally == nil {
+			return nil
+		}
+	}
 
-	return fs
+	return ts
 }
 
-// parseImportStatement parses an import statement.
-func (p *Parser) parseImportStatement() *ImportStatement {
-	is := &ImportStatement{
+// parseExceptClause parses an except clause: except, except ValueError,
+// except (KeyError, IndexError) as e and so on.
+func (p *Parser) parseExceptClause() *ExceptClause {
+	ec := &ExceptClause{
 		Token: p.curToken,
 	}
-	isSimpleImport := false
-	if !p.expectPeek(lexer.TokenString) {
-		isSimpleImport = true
+
+	if p.peekToken.Type != lexer.TokenColon {
 		p.nextToken()
-	}
+		switch t := p.parseExpression(LOWEST).(type) {
+		case *Identifier:
+			ec.Types = []*Identifier{t}
+		case *TupleLiteral:
+			for _, el := range t.Elements {
+				ident, ok := el.(*Identifier)
+				if !ok {
+					msg := fmt.Sprintf("except takes an exception class or a tuple of them, got %s (Line %d, Column %d)", el.String(), ec.Token.Line, ec.Token.Column)
+					p.errors = append(p.errors, msg)
+					return nil
+				}
+				ec.Types = append(ec.Types, ident)
+			}
+		default:
+			msg := fmt.Sprintf("except takes an exception class or a tuple of them (Line %d, Column %d)", ec.Token.Line, ec.Token.Column)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
 
-	is.ImportedModule = &StringLiteral{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
+		if p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "as" {
+			p.nextToken() // Move to 'as'
+			if !p.expectPeek(lexer.TokenIdentifier) {
+				return nil
+			}
+			ec.Name = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		}
 	}
-	is.IsSimpleImport = isSimpleImport
 
-	if p.peekToken.Type == lexer.TokenNewline {
-		p.nextToken()
+	if !p.expectPeek(lexer.TokenColon) {
+		return nil
+	}
+	ec.Body = p.parseBlockStatement()
+	if ec.Body == nil {
+		return nil
 	}
 
-	return is
+	return ec
 }
 
-// parseImportStatement parses an import statement.
-func (p *Parser) parseDeferStatement() *DeferStatement {
-	ds := &DeferStatement{
+// parseWithStatement parses a with statement with one context manager or
+// several, separated by commas: with open("a") as a, open("b") as b.
+func (p *Parser) parseWithStatement() *WithStatement {
+	ws := &WithStatement{
 		Token: p.curToken,
 	}
 
-	ds.Expression = p.parseExpression(LOWEST)
-
-	if p.peekToken.Type == lexer.TokenNewline {
+	for {
+		p.nextToken()
+		item := &WithItem{Context: p.parseExpression(LOWEST)}
+		if item.Context == nil {
+			return nil
+		}
+		if p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "as" {
+			p.nextToken() // Move to 'as'
+			if !p.expectPeek(lexer.TokenIdentifier) {
+				return nil
+			}
+			item.Name = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		}
+		ws.Items = append(ws.Items, item)
+		if p.peekToken.Type != lexer.TokenComma {
+			break
+		}
 		p.nextToken()
 	}
 
-	return ds
+	if !p.expectPeek(lexer.TokenColon) {
+		return nil
+	}
+	ws.Body = p.parseBlockStatement()
+	if ws.Body == nil {
+		return nil
+	}
+
+	return ws
 }
 
-// parseImportStatement parses an import statement.
-func (p *Parser) parseGoStatement() *GoStatement {
-	gs := &GoStatement{
+// opensBlock reports whether the line from the current token ends with a
+// colon.
+func (p *Parser) opensBlock() bool {
+	last := p.peekToken.Type
+	for x := 0; last != lexer.TokenNewline && last != lexer.TokenEOF; x++ {
+		tt := p.l.PeekAhead(x).Type
+		if tt == lexer.TokenNewline || tt == lexer.TokenEOF {
+			break
+		}
+		last = tt
+	}
+	return last == lexer.TokenColon
+}
+
+// parseMatchStatement parses a match statement. Like match, case is a name
+// anywhere but at the start of the cases.
+func (p *Parser) parseMatchStatement() *MatchStatement {
+	ms := &MatchStatement{
 		Token: p.curToken,
 	}
 
-	gs.Expression = p.parseExpression(LOWEST)
+	p.nextToken()
+	if ms.Subject = p.parseExpression(LOWEST); ms.Subject == nil {
+		return nil
+	}
+	if !p.expectPeek(lexer.TokenColon) || !p.expectPeek(lexer.TokenNewline) {
+		return nil
+	}
+	p.skipNewlines()
+	if !p.expectPeek(lexer.TokenIndent) {
+		return nil
+	}
+	p.nextToken()
 
-	if p.peekToken.Type == lexer.TokenNewline {
+	for p.curToken.Type != lexer.TokenDedent && p.curToken.Type != lexer.TokenEOF {
+		if p.curToken.Type == lexer.TokenNewline {
+			p.nextToken()
+			continue
+		}
+		if p.curToken.Type != lexer.TokenIdentifier || p.curToken.Literal != "case" {
+			msg := fmt.Sprintf("expected case, got %s instead (Line %d, Column %d)", p.curToken.Literal, p.curToken.Line, p.curToken.Column)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		mc := p.parseMatchCase()
+		if mc == nil {
+			return nil
+		}
+		ms.Cases = append(ms.Cases, mc)
 		p.nextToken()
 	}
 
-	return gs
-}
-
-// parseExpressionStatement parses an expression statement.
-func (p *Parser) parseExpressionStatement() *ExpressionStatement {
-	if p.curToken.Type == lexer.TokenNewline {
+	if p.curToken.Type != lexer.TokenDedent {
+		msg := fmt.Sprintf("expected DEDENT, got %s instead (Line %d, Column %d)", p.curToken.Type, p.curToken.Line, p.curToken.Column)
+		p.errors = append(p.errors, msg)
 		return nil
 	}
+	return ms
+}
 
-	es := &ExpressionStatement{
+// parseMatchCase parses a case of a match statement: its patterns, the name
+// after as, the guard and the body.
+func (p *Parser) parseMatchCase() *MatchCase {
+	mc := &MatchCase{
 		Token: p.curToken,
 	}
 
-	es.Expression = p.parseExpression(LOWEST)
+	for {
+		p.nextToken()
+		pattern := p.parseCasePattern()
+		if pattern == nil {
+			return nil
+		}
+		mc.Patterns = append(mc.Patterns, pattern)
+		if p.peekToken.Type != lexer.TokenPipe {
+			break
+		}
+		p.nextToken() // Move to '|'
+	}
 
-	if p.peekToken.Type == lexer.TokenNewline {
+	if p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "as" {
+		p.nextToken() // Move to 'as'
+		if !p.expectPeek(lexer.TokenIdentifier) {
+			return nil
+		}
+		mc.Name = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	if p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "if" {
+		p.nextToken() // Move to 'if'
 		p.nextToken()
+		if mc.Guard = p.parseExpression(LOWEST); mc.Guard == nil {
+			return nil
+		}
 	}
 
-	return es
+	if !p.expectPeek(lexer.TokenColon) {
+		return nil
+	}
+	if mc.Body = p.parseBlockStatement(); mc.Body == nil {
+		return nil
+	}
+	return mc
+}
+
+// parseCasePattern parses a pattern of a case.
+func (p *Parser) parseCasePattern() *CasePattern {
+	pattern := &CasePattern{
+		Token: p.curToken,
+	}
+
+	// Names other than dotted ones, such as Color.RED, aren't values
+	if p.curToken.Type == lexer.TokenIdentifier && p.peekToken.Type != lexer.TokenDot {
+		name := &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		switch {
+		case p.peekToken.Type == lexer.TokenParenOpen:
+			pattern.Type = name
+			p.nextToken() // Move to '('
+			if p.peekToken.Type == lexer.TokenIdentifier {
+				p.nextToken()
+				pattern.Capture = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			}
+			if !p.expectPeek(lexer.TokenParenClose) {
+				return nil
+			}
+		case name.Value != "_":
+			pattern.Capture = name
+		}
+		return pattern
+	}
+
+	// An if after the value is the case's guard, and a | the next pattern
+	if pattern.Value = p.parseExpression(BITOR); pattern.Value == nil {
+		return nil
+	}
+	return pattern
+}
+
+// parseForStatement parses a for loop.
+func (p *Parser) parseForStatement() *ForStatement {
+	fs := &ForStatement{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(lexer.TokenIdentifier) {
+		return nil
+	}
+
+	fs.Variable = &Identifier{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
+	}
+	for p.peekToken.Type == lexer.TokenComma {
+		if fs.Variable != nil {
+			fs.Variables, fs.Variable = []*Identifier{fs.Variable}, nil
+		}
+		p.nextToken()
+		if !p.expectPeek(lexer.TokenIdentifier) {
+			return nil
+		}
+		fs.Variables = append(fs.Variables, &Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	}
+
+	if !p.expectPeek(lexer.TokenKeyword) || p.curToken.Literal != "in" {
+		msg := fmt.Sprintf("expected 'in', got %s instead (Line %d, Column %d)", p.curToken.Literal, p.curToken.Line, p.curToken.Column)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	p.nextToken()
+	fs.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(lexer.TokenColon) {
+		return nil
+	}
+
+	fs.Body = p.parseBlockStatement()
+
+	return fs
+}
+
+// parseImportStatement parses an import statement. Several modules can be
+// imported at once, separated by commas or grouped in parentheses:
+//
+//	import "fmt", "os"
+//	import (
+//	    "net/http"
+//	    json
+//	)
+func (p *Parser) parseImportStatement() *ImportStatement {
+	is := &ImportStatement{
+		Token: p.curToken,
+	}
+
+	grouped := p.peekToken.Type == lexer.TokenParenOpen
+	if grouped {
+		p.nextToken()
+	}
+
+	for {
+		if grouped && p.peekToken.Type == lexer.TokenParenClose {
+			p.nextToken()
+			break
+		}
+
+		p.nextToken()
+		spec := p.parseImportSpec()
+		if spec == nil {
+			return nil
+		}
+		is.Imports = append(is.Imports, spec)
+
+		if p.peekToken.Type == lexer.TokenComma {
+			p.nextToken()
+			continue
+		}
+		if !grouped {
+			break
+		}
+	}
+
+	if p.peekToken.Type == lexer.TokenNewline {
+		p.nextToken()
+	}
+
+	return is
+}
+
+// parseImportSpec parses a single quoted Go package path or bare Simple
+// module name, optionally followed by `as alias`.
+func (p *Parser) parseImportSpec() *ImportSpec {
+	if p.curToken.Type != lexer.TokenString && p.curToken.Type != lexer.TokenIdentifier {
+		msg := fmt.Sprintf("expected module name in import, got %s instead (Line %d, Column %d)", p.curToken.Type, p.curToken.Line, p.curToken.Column)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	spec := &ImportSpec{
+		ImportedModule: &StringLiteral{
+			Token: p.curToken,
+			Value: p.curToken.Literal,
+		},
+		IsSimpleImport: p.curToken.Type == lexer.TokenIdentifier,
+	}
+
+	if p.peekToken.Type == lexer.TokenKeyword && p.peekToken.Literal == "as" {
+		p.nextToken()
+		if !p.expectPeek(lexer.TokenIdentifier) {
+			return nil
+		}
+		spec.Alias = p.curToken.Literal
+	}
+
+	return spec
+}
+
+// ResolveImportNames gives every Go package import that would share a name
+// with an imported Simple module a "go" prefixed alias. Simple modules take
+// precedence, so with both `import json` and `import "encoding/json"` the
+// Simple module is json and the Go package is gojson.
+func ResolveImportNames(program *Program) {
+	simpleModules := map[string]bool{}
+	for _, stmt := range program.Statements {
+		if is, ok := stmt.(*ImportStatement); ok {
+			for _, spec := range is.Imports {
+				if spec.IsSimpleImport {
+					simpleModules[spec.Name()] = true
+				}
+			}
+		}
+	}
+
+	for _, stmt := range program.Statements {
+		if is, ok := stmt.(*ImportStatement); ok {
+			for _, spec := range is.Imports {
+				if !spec.IsSimpleImport && spec.Alias == "" && simpleModules[spec.Name()] {
+					spec.Alias = "go" + spec.Name()
+				}
+			}
+		}
+	}
+}
+
+// parseDeferStatement parses a defer statement.
+func (p *Parser) parseDeferStatement() *DeferStatement {
+	ds := &DeferStatement{
+		Token: p.curToken,
+	}
+
+	p.nextToken()
+	ds.Expression = p.parseCallStatementExpression("defer")
+
+	if p.peekToken.Type == lexer.TokenNewline {
+		p.nextToken()
+	}
+
+	return ds
+}
+
+// parseGoStatement parses a go statement.
+func (p *Parser) parseGoStatement() *GoStatement {
+	gs := &GoStatement{
+		Token: p.curToken,
+	}
+
+	p.nextToken()
+	gs.Expression = p.parseCallStatementExpression("go")
+
+	if p.peekToken.Type == lexer.TokenNewline {
+		p.nextToken()
+	}
+
+	return gs
+}
+
+// parseBreakStatement parses a break statement.
+func (p *Parser) parseBreakStatement() *BreakStatement {
+	bs := &BreakStatement{Token: p.curToken}
+	if p.peekToken.Type == lexer.TokenNewline {
+		p.nextToken()
+	}
+	return bs
+}
+
+// parseRaiseStatement parses a raise statement.
+func (p *Parser) parseRaiseStatement() *RaiseStatement {
+	rs := &RaiseStatement{Token: p.curToken}
+	if p.peekToken.Type != lexer.TokenNewline && p.peekToken.Type != lexer.TokenEOF {
+		p.nextToken()
+		rs.Exception = p.parseExpression(LOWEST)
+	}
+	if p.peekToken.Type == lexer.TokenNewline {
+		p.nextToken()
+	}
+	return rs
+}
+
+// parseAssertStatement parses an assert statement: its condition and, after
+// a comma, its message.
+func (p *Parser) parseAssertStatement() *AssertStatement {
+	as := &AssertStatement{Token: p.curToken}
+	if p.peekToken.Type == lexer.TokenNewline || p.peekToken.Type == lexer.TokenEOF {
+		p.nextToken()
+		return as
+	}
+	p.nextToken()
+	as.Condition = p.parseExpression(LOWEST)
+	if p.peekToken.Type == lexer.TokenComma {
+		p.nextToken()
+		p.nextToken()
+		as.Message = p.parseExpression(LOWEST)
+	}
+	if p.peekToken.Type == lexer.TokenNewline {
+		p.nextToken()
+	}
+	return as
+}
+
+// parseGlobalStatement parses a global or nonlocal statement and the names
+// it declares, separated by commas.
+func (p *Parser) parseGlobalStatement() *GlobalStatement {
+	gs := &GlobalStatement{Token: p.curToken}
+	for {
+		if !p.expectPeek(lexer.TokenIdentifier) {
+			return nil
+		}
+		gs.Names = append(gs.Names, &Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		if p.peekToken.Type != lexer.TokenComma {
+			break
+		}
+		p.nextToken()
+	}
+	if p.peekToken.Type == lexer.TokenNewline {
+		p.nextToken()
+	}
+	return gs
+}
+
+// parseContinueStatement parses a continue statement.
+func (p *Parser) parseContinueStatement() *ContinueStatement {
+	cs := &ContinueStatement{Token: p.curToken}
+	if p.peekToken.Type == lexer.TokenNewline {
+		p.nextToken()
+	}
+	return cs
+}
+
+// parseDelStatement parses a del statement and the targets it deletes,
+// separated by commas.
+func (p *Parser) parseDelStatement() *DelStatement {
+	ds := &DelStatement{Token: p.curToken}
+	for p.peekToken.Type != lexer.TokenNewline && p.peekToken.Type != lexer.TokenEOF {
+		p.nextToken()
+		target := p.parseExpression(LOWEST)
+		if target == nil {
+			return nil
+		}
+		ds.Targets = append(ds.Targets, target)
+		if p.peekToken.Type != lexer.TokenComma {
+			break
+		}
+		p.nextToken()
+	}
+	if p.peekToken.Type == lexer.TokenNewline {
+		p.nextToken()
+	}
+	return ds
+}
+
+// parsePassStatement parses a pass statement.
+func (p *Parser) parsePassStatement() *PassStatement {
+	ps := &PassStatement{Token: p.curToken}
+	if p.peekToken.Type == lexer.TokenNewline {
+		p.nextToken()
+	}
+	return ps
+}
+
+// parseCallStatementExpression parses the function call of a defer or go
+// statement. Like Go, the call's arguments are evaluated when the statement
+// runs, not when the call is made.
+func (p *Parser) parseCallStatementExpression(keyword string) Expression {
+	tok := p.curToken
+	expr := p.parseExpression(LOWEST)
+	if _, ok := expr.(*CallExpression); !ok && expr != nil {
+		p.errors = append(p.errors, fmt.Sprintf("expression in %s must be a function call (Line %d, Column %d)", keyword, tok.Line, tok.Column))
+	}
+	return expr
+}
+
+// parseExpressionStatement parses an expression statement.
+func (p *Parser) parseExpressionStatement() *ExpressionStatement {
+	if p.curToken.Type == lexer.TokenNewline {
+		return nil
+	}
+
+	es := &ExpressionStatement{
+		Token: p.curToken,
+	}
+
+	es.Expression = p.parseExpression(LOWEST)
+
+	if p.peekToken.Type == lexer.TokenNewline {
+		p.nextToken()
+	}
+
+	return es
 }
 
 // parseExpression parses an expression with given precedence.
@@ -1365,26 +2748,6 @@ func (p *Parser) parseIdentifier() Expression {
 	}
 }
 
-// parseStringLiteral parses a string literal.
-func (p *Parser) parseDeferLiteral() Expression {
-	dl := &DeferLiteral{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
-	}
-
-	return dl
-}
-
-// parseStringLiteral parses a string literal.
-func (p *Parser) parseGoLiteral() Expression {
-	gl := &GoLiteral{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
-	}
-
-	return gl
-}
-
 // parseIntegerLiteral parses an integer literal.
 func (p *Parser) parseIntegerLiteral() Expression {
 	il := &IntegerLiteral{
@@ -1422,12 +2785,26 @@ func (p *Parser) parseIntegerLiteral() Expression {
 	return il
 }
 
-// parseStringLiteral parses a string literal.
+// parseStringLiteral parses a string literal. Adjacent literals are
+// concatenated, as in Python: "a" 'b' is the same as "ab".
 func (p *Parser) parseStringLiteral() Expression {
-	return &StringLiteral{
+	sl := &StringLiteral{
 		Token: p.curToken,
 		Value: p.curToken.Literal,
 	}
+	for p.peekToken.Type == lexer.TokenString {
+		p.nextToken()
+		sl.Value += p.curToken.Literal
+	}
+	if p.peekToken.Type == lexer.TokenFString {
+		// "a" f"{b}" is one f-string, starting with the plain text
+		p.nextToken()
+		fs := p.parseFStringLiteral().(*FStringLiteral)
+		fs.Token = sl.Token
+		fs.Parts = append([]FStringPart{{Text: sl.Value}}, fs.Parts...)
+		return fs
+	}
+	return sl
 }
 
 // parseBooleanLiteral parses a boolean literal.
@@ -1442,10 +2819,23 @@ func (p *Parser) parseBooleanLiteral() Expression {
 	}
 }
 
+// parseNoneLiteral parses None.
+func (p *Parser) parseNoneLiteral() Expression {
+	return &NoneLiteral{Token: p.curToken}
+}
+
 // parseGroupedExpression parses a grouped expression.
 func (p *Parser) parseGroupedExpression() Expression {
+	token := p.curToken
+	if p.peekToken.Type == lexer.TokenParenClose {
+		p.nextToken()
+		return &TupleLiteral{Token: token}
+	}
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
+	if p.peekToken.Type == lexer.TokenComma {
+		return &TupleLiteral{Token: token, Elements: p.parseExpressionListAfter(exp, lexer.TokenParenClose)}
+	}
 	if !p.expectPeek(lexer.TokenParenClose) {
 		return nil
 	}
@@ -1461,7 +2851,13 @@ func (p *Parser) parsePrefixExpression() Expression {
 
 	p.nextToken()
 
-	pe.Right = p.parseExpression(PREFIX)
+	if pe.Token.Type == lexer.TokenNot {
+		// As in Python, not binds more loosely than comparisons: not a == b
+		// is not (a == b)
+		pe.Right = p.parseExpression(NOT)
+	} else {
+		pe.Right = p.parseExpression(PREFIX)
+	}
 
 	return pe
 }
@@ -1475,6 +2871,10 @@ func (p *Parser) parseInfixExpression(left Expression) Expression {
 	}
 
 	precedence := p.curPrecedence()
+	if ie.Operator == "**" {
+		// ** groups from the right: 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		precedence--
+	}
 	p.nextToken()
 	ie.Right = p.parseExpression(precedence)
 
@@ -1488,11 +2888,121 @@ func (p *Parser) parseCallExpression(function Expression) Expression {
 		Function: function,
 	}
 
-	ce.Arguments = p.parseExpressionList(lexer.TokenParenClose)
+	ce.Arguments = p.parseCallArguments()
+
+	return ce
+}
+
+// parseKeywordExpression parses an expression continued by a keyword: a
+// membership test with in, an identity test with is, or a conditional
+// expression with if.
+func (p *Parser) parseKeywordExpression(left Expression) Expression {
+	if p.curToken.Literal == "in" || p.curToken.Literal == "is" {
+		return p.parseComparison(left)
+	}
+	return p.parseConditionalExpression(left)
+}
 
+// parseConditionalExpression parses the rest of consequence if condition
+// else alternative. The alternative reaches as far as it can, so a if b else c if d
+// else e chooses between c and e when b is false. Without an else, the
+// expression is left without an alternative for the analyzer to report.
+func (p *Parser) parseConditionalExpression(consequence Expression) Expression {
+	ce := &ConditionalExpression{Token: p.curToken, Consequence: consequence}
+	p.nextToken()
+	if ce.Condition = p.parseExpression(TERNARY); ce.Condition == nil {
+		return nil
+	}
+	if p.peekToken.Type != lexer.TokenKeyword || p.peekToken.Literal != "else" {
+		return ce
+	}
+	p.nextToken()
+	p.nextToken()
+	if ce.Alternative = p.parseExpression(LOWEST); ce.Alternative == nil {
+		return nil
+	}
 	return ce
 }
 
+// parseAssignmentExpression parses the value of target := value, which
+// reaches as far as it can, as in Python. A target that isn't a name is
+// left for the analyzer to report.
+func (p *Parser) parseAssignmentExpression(target Expression) Expression {
+	ae := &AssignmentExpression{Token: p.curToken, Target: target}
+	p.nextToken()
+	if ae.Value = p.parseExpression(LOWEST); ae.Value == nil {
+		return nil
+	}
+	return ae
+}
+
+// parseLambdaExpression parses lambda params: body. The body reaches as far
+// as it can, as in Python.
+func (p *Parser) parseLambdaExpression() Expression {
+	le := &LambdaExpression{Token: p.curToken}
+	for p.peekToken.Type != lexer.TokenColon {
+		if !p.expectPeek(lexer.TokenIdentifier) {
+			return nil
+		}
+		le.Parameters = append(le.Parameters, &Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		if p.peekToken.Type == lexer.TokenComma {
+			p.nextToken()
+		}
+	}
+	p.nextToken()
+	p.nextToken()
+	le.Body = p.parseExpression(LOWEST)
+	if le.Body == nil {
+		return nil
+	}
+	return le
+}
+
+// parseCallArguments parses the arguments of a call, which may be
+// positional or keyword (name=value) arguments.
+func (p *Parser) parseCallArguments() []Expression {
+	list := []Expression{}
+
+	if p.peekToken.Type == lexer.TokenParenClose {
+		p.nextToken()
+		return list
+	}
+
+	p.nextToken()
+	list = append(list, p.parseCallArgument())
+
+	for p.peekToken.Type == lexer.TokenComma {
+		p.nextToken()
+		// Allow a trailing comma before the closing parenthesis
+		if p.peekToken.Type == lexer.TokenParenClose {
+			break
+		}
+		p.nextToken()
+		list = append(list, p.parseCallArgument())
+	}
+
+	if !p.expectPeek(lexer.TokenParenClose) {
+		return nil
+	}
+
+	return list
+}
+
+// parseCallArgument parses a single call argument.
+func (p *Parser) parseCallArgument() Expression {
+	if p.curToken.Type == lexer.TokenIdentifier && p.peekToken.Type == lexer.TokenAssign {
+		ka := &KeywordArgument{
+			Token: p.curToken,
+			Name:  &Identifier{Token: p.curToken, Value: p.curToken.Literal},
+		}
+		p.nextToken() // Move to '='
+		p.nextToken() // Move to the value
+		ka.Value = p.parseExpression(LOWEST)
+		return ka
+	}
+	return p.parseExpression(LOWEST)
+}
+
 // parseExpressionList parses a list of expressions separated by commas.
 func (p *Parser) parseExpressionList(end lexer.TokenType) []Expression {
 	list := []Expression{}
@@ -1503,10 +3013,20 @@ func (p *Parser) parseExpressionList(end lexer.TokenType) []Expression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	return p.parseExpressionListAfter(p.parseExpression(LOWEST), end)
+}
+
+// parseExpressionListAfter parses the rest of a comma-separated list whose
+// first expression has been parsed.
+func (p *Parser) parseExpressionListAfter(first Expression, end lexer.TokenType) []Expression {
+	list := []Expression{first}
 
 	for p.peekToken.Type == lexer.TokenComma {
 		p.nextToken()
+		// Allow a trailing comma before the closing token
+		if p.peekToken.Type == end {
+			break
+		}
 		p.nextToken()
 		list = append(list, p.parseExpression(LOWEST))
 	}
@@ -1553,11 +3073,18 @@ func Inspect(node Node, pre NodeVisitor) {
 		}
 	case *FunctionLiteral:
 		if n != nil {
+			for _, d := range n.Decorators {
+				Inspect(d, pre)
+			}
 			for _, param := range n.Parameters {
 				Inspect(param, pre)
 			}
 			Inspect(n.Body, pre)
 		}
+	case *ClassStatement:
+		if n != nil {
+			Inspect(n.Body, pre)
+		}
 	case *BlockStatement:
 		if n != nil {
 			for _, stmt := range n.Statements {
@@ -1581,6 +3108,40 @@ func Inspect(node Node, pre NodeVisitor) {
 			Inspect(n.Iterable, pre)
 			Inspect(n.Body, pre)
 		}
+	case *TryStatement:
+		if n != nil {
+			Inspect(n.Body, pre)
+			for _, handler := range n.Handlers {
+				for _, t := range handler.Types {
+					Inspect(t, pre)
+				}
+				Inspect(handler.Body, pre)
+			}
+			Inspect(n.Else, pre)
+			Inspect(n.Finally, pre)
+		}
+	case *WithStatement:
+		if n != nil {
+			for _, item := range n.Items {
+				Inspect(item.Context, pre)
+			}
+			Inspect(n.Body, pre)
+		}
+	case *MatchStatement:
+		if n != nil {
+			Inspect(n.Subject, pre)
+			for _, mc := range n.Cases {
+				for _, pattern := range mc.Patterns {
+					if pattern.Value != nil {
+						Inspect(pattern.Value, pre)
+					}
+				}
+				if mc.Guard != nil {
+					Inspect(mc.Guard, pre)
+				}
+				Inspect(mc.Body, pre)
+			}
+		}
 	case *InfixExpression:
 		if n != nil {
 			Inspect(n.Left, pre)
@@ -1595,5 +3156,210 @@ func Inspect(node Node, pre NodeVisitor) {
 			Inspect(n.Left, pre)
 			Inspect(n.Selector, pre)
 		}
+	case *KeywordArgument:
+		if n != nil {
+			Inspect(n.Value, pre)
+		}
+	case *DeferStatement:
+		if n != nil {
+			Inspect(n.Expression, pre)
+		}
+	case *GoStatement:
+		if n != nil {
+			Inspect(n.Expression, pre)
+		}
+	case *AssignmentStatement:
+		if n != nil {
+			for _, left := range n.Left {
+				Inspect(left, pre)
+			}
+			Inspect(n.Value, pre)
+		}
+	case *ReturnStatement:
+		if n != nil && n.ReturnValue != nil {
+			Inspect(n.ReturnValue, pre)
+		}
+	case *YieldStatement:
+		if n != nil && n.Value != nil {
+			Inspect(n.Value, pre)
+		}
+	case *RaiseStatement:
+		if n != nil && n.Exception != nil {
+			Inspect(n.Exception, pre)
+		}
+	case *DelStatement:
+		if n != nil {
+			for _, target := range n.Targets {
+				Inspect(target, pre)
+			}
+		}
+	case *AssertStatement:
+		if n != nil && n.Condition != nil {
+			Inspect(n.Condition, pre)
+			if n.Message != nil {
+				Inspect(n.Message, pre)
+			}
+		}
+	case *IndexExpression:
+		if n != nil {
+			Inspect(n.Left, pre)
+			if n.Index != nil {
+				Inspect(n.Index, pre)
+			}
+			if n.End != nil {
+				Inspect(n.End, pre)
+			}
+		}
+	case *ArrayLiteral:
+		if n != nil {
+			for _, el := range n.Elements {
+				Inspect(el, pre)
+			}
+		}
+	case *MapLiteral:
+		if n != nil {
+			for _, key := range n.Keys {
+				Inspect(key, pre)
+				Inspect(n.Pairs[key], pre)
+			}
+		}
+	case *SetLiteral:
+		if n != nil {
+			for _, el := range n.Elements {
+				Inspect(el, pre)
+			}
+		}
+	case *TypeConversionExpression:
+		if n != nil {
+			Inspect(n.Expression, pre)
+		}
+	case *FStringLiteral:
+		if n != nil {
+			for _, part := range n.Parts {
+				if part.Expression != nil {
+					Inspect(part.Expression, pre)
+				}
+			}
+		}
+	case *LambdaExpression:
+		if n != nil {
+			Inspect(n.Body, pre)
+		}
+	case *ConditionalExpression:
+		if n != nil {
+			Inspect(n.Consequence, pre)
+			Inspect(n.Condition, pre)
+			Inspect(n.Alternative, pre)
+		}
+	case *AssignmentExpression:
+		if n != nil {
+			Inspect(n.Target, pre)
+			Inspect(n.Value, pre)
+		}
+	case *TupleLiteral:
+		if n != nil {
+			for _, el := range n.Elements {
+				Inspect(el, pre)
+			}
+		}
+	case *ListComprehension:
+		if n != nil {
+			Inspect(n.Element, pre)
+			inspectForClause(n.Clause, pre)
+		}
+	case *DictComprehension:
+		if n != nil {
+			Inspect(n.Key, pre)
+			Inspect(n.Value, pre)
+			inspectForClause(n.Clause, pre)
+		}
+	}
+}
+
+func inspectForClause(fc *ForClause, pre NodeVisitor) {
+	Inspect(fc.Iterable, pre)
+	if fc.Condition != nil {
+		Inspect(fc.Condition, pre)
+	}
+}
+
+// Bindings returns the names a node binds: the targets of an assignment or
+// an assignment expression, a function's name and parameters, a lambda's
+// parameters, the variables of a loop or comprehension, and the names given
+// by except, with and case.
+func Bindings(node Node) []*Identifier {
+	idents := []*Identifier{}
+	switch n := node.(type) {
+	case *FunctionLiteral:
+		if n != nil {
+			idents = append(append(idents, n.Name), n.Parameters...)
+		}
+	case *LambdaExpression:
+		if n != nil {
+			idents = append(idents, n.Parameters...)
+		}
+	case *AssignmentStatement:
+		if n == nil {
+			break
+		}
+		for _, left := range n.Left {
+			if ident, ok := left.(*Identifier); ok {
+				idents = append(idents, ident)
+			}
+		}
+	case *AssignmentExpression:
+		if n == nil {
+			break
+		}
+		if ident, ok := n.Target.(*Identifier); ok {
+			idents = append(idents, ident)
+		}
+	case *ForStatement:
+		if n == nil {
+			break
+		}
+		if n.Variable != nil {
+			idents = append(idents, n.Variable)
+		}
+		idents = append(idents, n.Variables...)
+	case *ListComprehension:
+		if n != nil {
+			idents = append(idents, n.Clause.Variables...)
+		}
+	case *DictComprehension:
+		if n != nil {
+			idents = append(idents, n.Clause.Variables...)
+		}
+	case *TryStatement:
+		if n == nil {
+			break
+		}
+		for _, handler := range n.Handlers {
+			idents = append(idents, handler.Name)
+		}
+	case *WithStatement:
+		if n == nil {
+			break
+		}
+		for _, item := range n.Items {
+			idents = append(idents, item.Name)
+		}
+	case *MatchStatement:
+		if n == nil {
+			break
+		}
+		for _, mc := range n.Cases {
+			for _, pattern := range mc.Patterns {
+				idents = append(idents, pattern.Capture)
+			}
+			idents = append(idents, mc.Name)
+		}
+	}
+	bound := idents[:0]
+	for _, ident := range idents {
+		if ident != nil {
+			bound = append(bound, ident)
+		}
 	}
+	return bound
 }
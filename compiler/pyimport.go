@@ -0,0 +1,445 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Importing Python
+//
+// simple import-from-python script.py writes script.simple beside a Python
+// script. Simple reads like Python, so most lines are kept as they are. The
+// block of if __name__ == "__main__": becomes the top level of the program,
+// strings on their own become comments, but for the docstrings of the script
+// and its functions, and imports take their Simple form. A line
+// using Python that Simple doesn't have gets a TODO comment above it saying
+// what to do instead, and the lines are listed when the script is written.
+// The .simple file is a start that is finished by hand.
+
+// pythonFinding is a line of a Python script that uses something Simple
+// doesn't have.
+type pythonFinding struct {
+	Line    int
+	Message string
+}
+
+// pythonModules are the Python modules Simple has, and how it imports them.
+var pythonModules = map[string]string{
+	"json": "import json",
+	"math": `import "math"`,
+}
+
+// pythonModuleGoPackages are the Go packages to use instead of Python
+// modules Simple doesn't have.
+var pythonModuleGoPackages = map[string]string{
+	"base64":     "encoding/base64",
+	"csv":        "encoding/csv",
+	"datetime":   "time",
+	"hashlib":    "crypto/sha256",
+	"logging":    "log",
+	"os":         "os",
+	"random":     "math/rand",
+	"re":         "regexp",
+	"requests":   "net/http",
+	"string":     "strings",
+	"subprocess": "os/exec",
+	"sys":        "os",
+	"time":       "time",
+	"urllib":     "net/http",
+}
+
+// pythonBuiltins are the builtin functions of Python that Simple doesn't
+// have, with what to use instead.
+var pythonBuiltins = map[string]string{
+	"abs":      `math.Abs from import "math"`,
+	"all":      "a for loop",
+	"any":      "a for loop",
+	"bool":     "a comparison, such as n != 0",
+	"dict":     "a dict literal",
+	"float":    `float64(n) for numbers, or strconv.ParseFloat from import "strconv" for strings`,
+	"list":     "a list literal or comprehension",
+	"reversed": "a while loop counting down",
+	"tuple":    "a tuple literal",
+}
+
+// pythonMethods are methods of Python's strings and lists that Simple
+// doesn't have.
+var pythonMethods = map[string]bool{
+	"append": true, "count": true, "endswith": true, "extend": true,
+	"find": true, "index": true, "insert": true, "join": true,
+	"lower": true, "lstrip": true, "pop": true, "remove": true,
+	"replace": true, "reverse": true, "rstrip": true, "sort": true,
+	"split": true, "startswith": true, "strip": true, "upper": true,
+}
+
+var (
+	pythonMainGuard     = regexp.MustCompile(`^if\s+__name__\s*==\s*("__main__"|'__main__')\s*:\s*(#.*)?$`)
+	pythonImport        = regexp.MustCompile(`^import\s+(.+)$`)
+	pythonFromImport    = regexp.MustCompile(`^from\s+(\S+)\s+import\b`)
+	pythonDef           = regexp.MustCompile(`^(async\s+)?def\s+([A-Za-z_]\w*)`)
+	pythonDocstring     = regexp.MustCompile(`^[rRuU]?("""|'''|"|')\s*("""|'''|"|')?$`)
+	pythonAnnotated     = regexp.MustCompile(`^([A-Za-z_][\w.]*)\s*:\s*[^\s=]`)
+	pythonCall          = regexp.MustCompile(`(^|[^\w.])([A-Za-z_]\w*)\s*\(`)
+	pythonMethodCall    = regexp.MustCompile(`([A-Za-z_]\w*)?\s*\.\s*([A-Za-z_]\w*)\s*\(`)
+	pythonAwait         = regexp.MustCompile(`\b(async|await)\b`)
+	pythonYieldFrom     = regexp.MustCompile(`\byield\s+from\b`)
+	pythonWord          = regexp.MustCompile(`[A-Za-z_]\w*$`)
+	pythonBlockKeywords = map[string]bool{"case": true, "class": true, "elif": true, "else": true, "except": true, "finally": true, "for": true, "if": true, "lambda": true, "match": true, "try": true, "while": true, "with": true}
+)
+
+// importFromPython writes the Simple version of a Python script beside it,
+// reporting the lines left to change by hand.
+func importFromPython(script string) error {
+	source, err := os.ReadFile(script)
+	if err != nil {
+		return err
+	}
+	target := strings.TrimSuffix(script, ".py") + ".simple"
+	if _, err := os.Stat(target); err == nil {
+		return fmt.Errorf("%s already exists; move it away to import %s again", target, script)
+	}
+	converted, findings := convertPython(string(source))
+	if err := os.WriteFile(target, []byte(converted), 0644); err != nil {
+		return err
+	}
+	lines := map[int]bool{}
+	for _, f := range findings {
+		fmt.Printf("%s:%d: %s\n", script, f.Line, f.Message)
+		lines[f.Line] = true
+	}
+	switch len(lines) {
+	case 0:
+		fmt.Printf("wrote %s\n", target)
+	case 1:
+		fmt.Printf("wrote %s; 1 line uses Python that Simple doesn't have and is marked with a TODO comment\n", target)
+	default:
+		fmt.Printf("wrote %s; %d lines use Python that Simple doesn't have and are marked with TODO comments\n", target, len(lines))
+	}
+	return nil
+}
+
+// convertPython converts a Python script to Simple, returning it with the
+// lines that need changing by hand.
+func convertPython(source string) (string, []pythonFinding) {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+
+	// Functions and methods the script defines aren't the builtins and
+	// methods of the same names
+	defined := map[string]bool{}
+	for _, line := range lines {
+		if m := pythonDef.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			defined[m[2]] = true
+		}
+	}
+
+	var out []string
+	var findings []pythonFinding
+	modules := map[string]string{} // names bound by imports, to the module
+	var brackets []string          // the callee, or "", of each open bracket
+	open := ""                     // the quotes of a string left open by the last line
+	continued := false             // whether the last line ended with a backslash
+	inDef := false                 // whether the brackets are a def's parameters
+	startsBody := true             // whether the next statement starts the script or a def, where a string is its docstring
+	guardIndent, guardDedent := -1, -1
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if i == 0 && strings.HasPrefix(line, "#!") {
+			continue
+		}
+
+		// The body of if __name__ == "__main__": moves to the top level
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if guardIndent >= 0 && open == "" && strings.TrimSpace(line) != "" {
+			if len(brackets) == 0 && !continued && indent <= guardIndent {
+				guardIndent, guardDedent = -1, -1
+			} else {
+				if guardDedent < 0 {
+					guardDedent = indent - guardIndent
+				}
+				line = line[min(guardDedent, indent):]
+				indent -= min(guardDedent, indent)
+			}
+		}
+		if open != "" {
+			_, open = maskPython(line, open)
+			out = append(out, line)
+			continue
+		}
+
+		masked, stillOpen := maskPython(line, "")
+		code := strings.TrimSpace(masked)
+		prefix := line[:indent]
+		statement := len(brackets) == 0 && !continued
+		var messages []string
+
+		if statement && code != "" {
+			switch m := pythonFromImport.FindStringSubmatch(code); {
+			case pythonMainGuard.MatchString(strings.TrimSpace(line)):
+				guardIndent = indent
+				continue
+			case startsBody && pythonDocstring.MatchString(code) && strings.ContainsAny(code[:1], `"'`):
+				// A docstring is kept as it is
+			case pythonDocstring.MatchString(code):
+				// Any other string on its own is a comment
+				startsBody = false
+				end := i
+				for stillOpen != "" && end+1 < len(lines) {
+					end++
+					_, stillOpen = maskPython(lines[end], stillOpen)
+				}
+				out = append(out, docstringComment(lines[i:end+1], prefix)...)
+				i = end
+				continue
+			case m != nil:
+				messages = append(messages, fmt.Sprintf("from %s import isn't supported; import the module and name what you use from it", m[1]))
+				line = prefix + "# " + strings.TrimSpace(line)
+				masked = ""
+			case pythonImport.MatchString(code):
+				line, messages = convertPythonImport(strings.TrimSpace(line), prefix, modules)
+				masked = ""
+			}
+			if m := pythonAnnotated.FindStringSubmatch(code); m != nil && !pythonBlockKeywords[m[1]] {
+				messages = append(messages, "variable annotations aren't supported; annotate the parameters and results of functions, or leave them out")
+			}
+			if m := pythonDef.FindStringSubmatch(code); m != nil {
+				inDef = true
+				if m[1] != "" {
+					messages = append(messages, "async functions aren't supported; run functions concurrently with go")
+				}
+			}
+		}
+
+		if pythonAwait.MatchString(masked) && pythonDef.FindStringSubmatch(code) == nil {
+			messages = append(messages, "await isn't supported; wait for goroutines with channels")
+		}
+		if pythonYieldFrom.MatchString(masked) {
+			messages = append(messages, "yield from isn't supported; yield each value in a for loop")
+		}
+		for _, m := range pythonCall.FindAllStringSubmatch(masked, -1) {
+			if hint, ok := pythonBuiltins[m[2]]; ok && !defined[m[2]] {
+				messages = append(messages, fmt.Sprintf("%s() isn't supported; use %s", m[2], hint))
+			}
+		}
+		for _, m := range pythonMethodCall.FindAllStringSubmatch(masked, -1) {
+			if _, module := modules[m[1]]; pythonMethods[m[2]] && !module && !defined[m[2]] {
+				messages = append(messages, fmt.Sprintf(".%s() of strings and lists isn't supported; use Go's strings and slices packages, or append", m[2]))
+			}
+		}
+		if len(modules) > 0 {
+			var notes []string
+			line, notes = capitalizeGoNames(line, masked, modules)
+			messages = append(messages, notes...)
+		}
+
+		// Brackets are followed across lines, for the parameters of a def
+		// and the arguments of print
+		for j := 0; j < len(masked); j++ {
+			c := masked[j]
+			switch {
+			case c == '(' || c == '[' || c == '{':
+				callee := ""
+				if c == '(' {
+					callee = pythonWord.FindString(strings.TrimRight(masked[:j], " \t"))
+				}
+				brackets = append(brackets, callee)
+			case c == ')' || c == ']' || c == '}':
+				if len(brackets) > 0 {
+					brackets = brackets[:len(brackets)-1]
+				}
+			case c == '=' && len(brackets) > 0 && !strings.ContainsRune("=!<>:+-*/%&|^@", rune(prev(masked, j))) && next(masked, j) != '=':
+				name := pythonWord.FindString(strings.TrimRight(masked[:j], " \t"))
+				if inDef && len(brackets) == 1 {
+					// The parameter, not its annotation, as in count: int = 1
+					param := masked[strings.LastIndexAny(masked[:j], "(,")+1 : j]
+					name = strings.TrimSpace(strings.Split(param, ":")[0])
+					messages = append(messages, fmt.Sprintf("the default value of parameter %s isn't supported; pass every argument", name))
+				} else if brackets[len(brackets)-1] == "print" {
+					messages = append(messages, fmt.Sprintf("print's %s= isn't supported; build the line with + or an f-string", name))
+				}
+			case c == '*' && inDef && len(brackets) == 1 && strings.ContainsRune("(,", rune(prevSignificant(masked, j))):
+				messages = append(messages, "*args and **kwargs aren't supported; pass a list or a dict")
+			}
+		}
+		if statement && code != "" {
+			startsBody = false
+		}
+		if inDef && len(brackets) == 0 && strings.HasSuffix(code, ":") {
+			inDef = false
+			startsBody = true
+		}
+		open = stillOpen
+		continued = strings.HasSuffix(strings.TrimRight(masked, " \t"), "\\")
+
+		messages = uniqueStrings(messages)
+		for _, message := range messages {
+			out = append(out, prefix+"# TODO: "+message)
+			findings = append(findings, pythonFinding{Line: i + 1, Message: message})
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n"), findings
+}
+
+// convertPythonImport converts an import statement of Python, recording the
+// names it binds to the modules Simple has.
+func convertPythonImport(statement, prefix string, modules map[string]string) (string, []string) {
+	var imports, messages []string
+	for _, part := range strings.Split(strings.TrimPrefix(statement, "import"), ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		module, name := fields[0], fields[0]
+		if len(fields) == 3 && fields[1] == "as" {
+			name = fields[2]
+		}
+		simple, ok := pythonModules[module]
+		if !ok {
+			base := strings.Split(module, ".")[0]
+			if pkg, ok := pythonModuleGoPackages[base]; ok {
+				messages = append(messages, fmt.Sprintf("Python's %s module isn't available; import a Go package such as \"%s\" instead", module, pkg))
+			} else {
+				messages = append(messages, fmt.Sprintf("Python's %s module isn't available; import a Go package instead", module))
+			}
+			imports = append(imports, "# import "+strings.Join(fields, " "))
+			continue
+		}
+		if name != module {
+			simple += " as " + name
+		}
+		if module == "math" {
+			modules[name] = module
+		}
+		imports = append(imports, simple)
+	}
+	for i := range imports {
+		imports[i] = prefix + imports[i]
+	}
+	return strings.Join(imports, "\n"), messages
+}
+
+// capitalizeGoNames gives the names a line uses from Go packages imported in
+// place of Python modules, such as math.sqrt, their Go spelling, math.Sqrt,
+// and notes the functions that return floats where Python's return ints.
+func capitalizeGoNames(line, masked string, modules map[string]string) (string, []string) {
+	b := []byte(line)
+	var notes []string
+	for name := range modules {
+		re := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\s*\.\s*([a-z]\w*)`)
+		for _, m := range re.FindAllStringSubmatchIndex(masked, -1) {
+			if m[0] > 0 && masked[m[0]-1] == '.' {
+				continue
+			}
+			b[m[2]] = byte(unicode.ToUpper(rune(b[m[2]])))
+			switch function := masked[m[2]:m[3]]; function {
+			case "floor", "ceil", "trunc":
+				notes = append(notes, fmt.Sprintf("math.%s returns an int in Python but a float in Go; wrap it in int() for an int", function))
+			}
+		}
+	}
+	return string(b), notes
+}
+
+// docstringComment turns the lines of a string on its own into comments.
+func docstringComment(lines []string, prefix string) []string {
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	text = strings.TrimLeft(text, "rRuU")
+	for _, quotes := range []string{`"""`, `'''`, `"`, `'`} {
+		if strings.HasPrefix(text, quotes) && strings.HasSuffix(text, quotes) && len(text) >= 2*len(quotes) {
+			text = text[len(quotes) : len(text)-len(quotes)]
+			break
+		}
+	}
+	var comments []string
+	for _, l := range strings.Split(strings.TrimSpace(text), "\n") {
+		comments = append(comments, strings.TrimRight(prefix+"# "+strings.TrimSpace(l), " "))
+	}
+	return comments
+}
+
+// maskPython blanks out the text of the strings and the comment of a line of
+// Python, so that what they say isn't taken for code. open is the quotes of
+// a triple-quoted string the line starts inside, and the quotes of one still
+// open at its end are returned.
+func maskPython(line, open string) (string, string) {
+	b := []byte(line)
+	for i := 0; i < len(b); {
+		if open == "" {
+			switch c := b[i]; c {
+			case '#':
+				return string(b[:i]), ""
+			case '"', '\'':
+				open = string(c)
+				if strings.HasPrefix(line[i:], strings.Repeat(open, 3)) {
+					open = strings.Repeat(open, 3)
+				}
+				i += len(open)
+			default:
+				i++
+			}
+			continue
+		}
+		switch {
+		case b[i] == '\\':
+			b[i] = ' '
+			if i+1 < len(b) {
+				b[i+1] = ' '
+			}
+			i += 2
+		case strings.HasPrefix(line[i:], open):
+			i += len(open)
+			open = ""
+		default:
+			b[i] = ' '
+			i++
+		}
+	}
+	// Only triple-quoted strings go on past their line
+	if len(open) == 1 {
+		open = ""
+	}
+	return string(b), open
+}
+
+// prev returns the byte before s[i], or 0.
+func prev(s string, i int) byte {
+	if i == 0 {
+		return 0
+	}
+	return s[i-1]
+}
+
+// next returns the byte after s[i], or 0.
+func next(s string, i int) byte {
+	if i+1 >= len(s) {
+		return 0
+	}
+	return s[i+1]
+}
+
+// prevSignificant returns the last byte before s[i] that isn't a space, or
+// 0.
+func prevSignificant(s string, i int) byte {
+	t := strings.TrimRight(s[:i], " \t")
+	if t == "" {
+		return 0
+	}
+	return t[len(t)-1]
+}
+
+// uniqueStrings returns strings without repeats, in their first order.
+func uniqueStrings(strs []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, s := range strs {
+		if !seen[s] {
+			seen[s] = true
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
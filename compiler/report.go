@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// goModule is a Go module dependency of a generated program.
+type goModule struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Replace string `json:"replace,omitempty"`
+	License string `json:"license,omitempty"`
+}
+
+// buildReport is the machine-readable summary written by --report.
+type buildReport struct {
+	CompilerVersion string             `json:"compiler_version"`
+	Source          string             `json:"source"`
+	OutputDir       string             `json:"output_dir"`
+	Binary          string             `json:"binary,omitempty"`
+	Package         string             `json:"package,omitempty"`
+	Success         bool               `json:"success"`
+	Error           string             `json:"error,omitempty"`
+	GeneratedFiles  []string           `json:"generated_files"`
+	GoPackages      []string           `json:"go_packages"`
+	GoModules       []goModule         `json:"go_modules"`
+	StdlibModules   []string           `json:"stdlib_modules"`
+	SimpleModules   []string           `json:"simple_modules"`
+	TimingsMs       map[string]float64 `json:"timings_ms"`
+
+	start time.Time
+}
+
+func newBuildReport(source, outputDir string) *buildReport {
+	return &buildReport{
+		CompilerVersion: version,
+		Source:          source,
+		OutputDir:       outputDir,
+		GeneratedFiles:  []string{},
+		GoPackages:      []string{},
+		GoModules:       []goModule{},
+		StdlibModules:   []string{},
+		SimpleModules:   []string{},
+		TimingsMs:       map[string]float64{},
+		start:           time.Now(),
+	}
+}
+
+// timed runs fn and records how long it took under name.
+func (r *buildReport) timed(name string, fn func()) {
+	start := time.Now()
+	fn()
+	r.TimingsMs[name] = float64(time.Since(start).Microseconds()) / 1000
+}
+
+// addImports sorts the main program's import paths into Go packages, Simple
+// stdlib modules and local Simple modules.
+func (r *buildReport) addImports(imports []string) {
+	moduleName := filepath.Base(r.OutputDir)
+	for _, imp := range imports {
+		switch {
+		case strings.HasPrefix(imp, moduleName+"/lib/"):
+			r.StdlibModules = append(r.StdlibModules, strings.TrimPrefix(imp, moduleName+"/lib/"))
+		case strings.HasPrefix(imp, moduleName+"/"):
+			r.SimpleModules = append(r.SimpleModules, strings.TrimPrefix(imp, moduleName+"/"))
+		default:
+			r.GoPackages = append(r.GoPackages, imp)
+		}
+	}
+}
+
+// collect fills in the generated files and the resolved Go module versions.
+func (r *buildReport) collect() {
+	filepath.Walk(r.OutputDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && strings.HasSuffix(path, ".go") {
+			rel, _ := filepath.Rel(r.OutputDir, path)
+			r.GeneratedFiles = append(r.GeneratedFiles, rel)
+		}
+		return nil
+	})
+	sort.Strings(r.GeneratedFiles)
+
+	cmd := exec.Command("go", "list", "-m", "-f", "{{if not .Main}}{{.Path}} {{.Version}} {{.Dir}}{{end}}", "all")
+	cmd.Dir = r.OutputDir
+	out, err := cmd.Output()
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		// A module not downloaded has no directory
+		if len(fields) >= 2 {
+			m := goModule{Path: fields[0], Version: fields[1]}
+			if len(fields) == 3 {
+				m.License = moduleLicense(fields[2])
+			}
+			r.GoModules = append(r.GoModules, m)
+		}
+	}
+}
+
+// write saves the report as indented JSON.
+func (r *buildReport) write(path string, err error) error {
+	r.Success = err == nil
+	if err != nil {
+		r.Error = err.Error()
+	}
+	r.TimingsMs["total"] = float64(time.Since(r.start).Microseconds()) / 1000
+	r.collect()
+
+	data, jsonErr := json.MarshalIndent(r, "", "  ")
+	if jsonErr != nil {
+		return jsonErr
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
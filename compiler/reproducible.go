@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Reproducible builds
+//
+// With -reproducible the same program, compiler and Go toolchain always make
+// the same generated Go and the same binary, byte for byte, so that anyone
+// can rebuild a binary and check it is the one they were given. The build
+// time is taken from SOURCE_DATE_EPOCH, as other reproducible builds take
+// it, and left out of the binary when that isn't set; go build is run with
+// -trimpath, so the binary holds no path of the machine it was built on,
+// without VCS stamping, and with -mod=readonly, so the modules go.sum pins
+// are the modules built. -verify-reproducible builds the program a second
+// time in a directory of its own, from the modules pinned by the first
+// build, and fails unless every file of the two builds is the same.
+//
+// Neither the compiler nor the go command it runs sends anything anywhere
+// in this mode beyond the module downloads of go mod tidy, which -sandbox
+// also turns off.
+
+// reproducible is set by -reproducible, or by -verify-reproducible.
+var reproducible bool
+
+// goBuildFlags returns the flags of go build beyond the output file.
+func goBuildFlags() []string {
+	if !reproducible {
+		return nil
+	}
+	return []string{"-trimpath", "-buildvcs=false", "-mod=readonly"}
+}
+
+// sourceDateEpoch returns the time SOURCE_DATE_EPOCH gives in seconds since
+// 1970, and whether it is set.
+func sourceDateEpoch() (time.Time, bool, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Time{}, false, nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("SOURCE_DATE_EPOCH must be a number of seconds, not %q", epoch)
+	}
+	return time.Unix(seconds, 0).UTC(), true, nil
+}
+
+// buildTime returns the build time embedded in the binary: SOURCE_DATE_EPOCH
+// when it is set, nothing in a reproducible build, and otherwise now.
+func buildTime() (string, error) {
+	t, ok, err := sourceDateEpoch()
+	switch {
+	case err != nil:
+		return "", err
+	case ok:
+		return t.Format(time.RFC3339), nil
+	case reproducible:
+		return "", nil
+	}
+	return time.Now().UTC().Format(time.RFC3339), nil
+}
+
+// zipModTime returns the modification time of the files of a serverless
+// zip: their own, unless the build is reproducible.
+func zipModTime(info fs.FileInfo) time.Time {
+	if !reproducible {
+		return info.ModTime()
+	}
+	if t, ok, _ := sourceDateEpoch(); ok {
+		return t
+	}
+	// The earliest time a zip can hold
+	return time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// verifyReproducible builds the program compiled into outputDir again, in a
+// temporary directory of the same name, and compares the two builds. The
+// second build uses the go.mod and go.sum of the first, so it builds the
+// same modules without downloading any. It returns the sha256 of the binary.
+func verifyReproducible(outputDir, binaryName string, build func(outputDir string) error) (string, error) {
+	tmp, err := os.MkdirTemp("", "simple-reproducible-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	secondDir := filepath.Join(tmp, filepath.Base(outputDir))
+	if err := os.MkdirAll(secondDir, os.ModePerm); err != nil {
+		return "", err
+	}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		data, err := os.ReadFile(filepath.Join(outputDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(secondDir, name), data, 0644); err != nil {
+			return "", err
+		}
+	}
+	if err := build(secondDir); err != nil {
+		return "", fmt.Errorf("second build: %w", err)
+	}
+
+	// Every file of the second build must be in the first, the same
+	err = filepath.WalkDir(secondDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(secondDir, path)
+		if err != nil {
+			return err
+		}
+		second, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		first, err := os.ReadFile(filepath.Join(outputDir, rel))
+		if err != nil {
+			return fmt.Errorf("%s was made by the second build only", rel)
+		}
+		if !bytes.Equal(first, second) {
+			if rel == binaryName {
+				return fmt.Errorf("the two builds made different binaries")
+			}
+			line, w, g := firstDifference(first, second)
+			return fmt.Errorf("the two builds made different %s, at line %d:\n  first:  %s\n  second: %s", rel, line, w, g)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("not reproducible: %w", err)
+	}
+
+	binary, err := os.ReadFile(filepath.Join(outputDir, binaryName))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(binary)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"github.com/sasogeek/simple/compiler/codegen"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Self test
+//
+// simple selftest compiles, builds and runs the programs of a conformance
+// suite, checking that each prints what Python prints for it. The suite is
+// built into the compiler, so it checks an installation as well as a change
+// to the compiler: it needs only the go command the compiler always needs.
+// Each program x.simple in testdata/conformance has its expected output
+// beside it in x.out, which is written by running the program with python3.
+
+//go:embed testdata/conformance
+var conformance embed.FS
+
+// conformanceDir is the directory of the suite in conformance.
+const conformanceDir = "testdata/conformance"
+
+// selftestTimeout is how long a program of the suite may run.
+const selftestTimeout = 30 * time.Second
+
+// selftest runs the conformance suite, reporting each program, and returns
+// the number that failed.
+func selftest() (int, error) {
+	programs, err := fs.Glob(conformance, path.Join(conformanceDir, "*.simple"))
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.MkdirTemp("", "simple-selftest-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(tmp)
+
+	failed := 0
+	for _, program := range programs {
+		name := strings.TrimSuffix(path.Base(program), ".simple")
+		if err := selftestProgram(program, filepath.Join(tmp, name)); err != nil {
+			fmt.Printf("FAIL %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("ok   %s\n", name)
+	}
+	fmt.Printf("%d of %d conformance programs passed\n", len(programs)-failed, len(programs))
+	return failed, nil
+}
+
+// selftestProgram compiles a program of the suite into outputDir, builds and
+// runs it, and compares what it prints with its expected output.
+func selftestProgram(program, outputDir string) error {
+	content, err := conformance.ReadFile(program)
+	if err != nil {
+		return err
+	}
+	want, err := conformance.ReadFile(strings.TrimSuffix(program, ".simple") + ".out")
+	if err != nil {
+		return err
+	}
+	got, err := buildAndRun(content, outputDir)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		line, w, g := firstDifference(want, got)
+		return fmt.Errorf("output differs at line %d:\n  want: %s\n  got:  %s", line, w, g)
+	}
+	return nil
+}
+
+// buildAndRun compiles a program into outputDir, builds it and runs it,
+// returning what it prints. It fails if the program fails or runs for
+// longer than selftestTimeout.
+func buildAndRun(content []byte, outputDir string) ([]byte, error) {
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if _, err := compile(string(content), outputDir, true); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	err := codegen.WriteBuildInfo(outputDir, codegen.BuildInfo{
+		CompilerVersion: version,
+		SourceHash:      hex.EncodeToString(sum[:]),
+		BuildTime:       time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeGoMod(outputDir); err != nil {
+		return nil, err
+	}
+	binaryName := filepath.Base(outputDir)
+	if _, err := buildGoProject(outputDir, binaryName); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), selftestTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, filepath.Join(outputDir, binaryName))
+	cmd.Dir = outputDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	got, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("still running after %v", selftestTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v\n%s", err, stderr.String())
+	}
+	return got, nil
+}
+
+// writeGoMod writes the go.mod of a program compiled into dir. It's enough
+// for programs that only use the standard library, which need no modules
+// downloaded, so go mod tidy isn't run.
+func writeGoMod(dir string) error {
+	mod := fmt.Sprintf("module %s\n\ngo %s\n", filepath.Base(dir), goVersion)
+	return os.WriteFile(filepath.Join(dir, "go.mod"), []byte(mod), 0644)
+}
+
+// firstDifference returns the number of the first line where got differs
+// from want, and the two lines, one of them empty if the other text is
+// shorter.
+func firstDifference(want, got []byte) (int, string, string) {
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := 0; ; i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g || i >= len(wantLines) || i >= len(gotLines) {
+			return i + 1, w, g
+		}
+	}
+}
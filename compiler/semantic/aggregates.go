@@ -0,0 +1,170 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// AggregateCall records a call of min(), max() or sum().
+type AggregateCall struct {
+	Name string // "min", "max" or "sum"
+	// Items is the list, dict or string whose items are aggregated, or nil
+	// for min(a, b, ...) and max(a, b, ...), which compare Arguments.
+	Items     parser.Expression
+	Arguments []parser.Expression
+	Kind      string                   // what Items is; see itemsOf
+	ItemType  parser.Type              // the type of the items, and of the result of min() and max()
+	Type      parser.Type              // the type of the result
+	Key       *parser.LambdaExpression // nil to compare the items themselves
+	Default   parser.Expression        // what min() and max() of no items return; nil to raise ValueError
+	Start     parser.Expression        // what sum() adds the items to; nil for 0
+}
+
+// handleAggregate checks a call of min(items, key=..., default=...),
+// min(a, b, ..., key=...), the same calls of max(), or sum(items, start),
+// and types its result as the items are, or as their sum is, so that
+// max(scores) of a list[int] is an int.
+func (a *Analyzer) handleAggregate(ce *parser.CallExpression) {
+	name := ce.Function.String()
+	ac := &AggregateCall{Name: name}
+	var positional []parser.Expression
+	var key parser.Expression
+	for _, arg := range ce.Arguments {
+		ka, ok := arg.(*parser.KeywordArgument)
+		if !ok {
+			positional = append(positional, arg)
+			continue
+		}
+		switch {
+		case ka.Name.Value == "key" && name != "sum":
+			key = ka.Value
+		case ka.Name.Value == "default" && name != "sum":
+			ac.Default = ka.Value
+		case ka.Name.Value == "start" && name == "sum":
+			ac.Start = ka.Value
+		default:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' is an invalid keyword argument for %s() (Line %d, Column %d)", ka.Name.Value, name, ka.Token.Line, ka.Token.Column))
+			return
+		}
+	}
+	for _, arg := range ce.Arguments {
+		a.Analyze(arg, []parser.Statement{})
+	}
+
+	if name == "sum" {
+		a.handleSum(ce, ac, positional)
+		return
+	}
+	switch {
+	case len(positional) == 0:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() takes a list, or two or more values to compare (Line %d, Column %d)", name, ce.Token.Line, ce.Token.Column))
+		return
+	case len(positional) == 1:
+		ac.Items = positional[0]
+		if !a.iterable(ce, ac) {
+			return
+		}
+	case ac.Default != nil:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() takes a default only for a list, as there are always values to compare (Line %d, Column %d)", name, ce.Token.Line, ce.Token.Column))
+		return
+	default:
+		ac.Arguments = positional
+		ac.ItemType = a.commonType(positional)
+	}
+	if ac.Default != nil && ac.Kind != "any" {
+		if t := a.InferExpressionTypes(ac.Default, false)[0]; annotationName(t) != annotationName(ac.ItemType) {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("the default of %s() is %s, but the items are %s (Line %d, Column %d)", name, annotationName(t), annotationName(ac.ItemType), ce.Token.Line, ce.Token.Column))
+			return
+		}
+	}
+	ac.Type = ac.ItemType
+
+	switch k := key.(type) {
+	case nil, *parser.NoneLiteral:
+	case *parser.LambdaExpression:
+		if len(k.Parameters) != 1 {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("the key function of %s() takes one argument, not %d (Line %d, Column %d)", name, len(k.Parameters), k.Token.Line, k.Token.Column))
+			return
+		}
+		ac.Key = k
+	default:
+		// A function such as key=len compares the result of calling it on
+		// each item, as sorted() does
+		item := &parser.Identifier{Token: lexer.Token{Type: lexer.TokenIdentifier, Literal: "item", Line: ce.Token.Line, Column: ce.Token.Column}, Value: "item"}
+		ac.Key = &parser.LambdaExpression{
+			Token:      ce.Token,
+			Parameters: []*parser.Identifier{item},
+			Body:       &parser.CallExpression{Token: ce.Token, Function: key, Arguments: []parser.Expression{item}},
+		}
+	}
+	if ac.Key != nil {
+		a.bindLambda(ac.Key, []parser.Type{ac.ItemType})
+	}
+	a.AggregateCalls[ce] = ac
+}
+
+// handleSum checks a call of sum(items) or sum(items, start), of numbers.
+// The sum is a float when the items or the start are.
+func (a *Analyzer) handleSum(ce *parser.CallExpression, ac *AggregateCall, positional []parser.Expression) {
+	switch {
+	case len(positional) == 2 && ac.Start == nil:
+		ac.Start = positional[1]
+	case len(positional) != 1:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("sum() takes a list and an optional start (%d arguments given) (Line %d, Column %d)", len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	ac.Items = positional[0]
+	if !a.iterable(ce, ac) {
+		return
+	}
+	ac.Type = ac.ItemType
+	if ac.Start != nil {
+		if start := a.InferExpressionTypes(ac.Start, false)[0]; annotationName(start) != annotationName(ac.ItemType) {
+			ac.Type = numberType(ac.ItemType.String(), start.String())
+		}
+	}
+	switch name := ac.Type.String(); name {
+	case "int", "float", "float64", "interface{}", "any":
+	default:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("sum() adds numbers, not %s (Line %d, Column %d)", annotationTypeName(name), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	a.AggregateCalls[ce] = ac
+}
+
+// iterable sets the kind and type of the items of an aggregate's one
+// argument, reporting one that has no items.
+func (a *Analyzer) iterable(ce *parser.CallExpression, ac *AggregateCall) bool {
+	t := a.InferExpressionTypes(ac.Items, false)[0]
+	ac.Kind, ac.ItemType = itemsOf(t)
+	if ac.Kind == "any" && t.String() != "interface{}" && t.String() != "any" {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() takes a list, a dict or a string, not %s (Line %d, Column %d)", ac.Name, annotationName(t), ce.Token.Line, ce.Token.Column))
+		return false
+	}
+	return true
+}
+
+// commonType returns the type values of several expressions share: their
+// type when they have the same one, float when they mix ints and floats,
+// and interface{} otherwise.
+func (a *Analyzer) commonType(values []parser.Expression) parser.Type {
+	common := a.InferExpressionTypes(values[0], false)[0]
+	for _, value := range values[1:] {
+		t := a.InferExpressionTypes(value, false)[0]
+		if annotationName(t) != annotationName(common) {
+			common = numberType(common.String(), t.String())
+		}
+	}
+	return common
+}
+
+// numberType returns float for two number types that differ, one of them
+// a float, and interface{} for any other two types that differ.
+func numberType(x, y string) parser.Type {
+	number := func(name string) bool { return name == "int" || name == "float" || name == "float64" }
+	if number(x) && number(y) {
+		return &parser.BasicType{Name: "float"}
+	}
+	return &parser.BasicType{Name: "interface{}"}
+}
@@ -0,0 +1,277 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"regexp"
+	"strings"
+)
+
+// Type annotations
+//
+// A parameter or the result of a function may be annotated with its type,
+// as in def add(a: int, b: int) -> int:, to pin the type instead of leaving
+// it to inference. An annotation is authoritative: an annotated parameter
+// keeps its type however the function is called and whatever its body does
+// with it, and an argument, return value or assignment of another type is
+// an error. The types are Python's: int, float, str, bool, any, None for a
+// function that returns nothing, list[T], dict[K, V], set[T], tuple[T, U]
+// and the classes of the program, and callable for a function that takes
+// no arguments and returns nothing. An int is accepted for a float only as a
+// number literal, since Go converts no variable from one to the other.
+
+// signature is the types a function's annotations give, nil where there is
+// no annotation.
+type signature struct {
+	params []parser.Type
+	result parser.Type
+}
+
+// annotations returns the types of the annotations of a function, reporting
+// annotations that name no type. A generator's result isn't annotated with
+// the type of the values it yields, so it is left to inference.
+func (a *Analyzer) annotations(fl *parser.FunctionLiteral) *signature {
+	if sig, ok := a.signatures[fl]; ok {
+		return sig
+	}
+	sig := &signature{params: make([]parser.Type, len(fl.Parameters))}
+	for i, ta := range fl.Annotations {
+		if ta != nil && i < len(sig.params) {
+			sig.params[i] = a.annotationType(ta, false)
+		}
+	}
+	if fl.Result != nil && !isGenerator(fl.Body) {
+		sig.result = a.annotationType(fl.Result, true)
+	}
+	a.signatures[fl] = sig
+	return sig
+}
+
+// ParameterAnnotation returns the annotated type of the parameter of a
+// function defined with def that the argument at position i of a call is
+// given to, or nil. The function may be one of an imported module.
+func (a *Analyzer) ParameterAnnotation(ft *parser.FunctionType, i int) parser.Type {
+	fl, ok := a.functions[ft]
+	if !ok {
+		if params := a.moduleAnnotations[ft]; i < len(params) {
+			return params[i]
+		}
+		return nil
+	}
+	if sig := a.annotations(fl); i < len(sig.params) {
+		return sig.params[i]
+	}
+	return nil
+}
+
+// ResultAnnotation returns the annotated type of the result of a function,
+// or nil.
+func (a *Analyzer) ResultAnnotation(fl *parser.FunctionLiteral) parser.Type {
+	if fl == nil {
+		return nil
+	}
+	return a.annotations(fl).result
+}
+
+// annotationType returns the type an annotation names, or nil after
+// reporting one that names no type. None annotates only a result.
+func (a *Analyzer) annotationType(ta *parser.TypeAnnotation, result bool) parser.Type {
+	arity := map[string]int{"list": 1, "set": 1, "dict": 2}
+	if n, ok := arity[ta.Name]; ok && len(ta.Arguments) != n && len(ta.Arguments) != 0 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s[] takes %d type(s) in an annotation, not %d (Line %d, Column %d)", ta.Name, n, len(ta.Arguments), ta.Token.Line, ta.Token.Column))
+		return nil
+	}
+	if _, ok := arity[ta.Name]; !ok && ta.Name != "tuple" && len(ta.Arguments) > 0 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' takes no types in brackets in an annotation (Line %d, Column %d)", ta.Name, ta.Token.Line, ta.Token.Column))
+		return nil
+	}
+	args := []parser.Type{}
+	for _, arg := range ta.Arguments {
+		t := a.annotationType(arg, false)
+		if t == nil {
+			return nil
+		}
+		args = append(args, t)
+	}
+
+	switch ta.Name {
+	case "int", "bool", "float":
+		return &parser.BasicType{Name: ta.Name}
+	case "str":
+		return &parser.BasicType{Name: "string"}
+	case "any", "object":
+		return &parser.BasicType{Name: "interface{}"}
+	case "callable":
+		return &parser.FunctionType{ReturnTypes: []parser.Type{&parser.BasicType{Name: "void"}}}
+	case "None":
+		if result {
+			return &parser.BasicType{Name: "void"}
+		}
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("only the result of a function can be annotated None (Line %d, Column %d)", ta.Token.Line, ta.Token.Column))
+		return nil
+	case "list", "dict", "set":
+		for len(args) < arity[ta.Name] {
+			args = append(args, &parser.BasicType{Name: "interface{}"})
+		}
+		switch ta.Name {
+		case "list":
+			return &parser.BasicType{Name: "[]" + args[0].String()}
+		case "dict":
+			return &parser.BasicType{Name: fmt.Sprintf("map[%s]%s", args[0], args[1])}
+		}
+		return setTypeOf(args[0])
+	case "tuple":
+		if len(args) < 2 {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("tuple[] takes the type of each item in an annotation, as in tuple[int, str] (Line %d, Column %d)", ta.Token.Line, ta.Token.Column))
+			return nil
+		}
+		return a.tupleOfTypes(args)
+	}
+	if class, ok := a.Classes[ta.Name]; ok {
+		return class.InstanceType()
+	}
+	a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("unknown type '%s' in annotation; a class must be defined above the function it annotates (Line %d, Column %d)", ta.Name, ta.Token.Line, ta.Token.Column))
+	return nil
+}
+
+// floatName matches float in the name of a type, which is Go's float64.
+var floatName = regexp.MustCompile(`\bfloat\b`)
+
+// accepts reports whether value, of type got, may be given where an
+// annotation asks for want: a value of the same type, an int literal for a
+// float, or a value whose type is only known when the program runs.
+func accepts(want, got parser.Type, value parser.Expression) bool {
+	w, g := floatName.ReplaceAllString(storedType(want).String(), "float64"), floatName.ReplaceAllString(storedType(got).String(), "float64")
+	switch {
+	case w == g, w == "interface{}", g == "interface{}":
+		return true
+	case w == "float64" && g == "int":
+		if pe, ok := value.(*parser.PrefixExpression); ok && pe != nil && pe.Operator == "-" {
+			value = pe.Right
+		}
+		_, literal := value.(*parser.IntegerLiteral)
+		return literal
+	}
+	// An empty [] or {} is of either kind
+	return strings.Contains(g, "interface{}") && typeKind(want) == typeKind(got)
+}
+
+// annotationName returns the name of a type as an annotation writes it.
+func annotationName(t parser.Type) string {
+	return annotationTypeName(t.String())
+}
+
+// annotationTypeName returns the name of a Go type as an annotation writes
+// it, as in list[str] for []string.
+func annotationTypeName(name string) string {
+	switch name {
+	case "void":
+		return "None"
+	case "interface{}", "any":
+		return "any"
+	case "string":
+		return "str"
+	case "float64", "float":
+		return "float"
+	case "func()":
+		return "callable"
+	}
+	switch {
+	case strings.HasPrefix(name, "[]"):
+		return "list[" + annotationTypeName(name[2:]) + "]"
+	case strings.HasPrefix(name, "map["):
+		// The key ends at the bracket that closes map[
+		depth := 0
+		for i := 3; i < len(name); i++ {
+			switch name[i] {
+			case '[':
+				depth++
+			case ']':
+				depth--
+			}
+			if depth == 0 {
+				key, value := annotationTypeName(name[4:i]), name[i+1:]
+				if value == "struct{}" {
+					return "set[" + key + "]"
+				}
+				return "dict[" + key + ", " + annotationTypeName(value) + "]"
+			}
+		}
+	}
+	return strings.TrimPrefix(name, "*")
+}
+
+// checkArgumentAnnotation reports an argument of a call of a function
+// defined with def whose type conflicts with its parameter's annotation. It
+// returns the annotation, or nil when the parameter has none.
+func (a *Analyzer) checkArgumentAnnotation(ce *parser.CallExpression, ft *parser.FunctionType, i int, arg parser.Expression, argType parser.Type) parser.Type {
+	want := a.ParameterAnnotation(ft, i)
+	if want != nil && !accepts(want, argType, arg) {
+		a.reportArgument(ce, ce.Function.String(), ft.Parameters[i].Value, want, argType)
+	}
+	return want
+}
+
+// checkMethodArguments reports arguments of a call of a method or __init__,
+// given for the parameters after self, whose types conflict with their
+// parameters' annotations.
+func (a *Analyzer) checkMethodArguments(ce *parser.CallExpression, name string, fl *parser.FunctionLiteral, args []parser.Expression) {
+	sig := a.annotations(fl)
+	for i, arg := range args {
+		want := sig.params[i+1]
+		if want == nil {
+			continue
+		}
+		if argType := a.InferExpressionTypes(arg, false)[0]; !accepts(want, argType, arg) {
+			a.reportArgument(ce, name, fl.Parameters[i+1].Value, want, argType)
+		}
+	}
+}
+
+// annotateParameters gives the parameters after self of a method or
+// __init__ the types they are annotated with, and reports which are.
+func (a *Analyzer) annotateParameters(fl *parser.FunctionLiteral, paramTypes []parser.Type) []bool {
+	sig := a.annotations(fl)
+	annotated := make([]bool, len(paramTypes))
+	for i := range paramTypes {
+		if t := sig.params[i+1]; t != nil {
+			paramTypes[i], annotated[i] = t, true
+		}
+	}
+	return annotated
+}
+
+// reportArgument reports an argument of a type its parameter's annotation
+// doesn't accept.
+func (a *Analyzer) reportArgument(ce *parser.CallExpression, function, param string, want, got parser.Type) {
+	a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("argument '%s' to %s() must be %s, not %s (Line %d, Column %d)", param, function, annotationName(want), annotationName(got), ce.Token.Line, ce.Token.Column))
+}
+
+// checkReturnAnnotation reports return statements of a function whose
+// values conflict with its annotated result.
+func (a *Analyzer) checkReturnAnnotation(fl *parser.FunctionLiteral, result parser.Type, funcTable *SymbolTable) {
+	prevTable := a.CurrentTable
+	a.CurrentTable = funcTable
+	defer func() { a.CurrentTable = prevTable }()
+
+	parser.Inspect(fl.Body, func(n parser.Node) bool {
+		switch n := n.(type) {
+		case *parser.FunctionLiteral, *parser.LambdaExpression:
+			// Their return statements are their own
+			return false
+		case *parser.ReturnStatement:
+			got := parser.Type(&parser.BasicType{Name: "void"})
+			if n.ReturnValue != nil {
+				got = a.InferExpressionTypes(n.ReturnValue, false)[0]
+			}
+			conflicts := !accepts(result, got, n.ReturnValue)
+			if result.String() == "void" || got.String() == "void" {
+				conflicts = result.String() != got.String()
+			}
+			if conflicts {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() is annotated to return %s but returns %s (Line %d, Column %d)", fl.Name.Value, annotationName(result), annotationName(got), n.Token.Line, n.Token.Column))
+			}
+		}
+		return true
+	})
+}
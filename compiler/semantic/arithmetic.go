@@ -0,0 +1,55 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// Powers and floor division
+//
+// ** raises a number to a power and // divides, rounding down, as in Python.
+// Of two ints, ** makes an int unless the exponent is a negative number, as
+// in 2 ** -1, and // makes an int rounded towards minus infinity, where Go's
+// / rounds towards zero. Either makes a float of a float.
+
+// ArithmeticType returns the type of a ** or // of values of the types given.
+func (a *Analyzer) ArithmeticType(ie *parser.InfixExpression, leftType, rightType parser.Type) parser.Type {
+	floats := map[string]bool{"float": true, "float64": true}
+	switch {
+	case floats[leftType.String()] || floats[rightType.String()]:
+		return &parser.BasicType{Name: "float64"}
+	case leftType.String() == "int" && rightType.String() == "int":
+		if ie.Operator == "**" && isNegativeNumber(ie.Right) {
+			return &parser.BasicType{Name: "float64"}
+		}
+		return &parser.BasicType{Name: "int"}
+	}
+	return &parser.BasicType{Name: "interface{}"}
+}
+
+// isNegativeNumber reports whether expr is a number literal with a minus.
+func isNegativeNumber(expr parser.Expression) bool {
+	pe, ok := expr.(*parser.PrefixExpression)
+	if !ok || pe == nil || pe.Operator != "-" {
+		return false
+	}
+	_, ok = pe.Right.(*parser.IntegerLiteral)
+	return ok
+}
+
+// handleArithmeticOperators analyzes ** and //, reporting operands that
+// aren't numbers.
+func (a *Analyzer) handleArithmeticOperators(ie *parser.InfixExpression, remainingStatements []parser.Statement) {
+	a.Analyze(ie.Left, remainingStatements)
+	a.Analyze(ie.Right, remainingStatements)
+	leftType := a.InferExpressionTypes(ie.Left, false)[0]
+	rightType := a.InferExpressionTypes(ie.Right, false)[0]
+	for _, t := range []parser.Type{leftType, rightType} {
+		switch t.String() {
+		case "int", "float", "float64", "interface{}", "any":
+		default:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("unsupported operand type(s) for %s: '%s' and '%s' (Line %d, Column %d)", ie.Operator, typeKind(leftType), typeKind(rightType), ie.Token.Line, ie.Token.Column))
+			return
+		}
+	}
+}
@@ -0,0 +1,681 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// Class records a class defined in Simple. It is generated as a Go struct
+// with a field for each attribute, a NewX function that makes an instance
+// and runs __init__, and a Go method for each of its methods. A class that
+// inherits from another embeds the other's struct.
+type Class struct {
+	Name       string
+	Parent     *Class // nil when the class inherits from no other
+	Fields     []*Field
+	Methods    []*Method
+	Defaults   []*parser.AssignmentStatement // fields given a value in the class body
+	Init       *parser.FunctionLiteral       // nil when the class has no __init__
+	ParamTypes []parser.Type                 // types of the parameters of __init__ after self
+	InitTable  *SymbolTable
+	Named      *types.Named
+	analyzed   bool
+	analyzing  bool
+}
+
+// Field is an attribute of a class, which becomes a field of its struct.
+type Field struct {
+	Name string
+	Type parser.Type
+}
+
+// Method is a method of a class other than __init__. Its type is defined in
+// the global table as Class.method, the name of its symbol table, and its
+// parameters after self take their types from the first call.
+type Method struct {
+	Class     *Class // the class that defines the method
+	Fn        *parser.FunctionLiteral
+	Type      *parser.FunctionType
+	Table     *SymbolTable
+	analyzed  bool
+	analyzing bool
+}
+
+// Name returns the name of a method.
+func (m *Method) Name() string {
+	return m.Fn.Name.Value
+}
+
+// Params returns the parameters of a method after self.
+func (m *Method) Params() []*parser.Identifier {
+	return m.Fn.Parameters[1:]
+}
+
+// Method returns the method of a class with the given name, which may be
+// inherited, or nil. A method of the class overrides one of its parents.
+func (c *Class) Method(name string) *Method {
+	for class := c; class != nil; class = class.Parent {
+		for _, method := range class.Methods {
+			if method.Name() == name {
+				return method
+			}
+		}
+	}
+	return nil
+}
+
+// Field returns the field of a class with the given name, which may be
+// inherited, or nil.
+func (c *Class) Field(name string) *Field {
+	field, _ := c.lookupField(name)
+	return field
+}
+
+// lookupField returns the field of a class with the given name and the
+// class that declares it, the class itself or one of its parents.
+func (c *Class) lookupField(name string) (*Field, *Class) {
+	for class := c; class != nil; class = class.Parent {
+		for _, field := range class.Fields {
+			if field.Name == name {
+				return field, class
+			}
+		}
+	}
+	return nil, nil
+}
+
+// InitClass returns the class whose __init__ constructs instances of a
+// class: the class itself, or the nearest parent that defines __init__.
+// It returns nil when none does.
+func (c *Class) InitClass() *Class {
+	for class := c; class != nil; class = class.Parent {
+		if class.Init != nil {
+			return class
+		}
+	}
+	return nil
+}
+
+// Params returns the parameters of __init__ after self, which are the
+// arguments of the class's constructor.
+func (c *Class) Params() []*parser.Identifier {
+	if c.Init == nil || len(c.Init.Parameters) == 0 {
+		return nil
+	}
+	return c.Init.Parameters[1:]
+}
+
+// InstanceType returns the type of the instances of a class, which are
+// pointers to its struct.
+func (c *Class) InstanceType() parser.Type {
+	return &parser.BasicType{Name: "*" + c.Name}
+}
+
+// storedType returns the type a value of type t is kept as in a field: a
+// float as a float64, and a value of unknown type as an interface{}.
+func storedType(t parser.Type) parser.Type {
+	switch t.String() {
+	case "float", "untyped float":
+		return &parser.BasicType{Name: "float64"}
+	case "", "void", "any":
+		return &parser.BasicType{Name: "interface{}"}
+	}
+	return t
+}
+
+// ClassOf returns the class whose instances have type t, if any.
+func (a *Analyzer) ClassOf(t parser.Type) (*Class, bool) {
+	if t == nil || !strings.HasPrefix(t.String(), "*") {
+		return nil, false
+	}
+	class, ok := a.Classes[strings.TrimPrefix(t.String(), "*")]
+	return class, ok
+}
+
+// IsSuperCall reports whether a call is super(), whose methods are those of
+// the parent of the class whose method makes the call.
+func IsSuperCall(ce *parser.CallExpression) bool {
+	ident, ok := ce.Function.(*parser.Identifier)
+	return ok && ident.Value == "super"
+}
+
+// superClass returns the class a super() call stands for: the parent of
+// the class whose method is being analyzed, or the one recorded for it.
+func (a *Analyzer) superClass(ce *parser.CallExpression) (*Class, bool) {
+	if parent, ok := a.SuperCalls[ce]; ok {
+		return parent, true
+	}
+	if a.currentClass == nil || a.currentClass.Parent == nil {
+		return nil, false
+	}
+	return a.currentClass.Parent, true
+}
+
+// handleSuperCall checks a super() call and records the class it stands
+// for. In a class that inherits from no other, super() is object, which
+// only has an __init__ that does nothing; see handleObjectCall.
+func (a *Analyzer) handleSuperCall(ce *parser.CallExpression) {
+	switch {
+	case len(ce.Arguments) > 0:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("super() takes no arguments in Simple (Line %d, Column %d)", ce.Token.Line, ce.Token.Column))
+	case a.currentClass == nil:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("super() can only be used in a method (Line %d, Column %d)", ce.Token.Line, ce.Token.Column))
+	case a.currentClass.Parent != nil:
+		a.SuperCalls[ce] = a.currentClass.Parent
+	}
+}
+
+// handleObjectCall checks a call of a method of super() in a class that
+// inherits from no other, where only super().__init__() can be called.
+func (a *Analyzer) handleObjectCall(ce *parser.CallExpression, se *parser.SelectorExpression) {
+	if a.currentClass == nil {
+		return
+	}
+	switch {
+	case se.Selector.Value != "__init__":
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'super' object has no attribute '%s' (Line %d, Column %d)", se.Selector.Value, se.Token.Line, se.Token.Column))
+	case len(ce.Arguments) > 0:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("object.__init__() takes exactly one argument (the instance to initialize) (Line %d, Column %d)", ce.Token.Line, ce.Token.Column))
+	}
+}
+
+// ConstructorOf returns the class a call constructs, as Point(1, 2) does.
+func (a *Analyzer) ConstructorOf(ce *parser.CallExpression) (*Class, bool) {
+	ident, ok := ce.Function.(*parser.Identifier)
+	if !ok {
+		return nil, false
+	}
+	symbol, ok := a.CurrentTable.Resolve(ident.Value)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := symbol.Type.(*parser.StructType); !ok {
+		return nil, false
+	}
+	class, ok := a.Classes[ident.Value]
+	return class, ok
+}
+
+// MethodOf returns the class and method an attribute of a class instance
+// names, as p.move does.
+func (a *Analyzer) MethodOf(se *parser.SelectorExpression) (*Class, *Method, bool) {
+	class, ok := a.ClassOf(a.InferExpressionTypes(se.Left, false)[0])
+	if !ok {
+		return nil, nil, false
+	}
+	method := class.Method(se.Selector.Value)
+	return class, method, method != nil
+}
+
+// FieldType returns the type of a field of a class instance, as in p.x.
+func (a *Analyzer) FieldType(se *parser.SelectorExpression) (parser.Type, bool) {
+	class, ok := a.ClassOf(a.InferExpressionTypes(se.Left, false)[0])
+	if !ok {
+		return nil, false
+	}
+	if field := class.Field(se.Selector.Value); field != nil {
+		return field.Type, true
+	}
+	return nil, false
+}
+
+// handleClassStatement defines a class. The values of the fields defined in
+// the class body are analyzed here, but __init__ is analyzed when the class
+// is first constructed, and each method when it is first called, so that
+// their parameters take the types of the first call's arguments.
+func (a *Analyzer) handleClassStatement(cs *parser.ClassStatement) {
+	if a.CurrentTable != a.GlobalTable {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("classes can only be defined at the top level of a file (Line %d, Column %d)", cs.Token.Line, cs.Token.Column))
+		return
+	}
+	if _, exists := a.Classes[cs.Name.Value]; exists || a.exceptionClasses[cs.Name.Value] != "" {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("class '%s' is already defined (Line %d, Column %d)", cs.Name.Value, cs.Token.Line, cs.Token.Column))
+		return
+	}
+	if len(cs.Bases) == 1 && a.IsException(cs.Bases[0].Value) {
+		a.handleExceptionClass(cs)
+		return
+	}
+
+	class := &Class{
+		Name:  cs.Name.Value,
+		Named: types.NewNamed(types.NewTypeName(token.NoPos, nil, cs.Name.Value, nil), types.NewStruct(nil, nil), nil),
+	}
+	for i, base := range cs.Bases {
+		parent, ok := a.Classes[base.Value]
+		switch {
+		case i > 0:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("class '%s' can only inherit from one class (Line %d, Column %d)", class.Name, base.Token.Line, base.Token.Column))
+		case base.Value == "object":
+			// Every class is an object, as in Python
+		case !ok:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("name '%s' is not defined; a class can only inherit from a class defined above it (Line %d, Column %d)", base.Value, base.Token.Line, base.Token.Column))
+		default:
+			class.Parent = parent
+		}
+	}
+	a.Classes[class.Name] = class
+	a.CurrentTable.Define(class.Name, &Symbol{
+		Name:   class.Name,
+		Type:   &parser.StructType{Name: class.Name},
+		Scope:  a.CurrentTable.Name,
+		GoType: class.Named,
+	})
+
+	for _, stmt := range cs.Body.Statements {
+		switch s := stmt.(type) {
+		case *parser.FunctionLiteral:
+			if len(s.Decorators) > 0 {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("methods can't be decorated in Simple; decorate a function defined with def outside the class (Line %d, Column %d)", s.Token.Line, s.Token.Column))
+				continue
+			}
+			if method := class.Method(s.Name.Value); (s.Name.Value == "__init__" && class.Init != nil) || (method != nil && method.Class == class) {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("class '%s' defines %s more than once (Line %d, Column %d)", class.Name, s.Name.Value, s.Token.Line, s.Token.Column))
+				continue
+			}
+			if len(s.Parameters) == 0 || s.Parameters[0].Value != "self" {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("the first parameter of %s must be self (Line %d, Column %d)", s.Name.Value, s.Token.Line, s.Token.Column))
+				continue
+			}
+			if s.Name.Value == "__init__" {
+				class.Init = s
+				continue
+			}
+			if class.Field(s.Name.Value) != nil {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("class '%s' can't have a field and a method both named '%s' (Line %d, Column %d)", class.Name, s.Name.Value, s.Token.Line, s.Token.Column))
+				continue
+			}
+			if overridden := class.Method(s.Name.Value); overridden != nil {
+				a.checkOverride(class, overridden)
+			}
+			a.defineMethod(class, s)
+		case *parser.AssignmentStatement:
+			target, ok := s.Left[0].(*parser.Identifier)
+			if len(s.Left) != 1 || !ok || s.Operator != "" {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("a class body can only give fields a value, as in count = 0 (Line %d, Column %d)", s.Token.Line, s.Token.Column))
+				continue
+			}
+			field, owner := class.lookupField(target.Value)
+			if owner == class {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("field '%s' of class '%s' is defined more than once (Line %d, Column %d)", target.Value, class.Name, s.Token.Line, s.Token.Column))
+				continue
+			}
+			if class.Method(target.Value) != nil {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("class '%s' can't have a field and a method both named '%s' (Line %d, Column %d)", class.Name, target.Value, s.Token.Line, s.Token.Column))
+				continue
+			}
+			a.Analyze(s.Value, []parser.Statement{})
+			valueType := storedType(a.InferExpressionTypes(s.Value, false)[0])
+			if field != nil {
+				// A new default for an inherited field
+				a.widenField(field, owner, valueType)
+			} else {
+				class.Fields = append(class.Fields, &Field{Name: target.Value, Type: valueType})
+			}
+			class.Defaults = append(class.Defaults, s)
+		case *parser.PassStatement:
+			// The body of a class with nothing in it
+		case *parser.ExpressionStatement:
+			// A docstring, or a line the parser left empty
+			if s == nil {
+				continue
+			}
+			if _, ok := s.Expression.(*parser.StringLiteral); !ok && s.Expression != nil {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("a class body can only define fields and methods (Line %d, Column %d)", s.Token.Line, s.Token.Column))
+			}
+		default:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("a class body can only define fields and methods (Line %d, Column %d)", cs.Token.Line, cs.Token.Column))
+		}
+	}
+	a.updateStruct(class)
+}
+
+// checkOverride warns when a method overrides one that the parent's own
+// methods call on self. Go methods aren't virtual, so those calls still
+// reach the parent's method rather than the override.
+func (a *Analyzer) checkOverride(class *Class, overridden *Method) {
+	for parent := class.Parent; parent != nil; parent = parent.Parent {
+		bodies := []*parser.FunctionLiteral{}
+		if parent.Init != nil {
+			bodies = append(bodies, parent.Init)
+		}
+		for _, method := range parent.Methods {
+			bodies = append(bodies, method.Fn)
+		}
+		for _, fl := range bodies {
+			calls := false
+			parser.Inspect(fl.Body, func(n parser.Node) bool {
+				if se, ok := n.(*parser.SelectorExpression); ok && se.Left.String() == "self" && se.Selector.Value == overridden.Name() {
+					calls = true
+				}
+				return !calls
+			})
+			if calls {
+				a.warnings = append(a.warnings, fmt.Sprintf("%s.%s overrides %s.%s, but %s.%s calls self.%s, which still calls %s.%s", class.Name, overridden.Name(), overridden.Class.Name, overridden.Name(), parent.Name, fl.Name.Value, overridden.Name(), overridden.Class.Name, overridden.Name()))
+				return
+			}
+		}
+	}
+}
+
+// handleConstructorCall checks the arguments of a call that constructs a
+// class against the parameters of its __init__, which may be inherited.
+func (a *Analyzer) handleConstructorCall(ce *parser.CallExpression, class *Class) {
+	if class.InitClass() == nil && len(ce.Arguments) > 0 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() takes no arguments (Line %d, Column %d)", class.Name, ce.Token.Line, ce.Token.Column))
+		return
+	}
+	a.handleInitCall(ce, class)
+}
+
+// handleInitCall checks the arguments of a call of the __init__ of a class,
+// as a constructor or super().__init__ calls it. The first call decides the
+// types of the parameters.
+func (a *Analyzer) handleInitCall(ce *parser.CallExpression, class *Class) {
+	initClass := class.InitClass()
+	if initClass == nil {
+		if len(ce.Arguments) > 0 {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("object.__init__() takes exactly one argument (the instance to initialize) (Line %d, Column %d)", ce.Token.Line, ce.Token.Column))
+		}
+		return
+	}
+	args, ok := a.bindArguments(ce, initClass.Name+".__init__", initClass.Params())
+	if !ok {
+		return
+	}
+	a.checkMethodArguments(ce, initClass.Name, initClass.Init, args)
+
+	if !initClass.analyzed && !initClass.analyzing {
+		argTypes := []parser.Type{}
+		for _, arg := range args {
+			argTypes = append(argTypes, storedType(a.InferExpressionTypes(arg, false)[0]))
+		}
+		a.analyzeInit(initClass, argTypes)
+	}
+}
+
+// defineMethod defines the type of a method in the global table, as
+// Class.method, with parameters of unknown type and no result until the
+// method is analyzed.
+func (a *Analyzer) defineMethod(class *Class, fl *parser.FunctionLiteral) {
+	method := &Method{Class: class, Fn: fl, Type: &parser.FunctionType{ReturnTypes: []parser.Type{&parser.BasicType{Name: "void"}}}}
+	for _, param := range method.Params() {
+		method.Type.Parameters = append(method.Type.Parameters, *param)
+		method.Type.ParameterTypes = append(method.Type.ParameterTypes, &parser.BasicType{Name: "interface{}"})
+	}
+	class.Methods = append(class.Methods, method)
+	name := class.Name + "." + method.Name()
+	a.GlobalTable.Define(name, &Symbol{Name: name, Type: method.Type, Scope: a.GlobalTable.Name})
+}
+
+// handleMethodCall checks the arguments of a call of a method, as in
+// p.move(1, 2), analyzing the method on its first call. A method with
+// several results gives a tuple, as a function does.
+func (a *Analyzer) handleMethodCall(ce *parser.CallExpression, method *Method) {
+	args, ok := a.bindArguments(ce, method.Class.Name+"."+method.Name(), method.Params())
+	if !ok {
+		return
+	}
+	a.checkMethodArguments(ce, method.Class.Name+"."+method.Name(), method.Fn, args)
+	if !method.analyzed && !method.analyzing {
+		argTypes := []parser.Type{}
+		for _, arg := range args {
+			argTypes = append(argTypes, storedType(a.InferExpressionTypes(arg, false)[0]))
+		}
+		a.analyzeMethod(method, argTypes)
+	}
+	if len(method.Type.ReturnTypes) > 1 {
+		a.ResultTuples[ce] = a.tupleOfTypes(method.Type.ReturnTypes)
+	}
+}
+
+// bindArguments checks the arguments of a call of a method, __init__ or
+// another, against the parameters after self, and puts keyword arguments in
+// the order of the parameters. The arguments of the call are replaced by
+// one for each parameter. Errors are reported as Python reports them.
+func (a *Analyzer) bindArguments(ce *parser.CallExpression, name string, params []*parser.Identifier) ([]parser.Expression, bool) {
+	args := make([]parser.Expression, len(params))
+	positional := 0
+	for _, arg := range ce.Arguments {
+		if _, ok := arg.(*parser.KeywordArgument); !ok {
+			positional++
+		}
+	}
+	for i, arg := range ce.Arguments {
+		ka, isKeyword := arg.(*parser.KeywordArgument)
+		position := i
+		if isKeyword {
+			arg = ka.Value
+			position = -1
+			for j, param := range params {
+				if param.Value == ka.Name.Value {
+					position = j
+				}
+			}
+			if position < 0 {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() got an unexpected keyword argument '%s' (Line %d, Column %d)", name, ka.Name.Value, ce.Token.Line, ce.Token.Column))
+				return nil, false
+			}
+		}
+		if position >= len(params) {
+			// Counted with self, as Python does
+			takes := fmt.Sprintf("%d positional arguments", len(params)+1)
+			if len(params) == 0 {
+				takes = "1 positional argument"
+			}
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() takes %s but %d were given (Line %d, Column %d)", name, takes, positional+1, ce.Token.Line, ce.Token.Column))
+			return nil, false
+		}
+		if args[position] != nil {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() got multiple values for argument '%s' (Line %d, Column %d)", name, params[position].Value, ce.Token.Line, ce.Token.Column))
+			return nil, false
+		}
+		a.Analyze(arg, []parser.Statement{})
+		args[position] = arg
+	}
+	missing := []string{}
+	for i, arg := range args {
+		if arg == nil {
+			missing = append(missing, "'"+params[i].Value+"'")
+		}
+	}
+	switch {
+	case len(missing) == 1:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() missing 1 required positional argument: %s (Line %d, Column %d)", name, missing[0], ce.Token.Line, ce.Token.Column))
+		return nil, false
+	case len(missing) > 1:
+		names := strings.Join(missing[:len(missing)-1], ", ") + " and " + missing[len(missing)-1]
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() missing %d required positional arguments: %s (Line %d, Column %d)", name, len(missing), names, ce.Token.Line, ce.Token.Column))
+		return nil, false
+	}
+	ce.Arguments = args
+	return args, true
+}
+
+// analyzeInit analyzes the __init__ of a class, whose parameters have the
+// given types. The attributes it assigns to self become the class's fields.
+func (a *Analyzer) analyzeInit(class *Class, paramTypes []parser.Type) {
+	prevClass := a.currentClass
+	a.currentClass = class
+	defer func() { a.currentClass = prevClass }()
+	class.analyzing = true
+	class.ParamTypes = paramTypes
+	class.InitTable = NewSymbolTable(a.GlobalTable, class.Name+".__init__")
+	a.SymbolTables.Tables[class.InitTable.Name] = class.InitTable
+	class.InitTable.Define("self", &Symbol{Name: "self", Type: class.InstanceType(), Scope: class.InitTable.Name})
+	var annotated []bool
+	if class.Init != nil {
+		annotated = a.annotateParameters(class.Init, paramTypes)
+	}
+	for i, param := range class.Params() {
+		class.InitTable.Define(param.Value, &Symbol{
+			Name:      param.Value,
+			Type:      paramTypes[i],
+			Scope:     class.InitTable.Name,
+			GoType:    a.GetGoTypeFromParserType(paramTypes[i]),
+			Annotated: annotated[i],
+		})
+	}
+
+	if class.Init != nil {
+		prevTable := a.CurrentTable
+		a.CurrentTable = class.InitTable
+		a.checkLoopControl(class.Init.Body.Statements, false)
+		a.Analyze(class.Init.Body, []parser.Statement{class.Init.Body})
+		a.CurrentTable = prevTable
+
+		parser.Inspect(class.Init.Body, func(n parser.Node) bool {
+			if rs, ok := n.(*parser.ReturnStatement); ok && rs.ReturnValue != nil {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("__init__() should return None (Line %d, Column %d)", rs.Token.Line, rs.Token.Column))
+			}
+			return true
+		})
+	}
+
+	class.analyzing = false
+	class.analyzed = true
+}
+
+// analyzeMethod analyzes a method of a class, whose parameters after self
+// have the given types, and infers its results from its return statements.
+func (a *Analyzer) analyzeMethod(method *Method, paramTypes []parser.Type) {
+	class := method.Class
+	prevClass := a.currentClass
+	a.currentClass = class
+	defer func() { a.currentClass = prevClass }()
+	method.analyzing = true
+	annotated := a.annotateParameters(method.Fn, paramTypes)
+	method.Type.ParameterTypes = paramTypes
+	method.Table = NewSymbolTable(a.GlobalTable, class.Name+"."+method.Name())
+	a.SymbolTables.Tables[method.Table.Name] = method.Table
+	method.Table.Define("self", &Symbol{Name: "self", Type: class.InstanceType(), Scope: method.Table.Name})
+	for i, param := range method.Params() {
+		method.Table.Define(param.Value, &Symbol{
+			Name:      param.Value,
+			Type:      paramTypes[i],
+			Scope:     method.Table.Name,
+			GoType:    a.GetGoTypeFromParserType(paramTypes[i]),
+			Annotated: annotated[i],
+		})
+	}
+
+	prevTable := a.CurrentTable
+	a.CurrentTable = method.Table
+	a.checkLoopControl(method.Fn.Body.Statements, false)
+	a.Analyze(method.Fn.Body, []parser.Statement{method.Fn.Body})
+	a.CurrentTable = prevTable
+	if result := a.annotations(method.Fn).result; result != nil {
+		a.checkReturnAnnotation(method.Fn, result, method.Table)
+		method.Type.ReturnTypes = []parser.Type{result}
+	} else {
+		method.Type.ReturnTypes = a.InferFunctionReturnType(method.Fn.Body, method.Table)
+	}
+
+	method.analyzing = false
+	method.analyzed = true
+}
+
+// finishClasses analyzes the __init__ and methods of a class that are never
+// called, with parameters of unknown type.
+func (a *Analyzer) finishClasses(program *parser.Program) {
+	for _, stmt := range program.Statements {
+		cs, ok := stmt.(*parser.ClassStatement)
+		if !ok {
+			continue
+		}
+		class := a.Classes[cs.Name.Value]
+		if class == nil {
+			continue
+		}
+		if class.Init != nil && !class.analyzed {
+			a.analyzeInit(class, unknownTypes(len(class.Params())))
+		}
+		for _, method := range class.Methods {
+			if !method.analyzed {
+				a.analyzeMethod(method, unknownTypes(len(method.Params())))
+			}
+		}
+	}
+}
+
+// unknownTypes returns n interface{} types, for parameters no call has
+// given a type.
+func unknownTypes(n int) []parser.Type {
+	ts := []parser.Type{}
+	for i := 0; i < n; i++ {
+		ts = append(ts, &parser.BasicType{Name: "interface{}"})
+	}
+	return ts
+}
+
+// assignField handles an assignment to an attribute of a class instance.
+// An attribute that __init__ assigns becomes a field, one assigned values
+// of different types becomes an interface{}, and anywhere else only
+// existing fields can be assigned, as the struct can't grow.
+func (a *Analyzer) assignField(se *parser.SelectorExpression, valueType parser.Type, line, column int) {
+	class, ok := a.ClassOf(a.InferExpressionTypes(se.Left, false)[0])
+	if !ok {
+		return
+	}
+	valueType = storedType(valueType)
+	if field, owner := class.lookupField(se.Selector.Value); field != nil {
+		a.widenField(field, owner, valueType)
+		return
+	}
+	if class.Method(se.Selector.Value) != nil {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("class '%s' can't have a field and a method both named '%s' (Line %d, Column %d)", class.Name, se.Selector.Value, line, column))
+		return
+	}
+	if !class.analyzing {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' object has no attribute '%s'; fields are created by assigning them in __init__ (Line %d, Column %d)", class.Name, se.Selector.Value, line, column))
+		return
+	}
+	class.Fields = append(class.Fields, &Field{Name: se.Selector.Value, Type: valueType})
+	a.updateStruct(class)
+}
+
+// widenField makes a field of a class an interface{} when it is given a
+// value of another type.
+func (a *Analyzer) widenField(field *Field, owner *Class, valueType parser.Type) {
+	if field.Type.String() != valueType.String() && field.Type.String() != "interface{}" {
+		field.Type = &parser.BasicType{Name: "interface{}"}
+		a.updateStruct(owner)
+	}
+}
+
+// handleSelectorExpression analyzes an attribute access, reporting
+// attributes that a class instance doesn't have.
+func (a *Analyzer) handleSelectorExpression(se *parser.SelectorExpression) {
+	a.Analyze(se.Left, []parser.Statement{})
+	a.checkAttribute(se)
+}
+
+// checkAttribute reports an attribute that a class instance doesn't have.
+func (a *Analyzer) checkAttribute(se *parser.SelectorExpression) {
+	class, ok := a.ClassOf(a.InferExpressionTypes(se.Left, false)[0])
+	if !ok || class.Field(se.Selector.Value) != nil || class.Method(se.Selector.Value) != nil {
+		return
+	}
+	name := class.Name
+	if ce, ok := se.Left.(*parser.CallExpression); ok && IsSuperCall(ce) {
+		name = "super"
+	}
+	a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' object has no attribute '%s' (Line %d, Column %d)", name, se.Selector.Value, se.Token.Line, se.Token.Column))
+}
+
+// updateStruct brings the Go struct of a class up to date with its fields.
+// The struct of its parent is embedded, so that Go promotes the parent's
+// fields and methods.
+func (a *Analyzer) updateStruct(class *Class) {
+	fields := []*types.Var{}
+	if class.Parent != nil {
+		fields = append(fields, types.NewField(token.NoPos, nil, class.Parent.Name, class.Parent.Named, true))
+	}
+	for _, field := range class.Fields {
+		fields = append(fields, types.NewField(token.NoPos, nil, field.Name, a.GetGoTypeFromParserType(field.Type), false))
+	}
+	class.Named.SetUnderlying(types.NewStruct(fields, nil))
+}
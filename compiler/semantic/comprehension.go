@@ -0,0 +1,125 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"strings"
+)
+
+// Comprehension records a list or dict comprehension: what its for clause
+// iterates over, the scope of its variables and the type it builds.
+type Comprehension struct {
+	Kind  string      // "list", "dict", "items", "string", "int", "iterator", "next", or "any" when untyped
+	Type  parser.Type // The list or dict built
+	Scope *SymbolTable
+}
+
+// comprehensionOf analyzes a comprehension the first time it's seen. The
+// results are its element, or its key and value.
+func (a *Analyzer) comprehensionOf(fc *parser.ForClause, results ...parser.Expression) *Comprehension {
+	if c, ok := a.Comprehensions[fc]; ok {
+		return c
+	}
+	c := &Comprehension{Scope: NewSymbolTable(a.CurrentTable, "comprehension")}
+	a.Comprehensions[fc] = c
+
+	var varTypes []parser.Type
+	if dict, ok := a.itemsCall(fc.Iterable); ok {
+		a.Analyze(dict.Left, []parser.Statement{})
+		keyType, valueType := mapTypes(a.InferExpressionTypes(dict.Left, false)[0])
+		c.Kind, varTypes = "items", []parser.Type{keyType, valueType}
+	} else {
+		a.Analyze(fc.Iterable, []parser.Statement{})
+		var next *IteratorLoop
+		fc.Iterable, next = a.classIterable(fc.Iterable, fc.Token, []parser.Statement{})
+		iterableType := a.InferExpressionTypes(fc.Iterable, false)[0]
+		var itemType parser.Type
+		if next != nil {
+			c.Kind, itemType = "next", next.ElemType
+		} else if iterableType.String() == "int" {
+			c.Kind, itemType = "int", iterableType
+		} else if loop := a.funcIterator(iterableType); loop != nil {
+			c.Kind, itemType = "iterator", loop.ElemType
+		} else {
+			c.Kind, itemType = itemsOf(iterableType)
+		}
+		varTypes = []parser.Type{itemType}
+	}
+	if len(fc.Variables) != len(varTypes) {
+		hint := ""
+		if c.Kind == "dict" {
+			hint = "; use .items() for a dict's keys and values"
+		}
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("cannot unpack each item of %s into %d variables%s (Line %d, Column %d)", fc.Iterable.String(), len(fc.Variables), hint, fc.Token.Line, fc.Token.Column))
+		varTypes = make([]parser.Type, len(fc.Variables))
+		for i := range varTypes {
+			varTypes[i] = &parser.BasicType{Name: "interface{}"}
+		}
+	}
+	for i, v := range fc.Variables {
+		c.Scope.Define(v.Value, &Symbol{
+			Name:     v.Value,
+			Type:     varTypes[i],
+			Scope:    c.Scope.Name,
+			GoType:   a.GetGoTypeFromParserType(varTypes[i]),
+			Metadata: map[string]any{"set": true},
+		})
+	}
+
+	prevTable := a.CurrentTable
+	a.CurrentTable = c.Scope
+	if fc.Condition != nil {
+		a.Analyze(fc.Condition, []parser.Statement{})
+	}
+	resultTypes := []string{}
+	for _, result := range results {
+		a.Analyze(result, []parser.Statement{})
+		resultType := a.InferExpressionTypes(result, false)[0]
+		if commonKnownType([]parser.Type{resultType}) == nil {
+			resultType = &parser.BasicType{Name: "interface{}"}
+		}
+		resultTypes = append(resultTypes, resultType.String())
+	}
+	a.CurrentTable = prevTable
+
+	if len(resultTypes) == 2 {
+		a.checkKey(&parser.BasicType{Name: resultTypes[0]}, fc.Token.Line, fc.Token.Column)
+		c.Type = &parser.BasicType{Name: fmt.Sprintf("map[%s]%s", resultTypes[0], resultTypes[1])}
+	} else {
+		c.Type = &parser.BasicType{Name: "[]" + resultTypes[0]}
+	}
+	return c
+}
+
+// funcIterator returns the loop over an iterator function of type t, such as
+// a generator returns, or nil.
+func (a *Analyzer) funcIterator(t parser.Type) *IteratorLoop {
+	goType := a.goTypeOf(t)
+	if goType == nil {
+		return nil
+	}
+	if loop := a.iteratorLoop(goType); loop != nil && loop.Kind == FuncIterator {
+		return loop
+	}
+	return nil
+}
+
+// itemsCall returns the dict d of a call d.items() on a dict.
+func (a *Analyzer) itemsCall(e parser.Expression) (*parser.SelectorExpression, bool) {
+	ce, ok := e.(*parser.CallExpression)
+	if !ok || len(ce.Arguments) != 0 {
+		return nil, false
+	}
+	se, ok := ce.Function.(*parser.SelectorExpression)
+	if !ok || se.Selector.Value != "items" {
+		return nil, false
+	}
+	return se, strings.HasPrefix(a.InferExpressionTypes(se.Left, false)[0].String(), "map[")
+}
+
+// mapTypes returns the key and value types of a dict type.
+func mapTypes(t parser.Type) (parser.Type, parser.Type) {
+	key := loopVariableType(t.String())
+	value := strings.TrimPrefix(t.String(), "map["+key.String()+"]")
+	return key, &parser.BasicType{Name: value}
+}
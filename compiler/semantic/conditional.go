@@ -0,0 +1,37 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// handleConditionalExpression analyzes a conditional expression, which
+// needs an else to give a value when its condition is false.
+func (a *Analyzer) handleConditionalExpression(ce *parser.ConditionalExpression, remainingStatements []parser.Statement) {
+	if ce.Alternative == nil {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s needs an else; write it as a if condition else b (Line %d, Column %d)", ce.String(), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	a.Analyze(ce.Consequence, remainingStatements)
+	a.Analyze(ce.Condition, remainingStatements)
+	a.Analyze(ce.Alternative, remainingStatements)
+}
+
+// ConditionalType returns the type of a conditional expression, the type
+// its branches share. An int and a float give a float, and other branches
+// of different types give interface{}.
+func (a *Analyzer) ConditionalType(ce *parser.ConditionalExpression) parser.Type {
+	if ce.Alternative == nil {
+		return &parser.BasicType{Name: "interface{}"}
+	}
+	consequence := a.InferExpressionTypes(ce.Consequence, false)[0]
+	alternative := a.InferExpressionTypes(ce.Alternative, false)[0]
+	if t := commonKnownType([]parser.Type{consequence, alternative}); t != nil {
+		return t
+	}
+	numbers := map[string]bool{"int": true, "float": true, "float64": true}
+	if numbers[consequence.String()] && numbers[alternative.String()] {
+		return &parser.BasicType{Name: "float64"}
+	}
+	return &parser.BasicType{Name: "interface{}"}
+}
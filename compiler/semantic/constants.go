@@ -0,0 +1,84 @@
+package semantic
+
+import (
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// Constants
+//
+// A variable assigned once, at the top level of a file, to a literal such
+// as False, 3 or "release" is a constant. It is generated as a Go constant,
+// which the file's functions can use too, and the transformer works out
+// the conditions made of constants, so code behind if DEBUG: is left out of
+// the program when DEBUG is False.
+
+// findConstants records the constants of a program. A name bound again,
+// even in a function, is a variable, as is one naming a class or an
+// imported package, beside which Go can't declare a constant.
+func (a *Analyzer) findConstants(program *parser.Program) {
+	bindings := map[string]int{}
+	for name := range importNames(program) {
+		bindings[name]++
+	}
+	parser.Inspect(program, func(n parser.Node) bool {
+		for _, ident := range parser.Bindings(n) {
+			bindings[ident.Value]++
+		}
+		if cs, ok := n.(*parser.ClassStatement); ok && cs != nil && cs.Name != nil {
+			bindings[cs.Name.Value]++
+		}
+		return true
+	})
+	for _, stmt := range program.Statements {
+		as, ok := stmt.(*parser.AssignmentStatement)
+		if !ok || as == nil || as.Operator != "" || len(as.Left) != 1 || !IsLiteral(as.Value) {
+			continue
+		}
+		if ident, ok := as.Left[0].(*parser.Identifier); ok && bindings[ident.Value] == 1 {
+			a.Constants[ident.Value] = as
+		}
+	}
+}
+
+// IsLiteral reports whether expr is a bool, number or string literal.
+func IsLiteral(expr parser.Expression) bool {
+	switch e := expr.(type) {
+	case *parser.BooleanLiteral:
+		return e != nil
+	case *parser.IntegerLiteral:
+		return e != nil
+	case *parser.StringLiteral:
+		return e != nil
+	case *parser.PrefixExpression:
+		if e == nil || e.Operator != "-" {
+			return false
+		}
+		number, ok := e.Right.(*parser.IntegerLiteral)
+		return ok && number != nil
+	}
+	return false
+}
+
+// Globals returns the names of the variables a program assigns at its top
+// level, leaving out its constants. They are generated as Go package
+// variables.
+func Globals(program *parser.Program) map[string]bool {
+	a := &Analyzer{Constants: map[string]*parser.AssignmentStatement{}}
+	a.findConstants(program)
+	globals := map[string]bool{}
+	for _, stmt := range program.Statements {
+		parser.Inspect(stmt, func(n parser.Node) bool {
+			switch n.(type) {
+			case *parser.FunctionLiteral, *parser.LambdaExpression, *parser.ClassStatement, *parser.ListComprehension, *parser.DictComprehension:
+				return false
+			}
+			for _, ident := range parser.Bindings(n) {
+				if a.Constants[ident.Value] == nil {
+					globals[ident.Value] = true
+				}
+			}
+			return true
+		})
+	}
+	return globals
+}
@@ -0,0 +1,102 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// Decoration records what the decorators of a function definition make of
+// it.
+type Decoration struct {
+	Function *parser.FunctionType // the function as it is defined
+	Type     parser.Type          // the function its name is bound to
+}
+
+// handleDecorators applies the decorators of a function definition that has
+// been analyzed, from the innermost out, and binds its name to what the last
+// returns. A decorator defined with def is analyzed again taking the type of
+// function it is given, so that it can call it, and returns the signature
+// of the function it wraps it in.
+func (a *Analyzer) handleDecorators(fl *parser.FunctionLiteral) {
+	symbol, ok := a.CurrentTable.Symbols[fl.Name.Value]
+	if !ok {
+		return
+	}
+	function, ok := symbol.Type.(*parser.FunctionType)
+	if !ok {
+		return
+	}
+
+	var t parser.Type = function
+	for i := len(fl.Decorators) - 1; i >= 0; i-- {
+		if t = a.decorate(fl, fl.Decorators[i], t); t == nil {
+			return
+		}
+	}
+	a.Decorations[fl] = &Decoration{Function: function, Type: t}
+	symbol.Type = t
+	symbol.GoType = a.GetGoTypeFromParserType(t)
+}
+
+// decorate returns the type of function a decorator makes of a function of
+// type t.
+func (a *Analyzer) decorate(fl *parser.FunctionLiteral, decorator parser.Expression, t parser.Type) parser.Type {
+	a.Analyze(decorator, []parser.Statement{})
+	ft, ok := a.InferExpressionTypes(decorator, false)[0].(*parser.FunctionType)
+	if !ok || len(ft.ParameterTypes) != 1 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' can't decorate %s: a decorator is a function taking the function it decorates (Line %d, Column %d)", decorator.String(), fl.Name.Value, fl.Token.Line, fl.Token.Column))
+		return nil
+	}
+
+	if defined, ok := a.functions[ft]; ok {
+		if previous, ok := a.decorated[defined]; ok && previous.String() != t.String() {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s decorates functions of different types, %s and %s; a function of Simple takes one type of function (Line %d, Column %d)", defined.Name.Value, previous.String(), t.String(), fl.Token.Line, fl.Token.Column))
+			return nil
+		} else if !ok {
+			a.decorated[defined] = t
+			a.reanalyzeFunction(defined)
+			ft, ok = a.InferExpressionTypes(decorator, false)[0].(*parser.FunctionType)
+			if !ok {
+				return nil
+			}
+		}
+	}
+
+	if len(ft.ReturnTypes) != 1 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' can't decorate %s: a decorator must return a function (Line %d, Column %d)", decorator.String(), fl.Name.Value, fl.Token.Line, fl.Token.Column))
+		return nil
+	}
+	if _, ok := ft.ReturnTypes[0].(*parser.FunctionType); !ok {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' can't decorate %s: a decorator must return a function, not %s (Line %d, Column %d)", decorator.String(), fl.Name.Value, ft.ReturnTypes[0].String(), fl.Token.Line, fl.Token.Column))
+		return nil
+	}
+	return ft.ReturnTypes[0]
+}
+
+// reanalyzeFunction analyzes a function defined with def again, with the
+// function it is defined in if it is nested, as decorator factories' are.
+func (a *Analyzer) reanalyzeFunction(fl *parser.FunctionLiteral) {
+	table := a.SymbolTables.Tables[fl.Name.Value]
+	for table.Outer != nil && table.Outer != a.GlobalTable {
+		outer, ok := a.literalOf(table.Outer)
+		if !ok {
+			break
+		}
+		fl, table = outer, table.Outer
+	}
+
+	prevTable := a.CurrentTable
+	a.CurrentTable = table.Outer
+	a.handleFunctionLiteral(fl)
+	a.CurrentTable = prevTable
+}
+
+// literalOf returns the function defined with def whose scope is table.
+func (a *Analyzer) literalOf(table *SymbolTable) (*parser.FunctionLiteral, bool) {
+	for _, fl := range a.functions {
+		if a.SymbolTables.Tables[fl.Name.Value] == table {
+			return fl, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,95 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// Del statements
+//
+// del deletes dict items, which is Go's delete, and variables. Go can't
+// take a variable out of its scope, so a deleted variable is only marked
+// deleted in its table, and using it before it is assigned again is
+// reported as Python reports a name that isn't defined.
+
+// handleDelStatement analyzes the targets of a del statement, marking the
+// variables it deletes and reporting targets that can't be deleted.
+func (a *Analyzer) handleDelStatement(ds *parser.DelStatement, remainingStatements []parser.Statement) {
+	if len(ds.Targets) == 0 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("del takes the variables or dict items to delete, as in del counts[key] (Line %d, Column %d)", ds.Token.Line, ds.Token.Column))
+		return
+	}
+	for _, target := range ds.Targets {
+		switch t := target.(type) {
+		case *parser.Identifier:
+			a.deleteVariable(t, ds.Token.Line)
+		case *parser.IndexExpression:
+			a.Analyze(t.Left, remainingStatements)
+			leftType := a.InferExpressionTypes(t.Left, false)[0]
+			if kind := typeKind(leftType); kind != "dict" || t.End != nil {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' object doesn't support item deletion; del deletes variables and dict items (Line %d, Column %d)", kind, t.Token.Line, t.Token.Column))
+				continue
+			}
+			a.Analyze(t.Index, remainingStatements)
+			a.checkIndexKey(t, leftType)
+		default:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("cannot delete %s; del deletes variables and dict items (Line %d, Column %d)", target.String(), ds.Token.Line, ds.Token.Column))
+		}
+	}
+}
+
+// deleteVariable marks a variable deleted by a del statement on line. Like
+// an assignment, del can only delete the variables of the function it is
+// in, or those a global or nonlocal statement declares.
+func (a *Analyzer) deleteVariable(id *parser.Identifier, line int) {
+	if a.checkDeleted(id) {
+		return
+	}
+	_, symbol, exists := a.bindingTable(id.Value)
+	table := a.variableTable(id.Value)
+	if !exists || table == nil || symbol.Scope == "builtin" {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("name '%s' is not defined; del deletes the variables of the function it is in (Line %d, Column %d)", id.Value, id.Token.Line, id.Token.Column))
+		return
+	}
+	if _, ok := symbol.Type.(*parser.FunctionType); ok || a.Classes[id.Value] != nil {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("cannot delete '%s'; del deletes variables and dict items, not functions or classes (Line %d, Column %d)", id.Value, id.Token.Line, id.Token.Column))
+		return
+	}
+	if table.Deleted == nil {
+		table.Deleted = map[string]int{}
+	}
+	table.Deleted[id.Value] = line
+}
+
+// variableTable returns the table, from the current one outwards, that has
+// a variable name, or nil.
+func (a *Analyzer) variableTable(name string) *SymbolTable {
+	for t := a.CurrentTable; t != nil; t = t.Outer {
+		if _, ok := t.Symbols[name]; ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// checkDeleted reports a use of a variable that a del statement deleted,
+// and whether it was one.
+func (a *Analyzer) checkDeleted(id *parser.Identifier) bool {
+	table := a.variableTable(id.Value)
+	if table == nil {
+		return false
+	}
+	line, deleted := table.Deleted[id.Value]
+	if deleted {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("name '%s' is not defined; it was deleted on line %d (Line %d, Column %d)", id.Value, line, id.Token.Line, id.Token.Column))
+	}
+	return deleted
+}
+
+// undelete makes a variable a del statement deleted usable again, once it
+// is assigned.
+func (a *Analyzer) undelete(name string) {
+	if table := a.variableTable(name); table != nil {
+		delete(table.Deleted, name)
+	}
+}
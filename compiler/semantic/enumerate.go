@@ -0,0 +1,101 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// Enumeration records a for loop over enumerate(items, start), which counts
+// the items as it goes: for i, item in enumerate(items): is Go's for i, item
+// := range items.
+type Enumeration struct {
+	Items    parser.Expression // the list, dict or string being counted
+	Kind     string            // "list", "dict", "string", or "any" when untyped
+	ItemType parser.Type
+	Start    parser.Expression // the index of the first item, or nil for 0
+}
+
+// handleSeveralVariables analyzes a for loop over enumerate() or zip(),
+// which give it several variables, and reports a loop with several
+// variables over anything else. It returns false for a loop of neither
+// kind, which is left to the caller.
+func (a *Analyzer) handleSeveralVariables(fs *parser.ForStatement) bool {
+	ce, _ := fs.Iterable.(*parser.CallExpression)
+	switch {
+	case ce != nil && a.IsBuiltinCall(ce, "enumerate"):
+		a.handleEnumerate(fs, ce)
+	case ce != nil && a.IsBuiltinCall(ce, "zip"):
+		a.handleZip(fs, ce)
+	case fs.Variables != nil:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("a for loop has several variables only over enumerate() or zip(), as in for i, item in enumerate(items): (Line %d, Column %d)", fs.Token.Line, fs.Token.Column))
+		a.defineLoopVariables(fs, nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// handleEnumerate analyzes a for loop over enumerate(), defining its index
+// and item.
+func (a *Analyzer) handleEnumerate(fs *parser.ForStatement, ce *parser.CallExpression) {
+	en := &Enumeration{}
+	positional := []parser.Expression{}
+	for _, arg := range ce.Arguments {
+		ka, ok := arg.(*parser.KeywordArgument)
+		if !ok {
+			positional = append(positional, arg)
+			continue
+		}
+		if ka.Name.Value != "start" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' is an invalid keyword argument for enumerate() (Line %d, Column %d)", ka.Name.Value, ka.Token.Line, ka.Token.Column))
+			return
+		}
+		en.Start = ka.Value
+	}
+	switch {
+	case len(positional) == 2 && en.Start == nil:
+		en.Start = positional[1]
+	case len(positional) != 1:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("enumerate() takes the items and an optional start (%d arguments given) (Line %d, Column %d)", len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	en.Items = positional[0]
+
+	a.Analyze(en.Items, []parser.Statement{})
+	itemsType := a.InferExpressionTypes(en.Items, false)[0]
+	en.Kind, en.ItemType = itemsOf(itemsType)
+	if en.Kind == "any" && itemsType.String() != "interface{}" {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("enumerate() counts the items of a list, dict or str, not %s (Line %d, Column %d)", annotationName(itemsType), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	if en.Start != nil {
+		a.Analyze(en.Start, []parser.Statement{})
+		if startType := a.InferExpressionTypes(en.Start, false)[0]; startType.String() != "int" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("the start of enumerate() must be an int, not %s (Line %d, Column %d)", annotationName(startType), ce.Token.Line, ce.Token.Column))
+			return
+		}
+	}
+	if len(fs.Variables) != 2 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("enumerate() gives an index and an item; loop over it with two variables, as in for i, item in enumerate(items): (Line %d, Column %d)", fs.Token.Line, fs.Token.Column))
+		return
+	}
+
+	a.Enumerations[fs] = en
+	a.defineLoopVariables(fs, []parser.Type{&parser.BasicType{Name: "int"}, en.ItemType})
+}
+
+// defineLoopVariables defines the variables of a loop with several, of
+// the given types, or of no known type when types is nil.
+func (a *Analyzer) defineLoopVariables(fs *parser.ForStatement, types []parser.Type) {
+	for i, v := range fs.Variables {
+		t := parser.Type(&parser.BasicType{Name: "interface{}"})
+		if types != nil {
+			t = types[i]
+		}
+		a.CurrentTable.Define(v.Value, &Symbol{
+			Name:  v.Value,
+			Type:  t,
+			Scope: a.CurrentTable.Name,
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"go/types"
+	"sort"
+)
+
+// exceptionParents maps the built-in exception classes to the classes they
+// inherit from, as in Python. Exception, the root, handles every exception.
+var exceptionParents = map[string]string{
+	"Exception":           "",
+	"ArithmeticError":     "Exception",
+	"ZeroDivisionError":   "ArithmeticError",
+	"LookupError":         "Exception",
+	"IndexError":          "LookupError",
+	"KeyError":            "LookupError",
+	"AttributeError":      "Exception",
+	"TypeError":           "Exception",
+	"ValueError":          "Exception",
+	"RuntimeError":        "Exception",
+	"StopIteration":       "Exception",
+	"AssertionError":      "Exception",
+	"EOFError":            "Exception",
+	"NotImplementedError": "RuntimeError",
+	"OSError":             "Exception",
+	"FileExistsError":     "OSError",
+	"FileNotFoundError":   "OSError",
+	"IsADirectoryError":   "OSError",
+	"PermissionError":     "OSError",
+}
+
+// exceptionParent returns the class an exception class inherits from, ""
+// for Exception, and whether name is an exception class at all.
+func (a *Analyzer) exceptionParent(name string) (string, bool) {
+	if parent, ok := exceptionParents[name]; ok {
+		return parent, true
+	}
+	parent, ok := a.exceptionClasses[name]
+	return parent, ok
+}
+
+// IsException reports whether name is an exception class, built in or
+// defined by the program.
+func (a *Analyzer) IsException(name string) bool {
+	_, ok := a.exceptionParent(name)
+	return ok
+}
+
+// ExceptionNames returns the exception classes an except clause naming the
+// class handles: the class and those that inherit from it, sorted. Except
+// clauses are matched by these names, so no class hierarchy is needed at
+// run time.
+func (a *Analyzer) ExceptionNames(name string) []string {
+	names := []string{}
+	classes := []string{}
+	for class := range exceptionParents {
+		classes = append(classes, class)
+	}
+	for class := range a.exceptionClasses {
+		classes = append(classes, class)
+	}
+	for _, class := range classes {
+		for c := class; c != ""; c, _ = a.exceptionParent(c) {
+			if c == name {
+				names = append(names, class)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// handleExceptionClass defines a class that inherits from an exception
+// class. An exception carries only its message, so the class can have a
+// docstring or pass but no fields or methods.
+func (a *Analyzer) handleExceptionClass(cs *parser.ClassStatement) {
+	for _, stmt := range cs.Body.Statements {
+		if _, ok := stmt.(*parser.PassStatement); ok {
+			continue
+		}
+		if es, ok := stmt.(*parser.ExpressionStatement); ok {
+			// A docstring, or a line the parser left empty
+			if es == nil || es.Expression == nil {
+				continue
+			}
+			if _, ok := es.Expression.(*parser.StringLiteral); ok {
+				continue
+			}
+		}
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("exception class '%s' can only have a docstring; exceptions carry only a message (Line %d, Column %d)", cs.Name.Value, cs.Token.Line, cs.Token.Column))
+		return
+	}
+	a.exceptionClasses[cs.Name.Value] = cs.Bases[0].Value
+}
+
+// handleTryStatement analyzes a try statement. The name an except clause
+// binds is an error, whose message is that of the exception. Bare raise
+// statements are recorded as valid in except clauses, but not in functions
+// defined in them.
+func (a *Analyzer) handleTryStatement(ts *parser.TryStatement, remainingStatements []parser.Statement) {
+	switch {
+	case len(ts.Handlers) == 0 && ts.Finally == nil:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("expected 'except' or 'finally' block (Line %d, Column %d)", ts.Token.Line, ts.Token.Column))
+	case len(ts.Handlers) == 0 && ts.Else != nil:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("a try statement can only have an else block after except clauses (Line %d, Column %d)", ts.Token.Line, ts.Token.Column))
+	}
+
+	a.Analyze(ts.Body, remainingStatements)
+	for i, handler := range ts.Handlers {
+		if len(handler.Types) == 0 && i < len(ts.Handlers)-1 {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("default 'except:' must be last (Line %d, Column %d)", handler.Token.Line, handler.Token.Column))
+		}
+		for _, t := range handler.Types {
+			if !a.IsException(t.Value) {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("name '%s' is not defined; except takes an exception class such as ValueError (Line %d, Column %d)", t.Value, t.Token.Line, t.Token.Column))
+			}
+		}
+		parser.Inspect(handler.Body, func(n parser.Node) bool {
+			switch s := n.(type) {
+			case *parser.FunctionLiteral:
+				return false
+			case *parser.RaiseStatement:
+				if s.Exception == nil {
+					a.reraises[s] = true
+				}
+			}
+			return true
+		})
+		if handler.Name != nil {
+			a.CurrentTable.Define(handler.Name.Value, &Symbol{
+				Name:   handler.Name.Value,
+				Type:   &parser.BasicType{Name: "error"},
+				Scope:  a.CurrentTable.Name,
+				GoType: types.Universe.Lookup("error").Type(),
+			})
+		}
+		a.Analyze(handler.Body, remainingStatements)
+	}
+	a.Analyze(ts.Else, remainingStatements)
+	a.Analyze(ts.Finally, remainingStatements)
+	if ts.Finally != nil {
+		a.checkFinally(ts.Finally.Statements, false)
+	}
+}
+
+// RaisedException returns the class of the exception a raise statement
+// raises and the expression of its message, nil if it has none, unless the
+// statement raises an error or raises the exception being handled again.
+func (a *Analyzer) RaisedException(rs *parser.RaiseStatement) (string, parser.Expression, bool) {
+	switch e := rs.Exception.(type) {
+	case *parser.Identifier:
+		if a.IsException(e.Value) {
+			return e.Value, nil, true
+		}
+	case *parser.CallExpression:
+		if ident, ok := e.Function.(*parser.Identifier); ok && a.IsException(ident.Value) {
+			if len(e.Arguments) == 0 {
+				return ident.Value, nil, true
+			}
+			return ident.Value, e.Arguments[0], true
+		}
+	}
+	return "", nil, false
+}
+
+// handleRaiseStatement analyzes a raise statement. It raises an exception
+// class, with a message or not, or an error, such as an exception bound by
+// an except clause. A bare raise raises the exception being handled again.
+func (a *Analyzer) handleRaiseStatement(rs *parser.RaiseStatement) {
+	if rs.Exception == nil {
+		if !a.reraises[rs] {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("a bare 'raise' can only be used in an except clause (Line %d, Column %d)", rs.Token.Line, rs.Token.Column))
+		}
+		return
+	}
+	if class, message, ok := a.RaisedException(rs); ok {
+		ce, isCall := rs.Exception.(*parser.CallExpression)
+		if !isCall {
+			return
+		}
+		if len(ce.Arguments) > 1 {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() takes one argument, its message, in Simple (%d given) (Line %d, Column %d)", class, len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+			return
+		}
+		if _, ok := message.(*parser.KeywordArgument); ok {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() takes no keyword arguments (Line %d, Column %d)", class, ce.Token.Line, ce.Token.Column))
+			return
+		}
+		if message != nil {
+			a.Analyze(message, []parser.Statement{})
+		}
+		return
+	}
+
+	a.Analyze(rs.Exception, []parser.Statement{})
+	if t := a.InferExpressionTypes(rs.Exception, false)[0]; t.String() != "error" {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("exceptions must derive from BaseException; raise an exception class such as ValueError(\"message\") or an error (Line %d, Column %d)", rs.Token.Line, rs.Token.Column))
+	}
+}
+
+// handleAssertStatement analyzes the condition and message of an assert
+// statement, reporting one without a condition.
+func (a *Analyzer) handleAssertStatement(as *parser.AssertStatement, remainingStatements []parser.Statement) {
+	if as.Condition == nil {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("assert takes a condition, as in assert x > 0, \"message\" (Line %d, Column %d)", as.Token.Line, as.Token.Column))
+		return
+	}
+	a.Analyze(as.Condition, remainingStatements)
+	if as.Message != nil {
+		a.Analyze(as.Message, remainingStatements)
+	}
+}
+
+// checkFinally reports statements that would leave a finally block, which
+// runs after the rest of the try statement has returned or raised. Break
+// and continue inside a loop of the block stay inside it.
+func (a *Analyzer) checkFinally(statements []parser.Statement, inLoop bool) {
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case *parser.ReturnStatement:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'return' in a finally block isn't supported (Line %d, Column %d)", s.Token.Line, s.Token.Column))
+		case *parser.YieldStatement:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'yield' in a finally block isn't supported (Line %d, Column %d)", s.Token.Line, s.Token.Column))
+		case *parser.BreakStatement:
+			if !inLoop {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'break' in a finally block isn't supported (Line %d, Column %d)", s.Token.Line, s.Token.Column))
+			}
+		case *parser.ContinueStatement:
+			if !inLoop {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'continue' in a finally block isn't supported (Line %d, Column %d)", s.Token.Line, s.Token.Column))
+			}
+		case *parser.IfStatement:
+			if s == nil {
+				continue
+			}
+			if s.Consequence != nil {
+				a.checkFinally(s.Consequence.Statements, inLoop)
+			}
+			if s.Alternative != nil {
+				a.checkFinally(s.Alternative.Statements, inLoop)
+			}
+		case *parser.WhileStatement:
+			if s != nil && s.Body != nil {
+				a.checkFinally(s.Body.Statements, true)
+			}
+		case *parser.ForStatement:
+			if s != nil && s.Body != nil {
+				a.checkFinally(s.Body.Statements, true)
+			}
+		case *parser.TryStatement:
+			if s == nil {
+				continue
+			}
+			for _, block := range s.Blocks() {
+				a.checkFinally(block.Statements, inLoop)
+			}
+		case *parser.WithStatement:
+			if s != nil && s.Body != nil {
+				a.checkFinally(s.Body.Statements, inLoop)
+			}
+		case *parser.MatchStatement:
+			if s == nil {
+				continue
+			}
+			for _, block := range s.Blocks() {
+				a.checkFinally(block.Statements, inLoop)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"strings"
+)
+
+// Exports
+//
+// The functions of a Simple module are public unless their names start with
+// an underscore. A public function is exported from the module's Go package
+// with its first letter capitalized, so util.count is util.Count in Go and
+// json.dumps calls a function defined as dumps or Dumps. A private function
+// keeps its name, which Go doesn't export, and only the module can call it.
+
+// IsPrivate reports whether a module function of the given name is private.
+func IsPrivate(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+// GoName returns the Go name of a function defined at the top level of a
+// module, or of a Simple module function called as module.name.
+func GoName(name string) string {
+	if name == "" || IsPrivate(name) {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// CheckExports reports the functions of a module that would be exported
+// with the same Go name as another of its functions, or as a generated
+// class constructor.
+func (a *Analyzer) CheckExports(program *parser.Program) {
+	owners := map[string]string{}
+	for _, stmt := range program.Statements {
+		if cs, ok := stmt.(*parser.ClassStatement); ok && cs != nil && cs.Name != nil {
+			owners[GoName(cs.Name.Value)] = "class " + cs.Name.Value
+			owners["New"+cs.Name.Value] = "the constructor of class " + cs.Name.Value
+		}
+	}
+	for _, stmt := range program.Statements {
+		fl, ok := stmt.(*parser.FunctionLiteral)
+		if !ok || fl == nil || fl.Name == nil {
+			continue
+		}
+		goName := GoName(fl.Name.Value)
+		if owner, ok := owners[goName]; ok {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s is exported as %s, which %s already is; rename it, or start it with _ to keep it private (Line %d, Column %d)", fl.Name.Value, goName, owner, fl.Token.Line, fl.Token.Column))
+			continue
+		}
+		owners[goName] = fl.Name.Value
+	}
+}
@@ -0,0 +1,257 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"github.com/sasogeek/simple/compiler/parser"
+	"strconv"
+	"strings"
+)
+
+// handleFStringLiteral reports problems in the braces of an f-string and
+// checks each format spec against the type of the value it formats.
+func (a *Analyzer) handleFStringLiteral(fs *parser.FStringLiteral) {
+	a.fatalErrors = append(a.fatalErrors, fs.Errors...)
+	for _, part := range fs.Parts {
+		if part.Expression != nil {
+			a.Analyze(part.Expression, []parser.Statement{})
+		}
+	}
+	a.checkFormatFields(fs, "f-string", braceField)
+}
+
+// handlePercentFormat lowers "..." % values to an f-string formatting the
+// values, reporting whether ie is such a format.
+func (a *Analyzer) handlePercentFormat(ie *parser.InfixExpression) bool {
+	template, ok := ie.Left.(*parser.StringLiteral)
+	if !ok {
+		if a.InferExpressionTypes(ie.Left, false)[0].String() != "string" {
+			return false
+		}
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("the format string of %% must be a string literal (Line %d, Column %d)", ie.Token.Line, ie.Token.Column))
+		return true
+	}
+	values := []parser.Expression{ie.Right}
+	if tl, ok := ie.Right.(*parser.TupleLiteral); ok {
+		values = tl.Elements
+	}
+	parts, err := parser.ParsePercentTemplate(template.Value)
+	if err != nil {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%% format: %v (Line %d, Column %d)", err, ie.Token.Line, ie.Token.Column))
+		return true
+	}
+
+	fs := &parser.FStringLiteral{Token: template.Token}
+	n := 0
+	for _, part := range parts {
+		if part.Text == "" {
+			if n == len(values) {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%% format: not enough arguments for format string (Line %d, Column %d)", ie.Token.Line, ie.Token.Column))
+				return true
+			}
+			part.Expression = values[n]
+			n++
+			a.Analyze(part.Expression, []parser.Statement{})
+			if valueType := a.InferExpressionTypes(part.Expression, false)[0].String(); strings.HasSuffix(part.Spec, "d") && (valueType == "float" || valueType == "float64") {
+				// %d truncates a float, as int() does. Go won't convert a
+				// constant such as 3.9 to an int, so literals are truncated here
+				if lit, ok := part.Expression.(*parser.IntegerLiteral); ok {
+					if f, ok := lit.Value.(float64); ok {
+						truncated := int64(f)
+						part.Expression = &parser.IntegerLiteral{Token: lexer.Token{Type: lexer.TokenNumber, Literal: strconv.FormatInt(truncated, 10), Line: lit.Token.Line, Column: lit.Token.Column}, Value: truncated}
+					}
+				} else {
+					part.Expression = &parser.TypeConversionExpression{Token: ie.Token, Expression: part.Expression, TargetType: &parser.BasicType{Name: "int"}}
+				}
+			}
+		}
+		fs.Parts = append(fs.Parts, part)
+	}
+	if n < len(values) {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%% format: not all arguments converted during string formatting (Line %d, Column %d)", ie.Token.Line, ie.Token.Column))
+		return true
+	}
+	a.StringFormats[ie] = fs
+	a.checkFormatFields(fs, "% format", func(part parser.FStringPart) string { return part.Source })
+	return true
+}
+
+// handleFormatMethod lowers "...".format(args) to an f-string formatting
+// the arguments, reporting whether ce is such a call.
+func (a *Analyzer) handleFormatMethod(ce *parser.CallExpression) bool {
+	se, ok := ce.Function.(*parser.SelectorExpression)
+	if !ok || se.Selector.Value != "format" {
+		return false
+	}
+	template, ok := se.Left.(*parser.StringLiteral)
+	if !ok {
+		if a.InferExpressionTypes(se.Left, false)[0].String() != "string" {
+			return false
+		}
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("format() must be called on a string literal (Line %d, Column %d)", ce.Token.Line, ce.Token.Column))
+		return true
+	}
+	parts, err := parser.ParseFormatTemplate(template.Value)
+	if err != nil {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("format(): %v (Line %d, Column %d)", err, ce.Token.Line, ce.Token.Column))
+		return true
+	}
+
+	positional := []parser.Expression{}
+	keywords := map[string]parser.Expression{}
+	for _, arg := range ce.Arguments {
+		if ka, ok := arg.(*parser.KeywordArgument); ok {
+			keywords[ka.Name.Value] = ka.Value
+			a.Analyze(ka.Value, []parser.Statement{})
+		} else {
+			positional = append(positional, arg)
+			a.Analyze(arg, []parser.Statement{})
+		}
+	}
+
+	fs := &parser.FStringLiteral{Token: template.Token}
+	next, numbering := 0, ""
+	for _, part := range parts {
+		if part.Text != "" {
+			fs.Parts = append(fs.Parts, part)
+			continue
+		}
+		var problem string
+		name := part.Source
+		index, err := strconv.Atoi(name)
+		switch {
+		case name == "":
+			if numbering == "manual" {
+				problem = "cannot switch from manual field specification to automatic field numbering"
+			}
+			numbering, index = "automatic", next
+			next++
+		case err == nil:
+			if numbering == "automatic" {
+				problem = "cannot switch from automatic field numbering to manual field specification"
+			}
+			numbering = "manual"
+		case strings.ContainsAny(name, ".["):
+			problem = fmt.Sprintf("attribute and index lookups such as {%s} aren't supported", name)
+		default:
+			if part.Expression, ok = keywords[name]; !ok {
+				problem = fmt.Sprintf("no argument named '%s'", name)
+			}
+		}
+		if problem == "" && part.Expression == nil {
+			if index >= len(positional) {
+				problem = fmt.Sprintf("replacement index %d out of range for positional args tuple", index)
+			} else {
+				part.Expression = positional[index]
+			}
+		}
+		if problem != "" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("format(): %s (Line %d, Column %d)", problem, ce.Token.Line, ce.Token.Column))
+			return true
+		}
+		fs.Parts = append(fs.Parts, part)
+	}
+	a.StringFormats[ce] = fs
+	a.checkFormatFields(fs, "format()", braceField)
+	return true
+}
+
+// handleFormatBuiltin lowers format(value, spec) to an f-string formatting
+// the value with the spec, which must be a string literal.
+func (a *Analyzer) handleFormatBuiltin(ce *parser.CallExpression) {
+	if len(ce.Arguments) < 1 || len(ce.Arguments) > 2 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("format() takes 1 or 2 arguments (%d given) (Line %d, Column %d)", len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	part := parser.FStringPart{Expression: ce.Arguments[0], Source: ce.Arguments[0].String()}
+	if len(ce.Arguments) == 2 {
+		spec, ok := ce.Arguments[1].(*parser.StringLiteral)
+		if !ok {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("the format spec of format() must be a string literal (Line %d, Column %d)", ce.Token.Line, ce.Token.Column))
+			return
+		}
+		part.Spec = spec.Value
+	}
+	a.Analyze(part.Expression, []parser.Statement{})
+	fs := &parser.FStringLiteral{Token: ce.Token, Parts: []parser.FStringPart{part}}
+	a.StringFormats[ce] = fs
+	a.checkFormatFields(fs, "format()", braceField)
+}
+
+// roundType returns the type of a call of round: an int without digits,
+// as Python's round returns, and otherwise the type of the number rounded.
+func (a *Analyzer) roundType(ce *parser.CallExpression) parser.Type {
+	if len(ce.Arguments) == 1 || a.InferExpressionTypes(ce.Arguments[0], false)[0].String() == "int" {
+		return &parser.BasicType{Name: "int"}
+	}
+	return &parser.BasicType{Name: "float"}
+}
+
+// checkFormatFields checks the format spec of each field of fs against
+// the type of the value it formats. what and field describe the format
+// and the field in errors.
+func (a *Analyzer) checkFormatFields(fs *parser.FStringLiteral, what string, field func(parser.FStringPart) string) {
+	for _, part := range fs.Parts {
+		if part.Expression == nil || part.Spec == "" {
+			continue
+		}
+		valueType := "string"
+		if part.Conversion == 0 {
+			valueType = a.InferExpressionTypes(part.Expression, false)[0].String()
+		}
+		if err := checkFormatSpec(part.Spec, valueType); err != nil {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s: %v in %s (Line %d, Column %d)", what, err, field(part), fs.Token.Line, fs.Token.Column))
+		}
+	}
+}
+
+// braceField writes a field as it appears in an f-string or a template of
+// format().
+func braceField(part parser.FStringPart) string {
+	if part.Spec == "" {
+		return "{" + part.Source + "}"
+	}
+	return "{" + part.Source + ":" + part.Spec + "}"
+}
+
+// checkFormatSpec checks that a format spec is valid for a value of the
+// given type, and that Go's fmt verbs can express it.
+func checkFormatSpec(spec string, valueType string) error {
+	fs, err := parser.ParseFormatSpec(spec)
+	if err != nil {
+		return err
+	}
+	switch {
+	case fs.Align == '^':
+		return fmt.Errorf("centering with '^' isn't supported")
+	case fs.Align == '=' && fs.Fill != '0':
+		return fmt.Errorf("padding after the sign with '=' is only supported with 0")
+	case fs.Fill != ' ' && !(fs.Fill == '0' && fs.Align != '<'):
+		return fmt.Errorf("padding with '%c' isn't supported; only spaces and zeros are", fs.Fill)
+	case fs.Grouping != 0 && (fs.Zero || fs.Fill == '0'):
+		return fmt.Errorf("padding with zeros and grouping thousands with '%c' together isn't supported", fs.Grouping)
+	case fs.Grouping != 0 && fs.Type != 0 && !strings.ContainsRune("deEfFgG%", fs.Type):
+		return fmt.Errorf("cannot specify '%c' with '%c'", fs.Grouping, fs.Type)
+	case fs.Grouping != 0 && valueType == "string":
+		return fmt.Errorf("cannot specify '%c' with 's'", fs.Grouping)
+	case fs.Type == 'n' || fs.Type == 'c':
+		return fmt.Errorf("format code '%c' isn't supported", fs.Type)
+	}
+
+	pythonType := map[string]string{"int": "int", "float": "float", "float64": "float", "string": "str", "bool": "bool"}[valueType]
+	if pythonType == "" || fs.Type == 0 {
+		return nil
+	}
+	allowed := map[string]string{
+		"int":   "bdoxXeEfFgG%",
+		"float": "eEfFgG%",
+		"str":   "s",
+	}[pythonType]
+	if pythonType == "bool" {
+		return fmt.Errorf("format code '%c' isn't supported for bool values", fs.Type)
+	}
+	if !strings.ContainsRune(allowed, fs.Type) {
+		return fmt.Errorf("unknown format code '%c' for object of type '%s'", fs.Type, pythonType)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// Generators
+//
+// A function with a yield statement is a generator. It returns a Go
+// iterator function, func(yield func(T) bool), which a for loop ranges over
+// as it does over iter.Seq values: the body runs as the loop asks for its
+// values, and returns from the yield in progress when the loop stops early.
+
+// isGenerator reports whether the body of a function yields, leaving out
+// the functions defined in it.
+func isGenerator(body *parser.BlockStatement) bool {
+	found := false
+	parser.Inspect(body, func(n parser.Node) bool {
+		switch n.(type) {
+		case *parser.FunctionLiteral:
+			return false
+		case *parser.YieldStatement:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// generatorType returns the type of the iterator functions returned by a
+// generator of values of type element.
+func generatorType(element parser.Type) *parser.FunctionType {
+	yield := &parser.FunctionType{
+		ParameterTypes: []parser.Type{element},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "bool"}},
+	}
+	return &parser.FunctionType{
+		ParameterTypes: []parser.Type{yield},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "void"}},
+	}
+}
+
+// handleYieldStatement checks a yield statement and its value.
+func (a *Analyzer) handleYieldStatement(ys *parser.YieldStatement, remainingStatements []parser.Statement) {
+	switch {
+	case a.CurrentTable == a.GlobalTable:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'yield' outside function (Line %d, Column %d)", ys.Token.Line, ys.Token.Column))
+		return
+	case a.currentClass != nil:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("methods can't be generators in Simple; yield from a function defined with def (Line %d, Column %d)", ys.Token.Line, ys.Token.Column))
+		return
+	case ys.Value == nil:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("yield needs a value in Simple (Line %d, Column %d)", ys.Token.Line, ys.Token.Column))
+		return
+	}
+	a.Analyze(ys.Value, remainingStatements)
+}
+
+// generatorResults returns the result of a generator, an iterator function
+// of the values it yields. Values of different types are yielded as
+// interface{}. A generator ends by returning, so its returns can't give a
+// value.
+func (a *Analyzer) generatorResults(fl *parser.FunctionLiteral, funcTable *SymbolTable) []parser.Type {
+	prevTable := a.CurrentTable
+	a.CurrentTable = funcTable
+	var element parser.Type
+	parser.Inspect(fl.Body, func(n parser.Node) bool {
+		switch s := n.(type) {
+		case *parser.FunctionLiteral:
+			return false
+		case *parser.ReturnStatement:
+			if s.ReturnValue != nil {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'return' with a value in a generator isn't supported; a bare return ends it (Line %d, Column %d)", s.Token.Line, s.Token.Column))
+			}
+		case *parser.YieldStatement:
+			if s.Value == nil {
+				break
+			}
+			t := a.InferExpressionTypes(s.Value, false)[0]
+			if element == nil {
+				element = t
+			} else if element.TypeName() != t.TypeName() {
+				element = &parser.BasicType{Name: "interface{}"}
+			}
+		}
+		return true
+	})
+	a.CurrentTable = prevTable
+
+	if element == nil {
+		element = &parser.BasicType{Name: "interface{}"}
+	}
+	a.Generators[fl] = element
+	return []parser.Type{generatorType(element)}
+}
@@ -0,0 +1,74 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"go/types"
+	"strings"
+)
+
+// Identity tests
+//
+// a is b is true when a and b are the same object. In Go, objects are
+// pointers, which == compares by what they point to, and x is None is a nil
+// check. Values such as numbers, strings and lists have no identity of
+// their own once compiled, so is between them is reported, with == as the
+// way to compare them.
+
+// handleIdentity analyzes a is b and a is not b.
+func (a *Analyzer) handleIdentity(ie *parser.InfixExpression, remainingStatements []parser.Statement) {
+	a.Analyze(ie.Left, remainingStatements)
+	a.Analyze(ie.Right, remainingStatements)
+	for _, side := range []parser.Expression{ie.Left, ie.Right} {
+		if _, ok := side.(*parser.NoneLiteral); ok {
+			return
+		}
+	}
+	for _, side := range []parser.Expression{ie.Left, ie.Right} {
+		t := a.InferExpressionTypes(side, false)[0]
+		if !a.hasIdentity(t) {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s compares objects, not values of type %s; use == to compare values (Line %d, Column %d)", ie.Operator, t.String(), ie.Token.Line, ie.Token.Column))
+			return
+		}
+	}
+}
+
+// hasIdentity reports whether values of a type are objects is can
+// compare: instances of classes and other pointers, and values of no known
+// type, which may hold them. True and False are singletons in Python, so
+// bools compare too.
+func (a *Analyzer) hasIdentity(t parser.Type) bool {
+	if _, ok := a.TupleOf(t); ok || !a.IsNilable(t) {
+		basic, ok := a.GetGoTypeFromParserType(t).(*types.Basic)
+		return ok && basic.Info()&types.IsBoolean != 0
+	}
+	name := t.String()
+	if strings.HasPrefix(name, "[]") || strings.HasPrefix(name, "map[") {
+		return false
+	}
+	if goType := a.GetGoTypeFromParserType(t); goType != nil {
+		switch goType.Underlying().(type) {
+		case *types.Slice, *types.Map, *types.Signature:
+			return false
+		}
+	}
+	return true
+}
+
+// IsNilable reports whether a value of a type can be nil, which is what
+// None is compared with: objects, lists, dicts, functions and values of no
+// known type.
+func (a *Analyzer) IsNilable(t parser.Type) bool {
+	if _, ok := a.TupleOf(t); ok {
+		return false
+	}
+	goType := a.GetGoTypeFromParserType(t)
+	if goType == nil {
+		return true
+	}
+	switch goType.Underlying().(type) {
+	case *types.Basic, *types.Struct, *types.Array:
+		return false
+	}
+	return true
+}
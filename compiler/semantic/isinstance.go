@@ -0,0 +1,152 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"sort"
+)
+
+// TypeCheck is a test of the type of a value at run time: a call of
+// isinstance(), or a comparison of type() with a type, as in
+// type(x) == int.
+type TypeCheck struct {
+	Value   parser.Expression
+	Types   []string // Go types the value may have, such as int or *Dog
+	Kinds   []string // kinds of value it may be instead: list, dict, set or tuple
+	Negated bool     // type(x) != T and type(x) is not T
+}
+
+// typeKinds are the types of Simple a type check can name that are kinds
+// of Go type rather than Go types.
+var typeKinds = map[string]bool{"list": true, "dict": true, "set": true, "tuple": true}
+
+// handleIsInstance analyzes isinstance(x, T), where T is a type or a tuple
+// of types. A class is an instance of a class it inherits from, and a bool
+// of int, as in Python.
+func (a *Analyzer) handleIsInstance(ce *parser.CallExpression) {
+	if len(ce.Arguments) != 2 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("isinstance() takes exactly 2 arguments (%d given) (Line %d, Column %d)", len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	a.Analyze(ce.Arguments[0], []parser.Statement{})
+	names := []parser.Expression{ce.Arguments[1]}
+	if tuple, ok := ce.Arguments[1].(*parser.TupleLiteral); ok {
+		names = tuple.Elements
+	}
+	tc := &TypeCheck{Value: ce.Arguments[0]}
+	for _, name := range names {
+		if !a.addCheckedType(tc, name, false, ce.Token.Line, ce.Token.Column) {
+			return
+		}
+	}
+	a.TypeChecks[ce] = tc
+}
+
+// handleTypeCall analyzes type(x), whose value is the name of x's type as
+// Python prints it, as in <class 'int'>.
+func (a *Analyzer) handleTypeCall(ce *parser.CallExpression) {
+	if len(ce.Arguments) != 1 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("type() takes exactly one argument (%d given) (Line %d, Column %d)", len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	a.Analyze(ce.Arguments[0], []parser.Statement{})
+}
+
+// handleTypeComparison analyzes type(x) == T, or !=, is or is not, as a
+// test of whether x is exactly of type T, reporting whether ie is one.
+func (a *Analyzer) handleTypeComparison(ie *parser.InfixExpression) bool {
+	switch ie.Operator {
+	case "==", "!=", "is", "is not":
+	default:
+		return false
+	}
+	ce, ok := a.TypeCall(ie.Left)
+	if !ok {
+		return false
+	}
+	name, ok := ie.Right.(*parser.Identifier)
+	if !ok || !a.isTypeName(name) {
+		return false
+	}
+	a.handleTypeCall(ce)
+	if len(ce.Arguments) != 1 {
+		return true
+	}
+	tc := &TypeCheck{Value: ce.Arguments[0], Negated: ie.Operator == "!=" || ie.Operator == "is not"}
+	if a.addCheckedType(tc, name, true, ie.Token.Line, ie.Token.Column) {
+		a.TypeChecks[ie] = tc
+	}
+	return true
+}
+
+// TypeCall returns the call of type() that e is, if it is one.
+func (a *Analyzer) TypeCall(e parser.Expression) (*parser.CallExpression, bool) {
+	ce, ok := e.(*parser.CallExpression)
+	if !ok || !a.IsBuiltinCall(ce, "type") {
+		return nil, false
+	}
+	return ce, true
+}
+
+// isTypeName reports whether name names a type a type check can test for.
+func (a *Analyzer) isTypeName(name *parser.Identifier) bool {
+	if typeKinds[name.Value] {
+		return true
+	}
+	_, ok := a.matchedType(name)
+	return ok
+}
+
+// addCheckedType adds the type named by e to those tc tests for: only that
+// type when exact, and otherwise the types that are instances of it too.
+// It reports whether e names a type, reporting an error at line and column
+// of the check when it doesn't.
+func (a *Analyzer) addCheckedType(tc *TypeCheck, e parser.Expression, exact bool, line, column int) bool {
+	name, ok := e.(*parser.Identifier)
+	if !ok || !a.isTypeName(name) {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s isn't a type a type check can test for; use int, float, str, bool, list, dict, set, tuple or a class (Line %d, Column %d)", e.String(), line, column))
+		return false
+	}
+	if typeKinds[name.Value] {
+		tc.Kinds = appendNew(tc.Kinds, name.Value)
+		return true
+	}
+	class, ok := a.Classes[name.Value]
+	if !ok {
+		t, _ := a.matchedType(name)
+		tc.Types = appendNew(tc.Types, t.String())
+		if name.Value == "int" && !exact {
+			tc.Types = appendNew(tc.Types, "bool")
+		}
+		return true
+	}
+	if exact {
+		tc.Types = appendNew(tc.Types, class.InstanceType().String())
+		return true
+	}
+	subclasses := []string{}
+	for _, c := range a.Classes {
+		for p := c; p != nil; p = p.Parent {
+			if p == class {
+				subclasses = append(subclasses, c.InstanceType().String())
+				break
+			}
+		}
+	}
+	sort.Strings(subclasses)
+	for _, subclass := range subclasses {
+		tc.Types = appendNew(tc.Types, subclass)
+	}
+	return true
+}
+
+// appendNew appends name to names unless it is already one of them, as a
+// type switch can't have a type twice.
+func appendNew(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
@@ -0,0 +1,186 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"github.com/sasogeek/simple/compiler/parser"
+	"go/types"
+)
+
+// IteratorKind is a shape of Go value that a for loop can iterate over
+// without being a slice, map or channel.
+type IteratorKind int
+
+const (
+	// ScanIterator is a bufio.Scanner style value: Scan() bool and Text() string.
+	ScanIterator IteratorKind = iota
+	// RowsIterator is a sql.Rows style value: Next() bool, Columns() and
+	// Scan(...) error. Each row is read as a list of column values.
+	RowsIterator
+	// NextIterator has Next() (V, bool).
+	NextIterator
+	// FuncIterator is a Go 1.23 iterator function, func(yield func(V) bool).
+	FuncIterator
+	// ClassIterator is an instance of a class with a __next__ method, which
+	// raises StopIteration after the last value.
+	ClassIterator
+)
+
+// IteratorLoop records a for loop over a Go iterator, or over an iterator
+// defined in Simple.
+type IteratorLoop struct {
+	Kind     IteratorKind
+	ElemType parser.Type // type of the loop variable
+	Value    bool        // the iterable is a struct value rather than a pointer
+}
+
+// goTypeOf returns the Go type behind an imported Simple type, or nil.
+func (a *Analyzer) goTypeOf(t parser.Type) types.Type {
+	switch tt := t.(type) {
+	case *parser.PointerType:
+		if elem := a.goTypeOf(tt.ElementType); elem != nil {
+			return types.NewPointer(elem)
+		}
+	case *parser.NamedType:
+		if named, ok := a.ExternalStructs[tt.String()]; ok {
+			return named
+		}
+		if iface, ok := a.ExternalInterfaces[tt.String()]; ok && iface.GoType != nil {
+			return iface.GoType
+		}
+		// Other named types, such as iterator functions, from imported
+		// packages or the packages they import (iter.Seq for maps.Keys)
+		for _, pkg := range a.importedPackages {
+			if pkg.Types == nil {
+				continue
+			}
+			for _, p := range append([]*types.Package{pkg.Types}, pkg.Types.Imports()...) {
+				if p.Name() != tt.Package {
+					continue
+				}
+				if obj, ok := p.Scope().Lookup(tt.Name).(*types.TypeName); ok {
+					return obj.Type()
+				}
+			}
+		}
+	case *parser.FunctionType:
+		if sig := a.createGoSignatureFromFunctionType(tt); sig != nil {
+			return sig
+		}
+	}
+	return nil
+}
+
+// methodSignature returns the signature of the named method of t, or nil.
+func methodSignature(t types.Type, name string) *types.Signature {
+	ms := types.NewMethodSet(t)
+	if _, isPointer := t.Underlying().(*types.Pointer); !isPointer && !types.IsInterface(t) {
+		// Loop variables are addressable, so pointer methods count too
+		ms = types.NewMethodSet(types.NewPointer(t))
+	}
+	// Exported method names need no package
+	sel := ms.Lookup(nil, name)
+	if sel == nil {
+		return nil
+	}
+	sig, _ := sel.Type().(*types.Signature)
+	return sig
+}
+
+// isKind reports whether t is a basic type of the given kind.
+func isKind(t types.Type, kind types.BasicKind) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Kind() == kind
+}
+
+// returns reports whether sig takes no arguments and returns a single value
+// of the given kind.
+func returns(sig *types.Signature, kind types.BasicKind) bool {
+	return sig != nil && sig.Params().Len() == 0 && sig.Results().Len() == 1 && isKind(sig.Results().At(0).Type(), kind)
+}
+
+// iteratorLoop recognizes the Go iterator shapes a for loop can range over.
+func (a *Analyzer) iteratorLoop(t types.Type) *IteratorLoop {
+	if sig, ok := t.Underlying().(*types.Signature); ok {
+		// func(yield func(V) bool)
+		if sig.Params().Len() == 1 && sig.Results().Len() == 0 {
+			if yield, ok := sig.Params().At(0).Type().Underlying().(*types.Signature); ok && yield.Params().Len() == 1 && yield.Results().Len() == 1 && isKind(yield.Results().At(0).Type(), types.Bool) {
+				return &IteratorLoop{Kind: FuncIterator, ElemType: a.convertGoType(yield.Params().At(0).Type())}
+			}
+		}
+		return nil
+	}
+
+	if returns(methodSignature(t, "Scan"), types.Bool) && returns(methodSignature(t, "Text"), types.String) {
+		return &IteratorLoop{Kind: ScanIterator, ElemType: &parser.BasicType{Name: "string"}}
+	}
+	if scan := methodSignature(t, "Scan"); scan != nil && scan.Variadic() && returns(methodSignature(t, "Next"), types.Bool) && methodSignature(t, "Columns") != nil {
+		_, isStruct := t.Underlying().(*types.Struct)
+		return &IteratorLoop{Kind: RowsIterator, ElemType: &parser.ArrayType{ElementType: &parser.BasicType{Name: "interface{}"}}, Value: isStruct}
+	}
+	if next := methodSignature(t, "Next"); next != nil && next.Params().Len() == 0 && next.Results().Len() == 2 && isKind(next.Results().At(1).Type(), types.Bool) {
+		return &IteratorLoop{Kind: NextIterator, ElemType: a.convertGoType(next.Results().At(0).Type())}
+	}
+	return nil
+}
+
+// handleClassIterable makes a for loop over an instance of a class loop
+// over what the class's __iter__ method returns, as in Python, and records
+// a loop over an instance of a class with a __next__ method, which is
+// called for each value until it raises StopIteration.
+func (a *Analyzer) handleClassIterable(fs *parser.ForStatement, remainingStatements []parser.Statement) {
+	var loop *IteratorLoop
+	fs.Iterable, loop = a.classIterable(fs.Iterable, fs.Token, remainingStatements)
+	if loop != nil {
+		a.IteratorLoops[fs] = loop
+	}
+}
+
+// classIterable returns what a loop over iterable loops over: a call of
+// its __iter__ method if it is an instance of a class with one, or else
+// iterable itself. The loop is returned too when that is an instance of a
+// class with a __next__ method. Instances of other classes can't be looped
+// over.
+func (a *Analyzer) classIterable(iterable parser.Expression, at lexer.Token, remainingStatements []parser.Statement) (parser.Expression, *IteratorLoop) {
+	class, ok := a.ClassOf(a.InferExpressionTypes(iterable, false)[0])
+	if !ok {
+		return iterable, nil
+	}
+	if class.Method("__iter__") != nil && !isMethodCall(iterable, "__iter__") {
+		iterable = methodCall(iterable, "__iter__", at)
+		a.Analyze(iterable, remainingStatements)
+		if class, ok = a.ClassOf(a.InferExpressionTypes(iterable, false)[0]); !ok {
+			return iterable, nil
+		}
+	}
+	if class.Method("__next__") == nil {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' object is not iterable; give the class an __iter__ method, or a __next__ method that raises StopIteration after the last value (Line %d, Column %d)", class.Name, at.Line, at.Column))
+		return iterable, nil
+	}
+	next := methodCall(iterable, "__next__", at)
+	a.Analyze(next, remainingStatements)
+	return iterable, &IteratorLoop{Kind: ClassIterator, ElemType: a.InferExpressionTypes(next, false)[0]}
+}
+
+// methodCall returns a call of the named method of object with no
+// arguments, placed at a token of the code that makes it.
+func methodCall(object parser.Expression, name string, at lexer.Token) *parser.CallExpression {
+	return &parser.CallExpression{
+		Token: lexer.Token{Type: lexer.TokenParenOpen, Literal: "(", Line: at.Line, Column: at.Column},
+		Function: &parser.SelectorExpression{
+			Token:    lexer.Token{Type: lexer.TokenDot, Literal: ".", Line: at.Line, Column: at.Column},
+			Left:     object,
+			Selector: &parser.Identifier{Token: lexer.Token{Type: lexer.TokenIdentifier, Literal: name, Line: at.Line, Column: at.Column}, Value: name},
+		},
+	}
+}
+
+// isMethodCall reports whether expr calls the named method.
+func isMethodCall(expr parser.Expression, name string) bool {
+	ce, ok := expr.(*parser.CallExpression)
+	if !ok {
+		return false
+	}
+	se, ok := ce.Function.(*parser.SelectorExpression)
+	return ok && se.Selector != nil && se.Selector.Value == name
+}
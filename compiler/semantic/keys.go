@@ -0,0 +1,102 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"strings"
+)
+
+// Dict keys and set items
+//
+// Go compares the keys of a map with ==, which lists, dicts and sets don't
+// support, as Python can't hash them, so they can't be keys or set items.
+// An instance of a class is a pointer, which == compares by identity: that
+// is what Python does for a class that doesn't define __eq__. A class that
+// defines __eq__ must define __hash__ too for its instances to be keys, and
+// instances equal by them are then one key: simpleKey finds the key of a
+// map that is equal to another when the program runs, calling __eq__
+// through a simpleEq method generated for the class.
+
+// checkKey reports a value of type t used as a dict key or set item that
+// can't be one, and readies the __eq__ and __hash__ methods of a class that
+// defines them to be called by simpleKey.
+func (a *Analyzer) checkKey(t parser.Type, line, column int) {
+	if tt, ok := a.TupleOf(t); ok {
+		for _, et := range tt.ElementTypes {
+			a.checkKey(et, line, column)
+		}
+		return
+	}
+	class, ok := a.ClassOf(t)
+	if !ok {
+		if kind := typeKind(t); kind == "list" || kind == "dict" || kind == "set" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("unhashable type: '%s'; dict keys and set items are values such as numbers, strings, tuples and objects (Line %d, Column %d)", kind, line, column))
+		}
+		return
+	}
+	eq, hash := class.Method("__eq__"), class.Method("__hash__")
+	switch {
+	case eq == nil:
+		return
+	case hash == nil:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("unhashable type: '%s'; a class with an __eq__ method needs a __hash__ method for its objects to be dict keys or set items (Line %d, Column %d)", class.Name, line, column))
+		return
+	}
+
+	if len(eq.Params()) != 1 || len(hash.Params()) != 0 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s.__eq__ must take one other object to compare with, and %s.__hash__ none, for its objects to be dict keys or set items (Line %d, Column %d)", class.Name, class.Name, line, column))
+		return
+	}
+
+	// __eq__ is given an instance of the class, whatever else it is called
+	// with, as simpleKey calls it
+	if !eq.analyzed && !eq.analyzing {
+		a.analyzeMethod(eq, []parser.Type{class.InstanceType()})
+	}
+	if !hash.analyzed && !hash.analyzing {
+		a.analyzeMethod(hash, []parser.Type{})
+	}
+	if params := eq.Type.ParameterTypes; len(params) != 1 || params[0].String() != t.String() {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s.__eq__ must take one other %s to compare with for its objects to be dict keys or set items (Line %d, Column %d)", class.Name, class.Name, line, column))
+	}
+	if !returnsOnly(eq.Type, "bool") {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s.__eq__ must return a bool (Line %d, Column %d)", class.Name, line, column))
+	}
+	if !returnsOnly(hash.Type, "int") {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s.__hash__ must return an int (Line %d, Column %d)", class.Name, line, column))
+	}
+	a.KeyClasses[class.Name] = true
+}
+
+// returnsOnly reports whether a function returns one value, of type name.
+func returnsOnly(ft *parser.FunctionType, name string) bool {
+	return len(ft.ReturnTypes) == 1 && ft.ReturnTypes[0].String() == name
+}
+
+// KeyedByValue reports whether keys of type t are objects of a class whose
+// __eq__ and __hash__ methods compare them.
+func (a *Analyzer) KeyedByValue(t parser.Type) bool {
+	class, ok := a.ClassOf(t)
+	return ok && a.KeyClasses[class.Name]
+}
+
+// handleMapLiteral analyzes the keys and values of a dict literal and
+// reports keys that can't be keys.
+func (a *Analyzer) handleMapLiteral(ml *parser.MapLiteral, remainingStatements []parser.Statement) {
+	for _, key := range ml.Keys {
+		a.Analyze(key, remainingStatements)
+		a.Analyze(ml.Pairs[key], remainingStatements)
+	}
+	a.refineMapLiteral(ml)
+	if ml.KeyType != nil {
+		a.checkKey(ml.KeyType, ml.Token.Line, ml.Token.Column)
+	}
+}
+
+// checkIndexKey reports a key that can't be one, indexing a dict.
+func (a *Analyzer) checkIndexKey(ie *parser.IndexExpression, leftType parser.Type) {
+	if _, ok := SetOf(leftType); ok || ie.Index == nil || !strings.HasPrefix(leftType.String(), "map[") {
+		return
+	}
+	a.checkKey(a.InferExpressionTypes(ie.Index, false)[0], ie.Token.Line, ie.Token.Column)
+}
@@ -0,0 +1,84 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"strings"
+)
+
+// Keyword arguments
+//
+// A function defined with def, in the program or in a module it imports,
+// may be called with keyword arguments, as in greet(name="bob"). They are
+// put in the order of the parameters, so the call is generated as one with
+// positional arguments. Go functions have no keyword arguments, and other
+// calls given them are reported, except for those of the builtins that
+// take some and of Go structs, which are constructed from them.
+
+// bindKeywords puts the keyword arguments of a call of the function of
+// type ft, nil when the function isn't typed as one, in the order of its
+// parameters, reporting errors as Python does. It reports whether the call
+// can be analyzed further.
+func (a *Analyzer) bindKeywords(ce *parser.CallExpression, ft *parser.FunctionType) bool {
+	keywords := false
+	for _, arg := range ce.Arguments {
+		if _, ok := arg.(*parser.KeywordArgument); ok {
+			keywords = true
+		}
+	}
+	if !keywords {
+		return true
+	}
+	name := ce.Function.String()
+	_, defined := a.functions[ft]
+	if se, ok := ce.Function.(*parser.SelectorExpression); ok && ft != nil && a.simpleModules[se.Left.String()] {
+		defined = true
+	}
+	if !defined {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() takes no keyword arguments; only functions defined with def and Go structs do (Line %d, Column %d)", name, ce.Token.Line, ce.Token.Column))
+		return false
+	}
+
+	args := make([]parser.Expression, len(ft.Parameters))
+	for i, arg := range ce.Arguments {
+		position := i
+		if ka, ok := arg.(*parser.KeywordArgument); ok {
+			arg = ka.Value
+			position = -1
+			for j, param := range ft.Parameters {
+				if param.Value == ka.Name.Value {
+					position = j
+				}
+			}
+			if position < 0 {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() got an unexpected keyword argument '%s' (Line %d, Column %d)", name, ka.Name.Value, ce.Token.Line, ce.Token.Column))
+				return false
+			}
+		} else if position >= len(args) {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() takes %d positional arguments but more were given (Line %d, Column %d)", name, len(args), ce.Token.Line, ce.Token.Column))
+			return false
+		}
+		if args[position] != nil {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() got multiple values for argument '%s' (Line %d, Column %d)", name, ft.Parameters[position].Value, ce.Token.Line, ce.Token.Column))
+			return false
+		}
+		args[position] = arg
+	}
+	missing := []string{}
+	for i, arg := range args {
+		if arg == nil {
+			missing = append(missing, "'"+ft.Parameters[i].Value+"'")
+		}
+	}
+	switch {
+	case len(missing) == 1:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() missing 1 required positional argument: %s (Line %d, Column %d)", name, missing[0], ce.Token.Line, ce.Token.Column))
+		return false
+	case len(missing) > 1:
+		names := strings.Join(missing[:len(missing)-1], ", ") + " and " + missing[len(missing)-1]
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() missing %d required positional arguments: %s (Line %d, Column %d)", name, len(missing), names, ce.Token.Line, ce.Token.Column))
+		return false
+	}
+	ce.Arguments = args
+	return true
+}
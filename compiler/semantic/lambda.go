@@ -0,0 +1,69 @@
+package semantic
+
+import (
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// Lambda records a lambda expression: its type, whose parameters are typed
+// by where the lambda is used, and the scope of its parameters.
+type Lambda struct {
+	Type  *parser.FunctionType
+	Scope *SymbolTable
+}
+
+// bindLambda types the parameters of a lambda and infers the type of its
+// body from them. Parameters without a type from the context are
+// interface{}.
+func (a *Analyzer) bindLambda(le *parser.LambdaExpression, paramTypes []parser.Type) *Lambda {
+	scope := NewSymbolTable(a.CurrentTable, "lambda")
+	ft := &parser.FunctionType{}
+	for i, param := range le.Parameters {
+		var paramType parser.Type = &parser.BasicType{Name: "interface{}"}
+		if i < len(paramTypes) {
+			paramType = paramTypes[i]
+		}
+		ft.Parameters = append(ft.Parameters, *param)
+		ft.ParameterTypes = append(ft.ParameterTypes, paramType)
+		scope.Define(param.Value, &Symbol{
+			Name:     param.Value,
+			Type:     paramType,
+			Scope:    scope.Name,
+			GoType:   a.GetGoTypeFromParserType(paramType),
+			Metadata: map[string]any{"set": true},
+		})
+	}
+
+	prevTable := a.CurrentTable
+	a.CurrentTable = scope
+	a.Analyze(le.Body, []parser.Statement{})
+	ft.ReturnTypes = a.InferExpressionTypes(le.Body, false)[:1]
+	a.CurrentTable = prevTable
+
+	lambda := &Lambda{Type: ft, Scope: scope}
+	a.Lambdas[le] = lambda
+	return lambda
+}
+
+// bindCalledLambda types the parameters of a lambda called where it is
+// written, such as those chained comparisons are expanded into, by the
+// arguments of the call.
+func (a *Analyzer) bindCalledLambda(ce *parser.CallExpression) {
+	le, ok := ce.Function.(*parser.LambdaExpression)
+	if !ok {
+		return
+	}
+	argTypes := []parser.Type{}
+	for _, arg := range ce.Arguments {
+		argTypes = append(argTypes, a.InferExpressionTypes(arg, false)[0])
+	}
+	a.bindLambda(le, argTypes)
+}
+
+// LambdaOf returns the analysis of a lambda, binding it without parameter
+// types if no context has typed it.
+func (a *Analyzer) LambdaOf(le *parser.LambdaExpression) *Lambda {
+	if lambda, ok := a.Lambdas[le]; ok {
+		return lambda
+	}
+	return a.bindLambda(le, nil)
+}
@@ -0,0 +1,78 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"go/types"
+	"strings"
+)
+
+// LenCall records a call of len that Go's len can't make: the length of an
+// instance of a class is what its __len__ method returns, and that of a Go
+// value with a Len method, such as a strings.Builder, what Len returns. The
+// length of a string is its number of characters, as in Python, rather than
+// of bytes. Values of no known type are measured when the program runs.
+type LenCall struct {
+	Method  *parser.CallExpression // the call of __len__ or Len, or nil
+	String  bool                   // the value is a string
+	Untyped bool                   // the value has no known type
+}
+
+// handleLen checks a call of len and records how the length is found when
+// Go's len can't find it.
+func (a *Analyzer) handleLen(ce *parser.CallExpression) {
+	if len(ce.Arguments) != 1 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("len() takes exactly one argument (%d given) (Line %d, Column %d)", len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	arg := ce.Arguments[0]
+	a.Analyze(arg, []parser.Statement{})
+	t := a.InferExpressionTypes(arg, false)[0]
+	if _, ok := a.TupleOf(t); ok {
+		return
+	}
+
+	if class, ok := a.ClassOf(t); ok {
+		if class.Method("__len__") == nil {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("object of type '%s' has no len(); give the class a __len__ method (Line %d, Column %d)", class.Name, ce.Token.Line, ce.Token.Column))
+			return
+		}
+		method := methodCall(arg, "__len__", ce.Token)
+		a.Analyze(method, []parser.Statement{})
+		if result := a.InferExpressionTypes(method, false)[0].String(); result != "int" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s.__len__ must return an int, not a value of type %s (Line %d, Column %d)", class.Name, result, ce.Token.Line, ce.Token.Column))
+			return
+		}
+		a.LenCalls[ce] = &LenCall{Method: method}
+		return
+	}
+
+	name := t.String()
+	switch {
+	case name == "interface{}" || name == "any":
+		a.LenCalls[ce] = &LenCall{Untyped: true}
+		return
+	case name == "string":
+		a.LenCalls[ce] = &LenCall{String: true}
+		return
+	case strings.HasPrefix(name, "[]"), strings.HasPrefix(name, "map["), strings.HasPrefix(name, "chan"):
+		return
+	}
+	if goType := a.GetGoTypeFromParserType(t); goType != nil {
+		if returns(methodSignature(goType, "Len"), types.Int) {
+			method := methodCall(arg, "Len", ce.Token)
+			a.Analyze(method, []parser.Statement{})
+			a.LenCalls[ce] = &LenCall{Method: method}
+			return
+		}
+		switch goType.Underlying().(type) {
+		case *types.Slice, *types.Map, *types.Chan, *types.Array:
+			return
+		case *types.Basic:
+			if isKind(goType, types.String) {
+				return
+			}
+		}
+	}
+	a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("object of type '%s' has no len() (Line %d, Column %d)", typeKind(t), ce.Token.Line, ce.Token.Column))
+}
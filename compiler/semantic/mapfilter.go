@@ -0,0 +1,62 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// handleMapFilter analyzes a call of map(f, items) or filter(pred, items)
+// as the list comprehension it makes, recorded in MapCalls: map is
+// [f(item) for item in items] and filter [item for item in items if
+// pred(item)], so both build a typed list with a loop. A lambda is
+// written out in place, its parameter the loop variable, as in
+// [x * 2 for x in items] for map(lambda x: x * 2, items), and filter(None,
+// items) keeps the items that are true. The lists are made at once, where
+// Python 3 makes iterators, which a loop over them can't tell apart.
+func (a *Analyzer) handleMapFilter(ce *parser.CallExpression) {
+	if _, ok := a.MapCalls[ce]; ok {
+		return
+	}
+	name := ce.Function.String()
+	for _, arg := range ce.Arguments {
+		if ka, ok := arg.(*parser.KeywordArgument); ok {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' is an invalid keyword argument for %s() (Line %d, Column %d)", ka.Name.Value, name, ka.Token.Line, ka.Token.Column))
+			return
+		}
+	}
+	if len(ce.Arguments) != 2 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() takes a function and a list (%d arguments given) (Line %d, Column %d)", name, len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+		return
+	}
+
+	item := &parser.Identifier{Token: lexer.Token{Type: lexer.TokenIdentifier, Literal: "item", Line: ce.Token.Line, Column: ce.Token.Column}, Value: "item"}
+	var result parser.Expression
+	switch f := ce.Arguments[0].(type) {
+	case *parser.LambdaExpression:
+		if len(f.Parameters) != 1 {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("the function of %s() takes one argument, not %d (Line %d, Column %d)", name, len(f.Parameters), f.Token.Line, f.Token.Column))
+			return
+		}
+		item, result = f.Parameters[0], f.Body
+	case *parser.NoneLiteral:
+		if name == "map" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("map() calls a function on each item, so it can't be None (Line %d, Column %d)", ce.Token.Line, ce.Token.Column))
+			return
+		}
+		result = item
+	default:
+		result = &parser.CallExpression{Token: ce.Token, Function: f, Arguments: []parser.Expression{item}}
+	}
+
+	lc := &parser.ListComprehension{
+		Token:   ce.Token,
+		Element: result,
+		Clause:  &parser.ForClause{Token: ce.Token, Variables: []*parser.Identifier{item}, Iterable: ce.Arguments[1]},
+	}
+	if name == "filter" {
+		lc.Element, lc.Clause.Condition = item, result
+	}
+	a.MapCalls[ce] = lc
+	a.Analyze(lc, []parser.Statement{})
+}
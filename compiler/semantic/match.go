@@ -0,0 +1,146 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// Match statements
+//
+// A match statement is generated as a Go switch. Cases matching values are
+// cases of a switch on the subject; cases matching types, with class
+// patterns such as int() or Dog(), are cases of a type switch on it. A
+// match statement can't do both.
+
+// Match records how a match statement is generated.
+type Match struct {
+	Types    bool                                // the cases match types, in a type switch
+	Patterns map[*parser.CasePattern]parser.Type // the types class patterns match
+	Scopes   map[*parser.MatchCase]*SymbolTable  // scopes of the cases binding names to the subject
+}
+
+// matchTypes are the types of Simple that class patterns can name, with
+// the Go types they match.
+var matchTypes = map[string]string{
+	"int":   "int",
+	"float": "float64",
+	"str":   "string",
+	"bool":  "bool",
+}
+
+// handleMatchStatement analyzes a match statement, its patterns and the
+// bodies of its cases.
+func (a *Analyzer) handleMatchStatement(ms *parser.MatchStatement, remainingStatements []parser.Statement) {
+	a.Analyze(ms.Subject, remainingStatements)
+	subjectType := a.InferExpressionTypes(ms.Subject, false)[0]
+	m := &Match{Patterns: map[*parser.CasePattern]parser.Type{}, Scopes: map[*parser.MatchCase]*SymbolTable{}}
+	a.Matches[ms] = m
+
+	values := false
+	for _, mc := range ms.Cases {
+		for _, pattern := range mc.Patterns {
+			values = values || pattern.Value != nil
+			m.Types = m.Types || pattern.Type != nil
+		}
+	}
+	if values && m.Types {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("a match statement compares values or matches types, not both (Line %d, Column %d)", ms.Token.Line, ms.Token.Column))
+		return
+	}
+
+	seen := map[string]bool{}
+	for i, mc := range ms.Cases {
+		if mc.Guard != nil {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("case guards aren't supported in Simple; test the condition with an if in the case (Line %d, Column %d)", mc.Token.Line, mc.Token.Column))
+			continue
+		}
+
+		var bound parser.Type = subjectType
+		names := []*parser.Identifier{}
+		if mc.Name != nil {
+			names = append(names, mc.Name)
+		}
+		for _, pattern := range mc.Patterns {
+			switch {
+			case pattern.Value != nil:
+				a.Analyze(pattern.Value, []parser.Statement{})
+				valueType := a.InferExpressionTypes(pattern.Value, false)[0]
+				if !canEqual(subjectType, valueType) {
+					a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("case %s can never match %s of type %s (Line %d, Column %d)", pattern.String(), ms.Subject.String(), subjectType.String(), pattern.Token.Line, pattern.Token.Column))
+				}
+			case pattern.Type != nil:
+				t, ok := a.matchedType(pattern.Type)
+				if !ok {
+					a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() isn't a type a case can match; use int(), float(), str(), bool() or a class (Line %d, Column %d)", pattern.Type.Value, pattern.Token.Line, pattern.Token.Column))
+					continue
+				}
+				m.Patterns[pattern] = t
+				bound = t
+			default:
+				// _ and capture patterns match anything, so nothing can
+				// follow them
+				if i < len(ms.Cases)-1 || len(mc.Patterns) > 1 {
+					a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("case %s matches anything, making the patterns after it unreachable (Line %d, Column %d)", pattern.String(), pattern.Token.Line, pattern.Token.Column))
+				}
+			}
+			if pattern.Capture != nil {
+				if len(mc.Patterns) > 1 {
+					a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("case %s binds %s in only one of its alternatives; use as after them (Line %d, Column %d)", mc.Patterns[0].String(), pattern.Capture.Value, pattern.Token.Line, pattern.Token.Column))
+				}
+				names = append(names, pattern.Capture)
+			}
+			if seen[pattern.String()] && (pattern.Value != nil || pattern.Type != nil) {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("case %s is already matched above (Line %d, Column %d)", pattern.String(), pattern.Token.Line, pattern.Token.Column))
+			}
+			seen[pattern.String()] = true
+		}
+		// Alternatives of several types leave the subject untyped
+		if m.Types && len(mc.Patterns) > 1 {
+			bound = &parser.BasicType{Name: "interface{}"}
+		}
+
+		if len(names) == 0 {
+			a.Analyze(mc.Body, remainingStatements)
+			continue
+		}
+		scope := NewSymbolTable(a.CurrentTable, "case")
+		for _, name := range names {
+			scope.Define(name.Value, &Symbol{
+				Name:     name.Value,
+				Type:     bound,
+				Scope:    scope.Name,
+				GoType:   a.GetGoTypeFromParserType(bound),
+				Metadata: map[string]any{"set": true},
+			})
+		}
+		m.Scopes[mc] = scope
+		prevTable := a.CurrentTable
+		a.CurrentTable = scope
+		a.Analyze(mc.Body, remainingStatements)
+		a.CurrentTable = prevTable
+	}
+}
+
+// matchedType returns the type a class pattern matches: a type of Simple
+// or a class of the program.
+func (a *Analyzer) matchedType(name *parser.Identifier) (parser.Type, bool) {
+	if t, ok := matchTypes[name.Value]; ok {
+		return &parser.BasicType{Name: t}, true
+	}
+	if class, ok := a.Classes[name.Value]; ok {
+		return class.InstanceType(), true
+	}
+	return nil, false
+}
+
+// canEqual reports whether a subject of type subject can equal a value of
+// type value. Values of unknown type, and numbers of either kind, can.
+func canEqual(subject, value parser.Type) bool {
+	known := map[string]bool{"int": true, "float": true, "float64": true, "string": true, "bool": true}
+	s, v := subject.String(), value.String()
+	if !known[s] || !known[v] {
+		return true
+	}
+	numbers := map[string]bool{"int": true, "float": true, "float64": true}
+	return s == v || (numbers[s] && numbers[v])
+}
@@ -0,0 +1,67 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"go/types"
+)
+
+// Membership tests
+//
+// item in container is true when item is a key of a dict, an item of a
+// list, set or tuple, or a substring of a string. The type of the container
+// chooses how it is generated: a map lookup, a scan of a slice or
+// strings.Contains, or, for containers of no known type, a check when the
+// program runs.
+
+// Membership records an in test.
+type Membership struct {
+	Kind     string      // "dict", "list", "string", or "any" when untyped or a tuple
+	ItemType parser.Type // the type of the keys, items or substrings looked for
+	Direct   bool        // Go can look for the item itself, having its type
+}
+
+// handleMembership analyzes item in container, and reports containers that
+// can't hold anything and items they can never hold.
+func (a *Analyzer) handleMembership(ie *parser.InfixExpression, remainingStatements []parser.Statement) {
+	a.Analyze(ie.Left, remainingStatements)
+	a.Analyze(ie.Right, remainingStatements)
+	containerType := a.InferExpressionTypes(ie.Right, false)[0]
+	itemType := a.InferExpressionTypes(ie.Left, false)[0]
+	m := &Membership{}
+	m.Kind, m.ItemType = itemsOf(containerType)
+	a.Memberships[ie] = m
+
+	// A list is scanned with Go's == only for items it compares as Python
+	// does, and 1 in [1.0] needs converting
+	item, want := a.GetGoTypeFromParserType(itemType), a.GetGoTypeFromParserType(m.ItemType)
+	if item != nil && want != nil && types.Identical(item, want) {
+		basic, ok := item.(*types.Basic)
+		m.Direct = m.Kind == "dict" || m.Kind == "string" || (m.Kind == "list" && ok && basic.Info()&(types.IsNumeric|types.IsString|types.IsBoolean) != 0)
+	}
+
+	switch m.Kind {
+	case "any":
+		name := containerType.String()
+		if _, ok := a.TupleOf(containerType); !ok && name != "interface{}" && name != "any" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("in can't look inside a value of type %s; use a list, dict, tuple or string (Line %d, Column %d)", name, ie.Token.Line, ie.Token.Column))
+		}
+	case "string":
+		if name := itemType.String(); name != "string" && name != "interface{}" && name != "any" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("in a string looks for a string, not a value of type %s (Line %d, Column %d)", name, ie.Token.Line, ie.Token.Column))
+		}
+	default:
+		if m.Kind == "dict" {
+			a.checkKey(itemType, ie.Token.Line, ie.Token.Column)
+		}
+		if !canEqual(m.ItemType, itemType) {
+			kind, what := m.Kind, "items"
+			if _, ok := SetOf(containerType); ok {
+				kind = "set"
+			} else if m.Kind == "dict" {
+				what = "keys"
+			}
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("a value of type %s can never be in a %s of %s %s (Line %d, Column %d)", itemType.String(), kind, m.ItemType.String(), what, ie.Token.Line, ie.Token.Column))
+		}
+	}
+}
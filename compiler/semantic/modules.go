@@ -0,0 +1,307 @@
+package semantic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"github.com/sasogeek/simple/compiler/parser"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// Simple modules
+//
+// A Simple module is compiled into a Go package of its own, but the program
+// importing it is analyzed first, so the analyzer reads the signatures of
+// the module's functions from a summary of its symbol table. The summary is
+// kept next to the module's package and made again, by analyzing the module,
+// when its source changes.
+
+// StdlibModules are the modules of the stdlib, which are imported from
+// StdlibDir and compiled into the lib directory of the program's package.
+var StdlibModules = map[string]bool{
+	"datetime": true,
+	"json":     true,
+	"storage":  true,
+}
+
+// StdlibDir returns the directory of the stdlib modules, ~/simple/stdlib.
+func StdlibDir() string {
+	usr, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(usr.HomeDir, "simple/stdlib")
+}
+
+// summaryFile is the name of a module's summary in its package.
+const summaryFile = "simple_symbols.json"
+
+// summaryVersion changes when the form of summaries does, making older ones
+// stale.
+const summaryVersion = 3
+
+// ModuleSummary describes what a Simple module defines for the programs
+// importing it.
+type ModuleSummary struct {
+	Version   int                         `json:"version"`
+	Source    string                      `json:"source"` // SHA-256 of the module's source
+	Functions map[string]*FunctionSummary `json:"functions"`
+}
+
+// FunctionSummary is the signature of a function of a module.
+type FunctionSummary struct {
+	Params     []string       `json:"params"`
+	ParamTypes []*TypeSummary `json:"param_types"`
+	Annotated  []bool         `json:"annotated,omitempty"` // which parameters are annotated with their types
+	Results    []*TypeSummary `json:"results"`
+}
+
+// TypeSummary is a parser.Type written down. Named types keep the path of
+// their package, which the importing program loads to use them.
+type TypeSummary struct {
+	Kind     string         `json:"kind"` // basic, named, pointer, array, map, tuple or func
+	Name     string         `json:"name,omitempty"`
+	Package  string         `json:"package,omitempty"`
+	Elem     *TypeSummary   `json:"elem,omitempty"` // element of a pointer, array or map
+	Key      *TypeSummary   `json:"key,omitempty"`
+	Elements []*TypeSummary `json:"elements,omitempty"` // items of a tuple, parameters of a func
+	Results  []*TypeSummary `json:"results,omitempty"`
+}
+
+// modulesInProgress are the sources of the modules being analyzed for their
+// summaries, so that modules importing each other don't recurse forever.
+var modulesInProgress = map[string]bool{}
+
+// importSimpleModule makes the functions of an imported Simple module known
+// as name.function, with the types its summary gives them.
+func (a *Analyzer) importSimpleModule(spec *parser.ImportSpec) {
+	module := spec.ImportedModule.Value
+	if a.OutputDir == "" {
+		return
+	}
+	// Modules are found where the code generator looks for them
+	source, packageDir := filepath.Join(filepath.Dir(a.OutputDir), module+".simple"), filepath.Join(a.OutputDir, module)
+	if StdlibModules[module] {
+		source, packageDir = filepath.Join(StdlibDir(), module+".simple"), filepath.Join(a.OutputDir, "lib", module)
+	}
+	data, err := os.ReadFile(source)
+	if err != nil || modulesInProgress[source] {
+		// The code generator reports modules it can't read
+		return
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	path := filepath.Join(packageDir, summaryFile)
+	summary, err := readSummary(path)
+	if err != nil || summary.Version != summaryVersion || summary.Source != hash {
+		modulesInProgress[source] = true
+		summary = summarizeModule(string(data), packageDir, StdlibModules[module])
+		delete(modulesInProgress, source)
+		if summary == nil {
+			return
+		}
+		summary.Source = hash
+		if err := writeSummary(path, summary); err != nil {
+			a.errors = append(a.errors, fmt.Sprintf("Failed to write the summary of %s: %v", module, err))
+		}
+	}
+
+	// Modules that don't compile are reported by the code generator, so
+	// only calls to the modules summarized are checked
+	a.simpleModules[spec.Name()] = true
+	for name, function := range summary.Functions {
+		ft := &parser.FunctionType{ReturnTypes: []parser.Type{&parser.BasicType{Name: "void"}}}
+		if len(function.Results) > 0 {
+			ft.ReturnTypes = nil
+		}
+		for i, param := range function.Params {
+			ft.Parameters = append(ft.Parameters, parser.Identifier{Value: param})
+			ft.ParameterTypes = append(ft.ParameterTypes, a.summarizedType(function.ParamTypes[i]))
+		}
+		// An annotated parameter's type is the annotation's, which calls
+		// are checked against as they are within the module
+		for i, annotated := range function.Annotated {
+			if annotated && i < len(ft.ParameterTypes) && ft.ParameterTypes[i].String() != "interface{}" {
+				if a.moduleAnnotations[ft] == nil {
+					a.moduleAnnotations[ft] = make([]parser.Type, len(ft.ParameterTypes))
+				}
+				a.moduleAnnotations[ft][i] = ft.ParameterTypes[i]
+			}
+		}
+		for _, result := range function.Results {
+			ft.ReturnTypes = append(ft.ReturnTypes, a.summarizedType(result))
+		}
+		// Calls name a function by any spelling with its Go name, so
+		// json.dumps calls Dumps
+		for _, variant := range []string{strings.ToLower(name[:1]) + name[1:], GoName(name)} {
+			qualified := spec.Name() + "." + variant
+			a.GlobalTable.Define(qualified, &Symbol{
+				Name:   qualified,
+				Type:   ft,
+				Scope:  a.GlobalTable.Name,
+				GoType: a.createGoSignatureFromFunctionType(ft),
+			})
+		}
+	}
+}
+
+// summarizeModule analyzes the source of a module, whose package is
+// generated in packageDir, and summarizes its functions. It returns nil
+// when the module doesn't compile; the code generator reports its errors.
+// The stdlib is trusted, so the active policy doesn't apply to it.
+func summarizeModule(source, packageDir string, stdlib bool) *ModuleSummary {
+	if stdlib {
+		policy := ActivePolicy
+		ActivePolicy = Policy{}
+		defer func() { ActivePolicy = policy }()
+	}
+	program := parser.NewParser(lexer.NewLexer(source)).ParseProgram()
+	a := NewAnalyzer()
+	a.OutputDir = packageDir
+	a.Analyze(program, []parser.Statement{})
+	a.CheckExports(program)
+	if len(a.fatalErrors) > 0 {
+		return nil
+	}
+
+	summary := &ModuleSummary{Version: summaryVersion, Functions: map[string]*FunctionSummary{}}
+	for _, stmt := range program.Statements {
+		fl, ok := stmt.(*parser.FunctionLiteral)
+		if !ok || fl == nil || IsPrivate(fl.Name.Value) {
+			continue
+		}
+		symbol, ok := a.GlobalTable.Symbols[fl.Name.Value]
+		if !ok {
+			continue
+		}
+		ft, ok := symbol.Type.(*parser.FunctionType)
+		if !ok {
+			continue
+		}
+		function := &FunctionSummary{}
+		sig := a.annotations(fl)
+		for i, param := range ft.Parameters {
+			function.Params = append(function.Params, param.Value)
+			function.ParamTypes = append(function.ParamTypes, a.typeSummary(ft.ParameterTypes[i]))
+			function.Annotated = append(function.Annotated, i < len(sig.params) && sig.params[i] != nil)
+		}
+		for _, result := range ft.ReturnTypes {
+			if result.String() != "void" {
+				function.Results = append(function.Results, a.typeSummary(result))
+			}
+		}
+		summary.Functions[fl.Name.Value] = function
+	}
+	return summary
+}
+
+// typeSummary writes down a type. Types the summary can't describe, such as
+// the module's classes, are left unknown.
+func (a *Analyzer) typeSummary(t parser.Type) *TypeSummary {
+	switch t := t.(type) {
+	case *parser.BasicType:
+		return &TypeSummary{Kind: "basic", Name: t.Name}
+	case *parser.NamedType:
+		path, ok := a.PkgPaths[t.Package]
+		if !ok {
+			path = t.Package
+		}
+		return &TypeSummary{Kind: "named", Name: t.Name, Package: path}
+	case *parser.PointerType:
+		return &TypeSummary{Kind: "pointer", Elem: a.typeSummary(t.ElementType)}
+	case *parser.ArrayType:
+		return &TypeSummary{Kind: "array", Elem: a.typeSummary(t.ElementType)}
+	case *parser.MapType:
+		return &TypeSummary{Kind: "map", Key: a.typeSummary(t.KeyType), Elem: a.typeSummary(t.ValueType)}
+	case *parser.TupleType:
+		summary := &TypeSummary{Kind: "tuple"}
+		for _, et := range t.ElementTypes {
+			summary.Elements = append(summary.Elements, a.typeSummary(et))
+		}
+		return summary
+	case *parser.FunctionType:
+		summary := &TypeSummary{Kind: "func"}
+		for _, pt := range t.ParameterTypes {
+			summary.Elements = append(summary.Elements, a.typeSummary(pt))
+		}
+		for _, rt := range t.ReturnTypes {
+			if rt.String() != "void" {
+				summary.Results = append(summary.Results, a.typeSummary(rt))
+			}
+		}
+		return summary
+	}
+	return &TypeSummary{Kind: "basic", Name: "interface{}"}
+}
+
+// summarizedType reads a type written down by typeSummary, loading the
+// packages of named types.
+func (a *Analyzer) summarizedType(s *TypeSummary) parser.Type {
+	if s == nil {
+		return &parser.BasicType{Name: "interface{}"}
+	}
+	switch s.Kind {
+	case "named":
+		if s.Package == "" {
+			return &parser.NamedType{Name: s.Name}
+		}
+		a.importGoPackage(s.Package, "")
+		return &parser.NamedType{Name: s.Name, Package: s.Package[strings.LastIndex(s.Package, "/")+1:]}
+	case "pointer":
+		return &parser.PointerType{ElementType: a.summarizedType(s.Elem)}
+	case "array":
+		return &parser.ArrayType{ElementType: a.summarizedType(s.Elem)}
+	case "map":
+		return &parser.MapType{KeyType: a.summarizedType(s.Key), ValueType: a.summarizedType(s.Elem)}
+	case "tuple":
+		tuple := &parser.TupleType{}
+		for _, et := range s.Elements {
+			tuple.ElementTypes = append(tuple.ElementTypes, a.summarizedType(et))
+		}
+		return tuple
+	case "func":
+		ft := &parser.FunctionType{ReturnTypes: []parser.Type{&parser.BasicType{Name: "void"}}}
+		for _, pt := range s.Elements {
+			ft.ParameterTypes = append(ft.ParameterTypes, a.summarizedType(pt))
+		}
+		if len(s.Results) > 0 {
+			ft.ReturnTypes = nil
+		}
+		for _, rt := range s.Results {
+			ft.ReturnTypes = append(ft.ReturnTypes, a.summarizedType(rt))
+		}
+		return ft
+	}
+	return &parser.BasicType{Name: s.Name}
+}
+
+// readSummary reads the summary of a module.
+func readSummary(path string) (*ModuleSummary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	summary := &ModuleSummary{}
+	if err := json.Unmarshal(data, summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// writeSummary writes the summary of a module into its package.
+func writeSummary(path string, summary *ModuleSummary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,63 @@
+package semantic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Policy restricts what the analyzer may do on behalf of a program. It is
+// used to enforce project rules and to analyze untrusted code, for example
+// in a playground.
+//
+// Import rules are Go package paths, or path prefixes ending in "/..."
+// ("github.com/acme/..." matches every package under github.com/acme).
+type Policy struct {
+	// Offline stops the analyzer from running `go get` for third-party modules.
+	Offline bool
+	// Allow, when non-empty, lists the only Go packages a program may import.
+	Allow []string
+	// Deny lists Go packages a program may never import. Deny wins over Allow.
+	Deny []string
+	// Hook, when set, is called for every Go import that passes the rules
+	// above; a non-nil error rejects the import.
+	Hook func(modulePath string) error
+}
+
+// ActivePolicy applies to every Analyzer, including the ones created for
+// imported Simple modules. The zero value allows everything.
+var ActivePolicy Policy
+
+// matchImportRule reports whether modulePath matches a single import rule.
+func matchImportRule(rule, modulePath string) bool {
+	if prefix, ok := strings.CutSuffix(rule, "/..."); ok {
+		return modulePath == prefix || strings.HasPrefix(modulePath, prefix+"/")
+	}
+	return rule == modulePath
+}
+
+// checkImport reports whether the active policy allows importing modulePath.
+func checkImport(modulePath string) error {
+	for _, rule := range ActivePolicy.Deny {
+		if matchImportRule(rule, modulePath) {
+			return fmt.Errorf("import of %q is denied by policy rule %q", modulePath, rule)
+		}
+	}
+	if len(ActivePolicy.Allow) > 0 {
+		allowed := false
+		for _, rule := range ActivePolicy.Allow {
+			if matchImportRule(rule, modulePath) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("import of %q is not in the policy's allowed imports", modulePath)
+		}
+	}
+	if ActivePolicy.Hook != nil {
+		if err := ActivePolicy.Hook(modulePath); err != nil {
+			return fmt.Errorf("import of %q rejected by policy: %v", modulePath, err)
+		}
+	}
+	return nil
+}
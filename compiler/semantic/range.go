@@ -0,0 +1,81 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// Range records a for loop over range(start, stop, step), which counts from
+// start up to stop, or down to it when step is negative: for i in range(10):
+// is Go's for i := range 10, and for i in range(10, 0, -2): is for i := 10;
+// i > 0; i -= 2.
+type Range struct {
+	Start parser.Expression // nil for 0
+	Stop  parser.Expression
+	Step  parser.Expression // nil for 1
+	// StepValue is the step when it is a constant, as in range(10, 0, -2),
+	// or 0 when it is known only as the program runs.
+	StepValue int64
+}
+
+// handleRange analyzes a for loop over range(), defining its variable as an
+// int. It returns false for a loop over anything else.
+func (a *Analyzer) handleRange(fs *parser.ForStatement) bool {
+	ce, ok := fs.Iterable.(*parser.CallExpression)
+	if !ok || !a.IsBuiltinCall(ce, "range") {
+		return false
+	}
+	a.CurrentTable.Define(fs.Variable.Value, &Symbol{
+		Name:  fs.Variable.Value,
+		Type:  &parser.BasicType{Name: "int"},
+		Scope: a.CurrentTable.Name,
+	})
+
+	for _, arg := range ce.Arguments {
+		if ka, ok := arg.(*parser.KeywordArgument); ok {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("range() takes no keyword arguments, but was given '%s' (Line %d, Column %d)", ka.Name.Value, ka.Token.Line, ka.Token.Column))
+			return true
+		}
+		a.Analyze(arg, []parser.Statement{})
+		if t := a.InferExpressionTypes(arg, false)[0]; t.String() != "int" && t.String() != "interface{}" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("range() counts in ints, not %s (Line %d, Column %d)", annotationName(t), ce.Token.Line, ce.Token.Column))
+			return true
+		}
+	}
+	r := &Range{StepValue: 1}
+	switch args := ce.Arguments; len(args) {
+	case 1:
+		r.Stop = args[0]
+	case 2:
+		r.Start, r.Stop = args[0], args[1]
+	case 3:
+		r.Start, r.Stop, r.Step = args[0], args[1], args[2]
+		step, constant := ConstantInt(r.Step)
+		if constant && step == 0 {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("the step of range() must not be zero (Line %d, Column %d)", ce.Token.Line, ce.Token.Column))
+			return true
+		}
+		r.StepValue = step
+	default:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("range() takes a stop, or a start, a stop and an optional step (%d arguments given) (Line %d, Column %d)", len(args), ce.Token.Line, ce.Token.Column))
+		return true
+	}
+	a.Ranges[fs] = r
+	return true
+}
+
+// ConstantInt returns the value of an int literal, negated or not, and
+// whether the expression is one.
+func ConstantInt(expr parser.Expression) (int64, bool) {
+	switch e := expr.(type) {
+	case *parser.IntegerLiteral:
+		value, ok := e.Value.(int64)
+		return value, ok
+	case *parser.PrefixExpression:
+		if e.Operator == "-" {
+			value, ok := ConstantInt(e.Right)
+			return -value, ok
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,103 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// Scopes
+//
+// As in Python, assigning a name in a function makes a local variable of
+// the function, even if the module or an enclosing function has a variable
+// of that name. A global statement makes the function's assignments to its
+// names rebind the module's variables instead, and a nonlocal statement
+// those of the enclosing function that has them. Variables declared global
+// are generated as Go package variables, which functions and main share,
+// and a nested function is a Go closure, which can assign the variables of
+// the function around it.
+
+// blockScopes are the tables that aren't the scope of a function or of the
+// module: their assignments make variables of the function they are in.
+var blockScopes = map[string]bool{"comprehension": true, "lambda": true, "case": true}
+
+// functionTable returns the table of the function being analyzed, or the
+// global table at the top level.
+func (a *Analyzer) functionTable() *SymbolTable {
+	t := a.CurrentTable
+	for t.Outer != nil && blockScopes[t.Name] {
+		t = t.Outer
+	}
+	return t
+}
+
+// bindingTable returns the table an assignment to name rebinds the variable
+// of, with the variable if it has one yet: the table a global or nonlocal
+// statement names, or else the current table, whose variables are those of
+// the function being analyzed, not of the functions or module around it.
+func (a *Analyzer) bindingTable(name string) (*SymbolTable, *Symbol, bool) {
+	fn := a.functionTable()
+	if table, ok := fn.Declared[name]; ok {
+		symbol, exists := table.Symbols[name]
+		return table, symbol, exists
+	}
+	for t := a.CurrentTable; t != nil; t = t.Outer {
+		if symbol, ok := t.Symbols[name]; ok {
+			return a.CurrentTable, symbol, true
+		}
+		if t == fn {
+			break
+		}
+	}
+	return a.CurrentTable, nil, false
+}
+
+// handleGlobalStatement records the names a global or nonlocal statement
+// declares in the function it is in, reporting names that are local
+// variables already and nonlocal names no enclosing function has.
+func (a *Analyzer) handleGlobalStatement(gs *parser.GlobalStatement) {
+	fn := a.functionTable()
+	keyword := gs.Token.Literal
+	if fn == a.GlobalTable {
+		if keyword == "nonlocal" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("nonlocal declaration not allowed at module level (Line %d, Column %d)", gs.Token.Line, gs.Token.Column))
+		}
+		return
+	}
+	for _, name := range gs.Names {
+		if _, declared := fn.Declared[name.Value]; declared {
+			continue
+		}
+		if _, local := fn.Symbols[name.Value]; local {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("name '%s' is a parameter or is assigned to before the %s declaration (Line %d, Column %d)", name.Value, keyword, name.Token.Line, name.Token.Column))
+			continue
+		}
+		table := a.GlobalTable
+		if keyword == "nonlocal" {
+			table = enclosingTable(fn.Outer, a.GlobalTable, name.Value)
+			if table == nil {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("no binding for nonlocal '%s' found; assign it in an enclosing function before the def (Line %d, Column %d)", name.Value, name.Token.Line, name.Token.Column))
+				continue
+			}
+		} else {
+			a.Globals[name.Value] = true
+		}
+		if fn.Declared == nil {
+			fn.Declared = map[string]*SymbolTable{}
+		}
+		fn.Declared[name.Value] = table
+	}
+}
+
+// enclosingTable returns the table, from t outwards but inside global, that
+// has a variable name, or nil.
+func enclosingTable(t, global *SymbolTable, name string) *SymbolTable {
+	for ; t != nil && t != global; t = t.Outer {
+		if _, ok := t.Symbols[name]; ok {
+			return t
+		}
+		if declared, ok := t.Declared[name]; ok && declared != global {
+			return declared
+		}
+	}
+	return nil
+}
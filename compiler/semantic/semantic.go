@@ -18,13 +18,18 @@ type Symbol struct {
 	Scope    string // "global", "local", "builtin", "imported"
 	GoType   types.Type
 	Metadata map[string]any
+	// Annotated is set on a parameter whose type is annotated, which no
+	// call or assignment changes
+	Annotated bool
 }
 
 // SymbolTable represents a symbol table with scope chaining.
 type SymbolTable struct {
-	Symbols map[string]*Symbol
-	Outer   *SymbolTable
-	Name    string
+	Symbols  map[string]*Symbol
+	Outer    *SymbolTable
+	Name     string
+	Declared map[string]*SymbolTable // names declared global or nonlocal, by the table of their variables
+	Deleted  map[string]int          // variables deleted by del, by the line of the del, until assigned again
 }
 
 type SymbolTables struct {
@@ -60,6 +65,7 @@ type ExternalInterface struct {
 	Name        string
 	MethodNames []string
 	Methods     []*parser.FunctionType
+	GoType      *types.Interface
 }
 
 // Analyzer performs semantic analysis on the AST.
@@ -68,12 +74,54 @@ type Analyzer struct {
 	CurrentTable        *SymbolTable
 	SymbolTables        *SymbolTables
 	errors              []string
+	warnings            []string
+	fatalErrors         []string
 	importedPackages    map[string]*packages.Package
 	PkgPaths            map[string]string
 	WrapFunctionCalls   map[*parser.CallExpression][]WrapperInfo
+	Adapters            map[string]*Adapter
 	ExternalFuncs       map[string]*parser.FunctionType // key: "package.Func"
 	ExternalInterfaces  map[string]*ExternalInterface
+	ExternalStructs     map[string]*types.Named // key: "package.Type"
+	StructLiterals      map[*parser.CallExpression]*StructLiteral
+	StructConversions   map[*parser.CallExpression]*StructConversion
+	IteratorLoops       map[*parser.ForStatement]*IteratorLoop
+	Enumerations        map[*parser.ForStatement]*Enumeration
+	Zips                map[*parser.ForStatement]*Zip
+	Ranges              map[*parser.ForStatement]*Range
+	ContextManagers     map[*parser.WithItem]*ContextManager
+	Decorations         map[*parser.FunctionLiteral]*Decoration
+	Generators          map[*parser.FunctionLiteral]parser.Type // generators, by the type of the values they yield
+	Matches             map[*parser.MatchStatement]*Match
+	OutputDir           string                                           // where the Go package is generated; its Simple modules are summarized in it
+	simpleModules       map[string]bool                                  // names bound to imported Simple modules
+	functions           map[*parser.FunctionType]*parser.FunctionLiteral // functions defined with def, by their types
+	decorated           map[*parser.FunctionLiteral]parser.Type          // decorators, by the type of function they take
+	signatures          map[*parser.FunctionLiteral]*signature           // the types of functions' annotations
+	moduleAnnotations   map[*parser.FunctionType][]parser.Type           // the annotated parameter types of imported modules' functions
+	Lambdas             map[*parser.LambdaExpression]*Lambda
+	SortedCalls         map[*parser.CallExpression]*SortedCall
+	AggregateCalls      map[*parser.CallExpression]*AggregateCall
+	MapCalls            map[*parser.CallExpression]*parser.ListComprehension // map() and filter(), as the comprehensions they make
+	SetCalls            map[*parser.CallExpression]*SetCall
+	LenCalls            map[*parser.CallExpression]*LenCall
+	TypeChecks          map[parser.Expression]*TypeCheck // isinstance() and comparisons of type() with a type
+	Memberships         map[*parser.InfixExpression]*Membership
+	Comprehensions      map[*parser.ForClause]*Comprehension
+	StringFormats       map[parser.Expression]*parser.FStringLiteral // % and format() as f-strings
+	Tuples              map[string]*parser.TupleType                 // key: the Go type of the tuple
+	TupleIndexes        map[*parser.IndexExpression]int
+	UntypedIndexes      map[*parser.IndexExpression]bool             // indexes of values of no known type
+	ResultTuples        map[*parser.CallExpression]*parser.TupleType // calls with several results used as one value
+	Classes             map[string]*Class
+	SuperCalls          map[*parser.CallExpression]*Class // super() calls, by the parent class they stand for
+	KeyClasses          map[string]bool                   // classes whose objects are keys compared by __eq__ and __hash__
+	Globals             map[string]bool                   // module variables functions declare global
+	currentClass        *Class                            // the class whose method is being analyzed
+	exceptionClasses    map[string]string                 // exception classes of the program, by the classes they inherit from
+	reraises            map[*parser.RaiseStatement]bool   // bare raise statements in except clauses
 	ExternalConstants   map[string]parser.Type
+	Constants           map[string]*parser.AssignmentStatement // top-level variables assigned once to a literal
 	ExpectedReturnTypes map[*parser.CallExpression][]parser.Type
 	Objects             []map[string]map[string]string
 	Assignments         map[string]map[string][]string
@@ -90,9 +138,47 @@ func NewAnalyzer() *Analyzer {
 		importedPackages:    make(map[string]*packages.Package),
 		PkgPaths:            make(map[string]string),
 		WrapFunctionCalls:   make(map[*parser.CallExpression][]WrapperInfo),
+		Adapters:            make(map[string]*Adapter),
 		ExternalFuncs:       make(map[string]*parser.FunctionType),
 		ExternalInterfaces:  make(map[string]*ExternalInterface),
+		ExternalStructs:     make(map[string]*types.Named),
+		StructLiterals:      make(map[*parser.CallExpression]*StructLiteral),
+		StructConversions:   make(map[*parser.CallExpression]*StructConversion),
+		IteratorLoops:       make(map[*parser.ForStatement]*IteratorLoop),
+		Enumerations:        make(map[*parser.ForStatement]*Enumeration),
+		Zips:                make(map[*parser.ForStatement]*Zip),
+		Ranges:              make(map[*parser.ForStatement]*Range),
+		ContextManagers:     make(map[*parser.WithItem]*ContextManager),
+		Decorations:         make(map[*parser.FunctionLiteral]*Decoration),
+		Generators:          make(map[*parser.FunctionLiteral]parser.Type),
+		Matches:             make(map[*parser.MatchStatement]*Match),
+		simpleModules:       make(map[string]bool),
+		functions:           make(map[*parser.FunctionType]*parser.FunctionLiteral),
+		decorated:           make(map[*parser.FunctionLiteral]parser.Type),
+		signatures:          make(map[*parser.FunctionLiteral]*signature),
+		moduleAnnotations:   make(map[*parser.FunctionType][]parser.Type),
+		Lambdas:             make(map[*parser.LambdaExpression]*Lambda),
+		SortedCalls:         make(map[*parser.CallExpression]*SortedCall),
+		AggregateCalls:      make(map[*parser.CallExpression]*AggregateCall),
+		MapCalls:            make(map[*parser.CallExpression]*parser.ListComprehension),
+		SetCalls:            make(map[*parser.CallExpression]*SetCall),
+		LenCalls:            make(map[*parser.CallExpression]*LenCall),
+		TypeChecks:          make(map[parser.Expression]*TypeCheck),
+		Memberships:         make(map[*parser.InfixExpression]*Membership),
+		Comprehensions:      make(map[*parser.ForClause]*Comprehension),
+		StringFormats:       make(map[parser.Expression]*parser.FStringLiteral),
+		Tuples:              make(map[string]*parser.TupleType),
+		TupleIndexes:        make(map[*parser.IndexExpression]int),
+		UntypedIndexes:      make(map[*parser.IndexExpression]bool),
+		ResultTuples:        make(map[*parser.CallExpression]*parser.TupleType),
+		Classes:             make(map[string]*Class),
+		SuperCalls:          make(map[*parser.CallExpression]*Class),
+		KeyClasses:          make(map[string]bool),
+		Globals:             make(map[string]bool),
+		exceptionClasses:    make(map[string]string),
+		reraises:            make(map[*parser.RaiseStatement]bool),
 		ExternalConstants:   make(map[string]parser.Type),
+		Constants:           make(map[string]*parser.AssignmentStatement),
 		ExpectedReturnTypes: make(map[*parser.CallExpression][]parser.Type),
 		Objects:             []map[string]map[string]string{},
 		Assignments:         make(map[string]map[string][]string),
@@ -109,6 +195,15 @@ type WrapperInfo struct {
 	Wrapper  string
 }
 
+// Adapter is a generated function type that implements a single-method Go
+// interface by calling itself, so Simple functions can be passed where the
+// interface is expected.
+type Adapter struct {
+	Name      string
+	Interface *ExternalInterface
+	Method    *types.Func
+}
+
 func (a *Analyzer) GetGoTypeFromParserType(pt parser.Type) types.Type {
 	switch t := pt.(type) {
 	case *parser.BasicType:
@@ -119,13 +214,16 @@ func (a *Analyzer) GetGoTypeFromParserType(pt parser.Type) types.Type {
 			return types.Typ[types.String]
 		case "bool":
 			return types.Typ[types.Bool]
-		case "float":
+		case "float", "float64":
 			return types.Typ[types.Float64]
 		case "untyped float":
 			return types.Typ[types.UntypedFloat]
 		case "void":
 			return types.Typ[types.UnsafePointer] // Represents 'void' as an unsafe pointer
 		default:
+			if class, ok := a.ClassOf(t); ok {
+				return types.NewPointer(class.Named)
+			}
 			// Attempt to resolve the type from the symbol table (e.g., imported types)
 			symbol, ok := a.GlobalTable.Resolve(t.Name)
 			if ok {
@@ -153,6 +251,19 @@ func (a *Analyzer) GetGoTypeFromParserType(pt parser.Type) types.Type {
 		}
 		// Default to struct{} if not found
 		return types.NewStruct(nil, nil)
+	case *parser.PointerType, *parser.NamedType:
+		// Types from imported packages, such as the client of a gRPC
+		// service, so that their methods can be looked up
+		if goType := a.goTypeOf(t); goType != nil {
+			return goType
+		}
+		return types.NewInterface(nil, nil)
+	case *parser.TupleType:
+		fields := []*types.Var{}
+		for i, et := range t.ElementTypes {
+			fields = append(fields, types.NewField(token.NoPos, nil, fmt.Sprintf("Item%d", i), a.GetGoTypeFromParserType(et), false))
+		}
+		return types.NewStruct(fields, nil)
 	default:
 		// Default to interface{} for unknown type kinds
 		return types.NewInterface(nil, nil)
@@ -183,6 +294,10 @@ func (a *Analyzer) createGoSignatureFromFunctionType(ft *parser.FunctionType) *t
 	if len(ft.ReturnTypes) > 0 {
 		var resultVars []*types.Var
 		for i, rt := range ft.ReturnTypes {
+			// A function returning nothing has no Go results
+			if rt.String() == "void" {
+				continue
+			}
 			returnType := a.GetGoTypeFromParserType(rt)
 			if returnType == nil {
 				a.errors = append(a.errors, fmt.Sprintf("Unknown return type: %s", rt.String()))
@@ -206,6 +321,19 @@ func (a *Analyzer) Errors() []string {
 	return a.errors
 }
 
+// FatalErrors returns the errors found during analysis that must stop
+// compilation, such as policy violations. Unlike Errors, which may contain
+// noise from incomplete inference, these are always reported.
+func (a *Analyzer) FatalErrors() []string {
+	return a.fatalErrors
+}
+
+// Warnings returns diagnostics that are worth showing the user, such as a
+// value passed where a Go interface it does not implement is expected.
+func (a *Analyzer) Warnings() []string {
+	return a.warnings
+}
+
 // initBuiltins adds built-in functions to the global symbol table.
 func (a *Analyzer) initBuiltins() {
 	// Define the 'print' built-in function
@@ -221,39 +349,386 @@ func (a *Analyzer) initBuiltins() {
 	}
 	a.GlobalTable.Define("print", symbol)
 
+	// Define the 'to_struct' built-in function
+	toStructFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}, &parser.BasicType{Name: "interface{}"}},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "interface{}"}},
+	}
+	a.GlobalTable.Define("to_struct", &Symbol{
+		Name:   "to_struct",
+		Type:   toStructFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(toStructFunctionType),
+	})
+
+	// Define the 'to_dict' built-in function, the reverse of to_struct
+	toDictFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "map[string]interface{}"}},
+	}
+	a.GlobalTable.Define("to_dict", &Symbol{
+		Name:   "to_dict",
+		Type:   toDictFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(toDictFunctionType),
+	})
+
+	// Define the 'str' and 'repr' built-in functions
+	for _, name := range []string{"str", "repr"} {
+		formatFunctionType := &parser.FunctionType{
+			ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}},
+			ReturnTypes:    []parser.Type{&parser.BasicType{Name: "string"}},
+		}
+		a.GlobalTable.Define(name, &Symbol{
+			Name:   name,
+			Type:   formatFunctionType,
+			Scope:  "builtin",
+			GoType: a.createGoSignatureFromFunctionType(formatFunctionType),
+		})
+	}
+
+	// Define the 'copy' and 'deepcopy' built-in functions. A copy has the
+	// type of its argument; see InferExpressionTypes.
+	for _, name := range []string{"copy", "deepcopy"} {
+		copyFunctionType := &parser.FunctionType{
+			ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}},
+			ReturnTypes:    []parser.Type{&parser.BasicType{Name: "interface{}"}},
+		}
+		a.GlobalTable.Define(name, &Symbol{
+			Name:   name,
+			Type:   copyFunctionType,
+			Scope:  "builtin",
+			GoType: a.createGoSignatureFromFunctionType(copyFunctionType),
+		})
+	}
+
+	// Define the 'sorted' built-in function. It returns a list of the
+	// items of its argument; see handleSorted.
+	sortedFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "[]interface{}"}},
+	}
+	a.GlobalTable.Define("sorted", &Symbol{
+		Name:   "sorted",
+		Type:   sortedFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(sortedFunctionType),
+	})
+
+	// Define the 'min', 'max' and 'sum' built-in functions. Their type
+	// depends on what they aggregate; see handleAggregate.
+	for _, name := range []string{"min", "max", "sum"} {
+		functionType := &parser.FunctionType{
+			ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}},
+			ReturnTypes:    []parser.Type{&parser.BasicType{Name: "interface{}"}},
+		}
+		a.GlobalTable.Define(name, &Symbol{
+			Name:   name,
+			Type:   functionType,
+			Scope:  "builtin",
+			GoType: a.createGoSignatureFromFunctionType(functionType),
+		})
+	}
+
+	// Define the 'map' and 'filter' built-in functions. They return a list
+	// made from the items of their second argument; see handleMapFilter.
+	for _, name := range []string{"map", "filter"} {
+		functionType := &parser.FunctionType{
+			ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}, &parser.BasicType{Name: "interface{}"}},
+			ReturnTypes:    []parser.Type{&parser.BasicType{Name: "[]interface{}"}},
+		}
+		a.GlobalTable.Define(name, &Symbol{
+			Name:   name,
+			Type:   functionType,
+			Scope:  "builtin",
+			GoType: a.createGoSignatureFromFunctionType(functionType),
+		})
+	}
+
+	// Define the 'len' built-in function; see handleLen.
+	lenFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "int"}},
+	}
+	a.GlobalTable.Define("len", &Symbol{
+		Name:   "len",
+		Type:   lenFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(lenFunctionType),
+	})
+
+	// Define the 'set' built-in function. It returns a set of the items of
+	// its argument, or an empty set; see handleSetCall.
+	setFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}},
+		ReturnTypes:    []parser.Type{setTypeOf(&parser.BasicType{Name: "interface{}"})},
+	}
+	a.GlobalTable.Define("set", &Symbol{
+		Name:   "set",
+		Type:   setFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(setFunctionType),
+	})
+
+	// Define the 'format' built-in function, which formats a value with a
+	// format spec as an f-string would; see handleFormatBuiltin.
+	formatBuiltinType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}, &parser.BasicType{Name: "string"}},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "string"}},
+	}
+	a.GlobalTable.Define("format", &Symbol{
+		Name:   "format",
+		Type:   formatBuiltinType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(formatBuiltinType),
+	})
+
+	// Define the 'round' built-in function. Its type depends on its
+	// arguments, of which the digits are optional; see roundType.
+	roundFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "interface{}"}},
+	}
+	a.GlobalTable.Define("round", &Symbol{
+		Name:   "round",
+		Type:   roundFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(roundFunctionType),
+	})
+
+	// Define the 'input' built-in function, which reads a line of standard
+	// input after writing its optional prompt.
+	inputFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "string"}},
+	}
+	a.GlobalTable.Define("input", &Symbol{
+		Name:   "input",
+		Type:   inputFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(inputFunctionType),
+	})
+
+	// Define the 'isinstance' and 'type' built-in functions, which test the
+	// type of a value at run time; see handleIsInstance and handleTypeCall.
+	isinstanceFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}, &parser.BasicType{Name: "interface{}"}},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "bool"}},
+	}
+	a.GlobalTable.Define("isinstance", &Symbol{
+		Name:   "isinstance",
+		Type:   isinstanceFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(isinstanceFunctionType),
+	})
+	typeFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "string"}},
+	}
+	a.GlobalTable.Define("type", &Symbol{
+		Name:   "type",
+		Type:   typeFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(typeFunctionType),
+	})
+
+	// Define the 'open' built-in function, which opens a file; see
+	// handleOpen.
+	openFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "string"}},
+		ReturnTypes:    []parser.Type{fileType},
+	}
+	a.GlobalTable.Define("open", &Symbol{
+		Name:   "open",
+		Type:   openFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(openFunctionType),
+	})
+
+	// Define the 'enumerate' built-in function, which a for loop iterates
+	// over; see handleEnumerate.
+	enumerateFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "interface{}"}},
+	}
+	a.GlobalTable.Define("enumerate", &Symbol{
+		Name:   "enumerate",
+		Type:   enumerateFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(enumerateFunctionType),
+	})
+
+	// Define the 'zip' built-in function, which a for loop iterates over;
+	// see handleZip.
+	zipFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "interface{}"}},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "interface{}"}},
+	}
+	a.GlobalTable.Define("zip", &Symbol{
+		Name:   "zip",
+		Type:   zipFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(zipFunctionType),
+	})
+
+	// Define the 'range' built-in function, which a for loop iterates over;
+	// see handleRange.
+	rangeFunctionType := &parser.FunctionType{
+		ParameterTypes: []parser.Type{&parser.BasicType{Name: "int"}},
+		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "interface{}"}},
+	}
+	a.GlobalTable.Define("range", &Symbol{
+		Name:   "range",
+		Type:   rangeFunctionType,
+		Scope:  "builtin",
+		GoType: a.createGoSignatureFromFunctionType(rangeFunctionType),
+	})
+
 	// Add other built-in functions if needed
 }
 
+// IsBuiltinCall reports whether ce calls the named built-in function rather
+// than a function of the program with the same name.
+func (a *Analyzer) IsBuiltinCall(ce *parser.CallExpression, name string) bool {
+	ident, ok := ce.Function.(*parser.Identifier)
+	if !ok || ident.Value != name {
+		return false
+	}
+	symbol, found := a.GlobalTable.Resolve(name)
+	return found && symbol.Scope == "builtin"
+}
+
 // Analyze performs semantic analysis on the AST node.
 func (a *Analyzer) Analyze(node parser.Node, remainingStatements []parser.Statement) {
 	switch n := node.(type) {
 	case *parser.Program:
 		if n != nil {
+			parser.ResolveImportNames(n)
+			a.checkImportNames(n)
+			a.findConstants(n)
+			a.checkLoopControl(n.Statements, false)
 			for i, stmt := range n.Statements {
 				a.Analyze(stmt, n.Statements[i+1:])
 			}
+			a.finishClasses(n)
 		}
 	case *parser.FunctionLiteral:
 		if n != nil {
 			a.handleFunctionLiteral(n)
+			if len(n.Decorators) > 0 {
+				a.handleDecorators(n)
+			}
+		}
+	case *parser.ClassStatement:
+		if n != nil {
+			a.handleClassStatement(n)
 		}
 	case *parser.ExpressionStatement:
+		if n != nil {
+			a.Analyze(n.Expression, remainingStatements)
+			a.keepResults(n.Expression)
+		}
+	case *parser.DeferStatement:
+		if n != nil {
+			a.Analyze(n.Expression, remainingStatements)
+		}
+	case *parser.GoStatement:
 		if n != nil {
 			a.Analyze(n.Expression, remainingStatements)
 		}
 
 	case *parser.CallExpression:
 		if n != nil {
+			a.bindCalledLambda(n)
 			a.handleCallExpression(n)
 		}
 	case *parser.AssignmentStatement:
-		if n != nil {
+		if n != nil && n.Operator != "" {
+			a.handleAugmentedAssignment(n, remainingStatements)
+		} else if n != nil {
 			a.handleAssignmentStatement(n, remainingStatements)
 		}
 	case *parser.Identifier:
 		if n != nil {
 			a.handleIdentifier(n, false)
 		}
+	case *parser.FStringLiteral:
+		if n != nil {
+			a.handleFStringLiteral(n)
+		}
+	case *parser.InfixExpression:
+		if n != nil && (n.Operator == "in" || n.Operator == "not in") {
+			a.handleMembership(n, remainingStatements)
+		} else if n != nil && a.handleTypeComparison(n) {
+			// type(x) == T, analyzed as a type check
+		} else if n != nil && (n.Operator == "is" || n.Operator == "is not") {
+			a.handleIdentity(n, remainingStatements)
+		} else if n != nil && (n.Operator == "|" || n.Operator == "&" || n.Operator == "^" || n.Operator == "-") {
+			a.handleSetOperators(n, remainingStatements)
+		} else if n != nil && (n.Operator == "**" || n.Operator == "//") {
+			a.handleArithmeticOperators(n, remainingStatements)
+		} else if n != nil && a.isStringOperator(n) {
+			a.handleStringOperators(n, remainingStatements)
+		} else if n != nil && !(n.Operator == "%" && a.handlePercentFormat(n)) {
+			a.Analyze(n.Left, remainingStatements)
+			a.Analyze(n.Right, remainingStatements)
+		}
+	case *parser.PrefixExpression:
+		if n != nil {
+			a.Analyze(n.Right, remainingStatements)
+		}
+	case *parser.ArrayLiteral:
+		if n != nil {
+			for _, el := range n.Elements {
+				a.Analyze(el, remainingStatements)
+			}
+		}
+	case *parser.TupleLiteral:
+		if n != nil {
+			for _, el := range n.Elements {
+				a.Analyze(el, remainingStatements)
+			}
+		}
+	case *parser.MapLiteral:
+		if n != nil {
+			a.handleMapLiteral(n, remainingStatements)
+		}
+	case *parser.SetLiteral:
+		if n != nil {
+			a.handleSetLiteral(n, remainingStatements)
+		}
+	case *parser.IndexExpression:
+		if n != nil {
+			a.handleIndexExpression(n, remainingStatements)
+		}
+	case *parser.SelectorExpression:
+		if n != nil {
+			a.handleSelectorExpression(n)
+		}
+	case *parser.LambdaExpression:
+		if n != nil {
+			a.LambdaOf(n)
+		}
+	case *parser.ConditionalExpression:
+		if n != nil {
+			a.handleConditionalExpression(n, remainingStatements)
+		}
+	case *parser.AssignmentExpression:
+		if n != nil {
+			a.handleAssignmentExpression(n, remainingStatements)
+		}
+	case *parser.ListComprehension:
+		if n != nil {
+			a.comprehensionOf(n.Clause, n.Element)
+		}
+	case *parser.DictComprehension:
+		if n != nil {
+			a.comprehensionOf(n.Clause, n.Key, n.Value)
+		}
+	case *parser.GlobalStatement:
+		if n != nil {
+			a.handleGlobalStatement(n)
+		}
 	case *parser.IfStatement:
 		if n != nil {
 			a.Analyze(n.Condition, remainingStatements)
@@ -266,8 +741,14 @@ func (a *Analyzer) Analyze(node parser.Node, remainingStatements []parser.Statem
 			a.Analyze(n.Body, remainingStatements)
 		}
 	case *parser.ForStatement:
-		if n != nil {
+		if n != nil && (a.handleSeveralVariables(n) || a.handleRange(n)) {
+			a.Analyze(n.Body, remainingStatements)
+		} else if n != nil {
 			a.Analyze(n.Iterable, remainingStatements)
+			a.handleClassIterable(n, remainingStatements)
+			if _, ok := a.TupleOf(a.InferExpressionTypes(n.Iterable, false)[0]); ok {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("iterating over a tuple isn't supported; use a list (Line %d, Column %d)", n.Token.Line, n.Token.Column))
+			}
 			switch n.Iterable.(type) {
 			case *parser.Identifier:
 				symbol, found := a.CurrentTable.Resolve(n.Iterable.(*parser.Identifier).Value)
@@ -284,7 +765,7 @@ func (a *Analyzer) Analyze(node parser.Node, remainingStatements []parser.Statem
 						default:
 							a.CurrentTable.Define(n.Variable.Value, &Symbol{
 								Name:  n.Variable.Value,
-								Type:  &parser.BasicType{Name: "int"}, // Initial type
+								Type:  loopVariableType(symbol.Type.(*parser.BasicType).Name),
 								Scope: a.CurrentTable.Name,
 							})
 						}
@@ -302,12 +783,84 @@ func (a *Analyzer) Analyze(node parser.Node, remainingStatements []parser.Statem
 						Scope: a.CurrentTable.Name,
 					})
 				}
+			case *parser.CallExpression:
+				if sc, ok := a.SortedCalls[n.Iterable.(*parser.CallExpression)]; ok {
+					a.CurrentTable.Define(n.Variable.Value, &Symbol{
+						Name:  n.Variable.Value,
+						Type:  sc.ItemType,
+						Scope: a.CurrentTable.Name,
+					})
+				} else if name := a.InferExpressionTypes(n.Iterable, false)[0].String(); strings.HasPrefix(name, "[]") || strings.HasPrefix(name, "map[") {
+					a.CurrentTable.Define(n.Variable.Value, &Symbol{
+						Name:  n.Variable.Value,
+						Type:  loopVariableType(name),
+						Scope: a.CurrentTable.Name,
+					})
+				}
+			case *parser.SelectorExpression, *parser.IndexExpression:
+				// A field, or an item of a list or dict, holding a list or dict
+				if name := a.InferExpressionTypes(n.Iterable, false)[0].String(); strings.HasPrefix(name, "[]") || strings.HasPrefix(name, "map[") {
+					a.CurrentTable.Define(n.Variable.Value, &Symbol{
+						Name:  n.Variable.Value,
+						Type:  loopVariableType(name),
+						Scope: a.CurrentTable.Name,
+					})
+				}
+			}
+			if iterableTypes := a.InferExpressionTypes(n.Iterable, false); len(iterableTypes) > 0 {
+				if goType := a.goTypeOf(iterableTypes[0]); goType != nil {
+					if loop := a.iteratorLoop(goType); loop != nil {
+						a.IteratorLoops[n] = loop
+						a.CurrentTable.Define(n.Variable.Value, &Symbol{
+							Name:   n.Variable.Value,
+							Type:   loop.ElemType,
+							Scope:  a.CurrentTable.Name,
+							GoType: a.GetGoTypeFromParserType(loop.ElemType),
+						})
+					}
+				}
+			}
+			if loop, ok := a.IteratorLoops[n]; ok && loop.Kind == ClassIterator {
+				a.CurrentTable.Define(n.Variable.Value, &Symbol{
+					Name:  n.Variable.Value,
+					Type:  loop.ElemType,
+					Scope: a.CurrentTable.Name,
+				})
 			}
 			a.Analyze(n.Body, remainingStatements)
 		}
+	case *parser.TryStatement:
+		if n != nil {
+			a.handleTryStatement(n, remainingStatements)
+		}
+	case *parser.WithStatement:
+		if n != nil {
+			a.handleWithStatement(n, remainingStatements)
+		}
+	case *parser.MatchStatement:
+		if n != nil {
+			a.handleMatchStatement(n, remainingStatements)
+		}
+	case *parser.RaiseStatement:
+		if n != nil {
+			a.handleRaiseStatement(n)
+		}
+	case *parser.AssertStatement:
+		if n != nil {
+			a.handleAssertStatement(n, remainingStatements)
+		}
+	case *parser.DelStatement:
+		if n != nil {
+			a.handleDelStatement(n, remainingStatements)
+		}
 	case *parser.ReturnStatement:
 		if n != nil {
 			a.Analyze(n.ReturnValue, remainingStatements)
+			a.keepResults(n.ReturnValue)
+		}
+	case *parser.YieldStatement:
+		if n != nil {
+			a.handleYieldStatement(n, remainingStatements)
 		}
 	case *parser.BlockStatement:
 		if n != nil {
@@ -323,6 +876,89 @@ func (a *Analyzer) Analyze(node parser.Node, remainingStatements []parser.Statem
 	}
 }
 
+// loopVariableType returns the type of the variable of a for loop over a
+// value of the named type: the items of a list, the keys of a dict, or
+// otherwise an int.
+func loopVariableType(name string) parser.Type {
+	switch {
+	case strings.HasPrefix(name, "[]"):
+		return &parser.BasicType{Name: name[len("[]"):]}
+	case strings.HasPrefix(name, "map["):
+		depth := 0
+		for i, ch := range name[len("map"):] {
+			switch ch {
+			case '[':
+				depth++
+			case ']':
+				depth--
+				if depth == 0 {
+					return &parser.BasicType{Name: name[len("map[") : len("map")+i]}
+				}
+			}
+		}
+	}
+	return &parser.BasicType{Name: "int"}
+}
+
+// checkLoopControl reports break and continue statements that are not
+// inside a loop. A function body starts outside any loop, even when the
+// function is defined inside one.
+func (a *Analyzer) checkLoopControl(statements []parser.Statement, inLoop bool) {
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case *parser.BreakStatement:
+			if !inLoop {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'break' outside loop (Line %d, Column %d)", s.Token.Line, s.Token.Column))
+			}
+		case *parser.ContinueStatement:
+			if !inLoop {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'continue' outside loop (Line %d, Column %d)", s.Token.Line, s.Token.Column))
+			}
+		case *parser.IfStatement:
+			// A statement the parser couldn't finish is left as a nil node
+			if s == nil {
+				continue
+			}
+			if s.Consequence != nil {
+				a.checkLoopControl(s.Consequence.Statements, inLoop)
+			}
+			if s.Alternative != nil {
+				a.checkLoopControl(s.Alternative.Statements, inLoop)
+			}
+		case *parser.WhileStatement:
+			if s != nil && s.Body != nil {
+				a.checkLoopControl(s.Body.Statements, true)
+			}
+		case *parser.ForStatement:
+			if s != nil && s.Body != nil {
+				a.checkLoopControl(s.Body.Statements, true)
+			}
+		case *parser.TryStatement:
+			if s == nil {
+				continue
+			}
+			for _, block := range s.Blocks() {
+				a.checkLoopControl(block.Statements, inLoop)
+			}
+		case *parser.WithStatement:
+			if s != nil && s.Body != nil {
+				a.checkLoopControl(s.Body.Statements, inLoop)
+			}
+		case *parser.MatchStatement:
+			if s == nil {
+				continue
+			}
+			for _, block := range s.Blocks() {
+				a.checkLoopControl(block.Statements, inLoop)
+			}
+		case *parser.FunctionLiteral:
+			if s != nil && s.Body != nil {
+				a.checkLoopControl(s.Body.Statements, false)
+			}
+		}
+	}
+}
+
 // handleFunctionLiteral processes function definitions.
 func (a *Analyzer) handleFunctionLiteral(fl *parser.FunctionLiteral) {
 	// Initialize function type with parameter types and 'void' return type
@@ -335,8 +971,16 @@ func (a *Analyzer) handleFunctionLiteral(fl *parser.FunctionLiteral) {
 	} else {
 		a.CurrentTable = NewSymbolTable(prevTable, fl.Name.Value)
 	}
+	sig := a.annotations(fl)
 	for i := range fl.Parameters {
 		paramTypes[i] = &parser.BasicType{Name: "interface{}"} // Initial type
+		if t, ok := a.decorated[fl]; ok && i == 0 {
+			// A decorator takes the function it decorates
+			paramTypes[i] = t
+		}
+		if sig.params[i] != nil {
+			paramTypes[i] = sig.params[i]
+		}
 		params[i] = *fl.Parameters[i]
 		paramSymbol := &Symbol{
 			Name:  fl.Parameters[i].Value,
@@ -354,6 +998,8 @@ func (a *Analyzer) handleFunctionLiteral(fl *parser.FunctionLiteral) {
 		ReturnTypes:    []parser.Type{&parser.BasicType{Name: "void"}},
 	}
 
+	a.functions[functionType] = fl
+
 	// Define the function symbol in the global table
 	symbol := &Symbol{
 		Name:  fl.Name.Value,
@@ -386,10 +1032,11 @@ func (a *Analyzer) handleFunctionLiteral(fl *parser.FunctionLiteral) {
 			continue
 		}
 		a.CurrentTable.Define(param.Value, &Symbol{
-			Name:   param.Value,
-			Type:   paramTypes[i],
-			Scope:  fl.Name.Value,
-			GoType: paramType,
+			Name:      param.Value,
+			Type:      paramTypes[i],
+			Scope:     fl.Name.Value,
+			GoType:    paramType,
+			Annotated: sig.params[i] != nil,
 		})
 	}
 
@@ -402,7 +1049,15 @@ func (a *Analyzer) handleFunctionLiteral(fl *parser.FunctionLiteral) {
 	a.InferFunctionParameterTypes(fl, funcTable)
 
 	// Infer return types based on return statements
-	functionType.ReturnTypes = a.InferFunctionReturnType(fl.Body, funcTable)
+	switch {
+	case isGenerator(fl.Body):
+		functionType.ReturnTypes = a.generatorResults(fl, funcTable)
+	case sig.result != nil:
+		a.checkReturnAnnotation(fl, sig.result, funcTable)
+		functionType.ReturnTypes = []parser.Type{sig.result}
+	default:
+		functionType.ReturnTypes = a.InferFunctionReturnType(fl.Body, funcTable)
+	}
 
 	// Update the function's GoType based on inferred return types
 	functionTypeInferred := a.createGoSignatureFromFunctionType(functionType)
@@ -433,6 +1088,12 @@ func (a *Analyzer) InferFunctionParameterTypes(fl *parser.FunctionLiteral, funcT
 				a.updateParameterType(fl, expr.Left, leftType)
 				a.updateParameterType(fl, expr.Right, rightType)
 			}
+			// A parameter repeating a string is its count
+			if _, count, ok := a.StringRepeat(expr); ok {
+				if t := a.InferExpressionTypes(count, false)[0].String(); t == "interface{}" || t == "any" {
+					a.updateParameterType(fl, count, &parser.BasicType{Name: "int"})
+				}
+			}
 		}
 		return true
 	})
@@ -461,7 +1122,7 @@ func (a *Analyzer) updateParameterType(fl *parser.FunctionLiteral, expr parser.E
 		for _, param := range fl.Parameters {
 			if param.Value == ident.Value {
 				symbol, found := a.CurrentTable.Resolve(ident.Value)
-				if found {
+				if found && !symbol.Annotated {
 					symbol.Type = newType
 				}
 				break
@@ -477,9 +1138,16 @@ func (a *Analyzer) InferFunctionReturnType(body *parser.BlockStatement, funcTabl
 	a.CurrentTable = funcTable
 
 	parser.Inspect(body, func(n parser.Node) bool {
+		if _, ok := n.(*parser.FunctionLiteral); ok {
+			// Functions defined inside return their own values
+			return false
+		}
 		if retStmt, ok := n.(*parser.ReturnStatement); ok {
 			if retStmt.ReturnValue != nil {
-				retTypes := a.InferExpressionTypes(retStmt.ReturnValue, false)
+				retTypes := a.ReturnTypesOf(retStmt.ReturnValue)
+				if len(collectedReturnTypes) > 0 && len(retTypes) != len(collectedReturnTypes[0]) {
+					a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("a function must return the same number of values from every return statement (Line %d, Column %d)", retStmt.Token.Line, retStmt.Token.Column))
+				}
 				collectedReturnTypes = append(collectedReturnTypes, retTypes)
 			} else {
 				collectedReturnTypes = append(collectedReturnTypes, []parser.Type{&parser.BasicType{Name: "void"}})
@@ -497,11 +1165,12 @@ func (a *Analyzer) InferFunctionReturnType(body *parser.BlockStatement, funcTabl
 	// For simplicity, assume all return statements return the same types
 	// You may want to implement a unification algorithm here
 	if len(collectedReturnTypes) > 1 {
-		ReturnType := collectedReturnTypes[0]
+		ReturnType := append([]parser.Type{}, collectedReturnTypes[0]...)
 		for _, returnType := range collectedReturnTypes[1:] {
-			if returnType[0].TypeName() != ReturnType[0].TypeName() {
-				ReturnType = []parser.Type{&parser.BasicType{Name: "interface{}"}}
-				return ReturnType
+			for i := range ReturnType {
+				if i < len(returnType) && returnType[i].TypeName() != ReturnType[i].TypeName() {
+					ReturnType[i] = &parser.BasicType{Name: "interface{}"}
+				}
 			}
 		}
 		return ReturnType
@@ -509,6 +1178,53 @@ func (a *Analyzer) InferFunctionReturnType(body *parser.BlockStatement, funcTabl
 	return collectedReturnTypes[0]
 }
 
+// handleAugmentedAssignment checks an augmented assignment such as x += 1.
+// The target keeps its type, so the value must fit it: any value can be
+// added to a string, but a float can't be added to an int variable.
+func (a *Analyzer) handleAugmentedAssignment(as *parser.AssignmentStatement, remainingStatements []parser.Statement) {
+	a.Analyze(as.Value, remainingStatements)
+
+	if len(as.Left) != 1 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s=' assigns to exactly one target (Line %d, Column %d)", as.Operator, as.Token.Line, as.Token.Column))
+		return
+	}
+
+	switch target := as.Left[0].(type) {
+	case *parser.Identifier:
+		if a.checkDeleted(target) {
+			return
+		}
+		symbol, found := a.CurrentTable.Resolve(target.Value)
+		if !found {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("name '%s' is not defined (Line %d, Column %d)", target.Value, target.Token.Line, target.Token.Column))
+			return
+		}
+		valueType := a.InferExpressionTypes(as.Value, false)[0]
+		switch symbol.Type.String() {
+		case "string":
+			if as.Operator == "*" {
+				if t := valueType.String(); t != "int" && t != "interface{}" && t != "any" {
+					a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("can't multiply sequence by non-int of type '%s' (Line %d, Column %d)", typeKind(valueType), as.Token.Line, as.Token.Column))
+				}
+			} else if as.Operator != "+" {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("unsupported operand type for %s=: string (Line %d, Column %d)", as.Operator, as.Token.Line, as.Token.Column))
+			}
+		case "int":
+			if as.Operator == "**" || as.Operator == "//" {
+				valueType = a.ArithmeticType(&parser.InfixExpression{Operator: as.Operator, Left: target, Right: as.Value}, symbol.Type, valueType)
+			}
+			if valueType.String() == "float64" {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("int variable '%s' can't hold the float result of '%s='; start it as a float such as 0.0 (Line %d, Column %d)", target.Value, as.Operator, as.Token.Line, as.Token.Column))
+			}
+		}
+	case *parser.IndexExpression, *parser.SelectorExpression:
+		a.Analyze(target, remainingStatements)
+		a.checkTupleItemAssignment(target)
+	default:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("cannot assign to %s with '%s=' (Line %d, Column %d)", target.String(), as.Operator, as.Token.Line, as.Token.Column))
+	}
+}
+
 // handleAssignmentStatement processes variable assignments.
 func (a *Analyzer) handleAssignmentStatement(as *parser.AssignmentStatement, remainingStatements []parser.Statement) {
 	// Analyze the expression on the right-hand side
@@ -516,14 +1232,16 @@ func (a *Analyzer) handleAssignmentStatement(as *parser.AssignmentStatement, rem
 
 	// Infer the type(s) of the value(s)
 	varTypes := a.InferExpressionTypes(as.Value, true) // Returns []parser.Type
-	if len(as.Value.String()) > 2 {
+	if _, ok := as.Value.(*parser.DictComprehension); !ok && len(as.Value.String()) > 2 {
 		if as.Value.String()[len(as.Value.String())-1:] == "}" {
 			varTypes = []parser.Type{&parser.BasicType{Name: as.Value.String()[:strings.Index(as.Value.String(), "{")]}}
 		}
 	}
-
-	// Determine the scope based on the current symbol table
-	scope := a.CurrentTable.Name
+	// Several targets unpack a tuple, or take the results of a call
+	if tt, ok := a.TupleOf(varTypes[0]); ok && len(as.Left) > 1 && len(varTypes) == 1 {
+		varTypes = a.unpackTuple(as, tt)
+		a.keepResults(as.Value)
+	}
 
 	// Attempt to resolve each variable or expression on the left-hand side
 	for i, leftExpr := range as.Left {
@@ -541,15 +1259,21 @@ func (a *Analyzer) handleAssignmentStatement(as *parser.AssignmentStatement, rem
 		case *parser.Identifier:
 			// Simple variable assignment
 			name := expr.Value
-			// Attempt to resolve the variable in the current scope
-			symbol, exists := a.CurrentTable.Resolve(name)
-			if !exists {
-				// Define the new variable in the symbol table
-				a.CurrentTable.Define(name, &Symbol{
+			// Attempt to resolve the variable in the scope it is assigned in
+			table, symbol, exists := a.bindingTable(name)
+			a.undelete(name)
+			if !exists || symbol.Scope == "builtin" {
+				// Define the new variable in the symbol table, hiding a
+				// built-in function of the same name
+				table.Define(name, &Symbol{
 					Name:  name,
 					Type:  currentVarType,
-					Scope: scope,
+					Scope: table.Name,
 				})
+			} else if symbol.Annotated {
+				if !accepts(symbol.Type, currentVarType, as.Value) {
+					a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("cannot assign %s to '%s', which is annotated %s (Line %d, Column %d)", annotationName(currentVarType), name, annotationName(symbol.Type), expr.Token.Line, expr.Token.Column))
+				}
 			} else {
 				//prevName := symbol.Name
 				if symbol.Type.TypeName() != currentVarType.TypeName() {
@@ -560,10 +1284,10 @@ func (a *Analyzer) handleAssignmentStatement(as *parser.AssignmentStatement, rem
 					switch symbol.Type.(type) {
 					case *parser.BasicType:
 						anyType := &parser.BasicType{Name: "interface{}"}
-						a.CurrentTable.Define(name, &Symbol{
+						table.Define(name, &Symbol{
 							Name:  name,
 							Type:  anyType,
-							Scope: scope,
+							Scope: table.Name,
 						})
 					}
 					// Update any references to the variable in the remaining statements
@@ -577,20 +1301,13 @@ func (a *Analyzer) handleAssignmentStatement(as *parser.AssignmentStatement, rem
 			switch currentVarType.(type) {
 			case *parser.PointerType:
 				pkgName := currentVarType.(*parser.PointerType).ElementType.(*parser.NamedType).Package
-				//funcName := currentVarType.(*parser.PointerType).ElementType.(*parser.NamedType).Name
-				//fmt.Println(funcName)
 				// Load the package using golang.org/x/tools/go/packages
-				cfg := &packages.Config{
-					Mode: packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedDeps | packages.NeedImports,
-				}
-				pkgs, err := packages.Load(cfg, a.PkgPaths[pkgName])
-				if err != nil || len(pkgs) == 0 {
+				pkg, err := loadGoPackage(a.PkgPaths[pkgName])
+				if err != nil {
 					a.errors = append(a.errors, fmt.Sprintf("Failed to load package: %s", a.PkgPaths[pkgName]))
 					return
 				}
 
-				pkg := pkgs[0]
-
 				pkgScope := pkg.Types.Scope()
 				for _, fname := range pkgScope.Names() {
 					obj := pkgScope.Lookup(fname)
@@ -617,7 +1334,13 @@ func (a *Analyzer) handleAssignmentStatement(as *parser.AssignmentStatement, rem
 		case *parser.IndexExpression, *parser.SelectorExpression:
 			// Assignment to an indexed element or object field, e.g., a[0] = ... or obj.field = ...
 			// Analyze the left expression to ensure validity
-			a.Analyze(expr, remainingStatements)
+			if se, ok := expr.(*parser.SelectorExpression); ok {
+				a.Analyze(se.Left, remainingStatements)
+				a.assignField(se, currentVarType, as.Token.Line, as.Token.Column)
+			} else {
+				a.Analyze(expr, remainingStatements)
+			}
+			a.checkTupleItemAssignment(expr)
 			// Optionally, perform additional checks or type inference if needed
 		default:
 			// Other types of expressions are invalid on the left-hand side of an assignment
@@ -642,6 +1365,14 @@ func (a *Analyzer) updateVariableReferences(stmt parser.Statement, oldName, newN
 		if n != nil {
 			a.updateVariableReferencesInExpression(n.Expression, oldName, newName)
 		}
+	case *parser.DeferStatement:
+		if n != nil {
+			a.updateVariableReferencesInExpression(n.Expression, oldName, newName)
+		}
+	case *parser.GoStatement:
+		if n != nil {
+			a.updateVariableReferencesInExpression(n.Expression, oldName, newName)
+		}
 	case *parser.IfStatement:
 		if n != nil {
 			a.updateVariableReferencesInExpression(n.Condition, oldName, newName)
@@ -662,11 +1393,57 @@ func (a *Analyzer) updateVariableReferences(stmt parser.Statement, oldName, newN
 			if n.Variable != nil && n.Variable.Value == oldName {
 				n.Variable.Value = newName
 			}
+			for _, v := range n.Variables {
+				if v.Value == oldName {
+					v.Value = newName
+				}
+			}
+		}
+	case *parser.TryStatement:
+		if n != nil {
+			for _, block := range n.Blocks() {
+				a.updateVariableReferences(block, oldName, newName)
+			}
+		}
+	case *parser.WithStatement:
+		if n != nil {
+			for _, item := range n.Items {
+				a.updateVariableReferencesInExpression(item.Context, oldName, newName)
+			}
+			a.updateVariableReferences(n.Body, oldName, newName)
+		}
+	case *parser.MatchStatement:
+		if n != nil {
+			a.updateVariableReferencesInExpression(n.Subject, oldName, newName)
+			for _, block := range n.Blocks() {
+				a.updateVariableReferences(block, oldName, newName)
+			}
 		}
 	case *parser.ReturnStatement:
 		if n != nil && n.ReturnValue != nil {
 			a.updateVariableReferencesInExpression(n.ReturnValue, oldName, newName)
 		}
+	case *parser.YieldStatement:
+		if n != nil && n.Value != nil {
+			a.updateVariableReferencesInExpression(n.Value, oldName, newName)
+		}
+	case *parser.RaiseStatement:
+		if n != nil && n.Exception != nil {
+			a.updateVariableReferencesInExpression(n.Exception, oldName, newName)
+		}
+	case *parser.DelStatement:
+		if n != nil {
+			for _, target := range n.Targets {
+				a.updateVariableReferencesInExpression(target, oldName, newName)
+			}
+		}
+	case *parser.AssertStatement:
+		if n != nil && n.Condition != nil {
+			a.updateVariableReferencesInExpression(n.Condition, oldName, newName)
+			if n.Message != nil {
+				a.updateVariableReferencesInExpression(n.Message, oldName, newName)
+			}
+		}
 	case *parser.BlockStatement:
 		if n != nil {
 			for _, s := range n.Statements {
@@ -697,6 +1474,15 @@ func (a *Analyzer) updateVariableReferencesInExpression(expr parser.Expression,
 		a.updateVariableReferencesInExpression(e.Right, oldName, newName)
 	case *parser.PrefixExpression:
 		a.updateVariableReferencesInExpression(e.Right, oldName, newName)
+	case *parser.AssignmentExpression:
+		a.updateVariableReferencesInExpression(e.Target, oldName, newName)
+		a.updateVariableReferencesInExpression(e.Value, oldName, newName)
+	case *parser.ConditionalExpression:
+		a.updateVariableReferencesInExpression(e.Consequence, oldName, newName)
+		a.updateVariableReferencesInExpression(e.Condition, oldName, newName)
+		if e.Alternative != nil {
+			a.updateVariableReferencesInExpression(e.Alternative, oldName, newName)
+		}
 	case *parser.CallExpression:
 		a.updateVariableReferencesInExpression(e.Function, oldName, newName)
 		for _, arg := range e.Arguments {
@@ -716,6 +1502,10 @@ func (a *Analyzer) updateVariableReferencesInExpression(expr parser.Expression,
 			a.updateVariableReferencesInExpression(key, oldName, newName)
 			a.updateVariableReferencesInExpression(value, oldName, newName)
 		}
+	case *parser.SetLiteral:
+		for _, elem := range e.Elements {
+			a.updateVariableReferencesInExpression(elem, oldName, newName)
+		}
 	case *parser.FunctionLiteral:
 		for _, param := range e.Parameters {
 			if param.Value == oldName {
@@ -728,6 +1518,118 @@ func (a *Analyzer) updateVariableReferencesInExpression(expr parser.Expression,
 
 // handleCallExpression processes function calls.
 func (a *Analyzer) handleCallExpression(ce *parser.CallExpression) {
+	if named, ok := a.ExternalStructs[ce.Function.String()]; ok {
+		a.handleStructLiteral(ce, named)
+		return
+	}
+	if ident, ok := ce.Function.(*parser.Identifier); ok && ident.Value == "to_struct" {
+		a.handleToStruct(ce)
+		return
+	}
+	if class, ok := a.ConstructorOf(ce); ok {
+		a.handleConstructorCall(ce, class)
+		return
+	}
+	if IsSuperCall(ce) {
+		a.handleSuperCall(ce)
+		return
+	}
+	if a.handleSetCall(ce) {
+		return
+	}
+	if se, ok := ce.Function.(*parser.SelectorExpression); ok {
+		if class, ok := a.ClassOf(a.InferExpressionTypes(se.Left, false)[0]); ok {
+			a.Analyze(se.Left, []parser.Statement{})
+			if se.Selector.Value == "__init__" {
+				a.handleInitCall(ce, class)
+				return
+			}
+			if _, method, ok := a.MethodOf(se); ok {
+				a.handleMethodCall(ce, method)
+				return
+			}
+			a.checkAttribute(se)
+		} else if inner, ok := se.Left.(*parser.CallExpression); ok && IsSuperCall(inner) {
+			a.handleSuperCall(inner)
+			a.handleObjectCall(ce, se)
+			return
+		}
+	}
+	if a.IsBuiltinCall(ce, "sorted") {
+		a.handleSorted(ce)
+		return
+	}
+	if a.IsBuiltinCall(ce, "min") || a.IsBuiltinCall(ce, "max") || a.IsBuiltinCall(ce, "sum") {
+		a.handleAggregate(ce)
+		return
+	}
+	if a.IsBuiltinCall(ce, "map") || a.IsBuiltinCall(ce, "filter") {
+		a.handleMapFilter(ce)
+		return
+	}
+	if a.IsBuiltinCall(ce, "len") {
+		a.handleLen(ce)
+		return
+	}
+	if a.IsBuiltinCall(ce, "enumerate") || a.IsBuiltinCall(ce, "zip") {
+		// A loop over them analyzes them; see handleSeveralVariables
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() is only supported as what a for loop iterates over, as in for a, b in %s(...): (Line %d, Column %d)", ce.Function.String(), ce.Function.String(), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	if a.IsBuiltinCall(ce, "range") {
+		// A loop over it analyzes it; see handleRange
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("range() is only supported as what a for loop iterates over, as in for i in range(10): (Line %d, Column %d)", ce.Token.Line, ce.Token.Column))
+		return
+	}
+	if a.handleFormatMethod(ce) {
+		return
+	}
+	if a.IsBuiltinCall(ce, "format") {
+		a.handleFormatBuiltin(ce)
+		return
+	}
+	if a.IsBuiltinCall(ce, "open") {
+		a.handleOpen(ce)
+		return
+	}
+	if a.IsBuiltinCall(ce, "round") && (len(ce.Arguments) < 1 || len(ce.Arguments) > 2) {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("round() takes 1 or 2 arguments (%d given) (Line %d, Column %d)", len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	if a.IsBuiltinCall(ce, "input") {
+		if len(ce.Arguments) > 1 {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("input() takes at most 1 argument, a prompt (%d given) (Line %d, Column %d)", len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+			return
+		}
+		for _, arg := range ce.Arguments {
+			a.Analyze(arg, []parser.Statement{})
+		}
+		return
+	}
+	if a.IsBuiltinCall(ce, "isinstance") {
+		a.handleIsInstance(ce)
+		return
+	}
+	if a.IsBuiltinCall(ce, "type") {
+		a.handleTypeCall(ce)
+		return
+	}
+	if (a.IsBuiltinCall(ce, "copy") || a.IsBuiltinCall(ce, "deepcopy") || a.IsBuiltinCall(ce, "to_dict")) && len(ce.Arguments) != 1 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s() takes exactly one argument (%d given) (Line %d, Column %d)", ce.Function.String(), len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+		return
+	}
+
+	if se, ok := ce.Function.(*parser.SelectorExpression); ok && a.simpleModules[se.Left.String()] {
+		if IsPrivate(se.Selector.Value) {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' is private to module '%s'; functions starting with _ can only be called inside their module (Line %d, Column %d)", se.Selector.Value, se.Left.String(), se.Selector.Token.Line, se.Selector.Token.Column))
+			return
+		}
+		if _, defined := a.GlobalTable.Symbols[se.String()]; !defined && a.OutputDir != "" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("module '%s' has no function '%s' (Line %d, Column %d)", se.Left.String(), se.Selector.Value, se.Selector.Token.Line, se.Selector.Token.Column))
+			return
+		}
+	}
+
 	// Analyze the function being called
 	funcTypes := a.InferExpressionTypes(ce.Function, true)
 	if len(funcTypes) == 0 {
@@ -735,39 +1637,51 @@ func (a *Analyzer) handleCallExpression(ce *parser.CallExpression) {
 		return
 	}
 	funcType := funcTypes[0]
+	if ft, _ := funcType.(*parser.FunctionType); !a.bindKeywords(ce, ft) {
+		return
+	}
 	switch funcType.(type) {
 	case *parser.FunctionType:
 		ft := funcType.(*parser.FunctionType)
+		a.recordResultTuple(ce, ft)
 		for i, arg := range ce.Arguments {
+			if ml, ok := arg.(*parser.MapLiteral); ok && i < len(ft.ParameterTypes) {
+				// A dict passed for a Go struct is converted to the struct
+				if named, pointer := a.externalStruct(ft.ParameterTypes[i]); named != nil {
+					arg = a.coerceToStruct(ml, named, pointer)
+					ce.Arguments[i] = arg
+				}
+			}
 			a.Analyze(arg, []parser.Statement{})
 			argTypes := a.InferExpressionTypes(arg, true)
 			argType := argTypes[0]
+			if a.checkArgumentAnnotation(ce, ft, i, arg, argType) != nil {
+				// The annotation, not the argument, gives the type
+				continue
+			}
 			var prevType parser.Type
 			if i < len(ft.ParameterTypes) {
 				paramType := ft.ParameterTypes[i]
-				if paramType.String() != "interface{}" {
+				// []interface{} is most often a variadic ...any, as in
+				// fmt.Sprintf, which like interface{} takes any argument
+				if paramType.String() != "interface{}" && paramType.String() != "[]interface{}" {
 					if argType.String() != paramType.String() {
 						// Additional check: if paramType is an interface, check if argType implements it
+						iface := a.externalInterface(paramType)
 						if a.doesTypeImplement(paramType, argType) {
-							// If the argument type implements the parameter type interface,
-							// and the argument is a function, we might need to wrap it
-							if _, ok := argType.(*parser.FunctionType); ok {
-								// Find the adapter needed
-								wrapper := a.findAdapterForInterface(paramType)
-								if wrapper != "" {
-									// Record that this call expression's argument needs wrapping
-									a.WrapFunctionCalls[ce] = append(a.WrapFunctionCalls[ce], WrapperInfo{
-										ArgIndex: i,
-										Wrapper:  wrapper,
-									})
-								}
+							// Nothing to do, Go accepts the argument as is
+						} else if _, isFunc := argType.(*parser.FunctionType); isFunc && iface != nil && iface.GoType.NumMethods() == 1 {
+							a.adaptFunction(ce, i, iface)
+						} else if iface != nil {
+							// Explain the mismatch instead of letting the call degrade silently
+							if problems := a.interfaceMismatch(iface.GoType, argType); len(problems) > 0 {
+								a.warnings = append(a.warnings, fmt.Sprintf("argument %d in call to '%s': %s does not implement %s: %s", i+1, ce.Function.String(), arg.String(), paramType.String(), strings.Join(problems, "; ")))
 							}
 						} else {
 							prevType = argType
 							argType = paramType
 							switch arg.(type) {
 							case *parser.Identifier:
-								//fmt.Println(prevType)
 								switch prevType.(type) {
 								case *parser.FunctionType:
 									if symbol, ok := a.CurrentTable.Resolve(arg.String()); ok {
@@ -858,6 +1772,12 @@ func (a *Analyzer) doesTypeImplement(paramType parser.Type, argType parser.Type)
 			}
 			paramGoType = symbol.GoType
 		}
+	case *parser.NamedType:
+		iface := a.externalInterface(pt)
+		if iface == nil {
+			return false
+		}
+		paramGoType = iface.GoType
 	default:
 		// Currently, only handling interface types
 		return false
@@ -920,8 +1840,64 @@ func (a *Analyzer) doesTypeImplement(paramType parser.Type, argType parser.Type)
 	return false
 }
 
+// externalInterface returns the imported Go interface a parameter type names, if any.
+func (a *Analyzer) externalInterface(paramType parser.Type) *ExternalInterface {
+	nt, ok := paramType.(*parser.NamedType)
+	if !ok {
+		return nil
+	}
+	return a.ExternalInterfaces[nt.String()]
+}
+
+// interfaceMismatch lists the methods of iface that argType is missing or
+// implements with the wrong signature. It returns nothing when argType's Go
+// type is unknown, since nothing useful can be said about it.
+func (a *Analyzer) interfaceMismatch(iface *types.Interface, argType parser.Type) []string {
+	qualifier := func(p *types.Package) string { return p.Name() }
+
+	var argGoType types.Type
+	switch at := argType.(type) {
+	case *parser.FunctionType:
+		// Functions have no methods, so every method is missing
+		if sig := a.createGoSignatureFromFunctionType(at); sig != nil {
+			argGoType = sig
+		}
+	default:
+		argGoType = a.GetGoTypeFromParserType(argType)
+	}
+	if argGoType == nil {
+		return nil
+	}
+	if basic, ok := argGoType.Underlying().(*types.Interface); ok && basic.Empty() {
+		return nil
+	}
+
+	problems := []string{}
+	for i := 0; i < iface.NumMethods(); i++ {
+		want := iface.Method(i)
+		wantSig := strings.TrimPrefix(types.TypeString(want.Type(), qualifier), "func")
+		lookupType := argGoType
+		// Interface values have their methods as they are
+		if _, isPointer := argGoType.(*types.Pointer); !isPointer && !types.IsInterface(argGoType) {
+			lookupType = types.NewPointer(argGoType)
+		}
+		obj, _, _ := types.LookupFieldOrMethod(lookupType, true, want.Pkg(), want.Name())
+		have, ok := obj.(*types.Func)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("missing method %s%s", want.Name(), wantSig))
+			continue
+		}
+		if !types.Identical(have.Type().(*types.Signature), want.Type().(*types.Signature)) {
+			haveSig := strings.TrimPrefix(types.TypeString(have.Type(), qualifier), "func")
+			problems = append(problems, fmt.Sprintf("method %s has signature %s%s, want %s%s", want.Name(), want.Name(), haveSig, want.Name(), wantSig))
+		}
+	}
+	return problems
+}
+
 // handleIdentifier processes identifier usage.
 func (a *Analyzer) handleIdentifier(id *parser.Identifier, reportErrors bool) {
+	a.checkDeleted(id)
 	// Resolve the identifier in the current and outer scopes
 	_, found := a.CurrentTable.Resolve(id.Value)
 	if !found {
@@ -945,14 +1921,21 @@ func (a *Analyzer) InferExpressionTypes(expr parser.Expression, reportErrors boo
 			return []parser.Type{&parser.BasicType{Name: "float64"}}
 		}
 		return []parser.Type{&parser.BasicType{Name: "int"}}
-	case *parser.StringLiteral:
+	case *parser.StringLiteral, *parser.FStringLiteral:
 		return []parser.Type{&parser.BasicType{Name: "string"}}
 	case *parser.BooleanLiteral:
 		return []parser.Type{&parser.BasicType{Name: "bool"}}
+	case *parser.NoneLiteral:
+		return []parser.Type{&parser.BasicType{Name: "interface{}"}}
 	case *parser.ArrayLiteral:
+		a.refineArrayLiteral(e)
 		return []parser.Type{&parser.BasicType{Name: fmt.Sprintf("[]%s", e.Type.String())}}
 	case *parser.MapLiteral:
+		a.refineMapLiteral(e)
 		return []parser.Type{&parser.BasicType{Name: fmt.Sprintf("map[%s]%s", e.KeyType.String(), e.ValueType.String())}}
+	case *parser.SetLiteral:
+		a.refineSetLiteral(e)
+		return []parser.Type{setTypeOf(e.ElementType)}
 	case *parser.Identifier:
 		symbol, found := a.CurrentTable.Resolve(e.Value)
 		if !found {
@@ -977,7 +1960,86 @@ func (a *Analyzer) InferExpressionTypes(expr parser.Expression, reportErrors boo
 			}
 		}
 		return []parser.Type{symbol.Type}
+	case *parser.KeywordArgument:
+		return a.InferExpressionTypes(e.Value, reportErrors)
+	case *parser.TupleLiteral:
+		return []parser.Type{a.tupleTypeOf(e)}
+	case *parser.IndexExpression:
+		// The values of tuples and the items of lists and dicts are typed;
+		// slices and the bytes of strings aren't yet
+		leftType := a.InferExpressionTypes(e.Left, reportErrors)[0]
+		if tt, ok := a.TupleOf(leftType); ok {
+			if i, err := tupleIndex(e.Index, len(tt.ElementTypes)); err == nil && e.End == nil {
+				return []parser.Type{tt.ElementTypes[i]}
+			}
+		} else if name := leftType.String(); e.End == nil && (strings.HasPrefix(name, "[]") || strings.HasPrefix(name, "map[")) {
+			itemType := loopVariableType(name)
+			if strings.HasPrefix(name, "map[") {
+				_, itemType = mapTypes(leftType)
+			}
+			if tt, ok := a.TupleOf(itemType); ok {
+				return []parser.Type{tt}
+			}
+			return []parser.Type{itemType}
+		}
+		return []parser.Type{&parser.BasicType{Name: "interface{}"}}
+	case *parser.LambdaExpression:
+		return []parser.Type{a.LambdaOf(e).Type}
+	case *parser.ConditionalExpression:
+		return []parser.Type{a.ConditionalType(e)}
+	case *parser.AssignmentExpression:
+		return a.InferExpressionTypes(e.Value, false)
+	case *parser.ListComprehension:
+		return []parser.Type{a.comprehensionOf(e.Clause, e.Element).Type}
+	case *parser.DictComprehension:
+		return []parser.Type{a.comprehensionOf(e.Clause, e.Key, e.Value).Type}
 	case *parser.CallExpression:
+		if sl, ok := a.StructLiterals[e]; ok {
+			return []parser.Type{sl.Type}
+		}
+		if sc, ok := a.StructConversions[e]; ok {
+			return []parser.Type{sc.ParserType()}
+		}
+		if ident, ok := e.Function.(*parser.Identifier); ok && ident.Value == "to_struct" {
+			// A to_struct call that failed its checks
+			return []parser.Type{&parser.BasicType{Name: "interface{}"}}
+		}
+		if _, ok := a.StringFormats[e]; ok {
+			return []parser.Type{&parser.BasicType{Name: "string"}}
+		}
+		if tt, ok := a.ResultTuples[e]; ok {
+			return []parser.Type{tt}
+		}
+		if class, ok := a.ConstructorOf(e); ok {
+			return []parser.Type{class.InstanceType()}
+		}
+		if IsSuperCall(e) {
+			if parent, ok := a.superClass(e); ok {
+				return []parser.Type{parent.InstanceType()}
+			}
+			return []parser.Type{&parser.BasicType{Name: "interface{}"}}
+		}
+		if sc, ok := a.SortedCalls[e]; ok {
+			return []parser.Type{&parser.BasicType{Name: "[]" + sc.ItemType.String()}}
+		}
+		if ac, ok := a.AggregateCalls[e]; ok {
+			return []parser.Type{ac.Type}
+		}
+		if lc, ok := a.MapCalls[e]; ok {
+			return a.InferExpressionTypes(lc, reportErrors)
+		}
+		if sc, ok := a.SetCalls[e]; ok {
+			if sc.Name == "set" {
+				return []parser.Type{setTypeOf(sc.ItemType)}
+			}
+			return []parser.Type{&parser.BasicType{Name: "void"}}
+		}
+		if (a.IsBuiltinCall(e, "copy") || a.IsBuiltinCall(e, "deepcopy")) && len(e.Arguments) == 1 {
+			return a.InferExpressionTypes(e.Arguments[0], reportErrors)
+		}
+		if a.IsBuiltinCall(e, "round") && len(e.Arguments) > 0 {
+			return []parser.Type{a.roundType(e)}
+		}
 		// Infer the type(s) of the function being called
 		funcTypes := a.InferExpressionTypes(e.Function, reportErrors)
 		if len(funcTypes) == 0 {
@@ -1009,7 +2071,12 @@ func (a *Analyzer) InferExpressionTypes(expr parser.Expression, reportErrors boo
 					prevTable := a.CurrentTable
 					switch e.Function.(type) {
 					case *parser.Identifier:
-						a.CurrentTable = a.SymbolTables.Tables[e.Function.(*parser.Identifier).Value]
+						table, ok := a.SymbolTables.Tables[e.Function.(*parser.Identifier).Value]
+						if !ok || i >= len(ft.Parameters) {
+							// Builtins have no symbol table or parameter names
+							break
+						}
+						a.CurrentTable = table
 						goType := a.GetGoTypeFromParserType(expectedType)
 						symbol, found := a.CurrentTable.Resolve(funcType.(*parser.FunctionType).Parameters[i].Value)
 						if found {
@@ -1079,13 +2146,30 @@ func (a *Analyzer) InferExpressionTypes(expr parser.Expression, reportErrors boo
 	//	return &parser.BasicType{Name: "interface{}"}
 
 	case *parser.InfixExpression:
+		if _, ok := a.TypeChecks[e]; ok {
+			return []parser.Type{&parser.BasicType{Name: "bool"}}
+		}
 		leftTypes := a.InferExpressionTypes(e.Left, reportErrors)
 		rightTypes := a.InferExpressionTypes(e.Right, reportErrors)
 		leftType := leftTypes[0]
 		rightType := rightTypes[0]
 		switch e.Operator {
-		case "+", "-", "*", "/", "%", "<", "<=", ">", ">=", "==":
+		case "<", "<=", ">", ">=", "==", "!=", "in", "not in", "is", "is not":
+			return []parser.Type{&parser.BasicType{Name: "bool"}}
+		case "|", "&", "^":
+			if _, ok := SetOf(leftType); ok {
+				return []parser.Type{leftType}
+			}
+			if leftType.String() == "bool" && rightType.String() == "bool" {
+				return []parser.Type{leftType}
+			}
+			return []parser.Type{&parser.BasicType{Name: "int"}}
+		case "+", "-", "*", "/", "%":
+			if _, ok := SetOf(leftType); ok && e.Operator == "-" {
+				return []parser.Type{leftType}
+			}
 			if leftType.String() == "string" || rightType.String() == "string" {
+				// Concatenation, % formatting and repetition all make strings
 				return []parser.Type{&parser.BasicType{Name: "string"}}
 			}
 			if leftType.String() == "float" || rightType.String() == "float" {
@@ -1095,8 +2179,12 @@ func (a *Analyzer) InferExpressionTypes(expr parser.Expression, reportErrors boo
 				return []parser.Type{&parser.BasicType{Name: "int"}}
 			}
 			return []parser.Type{&parser.BasicType{Name: "interface{}"}}
+		case "**", "//":
+			return []parser.Type{a.ArithmeticType(e, leftType, rightType)}
 		case "<-":
 			return []parser.Type{&parser.BasicType{Name: "chan any"}}
+		case "and", "or":
+			return []parser.Type{&parser.BasicType{Name: "bool"}}
 		default:
 			return []parser.Type{&parser.BasicType{Name: "interface{}"}}
 		}
@@ -1104,7 +2192,7 @@ func (a *Analyzer) InferExpressionTypes(expr parser.Expression, reportErrors boo
 		rightTypes := a.InferExpressionTypes(e.Right, reportErrors)
 		rightType := rightTypes[0]
 		switch e.Operator {
-		case "!":
+		case "!", "not":
 			return []parser.Type{&parser.BasicType{Name: "bool"}}
 		case "-":
 			return []parser.Type{rightType}
@@ -1119,7 +2207,58 @@ func (a *Analyzer) InferExpressionTypes(expr parser.Expression, reportErrors boo
 	}
 }
 
+// refineMapLiteral narrows the key or value type of a dict literal that the
+// parser left as any because it holds names or expressions, as in
+// {x: "five", x + 1: "six"}, when they all have the same known type.
+func (a *Analyzer) refineMapLiteral(ml *parser.MapLiteral) {
+	if len(ml.Pairs) == 0 || (ml.KeyType.String() != "any" && ml.ValueType.String() != "any") {
+		return
+	}
+	keyTypes := []parser.Type{}
+	valueTypes := []parser.Type{}
+	for _, key := range ml.Keys {
+		keyTypes = append(keyTypes, a.InferExpressionTypes(key, false)[0])
+		valueTypes = append(valueTypes, a.InferExpressionTypes(ml.Pairs[key], false)[0])
+	}
+	if t := commonKnownType(keyTypes); t != nil && ml.KeyType.String() == "any" {
+		ml.KeyType = t
+	}
+	if t := commonKnownType(valueTypes); t != nil && ml.ValueType.String() == "any" {
+		ml.ValueType = t
+	}
+	ml.Type = &parser.MapType{KeyType: ml.KeyType, ValueType: ml.ValueType}
+}
+
+// refineArrayLiteral narrows a list whose items the parser couldn't type,
+// such as [ann, bob], to the type its items share.
+func (a *Analyzer) refineArrayLiteral(al *parser.ArrayLiteral) {
+	if len(al.Elements) == 0 || al.Type.String() != "any" {
+		return
+	}
+	elemTypes := []parser.Type{}
+	for _, elem := range al.Elements {
+		elemTypes = append(elemTypes, a.InferExpressionTypes(elem, false)[0])
+	}
+	if t := commonKnownType(elemTypes); t != nil {
+		al.Type = t
+	}
+}
+
+// commonKnownType returns the type shared by all the candidates, or nil when they
+// differ or are untyped.
+func commonKnownType(candidates []parser.Type) parser.Type {
+	for _, t := range candidates {
+		if name := t.String(); name != candidates[0].String() || name == "interface{}" || name == "any" || name == "void" {
+			return nil
+		}
+	}
+	return candidates[0]
+}
+
 func (a *Analyzer) InferSelectorExpressionType(e *parser.SelectorExpression, reportErrors bool) []parser.Type {
+	if _, ok := a.TypeCall(e.Left); ok && e.Selector.Value == "__name__" {
+		return []parser.Type{&parser.BasicType{Name: "string"}}
+	}
 	// Handle package or object member access
 	if pkgMethod, exists := a.GlobalTable.Symbols[fmt.Sprintf("%s.%s", e.Left.String(), e.Selector.Value)]; exists {
 		return []parser.Type{pkgMethod.Type}
@@ -1132,6 +2271,14 @@ func (a *Analyzer) InferSelectorExpressionType(e *parser.SelectorExpression, rep
 		}
 		return []parser.Type{&parser.BasicType{Name: "interface{}"}}
 	}
+	if fieldType, ok := a.FieldType(e); ok {
+		return []parser.Type{fieldType}
+	}
+	if _, method, ok := a.MethodOf(e); ok {
+		if symbol, ok := a.GlobalTable.Resolve(method.Class.Name + "." + method.Name()); ok {
+			return []parser.Type{symbol.Type}
+		}
+	}
 
 	// Retrieve the Go type from leftType
 	leftGoType := a.GetGoTypeFromParserType(leftType)
@@ -1210,35 +2357,88 @@ func (a *Analyzer) InferFunctionType(fl *parser.FunctionLiteral) parser.Type {
 
 // handleImportStatement processes import statements.
 func (a *Analyzer) handleImportStatement(is *parser.ImportStatement) {
-	modulePath := strings.Trim(is.ImportedModule.Value, "\"")
+	for _, spec := range is.Imports {
+		// Simple modules are compiled separately by the code generator
+		if spec.IsSimpleImport {
+			a.importSimpleModule(spec)
+			continue
+		}
+		modulePath := strings.Trim(spec.ImportedModule.Value, "\"")
+		if err := checkImport(modulePath); err != nil {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%v (Line %d, Column %d)", err, spec.ImportedModule.Token.Line, spec.ImportedModule.Token.Column))
+			continue
+		}
+		a.importGoPackage(modulePath, spec.Alias)
+	}
+}
+
+// checkImportNames reports functions and classes named after a package the
+// program imports. In Go they would be declared alongside the package's
+// name, and every use of the package would refer to them.
+func (a *Analyzer) checkImportNames(program *parser.Program) {
+	imported := importNames(program)
+	for _, stmt := range program.Statements {
+		var name *parser.Identifier
+		switch s := stmt.(type) {
+		case *parser.FunctionLiteral:
+			if s != nil {
+				name = s.Name
+			}
+		case *parser.ClassStatement:
+			if s != nil {
+				name = s.Name
+			}
+		}
+		if name != nil && imported[name.Value] {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s has the name of the imported package %s; rename it, or import the package with as and another name (Line %d, Column %d)", name.Value, name.Value, name.Token.Line, name.Token.Column))
+		}
+	}
+}
+
+// importNames returns the names the packages a program imports are bound
+// to.
+func importNames(program *parser.Program) map[string]bool {
+	imported := map[string]bool{}
+	for _, stmt := range program.Statements {
+		if is, ok := stmt.(*parser.ImportStatement); ok && is != nil {
+			for _, spec := range is.Imports {
+				imported[spec.Name()] = true
+			}
+		}
+	}
+	return imported
+}
+
+// importGoPackage loads a Go package and adds its exported symbols to the
+// global table, qualified by alias when one is given.
+func (a *Analyzer) importGoPackage(modulePath string, alias string) {
 	if _, exists := a.importedPackages[modulePath]; exists {
 		// Package already imported
 		return
 	}
 
-	if strings.Contains(modulePath, ".") && strings.Contains(modulePath, "/") {
+	if strings.Contains(modulePath, ".") && strings.Contains(modulePath, "/") && !ActivePolicy.Offline {
 		cmd := exec.Command("go", "get", modulePath)
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 		cmd.Run()
 	}
 
-	// Load the package using golang.org/x/tools/go/packages
-	cfg := &packages.Config{
-		Mode: packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedDeps | packages.NeedImports,
-	}
-	pkgs, err := packages.Load(cfg, modulePath)
-	if err != nil || len(pkgs) == 0 {
+	// Load the package using golang.org/x/tools/go/packages, or its stub
+	pkg, err := loadGoPackage(modulePath)
+	if err != nil {
 		a.errors = append(a.errors, fmt.Sprintf("Failed to load package: %s", modulePath))
 		return
 	}
-
-	pkg := pkgs[0]
 	a.importedPackages[modulePath] = pkg
 	a.PkgPaths[pkg.Name] = modulePath
-	a.extractExternalFunctions(pkg)
-	a.extractExternalInterfaces(pkg)
-	a.extractExternalConstants(pkg)
+	pkgName := pkg.Name
+	if alias != "" {
+		pkgName = alias
+	}
+	a.extractExternalFunctions(pkg, pkgName)
+	a.extractExternalInterfaces(pkg, pkgName)
+	a.extractExternalConstants(pkg, pkgName)
 
 	// Add exported functions and types to the symbol table
 	scope := pkg.Types.Scope()
@@ -1260,12 +2460,12 @@ func (a *Analyzer) handleImportStatement(is *parser.ImportStatement) {
 
 			functionType := a.functionTypeFromSignature(sig)
 			symbol := &Symbol{
-				Name:   pkg.Name + "." + name,
+				Name:   pkgName + "." + name,
 				Type:   functionType,
 				Scope:  "imported",
 				GoType: sig,
 			}
-			a.GlobalTable.Define(pkg.Name+"."+name, symbol)
+			a.GlobalTable.Define(pkgName+"."+name, symbol)
 		case *types.TypeName:
 			// Handle structs and interfaces
 			named, ok := obj.Type().(*types.Named)
@@ -1274,6 +2474,7 @@ func (a *Analyzer) handleImportStatement(is *parser.ImportStatement) {
 			}
 			switch named.Underlying().(type) {
 			case *types.Struct:
+				a.ExternalStructs[pkgName+"."+name] = named
 				structType := &parser.StructType{Name: named.Obj().Name()}
 				symbol := &Symbol{
 					Name:   named.Obj().Name(),
@@ -1300,27 +2501,53 @@ func (a *Analyzer) handleImportStatement(is *parser.ImportStatement) {
 	}
 }
 
-func (a *Analyzer) findAdapterForInterface(paramType parser.Type) string {
-	switch pt := paramType.(type) {
-	case *parser.InterfaceType:
-		// Interface name may include package alias
-		parts := strings.Split(pt.Name, ".")
-		if len(parts) == 2 {
-			pkgAlias, interfaceName := parts[0], parts[1]
-			adapterFuncName := interfaceName + "Func"
-			return fmt.Sprintf("%s.%s", pkgAlias, adapterFuncName)
-		} else {
-			// Interface name without package alias
-			adapterFuncName := pt.Name + "Func"
-			return adapterFuncName
+// adaptFunction arranges for the function passed as argument argIndex of ce
+// to be wrapped in a generated adapter implementing the single-method
+// interface iface. The function's parameter and return types are taken from
+// the interface method so that the conversion to the adapter type compiles.
+func (a *Analyzer) adaptFunction(ce *parser.CallExpression, argIndex int, iface *ExternalInterface) {
+	method := iface.GoType.Method(0)
+	sig := method.Type().(*types.Signature)
+
+	arg := ce.Arguments[argIndex]
+	symbol, ok := a.CurrentTable.Resolve(arg.String())
+	if !ok {
+		return
+	}
+	fnType, ok := symbol.Type.(*parser.FunctionType)
+	if !ok {
+		return
+	}
+	if len(fnType.ParameterTypes) != sig.Params().Len() {
+		a.warnings = append(a.warnings, fmt.Sprintf("argument %d in call to '%s': %s takes %d parameters, but %s.%s takes %d", argIndex+1, ce.Function.String(), arg.String(), len(fnType.ParameterTypes), iface.Package+"."+iface.Name, method.Name(), sig.Params().Len()))
+		return
+	}
+
+	funcTable := a.SymbolTables.Tables[arg.String()]
+	for x := range fnType.ParameterTypes {
+		paramType := a.convertGoType(sig.Params().At(x).Type())
+		fnType.ParameterTypes[x] = paramType
+		if funcTable != nil && x < len(fnType.Parameters) {
+			funcTable.Define(fnType.Parameters[x].Value, &Symbol{Name: fnType.Parameters[x].Value, Type: paramType})
+		}
+	}
+	if sig.Results().Len() > 0 {
+		fnType.ReturnTypes = []parser.Type{}
+		for x := 0; x < sig.Results().Len(); x++ {
+			fnType.ReturnTypes = append(fnType.ReturnTypes, a.convertGoType(sig.Results().At(x).Type()))
 		}
-	default:
-		return ""
 	}
+
+	name := "simple" + capitalize(iface.Package) + iface.Name + "Adapter"
+	a.Adapters[name] = &Adapter{Name: name, Interface: iface, Method: method}
+	a.WrapFunctionCalls[ce] = append(a.WrapFunctionCalls[ce], WrapperInfo{
+		ArgIndex: argIndex,
+		Wrapper:  name,
+	})
 }
 
 // extractExternalInterfaces extracts exported interfaces from a loaded package.
-func (a *Analyzer) extractExternalInterfaces(pkg *packages.Package) {
+func (a *Analyzer) extractExternalInterfaces(pkg *packages.Package, pkgName string) {
 	scope := pkg.Types.Scope()
 	for _, name := range scope.Names() {
 		obj := scope.Lookup(name)
@@ -1374,14 +2601,15 @@ func (a *Analyzer) extractExternalInterfaces(pkg *packages.Package) {
 		}
 
 		// Fully qualified interface name
-		fqIfaceName := fmt.Sprintf("%s.%s", pkg.Name, name)
+		fqIfaceName := fmt.Sprintf("%s.%s", pkgName, name)
 
 		// Populate the ExternalInterfaces map
 		a.ExternalInterfaces[fqIfaceName] = &ExternalInterface{
-			Package:     pkg.Name,
+			Package:     pkgName,
 			Name:        name,
 			MethodNames: methodNames,
 			Methods:     methods,
+			GoType:      iface,
 		}
 	}
 }
@@ -1399,7 +2627,7 @@ func (a *Analyzer) combineReturnTypes(returnTypes []parser.Type) parser.Type {
 }
 
 // extractExternalFunctions extracts exported functions from a loaded package.
-func (a *Analyzer) extractExternalFunctions(pkg *packages.Package) {
+func (a *Analyzer) extractExternalFunctions(pkg *packages.Package, pkgName string) {
 	scope := pkg.Types.Scope()
 	for _, name := range scope.Names() {
 		obj := scope.Lookup(name)
@@ -1441,7 +2669,7 @@ func (a *Analyzer) extractExternalFunctions(pkg *packages.Package) {
 		}
 
 		// Fully qualified function name
-		fqFuncName := fmt.Sprintf("%s.%s", pkg.Name, funcObj.Name())
+		fqFuncName := fmt.Sprintf("%s.%s", pkgName, funcObj.Name())
 
 		// Populate the ExternalFuncs map
 		a.ExternalFuncs[fqFuncName] = &parser.FunctionType{
@@ -1451,7 +2679,7 @@ func (a *Analyzer) extractExternalFunctions(pkg *packages.Package) {
 	}
 }
 
-func (a *Analyzer) extractExternalConstants(pkg *packages.Package) {
+func (a *Analyzer) extractExternalConstants(pkg *packages.Package, pkgName string) {
 	scope := pkg.Types.Scope()
 	for _, name := range scope.Names() {
 		obj := scope.Lookup(name)
@@ -1471,7 +2699,7 @@ func (a *Analyzer) extractExternalConstants(pkg *packages.Package) {
 			constType := a.convertGoType(constObj.Type())
 
 			// Fully qualified constant name (e.g., "math.Pi")
-			fqConstName := fmt.Sprintf("%s.%s", pkg.Name, constObj.Name())
+			fqConstName := fmt.Sprintf("%s.%s", pkgName, constObj.Name())
 
 			// Populate the ExternalConstants map
 			a.ExternalConstants[fqConstName] = constType
@@ -1483,7 +2711,7 @@ func (a *Analyzer) extractExternalConstants(pkg *packages.Package) {
 			}
 
 			varType := a.convertGoType(constObj.Type())
-			fqVarName := fmt.Sprintf("%s.%s", pkg.Name, constObj.Name())
+			fqVarName := fmt.Sprintf("%s.%s", pkgName, constObj.Name())
 			a.ExternalConstants[fqVarName] = varType
 
 			// You can also handle other object types if needed
@@ -1491,7 +2719,6 @@ func (a *Analyzer) extractExternalConstants(pkg *packages.Package) {
 	}
 }
 
-// convertGoType converts Go's types.Type to Simple's parser.Type.
 // convertGoType converts Go's types.Type to Simple's parser.Type.
 func (a *Analyzer) convertGoType(goType types.Type) parser.Type {
 	switch t := goType.(type) {
@@ -1499,10 +2726,9 @@ func (a *Analyzer) convertGoType(goType types.Type) parser.Type {
 		return &parser.BasicType{Name: t.Name()}
 	case *types.Pointer:
 		elemType := a.convertGoType(t.Elem())
-		if strings.Contains(elemType.(*parser.NamedType).Package, "Engine") {
-			fmt.Println()
+		if named, ok := elemType.(*parser.NamedType); ok {
+			named.Package = strings.Split(named.Package, "/")[len(strings.Split(named.Package, "/"))-1]
 		}
-		elemType.(*parser.NamedType).Package = fmt.Sprintf("%s", strings.Split(elemType.(*parser.NamedType).Package, "/")[len(strings.Split(elemType.(*parser.NamedType).Package, "/"))-1])
 		return &parser.PointerType{ElementType: elemType}
 	case *types.Named:
 		obj := t.Obj()
@@ -1510,9 +2736,6 @@ func (a *Analyzer) convertGoType(goType types.Type) parser.Type {
 		if obj.Pkg() != nil {
 			pkgPath = obj.Pkg().Path()
 		}
-		if strings.Contains(pkgPath, "Engine") {
-			fmt.Println()
-		}
 		pkg := fmt.Sprintf("%s", strings.Split(pkgPath, "/")[len(strings.Split(pkgPath, "/"))-1])
 		return &parser.NamedType{
 			Name:    obj.Name(),
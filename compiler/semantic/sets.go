@@ -0,0 +1,171 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"strings"
+)
+
+// Sets
+//
+// A set is held in Go as a map from its items to empty structs, so a set of
+// ints is a map[int]struct{}. Looping over it, len and in work as they do
+// for the keys of a dict. The set builtin, the add, remove and discard
+// methods and the |, &, - and ^ operators are recorded here and generated
+// as calls to runtime helpers.
+
+// SetCall records a call of the set builtin or of a method of a set.
+type SetCall struct {
+	Name     string            // "set", or the method: "add", "remove" or "discard"
+	Set      parser.Expression // the set a method is called on
+	Argument parser.Expression // the item, or what set() takes its items from; nil for set()
+	Kind     string            // what set() takes its items from, as sorted does; see itemsOf
+	ItemType parser.Type       // the type of the items of the set
+}
+
+// SetOf returns the type of the items of a set, if t is the type of one.
+func SetOf(t parser.Type) (parser.Type, bool) {
+	if st, ok := t.(*parser.SetType); ok {
+		return st.ElementType, true
+	}
+	name := t.String()
+	if !strings.HasPrefix(name, "map[") || !strings.HasSuffix(name, "]struct{}") {
+		return nil, false
+	}
+	return loopVariableType(name), true
+}
+
+// setTypeOf returns the type of a set of items of type t.
+func setTypeOf(t parser.Type) parser.Type {
+	return &parser.BasicType{Name: fmt.Sprintf("map[%s]struct{}", t.String())}
+}
+
+// handleSetLiteral analyzes the items of a set literal and reports items
+// that can't be in a set.
+func (a *Analyzer) handleSetLiteral(sl *parser.SetLiteral, remainingStatements []parser.Statement) {
+	for _, el := range sl.Elements {
+		a.Analyze(el, remainingStatements)
+	}
+	a.refineSetLiteral(sl)
+	a.checkKey(sl.ElementType, sl.Token.Line, sl.Token.Column)
+}
+
+// refineSetLiteral narrows a set whose items the parser couldn't type, such
+// as {x, y}, to the type its items share.
+func (a *Analyzer) refineSetLiteral(sl *parser.SetLiteral) {
+	if sl.ElementType.String() != "any" {
+		return
+	}
+	elemTypes := []parser.Type{}
+	for _, el := range sl.Elements {
+		elemTypes = append(elemTypes, a.InferExpressionTypes(el, false)[0])
+	}
+	if t := commonKnownType(elemTypes); t != nil {
+		sl.ElementType = t
+	}
+}
+
+// handleSetCall analyzes a call of the set builtin or of a method of a set,
+// reporting whether ce is one.
+func (a *Analyzer) handleSetCall(ce *parser.CallExpression) bool {
+	if a.IsBuiltinCall(ce, "set") {
+		sc := &SetCall{Name: "set", ItemType: &parser.BasicType{Name: "interface{}"}}
+		switch len(ce.Arguments) {
+		case 0:
+		case 1:
+			sc.Argument = ce.Arguments[0]
+			a.Analyze(sc.Argument, []parser.Statement{})
+			sc.Kind, sc.ItemType = itemsOf(a.InferExpressionTypes(sc.Argument, false)[0])
+			a.checkKey(sc.ItemType, ce.Token.Line, ce.Token.Column)
+		default:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("set() takes at most one argument (%d given) (Line %d, Column %d)", len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+			return true
+		}
+		a.SetCalls[ce] = sc
+		return true
+	}
+
+	se, ok := ce.Function.(*parser.SelectorExpression)
+	if !ok {
+		return false
+	}
+	itemType, ok := SetOf(a.InferExpressionTypes(se.Left, false)[0])
+	if !ok {
+		return false
+	}
+	a.Analyze(se.Left, []parser.Statement{})
+	switch se.Selector.Value {
+	case "add", "remove", "discard":
+	default:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'set' object has no attribute '%s' (Line %d, Column %d)", se.Selector.Value, se.Selector.Token.Line, se.Selector.Token.Column))
+		return true
+	}
+	if len(ce.Arguments) != 1 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("set.%s() takes exactly one argument (%d given) (Line %d, Column %d)", se.Selector.Value, len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+		return true
+	}
+	a.Analyze(ce.Arguments[0], []parser.Statement{})
+	if argType := a.InferExpressionTypes(ce.Arguments[0], false)[0]; !canEqual(itemType, argType) {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("a set of %s items can't hold a value of type %s (Line %d, Column %d)", itemType.String(), argType.String(), ce.Token.Line, ce.Token.Column))
+		return true
+	}
+	a.SetCalls[ce] = &SetCall{Name: se.Selector.Value, Set: se.Left, Argument: ce.Arguments[0], ItemType: itemType}
+	return true
+}
+
+// IsSetOperation reports whether ie is a union, intersection, difference or
+// symmetric difference of sets rather than arithmetic or bitwise operation
+// on numbers.
+func (a *Analyzer) IsSetOperation(ie *parser.InfixExpression) bool {
+	switch ie.Operator {
+	case "|", "&", "-", "^":
+		_, ok := SetOf(a.InferExpressionTypes(ie.Left, false)[0])
+		return ok
+	}
+	return false
+}
+
+// handleSetOperators analyzes the operators sets share with numbers: |, &,
+// ^ and -. It reports operands they don't apply to.
+func (a *Analyzer) handleSetOperators(ie *parser.InfixExpression, remainingStatements []parser.Statement) {
+	a.Analyze(ie.Left, remainingStatements)
+	a.Analyze(ie.Right, remainingStatements)
+	leftType := a.InferExpressionTypes(ie.Left, false)[0]
+	rightType := a.InferExpressionTypes(ie.Right, false)[0]
+	leftItems, leftSet := SetOf(leftType)
+	rightItems, rightSet := SetOf(rightType)
+	switch {
+	case leftSet && rightSet:
+		if leftItems.String() != rightItems.String() {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s of a set of %s items and a set of %s items isn't supported; the sets must hold items of the same type (Line %d, Column %d)", ie.Operator, leftItems.String(), rightItems.String(), ie.Token.Line, ie.Token.Column))
+		}
+	case leftSet || rightSet:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("unsupported operand type(s) for %s: '%s' and '%s' (Line %d, Column %d)", ie.Operator, typeKind(leftType), typeKind(rightType), ie.Token.Line, ie.Token.Column))
+	case ie.Operator != "-":
+		for _, t := range []parser.Type{leftType, rightType} {
+			if name := t.String(); name != "int" && name != "bool" && name != "interface{}" && name != "any" {
+				a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("unsupported operand type(s) for %s: '%s' and '%s' (Line %d, Column %d)", ie.Operator, typeKind(leftType), typeKind(rightType), ie.Token.Line, ie.Token.Column))
+				return
+			}
+		}
+	}
+}
+
+// typeKind names the kind of value of a type, as Python's errors do.
+func typeKind(t parser.Type) string {
+	if _, ok := SetOf(t); ok {
+		return "set"
+	}
+	name := t.String()
+	switch {
+	case strings.HasPrefix(name, "[]"):
+		return "list"
+	case strings.HasPrefix(name, "map["):
+		return "dict"
+	case name == "string":
+		return "str"
+	case name == "float64":
+		return "float"
+	}
+	return name
+}
@@ -0,0 +1,97 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"github.com/sasogeek/simple/compiler/parser"
+	"strings"
+)
+
+// SortedCall records a call of the sorted builtin.
+type SortedCall struct {
+	Items    parser.Expression // the list, dict or string being sorted
+	Kind     string            // "list", "dict", "string", or "any" when untyped
+	ItemType parser.Type
+	Key      *parser.LambdaExpression // nil to compare the items themselves
+	Reverse  parser.Expression        // nil for ascending order
+}
+
+// handleSorted checks a call of sorted(items, key=..., reverse=...) and types
+// the parameter of the key function as the items being sorted.
+func (a *Analyzer) handleSorted(ce *parser.CallExpression) {
+	sc := &SortedCall{}
+	positional := 0
+	var key parser.Expression
+	for _, arg := range ce.Arguments {
+		ka, ok := arg.(*parser.KeywordArgument)
+		if !ok {
+			positional++
+			sc.Items = arg
+			continue
+		}
+		switch ka.Name.Value {
+		case "key":
+			key = ka.Value
+		case "reverse":
+			sc.Reverse = ka.Value
+		default:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' is an invalid keyword argument for sorted() (Line %d, Column %d)", ka.Name.Value, ka.Token.Line, ka.Token.Column))
+			return
+		}
+	}
+	if positional != 1 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("sorted() takes exactly one positional argument (%d given) (Line %d, Column %d)", positional, ce.Token.Line, ce.Token.Column))
+		return
+	}
+
+	a.Analyze(sc.Items, []parser.Statement{})
+	sc.Kind, sc.ItemType = itemsOf(a.InferExpressionTypes(sc.Items, false)[0])
+
+	switch k := key.(type) {
+	case nil, *parser.NoneLiteral:
+	case *parser.LambdaExpression:
+		if len(k.Parameters) != 1 {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("the key function of sorted() takes one argument, not %d (Line %d, Column %d)", len(k.Parameters), k.Token.Line, k.Token.Column))
+			return
+		}
+		sc.Key = k
+	default:
+		// A function such as key=len or key=by_age sorts by calling it on
+		// each item, as lambda item: by_age(item) would
+		item := &parser.Identifier{Token: lexer.Token{Type: lexer.TokenIdentifier, Literal: "item", Line: ce.Token.Line, Column: ce.Token.Column}, Value: "item"}
+		sc.Key = &parser.LambdaExpression{
+			Token:      ce.Token,
+			Parameters: []*parser.Identifier{item},
+			Body:       &parser.CallExpression{Token: ce.Token, Function: key, Arguments: []parser.Expression{item}},
+		}
+	}
+	if sc.Key != nil {
+		a.bindLambda(sc.Key, []parser.Type{sc.ItemType})
+	}
+	if sc.Reverse != nil {
+		a.Analyze(sc.Reverse, []parser.Statement{})
+	}
+	a.SortedCalls[ce] = sc
+}
+
+// itemsOf returns what builtins such as sorted take from a value of type t:
+// the items of a list, the keys of a dict, the characters of a string, or
+// untyped items from a value of no known type.
+func itemsOf(t parser.Type) (string, parser.Type) {
+	switch tt := t.(type) {
+	case *parser.ArrayType:
+		return "list", tt.ElementType
+	case *parser.MapType:
+		return "dict", tt.KeyType
+	}
+	name := t.String()
+	switch {
+	case strings.HasPrefix(name, "[]"):
+		return "list", loopVariableType(name)
+	case strings.HasPrefix(name, "map["):
+		return "dict", loopVariableType(name)
+	case name == "string":
+		return "string", &parser.BasicType{Name: "string"}
+	}
+	return "any", &parser.BasicType{Name: "interface{}"}
+}
@@ -0,0 +1,74 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// String operators
+//
+// A string times an int repeats the string, as in "-" * 40, which Go writes
+// with strings.Repeat. <, <=, > and >= compare strings by their bytes, which
+// orders them as Python orders them by code point.
+
+// StringRepeat returns the string and the count of a string repeated by *,
+// in either order, and whether ie is one.
+func (a *Analyzer) StringRepeat(ie *parser.InfixExpression) (str, count parser.Expression, ok bool) {
+	if ie.Operator != "*" {
+		return nil, nil, false
+	}
+	if a.InferExpressionTypes(ie.Left, false)[0].String() == "string" {
+		return ie.Left, ie.Right, true
+	}
+	if a.InferExpressionTypes(ie.Right, false)[0].String() == "string" {
+		return ie.Right, ie.Left, true
+	}
+	return nil, nil, false
+}
+
+// IsStringComparison reports whether ie orders two strings.
+func (a *Analyzer) IsStringComparison(ie *parser.InfixExpression) bool {
+	switch ie.Operator {
+	case "<", "<=", ">", ">=":
+		return a.InferExpressionTypes(ie.Left, false)[0].String() == "string" &&
+			a.InferExpressionTypes(ie.Right, false)[0].String() == "string"
+	}
+	return false
+}
+
+// isStringOperator reports whether ie repeats a string or orders a string
+// and another value.
+func (a *Analyzer) isStringOperator(ie *parser.InfixExpression) bool {
+	switch ie.Operator {
+	case "*", "<", "<=", ">", ">=":
+		return a.InferExpressionTypes(ie.Left, false)[0].String() == "string" ||
+			a.InferExpressionTypes(ie.Right, false)[0].String() == "string"
+	}
+	return false
+}
+
+// handleStringOperators analyzes a string repeated by * or ordered by a
+// comparison, reporting a count that isn't an int and a string ordered
+// against a value of another type.
+func (a *Analyzer) handleStringOperators(ie *parser.InfixExpression, remainingStatements []parser.Statement) {
+	a.Analyze(ie.Left, remainingStatements)
+	a.Analyze(ie.Right, remainingStatements)
+	leftType := a.InferExpressionTypes(ie.Left, false)[0]
+	rightType := a.InferExpressionTypes(ie.Right, false)[0]
+	if _, count, ok := a.StringRepeat(ie); ok {
+		switch countType := a.InferExpressionTypes(count, false)[0]; countType.String() {
+		case "int", "interface{}", "any":
+		default:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("can't multiply sequence by non-int of type '%s' (Line %d, Column %d)", typeKind(countType), ie.Token.Line, ie.Token.Column))
+		}
+		return
+	}
+	for _, t := range []parser.Type{leftType, rightType} {
+		switch t.String() {
+		case "string", "interface{}", "any":
+		default:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' not supported between instances of '%s' and '%s' (Line %d, Column %d)", ie.Operator, typeKind(leftType), typeKind(rightType), ie.Token.Line, ie.Token.Column))
+			return
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// StructLiteral records a call such as http.Client(timeout=5) that builds an
+// imported Go struct from keyword arguments.
+type StructLiteral struct {
+	Type         *parser.NamedType
+	Fields       []string      // Go field name for each argument
+	FieldTypes   []parser.Type // Simple type of each field
+	FieldGoTypes []types.Type  // Go type of each field
+}
+
+// fieldKey normalizes a field name so that timeout, Timeout and
+// max_idle_conns (for MaxIdleConns) can be matched against Go field names.
+func fieldKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// structFields returns the exported fields of a struct by fieldKey, along
+// with their sorted names for error messages.
+func structFields(named *types.Named) (map[string]*types.Var, []string) {
+	st := named.Underlying().(*types.Struct)
+	fields := map[string]*types.Var{}
+	fieldNames := []string{}
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if field.Exported() {
+			fields[fieldKey(field.Name())] = field
+			fieldNames = append(fieldNames, field.Name())
+		}
+	}
+	sort.Strings(fieldNames)
+	return fields, fieldNames
+}
+
+// handleStructLiteral maps the keyword arguments of a struct construction
+// to the struct's exported fields. Unknown fields and positional arguments
+// are fatal, since the generated composite literal could not compile.
+func (a *Analyzer) handleStructLiteral(ce *parser.CallExpression, named *types.Named) {
+	typeName := ce.Function.String()
+	fields, fieldNames := structFields(named)
+
+	sl := &StructLiteral{
+		Type: &parser.NamedType{
+			Name:    named.Obj().Name(),
+			Package: strings.TrimSuffix(typeName, "."+named.Obj().Name()),
+		},
+	}
+	seen := map[string]bool{}
+	for i, arg := range ce.Arguments {
+		ka, ok := arg.(*parser.KeywordArgument)
+		if !ok {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("argument %d in %s(...) must be a keyword argument naming a field (Line %d, Column %d)", i+1, typeName, ce.Token.Line, ce.Token.Column))
+			continue
+		}
+
+		field, ok := fields[fieldKey(ka.Name.Value)]
+		if !ok {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s has no field '%s'; its fields are %s (Line %d, Column %d)", typeName, ka.Name.Value, strings.Join(fieldNames, ", "), ka.Token.Line, ka.Token.Column))
+			continue
+		}
+		if seen[field.Name()] {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("field '%s' of %s is set more than once (Line %d, Column %d)", field.Name(), typeName, ka.Token.Line, ka.Token.Column))
+			continue
+		}
+		seen[field.Name()] = true
+
+		if ml, ok := ka.Value.(*parser.MapLiteral); ok {
+			if fieldNamed, pointer := structType(field.Type()); fieldNamed != nil {
+				ka.Value = a.coerceToStruct(ml, fieldNamed, pointer)
+			}
+		}
+		a.Analyze(ka.Value, []parser.Statement{})
+		sl.Fields = append(sl.Fields, field.Name())
+		sl.FieldTypes = append(sl.FieldTypes, a.convertGoType(field.Type()))
+		sl.FieldGoTypes = append(sl.FieldGoTypes, field.Type())
+	}
+
+	a.StructLiterals[ce] = sl
+}
+
+// StructConversion records a to_struct call, either written out as
+// to_struct(d, pkg.Type) or inserted where a dict is passed for a Go struct.
+// The dict is converted at run time by the generated simpleToStruct helper.
+type StructConversion struct {
+	Type    *parser.NamedType
+	GoType  *types.Named
+	Pointer bool // the struct is wanted as a pointer
+}
+
+// ParserType returns the Simple type the conversion produces.
+func (sc *StructConversion) ParserType() parser.Type {
+	if sc.Pointer {
+		return &parser.PointerType{ElementType: sc.Type}
+	}
+	return sc.Type
+}
+
+// structType returns the named struct behind t, and whether t points to it.
+func structType(t types.Type) (*types.Named, bool) {
+	pointer := false
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+		pointer = true
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := named.Underlying().(*types.Struct); !ok {
+		return nil, false
+	}
+	return named, pointer
+}
+
+// externalStruct looks up an imported Go struct type, or a pointer to one,
+// by its Simple type.
+func (a *Analyzer) externalStruct(t parser.Type) (*types.Named, bool) {
+	pointer := false
+	if pt, ok := t.(*parser.PointerType); ok {
+		t = pt.ElementType
+		pointer = true
+	}
+	nt, ok := t.(*parser.NamedType)
+	if !ok {
+		return nil, false
+	}
+	named, ok := a.ExternalStructs[nt.String()]
+	if !ok {
+		return nil, false
+	}
+	return named, pointer
+}
+
+// handleToStruct checks a to_struct(d, pkg.Type) call.
+func (a *Analyzer) handleToStruct(ce *parser.CallExpression) {
+	if _, ok := a.StructConversions[ce]; ok {
+		// Already checked, or inserted by coerceToStruct
+		a.Analyze(ce.Arguments[0], []parser.Statement{})
+		return
+	}
+	if len(ce.Arguments) != 2 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("to_struct takes a dict and a Go struct type, got %d arguments (Line %d, Column %d)", len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	named, ok := a.ExternalStructs[ce.Arguments[1].String()]
+	if !ok {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("to_struct: '%s' is not an imported Go struct type (Line %d, Column %d)", ce.Arguments[1].String(), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	if ml, ok := ce.Arguments[0].(*parser.MapLiteral); ok {
+		a.checkStructKeys(ml, named)
+	}
+	a.Analyze(ce.Arguments[0], []parser.Statement{})
+	a.StructConversions[ce] = newStructConversion(named, false)
+}
+
+// coerceToStruct wraps a dict literal passed where a Go struct is expected in
+// a to_struct call.
+func (a *Analyzer) coerceToStruct(ml *parser.MapLiteral, named *types.Named, pointer bool) *parser.CallExpression {
+	a.checkStructKeys(ml, named)
+	ce := &parser.CallExpression{
+		Token:     ml.Token,
+		Function:  &parser.Identifier{Token: ml.Token, Value: "to_struct"},
+		Arguments: []parser.Expression{ml},
+	}
+	a.StructConversions[ce] = newStructConversion(named, pointer)
+	return ce
+}
+
+func newStructConversion(named *types.Named, pointer bool) *StructConversion {
+	pkg := ""
+	if named.Obj().Pkg() != nil {
+		pkg = named.Obj().Pkg().Name()
+	}
+	return &StructConversion{
+		Type:    &parser.NamedType{Name: named.Obj().Name(), Package: pkg},
+		GoType:  named,
+		Pointer: pointer,
+	}
+}
+
+// checkStructKeys reports the string keys of a dict literal that name no
+// field of the struct, descending into dicts given for struct fields. Other
+// keys can only be checked at run time.
+func (a *Analyzer) checkStructKeys(ml *parser.MapLiteral, named *types.Named) {
+	fields, fieldNames := structFields(named)
+	typeName := qualifiedName(named)
+	for _, key := range ml.Keys {
+		value := ml.Pairs[key]
+		sl, ok := key.(*parser.StringLiteral)
+		if !ok {
+			continue
+		}
+		field, ok := fields[fieldKey(sl.Value)]
+		if !ok {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s has no field '%s'; its fields are %s (Line %d, Column %d)", typeName, sl.Value, strings.Join(fieldNames, ", "), sl.Token.Line, sl.Token.Column))
+			continue
+		}
+		if nested, ok := value.(*parser.MapLiteral); ok {
+			if fieldNamed, _ := structType(field.Type()); fieldNamed != nil {
+				a.checkStructKeys(nested, fieldNamed)
+			}
+		}
+	}
+}
+
+// qualifiedName formats a named type as pkg.Type.
+func qualifiedName(named *types.Named) string {
+	return types.TypeString(named, func(p *types.Package) string { return p.Name() })
+}
@@ -0,0 +1,145 @@
+package semantic
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/sasogeek/simple/compiler/verbose"
+	"go/token"
+	"go/types"
+	"golang.org/x/tools/go/gcexportdata"
+	"golang.org/x/tools/go/packages"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Type stubs
+//
+// Loading a Go package with go/packages type-checks it, and every package
+// it imports, from source, which for big modules such as gin or the AWS SDK
+// is most of the time a build takes. The analyzer reads only the types of
+// what a package exports, so once a package has been loaded they are
+// written to a stub, in the export data format the go command compiles
+// packages to, and later builds read the stub instead. Stubs are kept in
+// the user cache directory, ~/.cache/simple/stubs on Linux, named for the
+// package, the version of its module, the Go version and the platform, so a
+// new version of either makes a new stub. Packages of a module without a
+// version, such as one replaced by a local directory, are always loaded
+// from source, as their code may change.
+
+// stubVersion changes when what stubs hold does, making older ones stale.
+const stubVersion = 1
+
+// loadGoPackage loads the Go package of an import path, from its stub when
+// there is one.
+func loadGoPackage(path string) (*packages.Package, error) {
+	stub := stubPath(path)
+	if stub != "" {
+		if pkg, err := readStub(stub, path); err == nil {
+			verbose.Logf(2, "loaded Go package %s from its stub %s", path, stub)
+			return pkg, nil
+		}
+	}
+
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedDeps | packages.NeedImports,
+	}
+	done := verbose.Phase(2, "loading Go package "+path)
+	pkgs, err := packages.Load(cfg, path)
+	done()
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no package %s", path)
+	}
+	pkg := pkgs[0]
+	if stub != "" && len(pkg.Errors) == 0 && pkg.Types != nil && pkg.Types.Complete() {
+		if err := writeStub(stub, pkg); err != nil {
+			verbose.Logf(2, "writing the stub of %s: %v", path, err)
+		}
+	}
+	return pkg, nil
+}
+
+// goVersion returns the version of the go command, as in go1.23.1.
+var goVersion = sync.OnceValue(func() string {
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+})
+
+// stubPath returns the file of the stub of a package, or "" when the
+// package has no fixed version to key it by.
+func stubPath(path string) string {
+	cache, err := os.UserCacheDir()
+	if err != nil || goVersion() == "" {
+		return ""
+	}
+	// The module of the package, or what replaces it, and the platform
+	// and cgo setting, which change the types of packages such as syscall
+	// and net
+	cmd := exec.Command("go", "list", "-e", "-f", "{{.Standard}} {{context.GOOS}}/{{context.GOARCH}} cgo={{context.CgoEnabled}} {{with .Module}}{{with .Replace}}{{.Path}}@{{.Version}}{{else}}{{.Path}}@{{.Version}}{{end}}{{end}}", "--", path)
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(strings.TrimSpace(string(out)))
+	switch {
+	case len(fields) == 3 && fields[0] == "true":
+		// The standard library, whose version is Go's
+	case len(fields) == 4 && !strings.HasSuffix(fields[3], "@"):
+	default:
+		return ""
+	}
+	key := fmt.Sprintf("%d %s %s %s", stubVersion, path, goVersion(), strings.Join(fields[1:], " "))
+	sum := sha256.Sum256([]byte(key))
+	name := strings.ReplaceAll(path, "/", "_") + "-" + hex.EncodeToString(sum[:8])
+	return filepath.Join(cache, "simple", "stubs", name)
+}
+
+// readStub returns the package of the import path in a stub.
+func readStub(stub, path string) (*packages.Package, error) {
+	f, err := os.Open(stub)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	pkg, err := gcexportdata.Read(bufio.NewReader(f), token.NewFileSet(), map[string]*types.Package{}, path)
+	if err != nil {
+		return nil, err
+	}
+	return &packages.Package{ID: path, Name: pkg.Name(), PkgPath: path, Types: pkg}, nil
+}
+
+// writeStub writes the stub of a package, in full or not at all, so that
+// builds running at once never read half of one.
+func writeStub(stub string, pkg *packages.Package) error {
+	var buf bytes.Buffer
+	if err := gcexportdata.Write(&buf, pkg.Fset, pkg.Types); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(stub), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(stub), filepath.Base(stub)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), stub)
+}
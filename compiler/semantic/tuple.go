@@ -0,0 +1,147 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"strconv"
+)
+
+// tupleTypeOf returns the type of a tuple literal.
+func (a *Analyzer) tupleTypeOf(tl *parser.TupleLiteral) *parser.TupleType {
+	elementTypes := []parser.Type{}
+	for _, el := range tl.Elements {
+		elementTypes = append(elementTypes, a.InferExpressionTypes(el, false)[0])
+	}
+	return a.tupleOfTypes(elementTypes)
+}
+
+// tupleOfTypes returns the type of a tuple of values of the given types and
+// records it, so that the tuple is recognized by its Go type wherever the
+// type is passed on by name, as the items of a list are.
+func (a *Analyzer) tupleOfTypes(elementTypes []parser.Type) *parser.TupleType {
+	tt := &parser.TupleType{}
+	for _, elementType := range elementTypes {
+		tt.ElementTypes = append(tt.ElementTypes, storedType(elementType))
+	}
+	if known, ok := a.Tuples[tt.String()]; ok {
+		return known
+	}
+	a.Tuples[tt.String()] = tt
+	return tt
+}
+
+// ReturnTypesOf returns the types a return statement returns: a Go result
+// for each value of return a, b, or else the type of its value.
+func (a *Analyzer) ReturnTypesOf(value parser.Expression) []parser.Type {
+	tl, ok := value.(*parser.TupleLiteral)
+	if !ok {
+		return a.InferExpressionTypes(value, false)
+	}
+	return a.tupleTypeOf(tl).ElementTypes
+}
+
+// recordResultTuple records a call of a function with several results, to
+// be turned into a tuple unless the call turns out to be where Go accepts
+// several values; see keepResults.
+func (a *Analyzer) recordResultTuple(ce *parser.CallExpression, ft *parser.FunctionType) {
+	if _, ok := ce.Function.(*parser.Identifier); ok && len(ft.ReturnTypes) > 1 {
+		a.ResultTuples[ce] = a.tupleOfTypes(ft.ReturnTypes)
+	}
+}
+
+// keepResults leaves the results of a call as they are, for a statement
+// that takes them all: x, y = f(), return f() or a call on its own.
+func (a *Analyzer) keepResults(value parser.Expression) {
+	if ce, ok := value.(*parser.CallExpression); ok {
+		delete(a.ResultTuples, ce)
+	}
+}
+
+// TupleOf returns the tuple type t names, if it names one.
+func (a *Analyzer) TupleOf(t parser.Type) (*parser.TupleType, bool) {
+	if tt, ok := t.(*parser.TupleType); ok {
+		return tt, true
+	}
+	tt, ok := a.Tuples[t.String()]
+	return tt, ok
+}
+
+// tupleIndex returns the position an index such as 1 or -1 selects in a
+// tuple of n values. Tuples can only be indexed by integer literals, as each
+// value is a field of a struct.
+func tupleIndex(index parser.Expression, n int) (int, error) {
+	literal, sign := index, 1
+	if pe, ok := index.(*parser.PrefixExpression); ok && pe.Operator == "-" {
+		literal, sign = pe.Right, -1
+	}
+	il, ok := literal.(*parser.IntegerLiteral)
+	if !ok {
+		return 0, fmt.Errorf("tuple indexes must be integer literals, such as t[0]")
+	}
+	i, err := strconv.Atoi(il.Token.Literal)
+	if err != nil {
+		return 0, fmt.Errorf("tuple indexes must be integer literals, such as t[0]")
+	}
+	i *= sign
+	if i < 0 {
+		i += n
+	}
+	if i < 0 || i >= n {
+		return 0, fmt.Errorf("tuple index out of range")
+	}
+	return i, nil
+}
+
+// handleIndexExpression analyzes an index expression, checking and
+// recording the indexes of tuples and of values of no known type, such as
+// the dicts in a dict of any values.
+func (a *Analyzer) handleIndexExpression(ie *parser.IndexExpression, remainingStatements []parser.Statement) {
+	for _, e := range []parser.Expression{ie.Left, ie.Index, ie.End} {
+		if e != nil {
+			a.Analyze(e, remainingStatements)
+		}
+	}
+	leftType := a.InferExpressionTypes(ie.Left, false)[0]
+	if name := leftType.String(); (name == "interface{}" || name == "any") && ie.End == nil {
+		a.UntypedIndexes[ie] = true
+		return
+	}
+	tt, ok := a.TupleOf(leftType)
+	if !ok {
+		a.checkIndexKey(ie, leftType)
+		return
+	}
+	if ie.End != nil || ie.Index == nil {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("tuple slices aren't supported (Line %d, Column %d)", ie.Token.Line, ie.Token.Column))
+		return
+	}
+	i, err := tupleIndex(ie.Index, len(tt.ElementTypes))
+	if err != nil {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s (Line %d, Column %d)", err, ie.Token.Line, ie.Token.Column))
+		return
+	}
+	a.TupleIndexes[ie] = i
+}
+
+// unpackTuple returns the types of the values a tuple of type t assigns to
+// n targets, reporting a count that doesn't match as Python does.
+func (a *Analyzer) unpackTuple(as *parser.AssignmentStatement, tt *parser.TupleType) []parser.Type {
+	n := len(as.Left)
+	switch {
+	case len(tt.ElementTypes) > n:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("too many values to unpack (expected %d) (Line %d, Column %d)", n, as.Token.Line, as.Token.Column))
+	case len(tt.ElementTypes) < n:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("not enough values to unpack (expected %d, got %d) (Line %d, Column %d)", n, len(tt.ElementTypes), as.Token.Line, as.Token.Column))
+	}
+	return tt.ElementTypes
+}
+
+// checkTupleItemAssignment reports an assignment to a value of a tuple,
+// which can't be changed.
+func (a *Analyzer) checkTupleItemAssignment(target parser.Expression) {
+	if ie, ok := target.(*parser.IndexExpression); ok {
+		if _, ok := a.TupleIndexes[ie]; ok {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'tuple' object does not support item assignment (Line %d, Column %d)", ie.Token.Line, ie.Token.Column))
+		}
+	}
+}
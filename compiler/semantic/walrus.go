@@ -0,0 +1,32 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// Assignment expressions
+//
+// name := value assigns value to name, as name = value does, and is the
+// value, so a loop can read the next value in its condition, as in
+// while (line := reader.next()) != None:. The name is a variable of the
+// function it is assigned in, as in Python, and keeps its last value after
+// the loop.
+
+// handleAssignmentExpression analyzes an assignment expression as the
+// assignment of its value to its name, reporting a target that isn't a name.
+func (a *Analyzer) handleAssignmentExpression(ae *parser.AssignmentExpression, remainingStatements []parser.Statement) {
+	ident, ok := ae.Target.(*parser.Identifier)
+	if !ok || ident == nil {
+		kind := "expression"
+		switch ae.Target.(type) {
+		case *parser.IndexExpression:
+			kind = "subscript"
+		case *parser.SelectorExpression:
+			kind = "attribute"
+		}
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("cannot use assignment expressions with %s; assign to a name, as in (x := %s) (Line %d, Column %d)", kind, ae.Value.String(), ae.Token.Line, ae.Token.Column))
+		return
+	}
+	a.handleAssignmentStatement(&parser.AssignmentStatement{Token: ae.Token, Left: []parser.Expression{ident}, Value: ae.Value}, remainingStatements)
+}
@@ -0,0 +1,148 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"go/types"
+)
+
+// ContextKind is a kind of value a with statement can enter and exit.
+type ContextKind int
+
+const (
+	// ClassContext is an instance of a class with __enter__ and __exit__
+	// methods. The name of the item is bound to what __enter__ returns.
+	ClassContext ContextKind = iota
+	// LockContext is a Go value with Lock() and Unlock() methods, such as a
+	// sync.Mutex, which is held for the body.
+	LockContext
+	// CloserContext is a Go value with a Close() method, such as the file
+	// open returns, which is closed after the body.
+	CloserContext
+)
+
+// ContextManager records how a with statement enters and exits one of its
+// items.
+type ContextManager struct {
+	Kind  ContextKind
+	Type  parser.Type // type of the value the name of the item is bound to
+	Value bool        // the value is a struct, whose methods are called through its address
+}
+
+// fileType is the type of the files open returns.
+var fileType = &parser.PointerType{ElementType: &parser.NamedType{Name: "File", Package: "os"}}
+
+// handleWithStatement analyzes a with statement. Each item is checked to be
+// a context manager and its name bound before the body is analyzed.
+func (a *Analyzer) handleWithStatement(ws *parser.WithStatement, remainingStatements []parser.Statement) {
+	for _, item := range ws.Items {
+		a.Analyze(item.Context, []parser.Statement{})
+		manager := a.contextManager(item, ws)
+		if manager == nil {
+			continue
+		}
+		a.ContextManagers[item] = manager
+		if item.Name != nil {
+			a.CurrentTable.Define(item.Name.Value, &Symbol{
+				Name:   item.Name.Value,
+				Type:   manager.Type,
+				Scope:  a.CurrentTable.Name,
+				GoType: a.GetGoTypeFromParserType(manager.Type),
+			})
+		}
+	}
+	a.Analyze(ws.Body, remainingStatements)
+}
+
+// contextManager works out how the item of a with statement is entered and
+// exited, reporting values that can't be as Python reports them.
+func (a *Analyzer) contextManager(item *parser.WithItem, ws *parser.WithStatement) *ContextManager {
+	t := a.InferExpressionTypes(item.Context, false)[0]
+	if class, ok := a.ClassOf(t); ok {
+		enter, exit := class.Method("__enter__"), class.Method("__exit__")
+		switch {
+		case enter == nil:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' object does not support the context manager protocol (missed __enter__ method) (Line %d, Column %d)", class.Name, ws.Token.Line, ws.Token.Column))
+			return nil
+		case exit == nil:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' object does not support the context manager protocol (missed __exit__ method) (Line %d, Column %d)", class.Name, ws.Token.Line, ws.Token.Column))
+			return nil
+		case len(enter.Params()) > 0 || len(exit.Params()) > 0:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s.__enter__ and %s.__exit__ take only self in Simple; __exit__ isn't told about exceptions raised in the with block (Line %d, Column %d)", class.Name, class.Name, ws.Token.Line, ws.Token.Column))
+			return nil
+		}
+		for _, method := range []*Method{enter, exit} {
+			if !method.analyzed && !method.analyzing {
+				a.analyzeMethod(method, []parser.Type{})
+			}
+		}
+		manager := &ContextManager{Kind: ClassContext, Type: &parser.BasicType{Name: "void"}}
+		if results := enter.Type.ReturnTypes; len(results) == 1 {
+			manager.Type = results[0]
+		} else if len(results) > 1 {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s.__enter__ must return a single value to be bound by a with statement (Line %d, Column %d)", class.Name, ws.Token.Line, ws.Token.Column))
+			return nil
+		}
+		if item.Name != nil && manager.Type.String() == "void" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("%s.__enter__ returns nothing to bind to '%s' (Line %d, Column %d)", class.Name, item.Name.Value, item.Name.Token.Line, item.Name.Token.Column))
+			return nil
+		}
+		return manager
+	}
+
+	goType := a.GetGoTypeFromParserType(t)
+	manager := &ContextManager{Type: t}
+	switch {
+	case takesNothing(methodSignature(goType, "Lock")) && takesNothing(methodSignature(goType, "Unlock")):
+		manager.Kind = LockContext
+	case methodSignature(goType, "Close") != nil && methodSignature(goType, "Close").Params().Len() == 0:
+		manager.Kind = CloserContext
+	default:
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' object does not support the context manager protocol; with takes a value with a Close method, a lock or an instance of a class with __enter__ and __exit__ methods (Line %d, Column %d)", t.String(), ws.Token.Line, ws.Token.Column))
+		return nil
+	}
+	_, isPointer := goType.Underlying().(*types.Pointer)
+	manager.Value = !isPointer && !types.IsInterface(goType)
+	if manager.Value {
+		switch item.Context.(type) {
+		case *parser.Identifier, *parser.SelectorExpression, *parser.IndexExpression:
+		default:
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("a %s held by value must be a variable or an attribute to be used in a with statement (Line %d, Column %d)", t.String(), ws.Token.Line, ws.Token.Column))
+			return nil
+		}
+		if item.Name != nil {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("a %s held by value can't be bound with 'as'; use it by its own name (Line %d, Column %d)", t.String(), item.Name.Token.Line, item.Name.Token.Column))
+			return nil
+		}
+	}
+	return manager
+}
+
+// takesNothing reports whether sig is that of a method with no parameters
+// or results, such as Lock and Unlock.
+func takesNothing(sig *types.Signature) bool {
+	return sig != nil && sig.Params().Len() == 0 && sig.Results().Len() == 0
+}
+
+// handleOpen checks a call of open, which opens a file as Python does, with
+// a mode such as "w" as its optional second argument. The file is an
+// *os.File, so the os package is loaded for its methods.
+func (a *Analyzer) handleOpen(ce *parser.CallExpression) {
+	a.importGoPackage("os", "")
+	if len(ce.Arguments) < 1 || len(ce.Arguments) > 2 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("open() takes 1 or 2 arguments, the file name and its mode, in Simple (%d given) (Line %d, Column %d)", len(ce.Arguments), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	for _, arg := range ce.Arguments {
+		if _, ok := arg.(*parser.KeywordArgument); ok {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("open() takes no keyword arguments in Simple (Line %d, Column %d)", ce.Token.Line, ce.Token.Column))
+			return
+		}
+		a.Analyze(arg, []parser.Statement{})
+		// Arguments of unknown type are converted with str()
+		if t := a.InferExpressionTypes(arg, false)[0]; t.String() != "string" && t.String() != "interface{}" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("open() argument must be str, not %s (Line %d, Column %d)", t.String(), ce.Token.Line, ce.Token.Column))
+			return
+		}
+	}
+}
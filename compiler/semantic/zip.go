@@ -0,0 +1,48 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// Zip records a for loop over zip(xs, ys), which takes an item of each list
+// at the same index until the shortest ends: for a, b in zip(xs, ys): is a
+// loop counting up to the length of the shortest.
+type Zip struct {
+	Lists     []parser.Expression // the lists, dicts or strings zipped
+	Kinds     []string            // "list", "dict", "string", or "any" when untyped
+	ItemTypes []parser.Type
+}
+
+// handleZip analyzes a for loop over zip(), defining a variable for the
+// items of each list.
+func (a *Analyzer) handleZip(fs *parser.ForStatement, ce *parser.CallExpression) {
+	z := &Zip{}
+	for _, arg := range ce.Arguments {
+		if ka, ok := arg.(*parser.KeywordArgument); ok {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("'%s' is an invalid keyword argument for zip() (Line %d, Column %d)", ka.Name.Value, ka.Token.Line, ka.Token.Column))
+			return
+		}
+		a.Analyze(arg, []parser.Statement{})
+		listType := a.InferExpressionTypes(arg, false)[0]
+		kind, itemType := itemsOf(listType)
+		if kind == "any" && listType.String() != "interface{}" {
+			a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("zip() takes the items of lists, dicts or strs, not %s (Line %d, Column %d)", annotationName(listType), ce.Token.Line, ce.Token.Column))
+			return
+		}
+		z.Lists = append(z.Lists, arg)
+		z.Kinds = append(z.Kinds, kind)
+		z.ItemTypes = append(z.ItemTypes, itemType)
+	}
+	if len(z.Lists) < 2 {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("zip() takes two or more lists (%d given) (Line %d, Column %d)", len(z.Lists), ce.Token.Line, ce.Token.Column))
+		return
+	}
+	if len(fs.Variables) != len(z.Lists) {
+		a.fatalErrors = append(a.fatalErrors, fmt.Sprintf("zip() of %d lists gives %d items at a time; loop over it with a variable for each, as in for a, b in zip(xs, ys): (Line %d, Column %d)", len(z.Lists), len(z.Lists), fs.Token.Line, fs.Token.Column))
+		return
+	}
+
+	a.Zips[fs] = z
+	a.defineLoopVariables(fs, z.ItemTypes)
+}
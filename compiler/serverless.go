@@ -0,0 +1,172 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"github.com/sasogeek/simple/compiler/codegen"
+	"github.com/sasogeek/simple/compiler/verbose"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// azureHostJSON configures a function app whose functions are served by
+// the custom handler binary, with requests forwarded to it as they are.
+const azureHostJSON = `{
+  "version": "2.0",
+  "customHandler": {
+    "description": {
+      "defaultExecutablePath": "handler"
+    },
+    "enableForwardingHttpRequest": true
+  }
+}
+`
+
+// azureFunctionJSON binds a function to an HTTP trigger.
+const azureFunctionJSON = `{
+  "bindings": [
+    {
+      "type": "httpTrigger",
+      "direction": "in",
+      "name": "req",
+      "methods": ["get", "post"],
+      "authLevel": "function"
+    },
+    {
+      "type": "http",
+      "direction": "out",
+      "name": "res"
+    }
+  ]
+}
+`
+
+// packageFunction builds the program in outputDir for Linux and zips it with
+// the shim of provider, giving outputDir/<binaryName>-<provider>.zip, ready
+// to deploy as a function on the provider's custom or Go runtime.
+func packageFunction(provider, outputDir, binaryName string) (string, error) {
+	defer verbose.Phase(1, "packaging for "+provider)()
+	staging, err := os.MkdirTemp("", "simple-"+provider+"-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(staging)
+	contents := filepath.Join(staging, "package")
+	shim := filepath.Join(staging, "shim")
+	for _, dir := range []string{contents, shim} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return "", err
+		}
+	}
+
+	// On Azure the function's folder takes the program's name
+	program := binaryName
+	if provider == "azure" {
+		program = "bin/" + binaryName
+	}
+	if err := crossBuild(outputDir, filepath.Join(contents, program), "."); err != nil {
+		return "", err
+	}
+	switch provider {
+	case "lambda":
+		// The provided runtime starts the executable named bootstrap
+		err = buildShim(shim, provider, program, filepath.Join(contents, "bootstrap"))
+	case "azure":
+		err = buildShim(shim, provider, program, filepath.Join(contents, "handler"))
+		if err == nil {
+			err = os.WriteFile(filepath.Join(contents, "host.json"), []byte(azureHostJSON), 0644)
+		}
+		if err == nil {
+			err = os.MkdirAll(filepath.Join(contents, binaryName), os.ModePerm)
+		}
+		if err == nil {
+			err = os.WriteFile(filepath.Join(contents, binaryName, "function.json"), []byte(azureFunctionJSON), 0644)
+		}
+	case "gcf":
+		// Cloud Functions builds the shim's source itself
+		err = codegen.WriteServerlessShim(contents, provider, program)
+		if err == nil {
+			err = os.WriteFile(filepath.Join(contents, "go.mod"), []byte("module simplefunction\n\ngo 1.21\n"), 0644)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	zipPath := filepath.Join(outputDir, binaryName+"-"+provider+".zip")
+	return zipPath, zipDir(contents, zipPath)
+}
+
+// buildShim writes the shim of provider, which runs program, into dir and
+// builds it for Linux as output.
+func buildShim(dir, provider, program, output string) error {
+	if err := codegen.WriteServerlessShim(dir, provider, program); err != nil {
+		return err
+	}
+	return crossBuild(dir, output, "simple_serverless.go")
+}
+
+// crossBuild builds the Go package in dir for Linux without cgo, as cloud
+// functions run it, for amd64 unless GOARCH asks for another architecture.
+func crossBuild(dir, output, pkg string) error {
+	goarch := os.Getenv("GOARCH")
+	if goarch == "" {
+		goarch = "amd64"
+	}
+	args := append([]string{"build"}, goBuildFlags()...)
+	cmd := exec.Command("go", append(args, "-o", output, pkg)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOOS=linux", "GOARCH="+goarch, "CGO_ENABLED=0")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to build %s for linux/%s: %w", filepath.Base(output), goarch, err)
+	}
+	return nil
+}
+
+// zipDir writes the files under dir to a zip archive at path, keeping their
+// permissions so that binaries stay executable.
+func zipDir(dir, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	err = filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Modified = zipModTime(info)
+		header.Method = zip.Deflate
+		w, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(w, src)
+		return err
+	})
+	if err != nil {
+		archive.Close()
+		return err
+	}
+	return archive.Close()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Signals
+//
+// The program simple runs stops when simple is stopped: SIGINT, which
+// Ctrl-C sends, and SIGTERM, which process managers and CI runners send,
+// are forwarded to it, unless a terminal sent it the Ctrl-C itself, and
+// simple waits for it to shut down cleanly, as a server closing its
+// connections does, rather than exiting and leaving it running. A second
+// signal kills it. simple then exits as a shell reports a program stopped
+// by a signal, with 128 and the signal's number.
+
+// forwardedSignals are the signals forwarded to the program.
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// forwardSignals forwards the signals simple is sent to process, killing
+// it on the second, and returns a function that stops forwarding them and
+// returns the first, or nil.
+func forwardSignals(process *os.Process) func() os.Signal {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, forwardedSignals...)
+	done := make(chan struct{})
+	first := make(chan os.Signal, 1)
+	// The terminal sends the program its own Ctrl-C; see stdinIsTerminal
+	terminal := stdinIsTerminal()
+	go func() {
+		var received os.Signal
+		for {
+			select {
+			case sig := <-signals:
+				if received == nil {
+					received = sig
+					if sig == os.Interrupt && terminal {
+						continue
+					}
+					// Windows can't send a signal, only kill
+					if process.Signal(sig) == nil {
+						continue
+					}
+				}
+				process.Kill()
+			case <-done:
+				first <- received
+				return
+			}
+		}
+	}()
+	return func() os.Signal {
+		signal.Stop(signals)
+		close(done)
+		return <-first
+	}
+}
+
+// signalError is the error of a program stopped by a signal simple
+// forwarded to it.
+type signalError struct {
+	signal os.Signal
+}
+
+func (e *signalError) Error() string {
+	return "the program was stopped by " + e.signal.String()
+}
+
+// exitCode returns the status a shell reports for a program stopped by the
+// signal.
+func (e *signalError) exitCode() int {
+	if s, ok := e.signal.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+)
+
+// Standard input
+//
+// The program simple runs reads simple's standard input, so a program that
+// asks its user questions can be run from a terminal, and a program that
+// reads a file piped to simple, as in simple count.simple < words.txt, gets
+// the file. The program is given simple's own terminal rather than a pipe
+// copying it, so it can tell it is run from one, as Python's isatty and Go's
+// term.IsTerminal do. A terminal sends the Ctrl-C typed into it to every
+// program in the foreground, the program as well as simple, so simple
+// doesn't forward that SIGINT again when its input is a terminal.
+
+// stdinIsTerminal reports whether simple's standard input is a terminal.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Tests
+//
+// simple test builds and runs the test programs of a project, the files
+// named like parser_test.simple, and reports each as ok or FAIL. A test
+// program is an ordinary program that checks its results with assert, so it
+// passes when it exits cleanly and fails when an assertion, or anything
+// else, stops it. Each is run by simple run in a process of its own, with
+// the flags simple test was given, and the output of a test that fails is
+// printed under it. Directories are searched with the directories inside
+// them, except hidden ones.
+
+// testSuffix ends the names of test programs.
+const testSuffix = "_test.simple"
+
+// runTests runs the test programs of args, files or directories, and
+// returns 1 if any failed.
+func runTests(args []string) int {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	programs, err := testPrograms(args)
+	if err != nil {
+		printError(err)
+		return 1
+	}
+	if len(programs) == 0 {
+		printErrorf("no test programs, *%s, in %s", testSuffix, strings.Join(args, " "))
+		return 1
+	}
+	self, err := os.Executable()
+	if err != nil {
+		printError(err)
+		return 1
+	}
+
+	failed := 0
+	for _, program := range programs {
+		cmd := exec.Command(self, append(passedFlags(), "run", filepath.Base(program))...)
+		cmd.Dir = filepath.Dir(program)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL %s\n", program)
+			for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+			continue
+		}
+		fmt.Printf("ok   %s\n", program)
+	}
+	fmt.Printf("%d of %d test programs passed\n", len(programs)-failed, len(programs))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// testPrograms returns the test programs among paths and in the
+// directories among them, sorted.
+func testPrograms(paths []string) ([]string, error) {
+	var programs []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if !strings.HasSuffix(path, testSuffix) {
+				return nil, fmt.Errorf("%s isn't a test program; their names end in %s", path, testSuffix)
+			}
+			programs = append(programs, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case entry.IsDir() && path != "." && strings.HasPrefix(entry.Name(), "."):
+				return filepath.SkipDir
+			case !entry.IsDir() && strings.HasSuffix(path, testSuffix):
+				programs = append(programs, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(programs)
+	return programs, nil
+}
+
+// passedFlags returns the flags simple was given, to give them to the
+// simple run of each test program.
+func passedFlags() []string {
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "define" {
+			// A value may hold the comma String joins them with
+			for name, value := range defines {
+				args = append(args, "-define="+name+"="+value)
+			}
+			return
+		}
+		if f.Name == "transform" {
+			// Each program is run from its own directory
+			for _, path := range transforms {
+				if abs, err := filepath.Abs(path); err == nil {
+					path = abs
+				}
+				args = append(args, "-transform="+path)
+			}
+			return
+		}
+		args = append(args, "-"+f.Name+"="+f.Value.String())
+	})
+	return args
+}
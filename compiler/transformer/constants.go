@@ -0,0 +1,184 @@
+package transformer
+
+import (
+	"cmp"
+	"github.com/sasogeek/simple/compiler/lexer"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// foldConditions replaces the conditions of if statements and conditional
+// expressions that are made of constants and literals alone, such as
+// DEBUG or LEVEL > 1 and not DEBUG, by True or False. The Go compiler leaves
+// out the code behind a condition that is always false, while it still
+// checks it.
+func (t *Transformer) foldConditions(program *parser.Program) {
+	parser.Inspect(program, func(n parser.Node) bool {
+		switch node := n.(type) {
+		case *parser.IfStatement:
+			if node != nil {
+				node.Condition = t.fold(node.Condition)
+			}
+		case *parser.ConditionalExpression:
+			if node != nil {
+				node.Condition = t.fold(node.Condition)
+			}
+		}
+		return true
+	})
+}
+
+// fold returns a condition as True or False if its value is known.
+func (t *Transformer) fold(condition parser.Expression) parser.Expression {
+	value, ok := t.constantValue(condition)
+	if !ok {
+		return condition
+	}
+	if truthy(value) {
+		return &parser.BooleanLiteral{Token: lexer.Token{Type: lexer.TokenTrue, Literal: "True"}, Value: true}
+	}
+	return &parser.BooleanLiteral{Token: lexer.Token{Type: lexer.TokenFalse, Literal: "False"}, Value: false}
+}
+
+// constantValue returns the value of an expression made of constants and
+// literals: a bool, int64, float64 or string.
+func (t *Transformer) constantValue(expr parser.Expression) (any, bool) {
+	switch e := expr.(type) {
+	case *parser.Identifier:
+		if e == nil {
+			break
+		}
+		if as, ok := t.analyzer.Constants[e.Value]; ok {
+			return t.constantValue(as.Value)
+		}
+	case *parser.BooleanLiteral:
+		if e != nil {
+			return e.Value, true
+		}
+	case *parser.IntegerLiteral:
+		if e != nil {
+			return e.Value, true
+		}
+	case *parser.StringLiteral:
+		if e != nil {
+			return e.Value, true
+		}
+	case *parser.PrefixExpression:
+		if e == nil {
+			break
+		}
+		right, ok := t.constantValue(e.Right)
+		if !ok {
+			break
+		}
+		switch e.Operator {
+		case "not":
+			return !truthy(right), true
+		case "-":
+			switch r := right.(type) {
+			case int64:
+				return -r, true
+			case float64:
+				return -r, true
+			}
+		}
+	case *parser.InfixExpression:
+		if e == nil {
+			break
+		}
+		left, ok := t.constantValue(e.Left)
+		if !ok {
+			break
+		}
+		right, ok := t.constantValue(e.Right)
+		if !ok {
+			break
+		}
+		return compareConstants(left, e.Operator, right)
+	}
+	return nil, false
+}
+
+// compareConstants returns the value of left operator right, for and, or
+// and the comparisons of numbers, strings and bools.
+func compareConstants(left any, operator string, right any) (any, bool) {
+	switch operator {
+	case "and":
+		if !truthy(left) {
+			return left, true
+		}
+		return right, true
+	case "or":
+		if truthy(left) {
+			return left, true
+		}
+		return right, true
+	}
+
+	var order int
+	switch l := left.(type) {
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, false
+		}
+		order = cmp.Compare(l, r)
+	case bool:
+		r, ok := right.(bool)
+		if !ok || (operator != "==" && operator != "!=") {
+			return nil, false
+		}
+		if l != r {
+			order = 1
+		}
+	default:
+		l64, lok := number(left)
+		r64, rok := number(right)
+		if !lok || !rok {
+			return nil, false
+		}
+		order = cmp.Compare(l64, r64)
+	}
+
+	switch operator {
+	case "==":
+		return order == 0, true
+	case "!=":
+		return order != 0, true
+	case "<":
+		return order < 0, true
+	case "<=":
+		return order <= 0, true
+	case ">":
+		return order > 0, true
+	case ">=":
+		return order >= 0, true
+	}
+	return nil, false
+}
+
+// number returns an int or float constant as a float64.
+func number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// truthy reports whether a constant is true in a condition, as in Python:
+// False, zero and the empty string are false.
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package transformer
+
+import (
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+)
+
+// A Plugin rewrites the AST of a program before it is analyzed, as a
+// custom lowering does: adding a log call to the start of every function,
+// say, or replacing calls of a deprecated function. What it adds is
+// analyzed and compiled as if the program had been written that way, so it
+// is checked and typed like the rest. It returns an error to stop the
+// build.
+type Plugin func(program *parser.Program) error
+
+type namedPlugin struct {
+	name   string
+	plugin Plugin
+}
+
+var plugins []namedPlugin
+
+// Register adds a plugin, which runs on each program after the plugins
+// registered before it. The name identifies it in errors.
+func Register(name string, plugin Plugin) {
+	plugins = append(plugins, namedPlugin{name: name, plugin: plugin})
+}
+
+// RunPlugins runs the registered plugins on a program.
+func RunPlugins(program *parser.Program) error {
+	for _, p := range plugins {
+		if err := p.plugin(program); err != nil {
+			return fmt.Errorf("transform %s: %w", p.name, err)
+		}
+	}
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 
 type Transformer struct {
 	analyzer *semantic.Analyzer
+	function *parser.FunctionLiteral // the function being transformed
 }
 
 func NewTransformer(analyzer *semantic.Analyzer) *Transformer {
@@ -23,6 +24,7 @@ func NewTransformer(analyzer *semantic.Analyzer) *Transformer {
 func (t *Transformer) Transform(node parser.Node, rNode parser.Node) {
 	switch n := node.(type) {
 	case *parser.Program:
+		t.foldConditions(n)
 		for _, stmt := range n.Statements {
 			t.Transform(stmt, rNode)
 		}
@@ -30,18 +32,49 @@ func (t *Transformer) Transform(node parser.Node, rNode parser.Node) {
 		if n != nil {
 			t.Transform(n.Expression, rNode)
 		}
+	case *parser.DeferStatement:
+		if n != nil {
+			t.Transform(n.Expression, rNode)
+		}
+	case *parser.GoStatement:
+		if n != nil {
+			t.Transform(n.Expression, rNode)
+		}
 	case *parser.CallExpression:
 		t.handleCallExpression(n, rNode)
 	case *parser.FunctionLiteral:
-		prevTable := t.analyzer.CurrentTable
-		t.analyzer.CurrentTable = t.analyzer.SymbolTables.Tables[n.Name.Value]
+		for _, d := range n.Decorators {
+			t.Transform(d, rNode)
+		}
+		prevTable, prevFunction := t.analyzer.CurrentTable, t.function
+		t.analyzer.CurrentTable, t.function = t.analyzer.SymbolTables.Tables[n.Name.Value], n
 		t.Transform(n.Body, rNode)
-		t.analyzer.CurrentTable = prevTable
+		t.analyzer.CurrentTable, t.function = prevTable, prevFunction
+	case *parser.ClassStatement:
+		class, ok := t.analyzer.Classes[n.Name.Value]
+		if !ok {
+			break
+		}
+		prevTable, prevFunction := t.analyzer.CurrentTable, t.function
+		if class.Init != nil {
+			t.analyzer.CurrentTable, t.function = class.InitTable, class.Init
+			t.Transform(class.Init.Body, rNode)
+		}
+		for _, method := range class.Methods {
+			t.analyzer.CurrentTable, t.function = method.Table, method.Fn
+			t.Transform(method.Fn.Body, rNode)
+		}
+		t.analyzer.CurrentTable, t.function = prevTable, prevFunction
 	case *parser.BlockStatement:
 		for _, stmt := range n.Statements {
 			t.Transform(stmt, rNode)
 		}
 	case *parser.AssignmentStatement:
+		if n.Operator != "" {
+			// Augmented assignments keep the type of their target
+			t.Transform(n.Value, rNode)
+			break
+		}
 		t.handleAssignmentStatement(n, rNode)
 		// Handle other node types as needed
 	case *parser.ReturnStatement:
@@ -53,8 +86,21 @@ func (t *Transformer) handleReturnStatement(rs *parser.ReturnStatement, rNode pa
 	// Transform the return value
 	t.Transform(rs.ReturnValue, rNode)
 
+	// The names of generators and decorated functions aren't bound to the
+	// functions their returns return from
+	if _, ok := t.analyzer.Generators[t.function]; ok {
+		return
+	}
+	if _, ok := t.analyzer.Decorations[t.function]; ok {
+		return
+	}
+	// An annotated result is the function's whatever it returns
+	if t.analyzer.ResultAnnotation(t.function) != nil {
+		return
+	}
+
 	// Infer the type of the return value
-	returnTypes := t.analyzer.InferExpressionTypes(rs.ReturnValue, true)
+	returnTypes := t.analyzer.ReturnTypesOf(rs.ReturnValue)
 
 	// Update the enclosing function's return types
 	enclosingFunc := t.analyzer.CurrentTable.Name
@@ -78,6 +124,10 @@ func (t *Transformer) handleAssignmentStatement(as *parser.AssignmentStatement,
 			varTypes = []parser.Type{&parser.BasicType{Name: as.Value.String()[:strings.Index(as.Value.String(), "{")]}}
 		}
 	}
+	// Several targets unpack a tuple, or take the results of a call
+	if tt, ok := t.analyzer.TupleOf(varTypes[0]); ok && len(as.Left) > 1 && len(varTypes) == 1 {
+		varTypes = tt.ElementTypes
+	}
 	// Update the symbol table and variable types
 	for i, leftExpr := range as.Left {
 		var currentVarType parser.Type
@@ -198,7 +248,8 @@ func (t *Transformer) updateFunctionParameterTypes(varName string, varType parse
 
 				// Update the parameter type
 				if funcType, ok := funcSymbol.Type.(*parser.FunctionType); ok {
-					if i < len(funcType.ParameterTypes) {
+					// An annotated parameter keeps its type
+					if i < len(funcType.ParameterTypes) && t.analyzer.ParameterAnnotation(funcType, i) == nil {
 						funcType.ParameterTypes[i] = varType
 					}
 				}
@@ -209,6 +260,11 @@ func (t *Transformer) updateFunctionParameterTypes(varName string, varType parse
 }
 
 func (t *Transformer) handleCallExpression(ce *parser.CallExpression, rNode parser.Node) {
+	if sl, ok := t.analyzer.StructLiterals[ce]; ok {
+		t.handleStructLiteral(ce, sl, rNode)
+		return
+	}
+
 	// Check if the function is a SelectorExpression (e.g., pkg.Func)
 	switch ce.Function.(type) {
 	case *parser.SelectorExpression:
@@ -223,6 +279,11 @@ func (t *Transformer) handleCallExpression(ce *parser.CallExpression, rNode pars
 					pkgName = fmt.Sprintf("%s", strings.Split(symbol.Type.(*parser.NamedType).Package, "/")[len(strings.Split(symbol.Type.(*parser.NamedType).Package, "/"))-1])
 					pkgFuncName := symbol.Type.(*parser.NamedType).Name
 					funcSymbol, exsts := t.analyzer.CurrentTable.Resolve(pkgFuncName)
+					if exsts {
+						// A function of the program can share the name of
+						// the interface type
+						_, exsts = funcSymbol.GoType.(*types.Interface)
+					}
 					if exsts {
 						//var methods []interface{}
 						var methodName string
@@ -232,6 +293,9 @@ func (t *Transformer) handleCallExpression(ce *parser.CallExpression, rNode pars
 							methodSig := funcSymbol.GoType.(*types.Interface).Method(i).Signature()
 							if methodName == funcName {
 								for paramId := range ce.Arguments {
+									if t.isAdapted(ce, paramId) {
+										continue
+									}
 									expectedType := methodSig.Params().At(paramId)
 									switch ce.Arguments[paramId].(type) {
 									case *parser.StringLiteral:
@@ -239,14 +303,14 @@ func (t *Transformer) handleCallExpression(ce *parser.CallExpression, rNode pars
 										case *types.Slice:
 											ce.Arguments[paramId].(*parser.StringLiteral).Value = fmt.Sprintf("%s", ce.Arguments[paramId].(*parser.StringLiteral).String())
 										default:
-											ce.Arguments[paramId].(*parser.StringLiteral).Value = fmt.Sprintf("%s(\"%s\")", expectedType.Type().String(), ce.Arguments[paramId].(*parser.StringLiteral).String())
+											ce.Arguments[paramId].(*parser.StringLiteral).Value = fmt.Sprintf("%s(\"%s\")", qualifiedTypeName(expectedType.Type()), ce.Arguments[paramId].(*parser.StringLiteral).String())
 										}
 									case *parser.Identifier:
 										switch expectedType.Type().(type) {
 										case *types.Slice:
 											ce.Arguments[paramId].(*parser.Identifier).Value = fmt.Sprintf("%s", ce.Arguments[paramId].(*parser.Identifier).String())
 										default:
-											ce.Arguments[paramId].(*parser.Identifier).Value = fmt.Sprintf("%s(%s)", expectedType.Type().String(), ce.Arguments[paramId].(*parser.Identifier).String())
+											ce.Arguments[paramId].(*parser.Identifier).Value = fmt.Sprintf("%s(%s)", qualifiedTypeName(expectedType.Type()), ce.Arguments[paramId].(*parser.Identifier).String())
 										}
 									case *parser.InfixExpression:
 										stringValue := ""
@@ -260,7 +324,7 @@ func (t *Transformer) handleCallExpression(ce *parser.CallExpression, rNode pars
 											ce.Arguments[paramId] = stringLiteral
 										default:
 											stringLiteral.Value = t.expressionToString(ce.Arguments[paramId].(*parser.InfixExpression))
-											stringLiteral.Value = fmt.Sprintf("%s(%s)", expectedType.Type().String(), stringLiteral.Value)
+											stringLiteral.Value = fmt.Sprintf("%s(%s)", qualifiedTypeName(expectedType.Type()), stringLiteral.Value)
 											ce.Arguments[paramId] = stringLiteral
 										}
 									}
@@ -287,6 +351,9 @@ func (t *Transformer) handleCallExpression(ce *parser.CallExpression, rNode pars
 								if methodName == funcName {
 									f = true
 									for paramId := range ce.Arguments {
+										if t.isAdapted(ce, paramId) {
+											continue
+										}
 										expectedType := methodSig.Params().At(paramId)
 										switch ce.Arguments[paramId].(type) {
 										case *parser.StringLiteral:
@@ -296,7 +363,7 @@ func (t *Transformer) handleCallExpression(ce *parser.CallExpression, rNode pars
 											case *types.Slice:
 												ce.Arguments[paramId].(*parser.Identifier).Value = fmt.Sprintf("%s", ce.Arguments[paramId].(*parser.Identifier).String())
 											default:
-												ce.Arguments[paramId].(*parser.Identifier).Value = fmt.Sprintf("%s(%s)", expectedType.Type().String(), ce.Arguments[paramId].(*parser.Identifier).String())
+												ce.Arguments[paramId].(*parser.Identifier).Value = fmt.Sprintf("%s(%s)", qualifiedTypeName(expectedType.Type()), ce.Arguments[paramId].(*parser.Identifier).String())
 											}
 
 										case *parser.InfixExpression:
@@ -311,7 +378,7 @@ func (t *Transformer) handleCallExpression(ce *parser.CallExpression, rNode pars
 												ce.Arguments[paramId] = stringLiteral
 											default:
 												stringLiteral.Value = t.expressionToString(ce.Arguments[paramId].(*parser.InfixExpression))
-												stringLiteral.Value = fmt.Sprintf("%s(%s)", expectedType.Type().String(), stringLiteral.Value)
+												stringLiteral.Value = fmt.Sprintf("%s(%s)", qualifiedTypeName(expectedType.Type()), stringLiteral.Value)
 												ce.Arguments[paramId] = stringLiteral
 											}
 										}
@@ -336,6 +403,9 @@ func (t *Transformer) handleCallExpression(ce *parser.CallExpression, rNode pars
 													if methodName == funcName {
 														f = true
 														for paramId := range ce.Arguments {
+															if t.isAdapted(ce, paramId) {
+																continue
+															}
 															expectedType := methodSig.Params().At(paramId)
 															switch ce.Arguments[paramId].(type) {
 															case *parser.StringLiteral:
@@ -371,7 +441,7 @@ func (t *Transformer) handleCallExpression(ce *parser.CallExpression, rNode pars
 																	ce.Arguments[paramId] = stringLiteral
 																default:
 																	stringLiteral.Value = t.expressionToString(ce.Arguments[paramId].(*parser.InfixExpression))
-																	stringLiteral.Value = fmt.Sprintf("%s(%s)", expectedType.Type().String(), stringLiteral.Value)
+																	stringLiteral.Value = fmt.Sprintf("%s(%s)", qualifiedTypeName(expectedType.Type()), stringLiteral.Value)
 																	ce.Arguments[paramId] = stringLiteral
 																}
 															}
@@ -444,6 +514,56 @@ func (t *Transformer) handleCallExpression(ce *parser.CallExpression, rNode pars
 	}
 }
 
+// handleStructLiteral converts keyword argument values to their field types
+// where Go would not assign them as is. Literals are left alone, since Go's
+// untyped constants already convert (5 becomes a time.Duration).
+func (t *Transformer) handleStructLiteral(ce *parser.CallExpression, sl *semantic.StructLiteral, rNode parser.Node) {
+	i := 0
+	for _, arg := range ce.Arguments {
+		ka, ok := arg.(*parser.KeywordArgument)
+		if !ok || i >= len(sl.Fields) {
+			continue
+		}
+		t.Transform(ka.Value, rNode)
+
+		switch ka.Value.(type) {
+		case *parser.IntegerLiteral, *parser.StringLiteral, *parser.BooleanLiteral:
+		default:
+			argType := t.analyzer.InferExpressionTypes(ka.Value, true)[0]
+			srcGoType := t.analyzer.GetGoTypeFromParserType(argType)
+			if !types.AssignableTo(srcGoType, sl.FieldGoTypes[i]) && types.ConvertibleTo(srcGoType, sl.FieldGoTypes[i]) {
+				ka.Value = &parser.TypeConversionExpression{
+					Expression: ka.Value,
+					TargetType: sl.FieldTypes[i],
+				}
+			}
+		}
+		i++
+	}
+}
+
+// isAdapted reports whether argument argIndex of ce is wrapped in a generated
+// interface adapter, in which case it must not be converted.
+func (t *Transformer) isAdapted(ce *parser.CallExpression, argIndex int) bool {
+	for _, wrapper := range t.analyzer.WrapFunctionCalls[ce] {
+		if wrapper.ArgIndex == argIndex {
+			return true
+		}
+	}
+	return false
+}
+
+// qualifiedTypeName formats a Go type the way generated code refers to it,
+// qualifying named types by package name rather than import path.
+func qualifiedTypeName(typ types.Type) string {
+	name := types.TypeString(typ, func(p *types.Package) string { return p.Name() })
+	if _, ok := typ.(*types.Pointer); ok {
+		// Names are used in conversions, where *T(x) would dereference
+		return "(" + name + ")"
+	}
+	return name
+}
+
 func (t *Transformer) expressionToString(expr parser.Expression) string {
 	switch e := expr.(type) {
 	case *parser.InfixExpression:
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/sasogeek/simple/compiler/parser"
+	"github.com/sasogeek/simple/compiler/transformer"
+	"github.com/sasogeek/simple/compiler/verbose"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+)
+
+// Transforms
+//
+// -transform loads an AST transform from a Go plugin, so a team can add its
+// own lowerings, such as logging every call of its services, without
+// changing the compiler. A plugin is a main package built with go build
+// -buildmode=plugin that exports
+//
+//	func Transform(program *parser.Program) error
+//
+// which the compiler registers with transformer.Register and runs on the
+// program before analyzing it. -transform is given the plugin's .so file,
+// or the directory of its package, which the compiler builds into the user
+// cache directory first. simple.json can list transforms, relative to its
+// directory, to run on every build of a project.
+//
+// Go only loads a plugin built by the same Go version as the compiler, from
+// the same source of every package they share, so the plugin's go.mod
+// requires the compiler module at the compiler's version, or replaces it
+// with the directory the compiler was built from.
+
+// transformFlags are the -transform flags.
+type transformFlags []string
+
+var transforms transformFlags
+
+func (t *transformFlags) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *transformFlags) Set(value string) error {
+	*t = append(*t, value)
+	return nil
+}
+
+// transformSymbol is what a transform plugin exports.
+const transformSymbol = "Transform"
+
+// loadTransforms registers the transforms of the plugins at paths, .so
+// files or package directories.
+func loadTransforms(paths []string) error {
+	for _, path := range paths {
+		file := path
+		if info, err := os.Stat(path); err != nil {
+			return fmt.Errorf("-transform %s: %w", path, err)
+		} else if info.IsDir() {
+			if file, err = buildTransform(path); err != nil {
+				return fmt.Errorf("-transform %s: %w", path, err)
+			}
+		}
+		done := verbose.Phase(2, "loading transform "+file)
+		transform, err := openTransform(file)
+		done()
+		if err != nil {
+			return fmt.Errorf("-transform %s: %w", path, err)
+		}
+		transformer.Register(path, transform)
+	}
+	return nil
+}
+
+// buildTransform builds the plugin of the package in dir into the user
+// cache directory, returning its file. go build's cache makes building a
+// plugin that hasn't changed quick.
+func buildTransform(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(dir))
+	file := filepath.Join(cache, "simple", "transforms", filepath.Base(dir)+"-"+hex.EncodeToString(sum[:8])+".so")
+
+	args := []string{"build", "-buildmode=plugin"}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		// The plugin's packages must be built as the compiler's were
+		for _, setting := range info.Settings {
+			if setting.Key == "-trimpath" && setting.Value == "true" {
+				args = append(args, "-trimpath")
+			}
+		}
+	}
+	defer verbose.Phase(1, "building transform "+dir)()
+	cmd := exec.Command("go", append(args, "-o", file, ".")...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("go build: %w", err)
+	}
+	return file, nil
+}
+
+// transformOf returns the transform a plugin exports, which openTransform
+// looked up.
+func transformOf(symbol any) (transformer.Plugin, error) {
+	transform, ok := symbol.(func(*parser.Program) error)
+	if !ok {
+		return nil, fmt.Errorf("the plugin's %s is a %T, not a func(*parser.Program) error", transformSymbol, symbol)
+	}
+	return transform, nil
+}
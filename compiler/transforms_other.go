@@ -0,0 +1,14 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+
+package main
+
+import (
+	"errors"
+	"github.com/sasogeek/simple/compiler/transformer"
+)
+
+// openTransform reports that Go only loads plugins on Linux, macOS and
+// FreeBSD, into a compiler built with cgo.
+func openTransform(file string) (transformer.Plugin, error) {
+	return nil, errors.New("Go plugins can only be loaded on Linux, macOS and FreeBSD, by a compiler built with cgo")
+}
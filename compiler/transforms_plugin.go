@@ -0,0 +1,21 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package main
+
+import (
+	"github.com/sasogeek/simple/compiler/transformer"
+	"plugin"
+)
+
+// openTransform loads the plugin in file and returns its transform.
+func openTransform(file string) (transformer.Plugin, error) {
+	p, err := plugin.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := p.Lookup(transformSymbol)
+	if err != nil {
+		return nil, err
+	}
+	return transformOf(symbol)
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/sasogeek/simple/compiler/semantic"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Upgrades
+//
+// The compiler and the stdlib modules in ~/simple/stdlib are installed
+// together but read separately, so a compiler updated without its stdlib
+// compiled programs against modules written for an older one. install.sh
+// and simple upgrade record the version of the compiler the stdlib came
+// with in stdlib/VERSION, and simple version -check warns when that is
+// older than the compiler, or missing, as it is for an install made before.
+//
+// simple upgrade asks GitHub for the latest release of Simple. When it is
+// newer than the compiler, the compiler is replaced by the release's binary
+// for this platform, simple_linux_amd64 and so on, or, for a platform the
+// release has no binary for, by one the go command builds from the
+// release's source. A release's binary is only installed once its SHA-256
+// is the one the release's checksums.txt gives it; simple upgrade fails,
+// leaving the compiler as it was, when the release has no checksum for it
+// or the binary downloaded has another. The stdlib is replaced by the
+// release's whenever it is older than the release.
+
+// latestReleaseURL is where GitHub describes the latest release of Simple.
+var latestReleaseURL = "https://api.github.com/repos/sasogeek/simple/releases/latest"
+
+// checksumsAsset is the asset of a release listing the SHA-256 of each of
+// its binaries, as sha256sum prints them.
+const checksumsAsset = "checksums.txt"
+
+// stdlibVersionFile is the file of the stdlib directory holding the
+// version of the compiler it was installed with.
+const stdlibVersionFile = "VERSION"
+
+// githubRelease is the part of GitHub's description of a release that
+// upgrade reads.
+type githubRelease struct {
+	TagName    string         `json:"tag_name"`
+	TarballURL string         `json:"tarball_url"`
+	Assets     []releaseAsset `json:"assets"`
+}
+
+// releaseAsset is a file of a release, such as a binary.
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// downloads fetches releases, with a timeout so that a network that never
+// answers doesn't hang simple upgrade.
+var downloads = &http.Client{Timeout: 5 * time.Minute}
+
+func versionCommand(args []string) int {
+	if len(args) != 0 {
+		flag.Usage()
+		return 2
+	}
+	fmt.Println(version)
+	if !checkStdlib {
+		return 0
+	}
+	if problem := stdlibProblem(version); problem != "" {
+		printWarning(problem + "; run simple upgrade, or install.sh again, to update it")
+		return 1
+	}
+	return 0
+}
+
+// stdlibProblem describes why the installed stdlib is older than want, a
+// version of the compiler, or returns "" when it is not.
+func stdlibProblem(want string) string {
+	dir := semantic.StdlibDir()
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Sprintf("there is no stdlib in %s", dir)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, stdlibVersionFile))
+	if err != nil {
+		return fmt.Sprintf("the stdlib in %s has no %s file, so it was installed before %s", dir, stdlibVersionFile, want)
+	}
+	installed := strings.TrimSpace(string(data))
+	if compareVersions(installed, want) < 0 {
+		return fmt.Sprintf("the stdlib in %s is from %s, older than %s", dir, installed, want)
+	}
+	return ""
+}
+
+func upgradeCommand(args []string) int {
+	if len(args) != 0 {
+		flag.Usage()
+		return 2
+	}
+	latest, err := latestRelease()
+	if err != nil {
+		printErrorf("finding the latest release: %v", err)
+		return 1
+	}
+	latestVersion := "Simple " + strings.TrimPrefix(latest.TagName, "v")
+	newer := compareVersions(latestVersion, version) > 0
+	if !newer && stdlibProblem(latestVersion) == "" {
+		fmt.Printf("%s is the latest release\n", version)
+		return 0
+	}
+
+	source, err := download(latest.TarballURL)
+	if err != nil {
+		printErrorf("downloading the source of %s: %v", latestVersion, err)
+		return 1
+	}
+	defer source.Close()
+	tmp, err := os.MkdirTemp("", "simple-upgrade")
+	if err != nil {
+		printError(err)
+		return 1
+	}
+	defer os.RemoveAll(tmp)
+	if err := extractSource(source, tmp); err != nil {
+		printErrorf("extracting the source of %s: %v", latestVersion, err)
+		return 1
+	}
+
+	if newer {
+		if err := upgradeBinary(latest, filepath.Join(tmp, "compiler")); err != nil {
+			printErrorf("upgrading the compiler to %s: %v", latestVersion, err)
+			return 1
+		}
+		fmt.Printf("upgraded %s to %s\n", version, latestVersion)
+	}
+	if err := installStdlib(filepath.Join(tmp, "compiler", "stdlib"), latestVersion); err != nil {
+		printErrorf("installing the stdlib of %s: %v", latestVersion, err)
+		return 1
+	}
+	fmt.Printf("installed the stdlib of %s in %s\n", latestVersion, semantic.StdlibDir())
+	return 0
+}
+
+// latestRelease returns GitHub's description of the latest release.
+func latestRelease() (*githubRelease, error) {
+	body, err := download(latestReleaseURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	release := &githubRelease{}
+	if err := json.NewDecoder(body).Decode(release); err != nil {
+		return nil, err
+	}
+	if release.TagName == "" || release.TarballURL == "" {
+		return nil, fmt.Errorf("%s describes no release", latestReleaseURL)
+	}
+	return release, nil
+}
+
+// download returns the body of a successful GET of url.
+func download(url string) (io.ReadCloser, error) {
+	resp, err := downloads.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// extractSource extracts a GitHub source archive, a gzipped tar whose files
+// are in a directory named after the commit, into dir without that
+// directory.
+func extractSource(archive io.Reader, dir string) error {
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, name, found := strings.Cut(header.Name, "/")
+		if !found || name == "" {
+			continue
+		}
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("the archive holds %s, outside its directory", header.Name)
+		}
+		path := filepath.Join(dir, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// upgradeBinary replaces the running compiler with the release's binary for
+// this platform, or with one built from the release's compiler source.
+func upgradeBinary(release *githubRelease, compilerSource string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if self, err = filepath.EvalSymlinks(self); err != nil {
+		return err
+	}
+	next := self + ".new"
+	defer os.Remove(next)
+
+	asset := fmt.Sprintf("simple_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		asset += ".exe"
+	}
+	if release.assetURL(asset) != "" {
+		if err := downloadVerified(release, asset, next); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("the release has no %s; building it from source\n", asset)
+		cmd := exec.Command("go", "build", "-o", next, ".")
+		cmd.Dir = compilerSource
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("go build: %w", err)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		// A running program can't be replaced on Windows, only renamed
+		old := self + ".old"
+		os.Remove(old)
+		if err := os.Rename(self, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(next, self)
+}
+
+// assetURL returns where the asset of the release named name is
+// downloaded from, or "" if the release has no such asset.
+func (r *githubRelease) assetURL(name string) string {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL
+		}
+	}
+	return ""
+}
+
+// downloadVerified downloads the asset of the release named asset to path,
+// an executable, failing unless its SHA-256 is the one the release's
+// checksums.txt gives it.
+func downloadVerified(release *githubRelease, asset, path string) error {
+	want, err := releaseChecksum(release, asset)
+	if err != nil {
+		return err
+	}
+	body, err := download(release.assetURL(asset))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, hash), body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		return fmt.Errorf("the SHA-256 of the %s downloaded is %s, but %s gives it %s", asset, got, checksumsAsset, want)
+	}
+	return nil
+}
+
+// releaseChecksum returns the SHA-256 the release's checksums.txt gives
+// the asset named asset, in hex.
+func releaseChecksum(release *githubRelease, asset string) (string, error) {
+	url := release.assetURL(checksumsAsset)
+	if url == "" {
+		return "", fmt.Errorf("the release has no %s to verify %s with", checksumsAsset, asset)
+	}
+	body, err := download(url)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		// sha256sum marks a file it read in binary mode with a *
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			if sum, err := hex.DecodeString(fields[0]); err != nil || len(sum) != sha256.Size {
+				return "", fmt.Errorf("%s gives %s the checksum %q, which isn't a SHA-256", checksumsAsset, asset, fields[0])
+			}
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", checksumsAsset, asset)
+}
+
+// installStdlib copies the stdlib modules in dir into the installed stdlib,
+// recording the version they come with.
+func installStdlib(dir, version string) error {
+	stdlibDir := semantic.StdlibDir()
+	if err := os.MkdirAll(stdlibDir, 0755); err != nil {
+		return err
+	}
+	modules, err := filepath.Glob(filepath.Join(dir, "*.simple"))
+	if err != nil {
+		return err
+	}
+	if len(modules) == 0 {
+		return fmt.Errorf("the release has no stdlib modules")
+	}
+	for _, module := range modules {
+		data, err := os.ReadFile(module)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(stdlibDir, filepath.Base(module)), data, 0644); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(stdlibDir, stdlibVersionFile), []byte(version+"\n"), 0644)
+}
+
+// compareVersions compares two versions, as in Simple 0.0.4 or v0.0.4,
+// number by number, returning -1, 0 or 1 as a is older than b, the same or
+// newer.
+func compareVersions(a, b string) int {
+	as, bs := versionNumbers(a), versionNumbers(b)
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		x, y := 0, 0
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionNumbers returns the numbers of a version, ignoring what comes
+// before the first digit and any suffix of a number, as in 1.2.0-rc1.
+func versionNumbers(v string) []int {
+	if i := strings.IndexAny(v, "0123456789"); i >= 0 {
+		v = v[i:]
+	}
+	var numbers []int
+	for _, part := range strings.Split(v, ".") {
+		if i := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+			part = part[:i]
+		}
+		n, _ := strconv.Atoi(part)
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
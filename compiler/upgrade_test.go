@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownloadVerified(t *testing.T) {
+	binary := "the compiler"
+	sum := sha256.Sum256([]byte(binary))
+	good := hex.EncodeToString(sum[:])
+	bad := strings.Repeat("0", len(good))
+
+	tests := []struct {
+		name      string
+		checksums string // the content of checksums.txt, or "" for a release without one
+		wantErr   string
+	}{
+		{"verified", good + "  simple_linux_amd64\n" + bad + "  simple_darwin_arm64\n", ""},
+		{"binary mode", good + " *simple_linux_amd64\n", ""},
+		{"mismatch", bad + "  simple_linux_amd64\n", "SHA-256"},
+		{"no checksum", good + "  simple_darwin_arm64\n", "no checksum for simple_linux_amd64"},
+		{"not a checksum", "1234  simple_linux_amd64\n", "isn't a SHA-256"},
+		{"no checksums.txt", "", "no checksums.txt"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/simple_linux_amd64":
+					w.Write([]byte(binary))
+				case "/checksums.txt":
+					w.Write([]byte(test.checksums))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			release := &githubRelease{Assets: []releaseAsset{{"simple_linux_amd64", server.URL + "/simple_linux_amd64"}}}
+			if test.checksums != "" {
+				release.Assets = append(release.Assets, releaseAsset{checksumsAsset, server.URL + "/checksums.txt"})
+			}
+
+			path := filepath.Join(t.TempDir(), "simple.new")
+			err := downloadVerified(release, "simple_linux_amd64", path)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got error %v, want one saying %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data, err := os.ReadFile(path); err != nil || string(data) != binary {
+				t.Fatalf("downloaded %q, %v; want %q", data, err, binary)
+			}
+		})
+	}
+}
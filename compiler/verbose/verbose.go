@@ -0,0 +1,38 @@
+// Package verbose provides the compiler's leveled diagnostic logging,
+// enabled with the -v and -vv flags.
+package verbose
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Level is the current verbosity: 0 is silent, 1 logs pipeline phases and
+// 2 additionally logs per-import and per-module detail.
+var Level int
+
+// Output is where log lines are written.
+var Output io.Writer = os.Stderr
+
+// Logf writes a log line if the verbosity is at least level.
+func Logf(level int, format string, args ...interface{}) {
+	if Level < level {
+		return
+	}
+	fmt.Fprintf(Output, "[simple] "+format+"\n", args...)
+}
+
+// Phase logs the start of a named pipeline phase at the given level and
+// returns a function that logs its duration when called.
+func Phase(level int, name string) func() {
+	if Level < level {
+		return func() {}
+	}
+	Logf(level, "%s...", name)
+	start := time.Now()
+	return func() {
+		Logf(level, "%s done in %s", name, time.Since(start).Round(time.Microsecond))
+	}
+}